@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// animeListMasterURL is the upstream anime-lists mapping file
+const animeListMasterURL = "https://raw.githubusercontent.com/Anime-Lists/anime-lists/master/anime-list-master.xml"
+
+// AnimeListFinding is one disagreement or gap found when comparing the
+// dataset against anime-list.xml
+type AnimeListFinding struct {
+	Kind      string `json:"kind"` // missing_tvdb, tvdb_mismatch, season_mismatch or season_hint
+	MalID     int    `json:"mal_id"`
+	AniDBID   int    `json:"anidb_id"`
+	Title     string `json:"title"`
+	OurValue  string `json:"ours,omitempty"`
+	AnimeList string `json:"anime_list"`
+}
+
+// RunImportAnimeList implements the `import-anime-list` subcommand. It
+// cross-references tv_ex.json with ScudLee's anime-list.xml and, with -seed,
+// writes TVDB overrides for shows that have none. Season findings are only
+// reported: overrides cannot set a season.
+func RunImportAnimeList(args []string) error {
+	fs := flag.NewFlagSet("import-anime-list", flag.ExitOnError)
+	input := fs.String("input", "", "Path to anime-list.xml (empty = fetch anime-list-master.xml from GitHub)")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	animeAPIFile := fs.String("animeapi", "", "Path to animeapi.tsv for MAL → AniDB IDs (empty = fetch from animeapi.my.id)")
+	report := fs.String("report", "", "Write all findings to this JSON file")
+	seed := fs.Bool("seed", false, "Add TVDB IDs missing from the dataset to "+overridesPath("tv")+" (season findings are only reported)")
+	fs.Parse(args)
+
+	shows, err := LoadShowOutput(*tvFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 {
+		return fmt.Errorf("no entries found in %s", *tvFile)
+	}
+
+	list, err := loadAnimeListXML(*input)
+	if err != nil {
+		return err
+	}
+	_, malToRow, err := LoadAnimeAPITSV(*animeAPIFile)
+	if err != nil {
+		return err
+	}
+
+	findings := compareAnimeList(shows, list, malToRow)
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Kind]++
+	}
+	fmt.Printf("Compared %d shows against %d anime-list entries\n", len(shows), len(list.Anime))
+	for _, kind := range sortedKeys(counts) {
+		fmt.Printf("  %-16s %d\n", kind, counts[kind])
+	}
+
+	if *report != "" {
+		if err := os.MkdirAll(filepath.Dir(*report), 0755); err != nil {
+			return err
+		}
+		if err := SaveJSON(*report, findings); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d findings to %s\n", len(findings), *report)
+	}
+
+	if *seed {
+		added, err := seedTVDBOverrides(findings)
+		if err != nil {
+			return fmt.Errorf("seed overrides: %w", err)
+		}
+		fmt.Printf("Added %d TVDB overrides to %s\n", added, overridesPath("tv"))
+	}
+	return nil
+}
+
+// loadAnimeListXML reads anime-list.xml from path, or fetches the master list
+func loadAnimeListXML(path string) (AnimeListXML, error) {
+	var list AnimeListXML
+	var r io.Reader
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return list, fmt.Errorf("open anime-list %s: %w", path, err)
+		}
+		defer f.Close()
+		r = f
+	} else {
+		fmt.Println("Fetching anime-list-master.xml from GitHub …")
+		resp, err := NewHTTPClient(5 * time.Minute).Get(animeListMasterURL)
+		if err != nil {
+			return list, fmt.Errorf("fetch anime-list: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return list, fmt.Errorf("fetch anime-list: HTTP %d (expected 200)", resp.StatusCode)
+		}
+		r = resp.Body
+	}
+
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return list, fmt.Errorf("parse anime-list: %w", err)
+	}
+	return list, nil
+}
+
+// compareAnimeList reports where the dataset and anime-list.xml disagree.
+// Entries whose tvdbid is not numeric (movie, OVA, unknown, …) or whose
+// season is absolute ("a") carry no comparable information and are ignored.
+func compareAnimeList(shows []OutputShow, list AnimeListXML, malToRow map[int]AnimeAPIRow) []AnimeListFinding {
+	byAniDB := make(map[int]AnimeListAnime, len(list.Anime))
+	for _, anime := range list.Anime {
+		byAniDB[anime.AniDBID] = anime
+	}
+
+	var findings []AnimeListFinding
+	for _, show := range shows {
+		row, ok := malToRow[show.MyAnimeList.ID]
+		if !ok || row.AniDB == 0 {
+			continue
+		}
+		anime, ok := byAniDB[row.AniDB]
+		if !ok {
+			continue
+		}
+		tvdbID, err := strconv.Atoi(anime.TVDBID)
+		if err != nil || tvdbID <= 0 {
+			continue
+		}
+
+		finding := AnimeListFinding{MalID: show.MyAnimeList.ID, AniDBID: row.AniDB, Title: show.MyAnimeList.Title}
+		switch {
+		case show.Externals == nil || show.Externals.TVDB == nil:
+			finding.Kind = "missing_tvdb"
+			finding.AnimeList = anime.TVDBID
+			findings = append(findings, finding)
+			continue
+		case *show.Externals.TVDB != tvdbID:
+			// Season numbers of different TVDB shows are not comparable
+			finding.Kind = "tvdb_mismatch"
+			finding.OurValue = strconv.Itoa(*show.Externals.TVDB)
+			finding.AnimeList = anime.TVDBID
+			findings = append(findings, finding)
+			continue
+		}
+
+		season, err := strconv.Atoi(anime.DefaultTVDBSeason)
+		if err != nil {
+			continue
+		}
+		switch {
+		case show.AbsoluteOffset != nil:
+			// Numbered absolutely on purpose, by an override
+		case show.Trakt.Season == nil:
+			finding.Kind = "season_hint"
+			finding.AnimeList = anime.DefaultTVDBSeason
+			findings = append(findings, finding)
+		case show.Trakt.Season.Number != season:
+			finding.Kind = "season_mismatch"
+			finding.OurValue = strconv.Itoa(show.Trakt.Season.Number)
+			finding.AnimeList = anime.DefaultTVDBSeason
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].MalID < findings[j].MalID
+	})
+	return findings
+}
+
+// seedTVDBOverrides appends an externals override for every missing_tvdb
+// finding, leaving MAL IDs that already have an override untouched. An
+// overrides file that exists but cannot be read is an error, as rewriting it
+// would drop the hand-written overrides.
+func seedTVDBOverrides(findings []AnimeListFinding) (int, error) {
+	overridesFile := overridesPath("tv")
+	var overrides []Override
+	if err := LoadJSON(overridesFile, &overrides); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	existing := make(map[int]bool, len(overrides))
+	for _, override := range overrides {
+		existing[override.MalID] = true
+	}
+
+	added := 0
+	for _, finding := range findings {
+		if finding.Kind != "missing_tvdb" || existing[finding.MalID] {
+			continue
+		}
+		tvdbID, _ := strconv.Atoi(finding.AnimeList)
+		externals := json.RawMessage(fmt.Sprintf(`{"tvdb":%d}`, tvdbID))
+		overrides = append(overrides, Override{
+			MalID:       finding.MalID,
+			Description: fmt.Sprintf("TVDB ID seeded from anime-lists (AniDB %d)", finding.AniDBID),
+			Externals:   &externals,
+		})
+		existing[finding.MalID] = true
+		added++
+	}
+
+	if added == 0 {
+		return 0, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(overridesFile), 0755); err != nil {
+		return 0, err
+	}
+	if err := SaveJSON(overridesFile, overrides); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
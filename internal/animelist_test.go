@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareAnimeList(t *testing.T) {
+	tvdb := func(id int) *int { return &id }
+	tests := []struct {
+		name     string
+		tvdb     *int
+		season   int // 0 for a split cour without a season
+		absolute bool
+		tvdbID   string
+		dSeason  string
+		want     string // finding kind, "" for none
+		ours     string
+	}{
+		{name: "agree", tvdb: tvdb(76885), season: 1, tvdbID: "76885", dSeason: "1"},
+		{name: "missing tvdb", season: 1, tvdbID: "76885", dSeason: "1", want: "missing_tvdb"},
+		{name: "tvdb mismatch", tvdb: tvdb(1), season: 2, tvdbID: "76885", dSeason: "1", want: "tvdb_mismatch", ours: "1"},
+		{name: "season hint", tvdb: tvdb(76885), tvdbID: "76885", dSeason: "2", want: "season_hint"},
+		{name: "season mismatch", tvdb: tvdb(76885), season: 2, tvdbID: "76885", dSeason: "1", want: "season_mismatch", ours: "2"},
+		{name: "movie", season: 1, tvdbID: "movie", dSeason: "1"},
+		{name: "unknown", season: 1, tvdbID: "unknown"},
+		{name: "absolute season", tvdb: tvdb(76885), season: 2, tvdbID: "76885", dSeason: "a"},
+		{name: "absolute offset", tvdb: tvdb(76885), absolute: true, tvdbID: "76885", dSeason: "1"},
+	}
+	for _, tt := range tests {
+		var show OutputShow
+		show.MyAnimeList.ID = 1
+		show.MyAnimeList.Title = "Cowboy Bebop"
+		show.Externals = &TraktExternalsShow{TVDB: tt.tvdb}
+		if tt.season != 0 {
+			json.Unmarshal([]byte(fmt.Sprintf(`{"trakt":{"season":{"number":%d}}}`, tt.season)), &show)
+		}
+		if tt.absolute {
+			show.AbsoluteOffset = new(int)
+		}
+		list := AnimeListXML{Anime: []AnimeListAnime{{AniDBID: 23, TVDBID: tt.tvdbID, DefaultTVDBSeason: tt.dSeason}}}
+		findings := compareAnimeList([]OutputShow{show}, list, map[int]AnimeAPIRow{1: {AniDB: 23, MyAnimeList: 1}})
+
+		if tt.want == "" {
+			if len(findings) != 0 {
+				t.Errorf("%s: findings = %+v, want none", tt.name, findings)
+			}
+			continue
+		}
+		if len(findings) != 1 || findings[0].Kind != tt.want || findings[0].OurValue != tt.ours || findings[0].AniDBID != 23 {
+			t.Errorf("%s: findings = %+v, want one %s with ours %q", tt.name, findings, tt.want, tt.ours)
+		}
+	}
+
+	// Shows without an AniDB ID, or whose AniDB ID anime-lists lacks, are skipped
+	var show OutputShow
+	show.MyAnimeList.ID = 2
+	list := AnimeListXML{Anime: []AnimeListAnime{{AniDBID: 23, TVDBID: "76885"}}}
+	if findings := compareAnimeList([]OutputShow{show}, list, map[int]AnimeAPIRow{}); len(findings) != 0 {
+		t.Errorf("show without an AniDB ID: findings = %+v", findings)
+	}
+	if findings := compareAnimeList([]OutputShow{show}, list, map[int]AnimeAPIRow{2: {AniDB: 99}}); len(findings) != 0 {
+		t.Errorf("AniDB ID missing from anime-lists: findings = %+v", findings)
+	}
+}
+
+func TestRunImportAnimeListSeeds(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { layout = DefaultLayout() })
+	layout = DefaultLayout()
+	writeAnimeListFixture(t)
+	// MAL 6 and 8 lack a TVDB ID; MAL 1 differs on the season
+	os.WriteFile("anime-list.xml", []byte(`<anime-list>
+		<anime anidbid="23" tvdbid="76885" defaulttvdbseason="2"><name>Cowboy Bebop</name></anime>
+		<anime anidbid="2" tvdbid="72248" defaulttvdbseason="1"><name>Trigun</name></anime>
+		<anime anidbid="86" tvdbid="79177" defaulttvdbseason="1"><name>Witch Hunter Robin</name></anime>
+	</anime-list>`), 0644)
+	os.MkdirAll(layout.OverridesDir(), 0755)
+	os.WriteFile(overridesPath("tv"), []byte(`[
+		{"mal_id": 8, "description": "Hand-written", "externals": {"tvdb": 1}},
+		{"mal_id": 99, "description": "Unrelated", "ignore": true}
+	]`), 0644)
+
+	args := []string{"-input", "anime-list.xml", "-tv", "tv_ex.json", "-animeapi", "animeapi.tsv", "-report", "report.json", "-seed"}
+	if err := RunImportAnimeList(args); err != nil {
+		t.Fatal(err)
+	}
+
+	var findings []AnimeListFinding
+	LoadJSON("report.json", &findings)
+	kinds := make(map[int]string)
+	for _, finding := range findings {
+		kinds[finding.MalID] = finding.Kind
+	}
+	if kinds[1] != "season_mismatch" || kinds[6] != "missing_tvdb" || kinds[8] != "missing_tvdb" {
+		t.Errorf("findings = %+v, want MAL 1 season_mismatch and MAL 6 and 8 missing_tvdb", findings)
+	}
+
+	var overrides []Override
+	if err := LoadJSON(overridesPath("tv"), &overrides); err != nil {
+		t.Fatal(err)
+	}
+	byMAL := make(map[int]Override)
+	for _, override := range overrides {
+		byMAL[override.MalID] = override
+	}
+	if len(overrides) != 3 || byMAL[99].Description != "Unrelated" || byMAL[8].Description != "Hand-written" {
+		t.Fatalf("overrides = %+v, want the two existing ones kept and MAL 6 added", overrides)
+	}
+	var externals TraktExternalsShow
+	if seeded, ok := byMAL[6]; !ok || json.Unmarshal(*seeded.Externals, &externals) != nil || externals.TVDB == nil || *externals.TVDB != 72248 {
+		t.Errorf("seeded override = %+v, want TVDB 72248", byMAL[6])
+	}
+	if _, ok := byMAL[1]; ok {
+		t.Error("season finding was seeded")
+	}
+
+	// A malformed overrides file is left alone
+	os.WriteFile(overridesPath("tv"), []byte(`[{"mal_id": 8,`), 0644)
+	if err := RunImportAnimeList(args); err == nil {
+		t.Error("seeding over a malformed overrides file succeeded")
+	}
+	if data, _ := os.ReadFile(overridesPath("tv")); !strings.HasPrefix(string(data), `[{"mal_id": 8,`) {
+		t.Errorf("malformed overrides file rewritten: %s", data)
+	}
+
+	// An overrides path that cannot be written fails the command
+	os.Remove(overridesPath("tv"))
+	wd, _ := os.Getwd()
+	layout.Overrides = filepath.Join(wd, "tv_ex.json", "overrides")
+	if err := RunImportAnimeList(args); err == nil {
+		t.Error("seeding into an unwritable directory succeeded")
+	}
+}
@@ -3,16 +3,49 @@ package internal
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DefaultTraktAPIURL is the Trakt API root. Config.TraktAPIURL
+// (-trakt-api-url) replaces it, e.g. with a trakttest server.
+const DefaultTraktAPIURL = "https://api.trakt.tv"
+
+// DefaultLetterboxdURL is the Letterboxd root. Config.LetterboxdURL
+// replaces it, e.g. with a test server.
+const DefaultLetterboxdURL = "https://letterboxd.com"
+
+// letterboxdURL formats a Letterboxd path under the configured root
+func letterboxdURL(config Config, format string, args ...any) string {
+	base := config.LetterboxdURL
+	if base == "" {
+		base = DefaultLetterboxdURL
+	}
+	return strings.TrimSuffix(base, "/") + fmt.Sprintf(format, args...)
+}
+
+// traktURL formats a Trakt API path under the configured root
+func traktURL(config Config, format string, args ...any) string {
+	base := config.TraktAPIURL
+	if base == "" {
+		base = DefaultTraktAPIURL
+	}
+	return strings.TrimSuffix(base, "/") + fmt.Sprintf(format, args...)
+}
+
+// errTraktNotFound is wrapped by fetch errors for items Trakt answers 404 for
+var errTraktNotFound = errors.New("404")
+
 // decompressGzipIfNeeded decompresses gzip-compressed body if Content-Encoding header indicates gzip
 func decompressGzipIfNeeded(body []byte, resp *http.Response) []byte {
 	if strings.ToLower(resp.Header.Get("Content-Encoding")) == "gzip" {
@@ -32,19 +65,36 @@ func decompressGzipIfNeeded(body []byte, resp *http.Response) []byte {
 
 // FetchTraktShow fetches show data from Trakt API
 func FetchTraktShow(client *http.Client, config Config, showID int) (*TraktShow, error) {
+	// Concurrent lookups of the same key share one request
 	cacheFile := filepath.Join(config.TempDir, "shows", fmt.Sprintf("%d.json", showID))
-	if data, err := os.ReadFile(cacheFile); err == nil && !config.Force {
-		var show TraktShow
-		if json.Unmarshal(data, &show) == nil {
+	return singleFetch(cacheFile, func() (*TraktShow, error) {
+		return fetchTraktShow(client, config, strconv.Itoa(showID), cacheFile)
+	})
+}
+
+// FetchTraktShowBySlug fetches show data by Trakt slug. Results are cached
+// apart from ID lookups, under shows/slug_<slug>.json.
+func FetchTraktShowBySlug(client *http.Client, config Config, slug string) (*TraktShow, error) {
+	cacheFile := filepath.Join(config.TempDir, "shows", "slug_"+slug+".json")
+	return singleFetch(cacheFile, func() (*TraktShow, error) {
+		return fetchTraktShow(client, config, slug, cacheFile)
+	})
+}
+
+// fetchTraktShow does the work of FetchTraktShow and FetchTraktShowBySlug; id is
+// a Trakt ID or slug
+func fetchTraktShow(client *http.Client, config Config, id, cacheFile string) (*TraktShow, error) {
+	if useCache(config, cacheFile) {
+		if show, ok := cacheLoad[TraktShow](config.Cache, cacheFile); ok && !warmedIncomplete(config, show.Warmed, show.Images, show.Genres) {
 			if config.Verbose {
-				fmt.Printf("\n    - using cached Trakt show data")
+				Debugf("\n    - using cached Trakt show data")
 			}
 			return &show, nil
 		}
 	}
 
 	if config.Verbose {
-		fmt.Printf("\n    - fetching show %d from Trakt API", showID)
+		Debugf("\n    - fetching show %s from Trakt API", id)
 	}
 
 	config.RateLimiter.Wait()
@@ -52,7 +102,7 @@ func FetchTraktShow(client *http.Client, config Config, showID int) (*TraktShow,
 
 	retryConfig := DefaultRetryConfig()
 	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
-		url := fmt.Sprintf("https://api.trakt.tv/shows/%d", showID)
+		url := traktURL(config, "/shows/%s?extended=full,images", neturl.PathEscape(id))
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -71,10 +121,10 @@ func FetchTraktShow(client *http.Client, config Config, showID int) (*TraktShow,
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("\n    - show not found: 404")
+		return nil, fmt.Errorf("\n    - show not found: %w", errTraktNotFound)
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("\n    - API error: %d", resp.StatusCode)
+		return nil, statusError(resp, fmt.Errorf("\n    - API error: %d", resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -87,25 +137,48 @@ func FetchTraktShow(client *http.Client, config Config, showID int) (*TraktShow,
 		return nil, err
 	}
 
-	os.WriteFile(cacheFile, body, 0644)
+	cacheStore(config.Cache, cacheFile, body, show)
 	return &show, nil
 }
 
+// warmedIncomplete reports whether a cache entry written by `cache warm` lacks
+// fields this run adds to the output. Such an entry is fetched again rather
+// than trusted to be Trakt's answer.
+func warmedIncomplete(config Config, warmed bool, images *TraktImages, genres []string) bool {
+	return warmed && ((config.Images && images == nil) || (config.Genres && genres == nil))
+}
+
 // FetchTraktMovie fetches movie data from Trakt API
 func FetchTraktMovie(client *http.Client, config Config, movieID int) (*TraktMovie, error) {
 	cacheFile := filepath.Join(config.TempDir, "movies", fmt.Sprintf("%d.json", movieID))
-	if data, err := os.ReadFile(cacheFile); err == nil && !config.Force {
-		var movie TraktMovie
-		if json.Unmarshal(data, &movie) == nil {
+	return singleFetch(cacheFile, func() (*TraktMovie, error) {
+		return fetchTraktMovie(client, config, strconv.Itoa(movieID), cacheFile)
+	})
+}
+
+// FetchTraktMovieBySlug fetches movie data by Trakt slug. Results are cached
+// apart from ID lookups, under movies/slug_<slug>.json.
+func FetchTraktMovieBySlug(client *http.Client, config Config, slug string) (*TraktMovie, error) {
+	cacheFile := filepath.Join(config.TempDir, "movies", "slug_"+slug+".json")
+	return singleFetch(cacheFile, func() (*TraktMovie, error) {
+		return fetchTraktMovie(client, config, slug, cacheFile)
+	})
+}
+
+// fetchTraktMovie does the work of FetchTraktMovie and FetchTraktMovieBySlug; id is
+// a Trakt ID or slug
+func fetchTraktMovie(client *http.Client, config Config, id, cacheFile string) (*TraktMovie, error) {
+	if useCache(config, cacheFile) {
+		if movie, ok := cacheLoad[TraktMovie](config.Cache, cacheFile); ok && !warmedIncomplete(config, movie.Warmed, movie.Images, movie.Genres) {
 			if config.Verbose {
-				fmt.Printf("\n    - using cached Trakt movie data")
+				Debugf("\n    - using cached Trakt movie data")
 			}
 			return &movie, nil
 		}
 	}
 
 	if config.Verbose {
-		fmt.Printf("\n    - fetching movie %d from Trakt API", movieID)
+		Debugf("\n    - fetching movie %s from Trakt API", id)
 	}
 
 	config.RateLimiter.Wait()
@@ -113,7 +186,7 @@ func FetchTraktMovie(client *http.Client, config Config, movieID int) (*TraktMov
 
 	retryConfig := DefaultRetryConfig()
 	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
-		url := fmt.Sprintf("https://api.trakt.tv/movies/%d", movieID)
+		url := traktURL(config, "/movies/%s?extended=full,images", neturl.PathEscape(id))
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -132,10 +205,10 @@ func FetchTraktMovie(client *http.Client, config Config, movieID int) (*TraktMov
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("\n    - movie not found: 404")
+		return nil, fmt.Errorf("\n    - movie not found: %w", errTraktNotFound)
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("\n    - API error: %d", resp.StatusCode)
+		return nil, statusError(resp, fmt.Errorf("\n    - API error: %d", resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -148,29 +221,56 @@ func FetchTraktMovie(client *http.Client, config Config, movieID int) (*TraktMov
 		return nil, err
 	}
 
-	os.WriteFile(cacheFile, body, 0644)
+	cacheStore(config.Cache, cacheFile, body, movie)
 	return &movie, nil
 }
 
-// FetchTraktSeason fetches season data from Trakt API
+// FetchTraktSeason fetches season data from Trakt API. All seasons of a show
+// come from one request for its season list, which concurrent lookups of any
+// of them share.
 func FetchTraktSeason(client *http.Client, config Config, showID, seasonNum int) (*TraktSeason, error) {
 	cacheFile := filepath.Join(config.TempDir, "seasons", fmt.Sprintf("%d.json", showID))
-	if data, err := os.ReadFile(cacheFile); err == nil && !config.Force {
-		var seasons []TraktSeason
-		if json.Unmarshal(data, &seasons) == nil {
-			for _, season := range seasons {
-				if season.Number == seasonNum {
-					if config.Verbose {
-						fmt.Printf("\n        - using cached Trakt season data")
-					}
-					return &season, nil
+	if useCache(config, cacheFile) {
+		if seasons, ok := cacheLoad[[]TraktSeason](config.Cache, cacheFile); ok {
+			if season := findSeason(seasons, seasonNum); season != nil {
+				if config.Verbose {
+					Debugf("\n        - using cached Trakt season data")
 				}
+				return season, nil
+			}
+			// A list cached by an earlier run may predate the season
+			if config.Cache.fetchedThisRun(cacheFile) {
+				return nil, fmt.Errorf("\n        - season %d not found", seasonNum)
 			}
 		}
 	}
 
+	seasons, err := singleFetch(cacheFile, func() ([]TraktSeason, error) {
+		return fetchTraktSeasons(client, config, showID, cacheFile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if season := findSeason(seasons, seasonNum); season != nil {
+		return season, nil
+	}
+	return nil, fmt.Errorf("\n        - season %d not found", seasonNum)
+}
+
+// findSeason returns season seasonNum of a season list
+func findSeason(seasons []TraktSeason, seasonNum int) *TraktSeason {
+	for _, season := range seasons {
+		if season.Number == seasonNum {
+			return &season
+		}
+	}
+	return nil
+}
+
+// fetchTraktSeasons fetches the season list of a show for FetchTraktSeason
+func fetchTraktSeasons(client *http.Client, config Config, showID int, cacheFile string) ([]TraktSeason, error) {
 	if config.Verbose {
-		fmt.Printf("\n        - fetching seasons for show %d from Trakt API", showID)
+		Debugf("\n        - fetching seasons for show %d from Trakt API", showID)
 	}
 
 	config.RateLimiter.Wait()
@@ -178,7 +278,7 @@ func FetchTraktSeason(client *http.Client, config Config, showID, seasonNum int)
 
 	retryConfig := DefaultRetryConfig()
 	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
-		url := fmt.Sprintf("https://api.trakt.tv/shows/%d/seasons", showID)
+		url := traktURL(config, "/shows/%d/seasons?extended=full", showID)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -200,7 +300,7 @@ func FetchTraktSeason(client *http.Client, config Config, showID, seasonNum int)
 		return nil, fmt.Errorf("\n        - seasons not found: 404")
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("\n        - API error: %d", resp.StatusCode)
+		return nil, statusError(resp, fmt.Errorf("\n        - API error: %d", resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -213,28 +313,35 @@ func FetchTraktSeason(client *http.Client, config Config, showID, seasonNum int)
 		return nil, err
 	}
 
-	os.WriteFile(cacheFile, body, 0644)
-
-	for _, season := range seasons {
-		if season.Number == seasonNum {
-			return &season, nil
-		}
-	}
-
-	return nil, fmt.Errorf("\n        - season %d not found", seasonNum)
+	cacheStore(config.Cache, cacheFile, body, seasons)
+	return seasons, nil
 }
 
 // FetchLetterboxdInfo fetches Letterboxd info from the Letterboxd API
 // If fetchAttempted is true and returns an error, it will preserve existingData if provided
-func FetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, existingData *Letterboxd) (*Letterboxd, error) {
-	cacheFile := filepath.Join(config.TempDir, "letterboxd", fmt.Sprintf("%d.json", tmdbID))
-	if data, err := os.ReadFile(cacheFile); err == nil && !config.Force {
-		var lb Letterboxd
-		if json.Unmarshal(data, &lb) == nil {
+// imdbID, when not empty, is tried if the TMDB redirect fails, or on its own
+// when tmdbID is 0
+func FetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, imdbID string, existingData *Letterboxd) (*Letterboxd, error) {
+	return singleFetch(letterboxdCachePath(config, tmdbID, imdbID), func() (*Letterboxd, error) {
+		return fetchLetterboxdInfo(client, config, tmdbID, imdbID, existingData)
+	})
+}
+
+// fetchLetterboxdInfo does the work of FetchLetterboxdInfo
+func fetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, imdbID string, existingData *Letterboxd) (*Letterboxd, error) {
+	cacheFile := letterboxdCachePath(config, tmdbID, imdbID)
+	if useCache(config, cacheFile) {
+		if entry, ok := cacheLoad[letterboxdCacheEntry](config.Cache, cacheFile); ok && entry.fresh(config) {
+			if entry.NotFound {
+				if config.Verbose {
+					Debugf("\n    - Film not found on Letterboxd (cached)")
+				}
+				return nil, errLetterboxdNotFound(tmdbID, imdbID)
+			}
 			if config.Verbose {
-				fmt.Printf("\n    - using cached Letterboxd data")
+				Debugf("\n    - using cached Letterboxd data")
 			}
-			return &lb, nil
+			return &entry.Letterboxd, nil
 		}
 	}
 
@@ -244,134 +351,71 @@ func FetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, existin
 			(existingData.UID != nil && *existingData.UID != 0) ||
 			(existingData.LID != nil && *existingData.LID != "") {
 			if config.Verbose {
-				fmt.Printf("\n    - using existing Letterboxd data (fallback)")
+				Debugf("\n    - using existing Letterboxd data (fallback)")
 			}
 			return existingData, nil
 		}
 	}
 
-	// Step 1: Get Slug from redirect
+	// Step 1: Get Slug from redirect, by TMDB ID and then by IMDB ID
+	resolvedVia := "tmdb"
 	var slug string
-	redirectURL := fmt.Sprintf("https://letterboxd.com/tmdb/%d/", tmdbID)
-
-	noRedirectClient := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Timeout: 15 * time.Second,
+	err := errLetterboxdMissing
+	if tmdbID != 0 {
+		slug, err = resolveLetterboxdSlug(config, letterboxdURL(config, "/tmdb/%d/", tmdbID))
 	}
-
-	config.LetterboxdRateLimiter.Wait()
-	retryConfig := DefaultRetryConfig()
-	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
-		req, err := http.NewRequest("GET", redirectURL, nil)
-		if err != nil {
-			return nil, err
-		}
-		setLetterboxdHeaders(req)
-		return noRedirectClient.Do(req)
-	})
-
-	if err != nil {
+	if err != nil && imdbID != "" && !errors.Is(err, errLetterboxdDenied) && !errors.Is(err, errLetterboxdBlocked) {
 		if config.Verbose {
-			fmt.Printf("\n    - error fetching Letterboxd redirect: %v", err)
+			Debugf("\n    - no TMDB redirect, trying IMDB ID %s", imdbID)
+		}
+		imdbSlug, imdbErr := resolveLetterboxdSlug(config, letterboxdURL(config, "/imdb/%s/", imdbID))
+		if imdbErr == nil {
+			slug, err, resolvedVia = imdbSlug, nil, "imdb"
+		} else if !errors.Is(imdbErr, errLetterboxdMissing) {
+			// A transient IMDB failure must not turn into a cached miss
+			err = imdbErr
 		}
-		return nil, err
 	}
-	defer resp.Body.Close()
 
-	// Handle 403 gracefully - preserve existing data if available
-	if resp.StatusCode == 403 {
-		if config.Verbose {
-			fmt.Printf("\n    - Letterboxd returned 403 (access denied)")
-		}
+	switch {
+	case errors.Is(err, errLetterboxdDenied):
+		// Handle 403 gracefully - preserve existing data if available
 		if existingData != nil {
 			if config.Verbose {
-				fmt.Printf(", using existing data as fallback")
+				Debugf(", using existing data as fallback")
 			}
 			return existingData, nil
 		}
 		if config.Verbose {
-			fmt.Printf(", skipping")
+			Debugf(", skipping")
 		}
 		return nil, nil
-	}
-
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		location, err := resp.Location()
-		if err != nil {
-			return nil, err
-		}
-		pathParts := strings.Split(strings.Trim(location.Path, "/"), "/")
-		if len(pathParts) >= 2 && pathParts[0] == "film" {
-			slug = pathParts[1]
-			if config.Verbose {
-				fmt.Printf("\n    - successfully extracted slug from redirect: %s", slug)
-			}
-		} else {
-			return nil, fmt.Errorf("\n    - could not parse slug from redirect location: %s", location.Path)
-		}
-	} else if resp.StatusCode == 200 {
-		// 200 OK might indicate Cloudflare challenge page or we've been served the page directly
-		// Check if response body contains Cloudflare challenge
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		bodyBytes = decompressGzipIfNeeded(bodyBytes, resp)
-
-		resp.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
-		bodyStr := string(bodyBytes)
-
-		if strings.Contains(bodyStr, "Just a moment...") || strings.Contains(bodyStr, "Attention Required!") {
-			if config.Verbose {
-				fmt.Printf("\n    - Letterboxd blocked by Cloudflare challenge (unable to bypass via HTTP)")
-			}
-			return nil, fmt.Errorf("\n    - Letterboxd blocked by Cloudflare - cannot fetch via HTTP")
-		}
-
-		if strings.Contains(bodyStr, "Film not found") || strings.Contains(bodyStr, "film-not-found") || strings.Contains(bodyStr, "not-found") || strings.Contains(bodyStr, "TMDB Import Result") {
-			if config.Verbose {
-				fmt.Printf("\n    - Film not found on Letterboxd")
-			}
-			return nil, fmt.Errorf("\n    - Film not found on Letterboxd for TMDB ID %d", tmdbID)
-		}
-
-		// Create a preview of the response for debugging
-		preview := bodyStr
-		if len(preview) > 200 {
-			preview = preview[:200]
-		}
-		if config.Verbose {
-			fmt.Printf("\n    - Letterboxd returned 200 OK instead of redirect")
-			fmt.Printf("\n      Body preview: %s...", preview)
-		}
-		return nil, fmt.Errorf("\n    - expected redirect, but got 200 OK: %s...", preview)
-	} else {
-		if config.Verbose {
-			fmt.Printf("\n    - Letterboxd redirect failed with status %d (expected 300-399)", resp.StatusCode)
-		}
-		return nil, fmt.Errorf("\n    - expected redirect, but got status %d", resp.StatusCode)
-	}
-
-	if slug == "" {
-		return nil, fmt.Errorf("failed to extract slug")
+	case errors.Is(err, errLetterboxdMissing):
+		entry := letterboxdCacheEntry{NotFound: true, CheckedAt: time.Now().UTC()}
+		body, _ := json.MarshalIndent(entry, "", "  ")
+		cacheStore(config.Cache, cacheFile, body, entry)
+		return nil, errLetterboxdNotFound(tmdbID, imdbID)
+	case err != nil:
+		return nil, err
 	}
 
 	// Step 2: Get JSON data using the slug
 	config.LetterboxdRateLimiter.Wait()
-	time.Sleep(500 * time.Millisecond)
-	jsonURL := fmt.Sprintf("https://letterboxd.com/film/%s/json/", slug)
+	time.Sleep(config.LetterboxdDelay)
+	jsonURL := letterboxdURL(config, "/film/%s/json/", slug)
 
-	resp, err = RetryWithBackoff(retryConfig, func() (*http.Response, error) {
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
 		req, err := http.NewRequest("GET", jsonURL, nil)
 		if err != nil {
 			return nil, err
 		}
-		setLetterboxdHeaders(req)
+		setLetterboxdHeaders(req, config.LetterboxdUserAgent)
 		return client.Do(req)
 	})
 
 	if err != nil {
 		if config.Verbose {
-			fmt.Printf("\n    - error fetching Letterboxd JSON: %v", err)
+			Debugf("\n    - error fetching Letterboxd JSON: %v", err)
 		}
 		return nil, err
 	}
@@ -380,16 +424,16 @@ func FetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, existin
 	// Handle 403 gracefully - preserve existing data if available
 	if resp.StatusCode == 403 {
 		if config.Verbose {
-			fmt.Printf("\n    - Letterboxd returned 403 (access denied)")
+			Debugf("\n    - Letterboxd returned 403 (access denied)")
 		}
 		if existingData != nil {
 			if config.Verbose {
-				fmt.Printf(", using existing data as fallback")
+				Debugf(", using existing data as fallback")
 			}
 			return existingData, nil
 		}
 		if config.Verbose {
-			fmt.Printf(", skipping")
+			Debugf(", skipping")
 		}
 		return nil, nil
 	}
@@ -411,20 +455,122 @@ func FetchLetterboxdInfo(client *http.Client, config Config, tmdbID int, existin
 	lidPtr := lbResponse.LID
 
 	letterboxdInfo := &Letterboxd{
-		Slug: &slugPtr,
-		UID:  &uidPtr,
-		LID:  &lidPtr,
+		Slug:        &slugPtr,
+		UID:         &uidPtr,
+		LID:         &lidPtr,
+		ResolvedVia: resolvedVia,
 	}
 
-	SaveJSON(cacheFile, letterboxdInfo)
-	time.Sleep(500 * time.Millisecond)
+	entry := letterboxdCacheEntry{Letterboxd: *letterboxdInfo, CheckedAt: time.Now().UTC()}
+	body, _ = json.MarshalIndent(entry, "", "  ")
+	cacheStore(config.Cache, cacheFile, body, entry)
+	time.Sleep(config.LetterboxdDelay)
 
 	return letterboxdInfo, nil
 }
 
-// setLetterboxdHeaders sets comprehensive browser-like headers for Letterboxd requests
-func setLetterboxdHeaders(req *http.Request) {
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+// Failures of resolveLetterboxdSlug that fetchLetterboxdInfo handles specially
+var (
+	errLetterboxdDenied  = errors.New("\n    - Letterboxd returned 403 (access denied)")
+	errLetterboxdBlocked = errors.New("\n    - Letterboxd blocked by Cloudflare - cannot fetch via HTTP")
+	errLetterboxdMissing = errors.New("\n    - Film not found on Letterboxd")
+)
+
+// resolveLetterboxdSlug follows a letterboxd.com/{tmdb|imdb}/{id}/ redirect
+// and returns the film slug it points to
+func resolveLetterboxdSlug(config Config, redirectURL string) (string, error) {
+	noRedirectClient := NewHTTPClient(15 * time.Second)
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	config.LetterboxdRateLimiter.Wait()
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+		req, err := http.NewRequest("GET", redirectURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		setLetterboxdHeaders(req, config.LetterboxdUserAgent)
+		return noRedirectClient.Do(req)
+	})
+
+	if err != nil {
+		if config.Verbose {
+			Debugf("\n    - error fetching Letterboxd redirect: %v", err)
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 403 {
+		if config.Verbose {
+			Debugf("\n    - Letterboxd returned 403 (access denied)")
+		}
+		return "", errLetterboxdDenied
+	}
+
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location, err := resp.Location()
+		if err != nil {
+			return "", err
+		}
+		pathParts := strings.Split(strings.Trim(location.Path, "/"), "/")
+		if len(pathParts) < 2 || pathParts[0] != "film" || pathParts[1] == "" {
+			return "", fmt.Errorf("\n    - could not parse slug from redirect location: %s", location.Path)
+		}
+		if config.Verbose {
+			Debugf("\n    - successfully extracted slug from redirect: %s", pathParts[1])
+		}
+		return pathParts[1], nil
+	}
+
+	if resp.StatusCode != 200 {
+		if config.Verbose {
+			Debugf("\n    - Letterboxd redirect failed with status %d (expected 300-399)", resp.StatusCode)
+		}
+		return "", fmt.Errorf("\n    - expected redirect, but got status %d", resp.StatusCode)
+	}
+
+	// 200 OK might indicate Cloudflare challenge page or we've been served the page directly
+	// Check if response body contains Cloudflare challenge
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	bodyBytes = decompressGzipIfNeeded(bodyBytes, resp)
+	bodyStr := string(bodyBytes)
+
+	if strings.Contains(bodyStr, "Just a moment...") || strings.Contains(bodyStr, "Attention Required!") {
+		if config.Verbose {
+			Debugf("\n    - Letterboxd blocked by Cloudflare challenge (unable to bypass via HTTP)")
+		}
+		return "", errLetterboxdBlocked
+	}
+
+	if strings.Contains(bodyStr, "Film not found") || strings.Contains(bodyStr, "film-not-found") || strings.Contains(bodyStr, "not-found") || strings.Contains(bodyStr, "TMDB Import Result") {
+		if config.Verbose {
+			Debugf("\n    - Film not found on Letterboxd")
+		}
+		return "", errLetterboxdMissing
+	}
+
+	// Create a preview of the response for debugging
+	preview := bodyStr
+	if len(preview) > 200 {
+		preview = preview[:200]
+	}
+	if config.Verbose {
+		Debugf("\n    - Letterboxd returned 200 OK instead of redirect")
+		Debugf("\n      Body preview: %s...", preview)
+	}
+	return "", fmt.Errorf("\n    - expected redirect, but got 200 OK: %s...", preview)
+}
+
+// setLetterboxdHeaders sets comprehensive browser-like headers for Letterboxd
+// requests. The Chrome client hints are only sent with the default User-Agent,
+// which they describe.
+func setLetterboxdHeaders(req *http.Request, userAgent string) {
+	if userAgent == "" {
+		userAgent = DefaultLetterboxdUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8")
 	req.Header.Set("Accept-Encoding", "gzip")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
@@ -432,9 +578,11 @@ func setLetterboxdHeaders(req *http.Request) {
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("DNT", "1")
 	req.Header.Set("Referer", "https://letterboxd.com/")
-	req.Header.Set("Sec-Ch-Ua", `"Not A(Brand";v="99", "Google Chrome";v="120", "Chromium";v="120"`)
-	req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
-	req.Header.Set("Sec-Ch-Ua-Platform", `"Windows"`)
+	if userAgent == DefaultLetterboxdUserAgent {
+		req.Header.Set("Sec-Ch-Ua", `"Not A(Brand";v="99", "Google Chrome";v="120", "Chromium";v="120"`)
+		req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
+		req.Header.Set("Sec-Ch-Ua-Platform", `"Windows"`)
+	}
 	req.Header.Set("Sec-Fetch-Dest", "document")
 	req.Header.Set("Sec-Fetch-Mode", "navigate")
 	req.Header.Set("Sec-Fetch-Site", "none")
@@ -450,21 +598,27 @@ func setLetterboxdHeaders(req *http.Request) {
 //
 // Results are cached under config.TempDir/search/<idType>_<mediaType>_<id>.json.
 func FetchTraktByExternalID(client *http.Client, config Config, idType, id, mediaType string) ([]TraktSearchResult, error) {
+	return singleFetch(filepath.Join(config.TempDir, "search", fmt.Sprintf("%s_%s_%s.json", idType, mediaType, id)), func() ([]TraktSearchResult, error) {
+		return fetchTraktByExternalID(client, config, idType, id, mediaType)
+	})
+}
+
+// fetchTraktByExternalID does the work of FetchTraktByExternalID
+func fetchTraktByExternalID(client *http.Client, config Config, idType, id, mediaType string) ([]TraktSearchResult, error) {
 	cacheFile := filepath.Join(config.TempDir, "search",
 		fmt.Sprintf("%s_%s_%s.json", idType, mediaType, id))
 
-	if data, err := os.ReadFile(cacheFile); err == nil && !config.Force {
-		var results []TraktSearchResult
-		if json.Unmarshal(data, &results) == nil {
+	if useCache(config, cacheFile) {
+		if results, ok := cacheLoad[[]TraktSearchResult](config.Cache, cacheFile); ok {
 			if config.Verbose {
-				fmt.Printf("\n    - using cached Trakt search (%s %s ID %s)", idType, mediaType, id)
+				Debugf("\n    - using cached Trakt search (%s %s ID %s)", idType, mediaType, id)
 			}
 			return results, nil
 		}
 	}
 
 	if config.Verbose {
-		fmt.Printf("\n    - searching Trakt by %s %s ID %s", idType, mediaType, id)
+		Debugf("\n    - searching Trakt by %s %s ID %s", idType, mediaType, id)
 	}
 
 	config.RateLimiter.Wait()
@@ -472,7 +626,7 @@ func FetchTraktByExternalID(client *http.Client, config Config, idType, id, medi
 
 	retryConfig := DefaultRetryConfig()
 	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
-		url := fmt.Sprintf("https://api.trakt.tv/search/%s/%s?type=%s", idType, id, mediaType)
+		url := traktURL(config, "/search/%s/%s?type=%s&extended=full,images", idType, id, mediaType)
 		req, err := http.NewRequest("GET", url, nil)
 		if err != nil {
 			return nil, err
@@ -491,7 +645,7 @@ func FetchTraktByExternalID(client *http.Client, config Config, idType, id, medi
 		return nil, fmt.Errorf("%s %s %s not found on Trakt: 404", idType, mediaType, id)
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("%s search API error: %d", idType, resp.StatusCode)
+		return nil, statusError(resp, fmt.Errorf("%s search API error: %d", idType, resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -510,7 +664,57 @@ func FetchTraktByExternalID(client *http.Client, config Config, idType, id, medi
 
 	// Cache the result
 	os.MkdirAll(filepath.Dir(cacheFile), 0755)
-	os.WriteFile(cacheFile, body, 0644)
+	cacheStore(config.Cache, cacheFile, body, results)
+
+	return results, nil
+}
+
+// SearchTrakt searches Trakt by title for shows or movies (mediaType "show"
+// or "movie"). Results are cached under
+// config.TempDir/search/query_<mediaType>_<hash>.json.
+func SearchTrakt(client *http.Client, config Config, query, mediaType string) ([]TraktSearchResult, error) {
+	sum := sha256.Sum256([]byte(strings.ToLower(query)))
+	cacheFile := filepath.Join(config.TempDir, "search",
+		fmt.Sprintf("query_%s_%x.json", mediaType, sum[:8]))
+
+	if results, ok := cacheLoad[[]TraktSearchResult](config.Cache, cacheFile); ok {
+		return results, nil
+	}
+
+	config.RateLimiter.Wait()
+	retryConfig := DefaultRetryConfig()
+	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
+		url := traktURL(config, "/search/%s?query=%s&fields=title,aliases&limit=10", mediaType, neturl.QueryEscape(query))
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("trakt-api-version", "2")
+		req.Header.Set("trakt-api-key", config.APIKey)
+		return client.Do(req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, statusError(resp, fmt.Errorf("title search API error: %d", resp.StatusCode))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TraktSearchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		return nil, err
+	}
+
+	os.MkdirAll(filepath.Dir(cacheFile), 0755)
+	cacheStore(config.Cache, cacheFile, body, results)
 
 	return results, nil
 }
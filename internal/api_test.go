@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal/trakttest"
+)
+
+// roundTripFunc stubs an http.Client's transport
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestFetchShowOrSlug(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "shows"), 0755)
+	os.WriteFile(filepath.Join(dir, "shows", "slug_cowboy-bebop.json"), []byte(`{"title": "Cowboy Bebop", "ids": {"trakt": 30857, "slug": "cowboy-bebop"}}`), 0644)
+
+	requests := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{StatusCode: 404, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}}, nil
+	})}
+	config := Config{TempDir: dir, Cache: NewCache(0), RateLimiter: NewRateLimiter()}
+
+	show, bySlug, err := fetchShowOrSlug(client, config, InputShow{MalID: 1, TraktID: 1, GuessedSlug: "cowboy-bebop"})
+	if err != nil || !bySlug || show.IDs.Trakt != 30857 {
+		t.Fatalf("fetchShowOrSlug = %+v, %v, %v; want Trakt ID 30857 by slug", show, bySlug, err)
+	}
+	if requests != 1 {
+		t.Errorf("%d requests, want only the 404 ID lookup", requests)
+	}
+
+	if _, bySlug, err = fetchShowOrSlug(client, config, InputShow{MalID: 2, TraktID: 2}); bySlug || err == nil {
+		t.Errorf("without a guessed slug: bySlug = %v, err = %v; want the 404", bySlug, err)
+	}
+}
+
+func TestFetchTraktSeasonShared(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "seasons"), 0755)
+
+	requests := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		body := `[{"number": 1, "episode_count": 12}, {"number": 2, "episode_count": 13}]`
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	// -force skips the cache, but not what the run itself fetched
+	config := Config{TempDir: dir, Cache: NewCache(0), RateLimiter: NewRateLimiter(), Force: true}
+
+	for _, number := range []int{1, 2, 1} {
+		season, err := FetchTraktSeason(client, config, 30857, number)
+		if err != nil || season.Number != number {
+			t.Fatalf("season %d = %+v, %v", number, season, err)
+		}
+	}
+	if _, err := FetchTraktSeason(client, config, 30857, 5); err == nil {
+		t.Error("missing season 5 was found")
+	}
+	if requests != 1 {
+		t.Errorf("%d requests, want one for the season list", requests)
+	}
+}
+
+// traktTestConfig is a run's config against a trakttest server
+func traktTestConfig(t *testing.T, server *trakttest.Server) Config {
+	dir := t.TempDir()
+	for _, sub := range []string{"shows", "movies", "seasons"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+	}
+	return Config{
+		APIKey:      "key",
+		TraktAPIURL: server.URL,
+		TempDir:     dir,
+		Cache:       NewCache(0),
+		RateLimiter: NewRateLimiter(),
+		Budget:      &Budget{},
+	}
+}
+
+func TestFetchTraktShowRetriesRateLimits(t *testing.T) {
+	server := trakttest.NewServer(t)
+	server.Show(30857, "cowboy-bebop", map[string]any{"title": "Cowboy Bebop", "year": 1998, "ids": map[string]any{"trakt": 30857, "slug": "cowboy-bebop"}})
+	client := NewHTTPClient(5 * time.Second)
+	config := traktTestConfig(t, server)
+
+	server.RateLimit(2, 0)
+	show, err := FetchTraktShow(client, config, 30857)
+	if err != nil || show.IDs.Slug != "cowboy-bebop" {
+		t.Fatalf("FetchTraktShow = %+v, %v; want Cowboy Bebop after two 429s", show, err)
+	}
+	if n := server.Count("/shows/30857"); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+
+	if _, err := FetchTraktShow(client, config, 1); !errors.Is(err, errTraktNotFound) {
+		t.Errorf("unknown show: err = %v, want a 404", err)
+	}
+
+	server.RateLimit(DefaultRetryConfig().MaxRetries+1, 0)
+	if _, err := FetchTraktMovie(client, config, 47); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("rate limited past the retries: err = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestInvalidateUpdatedCache(t *testing.T) {
+	server := trakttest.NewServer(t)
+	server.Updates("shows", []map[string]any{
+		{"updated_at": "2026-02-01T00:00:00.000Z", "show": map[string]any{"ids": map[string]any{"trakt": 10, "slug": "new-slug"}}},
+	})
+	config := traktTestConfig(t, server)
+	write := func(path, body string) {
+		os.WriteFile(filepath.Join(config.TempDir, path), []byte(body), 0644)
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(config.TempDir, path))
+		return err == nil
+	}
+
+	stale := `{"title":"x","ids":{"trakt":10,"slug":"old-slug"},"updated_at":"2026-01-01T00:00:00.000Z"}`
+	write("shows/10.json", stale)
+	write("shows/slug_old-slug.json", stale)
+	write("shows/slug_new-slug.json", stale)
+	write("seasons/10.json", `[]`)
+	write("shows/11.json", `{"title":"y","ids":{"trakt":11},"updated_at":"2026-01-01T00:00:00.000Z"}`)
+	SaveJSON(filepath.Join(config.TempDir, CacheUpdatesFile), CacheUpdatesState{ShowsCheckedAt: time.Now().Add(-24 * time.Hour)})
+
+	InvalidateUpdatedCache(config)
+	for _, path := range []string{"shows/10.json", "shows/slug_old-slug.json", "shows/slug_new-slug.json", "seasons/10.json"} {
+		if exists(path) {
+			t.Errorf("%s survived the update", path)
+		}
+	}
+	if !exists("shows/11.json") {
+		t.Error("show Trakt did not update was dropped")
+	}
+
+	// Past the feed's window nothing can be trusted but what `cache warm` rebuilds
+	SaveJSON(filepath.Join(config.TempDir, CacheUpdatesFile), CacheUpdatesState{ShowsCheckedAt: time.Now().AddDate(0, -2, 0)})
+	write("shows/12.json", `{"title":"z","ids":{"trakt":12},"warmed":true}`)
+	write("seasons/12.json", `[]`)
+	write("seasons/13.json", `[]`)
+	InvalidateUpdatedCache(config)
+	if exists("shows/11.json") || exists("seasons/13.json") {
+		t.Error("cache not dropped after the updates window passed")
+	}
+	if !exists("shows/12.json") || !exists("seasons/12.json") {
+		t.Error("warmed show or its seasons dropped")
+	}
+	var state CacheUpdatesState
+	LoadJSON(filepath.Join(config.TempDir, CacheUpdatesFile), &state)
+	if time.Since(state.ShowsCheckedAt) > time.Minute {
+		t.Errorf("shows checked at %v, want now", state.ShowsCheckedAt)
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Bucket names used in the bbolt export. Forward buckets map a MAL ID to the
+// full entry JSON; reverse buckets map an external ID to a JSON array of MAL IDs.
+const (
+	boltBucketMeta        = "meta"
+	boltBucketShows       = "mal_shows"
+	boltBucketMovies      = "mal_movies"
+	boltBucketTraktShows  = "trakt_shows"
+	boltBucketTraktMovies = "trakt_movies"
+	boltBucketTMDBShows   = "tmdb_shows"
+	boltBucketTMDBMovies  = "tmdb_movies"
+	boltBucketTVDB        = "tvdb"
+	boltBucketIMDB        = "imdb"
+	boltSchemaVersion     = "1"
+)
+
+// defaultBoltPath is where export-bolt writes, and where bundle picks the
+// database up
+const defaultBoltPath = "dist/anitrakt.db"
+
+// RunExportBolt implements the `export-bolt` subcommand
+func RunExportBolt(args []string) error {
+	fs := flag.NewFlagSet("export-bolt", flag.ExitOnError)
+	output := fs.String("output", defaultBoltPath, "Path of the bbolt database to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	fs.Parse(args)
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		return err
+	}
+
+	// Build into a temp file and rename so readers never see a partial database
+	tmpPath := *output + ".tmp"
+	os.Remove(tmpPath)
+	if err := writeBoltDB(tmpPath, shows, movies); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, *output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d shows and %d movies to %s\n", len(shows), len(movies), *output)
+	return nil
+}
+
+// writeBoltDB writes the dataset and its reverse indexes into a new bbolt file
+func writeBoltDB(path string, shows []OutputShow, movies []OutputMovie) error {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer db.Close()
+
+	reverse := map[string]map[string][]int{
+		boltBucketTraktShows:  {},
+		boltBucketTraktMovies: {},
+		boltBucketTMDBShows:   {},
+		boltBucketTMDBMovies:  {},
+		boltBucketTVDB:        {},
+		boltBucketIMDB:        {},
+	}
+	addReverse := func(bucket, key string, malID int) {
+		reverse[bucket][key] = append(reverse[bucket][key], malID)
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		showBucket, err := tx.CreateBucket([]byte(boltBucketShows))
+		if err != nil {
+			return err
+		}
+		for _, show := range shows {
+			doc, err := json.Marshal(show)
+			if err != nil {
+				return err
+			}
+			malID := show.MyAnimeList.ID
+			if err := showBucket.Put([]byte(strconv.Itoa(malID)), doc); err != nil {
+				return err
+			}
+			addReverse(boltBucketTraktShows, strconv.Itoa(show.Trakt.ID), malID)
+			if show.Externals != nil {
+				if show.Externals.TMDB != nil {
+					addReverse(boltBucketTMDBShows, strconv.Itoa(*show.Externals.TMDB), malID)
+				}
+				if show.Externals.TVDB != nil {
+					addReverse(boltBucketTVDB, strconv.Itoa(*show.Externals.TVDB), malID)
+				}
+				if show.Externals.IMDB != nil && *show.Externals.IMDB != "" {
+					addReverse(boltBucketIMDB, *show.Externals.IMDB, malID)
+				}
+			}
+		}
+
+		movieBucket, err := tx.CreateBucket([]byte(boltBucketMovies))
+		if err != nil {
+			return err
+		}
+		for _, movie := range movies {
+			doc, err := json.Marshal(movie)
+			if err != nil {
+				return err
+			}
+			malID := movie.MyAnimeList.ID
+			if err := movieBucket.Put([]byte(strconv.Itoa(malID)), doc); err != nil {
+				return err
+			}
+			addReverse(boltBucketTraktMovies, strconv.Itoa(movie.Trakt.ID), malID)
+			if movie.Externals != nil {
+				if movie.Externals.TMDB != nil {
+					addReverse(boltBucketTMDBMovies, strconv.Itoa(*movie.Externals.TMDB), malID)
+				}
+				if movie.Externals.IMDB != nil && *movie.Externals.IMDB != "" {
+					addReverse(boltBucketIMDB, *movie.Externals.IMDB, malID)
+				}
+			}
+		}
+
+		for name, index := range reverse {
+			bucket, err := tx.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+			for key, malIDs := range index {
+				sort.Ints(malIDs)
+				value, _ := json.Marshal(malIDs)
+				if err := bucket.Put([]byte(key), value); err != nil {
+					return err
+				}
+			}
+		}
+
+		meta, err := tx.CreateBucket([]byte(boltBucketMeta))
+		if err != nil {
+			return err
+		}
+		metaValues := map[string]string{
+			"schema_version": boltSchemaVersion,
+			"generated_at":   time.Now().UTC().Format(time.RFC3339),
+			"shows":          strconv.Itoa(len(shows)),
+			"movies":         strconv.Itoa(len(movies)),
+		}
+		for key, value := range metaValues {
+			if err := meta.Put([]byte(key), []byte(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestRunExportBolt(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	// A second cut of Ghost in the Shell shares the TMDB and IMDB IDs of MAL 43
+	var recut OutputMovie
+	if err := json.Unmarshal([]byte(`{"myanimelist":{"title":"Koukaku Kidoutai 2.0","id":4672},"trakt":{"title":"Ghost in the Shell 2.0","id":2,"slug":"ghost-in-the-shell-2-0","type":"movies"},"release_year":2008,"externals":{"tmdb":9323,"imdb":"tt0113568"}}`), &recut); err != nil {
+		t.Fatal(err)
+	}
+	SaveJSON("tv_ex.json", data.ShowList())
+	SaveJSON("movies_ex.json", append(data.MovieList(), recut))
+
+	output := filepath.Join("dist", "anitrakt.db")
+	if err := RunExportBolt([]string{"-output", output, "-tv", "tv_ex.json", "-movies", "movies_ex.json"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(output + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temporary database left behind")
+	}
+
+	db, err := bolt.Open(output, 0644, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		get := func(bucket, key string) []byte {
+			t.Helper()
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				t.Fatalf("bucket %s missing", bucket)
+			}
+			return b.Get([]byte(key))
+		}
+
+		var show OutputShow
+		if err := json.Unmarshal(get(boltBucketShows, "1"), &show); err != nil || show.Trakt.Slug != "cowboy-bebop" {
+			t.Errorf("mal_shows/1 = %+v, %v; want Cowboy Bebop", show, err)
+		}
+		var movie OutputMovie
+		if err := json.Unmarshal(get(boltBucketMovies, "4672"), &movie); err != nil || movie.Trakt.ID != 2 {
+			t.Errorf("mal_movies/4672 = %+v, %v; want Ghost in the Shell 2.0", movie, err)
+		}
+		if got := get(boltBucketMovies, "1"); got != nil {
+			t.Errorf("mal_movies/1 = %s, want shows kept out of the movie bucket", got)
+		}
+
+		reverse := []struct {
+			bucket, key string
+			want        []int
+		}{
+			{boltBucketTraktShows, "30857", []int{1, 5}},
+			{boltBucketTraktMovies, "2", []int{4672}},
+			{boltBucketTMDBShows, "30991", []int{1}},
+			{boltBucketTMDBMovies, "9323", []int{43, 4672}},
+			{boltBucketTVDB, "76885", []int{1}},
+			{boltBucketIMDB, "tt0113568", []int{43, 4672}},
+			{boltBucketIMDB, "tt0213338", []int{1}},
+		}
+		for _, tt := range reverse {
+			var got []int
+			if err := json.Unmarshal(get(tt.bucket, tt.key), &got); err != nil || !slices.Equal(got, tt.want) {
+				t.Errorf("%s/%s = %v, %v; want %v", tt.bucket, tt.key, got, err, tt.want)
+			}
+		}
+
+		if got := string(get(boltBucketMeta, "schema_version")); got != boltSchemaVersion {
+			t.Errorf("meta/schema_version = %q, want %q", got, boltSchemaVersion)
+		}
+		if shows, movies := string(get(boltBucketMeta, "shows")), string(get(boltBucketMeta, "movies")); shows != "2" || movies != "2" {
+			t.Errorf("meta counts = %s shows, %s movies; want 2 and 2", shows, movies)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Budget caps the work of a run (-max-new, -max-api-calls, -max-host-calls).
+// Once it is spent the pipelines stop taking entries and save what they
+// have; the rest is picked up by the next run. A nil Budget never runs out.
+//
+// Requests are counted per host by the shared transport, so Trakt, the
+// enrichments, Letterboxd and any later integration on NewHTTPClient share
+// one count.
+type Budget struct {
+	MaxNew       int            // new entries across all pipelines (0 = unlimited)
+	MaxAPICalls  int            // outbound requests to all hosts (0 = unlimited)
+	MaxHostCalls map[string]int // outbound requests to one host, e.g. api.trakt.tv
+
+	mu       sync.Mutex
+	created  int
+	requests map[string]int // by host
+	reason   string
+}
+
+// activeBudget is the budget the shared transport counts requests into. It
+// is package-level for the same reason as resources: the transport is.
+var activeBudget atomic.Pointer[Budget]
+
+// ForRun returns an unspent copy of the budget for a new run (in -daemon
+// mode) and counts the requests from now on into it
+func (b *Budget) ForRun() *Budget {
+	if b == nil {
+		activeBudget.Store(nil)
+		return nil
+	}
+	run := &Budget{MaxNew: b.MaxNew, MaxAPICalls: b.MaxAPICalls, MaxHostCalls: b.MaxHostCalls}
+	activeBudget.Store(run)
+	return run
+}
+
+// AddNew counts an entry created in this run
+func (b *Budget) AddNew() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.created++
+}
+
+// addRequest counts an outbound request to host
+func (b *Budget) addRequest(host string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.requests == nil {
+		b.requests = make(map[string]int)
+	}
+	b.requests[host]++
+}
+
+// Requests returns the outbound requests of the run so far, by host
+func (b *Budget) Requests() map[string]int {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	requests := make(map[string]int, len(b.requests))
+	for host, n := range b.requests {
+		requests[host] = n
+	}
+	return requests
+}
+
+// Remaining returns the requests left under -max-api-calls, or -1 when it
+// is unlimited
+func (b *Budget) Remaining() int {
+	if b == nil || b.MaxAPICalls == 0 {
+		return -1
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(b.MaxAPICalls-b.total(), 0)
+}
+
+// total sums the requests to all hosts; callers hold b.mu
+func (b *Budget) total() int {
+	total := 0
+	for _, n := range b.requests {
+		total += n
+	}
+	return total
+}
+
+// Exhausted reports whether the budget is spent, logging the first time it is.
+// It is checked between entries, so a run may go over a request limit by the
+// requests of one entry.
+func (b *Budget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.reason != "" {
+		return true
+	}
+	switch {
+	case b.MaxNew > 0 && b.created >= b.MaxNew:
+		b.reason = fmt.Sprintf("-max-new %d reached", b.MaxNew)
+	case b.MaxAPICalls > 0 && b.total() >= b.MaxAPICalls:
+		b.reason = fmt.Sprintf("-max-api-calls %d reached", b.MaxAPICalls)
+	default:
+		for _, host := range sortedKeys(b.MaxHostCalls) {
+			if limit := b.MaxHostCalls[host]; limit > 0 && b.requests[host] >= limit {
+				b.reason = fmt.Sprintf("-max-host-calls %s=%d reached", host, limit)
+				break
+			}
+		}
+		if b.reason == "" {
+			return false
+		}
+	}
+	log.Printf("Budget spent (%s): stopping and saving partial results", b.reason)
+	return true
+}
+
+// Reason describes why the budget ran out, or is empty while it lasts
+func (b *Budget) Reason() string {
+	if b == nil {
+		return ""
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reason
+}
+
+// hostLimits is a flag.Value collecting repeated -max-host-calls host=N
+type hostLimits map[string]int
+
+func (h hostLimits) String() string {
+	var limits []string
+	for _, host := range sortedKeys(h) {
+		limits = append(limits, fmt.Sprintf("%s=%d", host, h[host]))
+	}
+	return strings.Join(limits, ",")
+}
+
+func (h hostLimits) Set(value string) error {
+	host, n, ok := strings.Cut(value, "=")
+	limit, err := strconv.Atoi(n)
+	if !ok || host == "" || err != nil || limit < 0 {
+		return fmt.Errorf("want host=N, e.g. api.trakt.tv=1000, got %q", value)
+	}
+	h[host] = limit
+	return nil
+}
+
+// FormatRequests renders requests by host, busiest first, e.g.
+// "api.trakt.tv 120, letterboxd.com 30 (150 in total)"
+func FormatRequests(requests map[string]int) string {
+	hosts := sortedKeys(requests)
+	slices.SortStableFunc(hosts, func(a, b string) int { return requests[b] - requests[a] })
+	parts := make([]string, len(hosts))
+	total := 0
+	for i, host := range hosts {
+		parts[i] = fmt.Sprintf("%s %d", host, requests[host])
+		total += requests[host]
+	}
+	return fmt.Sprintf("%s (%d in total)", strings.Join(parts, ", "), total)
+}
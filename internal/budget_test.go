@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestBudgetExhausted(t *testing.T) {
+	var unlimited *Budget
+	unlimited.AddNew()
+	unlimited.addRequest("api.trakt.tv")
+	if unlimited.Exhausted() || unlimited.Remaining() != -1 {
+		t.Error("nil budget ran out")
+	}
+
+	budget := &Budget{MaxNew: 2, MaxAPICalls: 3}
+	budget.AddNew()
+	budget.addRequest("api.trakt.tv")
+	if budget.Exhausted() {
+		t.Fatal("budget ran out early")
+	}
+	budget.addRequest("api.trakt.tv")
+	budget.addRequest("letterboxd.com")
+	if !budget.Exhausted() || budget.Reason() != "-max-api-calls 3 reached" {
+		t.Errorf("after 3 requests: reason %q", budget.Reason())
+	}
+	if budget.Remaining() != 0 {
+		t.Errorf("remaining = %d, want 0", budget.Remaining())
+	}
+	budget.AddNew()
+	if budget.Reason() != "-max-api-calls 3 reached" {
+		t.Errorf("reason changed to %q", budget.Reason())
+	}
+}
+
+func TestBudgetHostLimit(t *testing.T) {
+	budget := &Budget{MaxHostCalls: map[string]int{"letterboxd.com": 2}}
+	budget.addRequest("api.trakt.tv")
+	budget.addRequest("api.trakt.tv")
+	budget.addRequest("letterboxd.com")
+	if budget.Exhausted() {
+		t.Fatal("budget ran out on another host's requests")
+	}
+	budget.addRequest("letterboxd.com")
+	if !budget.Exhausted() || budget.Reason() != "-max-host-calls letterboxd.com=2 reached" {
+		t.Errorf("reason %q", budget.Reason())
+	}
+	if got := FormatRequests(budget.Requests()); got != "api.trakt.tv 2, letterboxd.com 2 (4 in total)" {
+		t.Errorf("FormatRequests = %q", got)
+	}
+}
+
+func TestBudgetForRun(t *testing.T) {
+	spent := &Budget{MaxAPICalls: 1}
+	spent.addRequest("api.trakt.tv")
+	if !spent.Exhausted() {
+		t.Fatal("budget not spent after one request")
+	}
+	next := spent.ForRun()
+	t.Cleanup(func() { activeBudget.Store(nil) })
+	if next.Exhausted() {
+		t.Error("next run starts with a spent budget")
+	}
+	activeBudget.Load().addRequest("api.trakt.tv")
+	if got := next.Requests()["api.trakt.tv"]; got != 1 {
+		t.Errorf("active budget counted %d requests, want 1", got)
+	}
+}
+
+func TestHostLimitsFlag(t *testing.T) {
+	limits := map[string]int{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(hostLimits(limits), "max-host-calls", "")
+	if err := fs.Parse([]string{"-max-host-calls", "api.trakt.tv=1000", "-max-host-calls", "letterboxd.com=50"}); err != nil {
+		t.Fatal(err)
+	}
+	if limits["api.trakt.tv"] != 1000 || limits["letterboxd.com"] != 50 {
+		t.Errorf("limits = %v", limits)
+	}
+	for _, bad := range []string{"api.trakt.tv", "=5", "api.trakt.tv=x", "api.trakt.tv=-1"} {
+		if err := hostLimits(limits).Set(bad); err == nil {
+			t.Errorf("Set(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunBundle implements the `bundle` subcommand: it packs the dataset files,
+// the manifest, any signatures, the bbolt database with the reverse indexes,
+// the SQLite export and extra artifacts into a versioned tar.gz
+func RunBundle(args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	version := fs.String("version", time.Now().UTC().Format("20060102"), "Bundle version (defaults to the current UTC date)")
+	outDir := fs.String("out", "dist", "Directory to write the bundle to")
+	manifestPath := fs.String("manifest", ManifestPath(), "Path to the dataset manifest")
+	var includes stringList
+	fs.Var(&includes, "include", "Extra file or glob to include (repeatable)")
+	fs.Parse(args)
+
+	files, err := bundleFiles(*manifestPath, includes)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to bundle")
+	}
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("anitrakt-%s", *version)
+	bundlePath := filepath.Join(*outDir, name+".tar.gz")
+
+	if err := writeTarGz(bundlePath, name, files); err != nil {
+		return fmt.Errorf("write %s: %w", bundlePath, err)
+	}
+
+	sum, err := sha256File(bundlePath)
+	if err != nil {
+		return err
+	}
+	checksum := fmt.Sprintf("%s  %s\n", sum, filepath.Base(bundlePath))
+	if err := os.WriteFile(bundlePath+".sha256", []byte(checksum), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Bundled %d files into %s\n", len(files), bundlePath)
+	return nil
+}
+
+// bundleFiles resolves the list of files that go into a bundle
+func bundleFiles(manifestPath string, includes []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+
+	datasetFiles := DatasetFiles()
+	if _, err := os.Stat(manifestPath); err == nil {
+		var manifest Manifest
+		if err := LoadJSON(manifestPath, &manifest); err != nil {
+			return nil, err
+		}
+		datasetFiles = datasetFiles[:0]
+		for _, file := range manifest.Files {
+			datasetFiles = append(datasetFiles, file.Path)
+		}
+		add(manifestPath)
+		add(manifestPath + signatureSuffix)
+	}
+
+	for _, path := range datasetFiles {
+		add(path)
+		add(path + signatureSuffix)
+	}
+
+	// The exports at their default paths, when they were built
+	add(defaultBoltPath)
+	add(defaultSQLitePath)
+
+	for _, pattern := range includes {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad include pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			fmt.Printf("Warning: include %q matched no files\n", pattern)
+		}
+		for _, path := range matches {
+			add(path)
+		}
+	}
+	return files, nil
+}
+
+// writeTarGz writes files into a gzip-compressed tarball under prefix/
+func writeTarGz(path, prefix string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, file))
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// sha256File returns the hex SHA-256 digest of a file
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestBundleFilesIncludesExports(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("json/output", 0755)
+	os.MkdirAll("dist", 0755)
+	SaveJSON(defaultOutput("tv"), testDataset(t).ShowList())
+	os.WriteFile(defaultBoltPath, []byte("bolt"), 0644)
+	os.WriteFile(defaultSQLitePath, []byte("sqlite"), 0644)
+
+	files, err := bundleFiles(ManifestPath(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{defaultOutput("tv"), defaultBoltPath, defaultSQLitePath} {
+		if !slices.Contains(files, want) {
+			t.Errorf("bundle files = %v, want %s included", files, want)
+		}
+	}
+
+	os.Remove(defaultSQLitePath)
+	if files, _ := bundleFiles(ManifestPath(), nil); slices.Contains(files, defaultSQLitePath) {
+		t.Error("missing SQLite export bundled")
+	}
+}
@@ -0,0 +1,481 @@
+package internal
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is the in-memory tier in front of the on-disk API cache under
+// config.TempDir. It keeps the decoded value of the most recently used cache
+// files, keyed by path, so repeated lookups (e.g. the seasons of a show with
+// many MAL entries) skip both the file read and the JSON decode. It also
+// counts hits and misses per endpoint. It is safe for concurrent use; a nil
+// *Cache falls through to the disk tier.
+type Cache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front = most recently used
+	items      map[string]*list.Element
+	counters   map[string]*CacheCounter
+	fetched    map[string]bool // cache files written by this run
+}
+
+// CacheCounter counts lookups of one endpoint (shows, movies, seasons,
+// letterboxd, search, tmdb, tvdb or popularity). Every hit is an API request that was not made.
+type CacheCounter struct {
+	MemoryHits int64 `json:"memory_hits"`
+	DiskHits   int64 `json:"disk_hits"`
+	Misses     int64 `json:"misses"`
+}
+
+// cacheItem is the value stored in the LRU list
+type cacheItem struct {
+	path  string
+	value interface{}
+}
+
+// NewCache returns an LRU holding up to maxEntries decoded cache files.
+// maxEntries <= 0 disables the in-memory tier but still counts lookups.
+func NewCache(maxEntries int) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+		counters:   make(map[string]*CacheCounter),
+		fetched:    make(map[string]bool),
+	}
+}
+
+// get returns the in-memory value for path
+func (c *Cache) get(path string) (interface{}, bool) {
+	if c == nil || c.maxEntries <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[path]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheItem).value, true
+}
+
+// add stores value for path, evicting the least recently used entry when full
+func (c *Cache) add(path string, value interface{}) {
+	if c == nil || c.maxEntries <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[path]; ok {
+		elem.Value.(*cacheItem).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[path] = c.order.PushFront(&cacheItem{path: path, value: value})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).path)
+	}
+}
+
+// markFetched notes that path holds a response fetched by this run
+func (c *Cache) markFetched(path string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetched[path] = true
+}
+
+// fetchedThisRun reports whether path holds a response fetched by this run
+func (c *Cache) fetchedThisRun(path string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fetched[path]
+}
+
+// useCache reports whether a lookup may be answered from cacheFile. -force
+// skips the cache, except for responses fetched earlier in the same run, so
+// MAL entries sharing a Trakt show do not fetch it once each.
+func useCache(config Config, cacheFile string) bool {
+	return !config.Force || config.Cache.fetchedThisRun(cacheFile)
+}
+
+// record counts a lookup of path. The endpoint is the cache sub-directory.
+func (c *Cache) record(path string, count func(*CacheCounter)) {
+	if c == nil {
+		return
+	}
+	endpoint := filepath.Base(filepath.Dir(path))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counter, ok := c.counters[endpoint]
+	if !ok {
+		counter = &CacheCounter{}
+		c.counters[endpoint] = counter
+	}
+	count(counter)
+}
+
+// Stats returns a copy of the per-endpoint counters
+func (c *Cache) Stats() map[string]CacheCounter {
+	stats := make(map[string]CacheCounter)
+	if c == nil {
+		return stats
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for endpoint, counter := range c.counters {
+		stats[endpoint] = *counter
+	}
+	return stats
+}
+
+// cacheLoad returns the cached value for path, trying memory first and then
+// the file on disk. Files that fail to decode count as a miss.
+func cacheLoad[T any](c *Cache, path string) (T, bool) {
+	if value, ok := c.get(path); ok {
+		if v, ok := value.(T); ok {
+			c.record(path, func(counter *CacheCounter) { counter.MemoryHits++ })
+			return v, true
+		}
+	}
+
+	var v T
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &v)
+	}
+	if err != nil {
+		c.record(path, func(counter *CacheCounter) { counter.Misses++ })
+		return v, false
+	}
+	c.record(path, func(counter *CacheCounter) { counter.DiskHits++ })
+	// The modification time doubles as the last-used time for disk eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	c.add(path, v)
+	return v, true
+}
+
+// cacheStore writes the raw API response to disk and keeps its decoded value
+// in memory
+func cacheStore[T any](c *Cache, path string, body []byte, v T) {
+	unlock := cacheKeys.lock(path)
+	defer unlock()
+	if err := writeFileAtomic(path, body, 0644); err != nil {
+		log.Printf("Warning: Failed to write cache file %s: %v", path, err)
+	}
+	c.add(path, v)
+	c.markFetched(path)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cacheKeys serialises writers of the same cache file
+var cacheKeys = keyedMutex{locks: make(map[string]*keyedLock)}
+
+// keyedMutex hands out one mutex per key, dropping it once unused
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is a mutex with the number of goroutines holding or awaiting it
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock acquires the mutex of key and returns its unlock function
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// cacheFlights deduplicates identical in-flight API lookups
+var cacheFlights = flightGroup{calls: make(map[string]*flightCall)}
+
+// flightGroup runs one call per key at a time; callers arriving while it is
+// in flight wait for and share its result
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is an in-flight or completed call
+type flightCall struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// do runs fn for key unless a call for key is already in flight
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.val, call.err = fn()
+	call.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return call.val, call.err
+}
+
+// singleFetch is a typed wrapper around cacheFlights, keyed by cache file
+func singleFetch[R any](key string, fetch func() (R, error)) (R, error) {
+	val, err := cacheFlights.do(key, func() (interface{}, error) {
+		return fetch()
+	})
+	result, _ := val.(R)
+	return result, err
+}
+
+// CacheStatsFile is kept in the cache directory so hit rates survive between
+// runs along with the cache they describe
+const CacheStatsFile = "cache_stats.json"
+
+// CacheStats is the content of CacheStatsFile
+type CacheStats struct {
+	UpdatedAt string                  `json:"updated_at"`
+	Runs      int                     `json:"runs"`
+	LastRun   map[string]CacheCounter `json:"last_run"`
+	Total     map[string]CacheCounter `json:"total"`
+}
+
+// SaveCacheStats adds the counters of this run to the stats file in dir
+func SaveCacheStats(dir string, run map[string]CacheCounter) {
+	path := filepath.Join(dir, CacheStatsFile)
+	var stats CacheStats
+	LoadJSONOptional(path, &stats)
+	if stats.Total == nil {
+		stats.Total = make(map[string]CacheCounter)
+	}
+	for endpoint, counter := range run {
+		total := stats.Total[endpoint]
+		total.MemoryHits += counter.MemoryHits
+		total.DiskHits += counter.DiskHits
+		total.Misses += counter.Misses
+		stats.Total[endpoint] = total
+	}
+	stats.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	stats.Runs++
+	stats.LastRun = run
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Warning: Failed to save cache stats: %v", err)
+		return
+	}
+	if err := SaveJSON(path, stats); err != nil {
+		log.Printf("Warning: Failed to save cache stats: %v", err)
+	}
+}
+
+// CacheLimits bounds the disk tier. Zero values mean unlimited.
+type CacheLimits struct {
+	MaxBytes int64
+	MaxFiles int
+}
+
+// cacheFile is a file found in the disk tier
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// listCacheFiles returns every file below dir, least recently used first
+func listCacheFiles(dir string) ([]cacheFile, error) {
+	var files []cacheFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() == CacheStatsFile || d.Name() == CacheUpdatesFile {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, err
+}
+
+// PruneDiskCache evicts the least recently used files below dir until it is
+// within limits, returning how many files and bytes were removed
+func PruneDiskCache(dir string, limits CacheLimits, dryRun bool) (int, int64, error) {
+	if limits.MaxBytes <= 0 && limits.MaxFiles <= 0 {
+		return 0, 0, nil
+	}
+	files, err := listCacheFiles(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	removed, freed := 0, int64(0)
+	for _, f := range files {
+		overSize := limits.MaxBytes > 0 && total-freed > limits.MaxBytes
+		overCount := limits.MaxFiles > 0 && len(files)-removed > limits.MaxFiles
+		if !overSize && !overCount {
+			break
+		}
+		if !dryRun {
+			if err := os.Remove(f.path); err != nil {
+				return removed, freed, err
+			}
+		}
+		removed++
+		freed += f.size
+	}
+	return removed, freed, nil
+}
+
+// CleanDiskCache removes files below dir that were last used before cutoff
+func CleanDiskCache(dir string, cutoff time.Time, dryRun bool) (int, int64, error) {
+	files, err := listCacheFiles(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	removed, freed := 0, int64(0)
+	for _, f := range files {
+		if !f.modTime.Before(cutoff) {
+			break
+		}
+		if !dryRun {
+			if err := os.Remove(f.path); err != nil {
+				return removed, freed, err
+			}
+		}
+		removed++
+		freed += f.size
+	}
+	return removed, freed, nil
+}
+
+// ParseByteSize parses sizes such as "512MB", "2GiB" or "1048576". K, M and G
+// are decimal like KB, MB and GB; KiB, MiB and GiB are binary.
+func ParseByteSize(size string) (int64, error) {
+	s := strings.TrimSpace(strings.ToUpper(size))
+	if s == "" {
+		return 0, nil
+	}
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+		{"G", 1e9}, {"M", 1e6}, {"K", 1e3}, {"B", 1},
+	}
+	factor := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, factor = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(n * float64(factor)), nil
+}
+
+// ParseAge parses a duration that may also be given in days, e.g. "30d"
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatBytes renders a byte count for humans
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheLRU(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(2)
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, string(rune('a'+i))+".json")
+		cacheStore(cache, paths[i], []byte(`[{"number":1}]`), []TraktSeason{{Number: i}})
+	}
+
+	// The first entry was evicted, so it is decoded from disk again
+	if seasons, ok := cacheLoad[[]TraktSeason](cache, paths[0]); !ok || seasons[0].Number != 1 {
+		t.Fatalf("evicted entry = %+v, %v; want the disk copy", seasons, ok)
+	}
+	// The memory tier wins over disk while the entry is resident
+	if seasons, ok := cacheLoad[[]TraktSeason](cache, paths[2]); !ok || seasons[0].Number != 2 {
+		t.Fatalf("resident entry = %+v, %v; want the in-memory value", seasons, ok)
+	}
+	// Reloading paths[0] pushed paths[1] out
+	if _, ok := cache.get(paths[1]); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+
+	os.Remove(paths[1])
+	if _, ok := cacheLoad[[]TraktSeason](cache, paths[1]); ok {
+		t.Error("missing file reported as a hit")
+	}
+	if _, ok := cacheLoad[[]TraktSeason](nil, paths[2]); !ok {
+		t.Error("nil cache did not fall through to disk")
+	}
+
+	endpoint := filepath.Base(dir)
+	want := CacheCounter{MemoryHits: 1, DiskHits: 1, Misses: 1}
+	if got := cache.Stats()[endpoint]; got != want {
+		t.Errorf("Stats()[%q] = %+v, want %+v", endpoint, got, want)
+	}
+}
+
+func TestPruneDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"old.json", "mid.json", "new.json"} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, make([]byte, 100), 0644)
+		used := base.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(path, used, used)
+	}
+
+	removed, freed, err := PruneDiskCache(dir, CacheLimits{MaxBytes: 250}, false)
+	if err != nil || removed != 1 || freed != 100 {
+		t.Fatalf("PruneDiskCache = %d, %d, %v; want 1, 100", removed, freed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Error("least recently used file survived")
+	}
+
+	removed, _, _ = CleanDiskCache(dir, base.Add(90*time.Second), false)
+	if removed != 1 {
+		t.Errorf("CleanDiskCache removed %d files, want 1", removed)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]int64{
+		"": 0, "1024": 1024, "512B": 512,
+		"512MB": 512e6, "512M": 512e6, "1.5k": 1500, "2G": 2e9,
+		"2GiB": 2 << 30, "1.5kib": 1536, "256 MiB": 256 << 20,
+	}
+	for in, want := range cases {
+		if got, err := ParseByteSize(in); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseByteSize("lots"); err == nil {
+		t.Error("ParseByteSize accepted garbage")
+	}
+}
+
+func TestCachedBefore(t *testing.T) {
+	dir := t.TempDir()
+	fresh := filepath.Join(dir, "fresh.json")
+	warmed := filepath.Join(dir, "warmed.json")
+	os.WriteFile(fresh, []byte(`{"title":"x","updated_at":"2025-03-01T10:00:00.000Z"}`), 0644)
+	os.WriteFile(warmed, []byte(`{"title":"x"}`), 0644)
+
+	update := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	if cachedBefore(fresh, update) {
+		t.Error("entry fetched after the update was invalidated")
+	}
+	if !cachedBefore(fresh, update.AddDate(0, 2, 0)) {
+		t.Error("entry fetched before the update was kept")
+	}
+	if !cachedBefore(warmed, update) {
+		t.Error("entry without updated_at was kept")
+	}
+	if cachedBefore(filepath.Join(dir, "missing.json"), update) {
+		t.Error("missing entry reported as stale")
+	}
+}
+
+func TestSingleFetchConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "1.json")
+	cache := NewCache(10)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]*TraktShow, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = singleFetch(path, func() (*TraktShow, error) {
+				calls.Add(1)
+				<-release
+				show := &TraktShow{Title: "Cowboy Bebop"}
+				cacheStore(cache, path, []byte(`{"title":"Cowboy Bebop"}`), *show)
+				return show, nil
+			})
+		}()
+	}
+	// Give every goroutine the chance to join the in-flight call
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetch ran %d times, want 1", n)
+	}
+	for i, show := range results {
+		if show == nil || show.Title != "Cowboy Bebop" {
+			t.Errorf("result %d = %+v", i, show)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("cache dir holds %d files, want only the renamed cache file", len(entries))
+	}
+}
+
+func TestLetterboxdCacheEntryFresh(t *testing.T) {
+	config := Config{LetterboxdTTL: 90 * 24 * time.Hour, LetterboxdNegativeTTL: 14 * 24 * time.Hour}
+	month := time.Now().AddDate(0, -1, 0)
+
+	if !(letterboxdCacheEntry{CheckedAt: month}).fresh(config) {
+		t.Error("month-old hit expired before -letterboxd-ttl")
+	}
+	if (letterboxdCacheEntry{NotFound: true, CheckedAt: month}).fresh(config) {
+		t.Error("month-old miss outlived -letterboxd-negative-ttl")
+	}
+	if !(letterboxdCacheEntry{}).fresh(config) {
+		t.Error("entry without checked_at expired")
+	}
+	config.LetterboxdNegativeTTL = 0
+	if !(letterboxdCacheEntry{NotFound: true, CheckedAt: month}).fresh(config) {
+		t.Error("zero TTL expired an entry")
+	}
+}
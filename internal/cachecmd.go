@@ -0,0 +1,391 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RunCache implements the `cache` subcommand, which manages the persistent
+// API cache under the temp directory
+func RunCache(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: cache ls|clear|clean|warm|stats [flags]")
+	}
+	switch args[0] {
+	case "ls":
+		return runCacheList(args[1:])
+	case "clear":
+		return runCacheClear(args[1:])
+	case "clean":
+		return runCacheClean(args[1:])
+	case "warm":
+		return runCacheWarm(args[1:])
+	case "stats":
+		return runCacheStats(args[1:])
+	default:
+		return fmt.Errorf("unknown cache action %q (expected ls, clear, clean, warm or stats)", args[0])
+	}
+}
+
+// cacheEndpoints are the sub-directories of the cache, one per API endpoint
+var cacheEndpoints = []string{"shows", "movies", "seasons", "letterboxd", "search", "tmdb", "tvdb", "popularity", "wikidata", "jikan", "simkl"}
+
+// runCacheList summarises each endpoint, or lists the files of one
+func runCacheList(args []string) error {
+	fs := flag.NewFlagSet("cache ls", flag.ExitOnError)
+	dir := fs.String("dir", defaultCacheDir(), "Cache directory")
+	endpoint := fs.String("endpoint", "", "List the files of this endpoint instead of a summary")
+	fs.Parse(args)
+
+	if *endpoint != "" {
+		files, err := listCacheFiles(filepath.Join(*dir, *endpoint))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			fmt.Printf("%s  %8s  %s\n", f.modTime.Format("2006-01-02 15:04"), formatBytes(f.size), filepath.Base(f.path))
+		}
+		return nil
+	}
+
+	fmt.Printf("%-12s %8s %10s  %-16s  %s\n", "ENDPOINT", "FILES", "SIZE", "OLDEST", "NEWEST")
+	for _, name := range cacheEndpoints {
+		files, err := listCacheFiles(filepath.Join(*dir, name))
+		if err != nil {
+			return err
+		}
+		var size int64
+		for _, f := range files {
+			size += f.size
+		}
+		oldest, newest := "-", "-"
+		if len(files) > 0 {
+			oldest = files[0].modTime.Format("2006-01-02 15:04")
+			newest = files[len(files)-1].modTime.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-12s %8d %10s  %-16s  %s\n", name, len(files), formatBytes(size), oldest, newest)
+	}
+	return nil
+}
+
+// runCacheClear removes every cached response, or those of one endpoint.
+// The statistics file is kept.
+func runCacheClear(args []string) error {
+	fs := flag.NewFlagSet("cache clear", flag.ExitOnError)
+	dir := fs.String("dir", defaultCacheDir(), "Cache directory")
+	endpoint := fs.String("endpoint", "", "Only clear this endpoint")
+	fs.Parse(args)
+
+	endpoints := cacheEndpoints
+	if *endpoint != "" {
+		endpoints = []string{*endpoint}
+	}
+	removed := 0
+	for _, name := range endpoints {
+		files, err := listCacheFiles(filepath.Join(*dir, name))
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(filepath.Join(*dir, name)); err != nil {
+			return err
+		}
+		removed += len(files)
+	}
+	fmt.Printf("Removed %d cached responses from %s\n", removed, *dir)
+	return nil
+}
+
+// defaultCacheDir is the cache directory used by the processing run
+func defaultCacheDir() string {
+	return filepath.Join(os.TempDir(), "trakt_data")
+}
+
+// runCacheClean prunes entries older than a threshold and enforces size limits
+func runCacheClean(args []string) error {
+	fs := flag.NewFlagSet("cache clean", flag.ExitOnError)
+	dir := fs.String("dir", defaultCacheDir(), "Cache directory")
+	olderThan := fs.String("older-than", "", "Remove entries not used for this long, e.g. 720h or 30d")
+	maxSize := fs.String("max-size", "", "Evict least recently used entries until the cache is at most this size, e.g. 512MB (K/M/G and KB/MB/GB are powers of 1000, KiB/MiB/GiB powers of 1024)")
+	maxFiles := fs.Int("max-files", 0, "Evict least recently used entries until at most this many remain")
+	dryRun := fs.Bool("dry-run", false, "Report what would be removed without deleting anything")
+	fs.Parse(args)
+
+	maxBytes, err := ParseByteSize(*maxSize)
+	if err != nil {
+		return err
+	}
+	if *olderThan == "" && maxBytes == 0 && *maxFiles == 0 {
+		return fmt.Errorf("nothing to do: pass -older-than, -max-size or -max-files")
+	}
+
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	if *olderThan != "" {
+		age, err := ParseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		removed, freed, err := CleanDiskCache(*dir, time.Now().Add(-age), *dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %d entries older than %s (%s)\n", verb, removed, *olderThan, formatBytes(freed))
+	}
+
+	removed, freed, err := PruneDiskCache(*dir, CacheLimits{MaxBytes: maxBytes, MaxFiles: *maxFiles}, *dryRun)
+	if err != nil {
+		return err
+	}
+	if maxBytes > 0 || *maxFiles > 0 {
+		fmt.Printf("%s %d entries to stay within limits (%s)\n", verb, removed, formatBytes(freed))
+	}
+	return nil
+}
+
+// runCacheStats prints the hit/miss counters recorded by previous runs
+func runCacheStats(args []string) error {
+	fs := flag.NewFlagSet("cache stats", flag.ExitOnError)
+	dir := fs.String("dir", defaultCacheDir(), "Cache directory")
+	fs.Parse(args)
+
+	var stats CacheStats
+	LoadJSONOptional(filepath.Join(*dir, CacheStatsFile), &stats)
+	if stats.Runs == 0 {
+		fmt.Printf("No cache statistics recorded in %s yet\n", *dir)
+	} else {
+		fmt.Printf("## Last run (%s)\n\n%s\n", stats.UpdatedAt, FormatCacheCounters(stats.LastRun))
+		fmt.Printf("## All %d runs\n\n%s\n", stats.Runs, FormatCacheCounters(stats.Total))
+	}
+
+	files, err := listCacheFiles(*dir)
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	fmt.Printf("%d files, %s on disk\n", len(files), formatBytes(size))
+	return nil
+}
+
+// runCacheWarm pre-populates the cache from output snapshots, so a fresh
+// runner does not have to re-fetch every show, season, movie and Letterboxd
+// lookup the dataset already knows about. Existing cache files are real API
+// responses and are never overwritten; entries an earlier warm wrote are
+// refreshed from the snapshot.
+//
+// Runs trust the cache as Trakt's answer, so only what Trakt returned is
+// written: overridden entries are skipped, and IDs that external_sources
+// names as backfilled are left out. Show and movie entries are marked as
+// warmed, so a run needing a field the output lacked fetches them again.
+func runCacheWarm(args []string) error {
+	fs := flag.NewFlagSet("cache warm", flag.ExitOnError)
+	dir := fs.String("dir", defaultCacheDir(), "Cache directory")
+	var from []string
+	fs.Var((*stringList)(&from), "from", "Output snapshot to warm from (repeatable; default json/output/tv_ex.json and movies_ex.json)")
+	fs.Parse(args)
+
+	if len(from) == 0 {
+		from = []string{defaultOutput("tv"), defaultOutput("movies")}
+	}
+	for _, name := range cacheEndpoints {
+		if err := os.MkdirAll(filepath.Join(*dir, name), 0755); err != nil {
+			return err
+		}
+	}
+
+	written := make(map[string]int)
+	for _, path := range from {
+		var probe []struct {
+			Trakt struct {
+				Type string `json:"type"`
+			} `json:"trakt"`
+		}
+		LoadJSONOptional(path, &probe)
+		if len(probe) == 0 {
+			log.Printf("Warning: No entries in %s", path)
+			continue
+		}
+		if probe[0].Trakt.Type == "movies" {
+			var movies []OutputMovie
+			if err := LoadJSON(path, &movies); err != nil {
+				return err
+			}
+			warmMovies(*dir, movies, written)
+		} else {
+			var shows []OutputShow
+			if err := LoadJSON(path, &shows); err != nil {
+				return err
+			}
+			warmShows(*dir, shows, written)
+		}
+	}
+
+	for _, name := range cacheEndpoints {
+		if written[name] > 0 {
+			fmt.Printf("Warmed %d %s entries\n", written[name], name)
+		}
+	}
+	return nil
+}
+
+// warmShows writes show and season cache files for a TV snapshot
+func warmShows(dir string, shows []OutputShow, written map[string]int) {
+	seasons := make(map[int][]TraktSeason)
+	for _, show := range shows {
+		if show.Quality == QualityOverride {
+			continue
+		}
+		var traktShow TraktShow
+		traktShow.Title = show.Trakt.Title
+		traktShow.IDs.Trakt = show.Trakt.ID
+		traktShow.IDs.Slug = show.Trakt.Slug
+		traktShow.Year = show.ReleaseYear
+		traktShow.Runtime = show.Runtime
+		traktShow.Status = show.Status
+		traktShow.Country = show.Country
+		traktShow.Language = show.Language
+		traktShow.Network = show.Network
+		traktShow.Genres = show.Genres
+		traktShow.Certification = show.Certification
+		traktShow.Images = warmImages(show.Images)
+		traktShow.Warmed = true
+		if show.Externals != nil {
+			traktShow.IDs.TVDB = fromTrakt(show.Externals.TVDB, show.ExternalSources, "tvdb")
+			traktShow.IDs.TMDB = fromTrakt(show.Externals.TMDB, show.ExternalSources, "tmdb")
+			traktShow.IDs.IMDB = fromTrakt(show.Externals.IMDB, show.ExternalSources, "imdb")
+		}
+		warmFile(filepath.Join(dir, "shows", fmt.Sprintf("%d.json", show.Trakt.ID)), traktShow, "shows", written)
+
+		// Split cours have no season to record; the run re-checks them
+		if show.Trakt.Season == nil {
+			continue
+		}
+		var season TraktSeason
+		season.Number = show.Trakt.Season.Number
+		season.IDs.Trakt = show.Trakt.Season.ID
+		season.EpisodeCount = show.Trakt.Season.EpisodeCount
+		if externals := show.Trakt.Season.Externals; externals != nil {
+			season.IDs.TVDB = fromTrakt(externals.TVDB, show.ExternalSources, "season_tvdb")
+			season.IDs.TMDB = fromTrakt(externals.TMDB, show.ExternalSources, "season_tmdb")
+			season.IDs.TVRage = externals.TVRage
+		}
+		known := false
+		for _, s := range seasons[show.Trakt.ID] {
+			known = known || s.Number == season.Number
+		}
+		if !known {
+			seasons[show.Trakt.ID] = append(seasons[show.Trakt.ID], season)
+		}
+	}
+
+	for traktID, list := range seasons {
+		sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+		warmFile(filepath.Join(dir, "seasons", fmt.Sprintf("%d.json", traktID)), list, "seasons", written)
+	}
+}
+
+// warmMovies writes movie and Letterboxd cache files for a movie snapshot
+func warmMovies(dir string, movies []OutputMovie, written map[string]int) {
+	for _, movie := range movies {
+		if movie.Quality == QualityOverride {
+			continue
+		}
+		var traktMovie TraktMovie
+		traktMovie.Title = movie.Trakt.Title
+		traktMovie.IDs.Trakt = movie.Trakt.ID
+		traktMovie.IDs.Slug = movie.Trakt.Slug
+		traktMovie.Year = movie.ReleaseYear
+		traktMovie.Runtime = movie.Runtime
+		traktMovie.Country = movie.Country
+		traktMovie.Language = movie.Language
+		traktMovie.Genres = movie.Genres
+		traktMovie.Certification = movie.Certification
+		traktMovie.Images = warmImages(movie.Images)
+		traktMovie.Warmed = true
+		if movie.Externals != nil {
+			traktMovie.IDs.TMDB = fromTrakt(movie.Externals.TMDB, movie.ExternalSources, "tmdb")
+			traktMovie.IDs.IMDB = fromTrakt(movie.Externals.IMDB, movie.ExternalSources, "imdb")
+		}
+		warmFile(filepath.Join(dir, "movies", fmt.Sprintf("%d.json", movie.Trakt.ID)), traktMovie, "movies", written)
+
+		if canLookUpLetterboxd(movie.Externals) &&
+			movie.Externals.Letterboxd != nil && movie.Externals.Letterboxd.Slug != nil {
+			var tmdbID int
+			var imdbID string
+			if movie.Externals.TMDB != nil {
+				tmdbID = *movie.Externals.TMDB
+			} else {
+				imdbID = *movie.Externals.IMDB
+			}
+			path := letterboxdCachePath(Config{TempDir: dir}, tmdbID, imdbID)
+			warmFile(path, *movie.Externals.Letterboxd, "letterboxd", written)
+		}
+	}
+}
+
+// isWarmed reports whether path holds a show or movie written by `cache warm`
+func isWarmed(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var cached struct {
+		Warmed bool `json:"warmed"`
+	}
+	return json.Unmarshal(data, &cached) == nil && cached.Warmed
+}
+
+// warmImages turns output artwork back into Trakt images, leaving out the
+// TMDB fallback that fillTMDBImages adds
+func warmImages(images *Images) *TraktImages {
+	if images == nil {
+		return nil
+	}
+	var result TraktImages
+	if images.Poster != nil && !strings.HasPrefix(*images.Poster, tmdbImageBase) {
+		result.Poster = []string{*images.Poster}
+	}
+	if images.Fanart != nil && !strings.HasPrefix(*images.Fanart, tmdbImageBase) {
+		result.Fanart = []string{*images.Fanart}
+	}
+	if result.Poster == nil && result.Fanart == nil {
+		return nil
+	}
+	return &result
+}
+
+// fromTrakt returns id, or nil when sources names it as backfilled
+func fromTrakt[T any](id *T, sources map[string]string, name string) *T {
+	if _, backfilled := sources[name]; backfilled {
+		return nil
+	}
+	return id
+}
+
+// warmFile writes v to path unless a cached response is already there
+func warmFile(path string, v interface{}, endpoint string, written map[string]int) {
+	if _, err := os.Stat(path); err == nil && !isWarmed(path) {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Warning: Failed to encode %s: %v", path, err)
+		return
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		log.Printf("Warning: Failed to write %s: %v", path, err)
+		return
+	}
+	written[endpoint]++
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheWarmWritesOnlyTraktData(t *testing.T) {
+	dir := t.TempDir()
+	tv := filepath.Join(dir, "tv_ex.json")
+	os.WriteFile(tv, []byte(`[
+		{"myanimelist": {"id": 1}, "trakt": {"title": "Cowboy Bebop", "id": 10, "slug": "cowboy-bebop", "type": "shows",
+		  "season": {"id": 100, "number": 1, "episode_count": 26, "externals": {"tvdb": 200, "tmdb": 300, "tvrage": null}}},
+		 "release_year": 1998, "externals": {"tvdb": 76885, "tmdb": 30991, "imdb": "tt0213338", "tvrage": null},
+		 "external_sources": {"tvdb": "wikidata", "season_tmdb": "tmdb"},
+		 "images": {"poster": "https://walter.trakt.tv/poster.jpg", "fanart": "https://image.tmdb.org/t/p/original/fanart.jpg"}},
+		{"myanimelist": {"id": 2}, "trakt": {"title": "Remapped", "id": 20, "slug": "remapped", "type": "shows",
+		  "season": {"id": 201, "number": 2, "externals": null}},
+		 "quality": "verified-by-override", "release_year": 2001, "externals": null}
+	]`), 0644)
+	movies := filepath.Join(dir, "movies_ex.json")
+	os.WriteFile(movies, []byte(`[
+		{"myanimelist": {"id": 3}, "trakt": {"title": "Akira", "id": 30, "slug": "akira-1988", "type": "movies"},
+		 "release_year": 1988, "externals": {"tmdb": 149, "imdb": "tt0094625", "letterboxd": null},
+		 "external_sources": {"imdb": "hook"}},
+		{"myanimelist": {"id": 4}, "trakt": {"title": "Overridden", "id": 40, "slug": "overridden", "type": "movies"},
+		 "quality": "verified-by-override", "release_year": 2002, "externals": null}
+	]`), 0644)
+
+	cache := filepath.Join(dir, "cache")
+	if err := runCacheWarm([]string{"-dir", cache, "-from", tv, "-from", movies}); err != nil {
+		t.Fatalf("runCacheWarm() error = %v", err)
+	}
+
+	var show TraktShow
+	if err := LoadJSON(filepath.Join(cache, "shows", "10.json"), &show); err != nil {
+		t.Fatalf("show not warmed: %v", err)
+	}
+	if show.IDs.TVDB != nil {
+		t.Errorf("show TVDB = %d, want the backfilled ID left out", *show.IDs.TVDB)
+	}
+	if show.IDs.TMDB == nil || *show.IDs.TMDB != 30991 || show.IDs.IMDB == nil || *show.IDs.IMDB != "tt0213338" {
+		t.Errorf("show IDs = %+v, want Trakt's TMDB and IMDB IDs kept", show.IDs)
+	}
+	if !show.Warmed {
+		t.Error("warmed show not marked as warmed")
+	}
+	if show.Images == nil || len(show.Images.Poster) != 1 || show.Images.Fanart != nil {
+		t.Errorf("show images = %+v, want Trakt's poster and no TMDB fanart", show.Images)
+	}
+
+	var seasons []TraktSeason
+	if err := LoadJSON(filepath.Join(cache, "seasons", "10.json"), &seasons); err != nil {
+		t.Fatalf("seasons not warmed: %v", err)
+	}
+	if len(seasons) != 1 || seasons[0].IDs.TMDB != nil || seasons[0].IDs.TVDB == nil || *seasons[0].IDs.TVDB != 200 {
+		t.Errorf("seasons = %+v, want season 1 with TVDB 200 and no backfilled TMDB", seasons)
+	}
+
+	var movie TraktMovie
+	if err := LoadJSON(filepath.Join(cache, "movies", "30.json"), &movie); err != nil {
+		t.Fatalf("movie not warmed: %v", err)
+	}
+	if movie.IDs.IMDB != nil || movie.IDs.TMDB == nil || *movie.IDs.TMDB != 149 {
+		t.Errorf("movie IDs = %+v, want TMDB 149 and no backfilled IMDB", movie.IDs)
+	}
+
+	for _, path := range []string{"shows/20.json", "seasons/20.json", "movies/40.json"} {
+		if _, err := os.Stat(filepath.Join(cache, path)); err == nil {
+			t.Errorf("%s was warmed from an overridden entry", path)
+		}
+	}
+}
+
+func TestCacheWarmKeepsExistingResponses(t *testing.T) {
+	dir := t.TempDir()
+	tv := filepath.Join(dir, "tv_ex.json")
+	os.WriteFile(tv, []byte(`[{"myanimelist": {"id": 1}, "trakt": {"title": "From Output", "id": 10, "type": "shows"}, "externals": null}]`), 0644)
+
+	cache := filepath.Join(dir, "cache")
+	os.MkdirAll(filepath.Join(cache, "shows"), 0755)
+	cached := `{"title":"From Trakt","ids":{"trakt":10,"slug":""},"year":0,"updated_at":"2026-01-01T00:00:00.000Z"}`
+	os.WriteFile(filepath.Join(cache, "shows", "10.json"), []byte(cached), 0644)
+
+	if err := runCacheWarm([]string{"-dir", cache, "-from", tv}); err != nil {
+		t.Fatalf("runCacheWarm() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cache, "shows", "10.json")); string(data) != cached {
+		t.Errorf("cached response overwritten: %s", data)
+	}
+}
+
+func TestCacheWarmRefreshesWarmedEntries(t *testing.T) {
+	dir := t.TempDir()
+	tv := filepath.Join(dir, "tv_ex.json")
+	cache := filepath.Join(dir, "cache")
+	for _, title := range []string{"Old Title", "New Title"} {
+		os.WriteFile(tv, []byte(`[{"myanimelist": {"id": 1}, "trakt": {"title": "`+title+`", "id": 10, "type": "shows"}, "externals": null}]`), 0644)
+		if err := runCacheWarm([]string{"-dir", cache, "-from", tv}); err != nil {
+			t.Fatalf("runCacheWarm() error = %v", err)
+		}
+	}
+
+	var show TraktShow
+	LoadJSON(filepath.Join(cache, "shows", "10.json"), &show)
+	if show.Title != "New Title" {
+		t.Errorf("warmed title = %q, want it refreshed from the latest snapshot", show.Title)
+	}
+}
+
+func TestWarmedIncomplete(t *testing.T) {
+	config := Config{Images: true}
+	if !warmedIncomplete(config, true, nil, nil) {
+		t.Error("warmed entry without images trusted for -images")
+	}
+	if warmedIncomplete(config, false, nil, nil) {
+		t.Error("Trakt response without images refetched")
+	}
+	if warmedIncomplete(config, true, &TraktImages{Poster: []string{"p"}}, nil) {
+		t.Error("warmed entry with images refetched")
+	}
+	if !warmedIncomplete(Config{Genres: true}, true, nil, nil) {
+		t.Error("warmed entry without genres trusted for -genres")
+	}
+}
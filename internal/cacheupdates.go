@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// CacheUpdatesFile records when the Trakt updates feed was last consulted,
+// relative to the cache directory
+const CacheUpdatesFile = "cache_updates.json"
+
+// traktUpdatesWindow is how far back the Trakt updates feed reaches
+const traktUpdatesWindow = 30 * 24 * time.Hour
+
+// CacheUpdatesState is the content of CacheUpdatesFile
+type CacheUpdatesState struct {
+	ShowsCheckedAt  time.Time `json:"shows_checked_at"`
+	MoviesCheckedAt time.Time `json:"movies_checked_at"`
+}
+
+// traktUpdate is one item of /shows/updates or /movies/updates
+type traktUpdate struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Show      *struct {
+		IDs traktUpdateIDs `json:"ids"`
+	} `json:"show"`
+	Movie *struct {
+		IDs traktUpdateIDs `json:"ids"`
+	} `json:"movie"`
+}
+
+// traktUpdateIDs are the IDs of an updated show or movie
+type traktUpdateIDs struct {
+	Trakt int    `json:"trakt"`
+	Slug  string `json:"slug"`
+}
+
+// InvalidateUpdatedCache drops cached shows (with their seasons) and movies
+// that Trakt reports as updated since the feed was last checked, and whose
+// cached updated_at is older than Trakt's, along with their slug_<slug>.json
+// copies. When the feed was last checked too long ago to cover the gap, the
+// whole shows, seasons and movies cache is dropped instead, apart from the
+// entries `cache warm` rebuilds from the output before each run.
+func InvalidateUpdatedCache(config Config) {
+	client := NewHTTPClient(30 * time.Second)
+	statePath := filepath.Join(config.TempDir, CacheUpdatesFile)
+	var state CacheUpdatesState
+	LoadJSONOptional(statePath, &state)
+
+	targets := []struct {
+		mediaType string
+		checkedAt *time.Time
+		dirs      []string
+	}{
+		{"shows", &state.ShowsCheckedAt, []string{"shows", "seasons"}},
+		{"movies", &state.MoviesCheckedAt, []string{"movies"}},
+	}
+	for _, target := range targets {
+		if files, _ := listCacheFiles(filepath.Join(config.TempDir, target.mediaType)); len(files) == 0 {
+			continue
+		}
+
+		now := time.Now().UTC()
+		since := *target.checkedAt
+		if since.IsZero() || now.Sub(since) > traktUpdatesWindow {
+			log.Printf("Warning: %s cache was not checked against Trakt updates in the last 30 days; dropping it",
+				target.mediaType)
+			dropUnwarmed(config.TempDir, target.dirs)
+			*target.checkedAt = now
+			continue
+		}
+
+		updates, err := fetchTraktUpdates(client, config, target.mediaType, since)
+		if err != nil {
+			log.Printf("Warning: Failed to fetch Trakt %s updates, keeping cache as-is: %v", target.mediaType, err)
+			continue
+		}
+
+		invalidated := 0
+		for _, update := range updates {
+			var ids traktUpdateIDs
+			switch {
+			case update.Show != nil:
+				ids = update.Show.IDs
+			case update.Movie != nil:
+				ids = update.Movie.IDs
+			default:
+				continue
+			}
+			cacheFile := filepath.Join(config.TempDir, target.mediaType, fmt.Sprintf("%d.json", ids.Trakt))
+			if !cachedBefore(cacheFile, update.UpdatedAt) {
+				continue
+			}
+			// The slug may have changed since the entry was cached, so drop
+			// the copy under the cached slug as well as the current one
+			for _, slug := range []string{ids.Slug, cachedSlug(cacheFile)} {
+				if slug != "" {
+					os.Remove(filepath.Join(config.TempDir, target.mediaType, "slug_"+slug+".json"))
+				}
+			}
+			for _, dir := range target.dirs {
+				os.Remove(filepath.Join(config.TempDir, dir, fmt.Sprintf("%d.json", ids.Trakt)))
+			}
+			invalidated++
+		}
+		*target.checkedAt = now
+		if config.Verbose || invalidated > 0 {
+			Infof("Invalidated %d cached %s updated on Trakt since %s\n", invalidated, target.mediaType, since.Format(time.RFC3339))
+		}
+	}
+
+	if err := SaveJSON(statePath, state); err != nil {
+		log.Printf("Warning: Failed to save cache update state: %v", err)
+	}
+}
+
+// cachedBefore reports whether path holds a cached show or movie whose
+// updated_at predates updatedAt. Entries without updated_at (e.g. written by
+// `cache warm`) are treated as stale.
+func cachedBefore(path string, updatedAt time.Time) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var cached struct {
+		UpdatedAt string `json:"updated_at"`
+	}
+	if json.Unmarshal(data, &cached) != nil {
+		return true
+	}
+	cachedAt, err := time.Parse(time.RFC3339, cached.UpdatedAt)
+	return err != nil || cachedAt.Before(updatedAt)
+}
+
+// dropUnwarmed removes the cache files below dirs, keeping warmed entries of
+// the first (shows or movies) and, in the others, the seasons of warmed shows
+func dropUnwarmed(tempDir string, dirs []string) {
+	for _, dir := range dirs {
+		files, err := listCacheFiles(filepath.Join(tempDir, dir))
+		if err != nil {
+			log.Printf("Warning: Failed to list %s cache: %v", dir, err)
+		}
+		for _, f := range files {
+			if isWarmed(filepath.Join(tempDir, dirs[0], filepath.Base(f.path))) {
+				continue
+			}
+			if err := os.Remove(f.path); err != nil {
+				log.Printf("Warning: Failed to drop %s: %v", f.path, err)
+			}
+		}
+	}
+}
+
+// cachedSlug returns the Trakt slug of the show or movie cached at path
+func cachedSlug(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var cached struct {
+		IDs struct {
+			Slug string `json:"slug"`
+		} `json:"ids"`
+	}
+	json.Unmarshal(data, &cached)
+	return cached.IDs.Slug
+}
+
+// fetchTraktUpdates pages through /{shows|movies}/updates/{since}
+func fetchTraktUpdates(client *http.Client, config Config, mediaType string, since time.Time) ([]traktUpdate, error) {
+	var updates []traktUpdate
+	for page, pageCount := 1, 1; page <= pageCount; page++ {
+		config.RateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			url := traktURL(config, "/%s/updates/%s?page=%d&limit=100",
+				mediaType, since.Format(time.RFC3339), page)
+			req, err := http.NewRequest("GET", url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("trakt-api-version", "2")
+			req.Header.Set("trakt-api-key", config.APIKey)
+			return client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("API error: %d", resp.StatusCode)
+		}
+
+		var items []traktUpdate
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, err
+		}
+		updates = append(updates, items...)
+		if n, err := strconv.Atoi(resp.Header.Get("X-Pagination-Page-Count")); err == nil {
+			pageCount = n
+		}
+	}
+	return updates, nil
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runIDFormat names runs after their UTC start time, so run ID order is run
+// order
+const runIDFormat = "20060102T150405Z"
+
+// NewRunID returns the ID of a run started at t
+func NewRunID(t time.Time) string {
+	return t.UTC().Format(runIDFormat)
+}
+
+// ChangesResponse is the response of the /changes endpoint. Passing Latest
+// as the next since returns what happened after this response.
+type ChangesResponse struct {
+	Since   string        `json:"since"`
+	Latest  string        `json:"latest"` // newest run included, or since
+	Runs    []string      `json:"runs"`   // runs with changes after since, oldest first
+	Changes []EntryChange `json:"changes"`
+}
+
+// readChangelogs returns the changes recorded in dir, in the order they were
+// made, skipping the days before the run fromRun ("" reads every day)
+func readChangelogs(dir, fromRun string) ([]EntryChange, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "changes-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	fromDay := "changes-" + strings.SplitN(fromRun, "T", 2)[0] + ".json"
+	var changes []EntryChange
+	for _, path := range paths {
+		// A run's changes are logged on the day it started or later
+		if fromRun != "" && filepath.Base(path) < fromDay {
+			continue
+		}
+		var changelog Changelog
+		if err := LoadJSON(path, &changelog); err != nil {
+			return nil, err
+		}
+		changes = append(changes, changelog.Changes...)
+	}
+	return changes, nil
+}
+
+// LoadChanges collects the changes recorded in dir by the runs after since
+// ("" for all of them). Changes of the run running, if any, are left out:
+// they are incomplete until it ends. Changes logged before runs had IDs are
+// only returned without since.
+func LoadChanges(dir, since, running string) (ChangesResponse, error) {
+	if since != "" {
+		if _, err := time.Parse(runIDFormat, since); err != nil {
+			return ChangesResponse{}, fmt.Errorf("invalid since %q: expected a run ID such as %s", since, NewRunID(time.Now()))
+		}
+	}
+	changes, err := readChangelogs(dir, since)
+	if err != nil {
+		return ChangesResponse{}, err
+	}
+	response := ChangesResponse{Since: since, Latest: since, Runs: []string{}, Changes: []EntryChange{}}
+	for _, change := range changes {
+		if (since != "" && change.RunID <= since) || (running != "" && change.RunID >= running) {
+			continue
+		}
+		response.Changes = append(response.Changes, change)
+		if change.RunID > response.Latest {
+			response.Latest = change.RunID
+			response.Runs = append(response.Runs, change.RunID)
+		}
+	}
+	return response, nil
+}
+
+// ChangeFeed serves the changelogs of a daemon as /changes?since=RUN_ID. A
+// nil *ChangeFeed ignores SetRunning, so the daemon need not check for one.
+type ChangeFeed struct {
+	dir     string
+	mu      sync.Mutex
+	running string
+}
+
+// StartChangeFeed serves the change feed of the changelogs in dir on addr
+func StartChangeFeed(addr, dir string) *ChangeFeed {
+	feed := &ChangeFeed{dir: dir}
+	mux := http.NewServeMux()
+	mux.Handle("GET /changes", feed)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           gzipResponses(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving the change feed on http://%s/changes", addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Warning: change feed server stopped: %v", err)
+		}
+	}()
+	return feed
+}
+
+// SetRunning records the run in progress ("" when idle)
+func (f *ChangeFeed) SetRunning(runID string) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	f.running = runID
+	f.mu.Unlock()
+}
+
+func (f *ChangeFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	running := f.running
+	f.mu.Unlock()
+	response, err := LoadChanges(f.dir, r.URL.Query().Get("since"), running)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid since") {
+			status = http.StatusBadRequest
+		}
+		writeJSONResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// WebhookPayload is the body of a -webhook notification. It only summarizes
+// the run; the changes themselves come from the change feed or changelogs.
+type WebhookPayload struct {
+	Event       string         `json:"event"` // "dataset.updated"
+	RunID       string         `json:"run_id"`
+	GeneratedAt string         `json:"generated_at"`
+	Changes     map[string]int `json:"changes"`     // by change: created, updated, removed
+	MediaTypes  []string       `json:"media_types"` // media types changed
+}
+
+// NotifyWebhook posts a WebhookPayload to config.WebhookURL when the run
+// config.RunID recorded changes. With WEBHOOK_SECRET set, the body is signed
+// in an X-Webhook-Signature: sha256=<HMAC> header. Failures are logged but
+// never fail the run.
+func NotifyWebhook(config Config) {
+	if config.WebhookURL == "" || config.ChangelogDir == "" {
+		return
+	}
+	changes, err := readChangelogs(config.ChangelogDir, config.RunID)
+	if err != nil {
+		log.Printf("Warning: could not read the changelogs for -webhook: %v", err)
+		return
+	}
+	payload := WebhookPayload{
+		Event:       "dataset.updated",
+		RunID:       config.RunID,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Changes:     map[string]int{},
+	}
+	mediaTypes := map[string]bool{}
+	for _, change := range changes {
+		if change.RunID == config.RunID {
+			payload.Changes[change.Change]++
+			mediaTypes[change.MediaType] = true
+		}
+	}
+	if len(mediaTypes) == 0 {
+		if config.Verbose {
+			Debugf("\nNo changes in run %s, webhook not sent", config.RunID)
+		}
+		return
+	}
+	payload.MediaTypes = sortedKeys(mediaTypes)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Warning: could not encode webhook payload: %v", err)
+		return
+	}
+
+	client := NewHTTPClient(30 * time.Second)
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, config.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		return client.Do(req)
+	})
+	if err != nil {
+		log.Printf("Warning: webhook to %s failed: %v", config.WebhookURL, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Printf("Warning: webhook to %s returned HTTP %d: %s", config.WebhookURL, resp.StatusCode, strings.TrimSpace(string(msg)))
+		return
+	}
+	if config.Verbose {
+		Debugf("\nNotified %s of run %s", config.WebhookURL, config.RunID)
+	}
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadChanges(t *testing.T) {
+	dir := t.TempDir()
+	day1 := time.Date(2026, 10, 15, 23, 59, 0, 0, time.UTC)
+	day2 := day1.Add(12 * time.Hour)
+	first, second := NewRunID(day1), NewRunID(day2)
+
+	// The changelog of a run lands in the file of the day it is written
+	SaveJSON(changelogPath(dir, day1), Changelog{Changes: []EntryChange{
+		{MediaType: "tv", Change: "created", MalID: 1},
+		{MediaType: "tv", Change: "created", MalID: 5, RunID: first},
+	}})
+	SaveJSON(changelogPath(dir, day2), Changelog{Changes: []EntryChange{
+		{MediaType: "movies", Change: "created", MalID: 43, RunID: first},
+		{MediaType: "tv", Change: "updated", MalID: 1, RunID: second},
+	}})
+
+	tests := []struct {
+		since, running string
+		want           []int
+		latest         string
+	}{
+		{"", "", []int{1, 5, 43, 1}, second},
+		{first, "", []int{1}, second},
+		{second, "", nil, second},
+		{"", second, []int{1, 5, 43}, first},
+		{NewRunID(day1.Add(-time.Hour)), "", []int{5, 43, 1}, second},
+	}
+	for _, tt := range tests {
+		response, err := LoadChanges(dir, tt.since, tt.running)
+		if err != nil {
+			t.Fatalf("LoadChanges(since=%q): %v", tt.since, err)
+		}
+		var got []int
+		for _, change := range response.Changes {
+			got = append(got, change.MalID)
+		}
+		if len(got) != len(tt.want) || response.Latest != tt.latest {
+			t.Errorf("LoadChanges(since=%q, running=%q) = %v latest %s, want %v latest %s", tt.since, tt.running, got, response.Latest, tt.want, tt.latest)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("LoadChanges(since=%q) = %v, want %v", tt.since, got, tt.want)
+				break
+			}
+		}
+	}
+
+	feed := &ChangeFeed{dir: dir}
+	rec := httptest.NewRecorder()
+	feed.ServeHTTP(rec, httptest.NewRequest("GET", "/changes?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("since=yesterday: status %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	feed.ServeHTTP(rec, httptest.NewRequest("GET", "/changes?since="+first, nil))
+	var response ChangesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || len(response.Runs) != 1 || response.Runs[0] != second {
+		t.Errorf("GET /changes?since=%s = %s, want the changes of %s", first, rec.Body, second)
+	}
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET", "s3cret")
+	var payload WebhookPayload
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		if got, want := r.Header.Get("X-Webhook-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		json.Unmarshal(body, &payload)
+	}))
+	defer ts.Close()
+
+	config := Config{ChangelogDir: t.TempDir(), WebhookURL: ts.URL, RunID: NewRunID(time.Now())}
+	NotifyWebhook(config)
+	if requests != 0 {
+		t.Fatalf("a run without changes sent %d webhooks", requests)
+	}
+
+	shows := testDataset(t).ShowList()
+	appendChangelog(config, "tv_ex.json", DiffShows(nil, shows))
+	appendChangelog(config, "tv_ex.json", DiffShows(shows, shows[:1]))
+	NotifyWebhook(config)
+	if requests != 1 {
+		t.Fatalf("sent %d webhooks, want 1", requests)
+	}
+	if payload.RunID != config.RunID || payload.Changes["created"] != len(shows) || payload.Changes["removed"] != 1 || len(payload.MediaTypes) != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Changelog is the changes-YYYYMMDD.json file of one UTC day. Every run of
+// the day appends to it, so consumers replaying Changes in order reach the
+// state of the output files at UpdatedAt.
+type Changelog struct {
+	Date      string        `json:"date"`
+	UpdatedAt string        `json:"updated_at"`
+	Changes   []EntryChange `json:"changes"`
+}
+
+// changelogPath returns the changelog file for the day of t
+func changelogPath(dir string, t time.Time) string {
+	return filepath.Join(dir, "changes-"+t.UTC().Format("20060102")+".json")
+}
+
+// appendChangelog adds a run's changes to the day's changelog in
+// config.ChangelogDir, stamped with config.RunID. Runs without changes, and
+// streamed output, leave it alone.
+func appendChangelog(config Config, outputFile string, changes []EntryChange) {
+	if config.ChangelogDir == "" || outputFile == StdioPath || len(changes) == 0 {
+		return
+	}
+	if err := os.MkdirAll(config.ChangelogDir, 0755); err != nil {
+		fmt.Printf("Warning: could not create changelog directory %s: %v\n", config.ChangelogDir, err)
+		return
+	}
+	now := time.Now().UTC()
+	path := changelogPath(config.ChangelogDir, now)
+	var changelog Changelog
+	LoadJSONOptional(path, &changelog)
+	changelog.Date = now.Format("2006-01-02")
+	changelog.UpdatedAt = now.Format(time.RFC3339)
+	for _, change := range changes {
+		change.RunID = config.RunID
+		changelog.Changes = append(changelog.Changes, change)
+	}
+	if err := SaveJSON(path, changelog); err != nil {
+		fmt.Printf("Warning: could not write changelog: %v\n", err)
+		return
+	}
+	if config.Verbose {
+		Debugf("\nRecorded %d changes in %s", len(changes), path)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// claim is an external ID held by an output entry
+type claim struct {
+	source  string // "IMDB" or "TMDB"
+	id      string
+	malID   int
+	title   string
+	traktID int
+}
+
+// ShowCollisions reports IMDB and TMDB IDs shared by shows with different
+// Trakt IDs. Seasons of one Trakt show share its IDs and are not reported.
+func ShowCollisions(shows map[int]OutputShow) []ChangeDetail {
+	var claims []claim
+	for _, show := range shows {
+		if show.Externals == nil {
+			continue
+		}
+		claims = appendClaims(claims, show.MyAnimeList.ID, show.MyAnimeList.Title, show.Trakt.ID, show.Externals.IMDB, show.Externals.TMDB)
+	}
+	return collisions(claims)
+}
+
+// MovieCollisions reports IMDB and TMDB IDs shared by movies with different
+// Trakt IDs
+func MovieCollisions(movies map[int]OutputMovie) []ChangeDetail {
+	var claims []claim
+	for _, movie := range movies {
+		if movie.Externals == nil {
+			continue
+		}
+		claims = appendClaims(claims, movie.MyAnimeList.ID, movie.MyAnimeList.Title, movie.Trakt.ID, movie.Externals.IMDB, movie.Externals.TMDB)
+	}
+	return collisions(claims)
+}
+
+// appendClaims adds the IMDB and TMDB IDs an entry holds
+func appendClaims(claims []claim, malID int, title string, traktID int, imdb *string, tmdb *int) []claim {
+	if imdb != nil && *imdb != "" {
+		claims = append(claims, claim{source: "IMDB", id: *imdb, malID: malID, title: title, traktID: traktID})
+	}
+	if tmdb != nil && *tmdb != 0 {
+		claims = append(claims, claim{source: "TMDB", id: fmt.Sprint(*tmdb), malID: malID, title: title, traktID: traktID})
+	}
+	return claims
+}
+
+// collisions groups claims by external ID and reports the IDs claimed under
+// more than one Trakt ID, one detail per ID on its lowest MAL ID
+func collisions(claims []claim) []ChangeDetail {
+	sort.Slice(claims, func(i, j int) bool { return claims[i].malID < claims[j].malID })
+	groups := make(map[[2]string][]claim)
+	var keys [][2]string
+	for _, c := range claims {
+		key := [2]string{c.source, c.id}
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], c)
+	}
+
+	var details []ChangeDetail
+	for _, key := range keys {
+		group := groups[key]
+		malIDs := make(map[int][]string) // Trakt ID -> MAL IDs
+		var traktIDs []int
+		for _, c := range group {
+			if malIDs[c.traktID] == nil {
+				traktIDs = append(traktIDs, c.traktID)
+			}
+			malIDs[c.traktID] = append(malIDs[c.traktID], fmt.Sprint(c.malID))
+		}
+		if len(traktIDs) < 2 {
+			continue
+		}
+		parts := make([]string, len(traktIDs))
+		for i, traktID := range traktIDs {
+			parts[i] = fmt.Sprintf("Trakt %d (MAL %s)", traktID, strings.Join(malIDs[traktID], ", "))
+		}
+		details = append(details, ChangeDetail{
+			MalID:  group[0].malID,
+			Title:  group[0].title,
+			Reason: fmt.Sprintf("%s %s claimed by %s", key[0], key[1], strings.Join(parts, " and ")),
+		})
+	}
+	sort.SliceStable(details, func(i, j int) bool { return details[i].MalID < details[j].MalID })
+	return details
+}
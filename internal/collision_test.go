@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestShowCollisions(t *testing.T) {
+	show := func(malID, traktID, tmdb int, imdb string) OutputShow {
+		var s OutputShow
+		s.MyAnimeList.ID = malID
+		s.Trakt.ID = traktID
+		s.Externals = &TraktExternalsShow{TMDB: &tmdb, IMDB: &imdb}
+		return s
+	}
+	shows := map[int]OutputShow{
+		1: show(1, 100, 500, "tt1"),
+		2: show(2, 100, 500, "tt1"), // another season of Trakt 100
+		3: show(3, 200, 500, "tt2"), // same TMDB ID, other Trakt show
+		4: show(4, 300, 600, "tt3"),
+	}
+
+	details := ShowCollisions(shows)
+	if len(details) != 1 {
+		t.Fatalf("ShowCollisions = %+v, want one collision", details)
+	}
+	if details[0].MalID != 1 || !strings.Contains(details[0].Reason, "TMDB 500 claimed by Trakt 100 (MAL 1, 2) and Trakt 200 (MAL 3)") {
+		t.Errorf("collision = %+v", details[0])
+	}
+}
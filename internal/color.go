@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ANSI colors of the run summary
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+)
+
+// useColor is set by SetColor; summaries are plain until then
+var useColor bool
+
+// ColorModes are the values of -color
+var ColorModes = []string{"auto", "always", "never"}
+
+// SetColor applies a -color mode: "always", "never", or "auto", which colors
+// only a terminal stdout and honours NO_COLOR and TERM=dumb
+func SetColor(mode string) {
+	switch mode {
+	case "always":
+		useColor = true
+	case "auto":
+		useColor = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" &&
+			term.IsTerminal(int(os.Stdout.Fd()))
+	default:
+		useColor = false
+	}
+}
+
+// summaryColors colors summary headings and metric rows by line prefix;
+// detail rows take the color of their section heading
+var summaryColors = []struct {
+	prefix string
+	color  string
+}{
+	{"| Created ", ansiGreen},
+	{"### ✨ Created", ansiGreen},
+	{"| Updated ", ansiYellow},
+	{"### 🔄 Updated", ansiYellow},
+	{"| Not Found ", ansiRed},
+	{"### ❌ Not Found", ansiRed},
+	{"### 📽️ Letterboxd Not Found", ansiRed},
+	{"| Healed ", ansiGreen},
+	{"### 🩹 Healed", ansiGreen},
+	{"| Redirected ", ansiYellow},
+	{"### ↪️ Trakt Redirects", ansiYellow},
+	{"| Warnings ", ansiYellow},
+	{"### 🟡 Warnings", ansiYellow},
+}
+
+// colorizeSummary colors a markdown run summary for the terminal: created
+// green, updated yellow, not found red
+func colorizeSummary(output string) string {
+	lines := strings.Split(output, "\n")
+	section := ""
+	for i, line := range lines {
+		color := ""
+		for _, c := range summaryColors {
+			if strings.HasPrefix(line, c.prefix) {
+				color = c.color
+				break
+			}
+		}
+		switch {
+		case strings.HasPrefix(line, "#"):
+			section = color
+		case color == "" && strings.HasPrefix(line, "| ") && !strings.HasPrefix(line, "| Title "):
+			color = section
+		}
+		if color != "" {
+			lines[i] = color + line + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
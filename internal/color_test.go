@@ -0,0 +1,32 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestColorizeSummary(t *testing.T) {
+	output := "## Tv - Summary\n\n" +
+		"| Metric | Before | After | Diff |\n" +
+		"| Created | - | 1 | +1 |\n" +
+		"| Not Found | - | 1 | +1 |\n" +
+		"\n### ❌ Not Found (1)\n\n" +
+		"| Title | MAL ID | Reason |\n" +
+		"| Trigun | 6 | Not found on Trakt.tv |\n"
+
+	lines := strings.Split(colorizeSummary(output), "\n")
+	want := map[int]string{
+		2: "",        // table header
+		3: ansiGreen, // Created metric
+		4: ansiRed,   // Not Found metric
+		6: ansiRed,   // section heading
+		8: "",        // detail header
+		9: ansiRed,   // detail row
+	}
+	for i, color := range want {
+		got := strings.HasPrefix(lines[i], "\033[")
+		if color == "" && got || color != "" && !strings.HasPrefix(lines[i], color) {
+			t.Errorf("line %d %q: want color %q", i, lines[i], color)
+		}
+	}
+}
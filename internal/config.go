@@ -1,10 +1,17 @@
 package internal
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"syscall"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -12,39 +19,327 @@ import (
 // ParseFlags parses command line flags
 func ParseFlags() Config {
 	var config Config
+	config.Budget = &Budget{}
+	config.HTTP = DefaultHTTPSettings()
 	flag.StringVar(&config.APIKey, "api-key", "", "Trakt API key")
-	flag.StringVar(&config.TvFile, "tv", "", "Path to TV shows JSON file")
-	flag.StringVar(&config.MovieFile, "movies", "", "Path to movies JSON file")
+	flag.BoolVar(&config.Keychain, "keychain", false, "Read the Trakt API key from the OS keychain, and save a prompted key there")
+	flag.StringVar(&config.TraktAPIURL, "trakt-api-url", envOr("TRAKT_API_URL", DefaultTraktAPIURL), "Trakt API root, e.g. a test server (env TRAKT_API_URL)")
+	flag.StringVar(&config.APIKeyFile, "api-key-file", "", "Read the Trakt API key from this file, or from the output of exec:<command> (env TRAKT_API_KEY_FILE)")
+	var tvInputs, movieInputs []string
+	flag.Var((*stringList)(&tvInputs), "tv", "Path, glob, URL, anitrakt:[ref] or anitrakt-site:[url] of TV shows JSON files (repeatable, merged)")
+	flag.Var((*stringList)(&movieInputs), "movies", "Path, glob, URL, anitrakt:[ref] or anitrakt-site:[url] of movies JSON files (repeatable, merged)")
 	flag.StringVar(&config.OutputFile, "output", "", "Output file path")
+	flag.StringVar(&layout.Base, "data-dir", layout.Base, "Base directory of the data files (env ANITRAKT_DATA_DIR)")
+	flag.StringVar(&layout.Output, "output-dir", layout.Output, "Directory of the output files, relative to -data-dir unless absolute (env ANITRAKT_OUTPUT_DIR)")
+	flag.StringVar(&layout.Overrides, "overrides-dir", layout.Overrides, "Directory of the override files, relative to -data-dir unless absolute (env ANITRAKT_OVERRIDES_DIR)")
+	flag.StringVar(&layout.NotFound, "not-found-dir", layout.NotFound, "Directory of the not-found lists, relative to -data-dir unless absolute (env ANITRAKT_NOT_FOUND_DIR)")
+	flag.StringVar(&config.OutputTemplate, "output-template", "", "Output file path with {type}, {name}, {date} and {run_id} variables, e.g. json/output/{type}_{date}_ex.json")
 	flag.BoolVar(&config.Verbose, "verbose", false, "Verbose output")
+	flag.StringVar(&config.Color, "color", "auto", "Color the summary: auto (terminal without NO_COLOR), always or never")
 	flag.BoolVar(&config.NoProgress, "no-progress", false, "Disable progress bar")
+	quiet := flag.Bool("quiet", false, "Only print errors and the final summary (same as -log-level error)")
+	flag.StringVar(&config.LogFile, "log-file", "", "Also write all output, down to debug level, to this file")
+	logLevel := flag.String("log-level", "info", "Output level: error, warn, info or debug (debug is -verbose)")
 	flag.BoolVar(&config.Force, "force", false, "Force update all entries, ignoring cache")
+	flag.IntVar(&config.CacheEntries, "cache-entries", 1000, "Decoded API responses kept in memory in front of the disk cache (0 disables)")
+	cacheMaxSize := flag.String("cache-max-size", "", "Evict least recently used disk cache entries beyond this size after the run, e.g. 512MB (K/M/G and KB/MB/GB are powers of 1000, KiB/MiB/GiB powers of 1024)")
+	flag.IntVar(&config.CacheLimits.MaxFiles, "cache-max-files", 0, "Evict least recently used disk cache entries beyond this count after the run")
+	enrich := flag.String("enrich", strings.Join(DefaultEnrichments, ","), "Comma-separated enrichments to run ("+strings.Join(KnownEnrichments, ", ")+")")
+	noEnrich := flag.Bool("no-enrich", false, "Skip all enrichments (fast Trakt-only pass)")
+	flag.Var((*stringList)(&config.Hooks), "hook", "Command run on each entry, which gets it as JSON on stdin and prints extra external IDs (repeatable)")
+	flag.BoolVar(&config.EnrichMissing, "enrich-missing", false, "Also enrich existing entries that lack enrichment data, without re-fetching them from Trakt")
+	flag.BoolVar(&config.RefreshAiring, "refresh-airing", false, "Re-fetch existing shows whose Trakt status is not ended or canceled")
+	flag.BoolVar(&config.Genres, "genres", false, "Add Trakt genres and certification to the output")
+	flag.BoolVar(&config.Popularity, "popularity", false, "Add a snapshot of Trakt watchers, plays and votes to the output (one extra request per entry)")
+	flag.BoolVar(&config.Images, "images", false, "Add poster/fanart URLs (from Trakt, or TMDB when Trakt has none) to the output")
+	flag.IntVar(&config.HTTP.MaxIdleConnsPerHost, "http-max-idle-per-host", config.HTTP.MaxIdleConnsPerHost, "Idle keep-alive connections kept per API host")
+	flag.IntVar(&config.HTTP.MaxConnsPerHost, "http-max-conns-per-host", 0, "Connections open at once per API host (0 unlimited)")
+	flag.DurationVar(&config.HTTP.IdleConnTimeout, "http-idle-timeout", config.HTTP.IdleConnTimeout, "Close keep-alive connections idle for this long")
+	flag.BoolVar(&config.HTTP.DisableHTTP2, "http1", false, "Use HTTP/1.1 only, without HTTP/2")
+	flag.IntVar(&config.LetterboxdWorkers, "letterboxd-workers", 4, "Parallel Letterboxd lookups, overlapping the Trakt requests")
+	flag.IntVar(&config.LetterboxdRate, "letterboxd-rate", DefaultLetterboxdRate, "Letterboxd requests per minute")
+	flag.DurationVar(&config.LetterboxdDelay, "letterboxd-delay", DefaultLetterboxdDelay, "Pause before and after each Letterboxd film lookup")
+	flag.StringVar(&config.LetterboxdUserAgent, "letterboxd-user-agent", DefaultLetterboxdUserAgent, "User-Agent sent to Letterboxd")
+	letterboxdTTL := flag.String("letterboxd-ttl", "90d", "Re-check cached Letterboxd IDs older than this (0 never expires)")
+	letterboxdNegativeTTL := flag.String("letterboxd-negative-ttl", "14d", "Re-check films cached as missing on Letterboxd after this long (0 never expires)")
+	onlyMalIDs := flag.String("only-mal-ids", "", "Only process these comma-separated MAL IDs (re-fetched as with -force)")
+	skipMalIDs := flag.String("skip-mal-ids", "", "Leave these comma-separated MAL IDs untouched")
+	flag.IntVar(&config.Filter.OnlyYear, "only-year", 0, "Only process entries whose recorded release year matches (re-fetched as with -force)")
+	onlyType := flag.String("only-type", "", "Only process this type: tv or movie")
+	flag.IntVar(&config.Budget.MaxNew, "max-new", 0, "Stop after adding this many new entries, saving partial results (0 = unlimited)")
+	flag.IntVar(&config.Budget.MaxAPICalls, "max-api-calls", 0, "Stop after this many outbound API requests to all hosts, saving partial results (0 = unlimited)")
+	config.Budget.MaxHostCalls = map[string]int{}
+	flag.Var(hostLimits(config.Budget.MaxHostCalls), "max-host-calls", "Stop after this many requests to one host, as host=N, e.g. api.trakt.tv=1000 (repeatable)")
+	flag.Float64Var(&config.Guardrails.MaxRemovedPercent, "max-removed-percent", 0, "Abort before writing if a run would remove more than this percentage of an output file's entries (0 = unlimited)")
+	flag.IntVar(&config.Guardrails.MaxTraktIDChanges, "max-trakt-id-changes", 0, "Abort before writing if a run would change the Trakt ID of more than this many entries (0 = unlimited)")
+	flag.BoolVar(&config.Guardrails.AllowShrink, "allow-shrink", false, "Replace an output file even with one holding more than 10% fewer entries")
+	flag.IntVar(&config.Thresholds.MaxNotFound, "fail-on-not-found-over", 0, "Exit with code 7 if more than this many entries of a media type are not found on Trakt (0 = unlimited)")
+	flag.IntVar(&config.Thresholds.MaxWarnings, "fail-on-warning-over", 0, "Exit with code 7 if a media type's entries get more than this many warnings (0 = unlimited)")
+	flag.BoolVar(&config.Guardrails.Override, "allow-changes", false, "Write the run even if its changes exceed -max-removed-percent or -max-trakt-id-changes")
+	flag.BoolVar(&config.Daemon, "daemon", false, "Keep running, repeating the run every -interval (inputs are re-read each time)")
+	flag.DurationVar(&config.Interval, "interval", 24*time.Hour, "Time between -daemon runs")
+	flag.StringVar(&config.ChangesAddr, "changes-addr", "", "In -daemon mode, serve the change feed (/changes?since=RUN_ID) on this address (e.g. :8081)")
+	flag.StringVar(&config.WebhookURL, "webhook", "", "POST a notification to this URL after each run that changes the dataset")
+	flag.BoolVar(&config.RecheckNotFound, "recheck-not-found", false, "Look up not-found entries again; those now on Trakt are added and leave the not-found list")
+	flag.BoolVar(&config.RetryFailed, "retry-failed", false, "Only process the entries earlier runs failed on with errors other than 404 (json/retry/)")
+	flag.StringVar(&config.ReviewQueue, "review-queue", filepath.Join(layout.ReviewDir(), "review_queue.json"), "Write Trakt search candidates of not-found entries, and shows whose episode counts disagree with Trakt, here (empty disables)")
+	flag.BoolVar(&config.NoPreflight, "no-preflight", false, "Skip checking the Trakt API key with one request before processing")
+	flag.StringVar(&config.PprofAddr, "pprof", "", "Serve net/http/pprof on this address (e.g. :6060) while running")
+	flag.StringVar(&config.ResourcesFile, "resources-file", "", "Write the run's resource summary (requests, retries, waits, bytes, phase times) to this JSON file")
+	flag.StringVar(&config.ReportHTML, "report-html", "", "Write a standalone HTML report of the run (sortable entry tables, growth charts) to this file")
+	flag.StringVar(&config.HistoryFile, "history-file", "stats/history.json", "Append the run's statistics per output file to this JSON history (empty disables; see the history subcommand)")
+	flag.StringVar(&config.ErrorsFile, "errors-file", "", "Write every per-entry error of the run (category, endpoint, status, retries) to this JSON file")
+	flag.BoolVar(&config.Journal, "journal", false, "Write run changes to json/journal instead of rewriting the output files (fold with `compact`)")
 	// Fribb-based ingestion (optional; pass empty string to fetch from internet)
+	sortFlag := flag.String("sort", string(SortMalID), "Entry order of the output files and push payloads: mal_id, trakt_id, title or year")
+	flag.BoolVar(&config.KeyedOutput, "keyed", false, "Also write each output file as a JSON object keyed by MAL ID (<output>_keyed.json)")
+	flag.IntVar(&config.Snapshots, "snapshots", 0, "Keep the last N written versions of each output file in json/snapshots (0 disables)")
+	flag.StringVar(&config.ChangelogDir, "changelog-dir", layout.ChangesDir(), "Directory of the per-day changes-YYYYMMDD.json changelogs (empty disables)")
 	flag.StringVar(&config.FribbFile, "fribb", "",
 		"Enable Fribb ingestion: path to anime-lists-reduced.json (omit value to fetch from GitHub)")
 	flag.StringVar(&config.AnimeAPIFile, "animeapi", "",
 		"Path to animeapi.tsv for Fribb ingestion (omit value to fetch from animeapi.my.id)")
+	var sinks []string
+	flag.Var((*stringList)(&sinks), "sink", "Also write the results to ndjson[:dir], sqlite[:path], redis[:url] or http:url (repeatable)")
+	// HTTP push sink (optional)
+	flag.StringVar(&config.PushURL, "push-url", "", "POST/PUT results to this HTTP endpoint after each run")
+	flag.StringVar(&config.PushMethod, "push-method", "POST", "HTTP method used for -push-url (POST or PUT)")
+	flag.StringVar(&config.PushMode, "push-mode", "delta", "What to push: full (all entries) or delta (changed entries only)")
+	flag.Var((*stringList)(&config.PushHeaders), "push-header", "Extra \"Name: value\" header for -push-url (repeatable, $VARS expanded)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", flag.CommandLine.Name())
+		flag.PrintDefaults()
+		PrintSubcommands()
+	}
 	flag.Parse()
 
 	// Detect whether -fribb or -animeapi was explicitly provided on the command
 	// line, even as an empty string.  flag.Visit only walks flags that were
 	// actually set by the caller, so "-fribb ''" counts as set.
+	set := map[string]bool{}
 	flag.Visit(func(f *flag.Flag) {
 		if f.Name == "fribb" || f.Name == "animeapi" {
 			config.UseFribb = true
 		}
+		set[f.Name] = true
 	})
+	// Defaults under -data-dir follow it
+	if set["data-dir"] && !set["review-queue"] {
+		config.ReviewQueue = filepath.Join(layout.ReviewDir(), "review_queue.json")
+	}
+	if set["data-dir"] && !set["changelog-dir"] {
+		config.ChangelogDir = layout.ChangesDir()
+	}
+
+	if config.PushMode != "full" && config.PushMode != "delta" {
+		log.Fatalf("Invalid -push-mode %q (expected full or delta)", config.PushMode)
+	}
+	config.PushMethod = strings.ToUpper(config.PushMethod)
+	if config.PushMethod != "POST" && config.PushMethod != "PUT" {
+		log.Fatalf("Invalid -push-method %q (expected POST or PUT)", config.PushMethod)
+	}
+	for _, header := range config.PushHeaders {
+		if _, _, err := splitPushHeader(header); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, spec := range sinks {
+		sink, err := ParseSink(spec)
+		if err != nil {
+			log.Fatalf("Invalid -sink: %v", err)
+		}
+		config.Sinks = append(config.Sinks, sink)
+	}
+	if config.PushURL != "" {
+		config.Sinks = append(config.Sinks, HTTPSink{URL: config.PushURL})
+	}
+
+	level, err := ParseLogLevel(*logLevel)
+	if err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	switch {
+	case *quiet && config.Verbose:
+		log.Fatalf("-quiet and -verbose cannot be combined")
+	case *quiet:
+		level = LogError
+	case config.Verbose:
+		level = LogDebug
+	}
+	config.LogLevel = level
+	// Per-entry detail is produced for the console at debug level, and
+	// always for the log file
+	config.Verbose = level == LogDebug || config.LogFile != ""
+	if level == LogError {
+		config.NoProgress = true
+	}
+
+	if !slices.Contains(ColorModes, config.Color) {
+		log.Fatalf("Invalid -color %q (expected %s)", config.Color, strings.Join(ColorModes, ", "))
+	}
+
+	if config.Enrichments, err = parseEnrichments(*enrich, *noEnrich, config.EnrichMissing, config.Hooks); err != nil {
+		log.Fatal(err)
+	}
+
+	if config.TvFiles, err = ExpandInputs(tvInputs); err != nil {
+		log.Fatalf("Invalid -tv: %v", err)
+	}
+	if config.MovieFiles, err = ExpandInputs(movieInputs); err != nil {
+		log.Fatalf("Invalid -movies: %v", err)
+	}
+	if config.Daemon {
+		if config.Interval <= 0 {
+			log.Fatalf("Invalid -interval %s: must be positive", config.Interval)
+		}
+		if config.OutputFile == StdioPath || slices.Contains(config.TvFiles, StdioPath) || slices.Contains(config.MovieFiles, StdioPath) {
+			log.Fatalf("-daemon cannot read stdin or write stdout")
+		}
+	} else if config.ChangesAddr != "" {
+		log.Fatalf("-changes-addr requires -daemon")
+	}
+	if (config.ChangesAddr != "" || config.WebhookURL != "") && config.ChangelogDir == "" {
+		log.Fatalf("-changes-addr and -webhook read the changelogs: set -changelog-dir")
+	}
+	if slices.Contains(config.TvFiles, StdioPath) && slices.Contains(config.MovieFiles, StdioPath) {
+		log.Fatalf("Only one of -tv and -movies can read from stdin")
+	}
+	if config.OutputTemplate != "" {
+		if config.OutputFile != "" {
+			log.Fatalf("-output and -output-template cannot be combined")
+		}
+		if err := CheckOutputTemplate(config.OutputTemplate); err != nil {
+			log.Fatalf("Invalid -output-template: %v", err)
+		}
+		sets := len(config.TvFiles) > 0 && len(config.MovieFiles) > 0 || config.UseFribb
+		if sets && !strings.Contains(config.OutputTemplate, "{type}") {
+			log.Fatalf("-output-template needs {type} when more than one media type is processed")
+		}
+	}
+	if config.OutputFile == StdioPath {
+		if len(config.TvFiles) > 0 && len(config.MovieFiles) > 0 {
+			log.Fatalf("-output - takes a single result set: pass -tv or -movies, not both")
+		}
+		if config.Journal {
+			log.Fatalf("-output - cannot be combined with -journal")
+		}
+	}
+	if config.LetterboxdTTL, err = ParseAge(*letterboxdTTL); err != nil {
+		log.Fatalf("Invalid -letterboxd-ttl: %v", err)
+	}
+	if config.LetterboxdNegativeTTL, err = ParseAge(*letterboxdNegativeTTL); err != nil {
+		log.Fatalf("Invalid -letterboxd-negative-ttl: %v", err)
+	}
+	if config.CacheLimits.MaxBytes, err = ParseByteSize(*cacheMaxSize); err != nil {
+		log.Fatalf("Invalid -cache-max-size: %v", err)
+	}
+	if config.Budget.MaxNew < 0 || config.Budget.MaxAPICalls < 0 {
+		log.Fatalf("Invalid budget: -max-new and -max-api-calls must not be negative")
+	}
+	if config.Sort, err = ParseSortOrder(*sortFlag); err != nil {
+		log.Fatalf("Invalid -sort: %v", err)
+	}
+	if config.Snapshots < 0 {
+		log.Fatalf("Invalid -snapshots: must not be negative")
+	}
+	if config.Guardrails.MaxRemovedPercent < 0 || config.Guardrails.MaxRemovedPercent > 100 || config.Guardrails.MaxTraktIDChanges < 0 {
+		log.Fatalf("Invalid guardrails: -max-removed-percent must be between 0 and 100 and -max-trakt-id-changes must not be negative")
+	}
+	if config.Thresholds.MaxNotFound < 0 || config.Thresholds.MaxWarnings < 0 {
+		log.Fatalf("Invalid thresholds: -fail-on-not-found-over and -fail-on-warning-over must not be negative")
+	}
+	if config.Filter.OnlyMalIDs, err = ParseMalIDs(*onlyMalIDs); err != nil {
+		log.Fatalf("Invalid -only-mal-ids: %v", err)
+	}
+	if config.Filter.SkipMalIDs, err = ParseMalIDs(*skipMalIDs); err != nil {
+		log.Fatalf("Invalid -skip-mal-ids: %v", err)
+	}
+	if config.Filter.OnlyType, err = ParseEntryType(*onlyType); err != nil {
+		log.Fatalf("Invalid -only-type: %v", err)
+	}
 
 	return config
 }
 
-// PromptForAPIKey prompts the user for API key
+// errNoAPIKey suggests the non-interactive ways of passing the API key
+var errNoAPIKey = errors.New("no Trakt API key: pass -api-key or -api-key-file, or set TRAKT_API_KEY or TRAKT_API_KEY_FILE")
+
+// parseEnrichments turns -enrich, -no-enrich and -enrich-missing into the
+// set of enrichments to run. -hook commands select the hook enrichment.
+func parseEnrichments(enrich string, noEnrich, enrichMissing bool, hooks []string) (map[string]bool, error) {
+	enrichments := make(map[string]bool)
+	if noEnrich {
+		if enrichMissing {
+			return nil, fmt.Errorf("-enrich-missing has nothing to fill in with -no-enrich")
+		}
+		return enrichments, nil
+	}
+	for _, name := range strings.Split(enrich, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(KnownEnrichments, name) {
+			return nil, fmt.Errorf("Unknown enrichment %q in -enrich (expected %s)", name, strings.Join(KnownEnrichments, ", "))
+		}
+		enrichments[name] = true
+	}
+	if len(hooks) > 0 {
+		enrichments["hook"] = true
+	}
+	if enrichments["hook"] && len(hooks) == 0 {
+		return nil, fmt.Errorf("-enrich hook needs a -hook command")
+	}
+	return enrichments, nil
+}
+
+// PromptForAPIKey prompts for the API key without echoing it. When stdin is
+// not a terminal (piped, or a Windows console term cannot drive) the key is
+// read from its first line instead.
 func PromptForAPIKey() string {
-	fmt.Print("Enter Trakt API key: ")
-	bytePassword, err := term.ReadPassword(int(syscall.Stdin))
+	fd := int(os.Stdin.Fd())
+	key, err := readAPIKeyFrom(os.Stdin, term.IsTerminal(fd), func() ([]byte, error) {
+		fmt.Print("Enter Trakt API key: ")
+		defer fmt.Println()
+		return term.ReadPassword(fd)
+	})
 	if err != nil {
-		log.Fatal("Failed to read API key:", err)
+		log.Fatal(err)
 	}
-	fmt.Println()
-	return string(bytePassword)
+	return key
+}
+
+// readAPIKeyFrom reads the API key with readPassword on a terminal, and
+// otherwise as the first line of in
+func readAPIKeyFrom(in io.Reader, tty bool, readPassword func() ([]byte, error)) (string, error) {
+	var key string
+	if tty {
+		password, err := readPassword()
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key from the terminal (%v); %w", err, errNoAPIKey)
+		}
+		key = string(password)
+	} else {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read API key from stdin (%v); %w", err, errNoAPIKey)
+		}
+		key = line
+	}
+	if key = strings.TrimSpace(key); key == "" {
+		return "", errNoAPIKey
+	}
+	return key, nil
+}
+
+// stringList is a flag.Value that collects repeated string flags
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
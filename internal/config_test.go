@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadAPIKeyFrom(t *testing.T) {
+	noTerminal := func() ([]byte, error) {
+		t.Error("read the terminal with stdin piped")
+		return nil, nil
+	}
+	if key, err := readAPIKeyFrom(strings.NewReader("  key\r\nrest\n"), false, noTerminal); key != "key" || err != nil {
+		t.Errorf("piped = %q, %v; want the first line", key, err)
+	}
+	if key, err := readAPIKeyFrom(strings.NewReader("key"), false, noTerminal); key != "key" || err != nil {
+		t.Errorf("piped without newline = %q, %v", key, err)
+	}
+	if _, err := readAPIKeyFrom(strings.NewReader(""), false, noTerminal); !errors.Is(err, errNoAPIKey) {
+		t.Errorf("empty stdin: err = %v, want errNoAPIKey", err)
+	}
+
+	terminal := func() ([]byte, error) { return []byte("secret"), nil }
+	if key, err := readAPIKeyFrom(strings.NewReader("not this"), true, terminal); key != "secret" || err != nil {
+		t.Errorf("terminal = %q, %v", key, err)
+	}
+	broken := func() ([]byte, error) { return nil, errors.New("The handle is invalid.") }
+	if _, err := readAPIKeyFrom(nil, true, broken); !errors.Is(err, errNoAPIKey) || !strings.Contains(err.Error(), "handle is invalid") {
+		t.Errorf("failed terminal: err = %v, want the cause and the alternatives", err)
+	}
+}
+
+func TestParseEnrichments(t *testing.T) {
+	tests := []struct {
+		name          string
+		enrich        string
+		noEnrich      bool
+		enrichMissing bool
+		hooks         []string
+		want          []string
+		wantErr       bool
+	}{
+		{name: "default", enrich: strings.Join(DefaultEnrichments, ","), want: DefaultEnrichments},
+		{name: "selected", enrich: " letterboxd, simkl ,", want: []string{"letterboxd", "simkl"}},
+		{name: "hook command", enrich: "tmdb", hooks: []string{"./ids.sh"}, want: []string{"tmdb", "hook"}},
+		{name: "no-enrich", enrich: "letterboxd", noEnrich: true, hooks: []string{"./ids.sh"}},
+		{name: "enrich-missing", enrich: "letterboxd", enrichMissing: true, want: []string{"letterboxd"}},
+		{name: "unknown", enrich: "letterboxd,imdb", wantErr: true},
+		{name: "hook without command", enrich: "hook", wantErr: true},
+		{name: "enrich-missing with no-enrich", enrich: "letterboxd", noEnrich: true, enrichMissing: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseEnrichments(tt.enrich, tt.noEnrich, tt.enrichMissing, tt.hooks)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: accepted, want an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: err = %v", tt.name, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: enrichments = %v, want %v", tt.name, got, tt.want)
+		}
+		for _, name := range tt.want {
+			if !got[name] {
+				t.Errorf("%s: enrichments = %v, want %s", tt.name, got, name)
+			}
+		}
+	}
+}
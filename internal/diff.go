@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// EntryChange describes how a single entry differs between two versions of
+// an output file
+type EntryChange struct {
+	MediaType string          `json:"media_type"`
+	Change    string          `json:"change"` // "created", "updated" or "removed"
+	MalID     int             `json:"mal_id"`
+	Title     string          `json:"title"`
+	Old       json.RawMessage `json:"old,omitempty"`
+	New       json.RawMessage `json:"new,omitempty"`
+	RunID     string          `json:"run_id,omitempty"` // set in changelogs
+}
+
+// DiffShows compares two versions of the TV output
+func DiffShows(oldShows, newShows []OutputShow) []EntryChange {
+	oldMap := make(map[int]json.RawMessage)
+	newMap := make(map[int]json.RawMessage)
+	titles := make(map[int]string)
+	for _, show := range oldShows {
+		oldMap[show.MyAnimeList.ID], _ = json.Marshal(show)
+		titles[show.MyAnimeList.ID] = show.MyAnimeList.Title
+	}
+	for _, show := range newShows {
+		newMap[show.MyAnimeList.ID], _ = json.Marshal(show)
+		titles[show.MyAnimeList.ID] = show.MyAnimeList.Title
+	}
+	return diffDocuments("tv", oldMap, newMap, titles)
+}
+
+// DiffMovies compares two versions of the movie output
+func DiffMovies(oldMovies, newMovies []OutputMovie) []EntryChange {
+	oldMap := make(map[int]json.RawMessage)
+	newMap := make(map[int]json.RawMessage)
+	titles := make(map[int]string)
+	for _, movie := range oldMovies {
+		oldMap[movie.MyAnimeList.ID], _ = json.Marshal(movie)
+		titles[movie.MyAnimeList.ID] = movie.MyAnimeList.Title
+	}
+	for _, movie := range newMovies {
+		newMap[movie.MyAnimeList.ID], _ = json.Marshal(movie)
+		titles[movie.MyAnimeList.ID] = movie.MyAnimeList.Title
+	}
+	return diffDocuments("movies", oldMap, newMap, titles)
+}
+
+// diffDocuments compares two MAL ID → JSON document maps, sorted by MAL ID
+func diffDocuments(mediaType string, oldMap, newMap map[int]json.RawMessage, titles map[int]string) []EntryChange {
+	var changes []EntryChange
+	for malID, newDoc := range newMap {
+		oldDoc, existed := oldMap[malID]
+		switch {
+		case !existed:
+			changes = append(changes, EntryChange{MediaType: mediaType, Change: "created", MalID: malID, Title: titles[malID], New: newDoc})
+		case !bytes.Equal(oldDoc, newDoc):
+			changes = append(changes, EntryChange{MediaType: mediaType, Change: "updated", MalID: malID, Title: titles[malID], Old: oldDoc, New: newDoc})
+		}
+	}
+	for malID, oldDoc := range oldMap {
+		if _, exists := newMap[malID]; !exists {
+			changes = append(changes, EntryChange{MediaType: mediaType, Change: "removed", MalID: malID, Title: titles[malID], Old: oldDoc})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].MalID < changes[j].MalID
+	})
+	return changes
+}
@@ -0,0 +1,81 @@
+package internal
+
+import "net/http"
+
+// Enricher adds data from one external source to the entries of a run. It
+// keeps its own rate limiter and cache; enrichers run after Trakt and before
+// overrides, so overrides can still correct what they add.
+type Enricher interface {
+	// Name is how -enrich selects the enricher
+	Name() string
+	// Applies reports whether the entry has what the enricher looks up by
+	Applies(entry EnrichEntry) bool
+	// Enrich updates the entry in place, recording findings in ctx.Stats
+	Enrich(ctx EnrichContext, entry EnrichEntry)
+}
+
+// retainer is implemented by enrichers whose data from the previous output
+// must survive a run they do not enrich in, so a run without them never
+// drops it
+type retainer interface {
+	Retain(entry EnrichEntry)
+}
+
+// EnrichContext is what enrichers share while enriching one media type
+type EnrichContext struct {
+	Client     *http.Client
+	Config     Config
+	Stats      *ProcessingStats
+	letterboxd *letterboxdPhase // movies only
+}
+
+// EnrichEntry is the entry being enriched: Show or Movie, with its previous
+// output when it had one. Reused is set when the entry is its previous
+// output, not fetched from Trakt this run.
+type EnrichEntry struct {
+	Show          *OutputShow
+	ExistingShow  *OutputShow
+	Movie         *OutputMovie
+	ExistingMovie *OutputMovie
+	Reused        bool
+}
+
+// enrichers is the registry, in run order: wikidata and tmdb run first, as
+// the IDs they fill help the lookups after them; hook runs before
+// letterboxd, so a TMDB ID it fills is used there
+var enrichers = []Enricher{
+	wikidataEnricher{},
+	tmdbEnricher{},
+	jikanEnricher{},
+	tvdbEnricher{},
+	simklEnricher{},
+	hookEnricher{},
+	letterboxdEnricher{},
+}
+
+// KnownEnrichments lists the enrichments selectable with -enrich
+var KnownEnrichments = enricherNames()
+
+// DefaultEnrichments are the enrichments run without -enrich. jikan is left
+// out: at Jikan's 60 requests per minute it would dominate a run.
+var DefaultEnrichments = []string{"wikidata", "tmdb", "tvdb", "letterboxd"}
+
+func enricherNames() []string {
+	names := make([]string, len(enrichers))
+	for i, enricher := range enrichers {
+		names[i] = enricher.Name()
+	}
+	return names
+}
+
+// runEnrichers runs the enrichers selected with -enrich that apply to the
+// entry; the others retain what the previous output had
+func runEnrichers(ctx EnrichContext, entry EnrichEntry) {
+	for _, enricher := range enrichers {
+		if ctx.Config.Enrichments[enricher.Name()] && enricher.Applies(entry) {
+			enricher.Enrich(ctx, entry)
+		} else if r, ok := enricher.(retainer); ok {
+			r.Retain(entry)
+		}
+	}
+}
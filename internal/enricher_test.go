@@ -0,0 +1,46 @@
+package internal
+
+import "testing"
+
+// countingEnricher records the entries it enriches
+type countingEnricher struct{ enriched *[]int }
+
+func (countingEnricher) Name() string { return "counting" }
+
+func (countingEnricher) Applies(entry EnrichEntry) bool { return entry.Movie != nil }
+
+func (e countingEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	*e.enriched = append(*e.enriched, entry.Movie.MyAnimeList.ID)
+}
+
+func TestRunEnrichersSelectsAndApplies(t *testing.T) {
+	var enriched []int
+	saved := enrichers
+	enrichers = []Enricher{countingEnricher{&enriched}}
+	t.Cleanup(func() { enrichers = saved })
+
+	movie := &OutputMovie{}
+	movie.MyAnimeList.ID = 43
+	ctx := EnrichContext{Config: Config{Enrichments: map[string]bool{"counting": true}}}
+	runEnrichers(ctx, EnrichEntry{Movie: movie})
+	runEnrichers(ctx, EnrichEntry{Show: &OutputShow{}})
+	runEnrichers(EnrichContext{Config: Config{Enrichments: map[string]bool{}}}, EnrichEntry{Movie: movie})
+	if len(enriched) != 1 || enriched[0] != 43 {
+		t.Errorf("enriched %v, want only the selected movie [43]", enriched)
+	}
+}
+
+func TestRunEnrichersRetainsUnselected(t *testing.T) {
+	slug := "ghost-in-the-shell"
+	existing := &OutputMovie{
+		TMDB:      &TMDBInfo{},
+		Externals: &TraktExternalsMovie{Letterboxd: &Letterboxd{Slug: &slug}},
+	}
+	existing.MyAnimeList.Titles = &MALTitles{}
+	movie := &OutputMovie{Externals: &TraktExternalsMovie{}}
+
+	runEnrichers(EnrichContext{Config: Config{Enrichments: map[string]bool{}}}, EnrichEntry{Movie: movie, ExistingMovie: existing})
+	if movie.TMDB != existing.TMDB || movie.MyAnimeList.Titles != existing.MyAnimeList.Titles || movie.Externals.Letterboxd != existing.Externals.Letterboxd {
+		t.Errorf("a run without enrichments dropped previous data: %+v", movie)
+	}
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+)
+
+// CheckEpisodeCounts cross-checks the MAL episode counts of the shows in
+// resolved with the episode count of their Trakt season. The cours sharing
+// a season (see SetEpisodeOffsets) are counted together. When the counts
+// disagree by much, the wrong season was usually picked: the entries get the
+// episode-count-mismatch quality, which puts them in the review queue, and
+// an episode_mismatch warning each.
+//
+// Seasons with a cour whose MAL count is unknown are not checked; counts
+// come from the jikan enrichment. Shows still airing are only flagged when
+// Trakt has more episodes than MAL, as their Trakt season is not complete
+// yet. Overridden entries are not flagged.
+func CheckEpisodeCounts(shows map[int]OutputShow, resolved []int) []EntryWarning {
+	resolvedSet := make(map[int]bool, len(resolved))
+	for _, malID := range resolved {
+		resolvedSet[malID] = true
+	}
+	var warnings []EntryWarning
+	for _, cours := range seasonCours(shows) {
+		malEpisodes := 0
+		for _, show := range cours {
+			if show.MyAnimeList.Episodes == 0 {
+				malEpisodes = 0
+				break
+			}
+			malEpisodes += show.MyAnimeList.Episodes
+		}
+		seasonNumber, traktEpisodes := cours[0].Trakt.Season.Number, seasonEpisodeCount(cours)
+		if malEpisodes == 0 || traktEpisodes == 0 || !episodeCountsDisagree(malEpisodes, traktEpisodes) {
+			continue
+		}
+		// Trakt lists only the episodes aired so far of an airing season
+		if traktEpisodes < malEpisodes && !slices.ContainsFunc(cours, func(show OutputShow) bool { return showFinished(show.Status) }) {
+			continue
+		}
+
+		reason := fmt.Sprintf("MAL has %d episodes, Trakt season %d has %d", malEpisodes, seasonNumber, traktEpisodes)
+		if len(cours) > 1 {
+			reason = fmt.Sprintf("MAL has %d episodes in the %d entries on Trakt season %d, which has %d",
+				malEpisodes, len(cours), seasonNumber, traktEpisodes)
+		}
+		for _, show := range cours {
+			if show.Quality == QualityOverride || !resolvedSet[show.MyAnimeList.ID] {
+				continue
+			}
+			show.Quality = QualityEpisodeMismatch
+			shows[show.MyAnimeList.ID] = show
+			warnings = append(warnings, EntryWarning{
+				ChangeDetail{MalID: show.MyAnimeList.ID, Title: show.MyAnimeList.Title, Reason: reason},
+				WarningEpisodeMismatch,
+			})
+		}
+	}
+	slices.SortFunc(warnings, func(a, b EntryWarning) int { return a.MalID - b.MalID })
+	return warnings
+}
+
+// episodeCountsDisagree reports whether two episode counts differ by more
+// than a recap or a double-length finale explains: by more than 3 episodes
+// and a quarter of the larger count
+func episodeCountsDisagree(mal, trakt int) bool {
+	diff := mal - trakt
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > 3 && diff*4 > max(mal, trakt)
+}
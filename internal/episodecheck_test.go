@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEpisodeCountsDisagree(t *testing.T) {
+	tests := []struct {
+		mal, trakt int
+		want       bool
+	}{
+		{12, 12, false},
+		{12, 13, false}, // a recap
+		{12, 16, false},
+		{12, 24, true},  // the wrong cour, or two cours in one season
+		{11, 28, true},  // minisodes
+		{64, 70, false}, // a long show with a few extras
+	}
+	for _, tt := range tests {
+		if got := episodeCountsDisagree(tt.mal, tt.trakt); got != tt.want {
+			t.Errorf("episodeCountsDisagree(%d, %d) = %v, want %v", tt.mal, tt.trakt, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEpisodeCounts(t *testing.T) {
+	var list []OutputShow
+	err := json.Unmarshal([]byte(`[
+		{"myanimelist": {"id": 1, "episodes": 16}, "trakt": {"id": 1, "season": {"number": 4, "episode_count": 28}}, "quality": "exact-id"},
+		{"myanimelist": {"id": 2, "episodes": 12}, "trakt": {"id": 1, "season": {"number": 4, "episode_count": 28}}, "quality": "exact-id"},
+		{"myanimelist": {"id": 3, "episodes": 12}, "trakt": {"id": 2, "season": {"number": 1, "episode_count": 25}}, "quality": "exact-id"},
+		{"myanimelist": {"id": 4, "episodes": 12}, "trakt": {"id": 3, "season": {"number": 1, "episode_count": 25}}, "quality": "exact-id"},
+		{"myanimelist": {"id": 5, "episodes": 12}, "trakt": {"id": 4, "season": {"number": 1, "episode_count": 25}}, "quality": "verified-by-override"},
+		{"myanimelist": {"id": 6}, "trakt": {"id": 5, "season": {"number": 1, "episode_count": 25}}, "quality": "exact-id"},
+		{"myanimelist": {"id": 7, "episodes": 24}, "trakt": {"id": 6, "season": {"number": 1, "episode_count": 10}}, "status": "returning series", "quality": "exact-id"},
+		{"myanimelist": {"id": 8, "episodes": 24}, "trakt": {"id": 7, "season": {"number": 1, "episode_count": 10}}, "status": "ended", "quality": "exact-id"}
+	]`), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shows := make(map[int]OutputShow)
+	for _, show := range list {
+		shows[show.MyAnimeList.ID] = show
+	}
+
+	// 4 was not resolved this run, 5 is overridden, 6 has no MAL count and 7
+	// is still airing
+	warnings := CheckEpisodeCounts(shows, []int{1, 2, 3, 5, 6, 7, 8})
+	if len(warnings) != 2 || warnings[0].MalID != 3 || warnings[1].MalID != 8 || warnings[0].Kind != WarningEpisodeMismatch {
+		t.Fatalf("warnings = %+v", warnings)
+	}
+	for malID, want := range map[int]string{1: QualityExactID, 3: QualityEpisodeMismatch, 4: QualityExactID, 5: QualityOverride, 7: QualityExactID} {
+		if got := shows[malID].Quality; got != want {
+			t.Errorf("MAL %d: quality = %q, want %q", malID, got, want)
+		}
+	}
+}
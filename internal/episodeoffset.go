@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SetEpisodeOffsets sets the episode offset of MAL entries that are a later
+// cour of a Trakt season. Trakt often keeps a two-cour anime as one season
+// of 24 episodes where MAL has two entries of 12; the second entry's episode
+// 1 is then Trakt's episode 13, an offset of 12.
+//
+// The entries mapped to one Trakt season are ordered by MAL start date (then
+// MAL ID), and each offset is the sum of the MAL episode counts of the cours
+// before it. An offset is only set while the cours before it leave episodes
+// of the Trakt season over, so none are set when Trakt's count is unknown,
+// and none after a cour whose MAL episode count is unknown; counts come from
+// the jikan enrichment. Overridden entries keep the offset of their override.
+func SetEpisodeOffsets(shows map[int]OutputShow) {
+	for _, cours := range seasonCours(shows) {
+		episodes := make([]int, len(cours))
+		for i, show := range cours {
+			episodes[i] = show.MyAnimeList.Episodes
+		}
+		offsets := courOffsets(episodes, seasonEpisodeCount(cours))
+		for i, show := range cours {
+			if show.Quality == QualityOverride || show.Trakt.Season.EpisodeOffset == offsets[i] {
+				continue
+			}
+			// The season is copied, as the existing entries may share it
+			season := *show.Trakt.Season
+			season.EpisodeOffset = offsets[i]
+			show.Trakt.Season = &season
+			shows[show.MyAnimeList.ID] = show
+		}
+	}
+}
+
+// seasonCours groups the shows mapped to a Trakt season by season, each
+// group ordered by MAL start date, then MAL ID. Entries with parts are left
+// out.
+func seasonCours(shows map[int]OutputShow) [][]OutputShow {
+	type seasonKey struct{ traktID, season int }
+	seasons := make(map[seasonKey][]OutputShow)
+	for _, show := range shows {
+		if show.Trakt.Season == nil || len(show.Parts) > 0 {
+			continue
+		}
+		key := seasonKey{show.Trakt.ID, show.Trakt.Season.Number}
+		seasons[key] = append(seasons[key], show)
+	}
+
+	groups := make([][]OutputShow, 0, len(seasons))
+	for _, cours := range seasons {
+		slices.SortFunc(cours, func(a, b OutputShow) int {
+			return cmp.Or(
+				compareStartDates(a.MyAnimeList.StartDate, b.MyAnimeList.StartDate),
+				cmp.Compare(a.MyAnimeList.ID, b.MyAnimeList.ID),
+			)
+		})
+		groups = append(groups, cours)
+	}
+	return groups
+}
+
+// seasonEpisodeCount returns the Trakt episode count of a group of cours,
+// 0 when unknown. The cours may have been resolved in different runs, so
+// some can lack the count or carry an older, smaller one; the largest wins.
+func seasonEpisodeCount(cours []OutputShow) int {
+	count := 0
+	for _, show := range cours {
+		count = max(count, show.Trakt.Season.EpisodeCount)
+	}
+	return count
+}
+
+// courOffsets returns the episode offset of each cour of a season, in order,
+// from their episode counts; see SetEpisodeOffsets. seasonEpisodes is the
+// Trakt season's episode count, 0 when unknown.
+func courOffsets(episodes []int, seasonEpisodes int) []int {
+	offsets := make([]int, len(episodes))
+	offset := 0
+	for i, count := range episodes {
+		if offset >= seasonEpisodes {
+			break
+		}
+		offsets[i] = offset
+		if count == 0 {
+			break
+		}
+		offset += count
+	}
+	return offsets
+}
+
+// compareStartDates orders YYYY-MM-DD dates, unknown ("") last
+func compareStartDates(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	return cmp.Compare(a, b)
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCourOffsets(t *testing.T) {
+	tests := []struct {
+		name           string
+		episodes       []int
+		seasonEpisodes int
+		want           []int
+	}{
+		{"two cours", []int{12, 12}, 24, []int{0, 12}},
+		{"three cours, last airing", []int{12, 13, 11}, 30, []int{0, 12, 25}},
+		{"airing cour, count unknown", []int{12, 0, 12}, 36, []int{0, 12, 0}},
+		{"earlier cours fill the season", []int{24, 12}, 24, []int{0, 0}},
+		{"Trakt count unknown", []int{12, 12}, 0, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		if got := courOffsets(tt.episodes, tt.seasonEpisodes); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: courOffsets(%v, %d) = %v, want %v", tt.name, tt.episodes, tt.seasonEpisodes, got, tt.want)
+		}
+	}
+}
+
+func TestSetEpisodeOffsets(t *testing.T) {
+	var list []OutputShow
+	err := json.Unmarshal([]byte(`[
+		{"myanimelist": {"id": 40028, "episodes": 16, "start_date": "2020-12-07"}, "trakt": {"id": 1, "season": {"number": 4, "episode_count": 28}}},
+		{"myanimelist": {"id": 48583, "episodes": 12, "start_date": "2022-01-10"}, "trakt": {"id": 1, "season": {"number": 4, "episode_count": 28}}},
+		{"myanimelist": {"id": 16498, "episodes": 25, "start_date": "2013-04-07"}, "trakt": {"id": 1, "season": {"number": 1, "episode_count": 25}}},
+		{"myanimelist": {"id": 5, "episodes": 12}, "trakt": {"id": 2, "season": {"number": 1, "episode_count": 12, "episode_offset": 3}}, "quality": "verified-by-override"},
+		{"myanimelist": {"id": 6, "episodes": 12, "start_date": "2021-01-01"}, "trakt": {"id": 3, "season": {"number": 1}}},
+		{"myanimelist": {"id": 7, "episodes": 12, "start_date": "2021-04-01"}, "trakt": {"id": 3, "season": {"number": 1, "episode_count": 24}}}
+	]`), &list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shows := make(map[int]OutputShow)
+	for _, show := range list {
+		shows[show.MyAnimeList.ID] = show
+	}
+	before := shows[48583].Trakt.Season
+
+	SetEpisodeOffsets(shows)
+	for malID, want := range map[int]int{40028: 0, 48583: 16, 16498: 0, 5: 3, 6: 0, 7: 12} {
+		if got := shows[malID].Trakt.Season.EpisodeOffset; got != want {
+			t.Errorf("MAL %d: episode_offset = %d, want %d", malID, got, want)
+		}
+	}
+	if before.EpisodeOffset != 0 {
+		t.Error("the season of the input entry was modified, not copied")
+	}
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// APIError is a failed API request: the status it ended with, and the
+// retries RetryWithBackoff spent on rate limiting before giving up
+type APIError struct {
+	Endpoint string // host and path, without the query (it may hold keys)
+	Status   int    // 0 when no response arrived
+	Retries  int
+	Err      error
+}
+
+func (e *APIError) Error() string { return e.Err.Error() }
+
+func (e *APIError) Unwrap() error { return e.Err }
+
+// requestEndpoint names a request's endpoint for an APIError
+func requestEndpoint(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+	return req.URL.Host + req.URL.Path
+}
+
+// statusError wraps err, returned for resp's unexpected status, in an APIError
+func statusError(resp *http.Response, err error) error {
+	return &APIError{Endpoint: requestEndpoint(resp.Request), Status: resp.StatusCode, Err: err}
+}
+
+// Error categories of an EntryError
+const (
+	ErrorRateLimit = "rate_limit" // still 429/403 after retries
+	ErrorServer    = "server"     // 5xx
+	ErrorClient    = "client"     // other unexpected status
+	ErrorNetwork   = "network"    // no response: timeout, DNS, connection reset
+	ErrorDecode    = "decode"     // response was not the expected JSON
+	ErrorOther     = "other"
+)
+
+// EntryError is one failure while processing an entry, as written to the
+// -errors-file report
+type EntryError struct {
+	Source   string `json:"source"` // integration: trakt, tmdb, tvdb, wikidata, jikan
+	MalID    int    `json:"mal_id,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category"`
+	Endpoint string `json:"endpoint,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Retries  int    `json:"retries"`
+	Message  string `json:"message"`
+}
+
+// ErrorReport is the -errors-file artifact of a run
+type ErrorReport struct {
+	GeneratedAt string         `json:"generated_at"`
+	Counts      map[string]int `json:"counts"` // by category
+	Errors      []EntryError   `json:"errors"`
+}
+
+// ErrorLog collects the per-entry errors of a run. A nil ErrorLog records
+// nothing, so callers need not check whether -errors-file is set.
+type ErrorLog struct {
+	mu     sync.Mutex
+	errors []EntryError
+}
+
+// NewErrorLog returns an empty error log
+func NewErrorLog() *ErrorLog {
+	return &ErrorLog{}
+}
+
+// Record adds an error met while processing an entry. malID and title may be
+// zero when the failing lookup is not tied to one entry.
+func (l *ErrorLog) Record(source string, malID int, title string, err error) {
+	if l == nil || err == nil {
+		return
+	}
+	entry := EntryError{
+		Source:   source,
+		MalID:    malID,
+		Title:    title,
+		Category: errorCategory(err),
+		Message:  err.Error(),
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		entry.Endpoint, entry.Status, entry.Retries = apiErr.Endpoint, apiErr.Status, apiErr.Retries
+	}
+	var urlErr *url.Error
+	if entry.Endpoint == "" && errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			entry.Endpoint = u.Host + u.Path
+		}
+	}
+	l.mu.Lock()
+	l.errors = append(l.errors, entry)
+	l.mu.Unlock()
+}
+
+// errorCategory classifies an error for the report
+func errorCategory(err error) string {
+	var apiErr *APIError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return ErrorRateLimit
+	case errors.As(err, &apiErr) && apiErr.Status >= 500:
+		return ErrorServer
+	case errors.As(err, &apiErr) && apiErr.Status > 0:
+		return ErrorClient
+	case errors.As(err, &netErr):
+		return ErrorNetwork
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrorDecode
+	}
+	return ErrorOther
+}
+
+// Write saves the report to path, sorted by source and MAL ID. It is written
+// even when empty, so CI always has an artifact to upload.
+func (l *ErrorLog) Write(path string) error {
+	if l == nil || path == "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report := ErrorReport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Counts:      map[string]int{},
+		Errors:      append([]EntryError{}, l.errors...),
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		if report.Errors[i].Source != report.Errors[j].Source {
+			return report.Errors[i].Source < report.Errors[j].Source
+		}
+		return report.Errors[i].MalID < report.Errors[j].MalID
+	})
+	for _, entry := range report.Errors {
+		report.Counts[entry.Category]++
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveJSON(path, report)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestErrorLogWrite(t *testing.T) {
+	log := NewErrorLog()
+	log.Record("tmdb", 20, "Naruto", &APIError{Endpoint: "api.themoviedb.org/3/tv/46260", Status: 502, Err: errors.New("API error: 502")})
+	log.Record("trakt", 5114, "Fullmetal Alchemist: Brotherhood", fmt.Errorf("show: %w", &APIError{
+		Endpoint: "api.trakt.tv/shows/11295", Status: 429, Retries: 5, Err: fmt.Errorf("%w (429)", ErrRateLimited),
+	}))
+	log.Record("wikidata", 1, "", &url.Error{Op: "Get", URL: "https://query.wikidata.org/sparql?query=x", Err: timeoutError{}})
+	log.Record("jikan", 2, "", nil)
+
+	path := filepath.Join(t.TempDir(), "logs", "errors.json")
+	if err := log.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	var report ErrorReport
+	if err := LoadJSON(path, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Errors) != 3 {
+		t.Fatalf("got %d errors, want 3 (nil errors are not recorded)", len(report.Errors))
+	}
+	want := []EntryError{
+		{Source: "tmdb", Category: ErrorServer, Endpoint: "api.themoviedb.org/3/tv/46260", Status: 502},
+		{Source: "trakt", Category: ErrorRateLimit, Endpoint: "api.trakt.tv/shows/11295", Status: 429, Retries: 5},
+		{Source: "wikidata", Category: ErrorNetwork, Endpoint: "query.wikidata.org/sparql"},
+	}
+	for i, w := range want {
+		got := report.Errors[i]
+		if got.Source != w.Source || got.Category != w.Category || got.Endpoint != w.Endpoint || got.Status != w.Status || got.Retries != w.Retries {
+			t.Errorf("error %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if report.Counts[ErrorServer] != 1 || report.Counts[ErrorRateLimit] != 1 || report.Counts[ErrorNetwork] != 1 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+}
+
+func TestNilErrorLog(t *testing.T) {
+	var log *ErrorLog
+	log.Record("trakt", 1, "", errors.New("boom"))
+	if err := log.Write(filepath.Join(t.TempDir(), "errors.json")); err != nil {
+		t.Fatal(err)
+	}
+}
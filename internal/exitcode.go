@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of a processing run, so wrappers can tell a run that saved
+// partial results from one that saved nothing
+const (
+	ExitOK          = 0 // every entry processed (or left for a budget)
+	ExitFatal       = 1 // bad flags, unreadable input or unwritable output
+	ExitPartial     = 2 // results saved, but some entries failed with errors other than 404
+	ExitRateLimited = 3 // results saved, but the run stopped because Trakt kept rate limiting
+	ExitValidation  = 4 // an input file failed validation; nothing was processed
+	ExitGuardrail   = 5 // the run's changes exceeded -max-removed-percent or -max-trakt-id-changes; nothing was written
+	ExitDrift       = 6 // verify-live found more drifted mappings than -max-drift-percent allows
+	ExitThreshold   = 7 // results saved, but the run's stats exceeded -fail-on-not-found-over or -fail-on-warning-over
+)
+
+// ExitError is an error that ends the process with Code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// exitErrorf formats an ExitError
+func exitErrorf(code int, format string, args ...any) error {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for a run error: ExitOK for nil, ExitFatal
+// for errors that carry no code
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitFatal
+}
+
+// SavedResults reports whether a run ending in err still saved its results
+func SavedResults(err error) bool {
+	switch ExitCode(err) {
+	case ExitOK, ExitPartial, ExitRateLimited, ExitThreshold:
+		return true
+	}
+	return false
+}
+
+// runResult is the error a finished run returns for its failures
+func runResult(mediaType string, failed int, stoppedEarly string, rateLimited bool) error {
+	if rateLimited {
+		return exitErrorf(ExitRateLimited, "%s: %s", mediaType, stoppedEarly)
+	}
+	if failed > 0 {
+		return exitErrorf(ExitPartial, "%s: %d entries failed", mediaType, failed)
+	}
+	return nil
+}
+
+// WorseError returns whichever error has the higher exit code, so a run that
+// processes several inputs exits with its worst outcome
+func WorseError(a, b error) error {
+	if ExitCode(b) > ExitCode(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	partial := runResult("shows", 2, "", false)
+	limited := runResult("movies", 0, "Trakt kept rate limiting", true)
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{runResult("shows", 0, "-max-new 5 reached", false), ExitOK},
+		{errors.New("disk full"), ExitFatal},
+		{partial, ExitPartial},
+		{fmt.Errorf("wrapped: %w", limited), ExitRateLimited},
+		{WorseError(partial, limited), ExitRateLimited},
+		{WorseError(nil, partial), ExitPartial},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestProcessShowsRejectsMovieInput(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "movies.json")
+	os.WriteFile(input, []byte(`[{"title": "Akira", "mal_id": 47, "trakt_id": 1, "type": "movies"}]`), 0644)
+
+	err := ProcessShows(Config{TvFiles: []string{input}})
+	if ExitCode(err) != ExitValidation {
+		t.Errorf("ProcessShows(movie input) = %v, want an ExitValidation error", err)
+	}
+}
@@ -2,32 +2,214 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
-// LoadJSON loads JSON from a file, fatal on error
-func LoadJSON(filename string, v interface{}) {
-	file, err := os.Open(filename)
+// StdioPath as an input or output path means stdin or stdout
+const StdioPath = "-"
+
+// stdout receives output written to StdioPath. It is the process's real
+// stdout even after RedirectStdout.
+var stdout = os.Stdout
+
+// RedirectStdout sends everything else printed to stdout (progress, verbose
+// output, summaries) to stderr, leaving stdout to the results
+func RedirectStdout() {
+	os.Stdout = os.Stderr
+}
+
+// isURL reports whether an input path is an http(s) URL
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// openInput opens an input path: a file, stdin for StdioPath, or an http(s)
+// URL, which is downloaded afresh on every call
+func openInput(path string) (io.ReadCloser, error) {
+	switch {
+	case path == StdioPath:
+		return io.NopCloser(os.Stdin), nil
+	case isURL(path):
+		return fetchURL(NewHTTPClient(5*time.Minute), path)
+	}
+	return os.Open(path)
+}
+
+// LoadJSON loads JSON from a file, stdin (StdioPath) or an http(s) URL
+func LoadJSON(filename string, v interface{}) error {
+	file, err := openInput(filename)
 	if err != nil {
-		log.Fatalf("Failed to open file %s: %v", filename, err)
+		return fmt.Errorf("failed to open file %s: %w", filename, err)
 	}
 	defer file.Close()
 
 	bytes, err := io.ReadAll(file)
 	if err != nil {
-		log.Fatalf("Failed to read file %s: %v", filename, err)
+		return fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
 
 	if err := json.Unmarshal(bytes, v); err != nil {
-		log.Fatalf("Failed to unmarshal JSON from %s: %v", filename, err)
+		return fmt.Errorf("failed to unmarshal JSON from %s: %w", filename, err)
+	}
+	return nil
+}
+
+// ExpandInputs resolves -tv / -movies values into file paths. Values with
+// glob characters are expanded (and must match something); plain paths,
+// URLs and source schemes (anitrakt:, anitrakt-site:) are kept as given. Paths listed twice are only returned once.
+func ExpandInputs(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if isURL(pattern) || isSchemeInput(pattern) || !strings.ContainsAny(pattern, "*?[") {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", pattern)
+		}
+		files = append(files, matches...) // already sorted
+	}
+	var unique []string
+	for _, file := range files {
+		if !slices.Contains(unique, file) {
+			unique = append(unique, file)
+		}
+	}
+	return unique, nil
+}
+
+// inputEntry is an input show or movie
+type inputEntry[T any] interface {
+	InputShow | InputMovie
+	inputKey() [3]int
+	withCleanTitle() T
+}
+
+// inputEntries loads the entries of T's media type from file's InputSource
+func inputEntries[T inputEntry[T]](file string) ([]T, error) {
+	var zero T
+	mediaType := "tv"
+	if _, ok := any(zero).(InputMovie); ok {
+		mediaType = "movies"
+	}
+	shows, movies, err := NewInputSource(file, mediaType).Load()
+	if err != nil {
+		return nil, err
+	}
+	if mediaType == "movies" {
+		return any(movies).([]T), nil
+	}
+	return any(shows).([]T), nil
+}
+
+// inputKey identifies a show mapping: the same MAL ID, Trakt ID and season
+// in two input files is one entry
+func (s InputShow) inputKey() [3]int { return [3]int{s.MalID, s.TraktID, s.Season} }
+
+// inputKey identifies a movie mapping
+func (m InputMovie) inputKey() [3]int { return [3]int{m.MalID, m.TraktID, 0} }
+
+// LoadInputs loads and merges inputs through their InputSource, keeping the
+// first copy of entries repeated across inputs. check is called with each
+// input's entries before they are merged.
+func LoadInputs[T inputEntry[T]](files []string, check func(file string, entries []T) error) ([]T, error) {
+	var merged []T
+	seen := make(map[[3]int]bool)
+	for _, file := range files {
+		entries, err := inputEntries[T](file)
+		if err != nil {
+			return nil, err
+		}
+		if err := check(file, entries); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if key := entry.inputKey(); !seen[key] {
+				seen[key] = true
+				merged = append(merged, entry.withCleanTitle())
+			}
+		}
+	}
+	return merged, nil
+}
+
+// DefaultOutputFile names the output of an input in the output directory
+// (json/output): {input}_ex.json for a single file, {name}_ex.json when
+// several are merged or the input is stdin or an AniTrakt source
+func DefaultOutputFile(files []string, name string) string {
+	return filepath.Join(layout.OutputDir(), outputName(files, name)+"_ex.json")
+}
+
+// outputName is the name of the output of files: the input file's name for
+// a single file, otherwise name
+func outputName(files []string, name string) string {
+	if len(files) == 1 && files[0] != StdioPath && !isSchemeInput(files[0]) {
+		name = strings.TrimSuffix(filepath.Base(files[0]), ".json")
+	}
+	return name
+}
+
+// outputTemplateVar matches the variables of an -output-template
+var outputTemplateVar = regexp.MustCompile(`\{[^{}]*\}`)
+
+// CheckOutputTemplate reports variables of an -output-template other than
+// {type}, {name}, {date} and {run_id}
+func CheckOutputTemplate(template string) error {
+	for _, v := range outputTemplateVar.FindAllString(template, -1) {
+		switch v {
+		case "{type}", "{name}", "{date}", "{run_id}":
+		default:
+			return fmt.Errorf("unknown variable %s (expected {type}, {name}, {date} or {run_id})", v)
+		}
+	}
+	return nil
+}
+
+// ExpandOutputTemplate expands an -output-template: {type} is tv or movies,
+// {name} the name DefaultOutputFile would use, {date} the UTC date the run
+// started (YYYYMMDD) and {run_id} the run ID
+func ExpandOutputTemplate(template, mediaType, name, runID string) string {
+	if runID == "" {
+		runID = NewRunID(time.Now())
 	}
+	return strings.NewReplacer(
+		"{type}", mediaType,
+		"{name}", name,
+		"{date}", runID[:len("20060102")],
+		"{run_id}", runID,
+	).Replace(template)
 }
 
-// LoadJSONOptional loads JSON from a file, silent on error
+// outputFileFor returns the output file of a run of mediaType over files:
+// -output, else -output-template, else DefaultOutputFile
+func outputFileFor(config Config, files []string, mediaType string) string {
+	switch {
+	case config.OutputFile != "":
+		return config.OutputFile
+	case config.OutputTemplate != "":
+		return ExpandOutputTemplate(config.OutputTemplate, mediaType, outputName(files, mediaType), config.RunID)
+	}
+	return DefaultOutputFile(files, mediaType)
+}
+
+// LoadJSONOptional loads JSON from a file, silent on error. StdioPath counts
+// as a missing file.
 func LoadJSONOptional(filename string, v interface{}) {
+	if filename == StdioPath {
+		return
+	}
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return
 	}
@@ -50,23 +232,38 @@ func LoadJSONOptional(filename string, v interface{}) {
 	}
 }
 
-// SaveJSON saves data to a JSON file
-func SaveJSON(filename string, v interface{}) {
+// SaveJSON saves data to a JSON file, or to stdout for StdioPath
+func SaveJSON(filename string, v interface{}) error {
 	bytes, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal data for %s: %v", filename, err)
+		return fmt.Errorf("failed to marshal data for %s: %w", filename, err)
+	}
+
+	if filename == StdioPath {
+		if _, err := stdout.Write(append(bytes, '\n')); err != nil {
+			return fmt.Errorf("failed to write to stdout: %w", err)
+		}
+		return nil
 	}
 
+	// The data directories need not exist outside the repository checkout
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", filename, err)
+	}
 	if err := os.WriteFile(filename, bytes, 0644); err != nil {
-		log.Fatalf("Failed to write to file %s: %v", filename, err)
+		return fmt.Errorf("failed to write to file %s: %w", filename, err)
 	}
+	return nil
 }
 
-// LoadNotFound loads the not found entries for an output file
+// LoadNotFound loads the not found entries for an output file. Output to
+// stdout has no not-found list.
 func LoadNotFound(outputFile string) map[int]bool {
-	notExistFile := filepath.Join("json/not_found", "not_exist_"+filepath.Base(outputFile))
+	if outputFile == StdioPath {
+		return make(map[int]bool)
+	}
 	var notExist []NotFoundEntry
-	LoadJSONOptional(notExistFile, &notExist)
+	LoadJSONOptional(notFoundPath(outputFile), &notExist)
 	notExistMap := make(map[int]bool)
 	for _, entry := range notExist {
 		notExistMap[entry.MalID] = true
@@ -74,51 +271,42 @@ func LoadNotFound(outputFile string) map[int]bool {
 	return notExistMap
 }
 
-// SaveNotFound saves not found entries
-func SaveNotFound(outputFile string, newNotExist []NotFoundEntry, notExistMap map[int]bool) {
-	if len(newNotExist) > 0 {
-		notExistFile := filepath.Join("json/not_found", "not_exist_"+filepath.Base(outputFile))
+// SaveNotFound saves not found entries, dropping the healed ones that were
+// found on Trakt after all
+func SaveNotFound(outputFile string, newNotExist []NotFoundEntry, notExistMap map[int]bool, healed map[int]bool) error {
+	if (len(newNotExist) > 0 || len(healed) > 0) && outputFile != StdioPath {
+		notExistFile := notFoundPath(outputFile)
 		var existingNotExist []NotFoundEntry
 		LoadJSONOptional(notExistFile, &existingNotExist)
+		existingNotExist = slices.DeleteFunc(existingNotExist, func(entry NotFoundEntry) bool {
+			return healed[entry.MalID]
+		})
 		for _, entry := range newNotExist {
 			if !notExistMap[entry.MalID] {
 				existingNotExist = append(existingNotExist, entry)
 			}
 		}
-		SaveJSON(notExistFile, existingNotExist)
+		return SaveJSON(notExistFile, existingNotExist)
 	}
+	return nil
 }
 
-// SaveResults saves show results to file
-func SaveResults(outputFile string, resultsMap map[int]OutputShow) {
+// SaveResults saves show results to file in the given order
+func SaveResults(outputFile string, resultsMap map[int]OutputShow, order SortOrder) error {
 	results := make([]OutputShow, 0, len(resultsMap))
 	for _, show := range resultsMap {
 		results = append(results, show)
 	}
-	// Sort by MAL ID
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[i].MyAnimeList.ID > results[j].MyAnimeList.ID {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
-	SaveJSON(outputFile, results)
+	SortEntries(results, order)
+	return SaveJSON(outputFile, results)
 }
 
-// SaveMovieResults saves movie results to file
-func SaveMovieResults(outputFile string, resultsMap map[int]OutputMovie) {
+// SaveMovieResults saves movie results to file in the given order
+func SaveMovieResults(outputFile string, resultsMap map[int]OutputMovie, order SortOrder) error {
 	results := make([]OutputMovie, 0, len(resultsMap))
 	for _, movie := range resultsMap {
 		results = append(results, movie)
 	}
-	// Sort by MAL ID
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[i].MyAnimeList.ID > results[j].MyAnimeList.ID {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
-	SaveJSON(outputFile, results)
+	SortEntries(results, order)
+	return SaveJSON(outputFile, results)
 }
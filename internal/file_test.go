@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal/trakttest"
+)
+
+func TestLoadInputsMergesGlobs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("tv_2024.json", `[{"mal_id":1,"trakt_id":10,"season":1,"type":"shows"},{"mal_id":2,"trakt_id":20,"season":1,"type":"shows"}]`)
+	write("tv_2025.json", `[{"mal_id":2,"trakt_id":20,"season":1,"type":"shows"},{"mal_id":2,"trakt_id":20,"season":2,"type":"shows"}]`)
+
+	files, err := ExpandInputs([]string{filepath.Join(dir, "tv_*.json"), filepath.Join(dir, "tv_2024.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(dir, "tv_2024.json"), filepath.Join(dir, "tv_2025.json")}; !slices.Equal(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	if _, err := ExpandInputs([]string{filepath.Join(dir, "movies_*.json")}); err == nil {
+		t.Error("a glob matching nothing was accepted")
+	}
+
+	checked := 0
+	shows, err := LoadInputs(files, func(string, []InputShow) error { checked++; return nil })
+	if err != nil || checked != 2 || len(shows) != 3 {
+		t.Errorf("checked %d files, merged %d shows; want 2 and 3", checked, len(shows))
+	}
+
+	if got := DefaultOutputFile(files, "tv"); got != filepath.Join("json/output", "tv_ex.json") {
+		t.Errorf("DefaultOutputFile(merged) = %s", got)
+	}
+	if got := DefaultOutputFile(files[:1], "tv"); got != filepath.Join("json/output", "tv_2024_ex.json") {
+		t.Errorf("DefaultOutputFile(single) = %s", got)
+	}
+}
+
+func TestOutputTemplate(t *testing.T) {
+	config := Config{OutputTemplate: "json/output/{type}_{name}_{date}.json", RunID: "20261016T050000Z"}
+	if got := outputFileFor(config, []string{"json/input/tv_2024.json"}, "tv"); got != "json/output/tv_tv_2024_20261016.json" {
+		t.Errorf("outputFileFor(template) = %s", got)
+	}
+	if got := ExpandOutputTemplate("{run_id}.json", "movies", "movies", config.RunID); got != "20261016T050000Z.json" {
+		t.Errorf("ExpandOutputTemplate({run_id}) = %s", got)
+	}
+	config.OutputFile = "out.json"
+	if got := outputFileFor(config, nil, "tv"); got != "out.json" {
+		t.Errorf("outputFileFor(-output) = %s, want -output to win", got)
+	}
+	if err := CheckOutputTemplate("json/{type}_{when}.json"); err == nil {
+		t.Error("CheckOutputTemplate accepted {when}")
+	}
+}
+
+func TestSaveNotFoundDropsHealed(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("json/not_found", 0755)
+	output := "json/output/tv_ex.json"
+	SaveJSON(notFoundPath(output), []NotFoundEntry{{MalID: 1}, {MalID: 2}})
+
+	SaveNotFound(output, []NotFoundEntry{{MalID: 3}}, LoadNotFound(output), map[int]bool{1: true})
+
+	var notFound []NotFoundEntry
+	LoadJSON(notFoundPath(output), &notFound)
+	if len(notFound) != 2 || notFound[0].MalID != 2 || notFound[1].MalID != 3 {
+		t.Errorf("not-found list = %+v, want MAL IDs 2 and 3", notFound)
+	}
+}
+
+func TestStdioRunKeepsStdoutToResults(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { layout = DefaultLayout() })
+	layout = DefaultLayout()
+
+	server := trakttest.NewServer(t)
+	server.Show(30857, "cowboy-bebop", map[string]any{"title": "Cowboy Bebop", "year": 1998, "ids": map[string]any{"trakt": 30857, "slug": "cowboy-bebop"}})
+	server.Seasons(30857, []map[string]any{{"number": 1, "episode_count": 26, "ids": map[string]any{"trakt": 1}}})
+
+	open := func(name, body string) *os.File {
+		t.Helper()
+		os.WriteFile(name, []byte(body), 0644)
+		file, err := os.OpenFile(name, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { file.Close() })
+		return file
+	}
+	in := open("stdin", `[{"title": "Cowboy Bebop", "mal_id": 1, "trakt_id": 30857, "season": 1, "type": "shows"}]`)
+	out := open("stdout", "")
+	errOut := open("stderr", "")
+
+	oldStdin, oldStdout, oldStderr, oldResults := os.Stdin, os.Stdout, os.Stderr, stdout
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout, os.Stderr, stdout = oldStdin, oldStdout, oldStderr, oldResults
+		SetLogLevel(LogInfo)
+	})
+	os.Stdin, os.Stdout, os.Stderr, stdout = in, out, errOut, out
+	SetLogLevel(LogDebug)
+	RedirectStdout()
+
+	config := traktTestConfig(t, server)
+	config.TvFiles = []string{StdioPath}
+	config.OutputFile = StdioPath
+	config.Verbose = true
+	if err := ProcessShows(config); err != nil {
+		t.Fatalf("ProcessShows = %v", err)
+	}
+	log.Printf("Warning: a log line")
+
+	data, _ := os.ReadFile("stdout")
+	var shows []OutputShow
+	if err := json.Unmarshal(data, &shows); err != nil {
+		t.Fatalf("stdout is not only the results: %v\n%s", err, data)
+	}
+	if len(shows) != 1 || shows[0].Trakt.ID != 30857 {
+		t.Errorf("results = %+v, want Cowboy Bebop parsed from stdin", shows)
+	}
+	logged, _ := os.ReadFile("stderr")
+	for _, want := range []string{"Processing show", "Summary", "Warning: a log line"} {
+		if !strings.Contains(string(logged), want) {
+			t.Errorf("stderr lacks %q:\n%s", want, logged)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntryFilter narrows a run down to some input entries (-only-mal-ids,
+// -skip-mal-ids, -only-year, -only-type). Entries it excludes are left as
+// they are in the output.
+type EntryFilter struct {
+	OnlyMalIDs map[int]bool // nil = any
+	SkipMalIDs map[int]bool
+	OnlyYear   int    // 0 = any
+	OnlyType   string // "shows", "movies" or "" for any
+}
+
+// Selective reports whether the filter picks entries out, rather than only
+// skipping some. Picked entries are re-processed as with -force.
+func (f EntryFilter) Selective() bool {
+	return f.OnlyMalIDs != nil || f.OnlyYear != 0 || f.OnlyType != ""
+}
+
+// Match reports whether an entry passes the filter. year is the release year
+// recorded in the existing output, 0 when the entry has none yet.
+func (f EntryFilter) Match(malID int, mediaType string, year int) bool {
+	if f.SkipMalIDs[malID] {
+		return false
+	}
+	if f.OnlyMalIDs != nil && !f.OnlyMalIDs[malID] {
+		return false
+	}
+	if f.OnlyYear != 0 && year != f.OnlyYear {
+		return false
+	}
+	return f.OnlyType == "" || f.OnlyType == mediaType
+}
+
+// ParseMalIDs parses a comma-separated list of MAL IDs, or returns nil for an
+// empty list
+func ParseMalIDs(s string) (map[int]bool, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	ids := make(map[int]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid MAL ID %q", field)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// ParseEntryType maps an -only-type value to the input type it selects. The
+// input only tells shows and movies apart; MAL's finer types (ona, ova,
+// special...) are not recorded, so they are rejected.
+func ParseEntryType(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return "", nil
+	case "tv", "shows":
+		return "shows", nil
+	case "movie", "movies":
+		return "movies", nil
+	}
+	return "", fmt.Errorf("unsupported type %q (expected tv or movie; the input does not record MAL media types such as ona)", s)
+}
@@ -0,0 +1,41 @@
+package internal
+
+import "testing"
+
+func TestEntryFilterMatch(t *testing.T) {
+	only, err := ParseMalIDs("1, 5,20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skip, _ := ParseMalIDs("5")
+	filter := EntryFilter{OnlyMalIDs: only, SkipMalIDs: skip, OnlyYear: 2024, OnlyType: "shows"}
+
+	tests := []struct {
+		malID     int
+		mediaType string
+		year      int
+		want      bool
+	}{
+		{1, "shows", 2024, true},
+		{5, "shows", 2024, false},  // skipped
+		{2, "shows", 2024, false},  // not listed
+		{20, "shows", 2023, false}, // other year
+		{20, "shows", 0, false},    // year unknown
+		{20, "movies", 2024, false},
+	}
+	for _, tt := range tests {
+		if got := filter.Match(tt.malID, tt.mediaType, tt.year); got != tt.want {
+			t.Errorf("Match(%d, %q, %d) = %v, want %v", tt.malID, tt.mediaType, tt.year, got, tt.want)
+		}
+	}
+
+	if !(EntryFilter{}).Match(2, "movies", 0) || (EntryFilter{SkipMalIDs: skip}).Selective() {
+		t.Error("empty filter should match everything, -skip-mal-ids alone should not be selective")
+	}
+	if _, err := ParseMalIDs("1,x"); err == nil {
+		t.Error("ParseMalIDs accepted a non-numeric ID")
+	}
+	if _, err := ParseEntryType("ona"); err == nil {
+		t.Error("ParseEntryType accepted ona")
+	}
+}
@@ -3,10 +3,10 @@ package internal
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -37,8 +37,8 @@ func LoadAnimeAPITSV(path string) (anidbToMAL map[int]int, malToRow map[int]Anim
 		r = f
 	} else {
 		// Fall back to remote — lowercase path is required by the Vercel function
-		fmt.Println("Fetching AnimeAPI TSV from animeapi.my.id …")
-		resp, httpErr := http.Get("https://animeapi.my.id/animeapi.tsv")
+		Infof("Fetching AnimeAPI TSV from animeapi.my.id …\n")
+		resp, httpErr := NewHTTPClient(5 * time.Minute).Get("https://animeapi.my.id/animeapi.tsv")
 		if httpErr != nil {
 			return nil, nil, fmt.Errorf("fetch animeapi tsv: %w", httpErr)
 		}
@@ -85,6 +85,12 @@ func LoadAnimeAPITSV(path string) (anidbToMAL map[int]int, malToRow map[int]Anim
 		if cols.title >= 0 && cols.title < len(fields) {
 			row.Title = strings.TrimSpace(fields[cols.title])
 		}
+		if cols.anilist >= 0 && cols.anilist < len(fields) {
+			row.AniList = parseInt(fields[cols.anilist])
+		}
+		if cols.kitsu >= 0 && cols.kitsu < len(fields) {
+			row.Kitsu = parseInt(fields[cols.kitsu])
+		}
 		if cols.trakt >= 0 && cols.trakt < len(fields) {
 			row.TraktID = parseInt(fields[cols.trakt])
 		}
@@ -131,8 +137,8 @@ func LoadFribbJSON(path string) ([]FribbEntry, error) {
 		defer f.Close()
 		r = f
 	} else {
-		fmt.Println("Fetching Fribb anime-lists-reduced.json from GitHub …")
-		resp, err := http.Get("https://raw.githubusercontent.com/Fribb/anime-lists/master/anime-lists-reduced.json")
+		Infof("Fetching Fribb anime-lists-reduced.json from GitHub …\n")
+		resp, err := NewHTTPClient(5 * time.Minute).Get("https://raw.githubusercontent.com/Fribb/anime-lists/master/anime-lists-reduced.json")
 		if err != nil {
 			return nil, fmt.Errorf("fetch fribb json: %w", err)
 		}
@@ -177,29 +183,37 @@ func isMovieTitle(title string) bool {
 //  4. Drops entries whose MAL ID is already present in the existing output files
 //  5. For each remaining entry, searches Trakt by the resolved external ID
 //  6. Merges new results into the existing output files
-func ProcessFribb(config Config) {
+func ProcessFribb(config Config) error {
 	// --- 1. Load Fribb data --------------------------------------------------
 	fribbEntries, err := LoadFribbJSON(config.FribbFile)
 	if err != nil {
-		log.Fatalf("Failed to load Fribb data: %v", err)
+		return fmt.Errorf("failed to load Fribb data: %w", err)
 	}
-	fmt.Printf("Loaded %d entries from Fribb anime-lists\n", len(fribbEntries))
+	Infof("Loaded %d entries from Fribb anime-lists\n", len(fribbEntries))
 
 	// --- 2. Load AnimeAPI TSV ------------------------------------------------
 	anidbToMAL, malToRow, err := LoadAnimeAPITSV(config.AnimeAPIFile)
 	if err != nil {
-		log.Fatalf("Failed to load AnimeAPI TSV: %v", err)
+		return fmt.Errorf("failed to load AnimeAPI TSV: %w", err)
 	}
-	fmt.Printf("Loaded %d AniDB→MAL mappings from AnimeAPI\n", len(anidbToMAL))
+	Infof("Loaded %d AniDB→MAL mappings from AnimeAPI\n", len(anidbToMAL))
 
 	// --- 3. Load existing output files ---------------------------------------
-	tvOutputFile := filepath.Join("json/output", "tv_ex.json")
-	movieOutputFile := filepath.Join("json/output", "movies_ex.json")
-
-	var existingShows []OutputShow
-	var existingMovies []OutputMovie
-	LoadJSONOptional(tvOutputFile, &existingShows)
-	LoadJSONOptional(movieOutputFile, &existingMovies)
+	// Fribb adds to the outputs of -tv and -movies. -output names a single
+	// result set, so only -output-template applies.
+	named := config
+	named.OutputFile = ""
+	tvOutputFile := outputFileFor(named, nil, "tv")
+	movieOutputFile := outputFileFor(named, nil, "movies")
+
+	existingShows, err := LoadShowOutput(tvOutputFile)
+	if err != nil {
+		return err
+	}
+	existingMovies, err := LoadMovieOutput(movieOutputFile)
+	if err != nil {
+		return err
+	}
 
 	existingShowMAL := make(map[int]OutputShow)
 	existingMovieMAL := make(map[int]OutputMovie)
@@ -224,6 +238,7 @@ func ProcessFribb(config Config) {
 		fribb      FribbEntry
 		malID      int
 		title      string
+		rawTitle   string // title before CleanTitle, "" when unchanged
 		isMovie    bool
 		lookupType string // "tmdb" | "tvdb" | "imdb"
 		lookupID   string
@@ -252,7 +267,7 @@ func ProcessFribb(config Config) {
 		}
 
 		row := malToRow[malID]
-		title := row.Title
+		title, rawTitle := CleanTitle(row.Title)
 		if title == "" {
 			title = fmt.Sprintf("AniDB:%d / MAL:%d", entry.AnidbID, malID)
 		}
@@ -272,7 +287,7 @@ func ProcessFribb(config Config) {
 				skippedExisting++
 				return true
 			}
-			if showNotExistMap[malID] {
+			if showNotExistMap[malID] && !config.RecheckNotFound {
 				skippedExisting++
 				return true
 			}
@@ -285,7 +300,7 @@ func ProcessFribb(config Config) {
 				skippedExisting++
 				return true
 			}
-			if movieNotExistMap[malID] {
+			if movieNotExistMap[malID] && !config.RecheckNotFound {
 				skippedExisting++
 				return true
 			}
@@ -303,6 +318,7 @@ func ProcessFribb(config Config) {
 				fribb:      entry,
 				malID:      malID,
 				title:      title,
+				rawTitle:   rawTitle,
 				isMovie:    true,
 				lookupType: "tmdb",
 				lookupID:   fmt.Sprintf("%d", *entry.ThemoviedbID.Movie),
@@ -323,6 +339,7 @@ func ProcessFribb(config Config) {
 				fribb:      entry,
 				malID:      malID,
 				title:      title,
+				rawTitle:   rawTitle,
 				isMovie:    false,
 				lookupType: "tmdb",
 				lookupID:   fmt.Sprintf("%d", *entry.ThemoviedbID.TV),
@@ -347,6 +364,7 @@ func ProcessFribb(config Config) {
 				fribb:      entry,
 				malID:      malID,
 				title:      title,
+				rawTitle:   rawTitle,
 				isMovie:    false,
 				lookupType: "tvdb",
 				lookupID:   fmt.Sprintf("%d", entry.TVDbID),
@@ -364,6 +382,7 @@ func ProcessFribb(config Config) {
 				fribb:      entry,
 				malID:      malID,
 				title:      title,
+				rawTitle:   rawTitle,
 				isMovie:    true,
 				lookupType: "imdb",
 				lookupID:   imdb,
@@ -374,13 +393,13 @@ func ProcessFribb(config Config) {
 		skippedNoID++
 	}
 
-	fmt.Printf("Work list: %d TV shows, %d movies  (skipped: %d existing, %d no-MAL, %d no-ID, %d season-0, %d type-mismatch)\n",
+	Infof("Work list: %d TV shows, %d movies  (skipped: %d existing, %d no-MAL, %d no-ID, %d season-0, %d type-mismatch)\n",
 		len(tvWork), len(movieWork), skippedExisting, skippedNoMAL, skippedNoID, skippedSeason0, skippedTypeMismatch)
 
 	// Ensure the search cache dir exists
 	os.MkdirAll(filepath.Join(config.TempDir, "search"), 0755)
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := NewHTTPClient(30 * time.Second)
 
 	// -------------------------------------------------------------------------
 	// 5a. Process TV shows
@@ -394,30 +413,48 @@ func ProcessFribb(config Config) {
 		NotFoundDetails: []ChangeDetail{},
 	}
 	var tvNewNotExist []NotFoundEntry
-	tvBar := setupProgressBar(len(tvWork), "Processing Fribb TV shows", config.NoProgress)
+	tvHealed := make(map[int]bool)
+	tvFailed, tvRateLimited := 0, false
+	var tvResolved []int // MAL IDs added, for the middleware
+	config.Progress = newProgress(config, len(tvWork), "Processing Fribb TV shows")
+	enrich := EnrichContext{Client: client, Config: config, Stats: &tvStats}
 
 	for _, item := range tvWork {
-		tvBar.Add(1)
+		if config.Budget.Exhausted() {
+			tvStats.StoppedEarly = config.Budget.Reason()
+			break
+		}
+		config.Progress.Add()
 
 		if override, exists := showOverrides[item.malID]; exists && override.Ignore {
 			if config.Verbose {
-				fmt.Printf("\nSkipping ignored show: %s (MAL ID: %d)", item.title, item.malID)
+				Debugf("\nSkipping ignored show: %s (MAL ID: %d)", item.title, item.malID)
 			}
 			continue
 		}
 
+		config.Progress.Phase("search")
 		results, err := FetchTraktByExternalID(client, config, item.lookupType, item.lookupID, "show")
 		if err != nil {
 			if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "no results") {
 				tvNewNotExist = append(tvNewNotExist, NotFoundEntry{MalID: item.malID, Title: item.title})
-				tvStats.NotFoundDetails = append(tvStats.NotFoundDetails, ChangeDetail{
-					MalID:  item.malID,
-					Title:  item.title,
-					Reason: fmt.Sprintf("Not found on Trakt via %s ID %s", item.lookupType, item.lookupID),
-				})
+				if !showNotExistMap[item.malID] {
+					tvStats.NotFoundDetails = append(tvStats.NotFoundDetails, ChangeDetail{
+						MalID:  item.malID,
+						Title:  item.title,
+						Reason: fmt.Sprintf("Not found on Trakt via %s ID %s", item.lookupType, item.lookupID),
+					})
+				}
+			} else if errors.Is(err, ErrRateLimited) {
+				config.Errors.Record("trakt", item.malID, item.title, err)
+				tvStats.StoppedEarly = fmt.Sprintf("Trakt kept rate limiting after retries (%v)", err)
+				tvRateLimited = true
+				break
 			} else {
 				log.Printf("Error searching Trakt (%s %s) for MAL %d: %v",
 					item.lookupType, item.lookupID, item.malID, err)
+				config.Errors.Record("trakt", item.malID, item.title, err)
+				tvFailed++
 			}
 			continue
 		}
@@ -432,28 +469,38 @@ func ProcessFribb(config Config) {
 		}
 		if traktShow == nil {
 			tvNewNotExist = append(tvNewNotExist, NotFoundEntry{MalID: item.malID, Title: item.title})
-			tvStats.NotFoundDetails = append(tvStats.NotFoundDetails, ChangeDetail{
-				MalID:  item.malID,
-				Title:  item.title,
-				Reason: fmt.Sprintf("Trakt returned no show for %s ID %s", item.lookupType, item.lookupID),
-			})
+			if !showNotExistMap[item.malID] {
+				tvStats.NotFoundDetails = append(tvStats.NotFoundDetails, ChangeDetail{
+					MalID:  item.malID,
+					Title:  item.title,
+					Reason: fmt.Sprintf("Trakt returned no show for %s ID %s", item.lookupType, item.lookupID),
+				})
+			}
 			continue
 		}
 
 		outputShow := &OutputShow{
 			MyAnimeList: struct {
-				Title string `json:"title"`
-				ID    int    `json:"id"`
-			}{Title: item.title, ID: item.malID},
+				Title     string     `json:"title"`
+				RawTitle  string     `json:"raw_title,omitempty"`
+				Titles    *MALTitles `json:"titles,omitempty"`
+				Episodes  int        `json:"episodes,omitempty"`
+				StartDate string     `json:"start_date,omitempty"`
+				ID        int        `json:"id"`
+				URL       string     `json:"url"`
+			}{Title: item.title, RawTitle: item.rawTitle, ID: item.malID},
 			Trakt: struct {
 				Title  string `json:"title"`
 				ID     int    `json:"id"`
 				Slug   string `json:"slug"`
+				URL    string `json:"url"`
 				Type   string `json:"type"`
 				Season *struct {
-					ID        int                   `json:"id"`
-					Number    int                   `json:"number"`
-					Externals *TraktExternalsSeason `json:"externals"`
+					ID            int                   `json:"id"`
+					Number        int                   `json:"number"`
+					EpisodeCount  int                   `json:"episode_count,omitempty"`
+					EpisodeOffset int                   `json:"episode_offset,omitempty"`
+					Externals     *TraktExternalsSeason `json:"externals"`
 				} `json:"season"`
 				IsSplitCour bool `json:"is_split_cour"`
 			}{
@@ -462,11 +509,15 @@ func ProcessFribb(config Config) {
 				Slug:  traktShow.IDs.Slug,
 				Type:  "shows",
 			},
+			Quality:     searchQuality(results, "show", item.title, traktShow.Title),
 			ReleaseYear: traktShow.Year,
 			Externals:   &TraktExternalsShow{TVDB: traktShow.IDs.TVDB, TMDB: traktShow.IDs.TMDB, IMDB: traktShow.IDs.IMDB},
 		}
 
+		setShowDetails(config, outputShow, traktShow)
+		outputShow.Popularity = fetchPopularity(client, config, "shows", traktShow.IDs.Trakt, item.malID, item.title)
 		updateSeasonInfo(client, config, outputShow, traktShow.IDs.Trakt, item.season)
+		runEnrichers(enrich, EnrichEntry{Show: outputShow})
 
 		if override, exists := showOverrides[item.malID]; exists && !override.Ignore {
 			ApplyShowOverride(outputShow, override)
@@ -477,7 +528,19 @@ func ProcessFribb(config Config) {
 			})
 		}
 
+		tvStats.WarningDetails = append(tvStats.WarningDetails, showWarnings(outputShow)...)
+		entryResolved(EntryEvent{Config: config, MediaType: "tv", Show: outputShow})
 		existingShowMAL[item.malID] = *outputShow
+		tvResolved = append(tvResolved, item.malID)
+		if showNotExistMap[item.malID] {
+			tvHealed[item.malID] = true
+			tvStats.HealedDetails = append(tvStats.HealedDetails, ChangeDetail{
+				MalID:  item.malID,
+				Title:  item.title,
+				Reason: fmt.Sprintf("Found on Trakt via %s ID %s", item.lookupType, item.lookupID),
+			})
+		}
+		config.Budget.AddNew()
 		tvStats.CreatedDetails = append(tvStats.CreatedDetails, ChangeDetail{
 			MalID:  item.malID,
 			Title:  item.title,
@@ -485,19 +548,42 @@ func ProcessFribb(config Config) {
 		})
 
 		if config.Verbose {
-			fmt.Printf("\n  ✓ %s (MAL %d) → Trakt %d (%s) [via %s]",
+			Debugf("\n  ✓ %s (MAL %d) → Trakt %d (%s) [via %s]",
 				item.title, item.malID, traktShow.IDs.Trakt, traktShow.IDs.Slug, item.lookupType)
 		}
 	}
 
+	SetEpisodeOffsets(existingShowMAL)
+	tvStats.WarningDetails = append(tvStats.WarningDetails, CheckEpisodeCounts(existingShowMAL, tvResolved)...)
 	tvStats.TotalAfter = len(existingShowMAL)
+	tvStats.Requests = config.Budget.Requests()
 	tvStats.Created = len(tvStats.CreatedDetails)
 	tvStats.NotFound = len(tvStats.NotFoundDetails)
+	tvStats.Healed = len(tvStats.HealedDetails)
+	tvStats.CollisionDetails = ShowCollisions(existingShowMAL)
 	tvStats.Modified = len(tvStats.ModifiedDetails)
 
-	SaveResults(tvOutputFile, existingShowMAL)
-	SaveNotFound(tvOutputFile, tvNewNotExist, showNotExistMap)
-	OutputStats("tv (fribb)", tvStats)
+	tvStats.Failed = tvFailed
+	tvStats.Warnings = len(tvStats.WarningDetails)
+	resources.enterPhase("save")
+	tvBatch := SinkBatch{
+		Config:        config,
+		MediaType:     "tv",
+		OutputFile:    tvOutputFile,
+		Stats:         tvStats,
+		Shows:         existingShowMAL,
+		ExistingShows: existingShows,
+	}
+	if err := writeSinks(tvBatch); err != nil {
+		return err
+	}
+	if err := SaveNotFound(tvOutputFile, tvNewNotExist, showNotExistMap, tvHealed); err != nil {
+		return err
+	}
+	resources.enterPhase("")
+	entriesWritten(tvBatch, tvResolved)
+	UpdateReviewQueue(client, config, "tv", tvOutputFile, existingShowMAL)
+	runComplete(RunEvent{Config: config, OutputFile: tvOutputFile, Stats: tvStats})
 
 	// -------------------------------------------------------------------------
 	// 5b. Process movies
@@ -512,30 +598,50 @@ func ProcessFribb(config Config) {
 		LetterboxdNotFoundDetails: []ChangeDetail{},
 	}
 	var movieNewNotExist []NotFoundEntry
-	movieBar := setupProgressBar(len(movieWork), "Processing Fribb movies", config.NoProgress)
+	movieHealed := make(map[int]bool)
+	movieFailed, movieRateLimited := 0, false
+	config.Progress = newProgress(config, len(movieWork), "Processing Fribb movies")
+	letterboxd := startLetterboxdPhase(client, config)
+	enrich = EnrichContext{Client: client, Config: config, Stats: &movieStats, letterboxd: letterboxd}
+	var enriched []*OutputMovie
+	var movieResolved []int
 
 	for _, item := range movieWork {
-		movieBar.Add(1)
+		if config.Budget.Exhausted() {
+			movieStats.StoppedEarly = config.Budget.Reason()
+			break
+		}
+		config.Progress.Add()
 
 		if override, exists := movieOverrides[item.malID]; exists && override.Ignore {
 			if config.Verbose {
-				fmt.Printf("\nSkipping ignored movie: %s (MAL ID: %d)", item.title, item.malID)
+				Debugf("\nSkipping ignored movie: %s (MAL ID: %d)", item.title, item.malID)
 			}
 			continue
 		}
 
+		config.Progress.Phase("search")
 		results, err := FetchTraktByExternalID(client, config, item.lookupType, item.lookupID, "movie")
 		if err != nil {
 			if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "no results") {
 				movieNewNotExist = append(movieNewNotExist, NotFoundEntry{MalID: item.malID, Title: item.title})
-				movieStats.NotFoundDetails = append(movieStats.NotFoundDetails, ChangeDetail{
-					MalID:  item.malID,
-					Title:  item.title,
-					Reason: fmt.Sprintf("Not found on Trakt via %s ID %s", item.lookupType, item.lookupID),
-				})
+				if !movieNotExistMap[item.malID] {
+					movieStats.NotFoundDetails = append(movieStats.NotFoundDetails, ChangeDetail{
+						MalID:  item.malID,
+						Title:  item.title,
+						Reason: fmt.Sprintf("Not found on Trakt via %s ID %s", item.lookupType, item.lookupID),
+					})
+				}
+			} else if errors.Is(err, ErrRateLimited) {
+				config.Errors.Record("trakt", item.malID, item.title, err)
+				movieStats.StoppedEarly = fmt.Sprintf("Trakt kept rate limiting after retries (%v)", err)
+				movieRateLimited = true
+				break
 			} else {
 				log.Printf("Error searching Trakt (%s %s) for MAL %d: %v",
 					item.lookupType, item.lookupID, item.malID, err)
+				config.Errors.Record("trakt", item.malID, item.title, err)
+				movieFailed++
 			}
 			continue
 		}
@@ -549,23 +655,29 @@ func ProcessFribb(config Config) {
 		}
 		if traktMovie == nil {
 			movieNewNotExist = append(movieNewNotExist, NotFoundEntry{MalID: item.malID, Title: item.title})
-			movieStats.NotFoundDetails = append(movieStats.NotFoundDetails, ChangeDetail{
-				MalID:  item.malID,
-				Title:  item.title,
-				Reason: fmt.Sprintf("Trakt returned no movie for %s ID %s", item.lookupType, item.lookupID),
-			})
+			if !movieNotExistMap[item.malID] {
+				movieStats.NotFoundDetails = append(movieStats.NotFoundDetails, ChangeDetail{
+					MalID:  item.malID,
+					Title:  item.title,
+					Reason: fmt.Sprintf("Trakt returned no movie for %s ID %s", item.lookupType, item.lookupID),
+				})
+			}
 			continue
 		}
 
 		outputMovie := &OutputMovie{
 			MyAnimeList: struct {
-				Title string `json:"title"`
-				ID    int    `json:"id"`
-			}{Title: item.title, ID: item.malID},
+				Title    string     `json:"title"`
+				RawTitle string     `json:"raw_title,omitempty"`
+				Titles   *MALTitles `json:"titles,omitempty"`
+				ID       int        `json:"id"`
+				URL      string     `json:"url"`
+			}{Title: item.title, RawTitle: item.rawTitle, ID: item.malID},
 			Trakt: struct {
 				Title string `json:"title"`
 				ID    int    `json:"id"`
 				Slug  string `json:"slug"`
+				URL   string `json:"url"`
 				Type  string `json:"type"`
 			}{
 				Title: traktMovie.Title,
@@ -573,6 +685,7 @@ func ProcessFribb(config Config) {
 				Slug:  traktMovie.IDs.Slug,
 				Type:  "movies",
 			},
+			Quality:     searchQuality(results, "movie", item.title, traktMovie.Title),
 			ReleaseYear: traktMovie.Year,
 			Externals: &TraktExternalsMovie{
 				TMDB: traktMovie.IDs.TMDB,
@@ -580,26 +693,27 @@ func ProcessFribb(config Config) {
 			},
 		}
 
+		setMovieDetails(config, outputMovie, traktMovie)
+		outputMovie.Popularity = fetchPopularity(client, config, "movies", traktMovie.IDs.Trakt, item.malID, item.title)
+
 		// Try to enrich with Letterboxd data
 		var existingMovie *OutputMovie
 		if existing, exists := existingMovieMAL[item.malID]; exists {
 			existingMovie = &existing
 		}
-		letterboxdNotFound := updateLetterboxdInfo(client, config, outputMovie, existingMovie)
-		if letterboxdNotFound != nil {
-			movieStats.LetterboxdNotFoundDetails = append(movieStats.LetterboxdNotFoundDetails, *letterboxdNotFound)
-		}
+		runEnrichers(enrich, EnrichEntry{Movie: outputMovie, ExistingMovie: existingMovie})
+		enriched = append(enriched, outputMovie)
 
-		if override, exists := movieOverrides[item.malID]; exists && !override.Ignore {
-			ApplyMovieOverride(outputMovie, override)
-			movieStats.ModifiedDetails = append(movieStats.ModifiedDetails, ChangeDetail{
+		existingMovieMAL[item.malID] = *outputMovie
+		if movieNotExistMap[item.malID] {
+			movieHealed[item.malID] = true
+			movieStats.HealedDetails = append(movieStats.HealedDetails, ChangeDetail{
 				MalID:  item.malID,
 				Title:  item.title,
-				Reason: override.Description,
+				Reason: fmt.Sprintf("Found on Trakt via %s ID %s", item.lookupType, item.lookupID),
 			})
 		}
-
-		existingMovieMAL[item.malID] = *outputMovie
+		config.Budget.AddNew()
 		movieStats.CreatedDetails = append(movieStats.CreatedDetails, ChangeDetail{
 			MalID:  item.malID,
 			Title:  item.title,
@@ -607,20 +721,66 @@ func ProcessFribb(config Config) {
 		})
 
 		if config.Verbose {
-			fmt.Printf("\n  ✓ %s (MAL %d) → Trakt %d (%s) [via %s]",
+			Debugf("\n  ✓ %s (MAL %d) → Trakt %d (%s) [via %s]",
 				item.title, item.malID, traktMovie.IDs.Trakt, traktMovie.IDs.Slug, item.lookupType)
 		}
 	}
 
+	// Overrides apply after Letterboxd so they can still correct its IDs
+	config.Progress.Phase("letterboxd")
+	movieStats.LetterboxdNotFoundDetails = append(movieStats.LetterboxdNotFoundDetails, letterboxd.wait()...)
+	for _, outputMovie := range enriched {
+		malID := outputMovie.MyAnimeList.ID
+		if override, exists := movieOverrides[malID]; exists && !override.Ignore {
+			ApplyMovieOverride(outputMovie, override)
+			movieStats.ModifiedDetails = append(movieStats.ModifiedDetails, ChangeDetail{
+				MalID:  malID,
+				Title:  outputMovie.MyAnimeList.Title,
+				Reason: override.Description,
+			})
+		}
+		movieStats.WarningDetails = append(movieStats.WarningDetails, movieWarnings(outputMovie)...)
+		entryResolved(EntryEvent{Config: config, MediaType: "movies", Movie: outputMovie})
+		existingMovieMAL[malID] = *outputMovie
+		movieResolved = append(movieResolved, malID)
+	}
+
 	movieStats.TotalAfter = len(existingMovieMAL)
+	movieStats.Requests = config.Budget.Requests()
 	movieStats.Created = len(movieStats.CreatedDetails)
 	movieStats.NotFound = len(movieStats.NotFoundDetails)
+	movieStats.Healed = len(movieStats.HealedDetails)
+	movieStats.CollisionDetails = MovieCollisions(existingMovieMAL)
 	movieStats.Modified = len(movieStats.ModifiedDetails)
 
-	SaveMovieResults(movieOutputFile, existingMovieMAL)
-	SaveNotFound(movieOutputFile, movieNewNotExist, movieNotExistMap)
-	OutputStats("movies (fribb)", movieStats)
+	movieStats.Failed = movieFailed
+	movieStats.Warnings = len(movieStats.WarningDetails)
+	resources.enterPhase("save")
+	movieBatch := SinkBatch{
+		Config:         config,
+		MediaType:      "movies",
+		OutputFile:     movieOutputFile,
+		Stats:          movieStats,
+		Movies:         existingMovieMAL,
+		ExistingMovies: existingMovies,
+	}
+	if err := writeSinks(movieBatch); err != nil {
+		return err
+	}
+	if err := SaveNotFound(movieOutputFile, movieNewNotExist, movieNotExistMap, movieHealed); err != nil {
+		return err
+	}
+	resources.enterPhase("")
+	entriesWritten(movieBatch, movieResolved)
+	UpdateReviewQueue(client, config, "movies", movieOutputFile, nil)
+	runComplete(RunEvent{Config: config, OutputFile: movieOutputFile, Stats: movieStats})
 
-	fmt.Printf("\nFribb processing complete: %d shows, %d movies added.\n",
+	Infof("\nFribb processing complete: %d shows, %d movies added.\n",
 		tvStats.Created, movieStats.Created)
+	result := WorseError(
+		runResult("fribb shows", tvFailed, tvStats.StoppedEarly, tvRateLimited),
+		runResult("fribb movies", movieFailed, movieStats.StoppedEarly, movieRateLimited),
+	)
+	result = WorseError(result, config.Thresholds.check("fribb shows", tvStats))
+	return WorseError(result, config.Thresholds.check("fribb movies", movieStats))
 }
@@ -293,3 +293,25 @@ func TestFribbCustomUnmarshal(t *testing.T) {
 func intPtr(i int) *int {
 	return &i
 }
+
+func TestSearchQuality(t *testing.T) {
+	show := TraktSearchResult{Type: "show", Show: &TraktShow{}}
+	movie := TraktSearchResult{Type: "movie", Movie: &TraktMovie{}}
+
+	if q := searchQuality([]TraktSearchResult{show, movie}, "show", "Bleach", "BLEACH"); q != QualitySearchHigh {
+		t.Errorf("one agreeing show result: got %s", q)
+	}
+	if q := searchQuality([]TraktSearchResult{show, show}, "show", "Bleach", "Bleach"); q != QualitySearchLow {
+		t.Errorf("two show results: got %s", q)
+	}
+	if q := searchQuality([]TraktSearchResult{show}, "show", "Shingeki no Kyojin", "Attack on Titan"); q != QualitySearchLow {
+		t.Errorf("disagreeing titles: got %s", q)
+	}
+
+	override := mappingOverride(reviewItem{MalID: 1}, show)
+	var out OutputShow
+	ApplyShowOverride(&out, &override)
+	if out.Quality != QualityOverride {
+		t.Errorf("override mapping: got %q", out.Quality)
+	}
+}
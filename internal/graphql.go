@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// GraphQL support for serve mode. Parsing, validation, execution and
+// introspection are left to graphql-go; this file holds the fixed, read-only
+// schema and the resolvers of the Query fields. Object fields resolve
+// straight from the dataset's JSON documents, as their names mirror the JSON
+// output. The schema is published as SDL on /graphql/schema.
+
+// gqlMaxBatch caps how many IDs a single batched lookup may request
+const gqlMaxBatch = 500
+
+// gqlMaxRequests caps how many requests a batched POST may carry
+const gqlMaxRequests = 20
+
+// gqlDatasetKey is the context key of the dataset a request runs against
+type gqlDatasetKey struct{}
+
+// gqlSources maps IdSource enum values to dataset lookup sources
+var gqlSources = map[string]string{
+	"MAL":   SourceMAL,
+	"TRAKT": SourceTrakt,
+	"TVDB":  SourceTVDB,
+	"TMDB":  SourceTMDB,
+	"IMDB":  SourceIMDB,
+}
+
+// gqlObject declares an object type whose fields are read from a JSON
+// document by name
+func gqlObject(name string, fields map[string]graphql.Output) *graphql.Object {
+	config := graphql.Fields{}
+	for field, typ := range fields {
+		config[field] = &graphql.Field{Type: typ}
+	}
+	return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: config})
+}
+
+// gqlSchema is the schema, built on first use
+var gqlSchema = sync.OnceValue(func() graphql.Schema {
+	// Values stand for themselves, so introspection shows defaults by name
+	values := graphql.EnumValueConfigMap{}
+	for name := range gqlSources {
+		values[name] = &graphql.EnumValueConfig{Value: name}
+	}
+	idSource := graphql.NewEnum(graphql.EnumConfig{Name: "IdSource", Values: values})
+
+	myAnimeList := gqlObject("MyAnimeList", map[string]graphql.Output{
+		"id":    graphql.NewNonNull(graphql.Int),
+		"title": graphql.NewNonNull(graphql.String),
+	})
+	seasonExternals := gqlObject("SeasonExternals", map[string]graphql.Output{
+		"tvdb":   graphql.Int,
+		"tmdb":   graphql.Int,
+		"tvrage": graphql.Int,
+	})
+	show := gqlObject("Show", map[string]graphql.Output{
+		"myanimelist": graphql.NewNonNull(myAnimeList),
+		"trakt": graphql.NewNonNull(gqlObject("TraktShow", map[string]graphql.Output{
+			"id":    graphql.NewNonNull(graphql.Int),
+			"title": graphql.NewNonNull(graphql.String),
+			"slug":  graphql.NewNonNull(graphql.String),
+			"type":  graphql.NewNonNull(graphql.String),
+			"season": gqlObject("TraktSeason", map[string]graphql.Output{
+				"id":        graphql.NewNonNull(graphql.Int),
+				"number":    graphql.NewNonNull(graphql.Int),
+				"externals": seasonExternals,
+			}),
+			"is_split_cour": graphql.NewNonNull(graphql.Boolean),
+		})),
+		"release_year": graphql.Int,
+		"externals": gqlObject("ShowExternals", map[string]graphql.Output{
+			"tvdb":   graphql.Int,
+			"tmdb":   graphql.Int,
+			"imdb":   graphql.String,
+			"tvrage": graphql.Int,
+		}),
+	})
+	movie := gqlObject("Movie", map[string]graphql.Output{
+		"myanimelist": graphql.NewNonNull(myAnimeList),
+		"trakt": graphql.NewNonNull(gqlObject("TraktMovie", map[string]graphql.Output{
+			"id":    graphql.NewNonNull(graphql.Int),
+			"title": graphql.NewNonNull(graphql.String),
+			"slug":  graphql.NewNonNull(graphql.String),
+			"type":  graphql.NewNonNull(graphql.String),
+		})),
+		"release_year": graphql.Int,
+		"externals": gqlObject("MovieExternals", map[string]graphql.Output{
+			"tmdb": graphql.Int,
+			"imdb": graphql.String,
+			"letterboxd": gqlObject("Letterboxd", map[string]graphql.Output{
+				"slug": graphql.String,
+				"uid":  graphql.Int,
+				"lid":  graphql.String,
+			}),
+		}),
+	})
+
+	malID := graphql.FieldConfigArgument{"mal_id": {Type: graphql.NewNonNull(graphql.Int)}}
+	batch := graphql.FieldConfigArgument{
+		"source": {Type: idSource, DefaultValue: "MAL"},
+		"ids":    {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+	}
+	query := graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields{
+		"show":   {Type: show, Args: malID, Resolve: gqlResolveEntry("shows")},
+		"movie":  {Type: movie, Args: malID, Resolve: gqlResolveEntry("movies")},
+		"shows":  {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(show))), Args: batch, Resolve: gqlResolveBatch("shows")},
+		"movies": {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(movie))), Args: batch, Resolve: gqlResolveBatch("movies")},
+	}})
+
+	// The schema is fixed, so an error here is a bug, not a runtime failure
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic(fmt.Sprintf("graphql schema: %v", err))
+	}
+	return schema
+})
+
+// gqlResolveEntry resolves show or movie: the entry with the MAL ID, or null
+func gqlResolveEntry(mediaType string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		data := p.Context.Value(gqlDatasetKey{}).(*Dataset)
+		docs := data.showDocs
+		if mediaType == "movies" {
+			docs = data.movieDocs
+		}
+		if doc, ok := docs[p.Args["mal_id"].(int)]; ok {
+			return doc, nil
+		}
+		return nil, nil
+	}
+}
+
+// gqlResolveBatch resolves shows or movies: every entry matching one of the
+// IDs, each entry once
+func gqlResolveBatch(mediaType string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		data := p.Context.Value(gqlDatasetKey{}).(*Dataset)
+		ids, _ := p.Args["ids"].([]interface{})
+		if len(ids) > gqlMaxBatch {
+			return nil, fmt.Errorf("argument ids: at most %d IDs may be requested at once", gqlMaxBatch)
+		}
+		name, _ := p.Args["source"].(string)
+		source := gqlSources[name]
+
+		lookup, docs := data.LookupShows, data.showDocs
+		if mediaType == "movies" {
+			lookup, docs = data.LookupMovies, data.movieDocs
+		}
+		results := []interface{}{}
+		seen := make(map[int]bool)
+		for _, id := range ids {
+			malIDs, err := lookup(source, fmt.Sprint(id))
+			if err != nil {
+				return nil, err
+			}
+			for _, malID := range malIDs {
+				if !seen[malID] {
+					seen[malID] = true
+					results = append(results, docs[malID])
+				}
+			}
+		}
+		return results, nil
+	}
+}
+
+// GraphQLSchema renders the schema as SDL, types and fields in name order
+func GraphQLSchema() string {
+	schema := gqlSchema()
+	var names []string
+	for name, typ := range schema.TypeMap() {
+		switch typ.(type) {
+		case *graphql.Object, *graphql.Enum:
+			if !strings.HasPrefix(name, "__") {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch typ := schema.Type(name).(type) {
+		case *graphql.Enum:
+			var values []string
+			for _, value := range typ.Values() {
+				values = append(values, value.Name)
+			}
+			sort.Strings(values)
+			fmt.Fprintf(&b, "enum %s {\n  %s\n}\n", name, strings.Join(values, "\n  "))
+		case *graphql.Object:
+			fmt.Fprintf(&b, "type %s {\n", name)
+			fields := typ.Fields()
+			for _, fieldName := range sortedKeys(fields) {
+				field := fields[fieldName]
+				var args []string
+				for _, arg := range field.Args {
+					args = append(args, gqlArgumentSDL(arg))
+				}
+				sort.Strings(args)
+				if len(args) > 0 {
+					fmt.Fprintf(&b, "  %s(%s): %s\n", fieldName, strings.Join(args, ", "), field.Type)
+				} else {
+					fmt.Fprintf(&b, "  %s: %s\n", fieldName, field.Type)
+				}
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.String()
+}
+
+// gqlArgumentSDL renders an argument, with its default when it has one
+func gqlArgumentSDL(arg *graphql.Argument) string {
+	s := arg.Name() + ": " + arg.Type.String()
+	if arg.DefaultValue == nil {
+		return s
+	}
+	return s + " = " + fmt.Sprint(arg.DefaultValue)
+}
+
+// sortedKeys returns the keys of a string-keyed map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GraphQLRequest is the standard GraphQL-over-HTTP request body
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// ExecuteGraphQL parses, validates and executes a request against the dataset
+func ExecuteGraphQL(data *Dataset, req GraphQLRequest) *graphql.Result {
+	return graphql.Do(graphql.Params{
+		Schema:         gqlSchema(),
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(context.Background(), gqlDatasetKey{}, data),
+	})
+}
+
+// gqlErrorResult is a response carrying only an error
+func gqlErrorResult(message string) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: message}}}
+}
+
+// handleGraphQL serves GraphQL over HTTP. GET takes query, variables and
+// operationName as URL parameters; POST takes a JSON request or a JSON array
+// of requests, which are executed as a batch.
+func (s *server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var requests []GraphQLRequest
+	batched := false
+
+	switch r.Method {
+	case http.MethodGet:
+		req := GraphQLRequest{Query: r.URL.Query().Get("query"), OperationName: r.URL.Query().Get("operationName")}
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				writeJSONResponse(w, http.StatusBadRequest, gqlErrorResult("invalid variables: "+err.Error()))
+				return
+			}
+		}
+		requests = append(requests, req)
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, gqlErrorResult(err.Error()))
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			requests = append(requests, GraphQLRequest{Query: string(body)})
+			break
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			batched = true
+			err = json.Unmarshal(body, &requests)
+		} else {
+			var req GraphQLRequest
+			err = json.Unmarshal(body, &req)
+			requests = append(requests, req)
+		}
+		if err != nil {
+			writeJSONResponse(w, http.StatusBadRequest, gqlErrorResult("invalid request body: "+err.Error()))
+			return
+		}
+		if len(requests) > gqlMaxRequests {
+			writeJSONResponse(w, http.StatusBadRequest, gqlErrorResult(fmt.Sprintf("batch of %d requests exceeds the limit of %d", len(requests), gqlMaxRequests)))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data := s.dataset()
+	responses := make([]*graphql.Result, len(requests))
+	for i, req := range requests {
+		responses[i] = ExecuteGraphQL(data, req)
+	}
+	if batched {
+		writeJSONResponse(w, http.StatusOK, responses)
+		return
+	}
+	status := http.StatusOK
+	if responses[0].Data == nil {
+		status = http.StatusBadRequest
+	}
+	writeJSONResponse(w, status, responses[0])
+}
+
+// handleGraphQLSchema serves the schema as SDL
+func (s *server) handleGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, GraphQLSchema())
+}
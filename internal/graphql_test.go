@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testDataset(t *testing.T) *Dataset {
+	t.Helper()
+	var shows []OutputShow
+	var movies []OutputMovie
+	if err := json.Unmarshal([]byte(`[
+		{"myanimelist":{"title":"Cowboy Bebop","id":1},"trakt":{"title":"Cowboy Bebop","id":30857,"slug":"cowboy-bebop","type":"shows","season":{"id":1,"number":1,"externals":null},"is_split_cour":false},"release_year":1998,"externals":{"tvdb":76885,"tmdb":30991,"imdb":"tt0213338","tvrage":null}},
+		{"myanimelist":{"title":"Cowboy Bebop: Tengoku no Tobira","id":5},"trakt":{"title":"Cowboy Bebop","id":30857,"slug":"cowboy-bebop","type":"shows","season":{"id":2,"number":0,"externals":null},"is_split_cour":false},"release_year":2001,"externals":null}
+	]`), &shows); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`[
+		{"myanimelist":{"title":"Koukaku Kidoutai","id":43},"trakt":{"title":"Ghost in the Shell","id":1,"slug":"ghost-in-the-shell-1995","type":"movies"},"release_year":1995,"externals":{"tmdb":9323,"imdb":"tt0113568","letterboxd":{"slug":"ghost-in-the-shell","uid":null,"lid":null}}}
+	]`), &movies); err != nil {
+		t.Fatal(err)
+	}
+	return NewDataset(shows, movies)
+}
+
+func executeGraphQLJSON(t *testing.T, req GraphQLRequest) string {
+	t.Helper()
+	data, err := json.Marshal(ExecuteGraphQL(testDataset(t), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGraphQLBatchedLookup(t *testing.T) {
+	got := executeGraphQLJSON(t, GraphQLRequest{
+		Query: `query Lookup($ids: [ID!]!) {
+			byTrakt: shows(source: TRAKT, ids: $ids) { ...Ids }
+			movie(mal_id: 43) { trakt { slug } externals { letterboxd { slug } } }
+		}
+		fragment Ids on Show { myanimelist { id } externals @skip(if: true) { tvdb } }`,
+		Variables: map[string]interface{}{"ids": []interface{}{float64(30857)}},
+	})
+	want := `{"data":{"byTrakt":[{"myanimelist":{"id":1}},{"myanimelist":{"id":5}}],` +
+		`"movie":{"externals":{"letterboxd":{"slug":"ghost-in-the-shell"}},"trakt":{"slug":"ghost-in-the-shell-1995"}}}}`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestGraphQLBatchLimit(t *testing.T) {
+	s := &server{}
+	s.data.Store(testDataset(t))
+	post := func(n int) *httptest.ResponseRecorder {
+		t.Helper()
+		body := "[" + strings.TrimSuffix(strings.Repeat(`{"query":"{ show(mal_id: 1) { myanimelist { id } } }"},`, n), ",") + "]"
+		w := httptest.NewRecorder()
+		s.handleGraphQL(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return w
+	}
+
+	if w := post(gqlMaxRequests); w.Code != http.StatusOK {
+		t.Fatalf("batch of %d = %d %s, want 200", gqlMaxRequests, w.Code, w.Body)
+	}
+	w := post(gqlMaxRequests + 1)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "exceeds the limit") {
+		t.Errorf("batch of %d = %d %s, want 400", gqlMaxRequests+1, w.Code, w.Body)
+	}
+}
+
+func TestGraphQLResolvers(t *testing.T) {
+	tooMany := make([]interface{}, gqlMaxBatch+1)
+	for i := range tooMany {
+		tooMany[i] = "1"
+	}
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"missing entry", `{ show(mal_id: 99) { myanimelist { id } } }`, nil,
+			`{"data":{"show":null}}`},
+		{"default source", `{ shows(ids: ["1", "5"]) { myanimelist { id } } }`, nil,
+			`{"data":{"shows":[{"myanimelist":{"id":1}},{"myanimelist":{"id":5}}]}}`},
+		{"single ID as a list", `query ($source: IdSource = TRAKT) { shows(source: $source, ids: "30857") { myanimelist { id } } }`, nil,
+			`{"data":{"shows":[{"myanimelist":{"id":1}},{"myanimelist":{"id":5}}]}}`},
+		{"entry matched twice", `{ shows(source: TRAKT, ids: [30857, 30857]) { myanimelist { id } } }`, nil,
+			`{"data":{"shows":[{"myanimelist":{"id":1}},{"myanimelist":{"id":5}}]}}`},
+		{"variable default", `query ($id: Int = 43) { movie(mal_id: $id) { trakt { slug } } }`, nil,
+			`{"data":{"movie":{"trakt":{"slug":"ghost-in-the-shell-1995"}}}}`},
+		{"unsupported source", `{ movies(source: TVDB, ids: [1]) { trakt { id } } }`, nil,
+			`unsupported ID source \"tvdb\" for movies`},
+		{"too many IDs", `query ($ids: [ID!]!) { shows(ids: $ids) { __typename } }`, map[string]interface{}{"ids": tooMany},
+			`at most 500 IDs may be requested at once`},
+	}
+	for _, tt := range tests {
+		got := executeGraphQLJSON(t, GraphQLRequest{Query: tt.query, Variables: tt.variables})
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGraphQLValidation(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ show(mal_id: 1) { nope } }`, `Cannot query field \"nope\" on type \"Show\"`},
+		{`{ show(mal_id: 1) { trakt } }`, `must have a sub selection`},
+		{`{ show(id: 1) { trakt { id } } }`, `Unknown argument \"id\"`},
+		{`{ shows(source: "TRAKT", ids: [1]) { __typename } }`, `Argument \"source\" has invalid value \"TRAKT\"`},
+		{`mutation { show }`, `Schema is not configured for mutations`},
+		{`{ show(mal_id: 1) { trakt { id }`, `Syntax Error`},
+	}
+	for _, tt := range tests {
+		if got := executeGraphQLJSON(t, GraphQLRequest{Query: tt.query}); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %s, want error containing %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{ __schema { queryType { name fields { name } } } }`,
+			`{"data":{"__schema":{"queryType":{"fields":[{"name":"movie"},{"name":"movies"},{"name":"show"},{"name":"shows"}],"name":"Query"}}}}`},
+		{`{ __type(name: "Show") { fields { name type { kind ofType { name kind } } } } }`,
+			`{"name":"myanimelist","type":{"kind":"NON_NULL","ofType":{"kind":"OBJECT","name":"MyAnimeList"}}}`},
+		{`{ show(mal_id: 1) { __typename trakt { __typename } } }`, `{"data":{"show":{"__typename":"Show","trakt":{"__typename":"TraktShow"}}}}`},
+	}
+	for _, tt := range tests {
+		if got := executeGraphQLJSON(t, GraphQLRequest{Query: tt.query}); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	sdl := GraphQLSchema()
+	for _, want := range []string{
+		"enum IdSource {\n  IMDB\n  MAL\n  TMDB\n  TRAKT\n  TVDB\n}\n",
+		"  shows(ids: [ID!]!, source: IdSource = MAL): [Show!]!\n",
+		"type TraktSeason {\n  externals: SeasonExternals\n  id: Int!\n  number: Int!\n}\n",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sdl)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	anitraktv1 "github.com/rensetsu/db.trakt.extended-anitrakt/proto/anitrakt/v1"
+)
+
+// gRPC lookup service for serve mode. The messages and service stubs in
+// proto/anitrakt/v1 are generated from lookup.proto; grpc-go handles the
+// transport, framing and status trailers, so this file only converts
+// entries and implements the methods.
+
+//go:generate protoc -I ../proto --go_out=../proto --go_opt=paths=source_relative --go-grpc_out=../proto --go-grpc_opt=paths=source_relative anitrakt/v1/lookup.proto
+
+// grpcMaxMessage caps the size of a request message
+const grpcMaxMessage = 4 << 20
+
+// grpcIDSources maps IdSource enum values to dataset lookup sources
+var grpcIDSources = map[anitraktv1.IdSource]string{
+	anitraktv1.IdSource_ID_SOURCE_UNSPECIFIED: SourceMAL,
+	anitraktv1.IdSource_ID_SOURCE_MAL:         SourceMAL,
+	anitraktv1.IdSource_ID_SOURCE_TRAKT:       SourceTrakt,
+	anitraktv1.IdSource_ID_SOURCE_TVDB:        SourceTVDB,
+	anitraktv1.IdSource_ID_SOURCE_TMDB:        SourceTMDB,
+	anitraktv1.IdSource_ID_SOURCE_IMDB:        SourceIMDB,
+}
+
+// grpcChangeTypes maps EntryChange kinds to ChangeType enum values
+var grpcChangeTypes = map[string]anitraktv1.ChangeType{
+	"created": anitraktv1.ChangeType_CHANGE_TYPE_CREATED,
+	"updated": anitraktv1.ChangeType_CHANGE_TYPE_UPDATED,
+	"removed": anitraktv1.ChangeType_CHANGE_TYPE_REMOVED,
+}
+
+// grpcService implements LookupService over the served dataset
+type grpcService struct {
+	anitraktv1.UnimplementedLookupServiceServer
+	s *server
+}
+
+// newGRPCServer creates a gRPC server with the lookup service registered
+func (s *server) newGRPCServer() *grpc.Server {
+	gs := grpc.NewServer(grpc.MaxRecvMsgSize(grpcMaxMessage))
+	anitraktv1.RegisterLookupServiceServer(gs, &grpcService{s: s})
+	return gs
+}
+
+// LookupByMAL returns the show and movie with the MAL ID
+func (g *grpcService) LookupByMAL(ctx context.Context, req *anitraktv1.LookupByMALRequest) (*anitraktv1.LookupResponse, error) {
+	malID := int(req.GetMalId())
+	if malID <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "mal_id is required")
+	}
+
+	data := g.s.dataset()
+	resp := &anitraktv1.LookupResponse{}
+	if show, ok := data.Shows[malID]; ok && req.GetMediaType() != anitraktv1.MediaType_MEDIA_TYPE_MOVIE {
+		resp.Entries = append(resp.Entries, showEntry(show))
+	}
+	if movie, ok := data.Movies[malID]; ok && req.GetMediaType() != anitraktv1.MediaType_MEDIA_TYPE_SHOW {
+		resp.Entries = append(resp.Entries, movieEntry(movie))
+	}
+	if len(resp.Entries) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no entry with MAL ID %d", malID)
+	}
+	return resp, nil
+}
+
+// LookupByTrakt returns every entry mapped to the Trakt ID
+func (g *grpcService) LookupByTrakt(ctx context.Context, req *anitraktv1.LookupByTraktRequest) (*anitraktv1.LookupResponse, error) {
+	traktID := int(req.GetTraktId())
+	if traktID <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "trakt_id is required")
+	}
+
+	entries := grpcLookup(g.s.dataset(), SourceTrakt, strconv.Itoa(traktID), req.GetMediaType())
+	if len(entries) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no entry with Trakt ID %d", traktID)
+	}
+	return &anitraktv1.LookupResponse{Entries: entries}, nil
+}
+
+// BulkLookup resolves many IDs of one source. Unknown IDs yield empty
+// results rather than an error so one bad ID does not fail the whole batch.
+func (g *grpcService) BulkLookup(ctx context.Context, req *anitraktv1.BulkLookupRequest) (*anitraktv1.BulkLookupResponse, error) {
+	source, ok := grpcIDSources[req.GetSource()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown source %d", req.GetSource())
+	}
+	if source == SourceTVDB && req.GetMediaType() == anitraktv1.MediaType_MEDIA_TYPE_MOVIE {
+		return nil, status.Error(codes.InvalidArgument, "TVDB IDs are only available for shows")
+	}
+	if len(req.GetIds()) > gqlMaxBatch {
+		return nil, status.Errorf(codes.ResourceExhausted, "at most %d IDs may be requested at once", gqlMaxBatch)
+	}
+
+	data := g.s.dataset()
+	resp := &anitraktv1.BulkLookupResponse{}
+	for _, id := range req.GetIds() {
+		resp.Results = append(resp.Results, &anitraktv1.BulkLookupResult{
+			Id:      id,
+			Entries: grpcLookup(data, source, id, req.GetMediaType()),
+		})
+	}
+	return resp, nil
+}
+
+// grpcLookup returns every entry of the media type matching id
+func grpcLookup(data *Dataset, source, id string, mediaType anitraktv1.MediaType) []*anitraktv1.Entry {
+	var entries []*anitraktv1.Entry
+	if mediaType != anitraktv1.MediaType_MEDIA_TYPE_MOVIE {
+		malIDs, _ := data.LookupShows(source, id)
+		for _, malID := range malIDs {
+			entries = append(entries, showEntry(data.Shows[malID]))
+		}
+	}
+	if mediaType != anitraktv1.MediaType_MEDIA_TYPE_SHOW && source != SourceTVDB {
+		malIDs, _ := data.LookupMovies(source, id)
+		for _, malID := range malIDs {
+			entries = append(entries, movieEntry(data.Movies[malID]))
+		}
+	}
+	return entries
+}
+
+// StreamChanges sends one Change message per entry change until the client
+// disconnects
+func (g *grpcService) StreamChanges(req *anitraktv1.StreamChangesRequest, stream grpc.ServerStreamingServer[anitraktv1.Change]) error {
+	updates := g.s.changes.subscribe()
+	defer g.s.changes.unsubscribe(updates)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case changes, ok := <-updates:
+			if !ok {
+				return status.Error(codes.Unavailable, "change stream fell behind, reconnect to resume")
+			}
+			recordedAt := time.Now().Unix()
+			for _, change := range changes {
+				if (req.GetMediaType() == anitraktv1.MediaType_MEDIA_TYPE_SHOW && change.MediaType != "tv") ||
+					(req.GetMediaType() == anitraktv1.MediaType_MEDIA_TYPE_MOVIE && change.MediaType != "movies") {
+					continue
+				}
+				msg, err := changeMessage(change, recordedAt)
+				if err != nil {
+					log.Printf("Warning: encode change for MAL ID %d: %v", change.MalID, err)
+					continue
+				}
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// showEntry converts a show to an Entry message
+func showEntry(show OutputShow) *anitraktv1.Entry {
+	entry := &anitraktv1.Entry{
+		MediaType:   anitraktv1.MediaType_MEDIA_TYPE_SHOW,
+		MalId:       int32(show.MyAnimeList.ID),
+		MalTitle:    show.MyAnimeList.Title,
+		TraktId:     int32(show.Trakt.ID),
+		TraktSlug:   show.Trakt.Slug,
+		TraktTitle:  show.Trakt.Title,
+		IsSplitCour: show.Trakt.IsSplitCour,
+		ReleaseYear: int32(show.ReleaseYear),
+	}
+	if season := show.Trakt.Season; season != nil {
+		entry.SeasonNumber = grpcInt32(&season.Number)
+		entry.SeasonTraktId = grpcInt32(&season.ID)
+		if season.Externals != nil {
+			entry.SeasonTvdb = grpcInt32(season.Externals.TVDB)
+			entry.SeasonTmdb = grpcInt32(season.Externals.TMDB)
+		}
+	}
+	if show.Externals != nil {
+		entry.Tvdb = grpcInt32(show.Externals.TVDB)
+		entry.Tmdb = grpcInt32(show.Externals.TMDB)
+		entry.Imdb = grpcString(show.Externals.IMDB)
+	}
+	return entry
+}
+
+// movieEntry converts a movie to an Entry message
+func movieEntry(movie OutputMovie) *anitraktv1.Entry {
+	entry := &anitraktv1.Entry{
+		MediaType:   anitraktv1.MediaType_MEDIA_TYPE_MOVIE,
+		MalId:       int32(movie.MyAnimeList.ID),
+		MalTitle:    movie.MyAnimeList.Title,
+		TraktId:     int32(movie.Trakt.ID),
+		TraktSlug:   movie.Trakt.Slug,
+		TraktTitle:  movie.Trakt.Title,
+		ReleaseYear: int32(movie.ReleaseYear),
+	}
+	if movie.Externals != nil {
+		entry.Tmdb = grpcInt32(movie.Externals.TMDB)
+		entry.Imdb = grpcString(movie.Externals.IMDB)
+		if movie.Externals.Letterboxd != nil {
+			entry.LetterboxdSlug = grpcString(movie.Externals.Letterboxd.Slug)
+		}
+	}
+	return entry
+}
+
+// changeMessage converts an EntryChange to a Change message
+func changeMessage(change EntryChange, recordedAt int64) (*anitraktv1.Change, error) {
+	msg := &anitraktv1.Change{
+		Type:       grpcChangeTypes[change.Change],
+		MediaType:  anitraktv1.MediaType_MEDIA_TYPE_SHOW,
+		MalId:      int32(change.MalID),
+		Title:      change.Title,
+		RecordedAt: recordedAt,
+	}
+	entry := func(doc json.RawMessage) (*anitraktv1.Entry, error) {
+		var show OutputShow
+		err := json.Unmarshal(doc, &show)
+		return showEntry(show), err
+	}
+	if change.MediaType == "movies" {
+		msg.MediaType = anitraktv1.MediaType_MEDIA_TYPE_MOVIE
+		entry = func(doc json.RawMessage) (*anitraktv1.Entry, error) {
+			var movie OutputMovie
+			err := json.Unmarshal(doc, &movie)
+			return movieEntry(movie), err
+		}
+	}
+
+	var err error
+	if change.New != nil {
+		if msg.Entry, err = entry(change.New); err != nil {
+			return nil, err
+		}
+	}
+	if change.Old != nil {
+		if msg.Previous, err = entry(change.Old); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// grpcInt32 converts an optional ID to an optional int32 field
+func grpcInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
+
+// grpcString converts an optional string to an optional string field, unset
+// when empty
+func grpcString(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	anitraktv1 "github.com/rensetsu/db.trakt.extended-anitrakt/proto/anitrakt/v1"
+)
+
+// testGRPCClient serves the test dataset over gRPC and returns a client
+func testGRPCClient(t *testing.T) (*server, anitraktv1.LookupServiceClient) {
+	t.Helper()
+	s := &server{}
+	s.data.Store(testDataset(t))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := s.newGRPCServer()
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, anitraktv1.NewLookupServiceClient(conn)
+}
+
+// malIDs lists the MAL IDs of entries
+func malIDs(entries []*anitraktv1.Entry) []int32 {
+	var ids []int32
+	for _, entry := range entries {
+		ids = append(ids, entry.GetMalId())
+	}
+	return ids
+}
+
+func TestGRPCLookups(t *testing.T) {
+	_, client := testGRPCClient(t)
+	ctx := context.Background()
+
+	resp, err := client.LookupByTrakt(ctx, &anitraktv1.LookupByTraktRequest{TraktId: 30857})
+	if err != nil {
+		t.Fatalf("LookupByTrakt: %v", err)
+	}
+	if got := malIDs(resp.GetEntries()); len(got) != 2 || got[0] != 1 || got[1] != 5 {
+		t.Fatalf("LookupByTrakt MAL IDs = %v, want [1 5]", got)
+	}
+	if entry := resp.GetEntries()[0]; entry.GetTvdb() != 76885 || entry.GetImdb() != "tt0213338" || entry.SeasonTmdb != nil {
+		t.Errorf("LookupByTrakt entry = %v", entry)
+	}
+
+	if _, err := client.LookupByMAL(ctx, &anitraktv1.LookupByMALRequest{MalId: 999}); status.Code(err) != codes.NotFound {
+		t.Fatalf("LookupByMAL unknown ID error = %v, want NOT_FOUND", err)
+	}
+
+	bulk, err := client.BulkLookup(ctx, &anitraktv1.BulkLookupRequest{
+		Source: anitraktv1.IdSource_ID_SOURCE_IMDB,
+		Ids:    []string{"tt0113568", "tt0000000"},
+	})
+	if err != nil {
+		t.Fatalf("BulkLookup: %v", err)
+	}
+	results := bulk.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("BulkLookup returned %d results, want 2", len(results))
+	}
+	if got := malIDs(results[0].GetEntries()); len(got) != 1 || got[0] != 43 || results[0].GetEntries()[0].GetLetterboxdSlug() != "ghost-in-the-shell" {
+		t.Fatalf("BulkLookup first result = %v, want MAL ID 43", results[0])
+	}
+	if got := malIDs(results[1].GetEntries()); len(got) != 0 {
+		t.Fatalf("BulkLookup second result MAL IDs = %v, want none", got)
+	}
+
+	_, err = client.BulkLookup(ctx, &anitraktv1.BulkLookupRequest{
+		Source:    anitraktv1.IdSource_ID_SOURCE_TVDB,
+		MediaType: anitraktv1.MediaType_MEDIA_TYPE_MOVIE,
+		Ids:       []string{"1"},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("BulkLookup of movies by TVDB ID error = %v, want INVALID_ARGUMENT", err)
+	}
+}
+
+func TestGRPCStreamChanges(t *testing.T) {
+	s, client := testGRPCClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamChanges(ctx, &anitraktv1.StreamChangesRequest{MediaType: anitraktv1.MediaType_MEDIA_TYPE_MOVIE})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subscription is registered once the call reaches the server
+	var updates chan []EntryChange
+	for updates == nil {
+		s.changes.mu.Lock()
+		for ch := range s.changes.subscribers {
+			updates = ch
+		}
+		s.changes.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	movie, _ := json.Marshal(testDataset(t).Movies[43])
+	s.changes.publish([]EntryChange{
+		{MediaType: "tv", Change: "removed", MalID: 5},
+		{MediaType: "movies", Change: "created", MalID: 43, Title: "Koukaku Kidoutai", New: movie},
+	})
+	change, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if change.GetType() != anitraktv1.ChangeType_CHANGE_TYPE_CREATED || change.GetMalId() != 43 ||
+		change.GetEntry().GetTraktSlug() != "ghost-in-the-shell-1995" || change.Previous != nil {
+		t.Errorf("change = %v", change)
+	}
+
+	// A subscriber dropped for falling behind ends the stream with a status
+	s.changes.unsubscribe(updates)
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Recv after the subscriber was dropped: %v, want UNAVAILABLE", err)
+	}
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// Guardrails cap how much one run may change an output file
+// (-max-removed-percent, -max-trakt-id-changes). An upstream glitch, such as
+// a scraper returning half the input or shifting mappings, then aborts the
+// run before anything is written instead of publishing the damage.
+type Guardrails struct {
+	MaxRemovedPercent float64 // entries removed, in percent of the existing output (0 = unlimited)
+	MaxTraktIDChanges int     // existing entries moved to another Trakt ID (0 = unlimited)
+	Override          bool    // -allow-changes: write anyway, e.g. after a deliberate cleanup
+	AllowShrink       bool    // -allow-shrink: replace an output file with a much smaller one
+}
+
+// shrinkTolerance is how many percent of its entries an output file may lose
+// in one write without -allow-shrink
+const shrinkTolerance = 10
+
+// changeVolume counts the changes the guardrails limit
+type changeVolume struct {
+	Removed        int
+	TraktIDChanges int
+}
+
+// countChangeVolume counts removed entries and updated entries whose Trakt
+// ID differs between the old and new document
+func countChangeVolume(changes []EntryChange) changeVolume {
+	var volume changeVolume
+	for _, change := range changes {
+		switch change.Change {
+		case "removed":
+			volume.Removed++
+		case "updated":
+			if changeTraktID(change.Old) != changeTraktID(change.New) {
+				volume.TraktIDChanges++
+			}
+		}
+	}
+	return volume
+}
+
+// changeTraktID reads trakt.id from an output document
+func changeTraktID(doc json.RawMessage) int {
+	var entry struct {
+		Trakt struct {
+			ID int `json:"id"`
+		} `json:"trakt"`
+	}
+	json.Unmarshal(doc, &entry)
+	return entry.Trakt.ID
+}
+
+// checkGuardrails returns an ExitGuardrail error when the changes a run
+// would write to outputFile, which held existing entries, exceed a guardrail
+func checkGuardrails(config Config, outputFile string, existing int, changes []EntryChange) error {
+	g := config.Guardrails
+	if g.Override {
+		return nil
+	}
+	volume := countChangeVolume(changes)
+	if g.MaxRemovedPercent > 0 && existing > 0 {
+		percent := float64(volume.Removed) / float64(existing) * 100
+		if percent > g.MaxRemovedPercent {
+			return exitErrorf(ExitGuardrail, "%s: run would remove %d of %d entries (%.1f%%, limit %.1f%%); nothing was written, pass -allow-changes if this is intended",
+				outputFile, volume.Removed, existing, percent, g.MaxRemovedPercent)
+		}
+	}
+	if g.MaxTraktIDChanges > 0 && volume.TraktIDChanges > g.MaxTraktIDChanges {
+		return exitErrorf(ExitGuardrail, "%s: run would change the Trakt ID of %d entries (limit %d); nothing was written, pass -allow-changes if this is intended",
+			outputFile, volume.TraktIDChanges, g.MaxTraktIDChanges)
+	}
+	if config.Verbose && (volume.Removed > 0 || volume.TraktIDChanges > 0) {
+		Debugf("\n%s: %d removed, %d Trakt ID changes (within guardrails)\n", outputFile, volume.Removed, volume.TraktIDChanges)
+	}
+	return nil
+}
+
+// checkShrink refuses to replace outputFile with one holding entries entries
+// when that drops more than shrinkTolerance percent of the entries on disk.
+// Unlike the opt-in guardrails it is always on, because a truncated or
+// malformed input otherwise silently destroys the dataset. A missing or
+// unparsable file has nothing to protect.
+func checkShrink(outputFile string, entries int, allow bool) error {
+	if allow || outputFile == StdioPath {
+		return nil
+	}
+	data, err := os.ReadFile(outputFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var onDisk []json.RawMessage
+	if json.Unmarshal(data, &onDisk) != nil {
+		return nil
+	}
+	return shrinkError(outputFile, len(onDisk), entries)
+}
+
+// checkJournalShrink is checkShrink for -journal runs, which leave the
+// canonical file alone: existing is the output with the pending journals
+// replayed, so a journal of mass removals is caught before compact folds it in
+func checkJournalShrink(outputFile string, existing, entries int, allow bool) error {
+	if allow {
+		return nil
+	}
+	return shrinkError(outputFile, existing, entries)
+}
+
+// shrinkError fails when going from before to after entries drops more than
+// shrinkTolerance percent of them
+func shrinkError(outputFile string, before, after int) error {
+	if lost := before - after; before > 0 && lost*100 > before*shrinkTolerance {
+		return exitErrorf(ExitGuardrail, "%s: refusing to replace %d entries with %d (more than %d%% fewer); nothing was written, pass -allow-shrink if this is intended",
+			outputFile, before, after, shrinkTolerance)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"testing"
+)
+
+func TestGuardrailsAbortBeforeWriting(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "tv_ex.json"
+	shows := testDataset(t).ShowList()
+	SaveJSON(outputFile, shows)
+
+	// Drop one of the two shows and move the other to another Trakt ID
+	moved := shows[1]
+	moved.Trakt.ID += 1000
+	resultsMap := map[int]OutputShow{moved.MyAnimeList.ID: moved}
+
+	tests := []struct {
+		guardrails Guardrails
+		want       int
+	}{
+		{Guardrails{MaxRemovedPercent: 5}, ExitGuardrail},
+		{Guardrails{MaxTraktIDChanges: 0}, ExitOK},
+		{Guardrails{MaxRemovedPercent: 50, MaxTraktIDChanges: 1}, ExitOK},
+		{Guardrails{MaxRemovedPercent: 5, Override: true}, ExitOK},
+	}
+	for _, tt := range tests {
+		err := checkGuardrails(Config{Guardrails: tt.guardrails}, outputFile, len(shows), DiffShows(shows, []OutputShow{moved}))
+		if got := ExitCode(err); got != tt.want {
+			t.Errorf("checkGuardrails(%+v) = %v, want exit code %d", tt.guardrails, err, tt.want)
+		}
+	}
+
+	config := Config{Guardrails: Guardrails{MaxRemovedPercent: 10, MaxTraktIDChanges: 5}}
+	if err := StoreResults(config, outputFile, shows, resultsMap); ExitCode(err) != ExitGuardrail {
+		t.Fatalf("StoreResults = %v, want an ExitGuardrail error", err)
+	}
+	if existing, _ := LoadShowOutput(outputFile); len(existing) != len(shows) {
+		t.Errorf("output has %d entries after the abort, want the original %d", len(existing), len(shows))
+	}
+}
+
+func TestCountChangeVolume(t *testing.T) {
+	shows := testDataset(t).ShowList()
+	renamed, moved := shows[0], shows[1]
+	renamed.MyAnimeList.Title += " (renamed)"
+	moved.Trakt.ID++
+
+	volume := countChangeVolume(DiffShows(shows, []OutputShow{renamed, moved}))
+	if volume != (changeVolume{TraktIDChanges: 1}) {
+		t.Errorf("volume = %+v, want one Trakt ID change", volume)
+	}
+	volume = countChangeVolume(DiffShows(shows, nil))
+	if volume != (changeVolume{Removed: len(shows)}) {
+		t.Errorf("volume = %+v, want %d removals", volume, len(shows))
+	}
+}
+
+func TestShrinkProtection(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "tv_ex.json"
+	shows := testDataset(t).ShowList()
+	SaveJSON(outputFile, shows)
+
+	resultsMap := map[int]OutputShow{shows[0].MyAnimeList.ID: shows[0]}
+	if err := StoreResults(Config{}, outputFile, nil, resultsMap); ExitCode(err) != ExitGuardrail {
+		t.Fatalf("StoreResults(truncated) = %v, want an ExitGuardrail error", err)
+	}
+	if existing, _ := LoadShowOutput(outputFile); len(existing) != len(shows) {
+		t.Fatalf("output has %d entries after the refusal, want the original %d", len(existing), len(shows))
+	}
+
+	config := Config{Guardrails: Guardrails{AllowShrink: true}}
+	if err := StoreResults(config, outputFile, nil, resultsMap); err != nil {
+		t.Fatalf("StoreResults with -allow-shrink: %v", err)
+	}
+	if existing, _ := LoadShowOutput(outputFile); len(existing) != 1 {
+		t.Errorf("output has %d entries, want 1", len(existing))
+	}
+
+	for _, tt := range []struct {
+		onDisk, entries int
+		want            int
+	}{
+		{100, 90, ExitOK},
+		{100, 89, ExitGuardrail},
+		{100, 120, ExitOK},
+	} {
+		SaveJSON(outputFile, make([]OutputShow, tt.onDisk))
+		if got := ExitCode(checkShrink(outputFile, tt.entries, false)); got != tt.want {
+			t.Errorf("checkShrink(%d → %d) exit code = %d, want %d", tt.onDisk, tt.entries, got, tt.want)
+		}
+	}
+	if err := checkShrink("missing.json", 0, false); err != nil {
+		t.Errorf("checkShrink(missing file) = %v", err)
+	}
+}
+
+func TestShrinkProtectionInJournalMode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "tv_ex.json"
+	shows := testDataset(t).ShowList()
+	SaveJSON(outputFile, shows)
+
+	existing, _ := LoadShowOutput(outputFile)
+	resultsMap := map[int]OutputShow{shows[0].MyAnimeList.ID: shows[0]}
+	if err := StoreResults(Config{Journal: true}, outputFile, existing, resultsMap); ExitCode(err) != ExitGuardrail {
+		t.Fatalf("StoreResults(truncated, -journal) = %v, want an ExitGuardrail error", err)
+	}
+	if journals, _ := pendingJournals(outputFile); len(journals) != 0 {
+		t.Errorf("%d journals written after the refusal, want none", len(journals))
+	}
+
+	config := Config{Journal: true, Guardrails: Guardrails{AllowShrink: true}}
+	if err := StoreResults(config, outputFile, existing, resultsMap); err != nil {
+		t.Fatalf("StoreResults with -allow-shrink: %v", err)
+	}
+	if existing, _ := LoadShowOutput(outputFile); len(existing) != 1 {
+		t.Errorf("output replays to %d entries, want 1", len(existing))
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// AnimeListXML is the root of ScudLee's anime-list.xml, the mapping format
+// read by the HAMA Plex agent and compatible anime scrapers
+type AnimeListXML struct {
+	XMLName xml.Name         `xml:"anime-list"`
+	Anime   []AnimeListAnime `xml:"anime"`
+}
+
+// AnimeListAnime maps one AniDB entry to TVDB/TMDB/IMDB. tvdbid is "movie"
+// for movies; defaulttvdbseason is "a" for absolute numbering.
+type AnimeListAnime struct {
+	AniDBID           int    `xml:"anidbid,attr"`
+	TVDBID            string `xml:"tvdbid,attr"`
+	DefaultTVDBSeason string `xml:"defaulttvdbseason,attr,omitempty"`
+	EpisodeOffset     string `xml:"episodeoffset,attr,omitempty"`
+	TMDBTV            string `xml:"tmdbtv,attr,omitempty"`
+	TMDBSeason        string `xml:"tmdbseason,attr,omitempty"`
+	TMDBID            string `xml:"tmdbid,attr,omitempty"`
+	IMDBID            string `xml:"imdbid,attr,omitempty"`
+	Name              string `xml:"name"`
+}
+
+// animeListSkips counts entries left out of an anime-list export
+type animeListSkips struct {
+	noAniDB    int
+	splitCour  int
+	noExternal int
+	duplicate  int
+}
+
+// RunExportHAMA implements the `export-hama` subcommand
+func RunExportHAMA(args []string) error {
+	fs := flag.NewFlagSet("export-hama", flag.ExitOnError)
+	output := fs.String("output", "dist/anime-list-anitrakt.xml", "Path of the anime-list XML to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file (empty to skip movies)")
+	animeAPIFile := fs.String("animeapi", "", "Path to animeapi.tsv for MAL → AniDB IDs (empty = fetch from animeapi.my.id)")
+	fs.Parse(args)
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+
+	_, malToRow, err := LoadAnimeAPITSV(*animeAPIFile)
+	if err != nil {
+		return err
+	}
+
+	list, skips := buildAnimeList(shows, movies, malToRow, false)
+	if err := writeAnimeListXML(*output, list); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d anime-list entries to %s\n", len(list.Anime), *output)
+	fmt.Printf("Skipped: %d without AniDB ID, %d split cours, %d without TVDB/TMDB, %d duplicate AniDB IDs\n",
+		skips.noAniDB, skips.splitCour, skips.noExternal, skips.duplicate)
+	return nil
+}
+
+// buildAnimeList converts the dataset into anime-list entries keyed by AniDB
+// ID. Split cours are left out because their Trakt season is not known, and
+// a wrong season is worse for a scraper than no mapping at all. Entries with
+// an absolute offset map to absolute numbering ("a"); episode offsets carry
+// over as episodeoffset. With requireTVDB, shows without a TVDB ID are left
+// out before duplicates are resolved, so they never take the AniDB ID of a
+// show that has one.
+func buildAnimeList(shows []OutputShow, movies []OutputMovie, malToRow map[int]AnimeAPIRow, requireTVDB bool) (AnimeListXML, animeListSkips) {
+	var list AnimeListXML
+	var skips animeListSkips
+	seen := make(map[int]bool)
+
+	for _, show := range shows {
+		row, ok := malToRow[show.MyAnimeList.ID]
+		if !ok || row.AniDB == 0 {
+			skips.noAniDB++
+			continue
+		}
+		if show.AbsoluteOffset == nil && (show.Trakt.IsSplitCour || show.Trakt.Season == nil) {
+			skips.splitCour++
+			continue
+		}
+		if show.Externals == nil || (show.Externals.TVDB == nil && (requireTVDB || show.Externals.TMDB == nil)) {
+			skips.noExternal++
+			continue
+		}
+		if seen[row.AniDB] {
+			skips.duplicate++
+			continue
+		}
+		seen[row.AniDB] = true
+
+		season, offset := "a", 0
+		if show.AbsoluteOffset != nil {
+			offset = *show.AbsoluteOffset
+		} else {
+			season, offset = strconv.Itoa(show.Trakt.Season.Number), show.Trakt.Season.EpisodeOffset
+		}
+		anime := AnimeListAnime{
+			AniDBID:           row.AniDB,
+			TVDBID:            "unknown",
+			DefaultTVDBSeason: season,
+			Name:              show.MyAnimeList.Title,
+		}
+		if offset != 0 {
+			anime.EpisodeOffset = strconv.Itoa(offset)
+		}
+		if show.Externals.TVDB != nil {
+			anime.TVDBID = strconv.Itoa(*show.Externals.TVDB)
+		}
+		if show.Externals.TMDB != nil {
+			anime.TMDBTV = strconv.Itoa(*show.Externals.TMDB)
+			anime.TMDBSeason = season
+		}
+		if show.Externals.IMDB != nil {
+			anime.IMDBID = *show.Externals.IMDB
+		}
+		list.Anime = append(list.Anime, anime)
+	}
+
+	for _, movie := range movies {
+		row, ok := malToRow[movie.MyAnimeList.ID]
+		if !ok || row.AniDB == 0 {
+			skips.noAniDB++
+			continue
+		}
+		if movie.Externals == nil || movie.Externals.TMDB == nil {
+			skips.noExternal++
+			continue
+		}
+		if seen[row.AniDB] {
+			skips.duplicate++
+			continue
+		}
+		seen[row.AniDB] = true
+
+		anime := AnimeListAnime{
+			AniDBID: row.AniDB,
+			TVDBID:  "movie",
+			TMDBID:  strconv.Itoa(*movie.Externals.TMDB),
+			Name:    movie.MyAnimeList.Title,
+		}
+		if movie.Externals.IMDB != nil {
+			anime.IMDBID = *movie.Externals.IMDB
+		}
+		list.Anime = append(list.Anime, anime)
+	}
+
+	sort.Slice(list.Anime, func(i, j int) bool {
+		return list.Anime[i].AniDBID < list.Anime[j].AniDBID
+	})
+	return list, skips
+}
+
+// writeAnimeListXML writes the list with an XML declaration, indented like
+// the upstream anime-list files
+func writeAnimeListXML(path string, list AnimeListXML) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := xml.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode anime-list: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+// writeAnimeListFixture writes the outputs and animeapi.tsv the anime-list
+// exporters read into the working directory. The TMDB-only MAL 6 shares its
+// AniDB ID with MAL 7, which has a TVDB ID; MAL 15 has no AniDB ID.
+func writeAnimeListFixture(t *testing.T) {
+	t.Helper()
+	os.WriteFile("tv_ex.json", []byte(`[
+		{"myanimelist":{"title":"Cowboy Bebop","id":1},"trakt":{"title":"Cowboy Bebop","id":30857,"slug":"cowboy-bebop","type":"shows","season":{"id":1,"number":1,"externals":null},"is_split_cour":false},"release_year":1998,"externals":{"tvdb":76885,"tmdb":30991,"imdb":"tt0213338","tvrage":null}},
+		{"myanimelist":{"title":"Trigun","id":6},"trakt":{"title":"Trigun","id":30865,"slug":"trigun","type":"shows","season":{"id":3,"number":1,"externals":null},"is_split_cour":false},"release_year":1998,"externals":{"tvdb":null,"tmdb":31003,"imdb":null,"tvrage":null}},
+		{"myanimelist":{"title":"Trigun (TVDB)","id":7},"trakt":{"title":"Trigun","id":30865,"slug":"trigun","type":"shows","season":{"id":4,"number":1,"episode_offset":2,"externals":null},"is_split_cour":false},"release_year":1998,"externals":{"tvdb":72248,"tmdb":31003,"imdb":"tt0251439","tvrage":null}},
+		{"myanimelist":{"title":"Witch Hunter Robin","id":8},"trakt":{"title":"Witch Hunter Robin","id":30866,"slug":"witch-hunter-robin","type":"shows","season":{"id":5,"number":1,"externals":null},"is_split_cour":false},"release_year":2002,"externals":{"tvdb":null,"tmdb":31004,"imdb":null,"tvrage":null}},
+		{"myanimelist":{"title":"Beet the Vandel Buster","id":15},"trakt":{"title":"Beet the Vandel Buster","id":30870,"slug":"beet-the-vandel-buster","type":"shows","season":{"id":6,"number":1,"externals":null},"is_split_cour":false},"release_year":2004,"externals":{"tvdb":78920,"tmdb":null,"imdb":null,"tvrage":null}}
+	]`), 0644)
+	os.WriteFile("movies_ex.json", []byte(`[
+		{"myanimelist":{"title":"Koukaku Kidoutai","id":43},"trakt":{"title":"Ghost in the Shell","id":1,"slug":"ghost-in-the-shell-1995","type":"movies"},"release_year":1995,"externals":{"tmdb":9323,"imdb":"tt0113568"}}
+	]`), 0644)
+	os.WriteFile("animeapi.tsv", []byte("anidb\tanilist\tkitsu\tmyanimelist\n"+
+		"23\t1\t1\t1\n2\t6\t6\t6\n2\t6\t6\t7\n86\t8\t8\t8\n61\t43\t43\t43\n"), 0644)
+}
+
+func TestRunExportHAMA(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeAnimeListFixture(t)
+
+	if err := RunExportHAMA([]string{"-output", "hama.xml", "-tv", "tv_ex.json", "-movies", "movies_ex.json", "-animeapi", "animeapi.tsv"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("hama.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "anime-list-anitrakt.xml", got)
+}
@@ -0,0 +1,264 @@
+package internal
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryRecord is what one run did to one output file, as appended to the
+// -history-file. Only counts are kept, so the history stays small enough to
+// commit.
+type HistoryRecord struct {
+	RunID         string `json:"run_id"`
+	RecordedAt    string `json:"recorded_at"`
+	MediaType     string `json:"media_type"` // as in the run summary, e.g. "tv (fribb)"
+	Total         int    `json:"total"`      // entries after the run
+	Created       int    `json:"created"`
+	Updated       int    `json:"updated"`
+	Modified      int    `json:"modified"`
+	NotFound      int    `json:"not_found"`       // entries the run did not find
+	NotFoundTotal int    `json:"not_found_total"` // size of the not-found list after the run
+	Healed        int    `json:"healed"`
+	Redirected    int    `json:"redirected"`
+	Failed        int    `json:"failed"`
+	Overrides     int    `json:"overrides"` // entries in the media type's override file
+	StoppedEarly  string `json:"stopped_early,omitempty"`
+}
+
+// historyMediaType maps a summary media type such as "tv (fribb)" to the
+// media type of its override file
+func historyMediaType(mediaType string) string {
+	base, _, _ := strings.Cut(mediaType, " ")
+	return base
+}
+
+// AppendHistory adds a record per output file the run saved to the history
+// at path. Streamed output is not part of the dataset and is left out.
+func (r *RunReport) AppendHistory(path, runID string) error {
+	if r == nil || path == "" {
+		return nil
+	}
+	r.mu.Lock()
+	stats := append([]ProcessingStats(nil), r.stats...)
+	outputFiles := append([]string(nil), r.outputFiles...)
+	r.mu.Unlock()
+
+	var history []HistoryRecord
+	LoadJSONOptional(path, &history)
+	added := 0
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, s := range stats {
+		if outputFiles[i] == StdioPath {
+			continue
+		}
+		var notFound []NotFoundEntry
+		LoadJSONOptional(notFoundPath(outputFiles[i]), &notFound)
+		history = append(history, HistoryRecord{
+			RunID:         runID,
+			RecordedAt:    now,
+			MediaType:     s.MediaType,
+			Total:         s.TotalAfter,
+			Created:       s.Created,
+			Updated:       s.Updated,
+			Modified:      s.Modified,
+			NotFound:      s.NotFound,
+			NotFoundTotal: len(notFound),
+			Healed:        s.Healed,
+			Redirected:    s.Redirected,
+			Failed:        s.Failed,
+			Overrides:     len(LoadOverrides(historyMediaType(s.MediaType))),
+			StoppedEarly:  s.StoppedEarly,
+		})
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveJSON(path, history)
+}
+
+// RunHistory implements the `history` subcommand, which prints the
+// -history-file as a table and can chart it as SVG
+func RunHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	path := fs.String("file", "stats/history.json", "History file written by runs with -history-file")
+	format := fs.String("format", "text", "Output format: text, markdown or csv")
+	mediaType := fs.String("media-type", "", "Only show this media type, e.g. tv or \"movies (fribb)\"")
+	last := fs.Int("last", 0, "Only show the last N records of each media type (0 = all)")
+	svgFile := fs.String("svg", "", "Also chart the entry and not-found totals over time to this SVG file")
+	fs.Parse(args)
+
+	var history []HistoryRecord
+	if err := LoadJSON(*path, &history); err != nil {
+		return err
+	}
+	history = filterHistory(history, *mediaType, *last)
+	if len(history) == 0 {
+		return fmt.Errorf("no history records in %s", *path)
+	}
+
+	switch *format {
+	case "text":
+		printHistoryText(history)
+	case "markdown":
+		printHistoryMarkdown(history)
+	case "csv":
+		if err := writeHistoryCSV(history); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q (expected text, markdown or csv)", *format)
+	}
+
+	if *svgFile != "" {
+		if err := os.WriteFile(*svgFile, []byte(historyChart(history)), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", *svgFile)
+	}
+	return nil
+}
+
+// filterHistory keeps the records of mediaType ("" for all), and of those
+// the last last per media type (0 for all)
+func filterHistory(history []HistoryRecord, mediaType string, last int) []HistoryRecord {
+	kept := map[string]int{}
+	var filtered []HistoryRecord
+	for i := len(history) - 1; i >= 0; i-- {
+		record := history[i]
+		if mediaType != "" && record.MediaType != mediaType {
+			continue
+		}
+		if last > 0 && kept[record.MediaType] >= last {
+			continue
+		}
+		kept[record.MediaType]++
+		filtered = append(filtered, record)
+	}
+	for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+		filtered[i], filtered[j] = filtered[j], filtered[i]
+	}
+	return filtered
+}
+
+// historyDate returns the day of a record, for tables
+func historyDate(record HistoryRecord) string {
+	if t, err := time.Parse(time.RFC3339, record.RecordedAt); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return record.RunID
+}
+
+// historyColumns are the column headers of every history table
+var historyColumns = []string{"Date", "Media Type", "Total", "Change", "Created", "Updated", "Not Found", "Overrides"}
+
+// historyRow renders one record for a table; Change is the difference to
+// the previous record of the same media type
+func historyRow(record HistoryRecord, previous map[string]int) []string {
+	change := "-"
+	if before, ok := previous[record.MediaType]; ok {
+		change = fmt.Sprintf("%+d", record.Total-before)
+	}
+	previous[record.MediaType] = record.Total
+	return []string{
+		historyDate(record), record.MediaType, strconv.Itoa(record.Total), change,
+		strconv.Itoa(record.Created), strconv.Itoa(record.Updated),
+		strconv.Itoa(record.NotFoundTotal), strconv.Itoa(record.Overrides),
+	}
+}
+
+func printHistoryText(history []HistoryRecord) {
+	fmt.Printf("%-10s  %-16s %7s %7s %7s %7s %9s %9s\n", "DATE", "MEDIA TYPE", "TOTAL", "CHANGE", "CREATED", "UPDATED", "NOT FOUND", "OVERRIDES")
+	previous := map[string]int{}
+	for _, record := range history {
+		row := historyRow(record, previous)
+		fmt.Printf("%-10s  %-16s %7s %7s %7s %7s %9s %9s\n", row[0], row[1], row[2], row[3], row[4], row[5], row[6], row[7])
+	}
+}
+
+func printHistoryMarkdown(history []HistoryRecord) {
+	fmt.Printf("| %s |\n", strings.Join(historyColumns, " | "))
+	fmt.Printf("|%s\n", strings.Repeat("------|", len(historyColumns)))
+	previous := map[string]int{}
+	for _, record := range history {
+		fmt.Printf("| %s |\n", strings.Join(historyRow(record, previous), " | "))
+	}
+}
+
+func writeHistoryCSV(history []HistoryRecord) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"run_id", "recorded_at", "media_type", "total", "created", "updated", "modified", "not_found", "not_found_total", "healed", "redirected", "failed", "overrides"})
+	for _, r := range history {
+		w.Write([]string{
+			r.RunID, r.RecordedAt, r.MediaType, strconv.Itoa(r.Total), strconv.Itoa(r.Created),
+			strconv.Itoa(r.Updated), strconv.Itoa(r.Modified), strconv.Itoa(r.NotFound),
+			strconv.Itoa(r.NotFoundTotal), strconv.Itoa(r.Healed), strconv.Itoa(r.Redirected),
+			strconv.Itoa(r.Failed), strconv.Itoa(r.Overrides),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// historyColors are the line colors of the media types in charts
+var historyColors = []string{"#48c", "#3a7", "#c84", "#a4c", "#888"}
+
+// historyChart draws a standalone SVG with, per media type, a solid line of
+// the entry total and a dashed line of the not-found total over time
+func historyChart(history []HistoryRecord) string {
+	const width, height, left, top, bottom = 640.0, 280.0, 50.0, 10.0, 40.0
+	var mediaTypes []string
+	series := map[string][]HistoryRecord{}
+	largest := 1
+	for _, record := range history {
+		if _, ok := series[record.MediaType]; !ok {
+			mediaTypes = append(mediaTypes, record.MediaType)
+		}
+		series[record.MediaType] = append(series[record.MediaType], record)
+		largest = max(largest, record.Total, record.NotFoundTotal)
+	}
+	longest := 1
+	for _, records := range series {
+		longest = max(longest, len(records))
+	}
+	x := func(i int) float64 {
+		if longest == 1 {
+			return left
+		}
+		return left + float64(i)*(width-left-10)/float64(longest-1)
+	}
+	y := func(v int) float64 { return top + (height-top-bottom)*(1-float64(v)/float64(largest)) }
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" font-family="sans-serif" font-size="11">`+"\n", width, height)
+	fmt.Fprintf(&svg, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#ccc"/>`+"\n", left, y(0), width-10, y(0))
+	fmt.Fprintf(&svg, `<text x="%.0f" y="%.0f" text-anchor="end">%d</text>`+"\n", left-4, y(largest)+4, largest)
+	fmt.Fprintf(&svg, `<text x="%.0f" y="%.0f" text-anchor="end">0</text>`+"\n", left-4, y(0)+4)
+	for i, mediaType := range mediaTypes {
+		color := historyColors[i%len(historyColors)]
+		records := series[mediaType]
+		var totals, notFound []string
+		for j, record := range records {
+			totals = append(totals, fmt.Sprintf("%.1f,%.1f", x(j), y(record.Total)))
+			notFound = append(notFound, fmt.Sprintf("%.1f,%.1f", x(j), y(record.NotFoundTotal)))
+		}
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`+"\n", color, strings.Join(totals, " "))
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-dasharray="4 3" points="%s"/>`+"\n", color, strings.Join(notFound, " "))
+		fmt.Fprintf(&svg, `<text x="%.0f" y="%.0f" fill="%s">%s: %d entries, %d not found</text>`+"\n",
+			left+float64(i%2)*280, height-bottom+18+float64(i/2)*14, color, html.EscapeString(mediaType), records[len(records)-1].Total, records[len(records)-1].NotFoundTotal)
+	}
+	fmt.Fprintf(&svg, `<text x="%.0f" y="%.0f" fill="#888" text-anchor="end">%s – %s (dashed: not found)</text>`+"\n",
+		width-10, top+10, historyDate(history[0]), historyDate(history[len(history)-1]))
+	svg.WriteString("</svg>\n")
+	return svg.String()
+}
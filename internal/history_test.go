@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAppendHistory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("json/overrides", 0755)
+	os.MkdirAll("json/not_found", 0755)
+	SaveJSON(overridesPath("tv"), []Override{{MalID: 1}, {MalID: 5}})
+	SaveJSON(notFoundPath("tv_ex.json"), []NotFoundEntry{{MalID: 99}})
+
+	for i, runID := range []string{"20261014T050000Z", "20261015T050000Z"} {
+		report := NewRunReport()
+		report.Add("tv_ex.json", ProcessingStats{MediaType: "tv (fribb)", TotalAfter: 10 + i, Created: 1})
+		report.Add(StdioPath, ProcessingStats{MediaType: "movies", TotalAfter: 3})
+		if err := report.AppendHistory("stats/history.json", runID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var history []HistoryRecord
+	if err := LoadJSON("stats/history.json", &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history has %d records, want 2 (streamed output is left out)", len(history))
+	}
+	if got := history[1]; got.RunID != "20261015T050000Z" || got.Total != 11 || got.Overrides != 2 || got.NotFoundTotal != 1 {
+		t.Errorf("record = %+v", got)
+	}
+
+	if last := filterHistory(history, "", 1); len(last) != 1 || last[0].Total != 11 {
+		t.Errorf("filterHistory(last 1) = %+v", last)
+	}
+	if none := filterHistory(history, "movies", 0); len(none) != 0 {
+		t.Errorf("filterHistory(movies) = %+v", none)
+	}
+	previous := map[string]int{}
+	historyRow(history[0], previous)
+	if row := historyRow(history[1], previous); row[3] != "+1" {
+		t.Errorf("change column = %q, want +1", row[3])
+	}
+	if svg := historyChart(history); strings.Count(svg, "<polyline") != 2 || !strings.Contains(svg, "tv (fribb): 11 entries, 1 not found") {
+		t.Errorf("historyChart = %s", svg)
+	}
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hookTimeout bounds one run of an exec hook for one entry
+const hookTimeout = 30 * time.Second
+
+// hookEnricher runs the -hook commands on each entry. A hook gets the entry
+// as JSON on stdin and ANITRAKT_MEDIA_TYPE (tv or movies) in its environment,
+// and prints a JSON object of external IDs, e.g. {"anidb": 23, "kitsu": "1"}.
+// Missing tvdb, tmdb and imdb IDs are filled from it; other IDs go to
+// extra_ids. A hook that prints nothing adds nothing.
+type hookEnricher struct{}
+
+func (hookEnricher) Name() string { return "hook" }
+
+func (hookEnricher) Applies(entry EnrichEntry) bool { return true }
+
+func (hookEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	var input any = entry.Show
+	mediaType, malID, title := "tv", 0, ""
+	if entry.Show != nil {
+		malID, title = entry.Show.MyAnimeList.ID, entry.Show.MyAnimeList.Title
+	} else {
+		input, mediaType = entry.Movie, "movies"
+		malID, title = entry.Movie.MyAnimeList.ID, entry.Movie.MyAnimeList.Title
+	}
+	ctx.Config.Progress.Phase("hook")
+
+	extra := map[string]string{}
+	failed := false
+	for _, command := range ctx.Config.Hooks {
+		ids, err := runHook(command, mediaType, input)
+		if err != nil {
+			log.Printf("Warning: Hook %s failed on MAL ID %d: %v", command, malID, err)
+			ctx.Config.Errors.Record("hook", malID, title, err)
+			failed = true
+			continue
+		}
+		for name, id := range ids {
+			if !fillHookExternal(entry, name, id) {
+				extra[name] = id
+			}
+		}
+	}
+	if failed && len(extra) == 0 {
+		hookEnricher{}.Retain(entry)
+		return
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	if entry.Show != nil {
+		entry.Show.ExtraIDs = extra
+	} else {
+		entry.Movie.ExtraIDs = extra
+	}
+	if ctx.Config.Verbose && len(extra) > 0 {
+		Debugf("\n    - Hook IDs: %v", extra)
+	}
+}
+
+// Retain keeps the previous extra IDs
+func (hookEnricher) Retain(entry EnrichEntry) {
+	if entry.Show != nil && entry.ExistingShow != nil {
+		entry.Show.ExtraIDs = entry.ExistingShow.ExtraIDs
+	} else if entry.Movie != nil && entry.ExistingMovie != nil {
+		entry.Movie.ExtraIDs = entry.ExistingMovie.ExtraIDs
+	}
+}
+
+// runHook runs one hook command, split on spaces and run without a shell,
+// and returns the IDs it printed as strings
+func runHook(command, mediaType string, entry any) (map[string]string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty hook command")
+	}
+	input, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "ANITRAKT_MEDIA_TYPE="+mediaType)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("output is not a JSON object of IDs: %w", err)
+	}
+	ids := make(map[string]string, len(raw))
+	for name, value := range raw {
+		var id any
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		decoder.Decode(&id)
+		switch id := id.(type) {
+		case json.Number:
+			ids[name] = id.String()
+		case string:
+			if id != "" {
+				ids[name] = id
+			}
+		case nil:
+		default:
+			return nil, fmt.Errorf("ID %q is neither a string nor a number", name)
+		}
+	}
+	return ids, nil
+}
+
+// fillHookExternal fills a missing tvdb, tmdb or imdb ID of the entry from a
+// hook. It reports whether the name is one of those, so the ID is not also
+// kept in extra_ids.
+func fillHookExternal(entry EnrichEntry, name, id string) bool {
+	fillInt := func(field **int, sources *map[string]string) {
+		if n, err := strconv.Atoi(id); err == nil && *field == nil {
+			*field = &n
+			setExternalSource(sources, name, "hook")
+		}
+	}
+	fillString := func(field **string, sources *map[string]string) {
+		if *field == nil || **field == "" {
+			*field = &id
+			setExternalSource(sources, name, "hook")
+		}
+	}
+	if show := entry.Show; show != nil {
+		if show.Externals == nil {
+			return false
+		}
+		switch name {
+		case "tvdb":
+			fillInt(&show.Externals.TVDB, &show.ExternalSources)
+		case "tmdb":
+			fillInt(&show.Externals.TMDB, &show.ExternalSources)
+		case "imdb":
+			fillString(&show.Externals.IMDB, &show.ExternalSources)
+		default:
+			return false
+		}
+		return true
+	}
+	movie := entry.Movie
+	if movie.Externals == nil {
+		return false
+	}
+	switch name {
+	case "tmdb":
+		fillInt(&movie.Externals.TMDB, &movie.ExternalSources)
+	case "imdb":
+		fillString(&movie.Externals.IMDB, &movie.ExternalSources)
+	default:
+		return false
+	}
+	return true
+}
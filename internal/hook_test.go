@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHookEnricher(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	hook := filepath.Join(dir, "hook.sh")
+	// Echoes the MAL ID it was given back as the anidb ID
+	os.WriteFile(hook, []byte(`#!/bin/sh
+test "$ANITRAKT_MEDIA_TYPE" = tv || exit 1
+id=$(sed -n 's/.*"id":\([0-9]*\),"url".*/\1/p' | head -n 1)
+echo "{\"anidb\": $id, \"kitsu\": \"7\", \"tvdb\": 76885, \"imdb\": \"\"}"
+`), 0755)
+
+	show := &OutputShow{Externals: &TraktExternalsShow{}}
+	show.MyAnimeList.ID = 1
+	ctx := EnrichContext{Config: Config{Hooks: []string{hook}}}
+	hookEnricher{}.Enrich(ctx, EnrichEntry{Show: show})
+	if show.ExtraIDs["anidb"] != "1" || show.ExtraIDs["kitsu"] != "7" || len(show.ExtraIDs) != 2 {
+		t.Errorf("extra_ids = %v, want anidb 1 and kitsu 7", show.ExtraIDs)
+	}
+	if show.Externals.TVDB == nil || *show.Externals.TVDB != 76885 || show.ExternalSources["tvdb"] != "hook" {
+		t.Errorf("missing TVDB ID not filled from the hook: %v, %v", show.Externals.TVDB, show.ExternalSources)
+	}
+
+	// A failing hook keeps what the entry had
+	movie := &OutputMovie{Externals: &TraktExternalsMovie{}}
+	existing := &OutputMovie{ExtraIDs: map[string]string{"anidb": "5"}}
+	hookEnricher{}.Enrich(ctx, EnrichEntry{Movie: movie, ExistingMovie: existing})
+	if movie.ExtraIDs["anidb"] != "5" {
+		t.Errorf("failed hook dropped the previous extra_ids: %v", movie.ExtraIDs)
+	}
+}
@@ -0,0 +1,315 @@
+package internal
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// growthDays is how many days of changelogs the growth chart covers
+const growthDays = 30
+
+// RunReport collects the statistics of each media type processed in a run
+// for the -report-html file and the -history-file. A nil RunReport records
+// nothing, so callers need not check whether either is set.
+type RunReport struct {
+	mu          sync.Mutex
+	stats       []ProcessingStats
+	outputFiles []string // output file of each stats
+}
+
+// NewRunReport returns an empty run report
+func NewRunReport() *RunReport {
+	return &RunReport{}
+}
+
+// Add records the statistics of one processed media type, saved to outputFile
+func (r *RunReport) Add(outputFile string, stats ProcessingStats) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats = append(r.stats, stats)
+	r.outputFiles = append(r.outputFiles, outputFile)
+}
+
+// reportSection is one table of entries, e.g. the created ones
+type reportSection struct {
+	Title string
+	Rows  []reportRow
+}
+
+type reportRow struct {
+	MediaType string
+	ChangeDetail
+}
+
+// reportPage is the data of the report template
+type reportPage struct {
+	GeneratedAt string
+	WallTime    time.Duration
+	Stats       []ProcessingStats
+	Sections    []reportSection
+	TotalsChart template.HTML
+	GrowthChart template.HTML
+	Requests    int64
+	Retries     int64
+	Endpoints   []reportEndpoint
+}
+
+type reportEndpoint struct {
+	Name string
+	EndpointUsage
+}
+
+// reportTemplate is a standalone page: styles, the table sorting script and
+// the SVG charts are inline, so the file can be opened straight from a CI
+// artifact
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Run report {{.GeneratedAt}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 1.5rem; color: #222; max-width: 75rem; }
+table { border-collapse: collapse; margin-bottom: 1rem; }
+th, td { text-align: left; padding: 0.25rem 0.75rem 0.25rem 0; border-bottom: 1px solid #eee; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th::after { content: " ↕"; color: #aaa; }
+.num { text-align: right; }
+svg text { font-size: 11px; fill: #444; }
+.created { fill: #3a7; } .updated { fill: #48c; } .removed { fill: #c44; } .before { fill: #bbb; } .after { fill: #48c; }
+.stopped { color: #a33; }
+</style>
+</head>
+<body>
+<h1>Run report</h1>
+<p>Generated {{.GeneratedAt}}, wall time {{.WallTime}}, {{.Requests}} API requests, {{.Retries}} retries.</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Media type</th><th class="num">Before</th><th class="num">After</th><th class="num">Created</th><th class="num">Updated</th><th class="num">Modified</th><th class="num">Not found</th><th class="num">Healed</th><th class="num">Failed</th></tr>
+{{range .Stats}}<tr><td>{{.MediaType}}</td><td class="num">{{.TotalBefore}}</td><td class="num">{{.TotalAfter}}</td><td class="num">{{.Created}}</td><td class="num">{{.Updated}}</td><td class="num">{{.Modified}}</td><td class="num">{{.NotFound}}</td><td class="num">{{.Healed}}</td><td class="num">{{.Failed}}</td></tr>
+{{end}}</table>
+{{range .Stats}}{{if .StoppedEarly}}<p class="stopped">{{.MediaType}} stopped early: {{.StoppedEarly}}</p>{{end}}{{end}}
+{{.TotalsChart}}
+
+<h2>Dataset growth</h2>
+{{if .GrowthChart}}{{.GrowthChart}}{{else}}<p>No changelogs to chart.</p>{{end}}
+
+{{range .Sections}}
+<h2>{{.Title}} ({{len .Rows}})</h2>
+<table class="sortable">
+<thead><tr><th>Media type</th><th>Title</th><th class="num">MAL ID</th><th>Reason</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.MediaType}}</td><td>{{.Title}}</td><td class="num"><a href="https://myanimelist.net/anime/{{.MalID}}">{{.MalID}}</a></td><td>{{.Reason}}</td></tr>
+{{end}}</tbody>
+</table>
+{{end}}
+
+{{if .Endpoints}}<h2>Endpoints</h2>
+<table class="sortable">
+<thead><tr><th>Endpoint</th><th class="num">Requests</th><th class="num">Retries</th><th class="num">Bytes</th></tr></thead>
+<tbody>
+{{range .Endpoints}}<tr><td>{{.Name}}</td><td class="num">{{.Requests}}</td><td class="num">{{.Retries}}</td><td class="num">{{.Bytes}}</td></tr>
+{{end}}</tbody>
+</table>{{end}}
+
+<script>
+for (const table of document.querySelectorAll("table.sortable")) {
+  table.querySelectorAll("th").forEach((th, column) => {
+    let ascending = true;
+    th.addEventListener("click", () => {
+      const body = table.tBodies[0];
+      const rows = Array.from(body.rows);
+      const value = row => row.cells[column].textContent;
+      const numeric = rows.every(row => value(row) === "" || !isNaN(value(row)));
+      rows.sort((a, b) => numeric ? value(a) - value(b) : value(a).localeCompare(value(b)));
+      if (!ascending) rows.reverse();
+      ascending = !ascending;
+      body.append(...rows);
+    });
+  });
+}
+</script>
+</body>
+</html>
+`))
+
+// Write renders the report to path, with the growth of the dataset taken
+// from the changelogs in changelogDir
+func (r *RunReport) Write(path string, resources ResourceReport, changelogDir string) error {
+	if r == nil || path == "" {
+		return nil
+	}
+	r.mu.Lock()
+	stats := append([]ProcessingStats(nil), r.stats...)
+	r.mu.Unlock()
+
+	page := reportPage{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		WallTime:    resources.WallTime.Round(time.Second),
+		Stats:       stats,
+		Sections:    reportSections(stats),
+		TotalsChart: totalsChart(stats),
+	}
+	if days := changelogGrowth(changelogDir, time.Now(), growthDays); len(days) > 0 {
+		page.GrowthChart = growthChart(days)
+	}
+	for _, name := range sortedKeys(resources.Endpoints) {
+		usage := resources.Endpoints[name]
+		page.Requests += usage.Requests
+		page.Retries += usage.Retries
+		page.Endpoints = append(page.Endpoints, reportEndpoint{name, usage})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, page); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// reportSections lists the entry tables of the report, skipping empty ones
+func reportSections(stats []ProcessingStats) []reportSection {
+	kinds := []struct {
+		title   string
+		details func(ProcessingStats) []ChangeDetail
+	}{
+		{"Created", func(s ProcessingStats) []ChangeDetail { return s.CreatedDetails }},
+		{"Updated", func(s ProcessingStats) []ChangeDetail { return s.UpdatedDetails }},
+		{"Modified via override", func(s ProcessingStats) []ChangeDetail { return s.ModifiedDetails }},
+		{"Not found", func(s ProcessingStats) []ChangeDetail { return s.NotFoundDetails }},
+		{"Healed", func(s ProcessingStats) []ChangeDetail { return s.HealedDetails }},
+		{"Trakt redirects", func(s ProcessingStats) []ChangeDetail { return s.RedirectDetails }},
+		{"Letterboxd not found", func(s ProcessingStats) []ChangeDetail { return s.LetterboxdNotFoundDetails }},
+		{"External ID checks", func(s ProcessingStats) []ChangeDetail { return s.ValidationDetails }},
+		{"External ID collisions", func(s ProcessingStats) []ChangeDetail { return s.CollisionDetails }},
+		{"Warnings", func(s ProcessingStats) []ChangeDetail { return warningDetails(s.WarningDetails) }},
+		{"Duplicates", func(s ProcessingStats) []ChangeDetail { return s.DuplicateDetails }},
+	}
+	var sections []reportSection
+	for _, kind := range kinds {
+		section := reportSection{Title: kind.title}
+		for _, s := range stats {
+			for _, detail := range kind.details(s) {
+				section.Rows = append(section.Rows, reportRow{s.MediaType, detail})
+			}
+		}
+		if len(section.Rows) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// dayGrowth counts one day's changelog entries by change
+type dayGrowth struct {
+	Date                      string
+	Created, Updated, Removed int
+}
+
+// changelogGrowth counts the changes in the changelogs of the days days up
+// to now, oldest first; days without a changelog are left out
+func changelogGrowth(dir string, now time.Time, days int) []dayGrowth {
+	if dir == "" {
+		return nil
+	}
+	var growth []dayGrowth
+	for i := days - 1; i >= 0; i-- {
+		day := now.AddDate(0, 0, -i)
+		var changelog Changelog
+		LoadJSONOptional(changelogPath(dir, day), &changelog)
+		if len(changelog.Changes) == 0 {
+			continue
+		}
+		count := dayGrowth{Date: day.UTC().Format("2006-01-02")}
+		for _, change := range changelog.Changes {
+			switch change.Change {
+			case "created":
+				count.Created++
+			case "updated":
+				count.Updated++
+			case "removed":
+				count.Removed++
+			}
+		}
+		growth = append(growth, count)
+	}
+	return growth
+}
+
+// totalsChart draws the entry count of each media type before and after the
+// run as pairs of horizontal bars
+func totalsChart(stats []ProcessingStats) template.HTML {
+	if len(stats) == 0 {
+		return ""
+	}
+	const labelWidth, barWidth, barHeight = 130.0, 420.0, 14.0
+	largest := 1
+	for _, s := range stats {
+		largest = max(largest, s.TotalBefore, s.TotalAfter)
+	}
+	var svg strings.Builder
+	height := float64(len(stats))*(2*barHeight+12) + 4
+	fmt.Fprintf(&svg, `<svg width="%.0f" height="%.0f" role="img" aria-label="Entries before and after the run">`, labelWidth+barWidth+60, height)
+	y := 2.0
+	for _, s := range stats {
+		fmt.Fprintf(&svg, `<text x="0" y="%.0f">%s</text>`, y+barHeight+4, html.EscapeString(s.MediaType))
+		for _, bar := range []struct {
+			class string
+			total int
+		}{{"before", s.TotalBefore}, {"after", s.TotalAfter}} {
+			width := barWidth * float64(bar.total) / float64(largest)
+			fmt.Fprintf(&svg, `<rect class="%s" x="%.0f" y="%.0f" width="%.1f" height="%.0f"><title>%s %s: %d</title></rect>`,
+				bar.class, labelWidth, y, width, barHeight, html.EscapeString(s.MediaType), bar.class, bar.total)
+			fmt.Fprintf(&svg, `<text x="%.1f" y="%.0f">%d</text>`, labelWidth+width+4, y+barHeight-3, bar.total)
+			y += barHeight
+		}
+		y += 12
+	}
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// growthChart draws the created, updated and removed entries of each day as
+// grouped vertical bars
+func growthChart(days []dayGrowth) template.HTML {
+	const chartHeight, groupWidth, barWidth = 160.0, 30.0, 8.0
+	largest := 1
+	for _, day := range days {
+		largest = max(largest, day.Created, day.Updated, day.Removed)
+	}
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg width="%.0f" height="%.0f" role="img" aria-label="Changes per day">`, float64(len(days))*groupWidth+10, chartHeight+50)
+	for i, day := range days {
+		x := float64(i)*groupWidth + 5
+		for j, bar := range []struct {
+			class string
+			count int
+		}{{"created", day.Created}, {"updated", day.Updated}, {"removed", day.Removed}} {
+			height := chartHeight * float64(bar.count) / float64(largest)
+			fmt.Fprintf(&svg, `<rect class="%s" x="%.0f" y="%.1f" width="%.0f" height="%.1f"><title>%s %s: %d</title></rect>`,
+				bar.class, x+float64(j)*barWidth, chartHeight-height, barWidth, height, day.Date, bar.class, bar.count)
+		}
+		fmt.Fprintf(&svg, `<text x="%.0f" y="%.0f" transform="rotate(60 %.0f %.0f)">%s</text>`, x, chartHeight+12, x, chartHeight+12, day.Date[5:])
+	}
+	svg.WriteString(`</svg>`)
+	legend := `<p><svg width="10" height="10"><rect class="created" width="10" height="10"/></svg> created ` +
+		`<svg width="10" height="10"><rect class="updated" width="10" height="10"/></svg> updated ` +
+		`<svg width="10" height="10"><rect class="removed" width="10" height="10"/></svg> removed</p>`
+	return template.HTML(svg.String() + legend)
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunReportWrite(t *testing.T) {
+	dir := t.TempDir()
+	shows := testDataset(t).ShowList()
+	now := time.Now()
+	SaveJSON(changelogPath(dir, now.AddDate(0, 0, -1)), Changelog{Changes: DiffShows(nil, shows)})
+	SaveJSON(changelogPath(dir, now), Changelog{Changes: DiffShows(shows, shows[:1])})
+
+	report := NewRunReport()
+	report.Add("tv_ex.json", ProcessingStats{
+		MediaType: "tv", TotalBefore: 2, TotalAfter: 3, Created: 1,
+		CreatedDetails:  []ChangeDetail{{MalID: 7, Title: "<i>New</i>", Reason: "new input entry"}},
+		NotFoundDetails: []ChangeDetail{{MalID: 99, Title: "Missing", Reason: "404"}},
+	})
+	report.Add("movies_ex.json", ProcessingStats{MediaType: "movies", TotalBefore: 1, TotalAfter: 1})
+	path := dir + "/logs/report.html"
+	resources := ResourceReport{Endpoints: map[string]EndpointUsage{"api.trakt.tv/shows": {Requests: 4, Retries: 1}}}
+	if err := report.Write(path, resources, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<h2>Created (1)</h2>", "<h2>Not found (1)</h2>", "&lt;i&gt;New&lt;/i&gt;",
+		`class="sortable"`, "4 API requests, 1 retries", `aria-label="Changes per day"`,
+		`<rect class="after"`, "api.trakt.tv/shows",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(page, "<h2>Updated") {
+		t.Errorf("report has a table for a category without entries")
+	}
+
+	growth := changelogGrowth(dir, now, growthDays)
+	if len(growth) != 2 || growth[0].Created != len(shows) || growth[1].Removed != len(shows)-1 {
+		t.Errorf("changelogGrowth = %+v", growth)
+	}
+
+	var none *RunReport
+	none.Add("tv_ex.json", ProcessingStats{})
+	if err := none.Write(path, resources, dir); err != nil {
+		t.Errorf("nil report: %v", err)
+	}
+}
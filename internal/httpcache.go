@@ -0,0 +1,177 @@
+package internal
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datasetVersion identifies the served output files for ETag and
+// Last-Modified: the checksums and generation time the manifest records for
+// them. Files the manifest does not list (or lists with another size, i.e.
+// it is stale) are hashed directly and dated by their modification time.
+func datasetVersion(manifestFile string, files ...string) (string, time.Time) {
+	var manifest Manifest
+	LoadJSONOptional(manifestFile, &manifest)
+	generated, _ := time.Parse(time.RFC3339, manifest.GeneratedAt)
+	listed := make(map[string]ManifestFileEntry, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		listed[filepath.ToSlash(filepath.Clean(entry.Path))] = entry
+	}
+
+	h := sha256.New()
+	var modTime time.Time
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			io.WriteString(h, path+":missing\n")
+			continue
+		}
+		if entry, ok := listed[filepath.ToSlash(filepath.Clean(path))]; ok && entry.Size == info.Size() && !generated.IsZero() {
+			io.WriteString(h, path+":"+entry.SHA256+"\n")
+			modTime = later(modTime, generated)
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			sum := sha256.Sum256(data)
+			io.WriteString(h, path+":"+hex.EncodeToString(sum[:])+"\n")
+		}
+		modTime = later(modTime, info.ModTime())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:20], modTime.UTC().Truncate(time.Second)
+}
+
+// later returns the later of two times
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// cacheable adds validators and caching headers to the GET and HEAD
+// responses of a dataset handler and answers conditional requests with 304.
+// Responses only depend on the URL and the dataset, so the dataset version is
+// a valid ETag for every URL; it is weak because the body may be gzipped.
+func (s *server) cacheable(maxAge time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := s.dataset()
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || data.Version == "" {
+			next(w, r)
+			return
+		}
+		etag := `W/"` + data.Version + `"`
+		h := w.Header()
+		h.Set("ETag", etag)
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+		if !data.ModTime.IsZero() {
+			h.Set("Last-Modified", data.ModTime.Format(http.TimeFormat))
+		}
+		if notModified(r, etag, data.ModTime) {
+			h.Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the client
+// sent no ETag (RFC 9110, section 13.2.2)
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modTime.After(since)
+	}
+	return false
+}
+
+// gzipResponses compresses responses for clients that accept gzip
+func gzipResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gz := &gzipResponseWriter{ResponseWriter: w}
+		defer gz.Close()
+		next.ServeHTTP(gz, r)
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body once the handler writes one;
+// bodiless responses such as 304 pass through unchanged
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	if status != http.StatusNoContent && status != http.StatusNotModified && status >= http.StatusOK {
+		g.Header().Set("Content-Encoding", "gzip")
+		g.Header().Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz == nil {
+		return g.ResponseWriter.Write(p)
+	}
+	return g.gz.Write(p)
+}
+
+// Flush lets streaming handlers push compressed data out
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the gzip stream
+func (g *gzipResponseWriter) Close() error {
+	if g.gz == nil {
+		return nil
+	}
+	return g.gz.Close()
+}
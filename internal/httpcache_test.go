@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestServeConditionalGET(t *testing.T) {
+	s := &server{}
+	data := testDataset(t)
+	data.Version = "v1"
+	data.ModTime = time.Date(2025, 1, 1, 5, 0, 0, 0, time.UTC)
+	s.data.Store(data)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /graphql/schema", s.cacheable(time.Minute, s.handleGraphQLSchema))
+	ts := httptest.NewServer(gzipResponses(mux))
+	t.Cleanup(ts.Close)
+
+	get := func(header, value string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/graphql/schema", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		// Setting Accept-Encoding ourselves keeps the client from decoding gzip
+		if req.Header.Get("Accept-Encoding") == "" {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := get("", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != `W/"v1"` || resp.Header.Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("GET = %d %v", resp.StatusCode, resp.Header)
+	}
+	if got := resp.Header.Get("Last-Modified"); got != "Wed, 01 Jan 2025 05:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	plain, _ := io.ReadAll(resp.Body)
+
+	tests := []struct {
+		header, value string
+		want          int
+	}{
+		{"If-None-Match", `W/"v1"`, http.StatusNotModified},
+		{"If-None-Match", `"v0", "v1"`, http.StatusNotModified},
+		{"If-None-Match", `"v0"`, http.StatusOK},
+		{"If-Modified-Since", "Wed, 01 Jan 2025 05:00:00 GMT", http.StatusNotModified},
+		{"If-Modified-Since", "Tue, 31 Dec 2024 05:00:00 GMT", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := get(tt.header, tt.value); resp.StatusCode != tt.want {
+			t.Errorf("%s: %s = %d, want %d", tt.header, tt.value, resp.StatusCode, tt.want)
+		}
+	}
+
+	resp = get("Accept-Encoding", "gzip, br")
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", resp.Header.Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != string(plain) {
+		t.Error("gzipped body differs from the plain one")
+	}
+	if resp := get("Accept-Encoding", "gzip;q=0"); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("gzip sent although the client refused it")
+	}
+}
+
+func TestDatasetVersion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("tv_ex.json", []byte(`[]`), 0644)
+	SaveJSON("manifest.json", Manifest{
+		GeneratedAt: "2025-01-01T05:00:00Z",
+		Files:       []ManifestFileEntry{{Path: "tv_ex.json", Size: 2, SHA256: "abc"}},
+	})
+
+	version, modTime := datasetVersion("manifest.json", "tv_ex.json", "movies_ex.json")
+	if !modTime.Equal(time.Date(2025, 1, 1, 5, 0, 0, 0, time.UTC)) {
+		t.Errorf("modTime = %v, want the manifest's generated_at", modTime)
+	}
+	// A file the manifest no longer describes is hashed directly
+	os.WriteFile("tv_ex.json", []byte(`[{}]`), 0644)
+	if changed, _ := datasetVersion("manifest.json", "tv_ex.json", "movies_ex.json"); changed == version {
+		t.Error("version did not change with the output file")
+	}
+}
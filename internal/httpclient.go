@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSettings tunes the transport every API client shares
+type HTTPSettings struct {
+	MaxIdleConnsPerHost int           // idle keep-alive connections kept per host
+	MaxConnsPerHost     int           // 0 = unlimited
+	IdleConnTimeout     time.Duration // how long an idle connection is kept
+	DisableHTTP2        bool          // stay on HTTP/1.1
+}
+
+// DefaultHTTPSettings keeps enough idle connections per host for the
+// Letterboxd workers and the enrichment lookups of one entry
+func DefaultHTTPSettings() HTTPSettings {
+	return HTTPSettings{
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+var (
+	transportMu     sync.Mutex
+	sharedTransport = newTransport(DefaultHTTPSettings())
+)
+
+// newTransport builds a pooled transport from Go's default one, keeping its
+// proxy, dial and TLS handshake settings
+func newTransport(settings HTTPSettings) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = settings.MaxConnsPerHost
+	transport.IdleConnTimeout = settings.IdleConnTimeout
+	transport.ForceAttemptHTTP2 = !settings.DisableHTTP2
+	if settings.DisableHTTP2 {
+		// A non-nil empty map turns off the bundled HTTP/2 support
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return transport
+}
+
+// ConfigureHTTP replaces the shared transport. Clients made earlier keep
+// the transport they were made with, so call it before any request.
+func ConfigureHTTP(settings HTTPSettings) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	sharedTransport.CloseIdleConnections()
+	sharedTransport = newTransport(settings)
+}
+
+// NewHTTPClient returns a client on the shared transport. Clients differ only
+// in their timeout, so connections to a host are reused across all of them.
+// Their requests are counted in the resource summary.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	return &http.Client{Transport: countingTransport{base: sharedTransport}, Timeout: timeout}
+}
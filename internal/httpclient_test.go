@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharedTransport(t *testing.T) {
+	t.Cleanup(func() { ConfigureHTTP(DefaultHTTPSettings()) })
+
+	a, b := NewHTTPClient(time.Second), NewHTTPClient(time.Minute)
+	if a.Transport != b.Transport {
+		t.Error("clients do not share a transport")
+	}
+
+	ConfigureHTTP(HTTPSettings{MaxIdleConnsPerHost: 2, MaxConnsPerHost: 4, IdleConnTimeout: time.Second, DisableHTTP2: true})
+	c := NewHTTPClient(time.Second)
+	if c.Transport == a.Transport {
+		t.Fatal("ConfigureHTTP kept the old transport")
+	}
+	transport := sharedTransport
+	if transport.MaxIdleConnsPerHost != 2 || transport.MaxConnsPerHost != 4 || transport.IdleConnTimeout != time.Second {
+		t.Errorf("transport settings not applied: %+v", transport)
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("HTTP/2 still enabled with DisableHTTP2")
+	}
+}
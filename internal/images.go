@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"net/http"
+	"strings"
+)
+
+// tmdbImageBase prefixes TMDB poster_path and backdrop_path values
+const tmdbImageBase = "https://image.tmdb.org/t/p/original"
+
+// Images holds artwork URLs added to the output with -images
+type Images struct {
+	Poster *string `json:"poster"`
+	Fanart *string `json:"fanart"`
+}
+
+// traktImages picks the first poster and fanart Trakt returned, or nil when
+// -images is off or Trakt has neither
+func traktImages(config Config, images *TraktImages) *Images {
+	if !config.Images || images == nil {
+		return nil
+	}
+	result := &Images{Poster: firstImageURL(images.Poster), Fanart: firstImageURL(images.Fanart)}
+	if result.Poster == nil && result.Fanart == nil {
+		return nil
+	}
+	return result
+}
+
+// firstImageURL turns the first Trakt image path into an https URL
+func firstImageURL(paths []string) *string {
+	if len(paths) == 0 || paths[0] == "" {
+		return nil
+	}
+	url := paths[0]
+	if !strings.HasPrefix(url, "http") {
+		url = "https://" + url
+	}
+	return &url
+}
+
+// fillTMDBImages completes images with TMDB's poster and backdrop when Trakt
+// had none. It needs the tmdb enrichment and reuses its cached response.
+func fillTMDBImages(client *http.Client, config Config, mediaType string, tmdbID *int, images **Images) {
+	if !config.Images || !tmdbEnabled(config) || tmdbID == nil {
+		return
+	}
+	if *images != nil && (*images).Poster != nil && (*images).Fanart != nil {
+		return
+	}
+	details, err := FetchTMDBDetails(client, config, mediaType, *tmdbID)
+	if err != nil {
+		return
+	}
+	result := &Images{}
+	if *images != nil {
+		*result = **images
+	}
+	if result.Poster == nil && details.PosterPath != "" {
+		url := tmdbImageBase + details.PosterPath
+		result.Poster = &url
+	}
+	if result.Fanart == nil && details.BackdropPath != "" {
+		url := tmdbImageBase + details.BackdropPath
+		result.Fanart = &url
+	}
+	if result.Poster != nil || result.Fanart != nil {
+		*images = result
+	}
+}
@@ -0,0 +1,23 @@
+package internal
+
+import "testing"
+
+func TestTraktImages(t *testing.T) {
+	images := &TraktImages{
+		Poster: []string{"walter-r2.trakt.tv/images/movies/000/001/posters/medium/a.jpg.webp", "ignored"},
+	}
+	if got := traktImages(Config{}, images); got != nil {
+		t.Errorf("images without -images = %+v, want nil", got)
+	}
+
+	got := traktImages(Config{Images: true}, images)
+	if got == nil || got.Poster == nil || *got.Poster != "https://walter-r2.trakt.tv/images/movies/000/001/posters/medium/a.jpg.webp" {
+		t.Fatalf("poster = %+v", got)
+	}
+	if got.Fanart != nil {
+		t.Errorf("fanart = %q, want nil", *got.Fanart)
+	}
+	if got := traktImages(Config{Images: true}, &TraktImages{}); got != nil {
+		t.Errorf("empty Trakt images = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// InputSource supplies input mappings. A source returns the entries of the
+// media type it was created for; the other slice is nil.
+type InputSource interface {
+	Load() ([]InputShow, []InputMovie, error)
+}
+
+// AniTraktScheme prefixes an input read from the upstream anitrakt index:
+// "anitrakt:" for its main branch, "anitrakt:<ref>" for a branch, tag or
+// commit
+const AniTraktScheme = "anitrakt:"
+
+// aniTraktIndexURL is where the upstream index publishes each media type
+const aniTraktIndexURL = "https://raw.githubusercontent.com/rensetsu/db.trakt.anitrakt/%s/db/%s.json"
+
+// NewInputSource returns the source of a -tv / -movies value: the upstream
+// index for AniTraktScheme, the scraped site for AniTraktSiteScheme, a
+// download for http(s) URLs, otherwise a local file or stdin (StdioPath)
+func NewInputSource(path, mediaType string) InputSource {
+	switch {
+	case strings.HasPrefix(path, AniTraktSiteScheme):
+		return AniTraktSiteSource{Site: strings.TrimPrefix(path, AniTraktSiteScheme), MediaType: mediaType}
+	case strings.HasPrefix(path, AniTraktScheme):
+		return AniTraktSource{Ref: strings.TrimPrefix(path, AniTraktScheme), MediaType: mediaType}
+	case isURL(path):
+		return URLSource{URL: path, MediaType: mediaType}
+	}
+	return FileSource{Path: path, MediaType: mediaType}
+}
+
+// isSchemeInput reports whether an input names a source rather than a file,
+// so it is neither globbed nor used to name the output
+func isSchemeInput(path string) bool {
+	return strings.HasPrefix(path, AniTraktScheme) || strings.HasPrefix(path, AniTraktSiteScheme)
+}
+
+// FileSource reads a local JSON file, or stdin for StdioPath
+type FileSource struct {
+	Path      string
+	MediaType string // "tv" or "movies"
+}
+
+func (s FileSource) Load() ([]InputShow, []InputMovie, error) {
+	var r io.ReadCloser = io.NopCloser(os.Stdin)
+	if s.Path != StdioPath {
+		file, err := os.Open(s.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open file %s: %w", s.Path, err)
+		}
+		r = file
+	}
+	defer r.Close()
+	return decodeInput(r, s.Path, s.MediaType)
+}
+
+// URLSource downloads a JSON file afresh on every Load
+type URLSource struct {
+	URL       string
+	MediaType string       // "tv" or "movies"
+	Client    *http.Client // nil for a default client
+}
+
+func (s URLSource) Load() ([]InputShow, []InputMovie, error) {
+	client := s.Client
+	if client == nil {
+		client = NewHTTPClient(5 * time.Minute)
+	}
+	body, err := fetchURL(client, s.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file %s: %w", s.URL, err)
+	}
+	defer body.Close()
+	return decodeInput(body, s.URL, s.MediaType)
+}
+
+// AniTraktSource reads the upstream anitrakt index, the input this tool
+// extends
+type AniTraktSource struct {
+	Ref       string // branch, tag or commit; "" for main
+	MediaType string // "tv" or "movies"
+	Client    *http.Client
+}
+
+// URL returns where the index of the source's media type is downloaded from
+func (s AniTraktSource) URL() string {
+	ref := s.Ref
+	if ref == "" {
+		ref = "main"
+	}
+	return fmt.Sprintf(aniTraktIndexURL, ref, s.MediaType)
+}
+
+func (s AniTraktSource) Load() ([]InputShow, []InputMovie, error) {
+	return URLSource{URL: s.URL(), MediaType: s.MediaType, Client: s.Client}.Load()
+}
+
+// fetchURL GETs url and returns the body of a 200 response
+func fetchURL(client *http.Client, url string) (io.ReadCloser, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// decodeInput decodes a JSON array of mediaType entries; name is the input
+// path for errors
+func decodeInput(r io.Reader, name, mediaType string) ([]InputShow, []InputMovie, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", name, err)
+	}
+	var shows []InputShow
+	var movies []InputMovie
+	if mediaType == "movies" {
+		err = json.Unmarshal(data, &movies)
+	} else {
+		err = json.Unmarshal(data, &shows)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal JSON from %s: %w", name, err)
+	}
+	return shows, movies, nil
+}
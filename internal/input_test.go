@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewInputSource(t *testing.T) {
+	tests := []struct {
+		path string
+		want InputSource
+	}{
+		{"json/input/tv.json", FileSource{Path: "json/input/tv.json", MediaType: "tv"}},
+		{StdioPath, FileSource{Path: StdioPath, MediaType: "tv"}},
+		{"https://example.org/tv.json", URLSource{URL: "https://example.org/tv.json", MediaType: "tv"}},
+		{"anitrakt:", AniTraktSource{MediaType: "tv"}},
+		{"anitrakt:v2", AniTraktSource{Ref: "v2", MediaType: "tv"}},
+	}
+	for _, tt := range tests {
+		if got := NewInputSource(tt.path, "tv"); got != tt.want {
+			t.Errorf("NewInputSource(%q) = %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAniTraktSource(t *testing.T) {
+	var requested string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = req.URL.String()
+		body := `[{"title": "Perfect Blue", "mal_id": 437, "trakt_id": 1234, "type": "movies"}]`
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	shows, movies, err := AniTraktSource{MediaType: "movies", Client: client}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://raw.githubusercontent.com/rensetsu/db.trakt.anitrakt/main/db/movies.json"; requested != want {
+		t.Errorf("requested %s, want %s", requested, want)
+	}
+	if shows != nil || len(movies) != 1 || movies[0].MalID != 437 {
+		t.Errorf("Load() = %v, %v", shows, movies)
+	}
+	if got := DefaultOutputFile([]string{"anitrakt:"}, "movies"); got != filepath.Join("json/output", "movies_ex.json") {
+		t.Errorf("DefaultOutputFile(anitrakt:) = %s", got)
+	}
+}
+
+func TestLoadInputsMovies(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "movies.json")
+	os.WriteFile(file, []byte(`[{"title": "Akira", "mal_id": 47, "trakt_id": 9}]`), 0644)
+	movies, err := LoadInputs([]string{file}, func(string, []InputMovie) error { return nil })
+	if err != nil || len(movies) != 1 || movies[0].Title != "Akira" {
+		t.Errorf("LoadInputs = %v, %v", movies, err)
+	}
+}
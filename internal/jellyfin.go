@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// JellyfinExport is a provider ID cross map keyed the way Jellyfin stores
+// ProviderIds, so plugins can copy the map onto an item as-is
+type JellyfinExport struct {
+	GeneratedAt string          `json:"generated_at"`
+	Series      []JellyfinEntry `json:"series"`
+	Movies      []JellyfinEntry `json:"movies"`
+}
+
+// JellyfinEntry is one item. Season is the Trakt/TVDB season the MAL entry
+// corresponds to, or null when the whole series (or a split cour) is meant.
+type JellyfinEntry struct {
+	Name        string            `json:"name"`
+	Year        int               `json:"year,omitempty"`
+	Season      *int              `json:"season"`
+	ProviderIDs map[string]string `json:"provider_ids"`
+}
+
+// RunExportJellyfin implements the `export-jellyfin` subcommand
+func RunExportJellyfin(args []string) error {
+	fs := flag.NewFlagSet("export-jellyfin", flag.ExitOnError)
+	output := fs.String("output", "dist/jellyfin-provider-ids.json", "Path of the JSON file to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	animeAPIFile := fs.String("animeapi", "", "Path to animeapi.tsv for AniDB, AniList and Kitsu IDs (empty = fetch from animeapi.my.id)")
+	sortFlag := fs.String("sort", string(SortMalID), sortUsage)
+	fs.Parse(args)
+	order, err := ParseSortOrder(*sortFlag)
+	if err != nil {
+		return err
+	}
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+	SortEntries(shows, order)
+	SortEntries(movies, order)
+
+	_, malToRow, err := LoadAnimeAPITSV(*animeAPIFile)
+	if err != nil {
+		return err
+	}
+
+	export := buildJellyfinExport(shows, movies, malToRow)
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d series and %d movies to %s\n", len(export.Series), len(export.Movies), *output)
+	return nil
+}
+
+// buildJellyfinExport converts the dataset, adding anime database IDs from AnimeAPI
+func buildJellyfinExport(shows []OutputShow, movies []OutputMovie, malToRow map[int]AnimeAPIRow) JellyfinExport {
+	export := JellyfinExport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Series:      []JellyfinEntry{},
+		Movies:      []JellyfinEntry{},
+	}
+
+	for _, show := range shows {
+		ids := jellyfinAnimeIDs(show.MyAnimeList.ID, malToRow)
+		ids["Trakt"] = strconv.Itoa(show.Trakt.ID)
+		if show.Externals != nil {
+			setProviderID(ids, "Tvdb", show.Externals.TVDB)
+			setProviderID(ids, "Tmdb", show.Externals.TMDB)
+			if show.Externals.IMDB != nil && *show.Externals.IMDB != "" {
+				ids["Imdb"] = *show.Externals.IMDB
+			}
+		}
+		entry := JellyfinEntry{Name: show.MyAnimeList.Title, Year: show.ReleaseYear, ProviderIDs: ids}
+		if show.Trakt.Season != nil {
+			season := show.Trakt.Season.Number
+			entry.Season = &season
+		}
+		export.Series = append(export.Series, entry)
+	}
+
+	for _, movie := range movies {
+		ids := jellyfinAnimeIDs(movie.MyAnimeList.ID, malToRow)
+		ids["Trakt"] = strconv.Itoa(movie.Trakt.ID)
+		if movie.Externals != nil {
+			setProviderID(ids, "Tmdb", movie.Externals.TMDB)
+			if movie.Externals.IMDB != nil && *movie.Externals.IMDB != "" {
+				ids["Imdb"] = *movie.Externals.IMDB
+			}
+		}
+		export.Movies = append(export.Movies, JellyfinEntry{Name: movie.MyAnimeList.Title, Year: movie.ReleaseYear, ProviderIDs: ids})
+	}
+	return export
+}
+
+// jellyfinAnimeIDs returns the MAL ID plus any AniDB, AniList and Kitsu IDs
+// under the provider keys used by the respective Jellyfin plugins
+func jellyfinAnimeIDs(malID int, malToRow map[int]AnimeAPIRow) map[string]string {
+	ids := map[string]string{"MyAnimeList": strconv.Itoa(malID)}
+	if row, ok := malToRow[malID]; ok {
+		if row.AniDB != 0 {
+			ids["AniDB"] = strconv.Itoa(row.AniDB)
+		}
+		if row.AniList != 0 {
+			ids["AniList"] = strconv.Itoa(row.AniList)
+		}
+		if row.Kitsu != 0 {
+			ids["Kitsu"] = strconv.Itoa(row.Kitsu)
+		}
+	}
+	return ids
+}
+
+// setProviderID stores an optional numeric ID
+func setProviderID(ids map[string]string, key string, id *int) {
+	if id != nil {
+		ids[key] = strconv.Itoa(*id)
+	}
+}
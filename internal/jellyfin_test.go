@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestRunExportJellyfin(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeAnimeListFixture(t)
+
+	if err := RunExportJellyfin([]string{"-output", "jellyfin.json", "-tv", "tv_ex.json", "-movies", "movies_ex.json", "-animeapi", "animeapi.tsv"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("jellyfin.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = regexp.MustCompile(`"generated_at": "[^"]*"`).ReplaceAll(got, []byte(`"generated_at": "GENERATED_AT"`))
+	checkGolden(t, "jellyfin-provider-ids.json", got)
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// jikanAPIBase is the Jikan (unofficial MyAnimeList) v4 API root
+const jikanAPIBase = "https://api.jikan.moe/v4"
+
+// errJikanNotFound is returned when Jikan has no anime with the MAL ID
+var errJikanNotFound = errors.New("not found on Jikan")
+
+// MALTitles are the title variants of a MyAnimeList entry, "" when MAL has
+// none
+type MALTitles struct {
+	Romaji  string `json:"romaji,omitempty"`  // MAL's default title
+	English string `json:"english,omitempty"` // official English title
+	Native  string `json:"native,omitempty"`  // Japanese (or other original) title
+}
+
+// jikanAnime is the part of /anime/{id} that is kept
+type jikanAnime struct {
+	Data struct {
+		Titles []struct {
+			Type  string `json:"type"` // "Default", "Synonym", "Japanese", "English"...
+			Title string `json:"title"`
+		} `json:"titles"`
+		Episodes int `json:"episodes"` // null until MAL knows it
+		Aired    struct {
+			From string `json:"from"` // RFC 3339, null before the first air date is known
+		} `json:"aired"`
+	} `json:"data"`
+}
+
+// MALDetails are the fields of a MyAnimeList entry kept from Jikan
+type MALDetails struct {
+	Titles    *MALTitles
+	Episodes  int    // 0 when unknown
+	StartDate string // YYYY-MM-DD, "" when unknown
+}
+
+// details picks the kept fields
+func (a jikanAnime) details() MALDetails {
+	details := MALDetails{Titles: a.titles(), Episodes: a.Data.Episodes}
+	if len(a.Data.Aired.From) >= len("2006-01-02") {
+		details.StartDate = a.Data.Aired.From[:len("2006-01-02")]
+	}
+	return details
+}
+
+// titles picks the title variants, cleaned like input titles
+func (a jikanAnime) titles() *MALTitles {
+	titles := &MALTitles{}
+	for _, title := range a.Data.Titles {
+		clean, _ := CleanTitle(title.Title)
+		switch {
+		case title.Type == "Default" && titles.Romaji == "":
+			titles.Romaji = clean
+		case title.Type == "English" && titles.English == "":
+			titles.English = clean
+		case title.Type == "Japanese" && titles.Native == "":
+			titles.Native = clean
+		}
+	}
+	if *titles == (MALTitles{}) {
+		return nil
+	}
+	return titles
+}
+
+// FetchMALDetails fetches the title variants, episode count and start date
+// of a MAL entry from Jikan. Results are cached under
+// config.TempDir/jikan/<mal_id>.json.
+func FetchMALDetails(client *http.Client, config Config, malID int) (MALDetails, error) {
+	cacheFile := filepath.Join(config.TempDir, "jikan", fmt.Sprintf("%d.json", malID))
+	anime, err := singleFetch(cacheFile, func() (*jikanAnime, error) {
+		if useCache(config, cacheFile) {
+			if anime, ok := cacheLoad[jikanAnime](config.Cache, cacheFile); ok {
+				return &anime, nil
+			}
+		}
+
+		config.JikanRateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("%s/anime/%d", jikanAPIBase, malID), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/json")
+			return client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, errJikanNotFound
+		}
+		if resp.StatusCode != 200 {
+			return nil, statusError(resp, fmt.Errorf("jikan API error: %d", resp.StatusCode))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var anime jikanAnime
+		if err := json.Unmarshal(body, &anime); err != nil {
+			return nil, err
+		}
+		os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		cacheStore(config.Cache, cacheFile, body, anime)
+		return &anime, nil
+	})
+	if err != nil {
+		return MALDetails{}, err
+	}
+	return anime.details(), nil
+}
+
+// jikanEnabled reports whether the jikan enrichment runs
+func jikanEnabled(config Config) bool {
+	return config.Enrichments["jikan"] && config.JikanRateLimiter != nil
+}
+
+// enrichMAL returns the Jikan details of a MAL entry. When the enrichment is
+// off or Jikan cannot be reached, the previous details are kept.
+func enrichMAL(client *http.Client, config Config, malID int, previous MALDetails) MALDetails {
+	if !jikanEnabled(config) {
+		return previous
+	}
+	config.Progress.Phase("jikan")
+	details, err := FetchMALDetails(client, config, malID)
+	if err != nil {
+		if !errors.Is(err, errJikanNotFound) {
+			log.Printf("Warning: Jikan lookup of MAL ID %d failed: %v", malID, err)
+			config.Errors.Record("jikan", malID, "", err)
+			return previous
+		}
+		return MALDetails{}
+	}
+	return details
+}
+
+// jikanEnricher adds MAL title variants, and episode counts and start dates
+// for shows, from Jikan
+type jikanEnricher struct{}
+
+func (jikanEnricher) Name() string { return "jikan" }
+
+func (jikanEnricher) Applies(entry EnrichEntry) bool { return true }
+
+func (jikanEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	if entry.Show != nil {
+		enrichShowTitles(ctx.Client, ctx.Config, entry.Show, entry.ExistingShow)
+	} else {
+		enrichMovieTitles(ctx.Client, ctx.Config, entry.Movie, entry.ExistingMovie)
+	}
+}
+
+// Retain keeps the previous details
+func (jikanEnricher) Retain(entry EnrichEntry) {
+	if entry.Show != nil && entry.ExistingShow != nil {
+		previous := entry.ExistingShow.MyAnimeList
+		entry.Show.MyAnimeList.Titles = previous.Titles
+		entry.Show.MyAnimeList.Episodes = previous.Episodes
+		entry.Show.MyAnimeList.StartDate = previous.StartDate
+	} else if entry.Movie != nil && entry.ExistingMovie != nil {
+		entry.Movie.MyAnimeList.Titles = entry.ExistingMovie.MyAnimeList.Titles
+	}
+}
+
+// enrichShowTitles runs the jikan enrichment on a show; see enrichMAL
+func enrichShowTitles(client *http.Client, config Config, show, existing *OutputShow) {
+	var previous MALDetails
+	if existing != nil {
+		previous = MALDetails{existing.MyAnimeList.Titles, existing.MyAnimeList.Episodes, existing.MyAnimeList.StartDate}
+	}
+	details := enrichMAL(client, config, show.MyAnimeList.ID, previous)
+	show.MyAnimeList.Titles = details.Titles
+	show.MyAnimeList.Episodes = details.Episodes
+	show.MyAnimeList.StartDate = details.StartDate
+}
+
+// enrichMovieTitles runs the jikan enrichment on a movie; see enrichMAL
+func enrichMovieTitles(client *http.Client, config Config, movie, existing *OutputMovie) {
+	var previous MALDetails
+	if existing != nil {
+		previous.Titles = existing.MyAnimeList.Titles
+	}
+	movie.MyAnimeList.Titles = enrichMAL(client, config, movie.MyAnimeList.ID, previous).Titles
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJikanTitles(t *testing.T) {
+	var anime jikanAnime
+	json.Unmarshal([]byte(`{"data": {"titles": [
+		{"type": "Default", "title": "Shingeki no Kyojin"},
+		{"type": "Synonym", "title": "AoT"},
+		{"type": "Japanese", "title": "進撃の巨人"},
+		{"type": "English", "title": "Attack on Titan"}
+	]}}`), &anime)
+	want := MALTitles{Romaji: "Shingeki no Kyojin", English: "Attack on Titan", Native: "進撃の巨人"}
+	if got := anime.titles(); got == nil || *got != want {
+		t.Errorf("titles() = %+v, want %+v", got, want)
+	}
+
+	if got := (jikanAnime{}).titles(); got != nil {
+		t.Errorf("titles() of an empty response = %+v, want nil", got)
+	}
+}
+
+func TestJikanDetails(t *testing.T) {
+	var anime jikanAnime
+	json.Unmarshal([]byte(`{"data": {"episodes": 12, "aired": {"from": "2022-01-10T00:00:00+00:00"}}}`), &anime)
+	if got := anime.details(); got.Episodes != 12 || got.StartDate != "2022-01-10" {
+		t.Errorf("details() = %+v", got)
+	}
+
+	if got := (jikanAnime{}).details(); got.Episodes != 0 || got.StartDate != "" {
+		t.Errorf("details() of an empty response = %+v", got)
+	}
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Journal is the set of changes one run made to an output file. Journals are
+// replayed in file name order, which is the UTC time they were written.
+type Journal struct {
+	GeneratedAt string        `json:"generated_at"`
+	Output      string        `json:"output"`
+	Changes     []EntryChange `json:"changes"`
+}
+
+// journalDirFor returns the journal directory of an output file, e.g.
+// json/journal/tv_ex for json/output/tv_ex.json
+func journalDirFor(outputFile string) string {
+	return filepath.Join(layout.JournalDir(), strings.TrimSuffix(filepath.Base(outputFile), ".json"))
+}
+
+// pendingJournals lists the journal files of an output file, oldest first
+func pendingJournals(outputFile string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(journalDirFor(outputFile), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// LoadShowOutput loads the TV output with any pending journals applied
+func LoadShowOutput(outputFile string) ([]OutputShow, error) {
+	var shows []OutputShow
+	LoadJSONOptional(outputFile, &shows)
+	shows, err := replayJournals(outputFile, shows, func(s OutputShow) int { return s.MyAnimeList.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay journals for %s: %w", outputFile, err)
+	}
+	return shows, nil
+}
+
+// LoadMovieOutput loads the movie output with any pending journals applied
+func LoadMovieOutput(outputFile string) ([]OutputMovie, error) {
+	var movies []OutputMovie
+	LoadJSONOptional(outputFile, &movies)
+	movies, err := replayJournals(outputFile, movies, func(m OutputMovie) int { return m.MyAnimeList.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay journals for %s: %w", outputFile, err)
+	}
+	return movies, nil
+}
+
+// LoadOutputs loads the TV and movie outputs with their pending journals
+// applied, so commands reading the dataset see what -journal runs wrote
+// since the last compact. An empty movieFile skips movies.
+func LoadOutputs(tvFile, movieFile string) ([]OutputShow, []OutputMovie, error) {
+	shows, err := LoadShowOutput(tvFile)
+	if err != nil || movieFile == "" {
+		return shows, nil, err
+	}
+	movies, err := LoadMovieOutput(movieFile)
+	return shows, movies, err
+}
+
+// replayJournals applies the pending journals of outputFile to entries and
+// returns the result sorted by MAL ID
+func replayJournals[T any](outputFile string, entries []T, malID func(T) int) ([]T, error) {
+	paths, err := pendingJournals(outputFile)
+	if err != nil || len(paths) == 0 {
+		return entries, err
+	}
+
+	byID := make(map[int]T, len(entries))
+	for _, entry := range entries {
+		byID[malID(entry)] = entry
+	}
+	for _, path := range paths {
+		var journal Journal
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &journal); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, change := range journal.Changes {
+			if change.Change == "removed" {
+				delete(byID, change.MalID)
+				continue
+			}
+			var entry T
+			if err := json.Unmarshal(change.New, &entry); err != nil {
+				return nil, fmt.Errorf("%s: MAL ID %d: %w", path, change.MalID, err)
+			}
+			byID[change.MalID] = entry
+		}
+	}
+
+	ids := make([]int, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	result := make([]T, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, byID[id])
+	}
+	return result, nil
+}
+
+// StoreResults persists a TV run. With -journal only the changes against
+// existing are written; otherwise the canonical file is rewritten, which
+// also absorbs any journals that were replayed into existing. Either way the
+// changes go to the day's changelog. The url fields of every entry are filled
+// in first.
+func StoreResults(config Config, outputFile string, existing []OutputShow, resultsMap map[int]OutputShow) error {
+	for malID, show := range resultsMap {
+		show.setURLs()
+		resultsMap[malID] = show
+	}
+	if outputFile == StdioPath {
+		return SaveResults(outputFile, resultsMap, config.Sort)
+	}
+	results := make([]OutputShow, 0, len(resultsMap))
+	for _, show := range resultsMap {
+		results = append(results, show)
+	}
+	changes := DiffShows(existing, results)
+	if err := checkGuardrails(config, outputFile, len(existing), changes); err != nil {
+		return err
+	}
+	if config.Journal {
+		if err := checkJournalShrink(outputFile, len(existing), len(resultsMap), config.Guardrails.AllowShrink); err != nil {
+			return err
+		}
+	} else if err := checkShrink(outputFile, len(resultsMap), config.Guardrails.AllowShrink); err != nil {
+		return err
+	}
+	appendChangelog(config, outputFile, changes)
+	if !config.Journal {
+		if err := SaveResults(outputFile, resultsMap, config.Sort); err != nil {
+			return err
+		}
+		if config.KeyedOutput {
+			if err := writeKeyedOutput(outputFile); err != nil {
+				return err
+			}
+		}
+		takeSnapshot(outputFile, config.Snapshots, config.Verbose)
+		clearJournals(outputFile)
+		return nil
+	}
+	return writeJournal(outputFile, changes, config.Verbose)
+}
+
+// StoreMovieResults persists a movie run, see StoreResults
+func StoreMovieResults(config Config, outputFile string, existing []OutputMovie, resultsMap map[int]OutputMovie) error {
+	for malID, movie := range resultsMap {
+		movie.setURLs()
+		resultsMap[malID] = movie
+	}
+	if outputFile == StdioPath {
+		return SaveMovieResults(outputFile, resultsMap, config.Sort)
+	}
+	results := make([]OutputMovie, 0, len(resultsMap))
+	for _, movie := range resultsMap {
+		results = append(results, movie)
+	}
+	changes := DiffMovies(existing, results)
+	if err := checkGuardrails(config, outputFile, len(existing), changes); err != nil {
+		return err
+	}
+	if config.Journal {
+		if err := checkJournalShrink(outputFile, len(existing), len(resultsMap), config.Guardrails.AllowShrink); err != nil {
+			return err
+		}
+	} else if err := checkShrink(outputFile, len(resultsMap), config.Guardrails.AllowShrink); err != nil {
+		return err
+	}
+	appendChangelog(config, outputFile, changes)
+	if !config.Journal {
+		if err := SaveMovieResults(outputFile, resultsMap, config.Sort); err != nil {
+			return err
+		}
+		if config.KeyedOutput {
+			if err := writeKeyedOutput(outputFile); err != nil {
+				return err
+			}
+		}
+		takeSnapshot(outputFile, config.Snapshots, config.Verbose)
+		clearJournals(outputFile)
+		return nil
+	}
+	return writeJournal(outputFile, changes, config.Verbose)
+}
+
+// writeJournal writes a new journal for outputFile. Runs without changes
+// leave no journal behind.
+func writeJournal(outputFile string, changes []EntryChange, verbose bool) error {
+	if len(changes) == 0 {
+		if verbose {
+			Debugf("\nNo changes to journal for %s", outputFile)
+		}
+		return nil
+	}
+	dir := journalDirFor(outputFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory %s: %w", dir, err)
+	}
+	now := time.Now().UTC()
+	path := filepath.Join(dir, now.Format("20060102T150405.000000000Z")+".json")
+	err := SaveJSON(path, Journal{
+		GeneratedAt: now.Format(time.RFC3339),
+		Output:      outputFile,
+		Changes:     changes,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nJournaled %d changes to %s\n", len(changes), path)
+	return nil
+}
+
+// clearJournals removes the pending journals of outputFile
+func clearJournals(outputFile string) {
+	paths, _ := pendingJournals(outputFile)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			log.Printf("Warning: Failed to remove journal %s: %v", path, err)
+		}
+	}
+}
+
+// RunCompact implements the `compact` subcommand, folding pending journals
+// into the canonical output files
+func RunCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file (empty to skip)")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file (empty to skip)")
+	dryRun := fs.Bool("dry-run", false, "Report pending journals without compacting")
+	sortFlag := fs.String("sort", string(SortMalID), sortUsage)
+	allowShrink := fs.Bool("allow-shrink", false, "Compact even if an output file would lose more than 10% of its entries")
+	fs.Parse(args)
+	order, err := ParseSortOrder(*sortFlag)
+	if err != nil {
+		return err
+	}
+
+	targets := []struct {
+		outputFile string
+		fold       func(string) (int, error)
+	}{
+		{*tvFile, func(path string) (int, error) {
+			shows, err := LoadShowOutput(path)
+			if err != nil {
+				return 0, err
+			}
+			if err := checkShrink(path, len(shows), *allowShrink); err != nil {
+				return 0, err
+			}
+			SortEntries(shows, order)
+			return len(shows), SaveJSON(path, shows)
+		}},
+		{*movieFile, func(path string) (int, error) {
+			movies, err := LoadMovieOutput(path)
+			if err != nil {
+				return 0, err
+			}
+			if err := checkShrink(path, len(movies), *allowShrink); err != nil {
+				return 0, err
+			}
+			SortEntries(movies, order)
+			return len(movies), SaveJSON(path, movies)
+		}},
+	}
+
+	compacted := false
+	for _, target := range targets {
+		if target.outputFile == "" {
+			continue
+		}
+		paths, err := pendingJournals(target.outputFile)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(paths) == 0:
+			fmt.Printf("%s: no pending journals\n", target.outputFile)
+			continue
+		case *dryRun:
+			fmt.Printf("%s: %d pending journals\n", target.outputFile, len(paths))
+			continue
+		}
+
+		entries, err := target.fold(target.outputFile)
+		if err != nil {
+			return err
+		}
+		if err := refreshKeyedOutput(target.outputFile); err != nil {
+			return err
+		}
+		clearJournals(target.outputFile)
+		fmt.Printf("%s: folded %d journals, %d entries\n", target.outputFile, len(paths), entries)
+		compacted = true
+	}
+
+	if compacted {
+		WriteManifest()
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReplayAndCompact(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := filepath.Join("json", "output", "tv_ex.json")
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	shows := testDataset(t).ShowList()
+	SaveJSON(outputFile, shows)
+
+	// First run renames MAL 5 and adds MAL 7
+	updated := map[int]OutputShow{}
+	for _, show := range shows {
+		updated[show.MyAnimeList.ID] = show
+	}
+	renamed := updated[5]
+	renamed.MyAnimeList.Title = "Cowboy Bebop: The Movie"
+	updated[5] = renamed
+	added := updated[1]
+	added.MyAnimeList.ID = 7
+	updated[7] = added
+	StoreResults(Config{Journal: true}, outputFile, shows, updated)
+
+	// Second run, built on the replayed state, drops MAL 1
+	existing, _ := LoadShowOutput(outputFile)
+	if len(existing) != 3 || existing[1].MyAnimeList.Title != "Cowboy Bebop: The Movie" {
+		t.Fatalf("replayed output = %+v", existing)
+	}
+	// One of three is more than the shrink protection lets a journal drop
+	delete(updated, 1)
+	StoreResults(Config{Journal: true, Guardrails: Guardrails{AllowShrink: true}}, outputFile, existing, updated)
+
+	if paths, _ := pendingJournals(outputFile); len(paths) != 2 {
+		t.Fatalf("pending journals = %v, want 2", paths)
+	}
+	var canonical []OutputShow
+	LoadJSON(outputFile, &canonical)
+	if len(canonical) != 2 {
+		t.Fatalf("canonical file changed before compaction: %d entries", len(canonical))
+	}
+
+	if err := RunCompact([]string{"-tv", outputFile, "-movies", ""}); err != nil {
+		t.Fatal(err)
+	}
+	canonical = nil
+	LoadJSON(outputFile, &canonical)
+	if len(canonical) != 2 || canonical[0].MyAnimeList.ID != 5 || canonical[1].MyAnimeList.ID != 7 {
+		t.Fatalf("compacted output = %+v", canonical)
+	}
+	if url := canonical[1].MyAnimeList.URL; url != "https://myanimelist.net/anime/7" {
+		t.Errorf("MAL url = %q", url)
+	}
+	if paths, _ := pendingJournals(outputFile); len(paths) != 0 {
+		t.Fatalf("journals left after compaction: %v", paths)
+	}
+}
+
+func TestChangelogAppendsRuns(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := Config{ChangelogDir: "changes"}
+	outputFile := "tv_ex.json"
+
+	shows := testDataset(t).ShowList()
+	resultsMap := map[int]OutputShow{}
+	for _, show := range shows {
+		resultsMap[show.MyAnimeList.ID] = show
+	}
+	StoreResults(config, outputFile, nil, resultsMap)
+
+	renamed := resultsMap[5]
+	renamed.MyAnimeList.Title = "Cowboy Bebop: The Movie"
+	resultsMap[5] = renamed
+	existing, _ := LoadShowOutput(outputFile)
+	StoreResults(config, outputFile, existing, resultsMap)
+
+	var changelog Changelog
+	LoadJSON(changelogPath("changes", time.Now()), &changelog)
+	if len(changelog.Changes) != len(shows)+1 {
+		t.Fatalf("changelog has %d changes, want %d", len(changelog.Changes), len(shows)+1)
+	}
+	last := changelog.Changes[len(changelog.Changes)-1]
+	if last.Change != "updated" || last.MalID != 5 || last.Old == nil {
+		t.Errorf("last change = %+v", last)
+	}
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// KeychainService names this tool's entries in the OS keychain
+const KeychainService = "db.trakt.extended-anitrakt"
+
+// Keychain accounts, one per stored credential
+const (
+	KeychainAPIKey      = "trakt-api-key"      // Trakt client ID
+	KeychainAccessToken = "trakt-access-token" // Trakt OAuth token for sync-list
+)
+
+// keychainNames maps the -name values of the keychain subcommand to accounts
+var keychainNames = map[string]string{
+	"api-key":      KeychainAPIKey,
+	"access-token": KeychainAccessToken,
+}
+
+var (
+	// ErrKeychainNotFound is returned for an account with nothing stored
+	ErrKeychainNotFound = errors.New("not stored in the OS keychain")
+	// ErrKeychainUnavailable is returned where no OS keychain can be reached,
+	// e.g. on Linux without secret-tool or a running Secret Service
+	ErrKeychainUnavailable = errors.New("no OS keychain available")
+)
+
+// KeychainGet reads a credential from the OS keychain: the login keychain on
+// macOS, Credential Manager on Windows and the Secret Service (GNOME
+// Keyring, KWallet) elsewhere
+func KeychainGet(account string) (string, error) {
+	return keychainGet(KeychainService, account)
+}
+
+// KeychainSet stores a credential in the OS keychain, replacing an older one
+func KeychainSet(account, secret string) error {
+	return keychainSet(KeychainService, account, secret)
+}
+
+// KeychainDelete removes a credential from the OS keychain
+func KeychainDelete(account string) error {
+	return keychainDelete(KeychainService, account)
+}
+
+// RunKeychain implements the `keychain` subcommand
+func RunKeychain(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: keychain store|delete|status [-name api-key|access-token]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("keychain "+action, flag.ExitOnError)
+	name := fs.String("name", "api-key", "Credential: api-key (Trakt client ID) or access-token (Trakt OAuth token)")
+	fs.Parse(args[1:])
+
+	account, ok := keychainNames[*name]
+	if !ok {
+		return fmt.Errorf("unknown -name %q (expected api-key or access-token)", *name)
+	}
+
+	switch action {
+	case "store":
+		fmt.Printf("Enter %s: ", *name)
+		secret, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Println()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", *name, err)
+		}
+		if len(secret) == 0 {
+			return fmt.Errorf("empty %s", *name)
+		}
+		if err := KeychainSet(account, string(secret)); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s in the OS keychain\n", *name)
+	case "delete":
+		if err := KeychainDelete(account); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted %s from the OS keychain\n", *name)
+	case "status":
+		_, err := KeychainGet(account)
+		switch {
+		case err == nil:
+			fmt.Printf("%s is stored in the OS keychain\n", *name)
+		case errors.Is(err, ErrKeychainNotFound):
+			fmt.Printf("%s is not stored in the OS keychain\n", *name)
+		default:
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown keychain action %q (expected store, delete or status)", action)
+	}
+	return nil
+}
+
+// KeychainLookup returns the stored credential of account, or "" when none
+// is stored. A keychain that cannot be read only warns, so the caller can
+// fall back to a prompt.
+func KeychainLookup(account string) string {
+	secret, err := KeychainGet(account)
+	if err != nil {
+		if !errors.Is(err, ErrKeychainNotFound) {
+			log.Printf("Warning: Failed to read %s from the OS keychain: %v", account, err)
+		}
+		return ""
+	}
+	return secret
+}
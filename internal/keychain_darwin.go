@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit status of security(1) for a missing item
+const securityNotFound = 44
+
+// keychainGet reads a generic password from the login keychain
+func keychainGet(service, account string) (string, error) {
+	out, err := exec.Command("/usr/bin/security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// keychainSet stores a generic password. The commands go through stdin
+// (security -i) and the password as hex, so it never shows up in ps.
+func keychainSet(service, account, secret string) error {
+	cmd := exec.Command("/usr/bin/security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, account, hex.EncodeToString([]byte(secret))))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keychainDelete removes a generic password
+func keychainDelete(service, account string) error {
+	if err := exec.Command("/usr/bin/security", "delete-generic-password", "-s", service, "-a", account).Run(); err != nil {
+		return securityError(err)
+	}
+	return nil
+}
+
+// securityError maps a failed security(1) call to the keychain errors
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFound {
+		return ErrKeychainNotFound
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return ErrKeychainUnavailable
+	}
+	return fmt.Errorf("security: %w", err)
+}
@@ -0,0 +1,68 @@
+//go:build !darwin && !windows
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretTool finds secret-tool, libsecret's client for the Secret Service
+func secretTool() (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", fmt.Errorf("%w: secret-tool (libsecret-tools) is not installed", ErrKeychainUnavailable)
+	}
+	return path, nil
+}
+
+// keychainGet looks up a secret by its service and account attributes.
+// secret-tool exits 1 without output when nothing matches.
+func keychainGet(service, account string) (string, error) {
+	tool, err := secretTool()
+	if err != nil {
+		return "", err
+	}
+	out, err := exec.Command(tool, "lookup", "service", service, "account", account).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+		return "", ErrKeychainNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("secret-tool: %w", err)
+	}
+	secret := strings.TrimSuffix(string(out), "\n")
+	if secret == "" {
+		return "", ErrKeychainNotFound
+	}
+	return secret, nil
+}
+
+// keychainSet stores a secret. secret-tool reads it from stdin, so it never
+// shows up in ps.
+func keychainSet(service, account, secret string) error {
+	tool, err := secretTool()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(tool, "store", "--label="+service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keychainDelete removes a secret
+func keychainDelete(service, account string) error {
+	if _, err := keychainGet(service, account); err != nil {
+		return err
+	}
+	tool, _ := secretTool()
+	if out, err := exec.Command(tool, "clear", "service", service, "account", account).CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+//go:build !darwin && !windows
+
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecretTool keeps one secret per service/account in a directory, like
+// secret-tool does in the Secret Service
+const fakeSecretTool = `#!/bin/sh
+store="$FAKE_SECRETS/$3.$5"
+case "$1" in
+store) store="$FAKE_SECRETS/$4.$6"; cat > "$store" ;;
+lookup) [ -f "$store" ] || exit 1; cat "$store" ;;
+clear) rm -f "$store" ;;
+esac
+`
+
+func TestKeychainSecretService(t *testing.T) {
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "secret-tool"), []byte(fakeSecretTool), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_SECRETS", t.TempDir())
+
+	if _, err := KeychainGet(KeychainAPIKey); !errors.Is(err, ErrKeychainNotFound) {
+		t.Fatalf("empty keychain: err = %v, want ErrKeychainNotFound", err)
+	}
+	if err := KeychainSet(KeychainAPIKey, "client-id"); err != nil {
+		t.Fatal(err)
+	}
+	if got := KeychainLookup(KeychainAPIKey); got != "client-id" {
+		t.Errorf("KeychainLookup = %q, want client-id", got)
+	}
+	if got := KeychainLookup(KeychainAccessToken); got != "" {
+		t.Errorf("other account = %q, want nothing", got)
+	}
+	if err := KeychainDelete(KeychainAPIKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := KeychainDelete(KeychainAPIKey); !errors.Is(err, ErrKeychainNotFound) {
+		t.Errorf("second delete: err = %v, want ErrKeychainNotFound", err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := KeychainGet(KeychainAPIKey); !errors.Is(err, ErrKeychainUnavailable) {
+		t.Errorf("without secret-tool: err = %v, want ErrKeychainUnavailable", err)
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyedPath returns the map-keyed variant of an output file, e.g.
+// json/output/tv_ex_keyed.json for json/output/tv_ex.json
+func KeyedPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, ".json") + "_keyed.json"
+}
+
+// writeKeyedOutput writes the map-keyed variant of the output file just
+// written: one JSON object with the entries under their MAL ID, in MAL ID
+// order whatever -sort says, so clients can look an entry up directly
+func writeKeyedOutput(outputFile string) error {
+	docs, _, err := loadOutputDocuments(outputFile)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(docs))
+	for id := range docs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	// encoding/json would order the keys as strings ("10" before "9"), so
+	// the object is assembled here, indented like SaveJSON
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, id := range ids {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  " + strconv.Quote(strconv.Itoa(id)) + ": ")
+		if err := json.Indent(&buf, docs[id], "  ", "  "); err != nil {
+			return fmt.Errorf("%s: MAL ID %d: %w", outputFile, id, err)
+		}
+	}
+	if len(ids) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}")
+
+	path := KeyedPath(outputFile)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", path, err)
+	}
+	return nil
+}
+
+// refreshKeyedOutput rewrites the map-keyed variant of outputFile when one
+// exists, for commands that rewrite output files without a -keyed flag
+func refreshKeyedOutput(outputFile string) error {
+	if _, err := os.Stat(KeyedPath(outputFile)); err != nil {
+		return nil
+	}
+	return writeKeyedOutput(outputFile)
+}
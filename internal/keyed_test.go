@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeyedOutput(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "tv_ex.json"
+
+	resultsMap := map[int]OutputShow{}
+	for _, show := range testDataset(t).ShowList() {
+		resultsMap[show.MyAnimeList.ID] = show
+	}
+	nine, ten := resultsMap[1], resultsMap[1]
+	nine.MyAnimeList.ID, ten.MyAnimeList.ID = 9, 10
+	resultsMap[9], resultsMap[10] = nine, ten
+
+	config := Config{KeyedOutput: true, Sort: SortTitle}
+	if err := StoreResults(config, outputFile, nil, resultsMap); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(KeyedPath(outputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyed map[string]OutputShow
+	if err := json.Unmarshal(data, &keyed); err != nil {
+		t.Fatalf("keyed output is not an object: %v", err)
+	}
+	if len(keyed) != len(resultsMap) || keyed["10"].MyAnimeList.ID != 10 {
+		t.Errorf("keyed output = %v", keyed)
+	}
+	if strings.Index(string(data), `"9":`) > strings.Index(string(data), `"10":`) {
+		t.Error("keys are not in numeric MAL ID order")
+	}
+
+	manifest, err := BuildManifest([]string{KeyedPath(outputFile)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manifest.Files[0].Entries; got != len(resultsMap) {
+		t.Errorf("manifest counts %d entries in the keyed output, want %d", got, len(resultsMap))
+	}
+}
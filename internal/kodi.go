@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+)
+
+// RunExportKodi implements the `export-kodi` subcommand. Kodi's AniDB
+// scrapers read the same anime-list.xml format as HAMA but only resolve
+// artwork and episodes through TVDB, so entries without a TVDB ID are dropped
+// and the TMDB attributes, which Kodi ignores, are left out.
+func RunExportKodi(args []string) error {
+	fs := flag.NewFlagSet("export-kodi", flag.ExitOnError)
+	output := fs.String("output", "dist/anime-list-kodi.xml", "Path of the anime-list XML to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file (empty to skip movies)")
+	animeAPIFile := fs.String("animeapi", "", "Path to animeapi.tsv for MAL → AniDB IDs (empty = fetch from animeapi.my.id)")
+	fs.Parse(args)
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+
+	_, malToRow, err := LoadAnimeAPITSV(*animeAPIFile)
+	if err != nil {
+		return err
+	}
+
+	kodi, skips := buildAnimeList(shows, movies, malToRow, true)
+	for i := range kodi.Anime {
+		kodi.Anime[i].TMDBTV = ""
+		kodi.Anime[i].TMDBSeason = ""
+		if kodi.Anime[i].TVDBID != "movie" {
+			kodi.Anime[i].TMDBID = ""
+		}
+	}
+	if err := writeAnimeListXML(*output, kodi); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d anime-list entries to %s\n", len(kodi.Anime), *output)
+	fmt.Printf("Skipped: %d without AniDB ID, %d split cours, %d without TVDB ID, %d duplicate AniDB IDs\n",
+		skips.noAniDB, skips.splitCour, skips.noExternal, skips.duplicate)
+	return nil
+}
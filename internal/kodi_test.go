@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRunExportKodi(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeAnimeListFixture(t)
+
+	if err := RunExportKodi([]string{"-output", "kodi.xml", "-tv", "tv_ex.json", "-movies", "movies_ex.json", "-animeapi", "animeapi.tsv"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("kodi.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "anime-list-kodi.xml", got)
+}
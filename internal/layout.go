@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// DataLayout is where the data files live: each directory is joined to Base
+// unless it is absolute
+type DataLayout struct {
+	Base      string // "json"
+	Input     string // input mappings, the default of scrape
+	Output    string // output files and the manifest
+	Overrides string // {tv,movies}_overrides.json
+	NotFound  string // not_exist_<output>.json
+	Retry     string // retry_<output>.json
+	Review    string // review_queue.json
+	Changes   string // per-day changelogs
+	Journal   string // pending journals
+	Snapshots string // output snapshots
+}
+
+// DefaultLayout is the layout of the repository checkout
+func DefaultLayout() DataLayout {
+	return DataLayout{
+		Base:      "json",
+		Input:     "input",
+		Output:    "output",
+		Overrides: "overrides",
+		NotFound:  "not_found",
+		Retry:     "retry",
+		Review:    "review",
+		Changes:   "changes",
+		Journal:   "journal",
+		Snapshots: "snapshots",
+	}
+}
+
+// layout is the layout of this process, see LoadLayout
+var layout = DefaultLayout()
+
+// dirs maps the name of each directory in its environment variable to its
+// field
+func (l *DataLayout) dirs() map[string]*string {
+	return map[string]*string{
+		"data":      &l.Base,
+		"input":     &l.Input,
+		"output":    &l.Output,
+		"overrides": &l.Overrides,
+		"not_found": &l.NotFound,
+		"retry":     &l.Retry,
+		"review":    &l.Review,
+		"changes":   &l.Changes,
+		"journal":   &l.Journal,
+		"snapshots": &l.Snapshots,
+	}
+}
+
+// LoadLayout sets the layout from the environment: ANITRAKT_DATA_DIR for the
+// base, ANITRAKT_<NAME>_DIR for a directory, e.g. ANITRAKT_NOT_FOUND_DIR. Call
+// it before flags are defined, as their defaults follow the layout.
+func LoadLayout() {
+	l := DefaultLayout()
+	for name, dir := range l.dirs() {
+		*dir = envOr("ANITRAKT_"+strings.ToUpper(name)+"_DIR", *dir)
+	}
+	layout = l
+}
+
+func (l DataLayout) dir(sub string) string {
+	if filepath.IsAbs(sub) {
+		return sub
+	}
+	return filepath.Join(l.Base, sub)
+}
+
+func (l DataLayout) InputDir() string     { return l.dir(l.Input) }
+func (l DataLayout) OutputDir() string    { return l.dir(l.Output) }
+func (l DataLayout) OverridesDir() string { return l.dir(l.Overrides) }
+func (l DataLayout) NotFoundDir() string  { return l.dir(l.NotFound) }
+func (l DataLayout) RetryDir() string     { return l.dir(l.Retry) }
+func (l DataLayout) ReviewDir() string    { return l.dir(l.Review) }
+func (l DataLayout) ChangesDir() string   { return l.dir(l.Changes) }
+func (l DataLayout) JournalDir() string   { return l.dir(l.Journal) }
+func (l DataLayout) SnapshotsDir() string { return l.dir(l.Snapshots) }
+
+// defaultOutput is the default output file of mediaType, the default of the
+// -tv and -movies flags of the subcommands reading the dataset
+func defaultOutput(mediaType string) string {
+	return DefaultOutputFile(nil, mediaType)
+}
+
+// ManifestPath is the path of the dataset manifest
+func ManifestPath() string {
+	return filepath.Join(layout.OutputDir(), "manifest.json")
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLayout(t *testing.T) {
+	t.Cleanup(func() { layout = DefaultLayout() })
+	abs := filepath.Join(t.TempDir(), "overrides")
+	t.Setenv("ANITRAKT_DATA_DIR", "data")
+	t.Setenv("ANITRAKT_NOT_FOUND_DIR", "missing")
+	t.Setenv("ANITRAKT_OVERRIDES_DIR", abs)
+	LoadLayout()
+
+	tests := []struct{ got, want string }{
+		{defaultOutput("tv"), filepath.Join("data", "output", "tv_ex.json")},
+		{notFoundPath("tv_ex.json"), filepath.Join("data", "missing", "not_exist_tv_ex.json")},
+		{overridesPath("movies"), filepath.Join(abs, "movies_overrides.json")},
+		{ManifestPath(), filepath.Join("data", "output", "manifest.json")},
+		{journalDirFor("tv_ex.json"), filepath.Join("data", "journal", "tv_ex")},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default Letterboxd politeness settings, adjustable with -letterboxd-rate,
+// -letterboxd-delay and -letterboxd-user-agent
+const (
+	DefaultLetterboxdRate      = 100 // requests per minute
+	DefaultLetterboxdDelay     = 500 * time.Millisecond
+	DefaultLetterboxdUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// letterboxdEnricher queues movies on the run's letterboxdPhase, which adds
+// their Letterboxd IDs once the Trakt loop is done
+type letterboxdEnricher struct{}
+
+func (letterboxdEnricher) Name() string { return "letterboxd" }
+
+func (letterboxdEnricher) Applies(entry EnrichEntry) bool { return entry.Movie != nil }
+
+func (letterboxdEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	ctx.letterboxd.submit(entry.Movie, entry.ExistingMovie)
+}
+
+// Retain carries the previous Letterboxd IDs over, so a Trakt-only pass
+// never drops them
+func (letterboxdEnricher) Retain(entry EnrichEntry) {
+	movie, existing := entry.Movie, entry.ExistingMovie
+	if movie != nil && movie.Externals != nil && existing != nil && existing.Externals != nil && movie.Externals.Letterboxd == nil {
+		movie.Externals.Letterboxd = existing.Externals.Letterboxd
+	}
+}
+
+// letterboxdPhase enriches movies with Letterboxd IDs on its own worker pool,
+// throttled by config.LetterboxdRateLimiter, so Letterboxd lookups overlap
+// with the Trakt loop instead of doubling its wall time
+type letterboxdPhase struct {
+	disabled bool
+	backfill bool
+	jobs     chan letterboxdJob
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	notFound []ChangeDetail
+}
+
+// letterboxdJob is one movie to enrich. movie is updated in place; existing
+// holds the previous output, whose Letterboxd IDs survive a failed lookup.
+type letterboxdJob struct {
+	movie    *OutputMovie
+	existing *OutputMovie
+}
+
+// startLetterboxdPhase starts config.LetterboxdWorkers workers (at least
+// one), or none when the letterboxd enrichment is not selected
+func startLetterboxdPhase(client *http.Client, config Config) *letterboxdPhase {
+	workers := config.LetterboxdWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	phase := &letterboxdPhase{
+		disabled: !config.Enrichments["letterboxd"],
+		backfill: config.EnrichMissing,
+		jobs:     make(chan letterboxdJob, workers*4),
+	}
+	if phase.disabled {
+		return phase
+	}
+	for i := 0; i < workers; i++ {
+		phase.wg.Add(1)
+		go func() {
+			defer phase.wg.Done()
+			for job := range phase.jobs {
+				if detail := updateLetterboxdInfo(client, config, job.movie, job.existing); detail != nil {
+					phase.mu.Lock()
+					phase.notFound = append(phase.notFound, *detail)
+					phase.mu.Unlock()
+				}
+			}
+		}()
+	}
+	return phase
+}
+
+// submit queues a movie; the caller must not touch it until wait returns.
+// A disabled phase ignores it.
+func (p *letterboxdPhase) submit(movie, existing *OutputMovie) {
+	if p.disabled {
+		return
+	}
+	p.jobs <- letterboxdJob{movie: movie, existing: existing}
+}
+
+// submitMissing queues a copy of an existing entry without Letterboxd IDs
+// when -enrich-missing is set. It returns the copy, or nil if nothing was
+// queued.
+func (p *letterboxdPhase) submitMissing(existing OutputMovie) *OutputMovie {
+	if p.disabled || !p.backfill || !canLookUpLetterboxd(existing.Externals) ||
+		(existing.Externals.Letterboxd != nil && existing.Externals.Letterboxd.Slug != nil) {
+		return nil
+	}
+	externals := *existing.Externals
+	movie := existing
+	movie.Externals = &externals
+	p.jobs <- letterboxdJob{movie: &movie}
+	return &movie
+}
+
+// wait drains the queue and returns the movies missing on Letterboxd, sorted
+// by MAL ID
+func (p *letterboxdPhase) wait() []ChangeDetail {
+	close(p.jobs)
+	p.wg.Wait()
+	sort.Slice(p.notFound, func(i, j int) bool {
+		return p.notFound[i].MalID < p.notFound[j].MalID
+	})
+	return p.notFound
+}
+
+// letterboxdCacheEntry is the content of a letterboxd/{tmdb}.json cache file,
+// or letterboxd/imdb_{imdb}.json for movies without a TMDB ID.
+// Misses are cached too, so films absent from Letterboxd are not looked up
+// again on every run. Entries without checked_at (older caches and `cache
+// warm`) never expire.
+type letterboxdCacheEntry struct {
+	Letterboxd
+	NotFound  bool      `json:"not_found,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
+// fresh reports whether the entry is within its TTL; a zero TTL never expires
+func (e letterboxdCacheEntry) fresh(config Config) bool {
+	ttl := config.LetterboxdTTL
+	if e.NotFound {
+		ttl = config.LetterboxdNegativeTTL
+	}
+	return ttl <= 0 || e.CheckedAt.IsZero() || time.Since(e.CheckedAt) < ttl
+}
+
+// errLetterboxdNotFound builds the error reported for films Letterboxd does
+// not know; updateLetterboxdInfo matches on its text
+func errLetterboxdNotFound(tmdbID int, imdbID string) error {
+	if tmdbID == 0 {
+		return fmt.Errorf("\n    - Film not found on Letterboxd for IMDB ID %s", imdbID)
+	}
+	return fmt.Errorf("\n    - Film not found on Letterboxd for TMDB ID %d", tmdbID)
+}
+
+// letterboxdCachePath returns the cache file of a Letterboxd lookup, keyed
+// by TMDB ID, or by IMDB ID when tmdbID is 0
+func letterboxdCachePath(config Config, tmdbID int, imdbID string) string {
+	if tmdbID == 0 {
+		return filepath.Join(config.TempDir, "letterboxd", "imdb_"+imdbID+".json")
+	}
+	return filepath.Join(config.TempDir, "letterboxd", fmt.Sprintf("%d.json", tmdbID))
+}
+
+// canLookUpLetterboxd reports whether a movie has an ID Letterboxd resolves
+func canLookUpLetterboxd(externals *TraktExternalsMovie) bool {
+	return externals != nil && (externals.TMDB != nil || (externals.IMDB != nil && *externals.IMDB != ""))
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal/trakttest"
+)
+
+// letterboxdServer fakes letterboxd.com: tmdb and imdb map IDs to film
+// slugs, other IDs are answered with Letterboxd's not-found page
+type letterboxdServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	tmdb     map[string]string
+	imdb     map[string]string
+	requests []string
+}
+
+func newLetterboxdServer(t *testing.T, tmdb, imdb map[string]string) *letterboxdServer {
+	t.Helper()
+	s := &letterboxdServer{tmdb: tmdb, imdb: imdb}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *letterboxdServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	s.mu.Unlock()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "film" && parts[2] == "json":
+		fmt.Fprintf(w, `{"id": %d, "lid": "lid-%s", "slug": %q}`, len(parts[1]), parts[1], parts[1])
+		return
+	case len(parts) == 2 && (parts[0] == "tmdb" || parts[0] == "imdb"):
+		slugs := s.tmdb
+		if parts[0] == "imdb" {
+			slugs = s.imdb
+		}
+		if slug, ok := slugs[parts[1]]; ok {
+			http.Redirect(w, r, "/film/"+slug+"/", http.StatusFound)
+			return
+		}
+	}
+	fmt.Fprint(w, "<html>TMDB Import Result: Film not found</html>")
+}
+
+// count returns how many requests started with prefix
+func (s *letterboxdServer) count(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, path := range s.requests {
+		if strings.HasPrefix(path, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func letterboxdTestConfig(t *testing.T, server *letterboxdServer) Config {
+	t.Helper()
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "letterboxd"), 0755)
+	return Config{
+		TempDir:               dir,
+		Cache:                 NewCache(0),
+		LetterboxdURL:         server.URL,
+		LetterboxdRateLimiter: NewLetterboxdRateLimiter(6000),
+		Enrichments:           map[string]bool{"letterboxd": true},
+	}
+}
+
+// letterboxdTestMovie returns a movie with the given TMDB and IMDB IDs (0 and
+// "" for none)
+func letterboxdTestMovie(malID, tmdbID int, imdbID string) OutputMovie {
+	var movie OutputMovie
+	movie.MyAnimeList.ID = malID
+	movie.MyAnimeList.Title = fmt.Sprintf("Movie %d", malID)
+	movie.Externals = &TraktExternalsMovie{}
+	if tmdbID != 0 {
+		movie.Externals.TMDB = &tmdbID
+	}
+	if imdbID != "" {
+		movie.Externals.IMDB = &imdbID
+	}
+	return movie
+}
+
+func TestUpdateLetterboxdInfoFallsBackToIMDB(t *testing.T) {
+	server := newLetterboxdServer(t,
+		map[string]string{"149": "akira"},
+		map[string]string{"tt0094625": "akira", "tt0000001": "no-tmdb-film", "tt0000002": "imdb-only"})
+	config := letterboxdTestConfig(t, server)
+	client := NewHTTPClient(0)
+
+	tests := []struct {
+		name   string
+		movie  OutputMovie
+		slug   string
+		via    string
+		lookup string // redirect that must have been asked
+	}{
+		{"TMDB redirect", letterboxdTestMovie(1, 149, "tt0094625"), "akira", "tmdb", "/tmdb/149/"},
+		{"TMDB miss", letterboxdTestMovie(2, 404, "tt0000001"), "no-tmdb-film", "imdb", "/imdb/tt0000001/"},
+		{"no TMDB ID", letterboxdTestMovie(3, 0, "tt0000002"), "imdb-only", "imdb", "/imdb/tt0000002/"},
+	}
+	for _, tt := range tests {
+		movie := tt.movie
+		if detail := updateLetterboxdInfo(client, config, &movie, nil); detail != nil {
+			t.Errorf("%s: not found: %+v", tt.name, detail)
+			continue
+		}
+		lb := movie.Externals.Letterboxd
+		if lb == nil || lb.Slug == nil || *lb.Slug != tt.slug || lb.ResolvedVia != tt.via {
+			t.Errorf("%s: letterboxd = %+v, want %s resolved via %s", tt.name, lb, tt.slug, tt.via)
+		}
+		if server.count(tt.lookup) != 1 {
+			t.Errorf("%s: %s asked %d times, want once", tt.name, tt.lookup, server.count(tt.lookup))
+		}
+	}
+	if n := server.count("/tmdb/0/"); n != 0 {
+		t.Errorf("looked up TMDB ID 0 %d times", n)
+	}
+
+	missing := letterboxdTestMovie(4, 0, "tt9999999")
+	if detail := updateLetterboxdInfo(client, config, &missing, nil); detail == nil || detail.Reason != "Film not found on Letterboxd" {
+		t.Errorf("missing film: detail = %+v, want it reported as not found", detail)
+	}
+	// Misses are cached under the IMDB ID too
+	updateLetterboxdInfo(client, config, &missing, nil)
+	if n := server.count("/imdb/tt9999999/"); n != 1 {
+		t.Errorf("cached miss looked up %d times, want once", n)
+	}
+}
+
+func TestLetterboxdPhaseBackfillsIMDBOnlyMovies(t *testing.T) {
+	server := newLetterboxdServer(t, nil, map[string]string{"tt0000002": "imdb-only"})
+	config := letterboxdTestConfig(t, server)
+	config.EnrichMissing = true
+	phase := startLetterboxdPhase(NewHTTPClient(0), config)
+
+	queued := phase.submitMissing(letterboxdTestMovie(1, 0, "tt0000002"))
+	if phase.submitMissing(letterboxdTestMovie(2, 0, "")) != nil {
+		t.Error("movie without TMDB and IMDB IDs was queued")
+	}
+	phase.wait()
+	if queued == nil || queued.Externals.Letterboxd == nil || queued.Externals.Letterboxd.ResolvedVia != "imdb" {
+		t.Fatalf("backfilled movie = %+v, want Letterboxd IDs resolved via IMDB", queued)
+	}
+}
+
+func TestLetterboxdPhaseCollectsNotFoundFromEveryWorker(t *testing.T) {
+	server := newLetterboxdServer(t, map[string]string{"1": "found"}, nil)
+	config := letterboxdTestConfig(t, server)
+	config.LetterboxdWorkers = 4
+	phase := startLetterboxdPhase(NewHTTPClient(0), config)
+
+	movies := make([]OutputMovie, 40)
+	for i := range movies {
+		movies[i] = letterboxdTestMovie(len(movies)-i, len(movies)-i, "")
+		phase.submit(&movies[i], nil)
+	}
+	notFound := phase.wait()
+	if len(notFound) != len(movies)-1 {
+		t.Fatalf("not found = %d entries, want %d", len(notFound), len(movies)-1)
+	}
+	for i, detail := range notFound {
+		if detail.MalID != i+2 {
+			t.Fatalf("not found[%d] = MAL ID %d, want %d in MAL ID order", i, detail.MalID, i+2)
+		}
+	}
+}
+
+func TestProcessMoviesOverridesWinOverLetterboxd(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { layout = DefaultLayout() })
+	layout = DefaultLayout()
+
+	trakt := trakttest.NewServer(t)
+	trakt.Movie(100, "akira-1988", map[string]any{"title": "Akira", "year": 1988, "ids": map[string]any{"trakt": 100, "slug": "akira-1988", "tmdb": 149}})
+	trakt.Movie(200, "perfect-blue-1997", map[string]any{"title": "Perfect Blue", "year": 1997, "ids": map[string]any{"trakt": 200, "slug": "perfect-blue-1997", "tmdb": 10494}})
+	letterboxd := newLetterboxdServer(t, map[string]string{"149": "akira", "10494": "perfect-blue"}, nil)
+
+	input := "movies.json"
+	os.WriteFile(input, []byte(`[
+		{"title": "Akira", "mal_id": 47, "trakt_id": 100, "type": "movies"},
+		{"title": "Perfect Blue", "mal_id": 437, "trakt_id": 200, "type": "movies"}
+	]`), 0644)
+	os.MkdirAll(layout.OverridesDir(), 0755)
+	os.WriteFile(overridesPath("movies"), []byte(`[
+		{"mal_id": 47, "description": "Letterboxd lists the restoration", "externals": {"letterboxd": {"slug": "akira-restored", "uid": null, "lid": null}}}
+	]`), 0644)
+
+	config := traktTestConfig(t, trakt)
+	lbConfig := letterboxdTestConfig(t, letterboxd)
+	os.MkdirAll(filepath.Join(config.TempDir, "letterboxd"), 0755)
+	config.LetterboxdURL = lbConfig.LetterboxdURL
+	config.LetterboxdRateLimiter = lbConfig.LetterboxdRateLimiter
+	config.Enrichments = lbConfig.Enrichments
+	config.LetterboxdWorkers = 2
+	config.MovieFiles = []string{input}
+	config.NoProgress = true
+
+	if err := ProcessMovies(config); err != nil {
+		t.Fatalf("ProcessMovies = %v", err)
+	}
+	var movies []OutputMovie
+	if err := LoadJSON(DefaultOutputFile(config.MovieFiles, "movies"), &movies); err != nil {
+		t.Fatal(err)
+	}
+	slugs := make(map[int]string)
+	for _, movie := range movies {
+		if lb := movie.Externals.Letterboxd; lb != nil && lb.Slug != nil {
+			slugs[movie.MyAnimeList.ID] = *lb.Slug
+		}
+	}
+	if slugs[47] != "akira-restored" {
+		t.Errorf("overridden slug = %q, want the override to win over Letterboxd", slugs[47])
+	}
+	if slugs[437] != "perfect-blue" {
+		t.Errorf("Letterboxd slug = %q, want perfect-blue", slugs[437])
+	}
+	if letterboxd.count("/tmdb/149/") != 1 {
+		t.Error("overridden movie was not looked up on Letterboxd")
+	}
+}
+
+func TestProcessMoviesNoEnrichSkipsLetterboxd(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { layout = DefaultLayout() })
+	layout = DefaultLayout()
+
+	trakt := trakttest.NewServer(t)
+	trakt.Movie(100, "akira-1988", map[string]any{"title": "Akira", "year": 1988, "ids": map[string]any{"trakt": 100, "slug": "akira-1988", "tmdb": 149}})
+	letterboxd := newLetterboxdServer(t, map[string]string{"149": "akira"}, nil)
+
+	input := "movies.json"
+	os.WriteFile(input, []byte(`[{"title": "Akira", "mal_id": 47, "trakt_id": 100, "type": "movies"}]`), 0644)
+	config := traktTestConfig(t, trakt)
+	config.LetterboxdURL = letterboxd.URL
+	config.LetterboxdRateLimiter = NewLetterboxdRateLimiter(6000)
+	config.Enrichments, _ = parseEnrichments("letterboxd", true, false, nil)
+	config.MovieFiles = []string{input}
+	config.NoProgress = true
+
+	if err := ProcessMovies(config); err != nil {
+		t.Fatalf("ProcessMovies = %v", err)
+	}
+	if n := letterboxd.count("/"); n != 0 {
+		t.Errorf("-no-enrich made %d Letterboxd requests, want none", n)
+	}
+	var movies []OutputMovie
+	LoadJSON(DefaultOutputFile(config.MovieFiles, "movies"), &movies)
+	if len(movies) != 1 || movies[0].Trakt.ID != 100 {
+		t.Errorf("output = %+v, want Akira from Trakt", movies)
+	}
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// maxSeason bounds plausible season numbers; anything above is a typo
+const maxSeason = 99
+
+// slugPattern is the shape of a Trakt slug
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// inputRow is an input entry as written, with pointers so missing fields can
+// be told apart from zero values
+type inputRow struct {
+	Title       *string `json:"title"`
+	MalID       *int    `json:"mal_id"`
+	TraktID     *int    `json:"trakt_id"`
+	GuessedSlug *string `json:"guessed_slug"`
+	Season      *int    `json:"season"`
+	Type        *string `json:"type"`
+}
+
+// lintProblem is one finding of lint-input, Row counting from 1
+type lintProblem struct {
+	Row     int
+	MalID   int
+	Message string
+}
+
+// lintInput checks input rows for missing fields, impossible IDs and season
+// numbers, malformed slugs and repeated rows
+func lintInput(rows []inputRow) []lintProblem {
+	var problems []lintProblem
+	seenKey := make(map[[3]int]int) // MAL ID, Trakt ID, season -> row
+	seenMal := make(map[int]int)    // MAL ID -> first row
+	fileType := ""
+
+	for i, row := range rows {
+		n := i + 1
+		malID := 0
+		if row.MalID != nil {
+			malID = *row.MalID
+		}
+		report := func(format string, args ...any) {
+			problems = append(problems, lintProblem{Row: n, MalID: malID, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if row.Title == nil || *row.Title == "" {
+			report("title is missing")
+		}
+		switch {
+		case row.MalID == nil:
+			report("mal_id is missing")
+		case *row.MalID <= 0:
+			report("mal_id is %d", *row.MalID)
+		}
+		switch {
+		case row.TraktID == nil:
+			report("trakt_id is missing")
+		case *row.TraktID <= 0:
+			report("trakt_id is %d", *row.TraktID)
+		}
+
+		switch {
+		case row.Type == nil:
+			report("type is missing")
+		case *row.Type != "shows" && *row.Type != "movies":
+			report("type is %q (expected shows or movies)", *row.Type)
+		case fileType == "":
+			fileType = *row.Type
+		case *row.Type != fileType:
+			report("type is %s but earlier rows are %s", *row.Type, fileType)
+		}
+
+		season := 0
+		if row.Season != nil {
+			season = *row.Season
+			if row.Type != nil && *row.Type == "movies" {
+				report("movies have no season (got %d)", season)
+			} else if season < 0 || season > maxSeason {
+				report("season %d is impossible", season)
+			}
+		} else if row.Type != nil && *row.Type == "shows" {
+			report("season is missing")
+		}
+
+		if row.GuessedSlug != nil && *row.GuessedSlug != "" && !slugPattern.MatchString(*row.GuessedSlug) {
+			report("guessed_slug %q is not a Trakt slug", *row.GuessedSlug)
+		}
+
+		if row.MalID == nil || row.TraktID == nil {
+			continue
+		}
+		key := [3]int{*row.MalID, *row.TraktID, season}
+		if first, ok := seenKey[key]; ok {
+			report("duplicate of row %d", first)
+			continue
+		}
+		seenKey[key] = n
+		if first, ok := seenMal[*row.MalID]; ok {
+			report("MAL ID already mapped on row %d", first)
+		} else {
+			seenMal[*row.MalID] = n
+		}
+	}
+	return problems
+}
+
+// RunLintInput implements the `lint-input` subcommand, checking input files
+// before a long run is spent on them
+func RunLintInput(args []string) error {
+	fs := flag.NewFlagSet("lint-input", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lint-input [file.json|glob|-]...\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no input files given")
+	}
+
+	files, err := ExpandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, file := range files {
+		rows, err := readInputRows(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		problems := lintInput(rows)
+		for _, p := range problems {
+			fmt.Printf("%s: row %d (MAL ID %d): %s\n", file, p.Row, p.MalID, p.Message)
+		}
+		if len(problems) == 0 {
+			fmt.Printf("✓ %s: %d rows\n", file, len(rows))
+		}
+		total += len(problems)
+	}
+
+	if total > 0 {
+		return exitErrorf(ExitValidation, "%d problems found", total)
+	}
+	return nil
+}
+
+// readInputRows decodes an input file, stdin (StdioPath) or URL
+func readInputRows(file string) ([]inputRow, error) {
+	r, err := openInput(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var rows []inputRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
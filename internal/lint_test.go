@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLintInput(t *testing.T) {
+	var rows []inputRow
+	input := `[
+		{"title": "Cowboy Bebop", "mal_id": 1, "trakt_id": 30857, "guessed_slug": "cowboy-bebop", "season": 1, "type": "shows"},
+		{"title": "Cowboy Bebop", "mal_id": 1, "trakt_id": 30857, "guessed_slug": "cowboy-bebop", "season": 1, "type": "shows"},
+		{"title": "Trigun", "mal_id": 6, "trakt_id": 0, "guessed_slug": "Trigun!", "season": 120, "type": "shows"},
+		{"mal_id": 6, "trakt_id": 1, "season": 1, "type": "movies"}
+	]`
+	if err := json.Unmarshal([]byte(input), &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []lintProblem{
+		{2, 1, "duplicate of row 1"},
+		{3, 6, "trakt_id is 0"},
+		{3, 6, "season 120 is impossible"},
+		{3, 6, `guessed_slug "Trigun!" is not a Trakt slug`},
+		{4, 6, "title is missing"},
+		{4, 6, "type is movies but earlier rows are shows"},
+		{4, 6, "movies have no season (got 1)"},
+		{4, 6, "MAL ID already mapped on row 3"},
+	}
+	got := lintInput(rows)
+	if len(got) != len(want) {
+		t.Fatalf("got %d problems, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// List endpoint page sizes
+const (
+	defaultPerPage = 100
+	maxPerPage     = 500
+)
+
+// ListPage is the response of the /shows and /movies list endpoints.
+// NextCursor continues after the last item; it stays valid across reloads
+// because entries are listed by MAL ID.
+type ListPage[T any] struct {
+	Total      int    `json:"total"`          // entries matching the filters
+	Page       int    `json:"page,omitempty"` // 1-based, when paging by number
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor"` // "" on the last page
+	Items      []T    `json:"items"`
+}
+
+// listFields are the fields the list endpoints filter on
+type listFields struct {
+	year              int
+	status, quality   string
+	country, language string
+	genres            []string
+	titles            []string // lower-cased MAL and Trakt titles
+	season            *int     // shows only
+	splitCour         bool     // shows only
+}
+
+func (s OutputShow) listFields() listFields {
+	f := listFields{
+		year: s.ReleaseYear, status: s.Status, quality: s.Quality,
+		country: s.Country, language: s.Language, genres: s.Genres,
+		titles:    []string{strings.ToLower(s.MyAnimeList.Title), strings.ToLower(s.Trakt.Title)},
+		splitCour: s.Trakt.IsSplitCour,
+	}
+	if s.Trakt.Season != nil {
+		f.season = &s.Trakt.Season.Number
+	}
+	return f
+}
+
+func (m OutputMovie) listFields() listFields {
+	return listFields{
+		year: m.ReleaseYear, quality: m.Quality,
+		country: m.Country, language: m.Language, genres: m.Genres,
+		titles: []string{strings.ToLower(m.MyAnimeList.Title), strings.ToLower(m.Trakt.Title)},
+	}
+}
+
+// listable is an entry of a list endpoint
+type listable interface {
+	sortable
+	listFields() listFields
+}
+
+// listFilter parses the filter parameters of a list request. Unknown
+// parameters are rejected so a typo does not silently return everything.
+func listFilter(query url.Values, shows bool) (func(listFields) bool, error) {
+	var checks []func(listFields) bool
+	for name, values := range query {
+		value := values[0]
+		switch name {
+		case "page", "per_page", "cursor":
+			continue
+		case "year":
+			year, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid year %q", value)
+			}
+			checks = append(checks, func(f listFields) bool { return f.year == year })
+		case "status":
+			checks = append(checks, func(f listFields) bool { return strings.EqualFold(f.status, value) })
+		case "quality":
+			checks = append(checks, func(f listFields) bool { return strings.EqualFold(f.quality, value) })
+		case "country":
+			checks = append(checks, func(f listFields) bool { return strings.EqualFold(f.country, value) })
+		case "language":
+			checks = append(checks, func(f listFields) bool { return strings.EqualFold(f.language, value) })
+		case "genre":
+			checks = append(checks, func(f listFields) bool {
+				return slices.ContainsFunc(f.genres, func(genre string) bool { return strings.EqualFold(genre, value) })
+			})
+		case "q":
+			q := strings.ToLower(value)
+			checks = append(checks, func(f listFields) bool {
+				return slices.ContainsFunc(f.titles, func(title string) bool { return strings.Contains(title, q) })
+			})
+		case "season":
+			if !shows {
+				return nil, fmt.Errorf("unknown filter %q", name)
+			}
+			if value == "none" {
+				checks = append(checks, func(f listFields) bool { return f.season == nil })
+				break
+			}
+			season, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid season %q (a number or none)", value)
+			}
+			checks = append(checks, func(f listFields) bool { return f.season != nil && *f.season == season })
+		case "split_cour":
+			if !shows {
+				return nil, fmt.Errorf("unknown filter %q", name)
+			}
+			split, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid split_cour %q", value)
+			}
+			checks = append(checks, func(f listFields) bool { return f.splitCour == split })
+		default:
+			return nil, fmt.Errorf("unknown filter %q", name)
+		}
+	}
+	return func(f listFields) bool {
+		for _, check := range checks {
+			if !check(f) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// encodeCursor and decodeCursor make the opaque cursor of a MAL ID
+func encodeCursor(malID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("mal:" + strconv.Itoa(malID)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		if id, ok := strings.CutPrefix(string(raw), "mal:"); ok {
+			return strconv.Atoi(id)
+		}
+	}
+	return 0, fmt.Errorf("invalid cursor")
+}
+
+// listEntries filters and pages entries sorted by MAL ID. A cursor continues
+// after the entry it names even if that entry is gone since; page numbers
+// are offsets into the current data.
+func listEntries[T listable](entries []T, query url.Values, shows bool) (ListPage[T], error) {
+	match, err := listFilter(query, shows)
+	if err != nil {
+		return ListPage[T]{}, err
+	}
+	perPage := defaultPerPage
+	if v := query.Get("per_page"); v != "" {
+		if perPage, err = strconv.Atoi(v); err != nil || perPage < 1 || perPage > maxPerPage {
+			return ListPage[T]{}, fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+		}
+	}
+
+	var matched []T
+	for _, entry := range entries {
+		if match(entry.listFields()) {
+			matched = append(matched, entry)
+		}
+	}
+	page := ListPage[T]{Total: len(matched), PerPage: perPage, Items: []T{}}
+
+	start := 0
+	switch cursor, number := query.Get("cursor"), query.Get("page"); {
+	case cursor != "" && number != "":
+		return ListPage[T]{}, fmt.Errorf("pass either cursor or page, not both")
+	case cursor != "":
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return ListPage[T]{}, err
+		}
+		start, _ = slices.BinarySearchFunc(matched, after+1, func(entry T, id int) int { return entry.sortKey().malID - id })
+	default:
+		page.Page = 1
+		if number != "" {
+			if page.Page, err = strconv.Atoi(number); err != nil || page.Page < 1 {
+				return ListPage[T]{}, fmt.Errorf("invalid page %q", number)
+			}
+		}
+		start = min((page.Page-1)*perPage, len(matched))
+	}
+
+	end := min(start+perPage, len(matched))
+	page.Items = append(page.Items, matched[start:end]...)
+	if end < len(matched) {
+		page.NextCursor = encodeCursor(matched[end-1].sortKey().malID)
+	}
+	return page, nil
+}
+
+// handleListShows serves GET /shows
+func (s *server) handleListShows(w http.ResponseWriter, r *http.Request) {
+	writeListPage(w, r, s.dataset().ShowList(), true)
+}
+
+// handleListMovies serves GET /movies
+func (s *server) handleListMovies(w http.ResponseWriter, r *http.Request) {
+	writeListPage(w, r, s.dataset().MovieList(), false)
+}
+
+// writeListPage answers a list request, with a Link header to the next page
+func writeListPage[T listable](w http.ResponseWriter, r *http.Request, entries []T, shows bool) {
+	page, err := listEntries(entries, r.URL.Query(), shows)
+	if err != nil {
+		writeJSONResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if page.NextCursor != "" {
+		next := r.URL.Query()
+		next.Del("page")
+		next.Set("cursor", page.NextCursor)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, next.Encode()))
+	}
+	writeJSONResponse(w, http.StatusOK, page)
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestListEntriesFilters(t *testing.T) {
+	shows := testDataset(t).ShowList()
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{1, 5}},
+		{"year=2001", []int{5}},
+		{"q=tobira", []int{5}},
+		{"q=COWBOY&season=1", []int{1}},
+		{"season=none", nil},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		page, err := listEntries(shows, query, true)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var got []int
+		for _, show := range page.Items {
+			got = append(got, show.MyAnimeList.ID)
+		}
+		if len(got) != len(tt.want) || page.Total != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s: got %v (total %d), want %v", tt.query, got, page.Total, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"yaer=2001", "year=soon", "per_page=501", "page=0", "cursor=x", "page=1&cursor=" + encodeCursor(1)} {
+		query, _ := url.ParseQuery(bad)
+		if _, err := listEntries(shows, query, true); err == nil {
+			t.Errorf("%s: accepted", bad)
+		}
+	}
+	if _, err := listEntries(testDataset(t).MovieList(), url.Values{"season": {"1"}}, false); err == nil {
+		t.Error("season filter accepted for movies")
+	}
+}
+
+func TestListEntriesPaging(t *testing.T) {
+	shows := testDataset(t).ShowList()
+	first, _ := listEntries(shows, url.Values{"per_page": {"1"}}, true)
+	if len(first.Items) != 1 || first.Items[0].MyAnimeList.ID != 1 || first.NextCursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+	second, _ := listEntries(shows, url.Values{"per_page": {"1"}, "cursor": {first.NextCursor}}, true)
+	if len(second.Items) != 1 || second.Items[0].MyAnimeList.ID != 5 || second.NextCursor != "" {
+		t.Fatalf("second page = %+v", second)
+	}
+	byNumber, _ := listEntries(shows, url.Values{"per_page": {"1"}, "page": {"2"}}, true)
+	if byNumber.Page != 2 || byNumber.Items[0].MyAnimeList.ID != 5 {
+		t.Errorf("page 2 = %+v", byNumber)
+	}
+
+	// The cursor stays valid when the entry it names is removed
+	again, _ := listEntries(shows[1:], url.Values{"per_page": {"1"}, "cursor": {first.NextCursor}}, true)
+	if len(again.Items) != 1 || again.Items[0].MyAnimeList.ID != 5 {
+		t.Errorf("page after a removed cursor entry = %+v", again)
+	}
+}
+
+func TestListShowsHandler(t *testing.T) {
+	s := &server{}
+	s.data.Store(testDataset(t))
+	rec := httptest.NewRecorder()
+	s.handleListShows(rec, httptest.NewRequest(http.MethodGet, "/shows?per_page=1&q=bebop", nil))
+
+	var page ListPage[OutputShow]
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /shows = %d %s", rec.Code, rec.Body)
+	}
+	if link := rec.Header().Get("Link"); !strings.Contains(link, "cursor="+page.NextCursor) || !strings.Contains(link, "q=bebop") {
+		t.Errorf("Link = %q, want the next cursor and the filters", link)
+	}
+}
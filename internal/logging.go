@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// LogLevel selects how much a run prints (-log-level, -quiet, -verbose)
+type LogLevel int
+
+const (
+	LogError LogLevel = iota // errors and the final summary only
+	LogWarn                  // plus warnings
+	LogInfo                  // plus progress and status lines (default)
+	LogDebug                 // plus per-entry detail, same as -verbose
+)
+
+// logLevelNames maps -log-level values to levels
+var logLevelNames = map[string]LogLevel{
+	"error": LogError,
+	"warn":  LogWarn,
+	"info":  LogInfo,
+	"debug": LogDebug,
+}
+
+// logLevel is the console level of the current process, see SetLogLevel
+var logLevel = LogInfo
+
+// logFile receives everything at debug level when -log-file is set
+var logFile io.Writer
+
+// ParseLogLevel parses a -log-level value
+func ParseLogLevel(s string) (LogLevel, error) {
+	level, ok := logLevelNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return LogInfo, fmt.Errorf("unknown log level %q (expected error, warn, info or debug)", s)
+	}
+	return level, nil
+}
+
+// SetLogLevel applies level to the console: below LogWarn the standard
+// logger drops "Warning: ..." lines, and below LogInfo status lines printed
+// with Infof are dropped. Errors and fatal messages always get through. The
+// log file, if open, gets every line regardless.
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+	log.SetOutput(levelWriter{os.Stderr})
+}
+
+// OpenLogFile starts copying all output, down to debug level, to path
+func OpenLogFile(path string) (io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	logFile = file
+	return file, nil
+}
+
+// levelWriter filters the standard logger's output by logLevel
+type levelWriter struct {
+	out *os.File
+}
+
+func (w levelWriter) Write(p []byte) (int, error) {
+	if logFile != nil {
+		logFile.Write(p)
+	}
+	if logLevel < LogWarn && bytes.Contains(p, []byte(" Warning: ")) {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// Infof prints a status line unless the console level is below LogInfo
+func Infof(format string, args ...any) {
+	printAt(LogInfo, format, args...)
+}
+
+// Debugf prints per-entry detail at LogDebug. Callers check config.Verbose
+// first, which is also set when only the log file wants the detail.
+func Debugf(format string, args ...any) {
+	printAt(LogDebug, format, args...)
+}
+
+// printAt prints to the console when its level allows and to the log file
+func printAt(level LogLevel, format string, args ...any) {
+	if logFile != nil {
+		fmt.Fprintf(logFile, format, args...)
+	}
+	if logLevel >= level {
+		fmt.Printf(format, args...)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelFiltersWarnings(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { logLevel = LogInfo }()
+
+	logger := log.New(levelWriter{w}, "", log.LstdFlags)
+	for _, level := range []string{"error", "warn"} {
+		parsed, err := ParseLogLevel(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logLevel = parsed
+		logger.Printf("Warning: shown at %s", level)
+		logger.Printf("Error processing show %d at %s", 1, level)
+	}
+	w.Close()
+	var out bytes.Buffer
+	out.ReadFrom(r)
+
+	got := out.String()
+	if strings.Contains(got, "shown at error") || !strings.Contains(got, "shown at warn") {
+		t.Errorf("warnings not filtered by level:\n%s", got)
+	}
+	if strings.Count(got, "Error processing show") != 2 {
+		t.Errorf("errors were dropped:\n%s", got)
+	}
+	if _, err := ParseLogLevel("loud"); err == nil {
+		t.Error("ParseLogLevel accepted an unknown level")
+	}
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Manifest describes the published dataset files and their checksums
+type Manifest struct {
+	GeneratedAt string              `json:"generated_at"`
+	Files       []ManifestFileEntry `json:"files"`
+}
+
+// ManifestFileEntry describes a single dataset file
+type ManifestFileEntry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Entries int    `json:"entries"`
+}
+
+// DatasetFiles returns the dataset files that currently exist on disk
+func DatasetFiles() []string {
+	tv, movies := defaultOutput("tv"), defaultOutput("movies")
+	candidates := []string{tv, movies, KeyedPath(tv), KeyedPath(movies), notFoundPath(tv), notFoundPath(movies)}
+
+	var files []string
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// BuildManifest hashes the given files and returns a manifest describing them
+func BuildManifest(paths []string) (*Manifest, error) {
+	manifest := &Manifest{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:       []ManifestFileEntry{},
+	}
+
+	for _, path := range paths {
+		entry, err := describeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, *entry)
+	}
+	return manifest, nil
+}
+
+// WriteManifest builds a manifest for all dataset files and saves it
+func WriteManifest() {
+	manifest, err := BuildManifest(DatasetFiles())
+	if err != nil {
+		fmt.Printf("Warning: could not build manifest: %v\n", err)
+		return
+	}
+	if err := SaveJSON(ManifestPath(), manifest); err != nil {
+		fmt.Printf("Warning: could not write manifest: %v\n", err)
+	}
+}
+
+// describeFile computes the size, checksum and entry count of a dataset file
+func describeFile(path string) (*ManifestFileEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	entry := &ManifestFileEntry{
+		Path:   filepath.ToSlash(path),
+		Size:   int64(len(data)),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+
+	// Dataset files are JSON arrays, or objects keyed by MAL ID; count
+	// entries when possible
+	var items []json.RawMessage
+	var keyed map[string]json.RawMessage
+	if json.Unmarshal(data, &items) == nil {
+		entry.Entries = len(items)
+	} else if json.Unmarshal(data, &keyed) == nil {
+		entry.Entries = len(keyed)
+	}
+	return entry, nil
+}
@@ -0,0 +1,87 @@
+package internal
+
+// Middleware subscribes to the events of a run. Events are delivered on the
+// goroutine that saves the results, in input order, so a middleware needs no
+// locking of its own. Embed BaseMiddleware to subscribe to only some events.
+type Middleware interface {
+	// OnEntryResolved is called once an entry is final, after enrichers
+	// and overrides and before it is saved; changes to the entry are saved
+	OnEntryResolved(event EntryEvent)
+	// OnEntryWritten is called for each entry resolved in the run once the
+	// sinks have written it
+	OnEntryWritten(event EntryEvent)
+	// OnRunComplete is called once the run of a media type has saved its
+	// results
+	OnRunComplete(event RunEvent)
+}
+
+// EntryEvent is an entry of a run: Show for "tv", Movie for "movies"
+type EntryEvent struct {
+	Config    Config
+	MediaType string // "tv" or "movies"
+	Show      *OutputShow
+	Movie     *OutputMovie
+}
+
+// RunEvent is the run of one media type; Stats.MediaType names it, e.g. "tv"
+// or "movies (fribb)"
+type RunEvent struct {
+	Config     Config
+	OutputFile string
+	Stats      ProcessingStats
+}
+
+// BaseMiddleware ignores every event
+type BaseMiddleware struct{}
+
+func (BaseMiddleware) OnEntryResolved(EntryEvent) {}
+func (BaseMiddleware) OnEntryWritten(EntryEvent)  {}
+func (BaseMiddleware) OnRunComplete(RunEvent)     {}
+
+// middlewares are the subscribers, in the order they get each event
+var middlewares = []Middleware{statsMiddleware{}}
+
+// RegisterMiddleware subscribes m to the events of the runs that follow;
+// call it before the first run, e.g. from an init function
+func RegisterMiddleware(m Middleware) {
+	middlewares = append(middlewares, m)
+}
+
+// statsMiddleware prints the summary of each run and adds it to the run
+// report
+type statsMiddleware struct{ BaseMiddleware }
+
+func (statsMiddleware) OnRunComplete(event RunEvent) {
+	OutputStats(event.Stats.MediaType, event.Stats)
+	event.Config.Report.Add(event.OutputFile, event.Stats)
+}
+
+func entryResolved(event EntryEvent) {
+	for _, m := range middlewares {
+		m.OnEntryResolved(event)
+	}
+}
+
+// entriesWritten sends OnEntryWritten for the entries of batch with the
+// given MAL IDs
+func entriesWritten(batch SinkBatch, malIDs []int) {
+	for _, malID := range malIDs {
+		event := EntryEvent{Config: batch.Config, MediaType: batch.MediaType}
+		if batch.MediaType == "movies" {
+			movie := batch.Movies[malID]
+			event.Movie = &movie
+		} else {
+			show := batch.Shows[malID]
+			event.Show = &show
+		}
+		for _, m := range middlewares {
+			m.OnEntryWritten(event)
+		}
+	}
+}
+
+func runComplete(event RunEvent) {
+	for _, m := range middlewares {
+		m.OnRunComplete(event)
+	}
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"os"
+	"testing"
+)
+
+// recordingMiddleware records the events it gets
+type recordingMiddleware struct {
+	BaseMiddleware
+	events *[]string
+}
+
+func (m recordingMiddleware) OnEntryResolved(event EntryEvent) {
+	event.Show.Status = "ended" // saved, as the entry is not yet written
+	*m.events = append(*m.events, "resolved")
+}
+
+func (m recordingMiddleware) OnEntryWritten(event EntryEvent) {
+	*m.events = append(*m.events, "written "+event.Show.Status)
+}
+
+func (m recordingMiddleware) OnRunComplete(event RunEvent) {
+	*m.events = append(*m.events, "complete "+event.OutputFile)
+}
+
+func registerTestMiddleware(t *testing.T) *[]string {
+	t.Helper()
+	var events []string
+	saved := middlewares
+	t.Cleanup(func() { middlewares = saved })
+	RegisterMiddleware(recordingMiddleware{events: &events})
+	return &events
+}
+
+func TestMiddlewareEvents(t *testing.T) {
+	events := registerTestMiddleware(t)
+	show := OutputShow{}
+	show.MyAnimeList.ID = 1
+	entryResolved(EntryEvent{MediaType: "tv", Show: &show})
+	entriesWritten(SinkBatch{MediaType: "tv", Shows: map[int]OutputShow{1: show}}, []int{1})
+
+	want := []string{"resolved", "written ended"}
+	if len(*events) != len(want) || (*events)[0] != want[0] || (*events)[1] != want[1] {
+		t.Errorf("events %v, want %v", *events, want)
+	}
+}
+
+func TestProcessShowsCompletesRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	events := registerTestMiddleware(t)
+	os.WriteFile("tv.json", []byte(`[]`), 0644)
+
+	if err := ProcessShows(Config{TvFiles: []string{"tv.json"}, OutputFile: "tv_ex.json", NoProgress: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*events) != 1 || (*events)[0] != "complete tv_ex.json" {
+		t.Errorf("events %v, want one completed run", *events)
+	}
+}
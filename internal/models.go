@@ -2,8 +2,10 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // InputShow structure for input shows
@@ -14,6 +16,7 @@ type InputShow struct {
 	GuessedSlug string `json:"guessed_slug"`
 	Season      int    `json:"season"`
 	Type        string `json:"type"`
+	RawTitle    string `json:"-"` // title before CleanTitle, "" when unchanged
 }
 
 // InputMovie structure for input movies
@@ -23,6 +26,7 @@ type InputMovie struct {
 	TraktID     int    `json:"trakt_id"`
 	GuessedSlug string `json:"guessed_slug"`
 	Type        string `json:"type"`
+	RawTitle    string `json:"-"` // title before CleanTitle, "" when unchanged
 }
 
 // NotFoundEntry structure for items not found on Trakt
@@ -40,9 +44,10 @@ type LetterboxdResponse struct {
 
 // Letterboxd structure for output
 type Letterboxd struct {
-	Slug *string `json:"slug"`
-	UID  *int    `json:"uid"`
-	LID  *string `json:"lid"`
+	Slug        *string `json:"slug"`
+	UID         *int    `json:"uid"`
+	LID         *string `json:"lid"`
+	ResolvedVia string  `json:"resolved_via,omitempty"` // "tmdb" or "imdb", the redirect that produced the slug
 }
 
 // Trakt API structures
@@ -62,7 +67,17 @@ type TraktShow struct {
 		IMDB  *string `json:"imdb,omitempty"`
 		TMDB  *int    `json:"tmdb,omitempty"`
 	} `json:"ids"`
-	Year int `json:"year"`
+	Year          int          `json:"year"`
+	Runtime       int          `json:"runtime,omitempty"`       // minutes (per episode for shows), only with ?extended=full
+	Genres        []string     `json:"genres,omitempty"`        // only with ?extended=full
+	Certification string       `json:"certification,omitempty"` // only with ?extended=full
+	Country       string       `json:"country,omitempty"`       // only with ?extended=full
+	Language      string       `json:"language,omitempty"`      // only with ?extended=full
+	Network       string       `json:"network,omitempty"`       // only with ?extended=full
+	Status        string       `json:"status,omitempty"`        // e.g. "returning series" or "ended", only with ?extended=full
+	UpdatedAt     string       `json:"updated_at,omitempty"`    // only with ?extended=full
+	Images        *TraktImages `json:"images,omitempty"`        // only with ?extended=images
+	Warmed        bool         `json:"warmed,omitempty"`        // written by `cache warm` from the output, not by Trakt
 }
 
 type TraktMovie struct {
@@ -73,7 +88,21 @@ type TraktMovie struct {
 		IMDB  *string `json:"imdb,omitempty"`
 		TMDB  *int    `json:"tmdb,omitempty"`
 	} `json:"ids"`
-	Year int `json:"year"`
+	Year          int          `json:"year"`
+	Runtime       int          `json:"runtime,omitempty"`       // minutes, only with ?extended=full
+	Genres        []string     `json:"genres,omitempty"`        // only with ?extended=full
+	Certification string       `json:"certification,omitempty"` // only with ?extended=full
+	Country       string       `json:"country,omitempty"`       // only with ?extended=full
+	Language      string       `json:"language,omitempty"`      // only with ?extended=full
+	UpdatedAt     string       `json:"updated_at,omitempty"`    // only with ?extended=full
+	Images        *TraktImages `json:"images,omitempty"`        // only with ?extended=images
+	Warmed        bool         `json:"warmed,omitempty"`        // written by `cache warm` from the output, not by Trakt
+}
+
+// TraktImages lists artwork URLs (without scheme), best first
+type TraktImages struct {
+	Poster []string `json:"poster"`
+	Fanart []string `json:"fanart"`
 }
 
 type TraktSeason struct {
@@ -84,6 +113,7 @@ type TraktSeason struct {
 		TMDB   *int `json:"tmdb,omitempty"`
 		TVRage *int `json:"tvrage,omitempty"`
 	} `json:"ids"`
+	EpisodeCount int `json:"episode_count,omitempty"` // only with ?extended=full
 }
 
 type TraktExternalsShow struct {
@@ -108,57 +138,183 @@ type TraktExternalsMovie struct {
 // OutputShow structure
 type OutputShow struct {
 	MyAnimeList struct {
-		Title string `json:"title"`
-		ID    int    `json:"id"`
+		Title     string     `json:"title"`
+		RawTitle  string     `json:"raw_title,omitempty"`  // before HTML unescaping and NFC
+		Titles    *MALTitles `json:"titles,omitempty"`     // jikan enrichment
+		Episodes  int        `json:"episodes,omitempty"`   // jikan enrichment
+		StartDate string     `json:"start_date,omitempty"` // jikan enrichment, YYYY-MM-DD
+		ID        int        `json:"id"`
+		URL       string     `json:"url"`
 	} `json:"myanimelist"`
 	Trakt struct {
 		Title  string `json:"title"`
 		ID     int    `json:"id"`
 		Slug   string `json:"slug"`
+		URL    string `json:"url"`
 		Type   string `json:"type"`
 		Season *struct {
-			ID        int                   `json:"id"`
-			Number    int                   `json:"number"`
-			Externals *TraktExternalsSeason `json:"externals"`
+			ID            int                   `json:"id"`
+			Number        int                   `json:"number"`
+			EpisodeCount  int                   `json:"episode_count,omitempty"`
+			EpisodeOffset int                   `json:"episode_offset,omitempty"` // Trakt episode = MAL episode + offset, for a later cour
+			Externals     *TraktExternalsSeason `json:"externals"`
 		} `json:"season"`
 		IsSplitCour bool `json:"is_split_cour"`
 	} `json:"trakt"`
-	ReleaseYear int                 `json:"release_year"`
-	Externals   *TraktExternalsShow `json:"externals"`
+	Quality        string              `json:"quality,omitempty"`         // mapping quality tier
+	Parts          []TraktPart         `json:"parts,omitempty"`           // override with several targets
+	AbsoluteOffset *int                `json:"absolute_offset,omitempty"` // override: Trakt absolute episode = MAL episode + offset
+	ReleaseYear    int                 `json:"release_year"`
+	Runtime        int                 `json:"runtime,omitempty"`       // minutes per episode
+	Status         string              `json:"status,omitempty"`        // Trakt airing status
+	Genres         []string            `json:"genres,omitempty"`        // -genres
+	Certification  string              `json:"certification,omitempty"` // -genres
+	Country        string              `json:"country,omitempty"`       // ISO 3166-1 alpha-2
+	Language       string              `json:"language,omitempty"`      // ISO 639-1
+	Network        string              `json:"network,omitempty"`
+	Popularity     *Popularity         `json:"popularity,omitempty"` // -popularity
+	Externals      *TraktExternalsShow `json:"externals"`
+	// ExternalSources names where backfilled external IDs came from, e.g.
+	// {"tvdb": "wikidata"}, season IDs as season_tvdb and season_tmdb; IDs
+	// from Trakt are not listed
+	ExternalSources map[string]string `json:"external_sources,omitempty"`
+	ExtraIDs        map[string]string `json:"extra_ids,omitempty"` // IDs from -hook beyond the externals
+	TMDB            *TMDBInfo         `json:"tmdb,omitempty"`      // tmdb enrichment
+	SIMKL           *SIMKLInfo        `json:"simkl,omitempty"`     // simkl enrichment
+	Images          *Images           `json:"images,omitempty"`    // -images
 }
 
 // OutputMovie structure
 type OutputMovie struct {
 	MyAnimeList struct {
-		Title string `json:"title"`
-		ID    int    `json:"id"`
+		Title    string     `json:"title"`
+		RawTitle string     `json:"raw_title,omitempty"` // before HTML unescaping and NFC
+		Titles   *MALTitles `json:"titles,omitempty"`    // jikan enrichment
+		ID       int        `json:"id"`
+		URL      string     `json:"url"`
 	} `json:"myanimelist"`
 	Trakt struct {
 		Title string `json:"title"`
 		ID    int    `json:"id"`
 		Slug  string `json:"slug"`
+		URL   string `json:"url"`
 		Type  string `json:"type"`
 	} `json:"trakt"`
-	ReleaseYear int                  `json:"release_year"`
-	Externals   *TraktExternalsMovie `json:"externals"`
+	Quality       string               `json:"quality,omitempty"` // mapping quality tier
+	Parts         []TraktPart          `json:"parts,omitempty"`   // override with several targets
+	ReleaseYear   int                  `json:"release_year"`
+	Runtime       int                  `json:"runtime,omitempty"`       // minutes
+	Genres        []string             `json:"genres,omitempty"`        // -genres
+	Certification string               `json:"certification,omitempty"` // -genres
+	Country       string               `json:"country,omitempty"`       // ISO 3166-1 alpha-2
+	Language      string               `json:"language,omitempty"`      // ISO 639-1
+	Popularity    *Popularity          `json:"popularity,omitempty"`    // -popularity
+	Externals     *TraktExternalsMovie `json:"externals"`
+	// ExternalSources names where backfilled external IDs came from, e.g.
+	// {"tmdb": "wikidata"}; IDs from Trakt are not listed
+	ExternalSources map[string]string `json:"external_sources,omitempty"`
+	ExtraIDs        map[string]string `json:"extra_ids,omitempty"` // IDs from -hook beyond the externals
+	TMDB            *TMDBInfo         `json:"tmdb,omitempty"`      // tmdb enrichment
+	SIMKL           *SIMKLInfo        `json:"simkl,omitempty"`     // simkl enrichment
+	Images          *Images           `json:"images,omitempty"`    // -images
+}
+
+// setURLs fills the url convenience fields from the MAL ID and Trakt slug
+func (s *OutputShow) setURLs() {
+	s.MyAnimeList.URL = fmt.Sprintf("https://myanimelist.net/anime/%d", s.MyAnimeList.ID)
+	s.Trakt.URL = "https://trakt.tv/shows/" + s.Trakt.Slug
+	if s.Trakt.Season != nil {
+		s.Trakt.URL += fmt.Sprintf("/seasons/%d", s.Trakt.Season.Number)
+	}
+}
+
+// setURLs fills the url convenience fields from the MAL ID and Trakt slug
+func (m *OutputMovie) setURLs() {
+	m.MyAnimeList.URL = fmt.Sprintf("https://myanimelist.net/anime/%d", m.MyAnimeList.ID)
+	m.Trakt.URL = "https://trakt.tv/movies/" + m.Trakt.Slug
 }
 
 // Config structure
 type Config struct {
 	APIKey                string
-	TvFile                string
-	MovieFile             string
+	APIKeyFile            string   // file or exec:<command> holding the API key
+	TraktAPIURL           string   // Trakt API root, "" for DefaultTraktAPIURL
+	Keychain              bool     // read (and save a prompted) API key in the OS keychain
+	TvFiles               []string // -tv inputs, globs expanded
+	MovieFiles            []string // -movies inputs, globs expanded
 	OutputFile            string
-	Verbose               bool
+	OutputTemplate        string   // -output-template, naming the output of each media type
+	Verbose               bool     // produce per-entry detail (-verbose, -log-level debug or -log-file)
+	LogLevel              LogLevel // console level (-log-level / -quiet)
+	LogFile               string   // file receiving all output at debug level
+	Color                 string   // -color: auto, always or never
 	NoProgress            bool
 	TempDir               string
 	Force                 bool
+	Journal               bool        // write per-run journals instead of rewriting outputs
+	ChangelogDir          string      // where changes-YYYYMMDD.json files go ("" disables)
+	Snapshots             int         // output versions kept in json/snapshots (0 disables)
+	Sort                  SortOrder   // entry order of the output files and push payloads
+	KeyedOutput           bool        // also write each output as an object keyed by MAL ID
+	CacheEntries          int         // size of the in-memory cache tier (0 disables it)
+	Cache                 *Cache      // in-memory tier in front of the TempDir cache
+	CacheLimits           CacheLimits // bounds on the disk tier, enforced after each run
 	RateLimiter           *RateLimiter
 	LetterboxdRateLimiter *RateLimiter
+	TMDBAPIKey            string // enables the tmdb enrichment (env TMDB_API_KEY)
+	TMDBRateLimiter       *RateLimiter
+	WikidataRateLimiter   *RateLimiter
+	JikanRateLimiter      *RateLimiter
+	SIMKLClientID         string // enables the simkl enrichment (env SIMKL_CLIENT_ID)
+	SIMKLRateLimiter      *RateLimiter
+	TVDB                  *TVDBClient     // enables the tvdb check (env TVDB_API_KEY), nil without a key
+	LetterboxdWorkers     int             // workers of the Letterboxd enrichment phase
+	LetterboxdRate        int             // Letterboxd requests per minute
+	HTTP                  HTTPSettings    // transport shared by every API client
+	PprofAddr             string          // serve net/http/pprof here, "" disables
+	NoPreflight           bool            // skip the API key check before the first run
+	LetterboxdDelay       time.Duration   // pause around each Letterboxd film lookup
+	LetterboxdUserAgent   string          // User-Agent sent to Letterboxd
+	LetterboxdURL         string          // Letterboxd root, "" for DefaultLetterboxdURL
+	LetterboxdTTL         time.Duration   // age after which cached Letterboxd IDs are re-checked (0 = never)
+	LetterboxdNegativeTTL time.Duration   // age after which cached Letterboxd misses are re-checked (0 = never)
+	Enrichments           map[string]bool // enrichments selected with -enrich / -no-enrich
+	EnrichMissing         bool            // enrich existing entries that lack enrichment data
+	Hooks                 []string        // -hook commands run on each entry
+	Images                bool            // add poster/fanart URLs to the output
+	Genres                bool            // add Trakt genres and certification to the output
+	Popularity            bool            // add a Trakt watchers/plays/votes snapshot to the output
+	RefreshAiring         bool            // re-fetch existing shows that have not ended
+	Filter                EntryFilter     // input entries to process (-only-*, -skip-mal-ids)
+	Budget                *Budget         // work limit of the run (-max-new, -max-api-calls)
+	Guardrails            Guardrails      // change volume limit of each output write
+	Thresholds            Thresholds      // data-quality limits that fail a run
+	Progress              *Progress       // progress bar of the running pipeline, nil without one
+	ReviewQueue           string          // review_queue.json of not-found candidates, "" disables
+	ErrorsFile            string          // per-entry error report of the run, "" disables
+	ResourcesFile         string          // resource summary export of the run, "" disables
+	ReportHTML            string          // standalone HTML report of the run, "" disables
+	HistoryFile           string          // per-run statistics history, "" disables
+	Errors                *ErrorLog       // per-entry errors of the running pipeline, nil without -errors-file
+	Report                *RunReport      // statistics of the running pipeline, nil without -report-html or -history-file
+	RecheckNotFound       bool            // fetch not-found entries again instead of skipping them
+	RetryFailed           bool            // process only the entries in the retry queue
+	Daemon                bool            // keep running, one run every Interval
+	Interval              time.Duration   // time between daemon runs
+	ChangesAddr           string          // serve the -daemon change feed here, "" disables
+	WebhookURL            string          // notified after runs that change the dataset, "" disables
+	RunID                 string          // ID of the running run, recorded in the changelogs
 	// Fribb-based ingestion
 	FribbFile    string // path to anime-lists-reduced.json (empty = fetch from GitHub)
 	AnimeAPIFile string // path to animeapi.tsv (empty = fetch from animeapi.my.id)
 	UseFribb     bool   // true when -fribb or -animeapi was explicitly passed
+	// Output sinks
+	Sinks []Sink // written after the JSON output file, see writeSinks
+	// HTTP push sink
+	PushURL     string   // endpoint receiving the results after each run
+	PushMethod  string   // "POST" or "PUT"
+	PushMode    string   // "full" or "delta"
+	PushHeaders []string // extra "Name: value" headers, $VARS are expanded
 }
 
 // ChangeDetail structure for tracking changes
@@ -177,12 +333,23 @@ type ProcessingStats struct {
 	Updated                   int            `json:"updated"`
 	Modified                  int            `json:"modified"`
 	NotFound                  int            `json:"not_found"`
+	Healed                    int            `json:"healed"`
+	Redirected                int            `json:"redirected"`
+	Failed                    int            `json:"failed"`   // errors other than 404; see ExitPartial
+	Warnings                  int            `json:"warnings"` // data-quality warnings on written entries
 	CreatedDetails            []ChangeDetail `json:"created_details"`
 	UpdatedDetails            []ChangeDetail `json:"updated_details"`
 	ModifiedDetails           []ChangeDetail `json:"modified_details"`
 	NotFoundDetails           []ChangeDetail `json:"not_found_details"`
+	HealedDetails             []ChangeDetail `json:"healed_details"`
+	RedirectDetails           []ChangeDetail `json:"redirect_details"` // Trakt merges and slug changes
 	DuplicateDetails          []ChangeDetail `json:"duplicate_details"`
 	LetterboxdNotFoundDetails []ChangeDetail `json:"letterboxd_not_found_details"`
+	ValidationDetails         []ChangeDetail `json:"validation_details"`
+	CollisionDetails          []ChangeDetail `json:"collision_details"` // external IDs shared across Trakt IDs
+	WarningDetails            []EntryWarning `json:"warning_details"`
+	StoppedEarly              string         `json:"stopped_early,omitempty"` // budget that ended the run
+	Requests                  map[string]int `json:"requests,omitempty"`      // outbound requests of the run so far, by host
 }
 
 // Override structure
@@ -192,6 +359,23 @@ type Override struct {
 	Trakt       *json.RawMessage `json:"trakt,omitempty"`
 	Externals   *json.RawMessage `json:"externals,omitempty"`
 	Ignore      bool             `json:"ignore,omitempty"`
+	// Parts maps a MAL entry covering several Trakt items (a compilation, a
+	// film released in two parts) to all of them, in watch order
+	Parts []TraktPart `json:"parts,omitempty"`
+	// AbsoluteOffset maps a MAL entry, such as one arc of One Piece, to a
+	// show Trakt numbers absolutely: its episode N is Trakt's absolute
+	// episode N + AbsoluteOffset, whatever the season. Shows only.
+	AbsoluteOffset *int `json:"absolute_offset,omitempty"`
+}
+
+// TraktPart is one of several Trakt items a MAL entry covers
+type TraktPart struct {
+	Order  int    `json:"order"` // 1-based position in watch order
+	Title  string `json:"title,omitempty"`
+	ID     int    `json:"id"`
+	Slug   string `json:"slug"`
+	URL    string `json:"url"`
+	Season *int   `json:"season,omitempty"` // shows only; the whole show when absent
 }
 
 // ---------------------------------------------------------------------------
@@ -354,6 +538,8 @@ func (f *FribbEntry) FirstIMDb() string {
 type AnimeAPIRow struct {
 	Title        string
 	AniDB        int
+	AniList      int
+	Kitsu        int
 	MyAnimeList  int
 	TraktID      int
 	TraktType    string // "shows" or "movies"
@@ -367,6 +553,8 @@ type AnimeAPIRow struct {
 type animeAPIColumns struct {
 	title              int
 	anidb              int
+	anilist            int
+	kitsu              int
 	myanimelist        int
 	themoviedb         int
 	themoviedbType     int
@@ -381,6 +569,8 @@ func parseAnimeAPIColumns(headers []string) animeAPIColumns {
 	cols := animeAPIColumns{
 		title:              -1,
 		anidb:              -1,
+		anilist:            -1,
+		kitsu:              -1,
 		myanimelist:        -1,
 		themoviedb:         -1,
 		themoviedbType:     -1,
@@ -395,6 +585,10 @@ func parseAnimeAPIColumns(headers []string) animeAPIColumns {
 			cols.title = i
 		case "anidb":
 			cols.anidb = i
+		case "anilist":
+			cols.anilist = i
+		case "kitsu":
+			cols.kitsu = i
 		case "myanimelist":
 			cols.myanimelist = i
 		case "themoviedb":
@@ -2,14 +2,15 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"slices"
 )
 
 // LoadOverrides loads override entries from file
 func LoadOverrides(mediaType string) map[int]*Override {
-	overridesFile := filepath.Join("json/overrides", mediaType+"_overrides.json")
 	var overrides []Override
-	LoadJSONOptional(overridesFile, &overrides)
+	LoadJSONOptional(overridesPath(mediaType), &overrides)
 
 	overridesMap := make(map[int]*Override)
 	for i := range overrides {
@@ -33,6 +34,7 @@ func ApplyShowOverride(show *OutputShow, override *Override) {
 			}
 			if traktOverride.ID != nil {
 				show.Trakt.ID = *traktOverride.ID
+				show.Quality = QualityOverride
 			}
 			if traktOverride.Slug != nil {
 				show.Trakt.Slug = *traktOverride.Slug
@@ -43,9 +45,26 @@ func ApplyShowOverride(show *OutputShow, override *Override) {
 		}
 	}
 
+	if len(override.Parts) > 0 {
+		show.Parts = orderedParts(override.Parts, "shows")
+		show.Quality = QualityOverride
+	}
+
+	// Absolute numbering spans the seasons, so the entry has none
+	if override.AbsoluteOffset != nil {
+		offset := *override.AbsoluteOffset
+		show.AbsoluteOffset = &offset
+		show.Trakt.Season = nil
+		show.Trakt.IsSplitCour = false
+		show.Quality = QualityOverride
+	}
+
 	if override.Externals != nil {
 		var extOverride TraktExternalsShow
 		if err := json.Unmarshal(*override.Externals, &extOverride); err == nil {
+			if show.Externals == nil {
+				show.Externals = &TraktExternalsShow{}
+			}
 			if extOverride.TVDB != nil {
 				show.Externals.TVDB = extOverride.TVDB
 			}
@@ -77,6 +96,7 @@ func ApplyMovieOverride(movie *OutputMovie, override *Override) {
 			}
 			if traktOverride.ID != nil {
 				movie.Trakt.ID = *traktOverride.ID
+				movie.Quality = QualityOverride
 			}
 			if traktOverride.Slug != nil {
 				movie.Trakt.Slug = *traktOverride.Slug
@@ -87,9 +107,17 @@ func ApplyMovieOverride(movie *OutputMovie, override *Override) {
 		}
 	}
 
+	if len(override.Parts) > 0 {
+		movie.Parts = orderedParts(override.Parts, "movies")
+		movie.Quality = QualityOverride
+	}
+
 	if override.Externals != nil {
 		var extOverride TraktExternalsMovie
 		if err := json.Unmarshal(*override.Externals, &extOverride); err == nil {
+			if movie.Externals == nil {
+				movie.Externals = &TraktExternalsMovie{}
+			}
 			if extOverride.TMDB != nil {
 				movie.Externals.TMDB = extOverride.TMDB
 			}
@@ -102,3 +130,69 @@ func ApplyMovieOverride(movie *OutputMovie, override *Override) {
 		}
 	}
 }
+
+// orderedParts copies override parts in watch order, numbering them and
+// filling their URLs. kind is the Trakt URL segment, "shows" or "movies".
+// Parts listed without an order come after those with one, in their
+// position in the override.
+func orderedParts(parts []TraktPart, kind string) []TraktPart {
+	ordered := slices.Clone(parts)
+	last := 0
+	for _, part := range ordered {
+		last = max(last, part.Order)
+	}
+	for i := range ordered {
+		if ordered[i].Order == 0 {
+			last++
+			ordered[i].Order = last
+		}
+	}
+	slices.SortStableFunc(ordered, func(a, b TraktPart) int { return a.Order - b.Order })
+	for i := range ordered {
+		ordered[i].Order = i + 1
+		ordered[i].URL = fmt.Sprintf("https://trakt.tv/%s/%s", kind, ordered[i].Slug)
+		if kind == "shows" && ordered[i].Season != nil {
+			ordered[i].URL += fmt.Sprintf("/seasons/%d", *ordered[i].Season)
+		}
+	}
+	return ordered
+}
+
+// traktID returns the Trakt ID an override maps its entry to, 0 when it does
+// not set one. Entries are fetched under that ID, so a mapping also fixes
+// entries whose input Trakt ID is not found. Without a trakt id, the first
+// part in watch order is the entry's main Trakt item.
+func (o *Override) traktID() int {
+	if o == nil {
+		return 0
+	}
+	if o.Trakt != nil {
+		var trakt struct {
+			ID int `json:"id"`
+		}
+		json.Unmarshal(*o.Trakt, &trakt)
+		if trakt.ID != 0 {
+			return trakt.ID
+		}
+	}
+	if len(o.Parts) > 0 {
+		return orderedParts(o.Parts, "")[0].ID
+	}
+	return 0
+}
+
+// overridesPath is the override file of a media type ("tv" or "movies")
+func overridesPath(mediaType string) string {
+	return filepath.Join(layout.OverridesDir(), mediaType+"_overrides.json")
+}
+
+// upsertOverride replaces the override of the same MAL ID, or appends it
+func upsertOverride(overrides []Override, override Override) []Override {
+	for i := range overrides {
+		if overrides[i].MalID == override.MalID {
+			overrides[i] = override
+			return overrides
+		}
+	}
+	return append(overrides, override)
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyMovieOverrideParts(t *testing.T) {
+	var override Override
+	json.Unmarshal([]byte(`{
+		"mal_id": 1,
+		"description": "Two-part film",
+		"parts": [
+			{"order": 2, "id": 20, "slug": "part-2"},
+			{"order": 1, "id": 10, "slug": "part-1"}
+		]
+	}`), &override)
+
+	if id := override.traktID(); id != 10 {
+		t.Errorf("traktID() = %d, want the first part's 10", id)
+	}
+
+	var movie OutputMovie
+	ApplyMovieOverride(&movie, &override)
+	if len(movie.Parts) != 2 || movie.Parts[0].ID != 10 || movie.Parts[1].ID != 20 {
+		t.Fatalf("Parts = %+v, want part-1 then part-2", movie.Parts)
+	}
+	if movie.Parts[1].URL != "https://trakt.tv/movies/part-2" {
+		t.Errorf("Parts[1].URL = %q", movie.Parts[1].URL)
+	}
+	if movie.Quality != QualityOverride {
+		t.Errorf("Quality = %q, want %q", movie.Quality, QualityOverride)
+	}
+}
+
+func TestOrderedPartsShowSeasons(t *testing.T) {
+	season := 2
+	parts := orderedParts([]TraktPart{{ID: 1, Slug: "show"}, {ID: 1, Slug: "show", Season: &season}}, "shows")
+	if parts[0].Order != 1 || parts[0].URL != "https://trakt.tv/shows/show" {
+		t.Errorf("parts[0] = %+v", parts[0])
+	}
+	if parts[1].Order != 2 || parts[1].URL != "https://trakt.tv/shows/show/seasons/2" {
+		t.Errorf("parts[1] = %+v", parts[1])
+	}
+}
+
+func TestOrderedPartsMixedOrder(t *testing.T) {
+	parts := orderedParts([]TraktPart{{Order: 2, Slug: "b"}, {Slug: "c"}, {Order: 1, Slug: "a"}, {Slug: "d"}}, "movies")
+	var slugs []string
+	for i, part := range parts {
+		if part.Order != i+1 {
+			t.Errorf("parts[%d].Order = %d, want %d", i, part.Order, i+1)
+		}
+		slugs = append(slugs, part.Slug)
+	}
+	if got := strings.Join(slugs, ","); got != "a,b,c,d" {
+		t.Errorf("order = %s, want a,b,c,d", got)
+	}
+}
+
+func TestApplyShowOverrideAbsoluteOffset(t *testing.T) {
+	var override Override
+	json.Unmarshal([]byte(`{"mal_id": 21, "description": "One Piece arc, absolute numbering", "absolute_offset": 61}`), &override)
+
+	var show OutputShow
+	json.Unmarshal([]byte(`{"trakt": {"slug": "one-piece", "season": {"number": 3, "episode_count": 16}}, "quality": "exact-id"}`), &show)
+	ApplyShowOverride(&show, &override)
+	if show.AbsoluteOffset == nil || *show.AbsoluteOffset != 61 {
+		t.Fatalf("AbsoluteOffset = %v, want 61", show.AbsoluteOffset)
+	}
+	if show.Trakt.Season != nil || show.Trakt.IsSplitCour || show.Quality != QualityOverride {
+		t.Errorf("season = %+v, split cour %v, quality %q", show.Trakt.Season, show.Trakt.IsSplitCour, show.Quality)
+	}
+	show.setURLs()
+	if show.Trakt.URL != "https://trakt.tv/shows/one-piece" {
+		t.Errorf("URL = %q, want the show's", show.Trakt.URL)
+	}
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ProcessShows and ProcessMovies run as a pipeline of stages connected by
+// channels:
+//
+//	load → resolve → enrich → validate → write
+//
+// load reads the inputs, the previous output, the not-found list, the
+// overrides and the retry queue. resolve picks the entries to fetch and
+// fetches them from Trakt. enrich runs the enrichers. validate records what
+// changed against the previous output and applies overrides. write saves
+// the results and reports the run.
+//
+// resolve and enrich run on goroutines of their own, so an entry is fetched
+// from Trakt while the one before it is being enriched. Each stage owns the
+// state it writes. enrich keeps its findings on the item, and validate
+// merges them in input order, so the summary reads as if the stages had run
+// one entry at a time.
+
+// stage runs fn on each item from in, in order, on a goroutine of its own,
+// and passes the items on
+func stage[T any](in <-chan T, fn func(T)) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for item := range in {
+			fn(item)
+			out <- item
+		}
+	}()
+	return out
+}
+
+// resolveState is the part of a run the resolve stage owns. The other
+// stages read it once resolve has closed its channel.
+type resolveState struct {
+	retries     *retryQueue
+	notExist    map[int]bool    // not-found list of the previous runs
+	newNotExist []NotFoundEntry // entries Trakt answered 404 for
+	healed      map[int]bool    // not-found entries found on a recheck
+	notFound    []ChangeDetail  // newly not found
+	failed      int
+	rateLimited bool
+	stopped     string // why resolve stopped before the end of the input
+}
+
+func newResolveState(outputFile string) resolveState {
+	return resolveState{
+		retries:  loadRetryQueue(outputFile),
+		notExist: LoadNotFound(outputFile),
+		healed:   make(map[int]bool),
+		notFound: []ChangeDetail{},
+	}
+}
+
+// fetchFailed records an entry Trakt did not return: a 404 goes to the
+// not-found list, other errors to the retry queue. It reports whether the
+// run must stop, as it must when Trakt keeps rate limiting.
+func (s *resolveState) fetchFailed(config Config, kind string, key [3]int, malID int, title string, err error) (stop bool) {
+	switch {
+	case strings.Contains(err.Error(), "404"):
+		s.retries.Done(key)
+		s.newNotExist = append(s.newNotExist, NotFoundEntry{MalID: malID, Title: title})
+		if !s.notExist[malID] {
+			s.notFound = append(s.notFound, ChangeDetail{
+				MalID:  malID,
+				Title:  title,
+				Reason: "Not found on Trakt.tv",
+			})
+		}
+	case errors.Is(err, ErrRateLimited):
+		config.Errors.Record("trakt", malID, title, err)
+		s.retries.Fail(key, title, err)
+		s.stopped = fmt.Sprintf("Trakt kept rate limiting after retries (%v)", err)
+		s.rateLimited = true
+		return true
+	default:
+		log.Printf("Error processing %s %d: %v", kind, malID, err)
+		config.Errors.Record("trakt", malID, title, err)
+		s.retries.Fail(key, title, err)
+		s.failed++
+	}
+	return false
+}
+
+// mergeFindings appends the details of src, the findings of one entry, to
+// stats
+func mergeFindings(stats *ProcessingStats, src *ProcessingStats) {
+	stats.CreatedDetails = append(stats.CreatedDetails, src.CreatedDetails...)
+	stats.UpdatedDetails = append(stats.UpdatedDetails, src.UpdatedDetails...)
+	stats.ModifiedDetails = append(stats.ModifiedDetails, src.ModifiedDetails...)
+	stats.NotFoundDetails = append(stats.NotFoundDetails, src.NotFoundDetails...)
+	stats.HealedDetails = append(stats.HealedDetails, src.HealedDetails...)
+	stats.RedirectDetails = append(stats.RedirectDetails, src.RedirectDetails...)
+	stats.DuplicateDetails = append(stats.DuplicateDetails, src.DuplicateDetails...)
+	stats.LetterboxdNotFoundDetails = append(stats.LetterboxdNotFoundDetails, src.LetterboxdNotFoundDetails...)
+	stats.ValidationDetails = append(stats.ValidationDetails, src.ValidationDetails...)
+}
+
+// changeDetails records how a fetched entry differs from the previous
+// output: a Trakt redirect, new Trakt metadata, or a new entry. current is
+// the entry's result so far this run (the previous output, or an earlier
+// duplicate), previous the entry in the previous output, nil when new.
+func changeDetails(stats *ProcessingStats, malID int, title string, requestedID int, previous, current, fetched *traktRef) {
+	if previous == nil {
+		stats.CreatedDetails = append(stats.CreatedDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: "New entry added",
+		})
+		return
+	}
+	if redirect := traktRedirect(requestedID, previous.id, previous.slug, fetched.id, fetched.slug); redirect != "" {
+		stats.RedirectDetails = append(stats.RedirectDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: redirect,
+		})
+	} else if fetched.id != current.id || fetched.slug != current.slug {
+		stats.UpdatedDetails = append(stats.UpdatedDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: "Trakt metadata updated",
+		})
+	} else if current.status != "" && fetched.status != current.status {
+		stats.UpdatedDetails = append(stats.UpdatedDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: fmt.Sprintf("Status changed from %s to %s", current.status, fetched.status),
+		})
+	}
+}
+
+// traktRef is what changeDetails compares of an entry; status is "" for
+// movies
+type traktRef struct {
+	id     int
+	slug   string
+	status string
+}
+
+// slugFallbackDetail is the External ID Checks line of an entry found by
+// its guessed slug after a 404 for its Trakt ID
+func slugFallbackDetail(malID int, title string, requestedID int, slug string, foundID int) ChangeDetail {
+	return ChangeDetail{
+		MalID:  malID,
+		Title:  title,
+		Reason: fmt.Sprintf("Trakt ID %d not found, fetched by guessed slug %s (Trakt ID %d)", requestedID, slug, foundID),
+	}
+}
+
+// duplicateDetails reports the MAL IDs listed with several Trakt IDs: the
+// one that succeeded (successful) is valid, the others invalid. MAL IDs a
+// stopped run may not have reached are left out.
+func duplicateDetails(traktIDs map[int][]int, successful map[int]int, titles map[int]string, stoppedEarly string) []ChangeDetail {
+	details := []ChangeDetail{}
+	for _, malID := range slices.Sorted(maps.Keys(traktIDs)) {
+		ids := traktIDs[malID]
+		if len(ids) < 2 {
+			continue
+		}
+		successfulID := successful[malID]
+		if successfulID == 0 && stoppedEarly != "" {
+			continue
+		}
+		var invalid []string
+		for _, id := range ids {
+			if id != successfulID {
+				invalid = append(invalid, fmt.Sprintf("%d", id))
+			}
+		}
+		reason := fmt.Sprintf("Duplicate: valid Trakt ID %d, invalid [%s]", successfulID, strings.Join(invalid, ", "))
+		if successfulID == 0 {
+			reason = fmt.Sprintf("Duplicate: no valid Trakt ID, invalid [%s]", strings.Join(invalid, ", "))
+		}
+		details = append(details, ChangeDetail{MalID: malID, Title: titles[malID], Reason: reason})
+	}
+	return details
+}
@@ -0,0 +1,72 @@
+package internal
+
+import "testing"
+
+func TestStageKeepsOrder(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 5; i++ {
+			in <- i
+		}
+	}()
+	var seen, got []int
+	for item := range stage(in, func(i int) { seen = append(seen, i) }) {
+		got = append(got, item)
+	}
+	for i, item := range got {
+		if item != i+1 || seen[i] != i+1 {
+			t.Fatalf("got %v, stage saw %v, want 1..5 in order", got, seen)
+		}
+	}
+	if len(got) != 5 {
+		t.Fatalf("got %d items, want 5", len(got))
+	}
+}
+
+func TestDuplicateDetails(t *testing.T) {
+	traktIDs := map[int][]int{5: {50, 51}, 1: {10, 11, 12}, 3: {30}, 7: {70, 71}}
+	successful := map[int]int{5: 51, 1: 10}
+	titles := map[int]string{1: "One", 5: "Five", 7: "Seven"}
+
+	details := duplicateDetails(traktIDs, successful, titles, "")
+	want := []ChangeDetail{
+		{MalID: 1, Title: "One", Reason: "Duplicate: valid Trakt ID 10, invalid [11, 12]"},
+		{MalID: 5, Title: "Five", Reason: "Duplicate: valid Trakt ID 51, invalid [50]"},
+		{MalID: 7, Title: "Seven", Reason: "Duplicate: no valid Trakt ID, invalid [70, 71]"},
+	}
+	if len(details) != len(want) {
+		t.Fatalf("got %v, want %v", details, want)
+	}
+	for i := range want {
+		if details[i] != want[i] {
+			t.Errorf("detail %d = %v, want %v", i, details[i], want[i])
+		}
+	}
+
+	// A stopped run may not have reached MAL ID 7
+	if details := duplicateDetails(traktIDs, successful, titles, "budget spent"); len(details) != 2 {
+		t.Errorf("stopped run: got %v, want MAL IDs 1 and 5 only", details)
+	}
+}
+
+func TestChangeDetails(t *testing.T) {
+	var stats ProcessingStats
+	changeDetails(&stats, 1, "New", 10, nil, &traktRef{}, &traktRef{id: 10, slug: "new"})
+	if len(stats.CreatedDetails) != 1 {
+		t.Errorf("created = %v, want one new entry", stats.CreatedDetails)
+	}
+
+	stats = ProcessingStats{}
+	ref := &traktRef{id: 10, slug: "show", status: "returning series"}
+	changeDetails(&stats, 2, "Same", 10, ref, ref, &traktRef{id: 10, slug: "show", status: "ended"})
+	if len(stats.UpdatedDetails) != 1 || stats.UpdatedDetails[0].Reason != "Status changed from returning series to ended" {
+		t.Errorf("updated = %v, want a status change", stats.UpdatedDetails)
+	}
+
+	stats = ProcessingStats{}
+	changeDetails(&stats, 3, "Unchanged", 10, ref, ref, ref)
+	if len(stats.CreatedDetails)+len(stats.UpdatedDetails)+len(stats.RedirectDetails) != 0 {
+		t.Errorf("unchanged entry recorded %+v", stats)
+	}
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// Popularity is a snapshot of Trakt's audience numbers, added with -popularity
+type Popularity struct {
+	Watchers   int       `json:"watchers"`
+	Plays      int       `json:"plays"`
+	Votes      int       `json:"votes"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// traktStats is the response of /{shows|movies}/{id}/stats
+type traktStats struct {
+	Watchers int `json:"watchers"`
+	Plays    int `json:"plays"`
+	Votes    int `json:"votes"`
+}
+
+// FetchTraktStats fetches the audience numbers of a show or movie
+// ("shows" or "movies")
+func FetchTraktStats(client *http.Client, config Config, mediaType string, traktID int) (*traktStats, error) {
+	cacheFile := filepath.Join(config.TempDir, "popularity", fmt.Sprintf("%s_%d.json", mediaType, traktID))
+	return singleFetch(cacheFile, func() (*traktStats, error) {
+		if useCache(config, cacheFile) {
+			if stats, ok := cacheLoad[traktStats](config.Cache, cacheFile); ok {
+				return &stats, nil
+			}
+		}
+
+		config.RateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			url := traktURL(config, "/%s/%d/stats", mediaType, traktID)
+			req, err := http.NewRequest("GET", url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("trakt-api-version", "2")
+			req.Header.Set("trakt-api-key", config.APIKey)
+			return client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, statusError(resp, fmt.Errorf("API error: %d", resp.StatusCode))
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		var stats traktStats
+		if err := json.Unmarshal(body, &stats); err != nil {
+			return nil, err
+		}
+		cacheStore(config.Cache, cacheFile, body, stats)
+		return &stats, nil
+	})
+}
+
+// fetchPopularity returns the popularity snapshot of a show or movie, or nil
+// when -popularity is off or the stats cannot be fetched
+func fetchPopularity(client *http.Client, config Config, mediaType string, traktID, malID int, title string) *Popularity {
+	if !config.Popularity {
+		return nil
+	}
+	stats, err := FetchTraktStats(client, config, mediaType, traktID)
+	if err != nil {
+		log.Printf("Warning: Failed to fetch Trakt stats for %s %d: %v", mediaType, traktID, err)
+		config.Errors.Record("trakt", malID, title, err)
+		return nil
+	}
+	return &Popularity{
+		Watchers:   stats.Watchers,
+		Plays:      stats.Plays,
+		Votes:      stats.Votes,
+		CapturedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// StartPprof serves net/http/pprof on addr in the background. The handlers
+// get their own mux, so `serve` never exposes them.
+func StartPprof(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Serving pprof on http://%s/debug/pprof/", addr)
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Warning: pprof server stopped: %v", err)
+		}
+	}()
+}
+
+// OutputRuntimeStats prints the memory and GC figures of a run that started
+// at start, in verbose mode
+func OutputRuntimeStats(config Config, start time.Time) {
+	if !config.Verbose {
+		return
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	Debugf("\nRuntime: %s elapsed, %s heap in use, %s obtained from the OS, %s allocated in total (%d objects)\n",
+		time.Since(start).Round(time.Millisecond), formatBytes(int64(mem.HeapInuse)), formatBytes(int64(mem.Sys)),
+		formatBytes(int64(mem.TotalAlloc)), mem.Mallocs)
+	Debugf("GC: %d cycles, %s paused in total, %d goroutines\n",
+		mem.NumGC, time.Duration(mem.PauseTotalNs).Round(time.Microsecond), runtime.NumGoroutine())
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// preflightPath is a small Trakt response that still needs a valid API key
+const preflightPath = "/genres/shows"
+
+// TraktRateLimit is Trakt's X-Ratelimit header: the request budget of the key
+type TraktRateLimit struct {
+	Name      string `json:"name"`
+	Period    int    `json:"period"` // seconds
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Until     string `json:"until"`
+}
+
+// Preflight checks the API key and the connection to Trakt with one request
+// before a run, without the retries a rejected key would get mid-run. A
+// rejected key or an unreachable Trakt is fatal; Trakt answering with a
+// rate limit or a server error only warns. The rate limit is nil when
+// Trakt sent none.
+func Preflight(client *http.Client, config Config) (*TraktRateLimit, error) {
+	req, err := http.NewRequest("GET", traktURL(config, preflightPath), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("trakt-api-version", "2")
+	req.Header.Set("trakt-api-key", config.APIKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, exitErrorf(ExitFatal, "cannot reach Trakt: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, exitErrorf(ExitFatal, "Trakt rejected the API key (HTTP %d); check -api-key or TRAKT_API_KEY", resp.StatusCode)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		log.Printf("Warning: Trakt is rate limiting this key already; the run starts with retries")
+	case resp.StatusCode >= 500:
+		log.Printf("Warning: Trakt answered the preflight check with HTTP %d; the run may fail", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, exitErrorf(ExitFatal, "unexpected Trakt preflight status %d", resp.StatusCode)
+	}
+
+	var limit TraktRateLimit
+	if header := resp.Header.Get("X-Ratelimit"); header == "" || json.Unmarshal([]byte(header), &limit) != nil {
+		return nil, nil
+	}
+	return &limit, nil
+}
+
+// OutputPreflight prints the outcome of a successful Preflight
+func OutputPreflight(limit *TraktRateLimit) {
+	if limit == nil {
+		Infof("Trakt API key accepted\n")
+		return
+	}
+	Infof("Trakt API key accepted: %d of %d requests left per %s (%s)\n",
+		limit.Remaining, limit.Limit, time.Duration(limit.Period)*time.Second, limit.Name)
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	respond := func(status int, header http.Header) *http.Client {
+		return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("trakt-api-key") != "key" {
+				t.Errorf("API key not sent")
+			}
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("[]")), Header: header}, nil
+		})}
+	}
+	config := Config{APIKey: "key"}
+
+	header := http.Header{}
+	header.Set("X-Ratelimit", `{"name":"UNAUTHED_API_GET_LIMIT","period":300,"limit":1000,"remaining":998,"until":"2026-10-16T03:40:00Z"}`)
+	limit, err := Preflight(respond(200, header), config)
+	if err != nil || limit == nil || limit.Limit != 1000 || limit.Remaining != 998 || limit.Period != 300 {
+		t.Errorf("Preflight = %+v, %v; want the parsed rate limit", limit, err)
+	}
+
+	if _, err := Preflight(respond(403, http.Header{}), config); ExitCode(err) != ExitFatal {
+		t.Errorf("rejected key: err = %v, want a fatal error", err)
+	}
+	if _, err := Preflight(respond(429, http.Header{}), config); err != nil {
+		t.Errorf("rate limited: err = %v, want only a warning", err)
+	}
+
+	offline := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("dial tcp: no route to host")
+	})}
+	if _, err := Preflight(offline, config); ExitCode(err) != ExitFatal {
+		t.Errorf("unreachable: err = %v, want a fatal error", err)
+	}
+}
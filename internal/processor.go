@@ -1,240 +1,442 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"path/filepath"
+	"slices"
 	"strings"
 	"time"
-
-	"github.com/schollz/progressbar/v3"
 )
 
-// ProcessShows processes TV shows
-func ProcessShows(config Config) {
-	var shows []InputShow
-	LoadJSON(config.TvFile, &shows)
-
-	// Validate input file type
-	for _, show := range shows {
-		if show.Type == "movies" {
-			log.Fatalf("Error: Input file %s contains movie entries, but is being processed as TV shows (-tv). Did you mean to use -movies?", config.TvFile)
-		}
+// ProcessShows processes TV shows; see pipeline.go for the stages
+func ProcessShows(config Config) error {
+	run, err := loadShows(config)
+	if err != nil {
+		return err
 	}
-
-	// Track duplicates - detect shows with same MAL ID but different Trakt IDs
-	malIDTraktMap := make(map[int][]int) // MAL ID -> list of Trakt IDs
-	for _, show := range shows {
-		malIDTraktMap[show.MalID] = append(malIDTraktMap[show.MalID], show.TraktID)
+	client := NewHTTPClient(30 * time.Second)
+	for item := range run.enrich(client, run.resolve(client)) {
+		run.validate(item)
 	}
+	return run.write(client)
+}
+
+// showRun is one ProcessShows run. The fields above resolveState are set by
+// the load stage and only read after it; stats and the maps below it belong
+// to the validate stage.
+type showRun struct {
+	config      Config
+	inputs      []InputShow
+	outputFile  string
+	existing    []OutputShow
+	existingMap map[int]OutputShow // previous output, empty with -force
+	overrides   map[int]*Override
+	traktIDs    map[int][]int // MAL ID -> Trakt IDs it is listed with
+
+	resolveState
+	resolved map[int]OutputShow // what resolve knows of each entry, for skipping
+
+	stats       ProcessingStats
+	results     map[int]OutputShow
+	successful  map[int]int // MAL ID -> Trakt ID that succeeded
+	resolvedIDs []int       // MAL IDs resolved this run, in input order
+}
+
+// showItem is one input show on its way through the stages
+type showItem struct {
+	input    InputShow
+	fetch    InputShow // as fetched: with the Trakt ID of an override
+	output   *OutputShow
+	existing *OutputShow // previous output, nil for a new entry
+	healed   bool
+	findings ProcessingStats // what the enrich stage found
+}
 
-	outputFile := config.OutputFile
-	if outputFile == "" {
-		outputFile = filepath.Join("json/output", filepath.Base(strings.TrimSuffix(config.TvFile, ".json"))+"_ex.json")
+// loadShows is the load stage of ProcessShows
+func loadShows(config Config) (*showRun, error) {
+	shows, err := LoadInputs(config.TvFiles, func(file string, shows []InputShow) error {
+		// Validate input file type
+		for _, show := range shows {
+			if show.Type == "movies" {
+				return exitErrorf(ExitValidation, "input file %s contains movie entries, but is being processed as TV shows (-tv). Did you mean to use -movies?", file)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var existingOutput []OutputShow
-	LoadJSONOptional(outputFile, &existingOutput)
+	outputFile := outputFileFor(config, config.TvFiles, "tv")
 
-	notExistMap := LoadNotFound(outputFile)
-	overridesMap := LoadOverrides("tv")
+	existingOutput, err := LoadShowOutput(outputFile)
+	if err != nil {
+		return nil, err
+	}
 
-	resultsMap := make(map[int]OutputShow)
-	existingMap := make(map[int]OutputShow)
+	run := &showRun{
+		outputFile:   outputFile,
+		existing:     existingOutput,
+		existingMap:  make(map[int]OutputShow),
+		overrides:    LoadOverrides("tv"),
+		traktIDs:     make(map[int][]int),
+		resolveState: newResolveState(outputFile),
+		resolved:     make(map[int]OutputShow),
+		results:      make(map[int]OutputShow),
+		successful:   make(map[int]int),
+	}
 	if !config.Force {
 		for _, show := range existingOutput {
-			resultsMap[show.MyAnimeList.ID] = show
-			existingMap[show.MyAnimeList.ID] = show
+			run.results[show.MyAnimeList.ID] = show
+			run.existingMap[show.MyAnimeList.ID] = show
+			run.resolved[show.MyAnimeList.ID] = show
 		}
 	}
 
-	// Track which Trakt IDs succeeded for each MAL ID
-	successfulTraktIDs := make(map[int]int) // MAL ID -> Trakt ID that succeeded
-
-	stats := ProcessingStats{
-		MediaType:        "tv",
-		TotalBefore:      len(existingOutput),
-		CreatedDetails:   []ChangeDetail{},
-		UpdatedDetails:   []ChangeDetail{},
-		ModifiedDetails:  []ChangeDetail{},
-		NotFoundDetails:  []ChangeDetail{},
-		DuplicateDetails: []ChangeDetail{},
+	// -retry-failed re-fetches only the entries earlier runs failed on
+	if config.RetryFailed {
+		shows = retryInputs(run.retries, shows)
+		config.Force = true
+		if config.Verbose {
+			Debugf("\nRetrying %d failed shows from %s", len(shows), run.retries.path)
+		}
 	}
 
-	var newNotExist []NotFoundEntry
-	bar := setupProgressBar(len(shows), "Processing shows", config.NoProgress)
-	client := &http.Client{Timeout: 30 * time.Second}
+	// Entries left out by the filter keep their existing output; the ones it
+	// picks are re-fetched as with -force
+	releaseYears := make(map[int]int, len(existingOutput))
+	for _, show := range existingOutput {
+		releaseYears[show.MyAnimeList.ID] = show.ReleaseYear
+	}
+	shows = slices.DeleteFunc(shows, func(show InputShow) bool {
+		return !config.Filter.Match(show.MalID, "shows", releaseYears[show.MalID])
+	})
+	if config.Filter.Selective() {
+		config.Force = true
+	}
 
+	// Track duplicates - detect shows with same MAL ID but different Trakt IDs
 	for _, show := range shows {
-		bar.Add(1)
+		run.traktIDs[show.MalID] = append(run.traktIDs[show.MalID], show.TraktID)
+	}
 
-		if override, exists := overridesMap[show.MalID]; exists && override.Ignore {
-			if config.Verbose {
-				fmt.Printf("\nSkipping ignored show: %s (MAL ID: %d) - %s", show.Title, show.MalID, override.Description)
-			}
-			continue
-		}
+	run.stats = ProcessingStats{
+		MediaType:         "tv",
+		TotalBefore:       len(existingOutput),
+		CreatedDetails:    []ChangeDetail{},
+		UpdatedDetails:    []ChangeDetail{},
+		ModifiedDetails:   []ChangeDetail{},
+		NotFoundDetails:   []ChangeDetail{},
+		DuplicateDetails:  []ChangeDetail{},
+		ValidationDetails: []ChangeDetail{},
+		WarningDetails:    []EntryWarning{},
+	}
+	config.Progress = newProgress(config, len(shows), "Processing shows")
+	run.config, run.inputs = config, shows
+	return run, nil
+}
 
-		if shouldSkipShow(show, resultsMap, notExistMap, config) {
-			continue
-		}
+// resolve is the resolve stage of ProcessShows: it fetches the shows to
+// process from Trakt, until the input or the run's budget ends
+func (r *showRun) resolve(client *http.Client) <-chan *showItem {
+	out := make(chan *showItem)
+	go func() {
+		defer close(out)
+		config := r.config
+		for _, show := range r.inputs {
+			if config.Budget.Exhausted() {
+				r.stopped = config.Budget.Reason()
+				return
+			}
+			config.Progress.Add()
 
-		outputShow, err := getShowData(client, config, show)
-		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				newNotExist = append(newNotExist, NotFoundEntry{MalID: show.MalID, Title: show.Title})
-				if !notExistMap[show.MalID] {
-					stats.NotFoundDetails = append(stats.NotFoundDetails, ChangeDetail{
-						MalID:  show.MalID,
-						Title:  show.Title,
-						Reason: "Not found on Trakt.tv",
-					})
+			if override, exists := r.overrides[show.MalID]; exists && override.Ignore {
+				if config.Verbose {
+					Debugf("\nSkipping ignored show: %s (MAL ID: %d) - %s", show.Title, show.MalID, override.Description)
 				}
-			} else {
-				log.Printf("Error processing show %d: %v", show.MalID, err)
+				r.retries.Done(show.inputKey())
+				continue
 			}
-			continue
-		}
 
-		if _, exists := existingMap[show.MalID]; exists {
-			if outputShow.Trakt.ID != resultsMap[show.MalID].Trakt.ID ||
-				outputShow.Trakt.Slug != resultsMap[show.MalID].Trakt.Slug {
-				stats.UpdatedDetails = append(stats.UpdatedDetails, ChangeDetail{
-					MalID:  show.MalID,
-					Title:  show.Title,
-					Reason: "Trakt metadata updated",
-				})
+			// An override mapping the entry elsewhere is fetched under its ID
+			fetch := show
+			if id := r.overrides[show.MalID].traktID(); id != 0 {
+				fetch.TraktID = id
+				fetch.GuessedSlug = "" // the slug guesses the input ID's item
 			}
-		} else {
-			stats.CreatedDetails = append(stats.CreatedDetails, ChangeDetail{
-				MalID:  show.MalID,
-				Title:  show.Title,
-				Reason: "New entry added",
-			})
-		}
 
-		if override, exists := overridesMap[show.MalID]; exists && !override.Ignore {
-			oldShow := *outputShow
-			ApplyShowOverride(outputShow, override)
-			if oldShow.Trakt.ID != outputShow.Trakt.ID ||
-				oldShow.Trakt.Slug != outputShow.Trakt.Slug ||
-				oldShow.Externals != outputShow.Externals {
-				stats.ModifiedDetails = append(stats.ModifiedDetails, ChangeDetail{
-					MalID:  show.MalID,
-					Title:  show.Title,
-					Reason: override.Description,
-				})
+			if shouldSkipShow(show, fetch.TraktID, r.resolved, r.notExist, config) {
+				continue
 			}
-		}
-
-		resultsMap[show.MalID] = *outputShow
-		successfulTraktIDs[show.MalID] = show.TraktID
-	}
-
-	// Build duplicate report: for each MAL ID with multiple Trakt IDs, report the failed ones
-	for malID, traktIDs := range malIDTraktMap {
-		if len(traktIDs) > 1 {
-			// Find which Trakt ID succeeded
-			successfulID := successfulTraktIDs[malID]
-
-			// Find the title (from any entry with this MAL ID)
-			var title string
-			for _, item := range shows {
-				if item.MalID == malID {
-					title = item.Title
-					break
+			config.Progress.Phase("trakt")
+			outputShow, err := getShowData(client, config, fetch)
+			if err != nil {
+				if r.fetchFailed(config, "show", show.inputKey(), show.MalID, show.Title, err) {
+					return
 				}
+				continue
 			}
+			r.retries.Done(show.inputKey())
 
-			// Collect invalid Trakt IDs
-			var invalidIDs []int
-			for _, traktID := range traktIDs {
-				if traktID != successfulID {
-					invalidIDs = append(invalidIDs, traktID)
-				}
+			item := &showItem{input: show, fetch: fetch, output: outputShow, healed: r.notExist[show.MalID]}
+			if item.healed {
+				r.healed[show.MalID] = true
 			}
-
-			// Format invalid IDs as [id1, id2, ...]
-			invalidStr := ""
-			for i, id := range invalidIDs {
-				if i > 0 {
-					invalidStr += ", "
-				}
-				invalidStr += fmt.Sprintf("%d", id)
+			if existing, exists := r.existingMap[show.MalID]; exists {
+				item.existing = &existing
+			} else {
+				// Counted here, so the next budget check sees it
+				config.Budget.AddNew()
 			}
+			r.resolved[show.MalID] = *outputShow
+			out <- item
+		}
+	}()
+	return out
+}
 
-			// Format the reason message
-			reason := fmt.Sprintf("Duplicate: valid Trakt ID %d, invalid [%s]", successfulID, invalidStr)
-			if successfulID == 0 {
-				reason = fmt.Sprintf("Duplicate: no valid Trakt ID, invalid [%s]", invalidStr)
-			}
+// enrich is the enrich stage of ProcessShows. TMDB and TVDB check what
+// Trakt reports, before overrides correct it.
+func (r *showRun) enrich(client *http.Client, in <-chan *showItem) <-chan *showItem {
+	ctx := EnrichContext{Client: client, Config: r.config}
+	return stage(in, func(item *showItem) {
+		ctx := ctx
+		ctx.Stats = &item.findings
+		runEnrichers(ctx, EnrichEntry{Show: item.output, ExistingShow: item.existing})
+	})
+}
+
+// validate is the validate stage of ProcessShows: it records how a show
+// changed, applies its override and takes it into the results
+func (r *showRun) validate(item *showItem) {
+	show, outputShow, stats := item.input, item.output, &r.stats
 
-			stats.DuplicateDetails = append(stats.DuplicateDetails, ChangeDetail{
-				MalID:  malID,
-				Title:  title,
-				Reason: reason,
+	if outputShow.Quality == QualitySlug {
+		stats.ValidationDetails = append(stats.ValidationDetails,
+			slugFallbackDetail(show.MalID, show.Title, item.fetch.TraktID, item.fetch.GuessedSlug, outputShow.Trakt.ID))
+	}
+	if item.healed {
+		stats.HealedDetails = append(stats.HealedDetails, ChangeDetail{
+			MalID:  show.MalID,
+			Title:  show.Title,
+			Reason: fmt.Sprintf("Found on Trakt as %s", outputShow.Trakt.Slug),
+		})
+	}
+	var previous *traktRef
+	if item.existing != nil {
+		previous = &traktRef{id: item.existing.Trakt.ID, slug: item.existing.Trakt.Slug}
+	}
+	current := r.results[show.MalID]
+	changeDetails(stats, show.MalID, show.Title, item.fetch.TraktID, previous,
+		&traktRef{id: current.Trakt.ID, slug: current.Trakt.Slug, status: current.Status},
+		&traktRef{id: outputShow.Trakt.ID, slug: outputShow.Trakt.Slug, status: outputShow.Status})
+	mergeFindings(stats, &item.findings)
+
+	if override, exists := r.overrides[show.MalID]; exists && !override.Ignore {
+		oldShow := *outputShow
+		ApplyShowOverride(outputShow, override)
+		if oldShow.Trakt.ID != outputShow.Trakt.ID ||
+			oldShow.Trakt.Slug != outputShow.Trakt.Slug ||
+			oldShow.Externals != outputShow.Externals ||
+			oldShow.AbsoluteOffset != outputShow.AbsoluteOffset ||
+			!slices.Equal(oldShow.Parts, outputShow.Parts) {
+			stats.ModifiedDetails = append(stats.ModifiedDetails, ChangeDetail{
+				MalID:  show.MalID,
+				Title:  show.Title,
+				Reason: override.Description,
 			})
 		}
 	}
 
-	stats.TotalAfter = len(resultsMap)
+	stats.WarningDetails = append(stats.WarningDetails, showWarnings(outputShow)...)
+
+	entryResolved(EntryEvent{Config: r.config, MediaType: "tv", Show: outputShow})
+	if _, done := r.successful[show.MalID]; !done {
+		r.resolvedIDs = append(r.resolvedIDs, show.MalID)
+	}
+	r.results[show.MalID] = *outputShow
+	r.successful[show.MalID] = show.TraktID
+}
+
+// write is the write stage of ProcessShows: it saves the results, the
+// not-found list and the retry queue, and reports the run
+func (r *showRun) write(client *http.Client) error {
+	config, stats := r.config, &r.stats
+	stats.StoppedEarly = r.stopped
+	stats.Requests = config.Budget.Requests()
+	stats.NotFoundDetails = append(stats.NotFoundDetails, r.notFound...)
+
+	titles := make(map[int]string)
+	for _, show := range r.inputs {
+		if _, ok := titles[show.MalID]; !ok {
+			titles[show.MalID] = show.Title
+		}
+	}
+	stats.DuplicateDetails = duplicateDetails(r.traktIDs, r.successful, titles, stats.StoppedEarly)
+	SetEpisodeOffsets(r.results)
+	stats.WarningDetails = append(stats.WarningDetails, CheckEpisodeCounts(r.results, r.resolvedIDs)...)
+
+	stats.TotalAfter = len(r.results)
 	stats.Created = len(stats.CreatedDetails)
 	stats.Updated = len(stats.UpdatedDetails)
 	stats.Modified = len(stats.ModifiedDetails)
 	stats.NotFound = len(stats.NotFoundDetails)
-
-	SaveResults(outputFile, resultsMap)
-	SaveNotFound(outputFile, newNotExist, notExistMap)
-	OutputStats("tv", stats)
+	stats.Healed = len(stats.HealedDetails)
+	stats.Redirected = len(stats.RedirectDetails)
+	stats.CollisionDetails = ShowCollisions(r.results)
+	stats.Failed = r.failed
+	stats.Warnings = len(stats.WarningDetails)
+
+	resources.enterPhase("save")
+	batch := SinkBatch{
+		Config:        config,
+		MediaType:     "tv",
+		OutputFile:    r.outputFile,
+		Stats:         *stats,
+		Shows:         r.results,
+		ExistingShows: r.existing,
+	}
+	if err := writeSinks(batch); err != nil {
+		return err
+	}
+	if err := SaveNotFound(r.outputFile, r.newNotExist, r.notExist, r.healed); err != nil {
+		return err
+	}
+	if err := r.retries.Save(); err != nil {
+		return err
+	}
+	resources.enterPhase("")
+	entriesWritten(batch, r.resolvedIDs)
+	UpdateReviewQueue(client, config, "tv", r.outputFile, r.results)
+	runComplete(RunEvent{Config: config, OutputFile: r.outputFile, Stats: *stats})
 
 	if config.Verbose {
-		fmt.Printf("\nProcessed %d shows, saved to %s\n", len(resultsMap), outputFile)
+		Debugf("\nProcessed %d shows, saved to %s\n", len(r.results), r.outputFile)
 	}
+	return WorseError(runResult("shows", r.failed, stats.StoppedEarly, r.rateLimited), config.Thresholds.check("shows", *stats))
 }
 
-// ProcessMovies processes movies
-func ProcessMovies(config Config) {
-	var movies []InputMovie
-	LoadJSON(config.MovieFile, &movies)
-
-	// Validate input file type
-	for _, movie := range movies {
-		if movie.Type == "shows" {
-			log.Fatalf("Error: Input file %s contains show entries, but is being processed as movies (-movies). Did you mean to use -tv?", config.MovieFile)
-		}
+// ProcessMovies processes movies; see pipeline.go for the stages. Movies
+// also go through the Letterboxd phase, which the enrich stage feeds and
+// write waits for.
+func ProcessMovies(config Config) error {
+	run, err := loadMovies(config)
+	if err != nil {
+		return err
 	}
-
-	// Track duplicates - detect movies with same MAL ID but different Trakt IDs
-	malIDTraktMap := make(map[int][]int) // MAL ID -> list of Trakt IDs
-	for _, movie := range movies {
-		malIDTraktMap[movie.MalID] = append(malIDTraktMap[movie.MalID], movie.TraktID)
+	client := NewHTTPClient(30 * time.Second)
+	run.letterboxd = startLetterboxdPhase(client, run.config)
+	for item := range run.enrich(client, run.resolve(client)) {
+		run.validate(item)
 	}
+	return run.write(client)
+}
+
+// movieRun is one ProcessMovies run, laid out like showRun
+type movieRun struct {
+	config      Config
+	inputs      []InputMovie
+	outputFile  string
+	existing    []OutputMovie
+	existingMap map[int]OutputMovie
+	overrides   map[int]*Override
+	traktIDs    map[int][]int
+	letterboxd  *letterboxdPhase
+
+	resolveState
+	resolved   map[int]OutputMovie
+	fetched    map[int]bool   // MAL IDs fetched from Trakt this run
+	backfilled []*OutputMovie // existing movies queued for Letterboxd IDs only
+
+	stats       ProcessingStats
+	results     map[int]OutputMovie
+	successful  map[int]int
+	enriched    []*OutputMovie // movies that went to the Letterboxd phase
+	resolvedIDs []int
+}
 
-	outputFile := config.OutputFile
-	if outputFile == "" {
-		outputFile = filepath.Join("json/output", filepath.Base(strings.TrimSuffix(config.MovieFile, ".json"))+"_ex.json")
+// movieItem is one input movie on its way through the stages
+type movieItem struct {
+	input    InputMovie
+	fetch    InputMovie
+	output   *OutputMovie
+	existing *OutputMovie
+	reused   bool // taken from the previous output, not fetched this run
+	healed   bool
+	findings ProcessingStats
+}
+
+// loadMovies is the load stage of ProcessMovies
+func loadMovies(config Config) (*movieRun, error) {
+	movies, err := LoadInputs(config.MovieFiles, func(file string, movies []InputMovie) error {
+		// Validate input file type
+		for _, movie := range movies {
+			if movie.Type == "shows" {
+				return exitErrorf(ExitValidation, "input file %s contains show entries, but is being processed as movies (-movies). Did you mean to use -tv?", file)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var existingOutput []OutputMovie
-	LoadJSONOptional(outputFile, &existingOutput)
+	outputFile := outputFileFor(config, config.MovieFiles, "movies")
 
-	notExistMap := LoadNotFound(outputFile)
-	overridesMap := LoadOverrides("movies")
+	existingOutput, err := LoadMovieOutput(outputFile)
+	if err != nil {
+		return nil, err
+	}
 
-	resultsMap := make(map[int]OutputMovie)
-	existingMap := make(map[int]OutputMovie)
+	run := &movieRun{
+		outputFile:   outputFile,
+		existing:     existingOutput,
+		existingMap:  make(map[int]OutputMovie),
+		overrides:    LoadOverrides("movies"),
+		traktIDs:     make(map[int][]int),
+		resolveState: newResolveState(outputFile),
+		resolved:     make(map[int]OutputMovie),
+		fetched:      make(map[int]bool),
+		results:      make(map[int]OutputMovie),
+		successful:   make(map[int]int),
+	}
 	if !config.Force {
 		for _, movie := range existingOutput {
-			resultsMap[movie.MyAnimeList.ID] = movie
-			existingMap[movie.MyAnimeList.ID] = movie
+			run.results[movie.MyAnimeList.ID] = movie
+			run.existingMap[movie.MyAnimeList.ID] = movie
+			run.resolved[movie.MyAnimeList.ID] = movie
+		}
+	}
+
+	// -retry-failed re-fetches only the entries earlier runs failed on
+	if config.RetryFailed {
+		movies = retryInputs(run.retries, movies)
+		config.Force = true
+		if config.Verbose {
+			Debugf("\nRetrying %d failed movies from %s", len(movies), run.retries.path)
 		}
 	}
 
-	// Track which Trakt IDs succeeded for each MAL ID
-	successfulTraktIDs := make(map[int]int) // MAL ID -> Trakt ID that succeeded
+	// Entries left out by the filter keep their existing output; the ones it
+	// picks are re-fetched as with -force
+	releaseYears := make(map[int]int, len(existingOutput))
+	for _, movie := range existingOutput {
+		releaseYears[movie.MyAnimeList.ID] = movie.ReleaseYear
+	}
+	movies = slices.DeleteFunc(movies, func(movie InputMovie) bool {
+		return !config.Filter.Match(movie.MalID, "movies", releaseYears[movie.MalID])
+	})
+	if config.Filter.Selective() {
+		config.Force = true
+	}
 
-	stats := ProcessingStats{
+	// Track duplicates - detect movies with same MAL ID but different Trakt IDs
+	for _, movie := range movies {
+		run.traktIDs[movie.MalID] = append(run.traktIDs[movie.MalID], movie.TraktID)
+	}
+
+	run.stats = ProcessingStats{
 		MediaType:                 "movies",
 		TotalBefore:               len(existingOutput),
 		CreatedDetails:            []ChangeDetail{},
@@ -243,147 +445,229 @@ func ProcessMovies(config Config) {
 		NotFoundDetails:           []ChangeDetail{},
 		DuplicateDetails:          []ChangeDetail{},
 		LetterboxdNotFoundDetails: []ChangeDetail{},
+		ValidationDetails:         []ChangeDetail{},
+		WarningDetails:            []EntryWarning{},
 	}
+	config.Progress = newProgress(config, len(movies), "Processing movies")
+	run.config, run.inputs = config, movies
+	return run, nil
+}
 
-	var newNotExist []NotFoundEntry
-	bar := setupProgressBar(len(movies), "Processing movies", config.NoProgress)
-	client := &http.Client{Timeout: 30 * time.Second}
-
-	for _, movie := range movies {
-		bar.Add(1)
+// resolve is the resolve stage of ProcessMovies. Existing movies that are
+// not re-fetched are taken from the previous output; with -enrich-missing,
+// skipped ones lacking Letterboxd IDs are queued for them.
+func (r *movieRun) resolve(client *http.Client) <-chan *movieItem {
+	out := make(chan *movieItem)
+	go func() {
+		defer close(out)
+		config := r.config
+		for _, movie := range r.inputs {
+			if config.Budget.Exhausted() {
+				r.stopped = config.Budget.Reason()
+				return
+			}
+			config.Progress.Add()
 
-		if override, exists := overridesMap[movie.MalID]; exists && override.Ignore {
-			if config.Verbose {
-				fmt.Printf("\nSkipping ignored movie: %s (MAL ID: %d) - %s", movie.Title, movie.MalID, override.Description)
+			if override, exists := r.overrides[movie.MalID]; exists && override.Ignore {
+				if config.Verbose {
+					Debugf("\nSkipping ignored movie: %s (MAL ID: %d) - %s", movie.Title, movie.MalID, override.Description)
+				}
+				r.retries.Done(movie.inputKey())
+				continue
 			}
-			continue
-		}
 
-		if shouldSkipMovie(movie, resultsMap, notExistMap, config) {
-			continue
-		}
+			if shouldSkipMovie(movie, r.resolved, r.notExist, config) {
+				if existing, exists := r.existingMap[movie.MalID]; exists {
+					if backfill := r.letterboxd.submitMissing(existing); backfill != nil {
+						r.backfilled = append(r.backfilled, backfill)
+					}
+				}
+				continue
+			}
 
-		outputMovie, err := getMovieData(client, config, movie, resultsMap)
-		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				newNotExist = append(newNotExist, NotFoundEntry{MalID: movie.MalID, Title: movie.Title})
-				if !notExistMap[movie.MalID] {
-					stats.NotFoundDetails = append(stats.NotFoundDetails, ChangeDetail{
-						MalID:  movie.MalID,
-						Title:  movie.Title,
-						Reason: "Not found on Trakt.tv",
-					})
+			fetch := movie
+			if id := r.overrides[movie.MalID].traktID(); id != 0 {
+				fetch.TraktID = id
+				fetch.GuessedSlug = "" // the slug guesses the input ID's item
+			}
+			config.Progress.Phase("trakt")
+			outputMovie, err := getMovieData(client, config, fetch, r.resolved)
+			if err != nil {
+				if r.fetchFailed(config, "movie", movie.inputKey(), movie.MalID, movie.Title, err) {
+					return
 				}
-			} else {
-				log.Printf("Error processing movie %d: %v", movie.MalID, err)
+				continue
 			}
-			continue
-		}
+			r.retries.Done(movie.inputKey())
 
-		if _, exists := existingMap[movie.MalID]; exists {
-			if outputMovie.Trakt.ID != resultsMap[movie.MalID].Trakt.ID ||
-				outputMovie.Trakt.Slug != resultsMap[movie.MalID].Trakt.Slug {
-				stats.UpdatedDetails = append(stats.UpdatedDetails, ChangeDetail{
-					MalID:  movie.MalID,
-					Title:  movie.Title,
-					Reason: "Trakt metadata updated",
-				})
+			item := &movieItem{input: movie, fetch: fetch, output: outputMovie, healed: r.notExist[movie.MalID]}
+			if item.healed {
+				r.healed[movie.MalID] = true
 			}
-		} else {
-			stats.CreatedDetails = append(stats.CreatedDetails, ChangeDetail{
-				MalID:  movie.MalID,
-				Title:  movie.Title,
-				Reason: "New entry added",
-			})
+			if existing, exists := r.existingMap[movie.MalID]; exists {
+				item.existing = &existing
+				// getMovieData returns existing movies as they are, unless forced
+				item.reused = !config.Force && !r.fetched[movie.MalID]
+			} else {
+				// Counted here, so the next budget check sees it
+				config.Budget.AddNew()
+			}
+			// Recorded now so duplicate MAL IDs further down the input reuse it
+			r.resolved[movie.MalID] = *outputMovie
+			if !item.reused {
+				r.fetched[movie.MalID] = true
+			}
+			out <- item
 		}
+	}()
+	return out
+}
 
-		// Pass existing movie data to preserve Letterboxd info if fetch fails
-		var existingMovie *OutputMovie
-		if existing, exists := existingMap[movie.MalID]; exists {
-			existingMovie = &existing
-		}
-		letterboxdNotFound := updateLetterboxdInfo(client, config, outputMovie, existingMovie)
-		if letterboxdNotFound != nil {
-			stats.LetterboxdNotFoundDetails = append(stats.LetterboxdNotFoundDetails, *letterboxdNotFound)
-		}
+// enrich is the enrich stage of ProcessMovies; letterboxd, the last
+// enricher, hands each movie to the Letterboxd phase
+func (r *movieRun) enrich(client *http.Client, in <-chan *movieItem) <-chan *movieItem {
+	ctx := EnrichContext{Client: client, Config: r.config, letterboxd: r.letterboxd}
+	return stage(in, func(item *movieItem) {
+		ctx := ctx
+		ctx.Stats = &item.findings
+		runEnrichers(ctx, EnrichEntry{Movie: item.output, ExistingMovie: item.existing, Reused: item.reused})
+	})
+}
+
+// validate is the validate stage of ProcessMovies. Overrides wait for the
+// Letterboxd phase, in write, so they can still correct its IDs.
+func (r *movieRun) validate(item *movieItem) {
+	movie, outputMovie, stats := item.input, item.output, &r.stats
+
+	if outputMovie.Quality == QualitySlug {
+		stats.ValidationDetails = append(stats.ValidationDetails,
+			slugFallbackDetail(movie.MalID, movie.Title, item.fetch.TraktID, item.fetch.GuessedSlug, outputMovie.Trakt.ID))
+	}
+	if item.healed {
+		stats.HealedDetails = append(stats.HealedDetails, ChangeDetail{
+			MalID:  movie.MalID,
+			Title:  movie.Title,
+			Reason: fmt.Sprintf("Found on Trakt as %s", outputMovie.Trakt.Slug),
+		})
+	}
+	var previous *traktRef
+	if item.existing != nil {
+		previous = &traktRef{id: item.existing.Trakt.ID, slug: item.existing.Trakt.Slug}
+	}
+	current := r.results[movie.MalID]
+	changeDetails(stats, movie.MalID, movie.Title, item.fetch.TraktID, previous,
+		&traktRef{id: current.Trakt.ID, slug: current.Trakt.Slug},
+		&traktRef{id: outputMovie.Trakt.ID, slug: outputMovie.Trakt.Slug})
+	mergeFindings(stats, &item.findings)
+
+	r.enriched = append(r.enriched, outputMovie)
+	// Replaced by the enriched entry once the Letterboxd phase finishes
+	r.results[movie.MalID] = *outputMovie
+	r.successful[movie.MalID] = movie.TraktID
+}
 
-		if override, exists := overridesMap[movie.MalID]; exists && !override.Ignore {
+// write is the write stage of ProcessMovies: it waits for the Letterboxd
+// phase, applies overrides, saves and reports the run
+func (r *movieRun) write(client *http.Client) error {
+	config, stats := r.config, &r.stats
+	stats.StoppedEarly = r.stopped
+	stats.Requests = config.Budget.Requests()
+	stats.NotFoundDetails = append(stats.NotFoundDetails, r.notFound...)
+
+	// Overrides apply after Letterboxd so they can still correct its IDs
+	config.Progress.Phase("letterboxd")
+	stats.LetterboxdNotFoundDetails = append(stats.LetterboxdNotFoundDetails, r.letterboxd.wait()...)
+	for _, outputMovie := range r.enriched {
+		malID := outputMovie.MyAnimeList.ID
+		if override, exists := r.overrides[malID]; exists && !override.Ignore {
 			oldMovie := *outputMovie
 			ApplyMovieOverride(outputMovie, override)
 			if oldMovie.Trakt.ID != outputMovie.Trakt.ID ||
 				oldMovie.Trakt.Slug != outputMovie.Trakt.Slug ||
-				oldMovie.Externals != outputMovie.Externals {
+				oldMovie.Externals != outputMovie.Externals ||
+				!slices.Equal(oldMovie.Parts, outputMovie.Parts) {
 				stats.ModifiedDetails = append(stats.ModifiedDetails, ChangeDetail{
-					MalID:  movie.MalID,
-					Title:  movie.Title,
+					MalID:  malID,
+					Title:  outputMovie.MyAnimeList.Title,
 					Reason: override.Description,
 				})
 			}
 		}
-
-		resultsMap[movie.MalID] = *outputMovie
-		successfulTraktIDs[movie.MalID] = movie.TraktID
+		stats.WarningDetails = append(stats.WarningDetails, movieWarnings(outputMovie)...)
+		r.finish(outputMovie)
+	}
+	for _, outputMovie := range r.backfilled {
+		if outputMovie.Externals.Letterboxd == nil || outputMovie.Externals.Letterboxd.Slug == nil {
+			continue
+		}
+		malID := outputMovie.MyAnimeList.ID
+		if override, exists := r.overrides[malID]; exists && !override.Ignore {
+			ApplyMovieOverride(outputMovie, override)
+		}
+		r.finish(outputMovie)
+		stats.UpdatedDetails = append(stats.UpdatedDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  outputMovie.MyAnimeList.Title,
+			Reason: "Letterboxd IDs added",
+		})
 	}
 
-	// Build duplicate report: for each MAL ID with multiple Trakt IDs, report the failed ones
-	for malID, traktIDs := range malIDTraktMap {
-		if len(traktIDs) > 1 {
-			// Find which Trakt ID succeeded
-			successfulID := successfulTraktIDs[malID]
-
-			// Find the title (from any entry with this MAL ID)
-			var title string
-			for _, item := range movies {
-				if item.MalID == malID {
-					title = item.Title
-					break
-				}
-			}
-
-			// Collect invalid Trakt IDs
-			var invalidIDs []int
-			for _, traktID := range traktIDs {
-				if traktID != successfulID {
-					invalidIDs = append(invalidIDs, traktID)
-				}
-			}
-
-			// Format invalid IDs as [id1, id2, ...]
-			invalidStr := ""
-			for i, id := range invalidIDs {
-				if i > 0 {
-					invalidStr += ", "
-				}
-				invalidStr += fmt.Sprintf("%d", id)
-			}
-
-			// Format the reason message
-			reason := fmt.Sprintf("Duplicate: valid Trakt ID %d, invalid [%s]", successfulID, invalidStr)
-			if successfulID == 0 {
-				reason = fmt.Sprintf("Duplicate: no valid Trakt ID, invalid [%s]", invalidStr)
-			}
-
-			stats.DuplicateDetails = append(stats.DuplicateDetails, ChangeDetail{
-				MalID:  malID,
-				Title:  title,
-				Reason: reason,
-			})
+	titles := make(map[int]string)
+	for _, movie := range r.inputs {
+		if _, ok := titles[movie.MalID]; !ok {
+			titles[movie.MalID] = movie.Title
 		}
 	}
+	stats.DuplicateDetails = duplicateDetails(r.traktIDs, r.successful, titles, stats.StoppedEarly)
 
-	stats.TotalAfter = len(resultsMap)
+	stats.TotalAfter = len(r.results)
 	stats.Created = len(stats.CreatedDetails)
 	stats.Updated = len(stats.UpdatedDetails)
 	stats.Modified = len(stats.ModifiedDetails)
 	stats.NotFound = len(stats.NotFoundDetails)
-
-	SaveMovieResults(outputFile, resultsMap)
-	SaveNotFound(outputFile, newNotExist, notExistMap)
-	OutputStats("movies", stats)
+	stats.Healed = len(stats.HealedDetails)
+	stats.Redirected = len(stats.RedirectDetails)
+	stats.CollisionDetails = MovieCollisions(r.results)
+	stats.Failed = r.failed
+	stats.Warnings = len(stats.WarningDetails)
+
+	resources.enterPhase("save")
+	batch := SinkBatch{
+		Config:         config,
+		MediaType:      "movies",
+		OutputFile:     r.outputFile,
+		Stats:          *stats,
+		Movies:         r.results,
+		ExistingMovies: r.existing,
+	}
+	if err := writeSinks(batch); err != nil {
+		return err
+	}
+	if err := SaveNotFound(r.outputFile, r.newNotExist, r.notExist, r.healed); err != nil {
+		return err
+	}
+	if err := r.retries.Save(); err != nil {
+		return err
+	}
+	resources.enterPhase("")
+	entriesWritten(batch, r.resolvedIDs)
+	UpdateReviewQueue(client, config, "movies", r.outputFile, nil)
+	runComplete(RunEvent{Config: config, OutputFile: r.outputFile, Stats: *stats})
 
 	if config.Verbose {
-		fmt.Printf("\nProcessed %d movies, saved to %s\n", len(resultsMap), outputFile)
+		Debugf("\nProcessed %d movies, saved to %s\n", len(r.results), r.outputFile)
+	}
+	return WorseError(runResult("movies", r.failed, stats.StoppedEarly, r.rateLimited), config.Thresholds.check("movies", *stats))
+}
+
+// finish takes a movie whose data is final into the results
+func (r *movieRun) finish(outputMovie *OutputMovie) {
+	entryResolved(EntryEvent{Config: r.config, MediaType: "movies", Movie: outputMovie})
+	malID := outputMovie.MyAnimeList.ID
+	if !slices.Contains(r.resolvedIDs, malID) {
+		r.resolvedIDs = append(r.resolvedIDs, malID)
 	}
+	r.results[malID] = *outputMovie
 }
 
 // getShowData gets data for a show
@@ -393,44 +677,101 @@ func getShowData(client *http.Client, config Config, show InputShow) (*OutputSho
 	malTitle := show.Title
 
 	if config.Verbose {
-		fmt.Printf("\nProcessing show: %s (MAL ID: %d, Trakt ID: %d)", malTitle, show.MalID, traktID)
+		Debugf("\nProcessing show: %s (MAL ID: %d, Trakt ID: %d)", malTitle, show.MalID, traktID)
 	}
 
-	traktShow, err := FetchTraktShow(client, config, traktID)
+	traktShow, bySlug, err := fetchShowOrSlug(client, config, show)
 	if err != nil {
 		return nil, err
 	}
 
 	outputShow := &OutputShow{
 		MyAnimeList: struct {
-			Title string `json:"title"`
-			ID    int    `json:"id"`
-		}{Title: malTitle, ID: show.MalID},
+			Title     string     `json:"title"`
+			RawTitle  string     `json:"raw_title,omitempty"`
+			Titles    *MALTitles `json:"titles,omitempty"`
+			Episodes  int        `json:"episodes,omitempty"`
+			StartDate string     `json:"start_date,omitempty"`
+			ID        int        `json:"id"`
+			URL       string     `json:"url"`
+		}{Title: malTitle, RawTitle: show.RawTitle, ID: show.MalID},
 		Trakt: struct {
 			Title  string `json:"title"`
 			ID     int    `json:"id"`
 			Slug   string `json:"slug"`
+			URL    string `json:"url"`
 			Type   string `json:"type"`
 			Season *struct {
-				ID        int                   `json:"id"`
-				Number    int                   `json:"number"`
-				Externals *TraktExternalsSeason `json:"externals"`
+				ID            int                   `json:"id"`
+				Number        int                   `json:"number"`
+				EpisodeCount  int                   `json:"episode_count,omitempty"`
+				EpisodeOffset int                   `json:"episode_offset,omitempty"`
+				Externals     *TraktExternalsSeason `json:"externals"`
 			} `json:"season"`
 			IsSplitCour bool `json:"is_split_cour"`
 		}{Title: traktShow.Title, ID: traktShow.IDs.Trakt, Slug: traktShow.IDs.Slug, Type: "shows"},
+		Quality:     QualityExactID,
 		ReleaseYear: traktShow.Year,
 		Externals:   &TraktExternalsShow{TVDB: traktShow.IDs.TVDB, TMDB: traktShow.IDs.TMDB, IMDB: traktShow.IDs.IMDB},
 	}
+	if bySlug {
+		outputShow.Quality = QualitySlug
+	}
+	setShowDetails(config, outputShow, traktShow)
+	outputShow.Popularity = fetchPopularity(client, config, "shows", traktShow.IDs.Trakt, show.MalID, malTitle)
 
-	updateSeasonInfo(client, config, outputShow, traktID, seasonNum)
+	updateSeasonInfo(client, config, outputShow, traktShow.IDs.Trakt, seasonNum)
 	return outputShow, nil
 }
 
+// fetchShowOrSlug fetches a show by Trakt ID. When Trakt answers 404, as it
+// does for an ID retired by a merge, the input's guessed slug is tried
+// instead; bySlug reports whether that found the show.
+func fetchShowOrSlug(client *http.Client, config Config, show InputShow) (traktShow *TraktShow, bySlug bool, err error) {
+	traktShow, err = FetchTraktShow(client, config, show.TraktID)
+	if !errors.Is(err, errTraktNotFound) || show.GuessedSlug == "" {
+		return traktShow, false, err
+	}
+	if config.Verbose {
+		Debugf("\n    - Trakt ID %d not found, trying slug %s", show.TraktID, show.GuessedSlug)
+	}
+	if traktShow, slugErr := FetchTraktShowBySlug(client, config, show.GuessedSlug); slugErr == nil {
+		return traktShow, true, nil
+	}
+	return nil, false, err
+}
+
+// setShowDetails copies the optional Trakt extended fields into the output
+func setShowDetails(config Config, outputShow *OutputShow, traktShow *TraktShow) {
+	outputShow.Runtime = traktShow.Runtime
+	outputShow.Status = traktShow.Status
+	outputShow.Country = traktShow.Country
+	outputShow.Language = traktShow.Language
+	outputShow.Network = traktShow.Network
+	outputShow.Images = traktImages(config, traktShow.Images)
+	if config.Genres {
+		outputShow.Genres = traktShow.Genres
+		outputShow.Certification = traktShow.Certification
+	}
+}
+
+// setMovieDetails copies the optional Trakt extended fields into the output
+func setMovieDetails(config Config, outputMovie *OutputMovie, traktMovie *TraktMovie) {
+	outputMovie.Runtime = traktMovie.Runtime
+	outputMovie.Country = traktMovie.Country
+	outputMovie.Language = traktMovie.Language
+	outputMovie.Images = traktImages(config, traktMovie.Images)
+	if config.Genres {
+		outputMovie.Genres = traktMovie.Genres
+		outputMovie.Certification = traktMovie.Certification
+	}
+}
+
 // getMovieData gets data for a movie
 func getMovieData(client *http.Client, config Config, movie InputMovie, resultsMap map[int]OutputMovie) (*OutputMovie, error) {
 	if outputMovie, exists := resultsMap[movie.MalID]; exists && !config.Force {
 		if config.Verbose {
-			fmt.Printf("\nUsing existing data for %s (MAL ID: %d)", movie.Title, movie.MalID)
+			Debugf("\nUsing existing data for %s (MAL ID: %d)", movie.Title, movie.MalID)
 		}
 		return &outputMovie, nil
 	}
@@ -439,39 +780,67 @@ func getMovieData(client *http.Client, config Config, movie InputMovie, resultsM
 	malTitle := movie.Title
 
 	if config.Verbose {
-		fmt.Printf("\nProcessing new/forced movie: %s (MAL ID: %d, Trakt ID: %d)", malTitle, movie.MalID, traktID)
+		Debugf("\nProcessing new/forced movie: %s (MAL ID: %d, Trakt ID: %d)", malTitle, movie.MalID, traktID)
 	}
 
-	traktMovie, err := FetchTraktMovie(client, config, traktID)
+	traktMovie, bySlug, err := fetchMovieOrSlug(client, config, movie)
 	if err != nil {
 		return nil, err
 	}
 
-	return &OutputMovie{
+	outputMovie := &OutputMovie{
 		MyAnimeList: struct {
-			Title string `json:"title"`
-			ID    int    `json:"id"`
-		}{Title: malTitle, ID: movie.MalID},
+			Title    string     `json:"title"`
+			RawTitle string     `json:"raw_title,omitempty"`
+			Titles   *MALTitles `json:"titles,omitempty"`
+			ID       int        `json:"id"`
+			URL      string     `json:"url"`
+		}{Title: malTitle, RawTitle: movie.RawTitle, ID: movie.MalID},
 		Trakt: struct {
 			Title string `json:"title"`
 			ID    int    `json:"id"`
 			Slug  string `json:"slug"`
+			URL   string `json:"url"`
 			Type  string `json:"type"`
 		}{Title: traktMovie.Title, ID: traktMovie.IDs.Trakt, Slug: traktMovie.IDs.Slug, Type: "movies"},
+		Quality:     QualityExactID,
 		ReleaseYear: traktMovie.Year,
 		Externals: &TraktExternalsMovie{
 			TMDB: traktMovie.IDs.TMDB,
 			IMDB: traktMovie.IDs.IMDB,
 		},
-	}, nil
+	}
+	if bySlug {
+		outputMovie.Quality = QualitySlug
+	}
+	setMovieDetails(config, outputMovie, traktMovie)
+	outputMovie.Popularity = fetchPopularity(client, config, "movies", traktMovie.IDs.Trakt, movie.MalID, malTitle)
+	return outputMovie, nil
+}
+
+// fetchMovieOrSlug fetches a movie by Trakt ID, falling back to the input's
+// guessed slug like fetchShowOrSlug
+func fetchMovieOrSlug(client *http.Client, config Config, movie InputMovie) (traktMovie *TraktMovie, bySlug bool, err error) {
+	traktMovie, err = FetchTraktMovie(client, config, movie.TraktID)
+	if !errors.Is(err, errTraktNotFound) || movie.GuessedSlug == "" {
+		return traktMovie, false, err
+	}
+	if config.Verbose {
+		Debugf("\n    - Trakt ID %d not found, trying slug %s", movie.TraktID, movie.GuessedSlug)
+	}
+	if traktMovie, slugErr := FetchTraktMovieBySlug(client, config, movie.GuessedSlug); slugErr == nil {
+		return traktMovie, true, nil
+	}
+	return nil, false, err
 }
 
 // updateSeasonInfo updates season information
 func updateSeasonInfo(client *http.Client, config Config, outputShow *OutputShow, traktID, seasonNum int) {
+	config.Progress.Phase("seasons")
 	season, err := FetchTraktSeason(client, config, traktID, seasonNum)
 	if err != nil {
 		if config.Verbose {
-			fmt.Printf("... season %d not found, marking as split cour", seasonNum)
+			Debugf("... season %d not found, marking as split cour", seasonNum)
 		}
 		outputShow.Trakt.IsSplitCour = true
 		outputShow.Trakt.Season = nil
@@ -480,12 +849,15 @@ func updateSeasonInfo(client *http.Client, config Config, outputShow *OutputShow
 
 	outputShow.Trakt.IsSplitCour = false
 	outputShow.Trakt.Season = &struct {
-		ID        int                   `json:"id"`
-		Number    int                   `json:"number"`
-		Externals *TraktExternalsSeason `json:"externals"`
+		ID            int                   `json:"id"`
+		Number        int                   `json:"number"`
+		EpisodeCount  int                   `json:"episode_count,omitempty"`
+		EpisodeOffset int                   `json:"episode_offset,omitempty"`
+		Externals     *TraktExternalsSeason `json:"externals"`
 	}{
-		ID:     season.IDs.Trakt,
-		Number: season.Number,
+		ID:           season.IDs.Trakt,
+		Number:       season.Number,
+		EpisodeCount: season.EpisodeCount,
 		Externals: &TraktExternalsSeason{
 			TVDB:   season.IDs.TVDB,
 			TMDB:   season.IDs.TMDB,
@@ -498,17 +870,26 @@ func updateSeasonInfo(client *http.Client, config Config, outputShow *OutputShow
 func updateLetterboxdInfo(client *http.Client, config Config, outputMovie *OutputMovie, existingMovie *OutputMovie) *ChangeDetail {
 	if outputMovie.Externals != nil && (outputMovie.Externals.Letterboxd == nil || outputMovie.Externals.Letterboxd.Slug == nil) {
 		if config.Verbose {
-			fmt.Printf("\n    - checking for Letterboxd info...")
+			Debugf("\n    - checking for Letterboxd info...")
 		}
 
-		if tmdbID := outputMovie.Externals.TMDB; tmdbID != nil {
+		// Without a TMDB ID, the IMDB ID is looked up on its own
+		if canLookUpLetterboxd(outputMovie.Externals) {
 			// Get existing Letterboxd data as fallback if it exists
 			var existingLetterboxdData *Letterboxd
 			if existingMovie != nil && existingMovie.Externals != nil && existingMovie.Externals.Letterboxd != nil {
 				existingLetterboxdData = existingMovie.Externals.Letterboxd
 			}
 
-			letterboxdInfo, err := FetchLetterboxdInfo(client, config, *tmdbID, existingLetterboxdData)
+			var tmdbID int
+			if outputMovie.Externals.TMDB != nil {
+				tmdbID = *outputMovie.Externals.TMDB
+			}
+			var imdbID string
+			if outputMovie.Externals.IMDB != nil {
+				imdbID = *outputMovie.Externals.IMDB
+			}
+			letterboxdInfo, err := FetchLetterboxdInfo(client, config, tmdbID, imdbID, existingLetterboxdData)
 			if err != nil {
 				if existingLetterboxdData != nil {
 					outputMovie.Externals.Letterboxd = existingLetterboxdData
@@ -523,34 +904,48 @@ func updateLetterboxdInfo(client *http.Client, config Config, outputMovie *Outpu
 					}
 				}
 				if config.Verbose {
-					fmt.Printf("\n    - Could not fetch Letterboxd info for TMDB ID %d: %v", *tmdbID, err)
+					Debugf("\n    - Could not fetch Letterboxd info for TMDB ID %d / IMDB ID %q: %v", tmdbID, imdbID, err)
 				}
 			} else {
 				outputMovie.Externals.Letterboxd = letterboxdInfo
 				if config.Verbose && letterboxdInfo != nil {
-					fmt.Printf("\n    - success!")
+					Debugf("\n    - success!")
 				}
 			}
 		} else if config.Verbose {
-			fmt.Printf("\n    - no TMDB ID available.")
+			Debugf("\n    - no TMDB or IMDB ID available.")
 		}
 	} else if config.Verbose {
-		fmt.Printf("\n    - Letterboxd info already present.")
+		Debugf("\n    - Letterboxd info already present.")
 	}
 	return nil
 }
 
-// shouldSkipShow checks if a show should be skipped
-func shouldSkipShow(show InputShow, resultsMap map[int]OutputShow, notExistMap map[int]bool, config Config) bool {
-	if _, exists := resultsMap[show.MalID]; exists && !config.Force {
+// showFinished reports whether a Trakt status means no more episodes will
+// air. An unknown (empty) status counts as airing, so it gets filled in.
+func showFinished(status string) bool {
+	return status == "ended" || status == "canceled"
+}
+
+// shouldSkipShow checks if a show should be skipped. traktID is the ID the
+// show is fetched under, after overrides.
+func shouldSkipShow(show InputShow, traktID int, resultsMap map[int]OutputShow, notExistMap map[int]bool, config Config) bool {
+	if existing, exists := resultsMap[show.MalID]; exists && !config.Force {
+		// Only the recorded Trakt ID is refreshed, so duplicates stay skipped
+		if config.RefreshAiring && existing.Trakt.ID == traktID && !showFinished(existing.Status) {
+			if config.Verbose {
+				Debugf("\nRefreshing airing show: %s (MAL ID: %d, status: %q)", show.Title, show.MalID, existing.Status)
+			}
+			return false
+		}
 		if config.Verbose {
-			fmt.Printf("\nSkipping already processed show: %s (MAL ID: %d)", show.Title, show.MalID)
+			Debugf("\nSkipping already processed show: %s (MAL ID: %d)", show.Title, show.MalID)
 		}
 		return true
 	}
-	if notExistMap[show.MalID] {
+	if notExistMap[show.MalID] && !config.RecheckNotFound {
 		if config.Verbose {
-			fmt.Printf("\nSkipping non-existent show: %s (MAL ID: %d)", show.Title, show.MalID)
+			Debugf("\nSkipping non-existent show: %s (MAL ID: %d)", show.Title, show.MalID)
 		}
 		return true
 	}
@@ -559,24 +954,11 @@ func shouldSkipShow(show InputShow, resultsMap map[int]OutputShow, notExistMap m
 
 // shouldSkipMovie checks if a movie should be skipped
 func shouldSkipMovie(movie InputMovie, resultsMap map[int]OutputMovie, notExistMap map[int]bool, config Config) bool {
-	if notExistMap[movie.MalID] {
+	if notExistMap[movie.MalID] && !config.RecheckNotFound {
 		if config.Verbose {
-			fmt.Printf("\nSkipping non-existent movie: %s (MAL ID: %d)", movie.Title, movie.MalID)
+			Debugf("\nSkipping non-existent movie: %s (MAL ID: %d)", movie.Title, movie.MalID)
 		}
 		return true
 	}
 	return false
 }
-
-// setupProgressBar creates a progress bar
-func setupProgressBar(total int, description string, noProgress bool) *progressbar.ProgressBar {
-	if noProgress {
-		return progressbar.New(0)
-	}
-	return progressbar.NewOptions(total,
-		progressbar.OptionSetDescription(description),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetPredictTime(true),
-		progressbar.OptionClearOnFinish(),
-	)
-}
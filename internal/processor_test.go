@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal/trakttest"
+)
+
+func TestProcessShowsAgainstServer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { layout = DefaultLayout() })
+	layout = DefaultLayout()
+
+	server := trakttest.NewServer(t)
+	server.Show(30857, "cowboy-bebop", map[string]any{"title": "Cowboy Bebop", "year": 1998, "ids": map[string]any{"trakt": 30857, "slug": "cowboy-bebop", "tmdb": 30991}})
+	server.Seasons(30857, []map[string]any{{"number": 1, "episode_count": 26, "ids": map[string]any{"trakt": 1}}})
+	server.SetLatency(10 * time.Millisecond)
+	server.RateLimit(1, 0)
+
+	input := "tv.json"
+	os.WriteFile(input, []byte(`[
+		{"title": "Cowboy Bebop", "mal_id": 1, "trakt_id": 30857, "season": 1, "type": "shows"},
+		{"title": "Gone", "mal_id": 2, "trakt_id": 999, "season": 1, "type": "shows"}
+	]`), 0644)
+	config := traktTestConfig(t, server)
+	config.TvFiles = []string{input}
+	config.NoProgress = true
+
+	if err := ProcessShows(config); err != nil {
+		t.Fatalf("ProcessShows = %v", err)
+	}
+	var shows []OutputShow
+	data, err := os.ReadFile(DefaultOutputFile(config.TvFiles, "tv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(data, &shows)
+	if len(shows) != 1 || shows[0].Trakt.ID != 30857 || shows[0].Trakt.Season == nil || shows[0].Trakt.Season.EpisodeCount != 26 {
+		t.Fatalf("output = %+v, want Cowboy Bebop season 1", shows)
+	}
+	var notFound []NotFoundEntry
+	LoadJSONOptional(notFoundPath(DefaultOutputFile(config.TvFiles, "tv")), &notFound)
+	if len(notFound) != 1 || notFound[0].MalID != 2 {
+		t.Errorf("not found = %v, want MAL ID 2", notFound)
+	}
+}
+
+func TestShouldSkipShowRefreshAiring(t *testing.T) {
+	existing := func(traktID int, status string) map[int]OutputShow {
+		var show OutputShow
+		show.Trakt.ID = traktID
+		show.Status = status
+		return map[int]OutputShow{1: show}
+	}
+	input := InputShow{MalID: 1, Title: "Cowboy Bebop", TraktID: 30857}
+
+	tests := []struct {
+		name     string
+		traktID  int // ID fetched under, after overrides
+		resolved map[int]OutputShow
+		refresh  bool
+		want     bool
+	}{
+		{"new show", 30857, nil, true, false},
+		{"existing show", 30857, existing(30857, "returning series"), false, true},
+		{"airing show", 30857, existing(30857, "returning series"), true, false},
+		{"ended show", 30857, existing(30857, "ended"), true, true},
+		{"airing show mapped by an override", 42, existing(42, "returning series"), true, false},
+		{"duplicate of another Trakt ID", 30857, existing(42, "returning series"), true, true},
+	}
+	for _, tt := range tests {
+		config := Config{RefreshAiring: tt.refresh}
+		if got := shouldSkipShow(input, tt.traktID, tt.resolved, nil, config); got != tt.want {
+			t.Errorf("%s: shouldSkipShow = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// Progress is the progress bar of a pipeline. Besides the count it shows the
+// current phase, cache hits against Trakt requests, and the Trakt rate limit
+// (and -max-api-calls budget) left, so a slow run shows where the time goes.
+// A nil Progress, as with -no-progress, does nothing.
+type Progress struct {
+	bar    *progressbar.ProgressBar
+	config Config
+	title  string
+
+	mu    sync.Mutex
+	phase string
+}
+
+// newProgress creates the progress bar of a pipeline over total entries
+func newProgress(config Config, total int, title string) *Progress {
+	if config.NoProgress {
+		return nil
+	}
+	p := &Progress{
+		bar: progressbar.NewOptions(total,
+			progressbar.OptionSetDescription(title),
+			progressbar.OptionShowCount(),
+			progressbar.OptionSetPredictTime(true),
+			progressbar.OptionClearOnFinish(),
+		),
+		config: config,
+		title:  title,
+	}
+	return p
+}
+
+// Add counts a finished entry
+func (p *Progress) Add() {
+	resources.enterPhase("")
+	if p == nil {
+		return
+	}
+	p.bar.Add(1)
+	p.describe()
+}
+
+// Phase names what the current entry is waiting on, e.g. "trakt",
+// "seasons", "tmdb" or "letterboxd". The resource summary times each phase,
+// with or without a progress bar.
+func (p *Progress) Phase(name string) {
+	resources.enterPhase(name)
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	changed := p.phase != name
+	p.phase = name
+	p.mu.Unlock()
+	if changed {
+		p.describe()
+	}
+}
+
+// describe refreshes the description from the phase and counters
+func (p *Progress) describe() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var hits int64
+	if p.config.Cache != nil {
+		for _, counter := range p.config.Cache.Stats() {
+			hits += counter.MemoryHits + counter.DiskHits
+		}
+	}
+	desc := p.title
+	if p.phase != "" {
+		desc += " [" + p.phase + "]"
+	}
+	if rl := p.config.RateLimiter; rl != nil {
+		desc += fmt.Sprintf(" cache %d · api %d · rate %d/%d", hits, rl.Calls(), rl.Remaining(), rl.maxRequests)
+		if left := p.config.Budget.Remaining(); left >= 0 {
+			desc += fmt.Sprintf(" · budget %d", left)
+		}
+	}
+	p.bar.Describe(desc)
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PushPayload is the body sent to the HTTP push sink
+type PushPayload struct {
+	MediaType   string          `json:"media_type"`
+	Mode        string          `json:"mode"`
+	GeneratedAt string          `json:"generated_at"`
+	Stats       ProcessingStats `json:"stats"`
+	Entries     interface{}     `json:"entries"`
+	Removed     []int           `json:"removed,omitempty"` // delta mode: MAL IDs no longer in the output
+}
+
+// pushEntries selects the entries to send: everything in "full" mode, or
+// only the entries created, updated, modified or redirected during this run
+// in "delta" mode
+func pushEntries[T any](mode string, results map[int]T, stats ProcessingStats) []T {
+	ids := make([]int, 0, len(results))
+	if mode == "full" {
+		for id := range results {
+			ids = append(ids, id)
+		}
+	} else {
+		seen := make(map[int]bool)
+		for _, details := range [][]ChangeDetail{stats.CreatedDetails, stats.UpdatedDetails, stats.ModifiedDetails, stats.RedirectDetails} {
+			for _, detail := range details {
+				if _, ok := results[detail.MalID]; ok && !seen[detail.MalID] {
+					seen[detail.MalID] = true
+					ids = append(ids, detail.MalID)
+				}
+			}
+		}
+	}
+	sort.Ints(ids)
+
+	entries := make([]T, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, results[id])
+	}
+	return entries
+}
+
+// pushRemoved returns the MAL IDs of existing that results no longer hold,
+// sorted, so a delta push can tell the endpoint to drop them
+func pushRemoved[T sortable](existing []T, results map[int]T) []int {
+	var removed []int
+	for _, entry := range existing {
+		id := entry.sortKey().malID
+		if _, ok := results[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Ints(removed)
+	return removed
+}
+
+// HTTPSink sends the results of each run to an HTTP endpoint, as set with
+// -push-url or -sink http:url. -push-method, -push-mode and -push-header
+// apply to it.
+type HTTPSink struct {
+	URL string
+}
+
+func (HTTPSink) Name() string { return "http" }
+
+func (s HTTPSink) Write(batch SinkBatch) error {
+	if batch.MediaType == "movies" {
+		return pushResults(batch.Config, s.URL, batch.Stats, batch.Movies, batch.ExistingMovies)
+	}
+	return pushResults(batch.Config, s.URL, batch.Stats, batch.Shows, batch.ExistingShows)
+}
+
+// pushResults sends the run results to url. In delta mode, the MAL IDs of
+// existing missing from results go out as removals.
+func pushResults[T sortable](config Config, url string, stats ProcessingStats, results map[int]T, existing []T) error {
+	entries := pushEntries(config.PushMode, results, stats)
+	SortEntries(entries, config.Sort)
+	var removed []int
+	if config.PushMode != "full" {
+		removed = pushRemoved(existing, results)
+	}
+	if config.PushMode != "full" && len(entries) == 0 && len(removed) == 0 {
+		if config.Verbose {
+			Debugf("\nNo %s changes to push", stats.MediaType)
+		}
+		return nil
+	}
+
+	payload := PushPayload{
+		MediaType:   stats.MediaType,
+		Mode:        config.PushMode,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Stats:       stats,
+		Entries:     entries,
+		Removed:     removed,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode push payload: %w", err)
+	}
+
+	method := strings.ToUpper(config.PushMethod)
+	if method == "" {
+		method = "POST"
+	}
+
+	client := NewHTTPClient(60 * time.Second)
+	retryConfig := DefaultRetryConfig()
+	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for _, header := range config.PushHeaders {
+			// ParseFlags rejected malformed headers
+			name, value, _ := splitPushHeader(header)
+			// Allow "Authorization: Bearer $PUSH_TOKEN" so secrets stay out of argv
+			req.Header.Set(name, os.ExpandEnv(value))
+		}
+		return client.Do(req)
+	})
+	if err != nil {
+		return fmt.Errorf("push to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push to %s returned HTTP %d: %s", url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if config.Verbose {
+		Debugf("\nPushed %d %s entries and %d removals (%s) to %s", len(entries), stats.MediaType, len(removed), config.PushMode, url)
+	}
+	return nil
+}
+
+// splitPushHeader splits a -push-header value of the form "Name: value"
+func splitPushHeader(header string) (name, value string, err error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid -push-header %q (expected \"Name: value\")", header)
+	}
+	return name, strings.TrimSpace(value), nil
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPushEntries(t *testing.T) {
+	results := map[int]OutputShow{}
+	for _, id := range []int{1, 2, 3, 4, 5} {
+		var show OutputShow
+		show.MyAnimeList.ID = id
+		results[id] = show
+	}
+	stats := ProcessingStats{
+		CreatedDetails:  []ChangeDetail{{MalID: 3}},
+		UpdatedDetails:  []ChangeDetail{{MalID: 1}, {MalID: 3}},
+		RedirectDetails: []ChangeDetail{{MalID: 5}},
+		NotFoundDetails: []ChangeDetail{{MalID: 2}},
+	}
+
+	ids := func(entries []OutputShow) []int {
+		var ids []int
+		for _, entry := range entries {
+			ids = append(ids, entry.MyAnimeList.ID)
+		}
+		return ids
+	}
+	if got := ids(pushEntries("full", results, stats)); len(got) != 5 {
+		t.Errorf("full push = %v, want all 5 entries", got)
+	}
+	if got := ids(pushEntries("delta", results, stats)); len(got) != 3 || got[0] != 1 || got[1] != 3 || got[2] != 5 {
+		t.Errorf("delta push = %v, want [1 3 5]", got)
+	}
+}
+
+func TestPushResultsSendsRemovalsAndRetries(t *testing.T) {
+	var requests atomic.Int32
+	var payload PushPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	shows := testDataset(t).ShowList()
+	results := map[int]OutputShow{shows[0].MyAnimeList.ID: shows[0]}
+	config := Config{PushMode: "delta", PushMethod: "PUT"}
+	if err := pushResults(config, server.URL, ProcessingStats{MediaType: "tv"}, results, shows); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want a retry after the 429", n)
+	}
+	if len(payload.Removed) != 1 || payload.Removed[0] != shows[1].MyAnimeList.ID {
+		t.Errorf("removed = %v, want [%d]", payload.Removed, shows[1].MyAnimeList.ID)
+	}
+
+	// Nothing changed and nothing removed: no request at all
+	requests.Store(1)
+	if err := pushResults(config, server.URL, ProcessingStats{MediaType: "tv"}, results, shows[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("empty delta sent %d requests", n-1)
+	}
+}
+
+func TestSplitPushHeader(t *testing.T) {
+	if name, value, err := splitPushHeader(" Authorization :  Bearer $PUSH_TOKEN "); err != nil || name != "Authorization" || value != "Bearer $PUSH_TOKEN" {
+		t.Errorf("splitPushHeader = %q, %q, %v", name, value, err)
+	}
+	if _, value, err := splitPushHeader("X-Empty:"); err != nil || value != "" {
+		t.Errorf("empty value = %q, %v; want it accepted", value, err)
+	}
+	for _, header := range []string{"Authorization Bearer $PUSH_TOKEN", ": value", "Bad Name: value"} {
+		if _, _, err := splitPushHeader(header); err == nil {
+			t.Errorf("splitPushHeader(%q) accepted", header)
+		}
+	}
+}
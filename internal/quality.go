@@ -0,0 +1,27 @@
+package internal
+
+// Mapping quality tiers, from most to least trustworthy. Entries not
+// processed since the field was added have none.
+const (
+	QualityOverride        = "verified-by-override"   // Trakt ID set by a maintainer override
+	QualityExactID         = "exact-id"               // Trakt ID given by the input mapping
+	QualitySlug            = "slug-fallback"          // input Trakt ID 404s; found by the input's guessed slug
+	QualitySearchHigh      = "search-high-confidence" // external ID search, one result whose title agrees
+	QualitySearchLow       = "search-low-confidence"  // external ID search, several results or titles disagree
+	QualityEpisodeMismatch = "episode-count-mismatch" // any tier but the override whose MAL and Trakt season episode counts disagree
+)
+
+// searchQuality rates a mapping found by external ID search. kind is the
+// search result type ("show" or "movie") that was picked.
+func searchQuality(results []TraktSearchResult, kind, malTitle, traktTitle string) string {
+	matches := 0
+	for _, result := range results {
+		if result.Type == kind {
+			matches++
+		}
+	}
+	if matches != 1 || lowConfidence(malTitle, traktTitle) {
+		return QualitySearchLow
+	}
+	return QualitySearchHigh
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -10,16 +11,19 @@ import (
 
 // RateLimiter implements token bucket algorithm for rate limiting
 type RateLimiter struct {
+	name        string        // service, for the resource summary
 	maxRequests int           // Max requests per window
 	windowSize  time.Duration // Time window for rate limit
 	tokens      float64       // Current tokens available
 	lastRefill  time.Time     // Last time tokens were refilled
+	calls       int           // Tokens consumed so far
 	mu          sync.Mutex
 }
 
 // NewRateLimiter creates a new rate limiter (1000 requests per 5 minutes for Trakt)
 func NewRateLimiter() *RateLimiter {
 	return &RateLimiter{
+		name:        "trakt",
 		maxRequests: 1000,
 		windowSize:  5 * time.Minute,
 		tokens:      1000,
@@ -27,12 +31,72 @@ func NewRateLimiter() *RateLimiter {
 	}
 }
 
-// NewLetterboxdRateLimiter creates a new rate limiter for Letterboxd (100 requests per minute)
-func NewLetterboxdRateLimiter() *RateLimiter {
+// NewLetterboxdRateLimiter creates a new rate limiter for Letterboxd allowing
+// perMinute requests per minute (DefaultLetterboxdRate if not positive)
+func NewLetterboxdRateLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		perMinute = DefaultLetterboxdRate
+	}
 	return &RateLimiter{
-		maxRequests: 100,
+		name:        "letterboxd",
+		maxRequests: perMinute,
 		windowSize:  1 * time.Minute,
-		tokens:      100,
+		tokens:      float64(perMinute),
+		lastRefill:  time.Now(),
+	}
+}
+
+// NewTMDBRateLimiter creates a new rate limiter for TMDB (40 requests per second)
+func NewTMDBRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		name:        "tmdb",
+		maxRequests: 40,
+		windowSize:  1 * time.Second,
+		tokens:      40,
+		lastRefill:  time.Now(),
+	}
+}
+
+// NewTVDBRateLimiter creates a new rate limiter for TheTVDB (10 requests per second)
+func NewTVDBRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		name:        "tvdb",
+		maxRequests: 10,
+		windowSize:  1 * time.Second,
+		tokens:      10,
+		lastRefill:  time.Now(),
+	}
+}
+
+// NewWikidataRateLimiter creates a new rate limiter for the Wikidata Query Service (5 requests per second)
+func NewWikidataRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		name:        "wikidata",
+		maxRequests: 5,
+		windowSize:  1 * time.Second,
+		tokens:      5,
+		lastRefill:  time.Now(),
+	}
+}
+
+// NewJikanRateLimiter creates a new rate limiter for Jikan (1 request per second, within its 60 per minute)
+func NewJikanRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		name:        "jikan",
+		maxRequests: 1,
+		windowSize:  1 * time.Second,
+		tokens:      1,
+		lastRefill:  time.Now(),
+	}
+}
+
+// NewSIMKLRateLimiter creates a new rate limiter for SIMKL (5 requests per second)
+func NewSIMKLRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		name:        "simkl",
+		maxRequests: 5,
+		windowSize:  1 * time.Second,
+		tokens:      5,
 		lastRefill:  time.Now(),
 	}
 }
@@ -42,6 +106,12 @@ func (rl *RateLimiter) Wait() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
+	var waited time.Duration
+	defer func() {
+		if waited > 0 {
+			resources.recordWait(rl.name, waited)
+		}
+	}()
 	for {
 		now := time.Now()
 		elapsed := now.Sub(rl.lastRefill)
@@ -57,6 +127,7 @@ func (rl *RateLimiter) Wait() {
 
 		if rl.tokens >= 1 {
 			rl.tokens--
+			rl.calls++
 			return
 		}
 
@@ -68,10 +139,25 @@ func (rl *RateLimiter) Wait() {
 
 		rl.mu.Unlock()
 		time.Sleep(waitTime)
+		waited += waitTime
 		rl.mu.Lock()
 	}
 }
 
+// Calls returns the number of requests let through so far
+func (rl *RateLimiter) Calls() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.calls
+}
+
+// Remaining returns the whole tokens left in the current window
+func (rl *RateLimiter) Remaining() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return int(rl.tokens)
+}
+
 // RetryConfig contains retry parameters
 type RetryConfig struct {
 	MaxRetries     int           // Maximum number of retries (default: 3)
@@ -88,6 +174,10 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
+// ErrRateLimited is returned by RetryWithBackoff when the API still answers
+// 429 or 403 after the last retry
+var ErrRateLimited = errors.New("rate limited or blocked")
+
 // RetryWithBackoff executes a function with exponential backoff retry on 429 and 403 errors
 func RetryWithBackoff(config RetryConfig, fn func() (*http.Response, error)) (*http.Response, error) {
 	var lastErr error
@@ -108,7 +198,12 @@ func RetryWithBackoff(config RetryConfig, fn func() (*http.Response, error)) (*h
 
 		// 429 or 403 error - should retry
 		if resp != nil && (resp.StatusCode == 429 || resp.StatusCode == 403) {
-			lastErr = fmt.Errorf("rate limited or blocked (%d)", resp.StatusCode)
+			lastErr = &APIError{
+				Endpoint: requestEndpoint(resp.Request),
+				Status:   resp.StatusCode,
+				Retries:  attempt,
+				Err:      fmt.Errorf("%w (%d)", ErrRateLimited, resp.StatusCode),
+			}
 
 			// Check for Retry-After header
 			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
@@ -119,6 +214,7 @@ func RetryWithBackoff(config RetryConfig, fn func() (*http.Response, error)) (*h
 
 			if attempt < config.MaxRetries {
 				resp.Body.Close()
+				resources.recordRetry(resourceEndpoint(resp.Request), backoff)
 				time.Sleep(backoff)
 				backoff = time.Duration(math.Min(
 					float64(backoff)*2,
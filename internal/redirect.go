@@ -0,0 +1,25 @@
+package internal
+
+import "fmt"
+
+// traktRedirect describes how Trakt moved an entry since the previous output,
+// or returns "" when it did not. requestedID is the Trakt ID the entry was
+// fetched under. A move only counts while the entry is still requested under
+// its previous ID: when the input or an override changed the ID, the new item
+// is an ordinary update.
+//
+// Trakt merges duplicates by retiring one ID: it then resolves to the
+// surviving item (a different ID and slug), or 404s and is found by slug. A
+// rename keeps the ID and changes only the slug.
+func traktRedirect(requestedID, previousID int, previousSlug string, currentID int, currentSlug string) string {
+	if previousID == 0 || previousID != requestedID {
+		return ""
+	}
+	if currentID != previousID {
+		return fmt.Sprintf("Trakt ID %d (%s) redirects to %d (%s)", previousID, previousSlug, currentID, currentSlug)
+	}
+	if previousSlug != "" && currentSlug != previousSlug {
+		return fmt.Sprintf("Trakt slug changed from %s to %s", previousSlug, currentSlug)
+	}
+	return ""
+}
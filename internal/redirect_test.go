@@ -0,0 +1,25 @@
+package internal
+
+import "testing"
+
+func TestTraktRedirect(t *testing.T) {
+	tests := []struct {
+		name                  string
+		requested, previousID int
+		previousSlug          string
+		currentID             int
+		currentSlug           string
+		want                  string
+	}{
+		{"unchanged", 1, 1, "a", 1, "a", ""},
+		{"merged", 1, 1, "a", 2, "b", "Trakt ID 1 (a) redirects to 2 (b)"},
+		{"renamed", 1, 1, "a", 1, "b", "Trakt slug changed from a to b"},
+		{"input remapped", 3, 1, "a", 3, "c", ""},
+		{"new entry", 1, 0, "", 1, "a", ""},
+	}
+	for _, tt := range tests {
+		if got := traktRedirect(tt.requested, tt.previousID, tt.previousSlug, tt.currentID, tt.currentSlug); got != tt.want {
+			t.Errorf("%s: traktRedirect() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisConn is a minimal RESP2 client supporting pipelined commands and
+// MULTI/EXEC transactions, optionally under WATCH, which is all the exporter
+// and the sink need.
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// dialRedis connects to a redis:// URL, authenticating and
+// selecting the database given in the URL
+func dialRedis(rawURL string) (*redisConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("unsupported redis url scheme %q (expected redis://)", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	if u.User != nil {
+		password, hasPassword := u.User.Password()
+		args := []string{"AUTH", u.User.Username()}
+		if hasPassword {
+			args = append(args, password)
+		}
+		if u.User.Username() == "" {
+			args = []string{"AUTH", password}
+		}
+		if _, err := rc.Do(args...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis auth: %w", err)
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if _, err := rc.Do("SELECT", db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis select %s: %w", db, err)
+		}
+	}
+	return rc, nil
+}
+
+func (rc *redisConn) Close() error {
+	return rc.conn.Close()
+}
+
+// Send buffers a command without waiting for its reply
+func (rc *redisConn) Send(args ...string) {
+	fmt.Fprintf(rc.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// SendTx buffers cmds as one MULTI/EXEC transaction without waiting for the
+// replies, returning how many replies Flush must read for it
+func (rc *redisConn) SendTx(cmds ...[]string) int {
+	rc.Send("MULTI")
+	for _, cmd := range cmds {
+		rc.Send(cmd...)
+	}
+	rc.Send("EXEC")
+	return len(cmds) + 2
+}
+
+// Replies sends buffered commands and returns their n replies along with the
+// first error reply
+func (rc *redisConn) Replies(n int) ([]interface{}, error) {
+	if err := rc.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, n)
+	var firstErr error
+	for i := range replies {
+		reply, err := rc.readReply()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		replies[i] = reply
+	}
+	return replies, firstErr
+}
+
+// Exec runs cmds as one MULTI/EXEC transaction and reports whether it was
+// committed: Redis drops it when a WATCHed key changed
+func (rc *redisConn) Exec(cmds ...[]string) (bool, error) {
+	replies, err := rc.Replies(rc.SendTx(cmds...))
+	if err != nil {
+		return false, err
+	}
+	return replies[len(replies)-1] != nil, nil
+}
+
+// Flush sends buffered commands and reads n replies, returning the first error reply
+func (rc *redisConn) Flush(n int) error {
+	if err := rc.w.Flush(); err != nil {
+		return err
+	}
+	var firstErr error
+	for i := 0; i < n; i++ {
+		if _, err := rc.readReply(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Do sends a single command and returns its reply
+func (rc *redisConn) Do(args ...string) (interface{}, error) {
+	rc.Send(args...)
+	if err := rc.w.Flush(); err != nil {
+		return nil, err
+	}
+	return rc.readReply()
+}
+
+// readReply parses one RESP2 reply
+func (rc *redisConn) readReply() (interface{}, error) {
+	line, err := rc.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, _ := strconv.Atoi(line[1:])
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rc.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, _ := strconv.Atoi(line[1:])
+		if count < 0 {
+			return nil, nil
+		}
+		// Read every item, even after an error item such as a failed command
+		// of an EXEC, so the next reply starts where it should
+		items := make([]interface{}, 0, count)
+		var firstErr error
+		for i := 0; i < count; i++ {
+			item, err := rc.readReply()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			items = append(items, item)
+		}
+		return items, firstErr
+	}
+	return nil, fmt.Errorf("unexpected redis reply %q", line)
+}
+
+// redisRecord is one hash to write: key plus field/value pairs
+type redisRecord struct {
+	key    string
+	fields []string
+}
+
+// RunExportRedis implements the `export-redis` subcommand
+func RunExportRedis(args []string) error {
+	fs := flag.NewFlagSet("export-redis", flag.ExitOnError)
+	redisURL := fs.String("url", envOr("REDIS_URL", "redis://localhost:6379/0"), "Redis URL (env REDIS_URL)")
+	prefix := fs.String("prefix", "anitrakt:", "Key prefix")
+	channel := fs.String("channel", "anitrakt:changes", "Channel to publish a change notification on (empty to disable)")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	fs.Parse(args)
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+
+	records := buildRedisRecords(*prefix, shows, movies)
+
+	rc, err := dialRedis(*redisURL)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Remove keys written by the previous export that no longer exist
+	keysKey := *prefix + "keys"
+	reply, err := rc.Do("SMEMBERS", keysKey)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", keysKey, err)
+	}
+	current := make(map[string]bool, len(records))
+	for _, record := range records {
+		current[record.key] = true
+	}
+	removed := 0
+	if members, ok := reply.([]interface{}); ok {
+		for _, m := range members {
+			if key, ok := m.(string); ok && !current[key] {
+				rc.Send("DEL", key)
+				removed++
+			}
+		}
+	}
+	if err := rc.Flush(removed); err != nil {
+		return fmt.Errorf("remove stale keys: %w", err)
+	}
+
+	// Replace every hash wholesale so stale fields never linger. DEL and HSET
+	// run as one transaction so readers never see a key missing or half
+	// written.
+	const batchSize = 500
+	pending := 0
+	for _, record := range records {
+		pending += rc.SendTx(
+			[]string{"DEL", record.key},
+			append([]string{"HSET", record.key}, record.fields...),
+		)
+		if pending >= batchSize {
+			if err := rc.Flush(pending); err != nil {
+				return fmt.Errorf("write hashes: %w", err)
+			}
+			pending = 0
+		}
+	}
+	keysCmds := [][]string{{"DEL", keysKey}}
+	for start := 0; start < len(records); start += batchSize {
+		end := min(start+batchSize, len(records))
+		args := []string{"SADD", keysKey}
+		for _, record := range records[start:end] {
+			args = append(args, record.key)
+		}
+		keysCmds = append(keysCmds, args)
+	}
+	pending += rc.SendTx(keysCmds...)
+	if err := rc.Flush(pending); err != nil {
+		return fmt.Errorf("write hashes: %w", err)
+	}
+
+	fmt.Printf("Exported %d shows and %d movies to Redis (%d hashes, %d stale removed)\n",
+		len(shows), len(movies), len(records), removed)
+
+	if *channel != "" {
+		notification, _ := json.Marshal(map[string]interface{}{
+			"event":       "dataset_updated",
+			"shows":       len(shows),
+			"movies":      len(movies),
+			"removed":     removed,
+			"exported_at": time.Now().UTC().Format(time.RFC3339),
+		})
+		if _, err := rc.Do("PUBLISH", *channel, string(notification)); err != nil {
+			return fmt.Errorf("publish to %s: %w", *channel, err)
+		}
+	}
+	return nil
+}
+
+// RedisSink writes the hashes of export-redis for the entries of each run.
+// Entry hashes are replaced, reverse lookups are added to, and the MAL ID is
+// dropped from the reverse lookups the previous version of an entry pointed
+// at; keys of entries gone from the dataset stay until the next export-redis.
+type RedisSink struct {
+	URL    string
+	Prefix string
+}
+
+func (RedisSink) Name() string { return "redis" }
+
+// redisWatchBatch is how many entry hashes RedisSink reads and replaces per
+// WATCH/MULTI/EXEC round
+const redisWatchBatch = 100
+
+func (s RedisSink) Write(batch SinkBatch) error {
+	var records []redisRecord
+	if batch.MediaType == "movies" {
+		records = buildRedisRecords(s.Prefix, nil, sortedResults(batch.Movies, SortMalID))
+	} else {
+		records = buildRedisRecords(s.Prefix, sortedResults(batch.Shows, SortMalID), nil)
+	}
+	var entries, reverse []redisRecord
+	for _, record := range records {
+		if strings.HasPrefix(record.key, s.Prefix+"mal:") {
+			entries = append(entries, record)
+		} else {
+			reverse = append(reverse, record)
+		}
+	}
+
+	rc, err := dialRedis(s.URL)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	for start := 0; start < len(entries); start += redisWatchBatch {
+		if err := s.replaceEntries(rc, entries[start:min(start+redisWatchBatch, len(entries))]); err != nil {
+			return fmt.Errorf("write hashes: %w", err)
+		}
+	}
+
+	const batchSize = 500
+	pending := 0
+	for _, record := range reverse {
+		pending += rc.SendTx(
+			append([]string{"HSET", record.key}, record.fields...),
+			[]string{"SADD", s.Prefix + "keys", record.key},
+		)
+		if pending >= batchSize {
+			if err := rc.Flush(pending); err != nil {
+				return fmt.Errorf("write hashes: %w", err)
+			}
+			pending = 0
+		}
+	}
+	if err := rc.Flush(pending); err != nil {
+		return fmt.Errorf("write hashes: %w", err)
+	}
+	if batch.Config.Verbose {
+		Debugf("\nWrote %d %s hashes to Redis", len(records), batch.MediaType)
+	}
+	return nil
+}
+
+// replaceEntries replaces entry hashes and removes their MAL IDs from the
+// reverse lookups of the previous hashes that the new ones no longer have.
+// The previous hashes are read under WATCH, so when another writer changes
+// one before EXEC the transaction is dropped and the round is retried.
+func (s RedisSink) replaceEntries(rc *redisConn, records []redisRecord) error {
+	keys := make([]string, len(records))
+	for i, record := range records {
+		keys[i] = record.key
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		if _, err := rc.Do(append([]string{"WATCH"}, keys...)...); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			rc.Send("HMGET", key, "media_type", "trakt_id", "imdb")
+		}
+		previous, err := rc.Replies(len(keys))
+		if err != nil {
+			return err
+		}
+
+		var cmds [][]string
+		for i, record := range records {
+			malID := strings.TrimPrefix(record.key, s.Prefix+"mal:")
+			current := redisReverseKeys(s.Prefix, record.fields)
+			var old []string
+			if values, ok := previous[i].([]interface{}); ok && len(values) == 3 {
+				for j, field := range []string{"media_type", "trakt_id", "imdb"} {
+					if value, ok := values[j].(string); ok {
+						old = append(old, field, value)
+					}
+				}
+			}
+			for _, key := range redisReverseKeys(s.Prefix, old) {
+				if !slices.Contains(current, key) {
+					cmds = append(cmds, []string{"HDEL", key, malID})
+				}
+			}
+			cmds = append(cmds,
+				[]string{"DEL", record.key},
+				append([]string{"HSET", record.key}, record.fields...),
+				[]string{"SADD", s.Prefix + "keys", record.key},
+			)
+		}
+		committed, err := rc.Exec(cmds...)
+		if err != nil || committed {
+			return err
+		}
+	}
+	return fmt.Errorf("%d entry hashes kept changing under WATCH", len(records))
+}
+
+// redisReverseKeys returns the reverse lookup keys buildRedisRecords files an
+// entry hash with the given field/value pairs under
+func redisReverseKeys(prefix string, fields []string) []string {
+	values := make(map[string]string)
+	for i := 0; i+1 < len(fields); i += 2 {
+		values[fields[i]] = fields[i+1]
+	}
+	var keys []string
+	if id := values["trakt_id"]; id != "" {
+		kind := "shows"
+		if values["media_type"] == "movies" {
+			kind = "movies"
+		}
+		keys = append(keys, prefix+"trakt:"+kind+":"+id)
+	}
+	if imdb := values["imdb"]; imdb != "" {
+		keys = append(keys, prefix+"imdb:"+imdb)
+	}
+	return keys
+}
+
+// buildRedisRecords converts the dataset into Redis hashes:
+//
+//   - mal:{id}            → entry fields plus the full JSON document
+//   - trakt:{type}:{id}   → MAL ID → season number ("" for movies/split cours)
+//   - imdb:{id}           → MAL ID → media type
+func buildRedisRecords(prefix string, shows []OutputShow, movies []OutputMovie) []redisRecord {
+	var records []redisRecord
+	reverse := make(map[string][]string)
+	var reverseKeys []string
+	addReverse := func(key, field, value string) {
+		if _, ok := reverse[key]; !ok {
+			reverseKeys = append(reverseKeys, key)
+		}
+		reverse[key] = append(reverse[key], field, value)
+	}
+
+	for _, show := range shows {
+		malID := strconv.Itoa(show.MyAnimeList.ID)
+		doc, _ := json.Marshal(show)
+		fields := []string{
+			"media_type", "tv",
+			"title", show.MyAnimeList.Title,
+			"trakt_id", strconv.Itoa(show.Trakt.ID),
+			"trakt_slug", show.Trakt.Slug,
+			"trakt_type", show.Trakt.Type,
+			"release_year", strconv.Itoa(show.ReleaseYear),
+			"json", string(doc),
+		}
+		season := ""
+		if show.Trakt.Season != nil {
+			season = strconv.Itoa(show.Trakt.Season.Number)
+			fields = append(fields, "season", season)
+		}
+		if show.Externals != nil {
+			fields = appendExternalFields(fields, show.Externals.TVDB, show.Externals.TMDB, show.Externals.IMDB)
+			if show.Externals.IMDB != nil && *show.Externals.IMDB != "" {
+				addReverse(prefix+"imdb:"+*show.Externals.IMDB, malID, "tv")
+			}
+		}
+		records = append(records, redisRecord{key: prefix + "mal:" + malID, fields: fields})
+		addReverse(fmt.Sprintf("%strakt:shows:%d", prefix, show.Trakt.ID), malID, season)
+	}
+
+	for _, movie := range movies {
+		malID := strconv.Itoa(movie.MyAnimeList.ID)
+		doc, _ := json.Marshal(movie)
+		fields := []string{
+			"media_type", "movies",
+			"title", movie.MyAnimeList.Title,
+			"trakt_id", strconv.Itoa(movie.Trakt.ID),
+			"trakt_slug", movie.Trakt.Slug,
+			"trakt_type", movie.Trakt.Type,
+			"release_year", strconv.Itoa(movie.ReleaseYear),
+			"json", string(doc),
+		}
+		if movie.Externals != nil {
+			fields = appendExternalFields(fields, nil, movie.Externals.TMDB, movie.Externals.IMDB)
+			if movie.Externals.IMDB != nil && *movie.Externals.IMDB != "" {
+				addReverse(prefix+"imdb:"+*movie.Externals.IMDB, malID, "movies")
+			}
+		}
+		records = append(records, redisRecord{key: prefix + "mal:" + malID, fields: fields})
+		addReverse(fmt.Sprintf("%strakt:movies:%d", prefix, movie.Trakt.ID), malID, "")
+	}
+
+	for _, key := range reverseKeys {
+		records = append(records, redisRecord{key: key, fields: reverse[key]})
+	}
+	return records
+}
+
+// appendExternalFields adds non-nil external IDs as hash fields
+func appendExternalFields(fields []string, tvdb, tmdb *int, imdb *string) []string {
+	if tvdb != nil {
+		fields = append(fields, "tvdb", strconv.Itoa(*tvdb))
+	}
+	if tmdb != nil {
+		fields = append(fields, "tmdb", strconv.Itoa(*tmdb))
+	}
+	if imdb != nil && *imdb != "" {
+		fields = append(fields, "imdb", *imdb)
+	}
+	return fields
+}
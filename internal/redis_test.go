@@ -0,0 +1,411 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is an in-memory RESP2 server implementing the commands the
+// exporter sends. It logs every command and every MULTI/EXEC transaction.
+// The next interfere WATCHes act as if another client changed a watched
+// key, so the EXEC after them is dropped.
+type fakeRedis struct {
+	addr string
+
+	mu           sync.Mutex
+	hashes       map[string]map[string]string
+	sets         map[string]map[string]bool
+	published    map[string][]string
+	commands     [][]string
+	transactions [][][]string
+	interfere    int
+}
+
+// newFakeRedis starts a fake Redis server listening on a loopback port
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	f := &fakeRedis{
+		addr:      lis.Addr().String(),
+		hashes:    map[string]map[string]string{},
+		sets:      map[string]map[string]bool{},
+		published: map[string][]string{},
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// serve answers the commands of one connection
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var queued [][]string
+	inMulti, dirty := false, false
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		var reply string
+		switch name := strings.ToUpper(cmd[0]); {
+		case name == "MULTI":
+			inMulti, queued, reply = true, nil, "+OK\r\n"
+		case name == "WATCH":
+			dirty, reply = f.interfere > 0, "+OK\r\n"
+			if dirty {
+				f.interfere--
+			}
+		case name == "EXEC" && dirty:
+			reply, inMulti, queued, dirty = "*-1\r\n", false, nil, false
+		case name == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n", len(queued))
+			for _, c := range queued {
+				reply += f.exec(c)
+			}
+			f.transactions = append(f.transactions, queued)
+			inMulti, queued = false, nil
+		case inMulti:
+			queued, reply = append(queued, cmd), "+QUEUED\r\n"
+		default:
+			reply = f.exec(cmd)
+		}
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs one command and returns its encoded reply
+func (f *fakeRedis) exec(cmd []string) string {
+	args := cmd[1:]
+	switch strings.ToUpper(cmd[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args {
+			if _, ok := f.hashes[key]; ok {
+				n++
+			}
+			if _, ok := f.sets[key]; ok {
+				n++
+			}
+			delete(f.hashes, key)
+			delete(f.sets, key)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "HSET":
+		if len(args) < 3 || len(args)%2 == 0 {
+			return "-ERR wrong number of arguments for 'hset' command\r\n"
+		}
+		if f.hashes[args[0]] == nil {
+			f.hashes[args[0]] = map[string]string{}
+		}
+		for i := 1; i < len(args); i += 2 {
+			f.hashes[args[0]][args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", (len(args)-1)/2)
+	case "HMGET":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, field := range args[1:] {
+			if value, ok := f.hashes[args[0]][field]; ok {
+				reply += fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case "HDEL":
+		n := 0
+		for _, field := range args[1:] {
+			if _, ok := f.hashes[args[0]][field]; ok {
+				delete(f.hashes[args[0]], field)
+				n++
+			}
+		}
+		if len(f.hashes[args[0]]) == 0 {
+			delete(f.hashes, args[0])
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SADD":
+		if f.sets[args[0]] == nil {
+			f.sets[args[0]] = map[string]bool{}
+		}
+		for _, member := range args[1:] {
+			f.sets[args[0]][member] = true
+		}
+		return fmt.Sprintf(":%d\r\n", len(args)-1)
+	case "SMEMBERS":
+		reply := fmt.Sprintf("*%d\r\n", len(f.sets[args[0]]))
+		for member := range f.sets[args[0]] {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(member), member)
+		}
+		return reply
+	case "PUBLISH":
+		f.published[args[0]] = append(f.published[args[0]], args[1])
+		return ":0\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", cmd[0])
+}
+
+// readCommand parses one command sent as a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("bad command header %q", line)
+	}
+	cmd := make([]string, count)
+	for i := range cmd {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("bad bulk header %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(buf[:size])
+	}
+	return cmd, nil
+}
+
+// transaction returns the logged transaction writing the hash at key
+func (f *fakeRedis) transaction(key string) [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, tx := range f.transactions {
+		for _, cmd := range tx {
+			if cmd[0] == "HSET" && cmd[1] == key {
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+// commandNames lists the command names of tx
+func commandNames(tx [][]string) []string {
+	var names []string
+	for _, cmd := range tx {
+		names = append(names, cmd[0])
+	}
+	return names
+}
+
+func TestRedisConnReadReply(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    interface{}
+		wantErr string
+	}{
+		{"+OK\r\n", "OK", ""},
+		{"-ERR unknown command\r\n", nil, "ERR unknown command"},
+		{":42\r\n", int64(42), ""},
+		{"$5\r\nhello\r\n", "hello", ""},
+		{"$0\r\n\r\n", "", ""},
+		{"$-1\r\n", nil, ""},
+		{"*-1\r\n", nil, ""},
+		{"*2\r\n$1\r\na\r\n:1\r\n", []interface{}{"a", int64(1)}, ""},
+		// An EXEC whose second command failed
+		{"*2\r\n:1\r\n-WRONGTYPE Operation against a key holding the wrong kind of value\r\n",
+			[]interface{}{int64(1), nil}, "WRONGTYPE Operation against a key holding the wrong kind of value"},
+		{"!3\r\n", nil, `unexpected redis reply "!3"`},
+	}
+	for _, tt := range tests {
+		// The reply is followed by another so a partially read reply shows
+		rc := &redisConn{r: bufio.NewReader(strings.NewReader(tt.reply + "+NEXT\r\n"))}
+		got, err := rc.readReply()
+		if (err == nil) != (tt.wantErr == "") || err != nil && err.Error() != tt.wantErr {
+			t.Errorf("readReply(%q) error = %v, want %q", tt.reply, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readReply(%q) = %#v, want %#v", tt.reply, got, tt.want)
+		}
+		if next, err := rc.readReply(); next != "NEXT" || err != nil {
+			t.Errorf("reply after %q = %#v, %v, want NEXT", tt.reply, next, err)
+		}
+	}
+}
+
+func TestRedisConnCommands(t *testing.T) {
+	f := newFakeRedis(t)
+	rc, err := dialRedis("redis://:secret@" + f.addr + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	if reply, err := rc.Do("HSET", "h", "a", "1", "b", "2"); err != nil || reply != int64(2) {
+		t.Fatalf("HSET = %#v, %v, want 2", reply, err)
+	}
+	if _, err := rc.Do("HSET", "h", "a"); err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Fatalf("HSET with a missing value error = %v, want the error reply", err)
+	}
+
+	// A failed command inside a transaction is reported, and the replies
+	// after it are still read in step
+	pending := rc.SendTx([]string{"SADD", "s", "x"}, []string{"HSET", "h", "odd"})
+	rc.Send("SMEMBERS", "s")
+	if err := rc.Flush(pending); err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Fatalf("Flush of the transaction error = %v, want the HSET error", err)
+	}
+	if reply, err := rc.readReply(); err != nil || !reflect.DeepEqual(reply, []interface{}{"x"}) {
+		t.Fatalf("SMEMBERS after the transaction = %#v, %v, want [x]", reply, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	want := [][]string{{"AUTH", "secret"}, {"SELECT", "2"}}
+	if got := f.commands[:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("first commands = %v, want %v", got, want)
+	}
+}
+
+func TestRunExportRedis(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	SaveJSON("tv_ex.json", data.ShowList())
+	SaveJSON("movies_ex.json", data.MovieList())
+
+	f := newFakeRedis(t)
+	// A hash of an entry gone from the dataset, and a stale field
+	f.hashes["anitrakt:mal:999"] = map[string]string{"title": "Gone"}
+	f.sets["anitrakt:keys"] = map[string]bool{"anitrakt:mal:999": true, "anitrakt:mal:1": true}
+	f.hashes["anitrakt:mal:1"] = map[string]string{"stale": "x"}
+
+	if err := RunExportRedis([]string{"-url", "redis://" + f.addr, "-tv", "tv_ex.json", "-movies", "movies_ex.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	if _, ok := f.hashes["anitrakt:mal:999"]; ok {
+		t.Error("hash of the removed entry was kept")
+	}
+	show := f.hashes["anitrakt:mal:1"]
+	if _, ok := show["stale"]; ok || show["trakt_id"] != "30857" || show["tvdb"] != "76885" {
+		t.Errorf("anitrakt:mal:1 = %v, want only the fields of the export", show)
+	}
+	if got := f.hashes["anitrakt:trakt:shows:30857"]; got["1"] == "" || len(got) != 2 {
+		t.Errorf("anitrakt:trakt:shows:30857 = %v, want both shows", got)
+	}
+	if got := f.hashes["anitrakt:imdb:tt0113568"]; got["43"] != "movies" {
+		t.Errorf("anitrakt:imdb:tt0113568 = %v, want MAL ID 43", got)
+	}
+	keys := f.sets["anitrakt:keys"]
+	if keys["anitrakt:mal:999"] || !keys["anitrakt:mal:43"] || len(keys) != 7 {
+		t.Errorf("anitrakt:keys = %v, want the 7 exported keys", keys)
+	}
+	if got := f.published["anitrakt:changes"]; len(got) != 1 || !strings.Contains(got[0], `"removed":1`) {
+		t.Errorf("published notifications = %v, want one with a removal", got)
+	}
+	f.mu.Unlock()
+
+	// Each hash is deleted and rewritten in one transaction
+	for _, key := range []string{"anitrakt:mal:1", "anitrakt:trakt:movies:1"} {
+		tx := f.transaction(key)
+		if names := commandNames(tx); !reflect.DeepEqual(names, []string{"DEL", "HSET"}) || tx[0][1] != key {
+			t.Errorf("transaction writing %s = %v, want DEL and HSET of the key", key, tx)
+		}
+	}
+}
+
+func TestRedisSink(t *testing.T) {
+	f := newFakeRedis(t)
+	f.hashes["anitrakt:mal:1"] = map[string]string{"stale": "x"}
+	f.hashes["anitrakt:trakt:shows:30857"] = map[string]string{"7": "2"}
+
+	batch := testShowBatch(t, Config{})
+	if err := (RedisSink{URL: "redis://" + f.addr, Prefix: "anitrakt:"}).Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	if show := f.hashes["anitrakt:mal:1"]; show["stale"] != "" || show["trakt_slug"] == "" {
+		t.Errorf("anitrakt:mal:1 = %v, want only the fields of the entry", show)
+	}
+	// Reverse lookups are added to rather than replaced
+	if got := f.hashes["anitrakt:trakt:shows:30857"]; got["7"] != "2" || len(got) != 3 {
+		t.Errorf("anitrakt:trakt:shows:30857 = %v, want the old and both new shows", got)
+	}
+	if keys := f.sets["anitrakt:keys"]; !keys["anitrakt:mal:1"] || !keys["anitrakt:mal:5"] || len(keys) != 4 {
+		t.Errorf("anitrakt:keys = %v, want the 4 written keys", keys)
+	}
+	f.mu.Unlock()
+
+	// Entry hashes of a round are replaced in one transaction
+	if names := commandNames(f.transaction("anitrakt:mal:1")); !reflect.DeepEqual(names, []string{"DEL", "HSET", "SADD", "DEL", "HSET", "SADD"}) {
+		t.Errorf("transaction writing anitrakt:mal:1 = %v, want DEL, HSET and SADD of both shows", names)
+	}
+	if names := commandNames(f.transaction("anitrakt:trakt:shows:30857")); !reflect.DeepEqual(names, []string{"HSET", "SADD"}) {
+		t.Errorf("transaction writing anitrakt:trakt:shows:30857 = %v, want HSET and SADD", names)
+	}
+}
+
+func TestRedisSinkDropsStaleReverseLookups(t *testing.T) {
+	f := newFakeRedis(t)
+	// MAL ID 1 was filed under another Trakt ID and IMDB ID before
+	f.hashes["anitrakt:mal:1"] = map[string]string{"media_type": "tv", "trakt_id": "111", "imdb": "tt0000001"}
+	f.hashes["anitrakt:trakt:shows:111"] = map[string]string{"1": "1", "7": "1"}
+	f.hashes["anitrakt:imdb:tt0000001"] = map[string]string{"1": "tv"}
+	f.interfere = 1
+
+	batch := testShowBatch(t, Config{})
+	if err := (RedisSink{URL: "redis://" + f.addr, Prefix: "anitrakt:"}).Write(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got := f.hashes["anitrakt:trakt:shows:111"]; got["1"] != "" || got["7"] != "1" {
+		t.Errorf("anitrakt:trakt:shows:111 = %v, want only the other show left", got)
+	}
+	if got, ok := f.hashes["anitrakt:imdb:tt0000001"]; ok {
+		t.Errorf("anitrakt:imdb:tt0000001 = %v, want it gone with its only entry", got)
+	}
+	if got := f.hashes["anitrakt:trakt:shows:30857"]; got["1"] != "1" {
+		t.Errorf("anitrakt:trakt:shows:30857 = %v, want MAL ID 1", got)
+	}
+	if got := f.hashes["anitrakt:imdb:tt0213338"]; got["1"] != "tv" {
+		t.Errorf("anitrakt:imdb:tt0213338 = %v, want MAL ID 1", got)
+	}
+	// The interfered transaction was dropped and the round retried
+	watches := 0
+	for _, cmd := range f.commands {
+		if cmd[0] == "WATCH" {
+			watches++
+		}
+	}
+	if watches != 2 {
+		t.Errorf("WATCHed %d times, want a retry after the dropped EXEC", watches)
+	}
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resources accounts where a run's time and API quota went: requests,
+// retries, bytes and latency per endpoint, time spent blocked on each rate
+// limiter, and wall time per processing phase. It is package-level rather
+// than part of Config, because the shared transport and the rate limiters
+// record into it; StartResources resets it for each run.
+type Resources struct {
+	mu         sync.Mutex
+	start      time.Time
+	endpoints  map[string]*EndpointUsage
+	waits      map[string]time.Duration // by rate limiter
+	phases     map[string]time.Duration
+	phase      string
+	phaseStart time.Time
+}
+
+// EndpointUsage is the traffic of one endpoint (host and first path segment)
+type EndpointUsage struct {
+	Requests  int64         `json:"requests"`
+	Retries   int64         `json:"retries"`    // rate-limit retries by RetryWithBackoff
+	Bytes     int64         `json:"bytes"`      // response bodies as received
+	Latency   time.Duration `json:"latency_ns"` // until the response headers arrived
+	RetryWait time.Duration `json:"retry_wait_ns"`
+}
+
+// ResourceReport is the -resources-file export of a run
+type ResourceReport struct {
+	GeneratedAt    string                   `json:"generated_at"`
+	WallTime       time.Duration            `json:"wall_time_ns"`
+	Endpoints      map[string]EndpointUsage `json:"endpoints"`
+	RateLimitWaits map[string]time.Duration `json:"rate_limit_waits_ns"`
+	Phases         map[string]time.Duration `json:"phases_ns"`
+	Cache          map[string]CacheCounter  `json:"cache"`
+}
+
+var resources = newResources()
+
+func newResources() *Resources {
+	return &Resources{
+		start:     time.Now(),
+		endpoints: make(map[string]*EndpointUsage),
+		waits:     make(map[string]time.Duration),
+		phases:    make(map[string]time.Duration),
+	}
+}
+
+// StartResources starts accounting a new run
+func StartResources() {
+	fresh := newResources()
+	resources.mu.Lock()
+	defer resources.mu.Unlock()
+	resources.start = fresh.start
+	resources.endpoints, resources.waits, resources.phases = fresh.endpoints, fresh.waits, fresh.phases
+	resources.phase = ""
+}
+
+// CurrentResources reports the accounting of the current run
+func CurrentResources(cache map[string]CacheCounter) ResourceReport {
+	return resources.Report(cache)
+}
+
+// versionSegment matches API version prefixes such as "3" (TMDB) or "v4"
+var versionSegment = regexp.MustCompile(`^v?\d+$`)
+
+// resourceEndpoint names a request's endpoint: its host and first path
+// segment after any version prefix, e.g. api.trakt.tv/shows
+func resourceEndpoint(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return "unknown"
+	}
+	for _, segment := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+		if segment != "" && !versionSegment.MatchString(segment) {
+			return req.URL.Host + "/" + segment
+		}
+	}
+	return req.URL.Host
+}
+
+// endpoint returns the usage of an endpoint; callers hold r.mu
+func (r *Resources) endpoint(name string) *EndpointUsage {
+	usage, ok := r.endpoints[name]
+	if !ok {
+		usage = &EndpointUsage{}
+		r.endpoints[name] = usage
+	}
+	return usage
+}
+
+// recordRequest counts a request that got its response headers after latency
+func (r *Resources) recordRequest(endpoint string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage := r.endpoint(endpoint)
+	usage.Requests++
+	usage.Latency += latency
+}
+
+// recordBytes counts response body bytes
+func (r *Resources) recordBytes(endpoint string, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoint(endpoint).Bytes += n
+}
+
+// recordRetry counts a rate-limit retry and the backoff before it
+func (r *Resources) recordRetry(endpoint string, backoff time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage := r.endpoint(endpoint)
+	usage.Retries++
+	usage.RetryWait += backoff
+}
+
+// recordWait adds time spent blocked on a rate limiter
+func (r *Resources) recordWait(limiter string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.waits[limiter] += d
+}
+
+// enterPhase charges the time since the last phase change to that phase.
+// "" leaves phases, e.g. between entries.
+func (r *Resources) enterPhase(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.phase != "" {
+		r.phases[r.phase] += now.Sub(r.phaseStart)
+	}
+	r.phase, r.phaseStart = name, now
+}
+
+// Report snapshots the accounting of the run so far
+func (r *Resources) Report(cache map[string]CacheCounter) ResourceReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := ResourceReport{
+		GeneratedAt:    time.Now().UTC().Format(time.RFC3339),
+		WallTime:       time.Since(r.start),
+		Endpoints:      make(map[string]EndpointUsage, len(r.endpoints)),
+		RateLimitWaits: make(map[string]time.Duration, len(r.waits)),
+		Phases:         make(map[string]time.Duration, len(r.phases)),
+		Cache:          cache,
+	}
+	for name, usage := range r.endpoints {
+		report.Endpoints[name] = *usage
+	}
+	for name, d := range r.waits {
+		report.RateLimitWaits[name] = d
+	}
+	for name, d := range r.phases {
+		report.Phases[name] = d
+	}
+	if r.phase != "" {
+		report.Phases[r.phase] += time.Since(r.phaseStart)
+	}
+	return report
+}
+
+// countingTransport records every request of the shared transport
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	endpoint := resourceEndpoint(req)
+	resources.recordRequest(endpoint, time.Since(start))
+	activeBudget.Load().addRequest(req.URL.Host)
+	if err == nil && resp.Body != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, endpoint: endpoint}
+	}
+	return resp, err
+}
+
+// countingBody counts the bytes read from a response body
+type countingBody struct {
+	io.ReadCloser
+	endpoint string
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		resources.recordBytes(b.endpoint, int64(n))
+	}
+	return n, err
+}
+
+// OutputResources prints the resource summary of a run
+func OutputResources(report ResourceReport) {
+	if len(report.Endpoints) == 0 && len(report.Phases) == 0 {
+		return
+	}
+	writeSummary("\n## Resources - Summary\n\n" + FormatResources(report))
+}
+
+// FormatResources renders a resource report as markdown tables
+func FormatResources(report ResourceReport) string {
+	output := "| Endpoint | Requests | Retries | Retry Wait | Avg Latency | Downloaded |\n|----------|----------|---------|------------|-------------|------------|\n"
+	var total EndpointUsage
+	for _, name := range sortedKeys(report.Endpoints) {
+		usage := report.Endpoints[name]
+		output += formatEndpointRow(name, usage)
+		total.Requests += usage.Requests
+		total.Retries += usage.Retries
+		total.RetryWait += usage.RetryWait
+		total.Latency += usage.Latency
+		total.Bytes += usage.Bytes
+	}
+	output += formatEndpointRow("**Total**", total)
+
+	if len(report.RateLimitWaits) > 0 {
+		output += "\n| Rate Limiter | Waited |\n|--------------|--------|\n"
+		for _, name := range sortedKeys(report.RateLimitWaits) {
+			output += fmt.Sprintf("| %s | %s |\n", name, formatDuration(report.RateLimitWaits[name]))
+		}
+	}
+
+	if len(report.Phases) > 0 {
+		output += "\n| Phase | Wall Time | Share |\n|-------|-----------|-------|\n"
+		for _, name := range sortedKeys(report.Phases) {
+			d := report.Phases[name]
+			output += fmt.Sprintf("| %s | %s | %.1f%% |\n", name, formatDuration(d), float64(d)/float64(max(report.WallTime, 1))*100)
+		}
+	}
+
+	var hits, lookups int64
+	for _, counter := range report.Cache {
+		hits += counter.MemoryHits + counter.DiskHits
+		lookups += counter.MemoryHits + counter.DiskHits + counter.Misses
+	}
+	output += fmt.Sprintf("\nWall time %s", formatDuration(report.WallTime))
+	if lookups > 0 {
+		output += fmt.Sprintf(", cache hit rate %.1f%%", float64(hits)/float64(lookups)*100)
+	}
+	return output + ".\n"
+}
+
+// formatEndpointRow renders one row of the endpoint table
+func formatEndpointRow(name string, usage EndpointUsage) string {
+	avg := "-"
+	if usage.Requests > 0 {
+		avg = formatDuration(usage.Latency / time.Duration(usage.Requests))
+	}
+	return fmt.Sprintf("| %s | %d | %d | %s | %s | %s |\n", name, usage.Requests, usage.Retries, formatDuration(usage.RetryWait), avg, formatBytes(usage.Bytes))
+}
+
+// formatDuration rounds a duration for the summary tables
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// SaveResources writes a resource report to path
+func SaveResources(path string, report ResourceReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return SaveJSON(path, report)
+}
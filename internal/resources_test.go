@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResourceEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"https://api.trakt.tv/shows/1/seasons":        "api.trakt.tv/shows",
+		"https://api.themoviedb.org/3/tv/1":           "api.themoviedb.org/tv",
+		"https://api4.thetvdb.com/v4/series/1":        "api4.thetvdb.com/series",
+		"https://letterboxd.com/tmdb/550/":            "letterboxd.com/tmdb",
+		"https://query.wikidata.org/sparql?query=foo": "query.wikidata.org/sparql",
+		"https://api.trakt.tv/":                       "api.trakt.tv",
+	}
+	for raw, want := range cases {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resourceEndpoint(&http.Request{URL: u}); got != want {
+			t.Errorf("resourceEndpoint(%s) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestCountingTransport(t *testing.T) {
+	StartResources()
+	t.Cleanup(StartResources)
+
+	client := &http.Client{Transport: countingTransport{base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("0123456789")), Request: req}, nil
+	})}}
+	for range 2 {
+		resp, err := client.Get("https://api.trakt.tv/shows/1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	resources.recordRetry("api.trakt.tv/shows", time.Second)
+
+	usage := CurrentResources(nil).Endpoints["api.trakt.tv/shows"]
+	if usage.Requests != 2 || usage.Bytes != 20 || usage.Retries != 1 || usage.RetryWait != time.Second {
+		t.Errorf("usage = %+v, want 2 requests, 20 bytes, 1 retry of 1s", usage)
+	}
+}
+
+func TestEnterPhase(t *testing.T) {
+	StartResources()
+	t.Cleanup(StartResources)
+
+	resources.enterPhase("trakt")
+	time.Sleep(5 * time.Millisecond)
+	resources.enterPhase("")
+	time.Sleep(5 * time.Millisecond)
+	resources.enterPhase("trakt")
+	time.Sleep(5 * time.Millisecond)
+	resources.enterPhase("save")
+
+	report := CurrentResources(nil)
+	if got := report.Phases["trakt"]; got < 10*time.Millisecond || got >= report.WallTime {
+		t.Errorf("trakt phase = %v, want at least 10ms and less than the wall time %v", got, report.WallTime)
+	}
+	if _, ok := report.Phases["save"]; !ok {
+		t.Error("the open save phase is missing from the report")
+	}
+	if _, ok := report.Phases[""]; ok {
+		t.Error("time outside phases was charged to a phase")
+	}
+}
+
+func TestFormatResources(t *testing.T) {
+	report := ResourceReport{
+		WallTime: 10 * time.Second,
+		Endpoints: map[string]EndpointUsage{
+			"api.trakt.tv/shows":  {Requests: 4, Retries: 1, Bytes: 2048, Latency: 800 * time.Millisecond, RetryWait: 2 * time.Second},
+			"letterboxd.com/tmdb": {Requests: 1, Bytes: 1024, Latency: 100 * time.Millisecond},
+		},
+		RateLimitWaits: map[string]time.Duration{"trakt": 3 * time.Second},
+		Phases:         map[string]time.Duration{"trakt": 5 * time.Second},
+		Cache:          map[string]CacheCounter{"shows": {DiskHits: 3, Misses: 1}},
+	}
+	output := FormatResources(report)
+	for _, want := range []string{
+		"| api.trakt.tv/shows | 4 | 1 | 2s | 200ms |",
+		"| **Total** | 5 | 1 | 2s | 180ms |",
+		"| trakt | 3s |",
+		"| trakt | 5s | 50.0% |",
+		"cache hit rate 75.0%",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("summary is missing %q:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetryEntry is an input entry whose last attempt failed with an error other
+// than 404, kept for -retry-failed
+type RetryEntry struct {
+	MalID    int    `json:"mal_id"`
+	Title    string `json:"title"`
+	TraktID  int    `json:"trakt_id"`
+	Season   int    `json:"season,omitempty"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+	FailedAt string `json:"failed_at"`
+}
+
+func (e RetryEntry) key() [3]int { return [3]int{e.MalID, e.TraktID, e.Season} }
+
+// retryPath is the retry queue kept next to an output file
+func retryPath(outputFile string) string {
+	return filepath.Join(layout.RetryDir(), "retry_"+filepath.Base(outputFile))
+}
+
+// retryQueue tracks the failed entries of an output across runs: a run adds
+// the entries that fail and drops the ones it gets an answer for (a result
+// or a 404). Entries a run does not reach stay queued.
+type retryQueue struct {
+	path    string // "" for streamed output, which keeps no queue
+	entries map[[3]int]RetryEntry
+	changed bool
+}
+
+// loadRetryQueue loads the retry queue of outputFile
+func loadRetryQueue(outputFile string) *retryQueue {
+	q := &retryQueue{entries: make(map[[3]int]RetryEntry)}
+	if outputFile == StdioPath {
+		return q
+	}
+	q.path = retryPath(outputFile)
+	var entries []RetryEntry
+	LoadJSONOptional(q.path, &entries)
+	for _, entry := range entries {
+		q.entries[entry.key()] = entry
+	}
+	return q
+}
+
+// retryInputs returns the input entries that are queued for a retry, in
+// input order. Queued entries no longer in the input are dropped.
+func retryInputs[T inputEntry[T]](q *retryQueue, inputs []T) []T {
+	var picked []T
+	inInput := make(map[[3]int]bool)
+	for _, input := range inputs {
+		key := input.inputKey()
+		inInput[key] = true
+		if _, queued := q.entries[key]; queued {
+			picked = append(picked, input)
+		}
+	}
+	for key := range q.entries {
+		if !inInput[key] {
+			delete(q.entries, key)
+			q.changed = true
+		}
+	}
+	return picked
+}
+
+// Fail queues an entry that failed, counting its attempts
+func (q *retryQueue) Fail(key [3]int, title string, err error) {
+	entry := q.entries[key]
+	entry.MalID, entry.TraktID, entry.Season = key[0], key[1], key[2]
+	entry.Title = title
+	entry.Error = err.Error()
+	entry.Attempts++
+	entry.FailedAt = time.Now().UTC().Format(time.RFC3339)
+	q.entries[key] = entry
+	q.changed = true
+}
+
+// Done drops an entry that got an answer from Trakt
+func (q *retryQueue) Done(key [3]int) {
+	if _, queued := q.entries[key]; queued {
+		delete(q.entries, key)
+		q.changed = true
+	}
+}
+
+// Len is the number of queued entries
+func (q *retryQueue) Len() int {
+	return len(q.entries)
+}
+
+// Save writes the queue sorted by MAL ID when the run changed it, and
+// removes the file once the queue is empty
+func (q *retryQueue) Save() error {
+	if q.path == "" || !q.changed {
+		return nil
+	}
+	if len(q.entries) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	entries := make([]RetryEntry, 0, len(q.entries))
+	for _, entry := range q.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].MalID != entries[j].MalID {
+			return entries[i].MalID < entries[j].MalID
+		}
+		if entries[i].TraktID != entries[j].TraktID {
+			return entries[i].TraktID < entries[j].TraktID
+		}
+		return entries[i].Season < entries[j].Season
+	})
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return err
+	}
+	return SaveJSON(q.path, entries)
+}
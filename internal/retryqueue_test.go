@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRetryQueue(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "json/output/tv_ex.json"
+	a := InputShow{MalID: 1, TraktID: 10, Season: 1, Title: "A"}
+	b := InputShow{MalID: 2, TraktID: 20, Season: 1, Title: "B"}
+	c := InputShow{MalID: 3, TraktID: 30, Season: 1, Title: "C"}
+
+	q := loadRetryQueue(outputFile)
+	q.Fail(a.inputKey(), a.Title, errors.New("API error: 502"))
+	q.Fail(b.inputKey(), b.Title, errors.New("API error: 503"))
+	q.Fail(c.inputKey(), c.Title, errors.New("API error: 504"))
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// c left the input; a fails again, b is answered
+	q = loadRetryQueue(outputFile)
+	inputs := retryInputs(q, []InputShow{{MalID: 9, TraktID: 90}, b, a})
+	if len(inputs) != 2 || inputs[0].MalID != 2 || inputs[1].MalID != 1 {
+		t.Fatalf("retryInputs = %+v, want MAL IDs 2 and 1 in input order", inputs)
+	}
+	q.Fail(a.inputKey(), a.Title, errors.New("API error: 500"))
+	q.Done(b.inputKey())
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []RetryEntry
+	if err := LoadJSON(retryPath(outputFile), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].MalID != 1 || entries[0].Attempts != 2 || entries[0].Error != "API error: 500" {
+		t.Fatalf("queue = %+v, want MAL ID 1 with 2 attempts", entries)
+	}
+
+	q = loadRetryQueue(outputFile)
+	q.Done(a.inputKey())
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(retryPath(outputFile)); !os.IsNotExist(err) {
+		t.Errorf("empty queue file still exists: %v", err)
+	}
+}
+
+func TestRetryQueueStdio(t *testing.T) {
+	t.Chdir(t.TempDir())
+	q := loadRetryQueue(StdioPath)
+	q.Fail([3]int{1, 10, 0}, "A", errors.New("API error: 502"))
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("json"); !os.IsNotExist(err) {
+		t.Errorf("streamed run wrote a retry queue: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCandidates is how many Trakt search results review offers per entry
+const maxCandidates = 5
+
+// reviewItem is an entry waiting for a decision in `review`
+type reviewItem struct {
+	MalID  int
+	Title  string
+	Reason string // why it is up for review
+}
+
+// mappedEntry is an output entry with the titles review compares
+type mappedEntry struct {
+	MalID      int
+	Title      string
+	TraktTitle string
+}
+
+// reviewQueue lists the entries to review: not-found entries first, then
+// mapped entries whose Trakt title shares no word with the MAL title. Entries
+// that already have an override were reviewed before and are left out.
+func reviewQueue(notFound []NotFoundEntry, mapped []mappedEntry, overrides map[int]*Override) []reviewItem {
+	var queue []reviewItem
+	for _, entry := range notFound {
+		if overrides[entry.MalID] == nil {
+			queue = append(queue, reviewItem{MalID: entry.MalID, Title: entry.Title, Reason: "not found on Trakt"})
+		}
+	}
+	for _, entry := range mapped {
+		if overrides[entry.MalID] == nil && lowConfidence(entry.Title, entry.TraktTitle) {
+			queue = append(queue, reviewItem{MalID: entry.MalID, Title: entry.Title, Reason: fmt.Sprintf("mapped to %q", entry.TraktTitle)})
+		}
+	}
+	return queue
+}
+
+// lowConfidence reports whether a MAL title and the Trakt title it was mapped
+// to have no word in common
+func lowConfidence(malTitle, traktTitle string) bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(malTitle) {
+		if w := normalizeTitle(word); w != "" {
+			words[w] = true
+		}
+	}
+	for _, word := range strings.Fields(traktTitle) {
+		if words[normalizeTitle(word)] {
+			return false
+		}
+	}
+	return len(words) > 0
+}
+
+// mappingOverride records a review decision mapping an entry to a Trakt entry
+func mappingOverride(item reviewItem, result TraktSearchResult) Override {
+	title, id, slug := searchResultIDs(result)
+	trakt, _ := json.Marshal(map[string]any{"title": title, "id": id, "slug": slug})
+	raw := json.RawMessage(trakt)
+	return Override{
+		MalID:       item.MalID,
+		Description: fmt.Sprintf("Mapped to %s (Trakt ID %d) in review", title, id),
+		Trakt:       &raw,
+	}
+}
+
+// searchResultIDs returns the title, Trakt ID and slug of a search result
+func searchResultIDs(result TraktSearchResult) (string, int, string) {
+	if result.Show != nil {
+		return result.Show.Title, result.Show.IDs.Trakt, result.Show.IDs.Slug
+	}
+	if result.Movie != nil {
+		return result.Movie.Title, result.Movie.IDs.Trakt, result.Movie.IDs.Slug
+	}
+	return "", 0, ""
+}
+
+// searchResultYear returns the release year of a search result
+func searchResultYear(result TraktSearchResult) int {
+	if result.Show != nil {
+		return result.Show.Year
+	}
+	if result.Movie != nil {
+		return result.Movie.Year
+	}
+	return 0
+}
+
+// RunReview implements the `review` subcommand: it walks through not-found
+// and low-confidence entries, offers Trakt search candidates and records the
+// maintainer's decision as an override
+func RunReview(args []string) error {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	mediaType := fs.String("type", "tv", "Entries to review: tv or movies")
+	output := fs.String("output", "", "Output file (default json/output/<type>_ex.json)")
+	apiKey := fs.String("api-key", "", "Trakt API key (prompted for when empty)")
+	lowConf := fs.Bool("low-confidence", true, "Also review mapped entries whose Trakt title shares no word with the MAL title")
+	fs.Parse(args)
+
+	searchType := map[string]string{"tv": "show", "movies": "movie"}[*mediaType]
+	if searchType == "" {
+		return fmt.Errorf("unsupported -type %q (expected tv or movies)", *mediaType)
+	}
+	if *output == "" {
+		*output = defaultOutput(*mediaType)
+	}
+
+	var notFound []NotFoundEntry
+	LoadJSONOptional(notFoundPath(*output), &notFound)
+	var mapped []mappedEntry
+	if *lowConf {
+		var entries []struct {
+			MyAnimeList struct {
+				Title string `json:"title"`
+				ID    int    `json:"id"`
+			} `json:"myanimelist"`
+			Trakt struct {
+				Title string `json:"title"`
+			} `json:"trakt"`
+		}
+		LoadJSONOptional(*output, &entries)
+		for _, entry := range entries {
+			mapped = append(mapped, mappedEntry{MalID: entry.MyAnimeList.ID, Title: entry.MyAnimeList.Title, TraktTitle: entry.Trakt.Title})
+		}
+	}
+
+	queue := reviewQueue(notFound, mapped, LoadOverrides(*mediaType))
+	if len(queue) == 0 {
+		fmt.Println("Nothing to review")
+		return nil
+	}
+
+	if *apiKey == "" {
+		*apiKey = PromptForAPIKey()
+	}
+	config := Config{
+		APIKey:      *apiKey,
+		TraktAPIURL: os.Getenv("TRAKT_API_URL"),
+		TempDir:     defaultCacheDir(),
+		Cache:       NewCache(0),
+		RateLimiter: NewRateLimiter(),
+	}
+	r := reviewer{
+		config:     config,
+		client:     NewHTTPClient(30 * time.Second),
+		in:         bufio.NewScanner(os.Stdin),
+		out:        os.Stdout,
+		mediaType:  *mediaType,
+		searchType: searchType,
+		output:     *output,
+	}
+	LoadJSONOptional(overridesPath(*mediaType), &r.overrides)
+
+	for i, item := range queue {
+		fmt.Fprintf(r.out, "\n[%d/%d] %s (MAL ID %d) - %s\n", i+1, len(queue), item.Title, item.MalID, item.Reason)
+		if !r.review(item) {
+			break
+		}
+	}
+	fmt.Fprintf(r.out, "\n%d mapped, %d marked absent\n", r.mapped, r.absent)
+	return nil
+}
+
+// reviewer holds the state of an interactive review session
+type reviewer struct {
+	config     Config
+	client     *http.Client
+	in         *bufio.Scanner
+	out        io.Writer
+	mediaType  string
+	searchType string
+	output     string
+	overrides  []Override
+	mapped     int
+	absent     int
+}
+
+// review asks for a decision on one entry; it returns false when the
+// maintainer quits
+func (r *reviewer) review(item reviewItem) bool {
+	query := item.Title
+	for {
+		results, err := SearchTrakt(r.client, r.config, query, r.searchType)
+		if err != nil {
+			fmt.Fprintf(r.out, "  search failed: %v\n", err)
+		}
+		results = results[:min(len(results), maxCandidates)]
+		for n, result := range results {
+			title, id, slug := searchResultIDs(result)
+			fmt.Fprintf(r.out, "  %d) %s (%d) - Trakt ID %d, %s\n", n+1, title, searchResultYear(result), id, slug)
+		}
+		if len(results) == 0 {
+			fmt.Fprintf(r.out, "  no candidates for %q\n", query)
+		}
+
+		fmt.Fprint(r.out, "  [number] map, a absent, /query search again, s skip, q quit: ")
+		if !r.in.Scan() {
+			return false
+		}
+		answer := strings.TrimSpace(r.in.Text())
+		switch {
+		case answer == "q":
+			return false
+		case answer == "" || answer == "s":
+			return true
+		case answer == "a":
+			r.save(Override{MalID: item.MalID, Description: "Not on Trakt (marked in review)", Ignore: true})
+			r.absent++
+			return true
+		case strings.HasPrefix(answer, "/"):
+			query = strings.TrimSpace(answer[1:])
+		default:
+			n, err := strconv.Atoi(answer)
+			if err != nil || n < 1 || n > len(results) {
+				fmt.Fprintf(r.out, "  unknown answer %q\n", answer)
+				continue
+			}
+			r.save(mappingOverride(item, results[n-1]))
+			r.dropNotFound(item.MalID)
+			r.mapped++
+			return true
+		}
+	}
+}
+
+// save records a decision in the override file right away, so quitting
+// keeps the decisions made so far
+func (r *reviewer) save(override Override) {
+	r.overrides = upsertOverride(r.overrides, override)
+	os.MkdirAll(layout.OverridesDir(), 0755)
+	if err := SaveJSON(overridesPath(r.mediaType), r.overrides); err != nil {
+		fmt.Fprintf(r.out, "  could not save the decision: %v\n", err)
+	}
+}
+
+// dropNotFound removes a mapped entry from the not-found list, so the next
+// run fetches it under its override
+func (r *reviewer) dropNotFound(malID int) {
+	notExistFile := notFoundPath(r.output)
+	var notFound []NotFoundEntry
+	LoadJSONOptional(notExistFile, &notFound)
+	kept := notFound[:0]
+	for _, entry := range notFound {
+		if entry.MalID != malID {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) < len(notFound) {
+		if err := SaveJSON(notExistFile, kept); err != nil {
+			fmt.Fprintf(r.out, "  could not update %s: %v\n", notExistFile, err)
+		}
+	}
+}
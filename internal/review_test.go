@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestReviewQueue(t *testing.T) {
+	notFound := []NotFoundEntry{{MalID: 1, Title: "Missing"}, {MalID: 2, Title: "Reviewed"}}
+	mapped := []mappedEntry{
+		{MalID: 3, Title: "Shingeki no Kyojin", TraktTitle: "Attack on Titan"},
+		{MalID: 4, Title: "Kimi no Na wa.", TraktTitle: "Your Name. (Kimi no Na wa)"},
+	}
+	overrides := map[int]*Override{2: {MalID: 2, Ignore: true}}
+
+	queue := reviewQueue(notFound, mapped, overrides)
+	if len(queue) != 2 || queue[0].MalID != 1 || queue[1].MalID != 3 {
+		t.Fatalf("reviewQueue = %+v, want MAL IDs 1 and 3", queue)
+	}
+}
+
+func TestMappingOverride(t *testing.T) {
+	show := &TraktShow{Title: "Attack on Titan"}
+	show.IDs.Trakt = 1420
+	show.IDs.Slug = "attack-on-titan"
+	override := mappingOverride(reviewItem{MalID: 16498}, TraktSearchResult{Type: "show", Show: show})
+	if got := override.traktID(); got != 1420 {
+		t.Errorf("traktID() = %d, want 1420", got)
+	}
+
+	var trakt map[string]any
+	json.Unmarshal(*override.Trakt, &trakt)
+	if trakt["slug"] != "attack-on-titan" {
+		t.Errorf("override trakt = %v", trakt)
+	}
+
+	overrides := upsertOverride([]Override{{MalID: 16498, Ignore: true}}, override)
+	if len(overrides) != 1 || overrides[0].Ignore {
+		t.Errorf("upsertOverride did not replace the earlier decision: %+v", overrides)
+	}
+}
+
+func TestUpdateReviewQueue(t *testing.T) {
+	t.Chdir(t.TempDir())
+	config := Config{ReviewQueue: "json/review/review_queue.json"}
+	output := "json/output/tv_ex.json"
+
+	os.MkdirAll("json/overrides", 0755)
+	os.MkdirAll("json/not_found", 0755)
+	os.MkdirAll("json/review", 0755)
+	SaveJSON(notFoundPath(output), []NotFoundEntry{{MalID: 1, Title: "Kept"}, {MalID: 2, Title: "Resolved"}})
+	SaveJSON(overridesPath("tv"), []Override{{MalID: 2, Ignore: true}})
+	SaveJSON(config.ReviewQueue, []ReviewQueueEntry{
+		{MalID: 1, Title: "Kept", Type: "tv", Candidates: []ReviewCandidate{{TraktID: 10}}},
+		{MalID: 2, Title: "Resolved", Type: "tv"},
+		{MalID: 3, Title: "Movie", Type: "movies"},
+	})
+
+	var shows []OutputShow
+	json.Unmarshal([]byte(`[
+		{"myanimelist": {"id": 4, "episodes": 12}, "trakt": {"id": 40, "season": {"number": 2, "episode_count": 25}}, "quality": "episode-count-mismatch"},
+		{"myanimelist": {"id": 5, "episodes": 12}, "trakt": {"id": 50, "season": {"number": 1, "episode_count": 12}}, "quality": "exact-id"}
+	]`), &shows)
+	results := map[int]OutputShow{4: shows[0], 5: shows[1]}
+
+	// Every remaining entry has candidates already, so nothing is searched
+	UpdateReviewQueue(nil, config, "tv", output, results)
+
+	var queue []ReviewQueueEntry
+	LoadJSON(config.ReviewQueue, &queue)
+	if len(queue) != 3 || queue[0].MalID != 1 || queue[0].Candidates[0].TraktID != 10 || queue[2].Type != "movies" {
+		t.Fatalf("queue = %+v", queue)
+	}
+	if mismatch := queue[1]; mismatch.MalID != 4 || mismatch.TraktID != 40 || mismatch.Season != 2 || mismatch.Reason == "" {
+		t.Errorf("episode count mismatch entry = %+v", mismatch)
+	}
+}
+
+func TestReviewCandidatesKeepBest(t *testing.T) {
+	var results []TraktSearchResult
+	for i := range 7 {
+		show := &TraktShow{}
+		show.IDs.Trakt = i + 1
+		results = append(results, TraktSearchResult{Score: float64(i), Show: show})
+	}
+	candidates := reviewCandidates(results)
+	if len(candidates) != maxCandidates || candidates[0].TraktID != 7 {
+		t.Errorf("reviewCandidates = %+v", candidates)
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ReviewCandidate is a Trakt search result offered for a not-found entry
+type ReviewCandidate struct {
+	TraktID int     `json:"trakt_id"`
+	Slug    string  `json:"slug"`
+	Title   string  `json:"title"`
+	Year    int     `json:"year,omitempty"`
+	Score   float64 `json:"score"`
+}
+
+// ReviewQueueEntry is a not-found entry with its best Trakt search
+// candidates, or a mapped show whose season looks wrong. Fixes are made by
+// adding an override for the MAL ID.
+type ReviewQueueEntry struct {
+	MalID      int               `json:"mal_id"`
+	Title      string            `json:"title"`
+	Type       string            `json:"type"` // "tv" or "movies"
+	Candidates []ReviewCandidate `json:"candidates"`
+	// Reason, TraktID and Season describe the current mapping of a mapped
+	// entry; not-found entries have none
+	Reason  string `json:"reason,omitempty"`
+	TraktID int    `json:"trakt_id,omitempty"`
+	Season  int    `json:"season,omitempty"`
+}
+
+// notFoundPath is the not-found list kept next to an output file
+func notFoundPath(outputFile string) string {
+	return filepath.Join(layout.NotFoundDir(), "not_exist_"+filepath.Base(outputFile))
+}
+
+// UpdateReviewQueue rewrites the mediaType part of config.ReviewQueue from
+// the current not-found list of outputFile and the shows whose episode
+// counts disagree with their Trakt season (see CheckEpisodeCounts). Entries
+// with an override are resolved and dropped; candidates already in the queue
+// are reused, so only new entries cost a Trakt search.
+func UpdateReviewQueue(client *http.Client, config Config, mediaType, outputFile string, shows map[int]OutputShow) {
+	if config.ReviewQueue == "" || outputFile == StdioPath {
+		return
+	}
+	var notFound []NotFoundEntry
+	LoadJSONOptional(notFoundPath(outputFile), &notFound)
+	var queue []ReviewQueueEntry
+	LoadJSONOptional(config.ReviewQueue, &queue)
+
+	previous := make(map[int]ReviewQueueEntry)
+	kept := queue[:0]
+	for _, entry := range queue {
+		if entry.Type == mediaType {
+			previous[entry.MalID] = entry
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	queue = kept
+
+	searchType := map[string]string{"tv": "show", "movies": "movie"}[mediaType]
+	overrides := LoadOverrides(mediaType)
+	for _, entry := range notFound {
+		if overrides[entry.MalID] != nil {
+			continue
+		}
+		if known, ok := previous[entry.MalID]; ok {
+			queue = append(queue, known)
+			continue
+		}
+		results, err := SearchTrakt(client, config, entry.Title, searchType)
+		if err != nil {
+			if config.Verbose {
+				Debugf("\n    - review queue search for %s (MAL ID %d) failed: %v", entry.Title, entry.MalID, err)
+			}
+			continue
+		}
+		queue = append(queue, ReviewQueueEntry{
+			MalID:      entry.MalID,
+			Title:      entry.Title,
+			Type:       mediaType,
+			Candidates: reviewCandidates(results),
+		})
+	}
+	for _, show := range shows {
+		season := show.Trakt.Season
+		if show.Quality != QualityEpisodeMismatch || season == nil || overrides[show.MyAnimeList.ID] != nil {
+			continue
+		}
+		queue = append(queue, ReviewQueueEntry{
+			MalID:      show.MyAnimeList.ID,
+			Title:      show.MyAnimeList.Title,
+			Type:       mediaType,
+			Candidates: []ReviewCandidate{},
+			Reason: fmt.Sprintf("Episode count mismatch: MAL has %d episodes, Trakt season %d has %d",
+				show.MyAnimeList.Episodes, season.Number, season.EpisodeCount),
+			TraktID: show.Trakt.ID,
+			Season:  season.Number,
+		})
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		if queue[i].Type != queue[j].Type {
+			return queue[i].Type > queue[j].Type // tv before movies
+		}
+		return queue[i].MalID < queue[j].MalID
+	})
+	os.MkdirAll(filepath.Dir(config.ReviewQueue), 0755)
+	if err := SaveJSON(config.ReviewQueue, queue); err != nil {
+		log.Printf("Warning: Failed to save review queue: %v", err)
+	}
+}
+
+// reviewCandidates keeps the maxCandidates best search results
+func reviewCandidates(results []TraktSearchResult) []ReviewCandidate {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	candidates := make([]ReviewCandidate, 0, maxCandidates)
+	for _, result := range results[:min(len(results), maxCandidates)] {
+		title, id, slug := searchResultIDs(result)
+		candidates = append(candidates, ReviewCandidate{
+			TraktID: id,
+			Slug:    slug,
+			Title:   title,
+			Year:    searchResultYear(result),
+			Score:   result.Score,
+		})
+	}
+	return candidates
+}
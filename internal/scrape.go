@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AniTraktSiteScheme prefixes an input scraped from the AniTrakt site:
+// "anitrakt-site:" for DefaultAniTraktSite, "anitrakt-site:<url>" for a
+// mirror
+const AniTraktSiteScheme = "anitrakt-site:"
+
+// DefaultAniTraktSite is the AniTrakt index the upstream mappings come from
+const DefaultAniTraktSite = "https://anitrakt.huere.net/db"
+
+// aniTraktIndexPages are the index pages of the AniTrakt site per media type
+var aniTraktIndexPages = map[string]string{
+	"tv":     "db_index_shows.php",
+	"movies": "db_index_movies.php",
+}
+
+var (
+	tableRowPattern = regexp.MustCompile(`(?is)<tr[\s>].*?</tr>`)
+	malLinkPattern  = regexp.MustCompile(`(?is)<a[^>]+href="https?://myanimelist\.net/anime/(\d+)[^"]*"[^>]*>(.*?)</a>`)
+	// The Trakt link carries either the numeric ID or the slug
+	traktLinkPattern = regexp.MustCompile(`(?i)href="https?://trakt\.tv/(?:shows|movies)/([^/"?#]+)(?:/seasons/(\d+))?`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+	nonSlugPattern   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// AniTraktSiteSource scrapes the AniTrakt index pages, the same data the
+// upstream index is built from
+type AniTraktSiteSource struct {
+	Site      string // base URL; "" for DefaultAniTraktSite
+	MediaType string // "tv" or "movies"
+	Client    *http.Client
+}
+
+// URL returns the index page of the source's media type
+func (s AniTraktSiteSource) URL() string {
+	site := s.Site
+	if site == "" {
+		site = DefaultAniTraktSite
+	}
+	return strings.TrimSuffix(site, "/") + "/" + aniTraktIndexPages[s.MediaType]
+}
+
+func (s AniTraktSiteSource) Load() ([]InputShow, []InputMovie, error) {
+	client := s.Client
+	if client == nil {
+		client = NewHTTPClient(5 * time.Minute)
+	}
+	body, err := fetchURL(client, s.URL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", s.URL(), err)
+	}
+	defer body.Close()
+	page, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", s.URL(), err)
+	}
+	shows, movies := parseAniTraktIndex(string(page), s.MediaType)
+	if len(shows) == 0 && len(movies) == 0 {
+		return nil, nil, fmt.Errorf("no mappings found on %s; has the page layout changed?", s.URL())
+	}
+	return shows, movies, nil
+}
+
+// parseAniTraktIndex reads the mappings of an index page. Each table row
+// links one Trakt item (by ID or slug, with the season for shows) and the
+// MAL entries mapped to it. Shows without a season link map to season 1.
+// Rows are sorted by MAL ID, and a MAL entry mapped to the same item twice
+// is kept once.
+func parseAniTraktIndex(page, mediaType string) ([]InputShow, []InputMovie) {
+	var shows []InputShow
+	var movies []InputMovie
+	seen := make(map[[3]int]bool)
+	for _, row := range tableRowPattern.FindAllString(page, -1) {
+		trakt := traktLinkPattern.FindStringSubmatch(row)
+		if trakt == nil {
+			continue
+		}
+		traktID, slug := 0, strings.ToLower(trakt[1])
+		if id, err := strconv.Atoi(trakt[1]); err == nil {
+			traktID, slug = id, ""
+		}
+		season := 1
+		if trakt[2] != "" {
+			season, _ = strconv.Atoi(trakt[2])
+		}
+
+		for _, mal := range malLinkPattern.FindAllStringSubmatch(row, -1) {
+			malID, _ := strconv.Atoi(mal[1])
+			title := strings.TrimSpace(tagPattern.ReplaceAllString(mal[2], ""))
+			guessedSlug := slug
+			if guessedSlug == "" {
+				guessedSlug = guessSlug(title)
+			}
+			key := [3]int{malID, traktID, season}
+			if mediaType == "movies" {
+				key[2] = 0
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if mediaType == "movies" {
+				movies = append(movies, InputMovie{Title: title, MalID: malID, TraktID: traktID, GuessedSlug: guessedSlug, Type: "movies"})
+			} else {
+				shows = append(shows, InputShow{Title: title, MalID: malID, TraktID: traktID, GuessedSlug: guessedSlug, Season: season, Type: "shows"})
+			}
+		}
+	}
+	slices.SortStableFunc(shows, func(a, b InputShow) int { return a.MalID - b.MalID })
+	slices.SortStableFunc(movies, func(a, b InputMovie) int { return a.MalID - b.MalID })
+	return shows, movies
+}
+
+// guessSlug derives a Trakt-style slug from a title, as the upstream index
+// does for its guessed_slug
+func guessSlug(title string) string {
+	clean, _ := CleanTitle(title)
+	return strings.Trim(nonSlugPattern.ReplaceAllString(strings.ToLower(clean), "-"), "-")
+}
+
+// RunScrape implements the `scrape` subcommand, which writes the AniTrakt
+// index pages as input files, replacing the upstream download step
+func RunScrape(args []string) error {
+	fs := flag.NewFlagSet("scrape", flag.ExitOnError)
+	tvFile := fs.String("tv", filepath.Join(layout.InputDir(), "tv.json"), "Where to write the TV mappings (\"\" to skip, - for stdout)")
+	movieFile := fs.String("movies", filepath.Join(layout.InputDir(), "movies.json"), "Where to write the movie mappings (\"\" to skip, - for stdout)")
+	site := fs.String("site", DefaultAniTraktSite, "Base URL of the AniTrakt index")
+	fs.Parse(args)
+
+	if *tvFile == "" && *movieFile == "" {
+		return fmt.Errorf("nothing to scrape: -tv and -movies are both empty")
+	}
+	if *tvFile == StdioPath && *movieFile == StdioPath {
+		return fmt.Errorf("only one of -tv and -movies can be written to stdout")
+	}
+	if *tvFile == StdioPath || *movieFile == StdioPath {
+		RedirectStdout()
+	}
+
+	client := NewHTTPClient(5 * time.Minute)
+	for _, mediaType := range []string{"tv", "movies"} {
+		path := *tvFile
+		if mediaType == "movies" {
+			path = *movieFile
+		}
+		if path == "" {
+			continue
+		}
+		source := AniTraktSiteSource{Site: *site, MediaType: mediaType, Client: client}
+		shows, movies, err := source.Load()
+		if err != nil {
+			return err
+		}
+		var entries any = shows
+		if mediaType == "movies" {
+			entries = movies
+		}
+		if err := SaveJSON(path, entries); err != nil {
+			return err
+		}
+		withoutID := 0
+		for _, show := range shows {
+			if show.TraktID == 0 {
+				withoutID++
+			}
+		}
+		for _, movie := range movies {
+			if movie.TraktID == 0 {
+				withoutID++
+			}
+		}
+		fmt.Printf("✓ %s: %d %s mappings from %s\n", path, len(shows)+len(movies), mediaType, source.URL())
+		if withoutID > 0 {
+			fmt.Printf("Warning: %d %s mappings link Trakt by slug only; they are looked up by guessed_slug\n", withoutID, mediaType)
+		}
+	}
+	return nil
+}
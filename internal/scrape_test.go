@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const testAniTraktShows = `<table>
+<tr><th>MyAnimeList</th><th>Trakt</th></tr>
+<tr>
+  <td><a href="https://myanimelist.net/anime/1/Cowboy_Bebop">Cowboy Bebop</a></td>
+  <td><a href="https://trakt.tv/shows/30857/seasons/1">Cowboy Bebop</a></td>
+</tr>
+<tr>
+  <td><a href="https://myanimelist.net/anime/9253">Steins;Gate</a><br>
+      <a href="https://myanimelist.net/anime/10863"><span>Steins;Gate: Oukoubakko no Poriomania</span></a></td>
+  <td><a href="https://trakt.tv/shows/41522/seasons/0">Steins;Gate</a></td>
+</tr>
+<tr>
+  <td><a href="https://myanimelist.net/anime/5">Cowboy Bebop: Tengoku no Tobira</a></td>
+  <td><a href="https://trakt.tv/shows/cowboy-bebop">Cowboy Bebop</a></td>
+</tr>
+<tr><td><a href="https://myanimelist.net/anime/1/Cowboy_Bebop">Cowboy Bebop</a></td><td><a href="https://trakt.tv/shows/30857/seasons/1">again</a></td></tr>
+</table>`
+
+func TestParseAniTraktIndex(t *testing.T) {
+	shows, movies := parseAniTraktIndex(testAniTraktShows, "tv")
+	want := []InputShow{
+		{Title: "Cowboy Bebop", MalID: 1, TraktID: 30857, GuessedSlug: "cowboy-bebop", Season: 1, Type: "shows"},
+		{Title: "Cowboy Bebop: Tengoku no Tobira", MalID: 5, GuessedSlug: "cowboy-bebop", Season: 1, Type: "shows"},
+		{Title: "Steins;Gate", MalID: 9253, TraktID: 41522, GuessedSlug: "steins-gate", Season: 0, Type: "shows"},
+		{Title: "Steins;Gate: Oukoubakko no Poriomania", MalID: 10863, TraktID: 41522, GuessedSlug: "steins-gate-oukoubakko-no-poriomania", Season: 0, Type: "shows"},
+	}
+	if movies != nil || len(shows) != len(want) {
+		t.Fatalf("parsed %d shows and %d movies, want %d shows", len(shows), len(movies), len(want))
+	}
+	for i := range want {
+		if shows[i] != want[i] {
+			t.Errorf("show %d = %+v, want %+v", i, shows[i], want[i])
+		}
+	}
+}
+
+func TestRunScrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/db/db_index_shows.php" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testAniTraktShows))
+	}))
+	defer server.Close()
+
+	tvFile := filepath.Join(t.TempDir(), "tv.json")
+	if err := RunScrape([]string{"-site", server.URL + "/db", "-tv", tvFile, "-movies", ""}); err != nil {
+		t.Fatal(err)
+	}
+	shows, err := LoadInputs([]string{tvFile}, func(string, []InputShow) error { return nil })
+	if err != nil || len(shows) != 4 {
+		t.Errorf("scraped %d shows (%v), want 4", len(shows), err)
+	}
+
+	// The site is also an input source of its own
+	shows, err = LoadInputs([]string{AniTraktSiteScheme + server.URL + "/db"}, func(string, []InputShow) error { return nil })
+	if err != nil || len(shows) != 4 {
+		t.Errorf("loaded %d shows (%v) from the site source, want 4", len(shows), err)
+	}
+	if err := RunScrape([]string{"-site", server.URL + "/db", "-tv", "", "-movies", filepath.Join(t.TempDir(), "movies.json")}); err == nil {
+		t.Error("scraping a page without mappings succeeded")
+	}
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretExecPrefix marks a secret source that is a credential helper command
+const secretExecPrefix = "exec:"
+
+// secretExecTimeout bounds a credential helper, which may wait on a locked
+// vault or a network call
+const secretExecTimeout = 30 * time.Second
+
+// ReadSecret resolves a secret source such as -api-key-file: the path of a
+// file holding the secret (e.g. a Docker or Kubernetes secret mount), or
+// exec:<command> to run a credential helper and use what it prints. The
+// command is split on spaces and run without a shell. Surrounding whitespace
+// is trimmed, and an empty secret is an error.
+func ReadSecret(source string) (string, error) {
+	var secret []byte
+	if command, ok := strings.CutPrefix(source, secretExecPrefix); ok {
+		args := strings.Fields(command)
+		if len(args) == 0 {
+			return "", errors.New("exec: needs a command")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stderr = os.Stderr // helpers may prompt or explain failures there
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("credential helper %s: %w", args[0], err)
+		}
+		secret = out
+	} else {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return "", err
+		}
+		secret = data
+	}
+
+	key := strings.TrimSpace(string(secret))
+	if key == "" {
+		return "", fmt.Errorf("%s is empty", source)
+	}
+	return key, nil
+}
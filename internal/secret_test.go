@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trakt")
+	os.WriteFile(path, []byte("  secret-key\n"), 0600)
+	if key, err := ReadSecret(path); err != nil || key != "secret-key" {
+		t.Errorf("file: ReadSecret = %q, %v", key, err)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	os.WriteFile(empty, []byte("\n"), 0600)
+	if _, err := ReadSecret(empty); err == nil {
+		t.Error("empty file accepted")
+	}
+	if _, err := ReadSecret(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing file accepted")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if key, err := ReadSecret("exec:echo helper-key"); err != nil || key != "helper-key" {
+		t.Errorf("exec: ReadSecret = %q, %v", key, err)
+	}
+	if _, err := ReadSecret("exec:false"); err == nil {
+		t.Error("failing helper accepted")
+	}
+	if _, err := ReadSecret("exec: "); err == nil {
+		t.Error("exec: without a command accepted")
+	}
+}
@@ -0,0 +1,436 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ID sources understood by dataset lookups
+const (
+	SourceMAL   = "mal"
+	SourceTrakt = "trakt"
+	SourceTVDB  = "tvdb"
+	SourceTMDB  = "tmdb"
+	SourceIMDB  = "imdb"
+)
+
+// Dataset is an in-memory, read-only view of the output files with indexes
+// for every ID type. Lookups return MAL IDs sorted ascending.
+type Dataset struct {
+	Shows    map[int]OutputShow
+	Movies   map[int]OutputMovie
+	LoadedAt time.Time
+	Version  string    // ETag of the output files, "" when unknown
+	ModTime  time.Time // Last-Modified of the output files
+
+	showIndex  map[string]map[string][]int // source → external ID → MAL IDs
+	movieIndex map[string]map[string][]int
+	showDocs   map[int]map[string]interface{} // generic documents for GraphQL
+	movieDocs  map[int]map[string]interface{}
+}
+
+// LoadDataset reads the TV and movie output files, with the journals of
+// -journal runs since the last compact applied, and builds the indexes.
+// A missing file counts as empty, but one that does not parse is an error,
+// so a reload never swaps in a file caught halfway through a rewrite.
+func LoadDataset(tvFile, movieFile string) (*Dataset, error) {
+	var shows []OutputShow
+	var movies []OutputMovie
+	if err := loadDatasetFile(tvFile, &shows); err != nil {
+		return nil, err
+	}
+	if err := loadDatasetFile(movieFile, &movies); err != nil {
+		return nil, err
+	}
+	// Unlike LoadShowOutput and LoadMovieOutput, a broken canonical file is
+	// not read as empty before the journals are replayed onto it
+	shows, err := replayJournals(tvFile, shows, func(s OutputShow) int { return s.MyAnimeList.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay journals for %s: %w", tvFile, err)
+	}
+	movies, err = replayJournals(movieFile, movies, func(m OutputMovie) int { return m.MyAnimeList.ID })
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay journals for %s: %w", movieFile, err)
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return nil, fmt.Errorf("no entries found in %s or %s", tvFile, movieFile)
+	}
+	return NewDataset(shows, movies), nil
+}
+
+// loadDatasetFile loads an output file that may be missing
+func loadDatasetFile(path string, v interface{}) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return LoadJSON(path, v)
+}
+
+// NewDataset indexes the given entries
+func NewDataset(shows []OutputShow, movies []OutputMovie) *Dataset {
+	d := &Dataset{
+		Shows:      make(map[int]OutputShow, len(shows)),
+		Movies:     make(map[int]OutputMovie, len(movies)),
+		LoadedAt:   time.Now().UTC(),
+		showIndex:  map[string]map[string][]int{SourceMAL: {}, SourceTrakt: {}, SourceTVDB: {}, SourceTMDB: {}, SourceIMDB: {}},
+		movieIndex: map[string]map[string][]int{SourceMAL: {}, SourceTrakt: {}, SourceTMDB: {}, SourceIMDB: {}},
+		showDocs:   make(map[int]map[string]interface{}, len(shows)),
+		movieDocs:  make(map[int]map[string]interface{}, len(movies)),
+	}
+
+	for _, show := range shows {
+		malID := show.MyAnimeList.ID
+		d.Shows[malID] = show
+		d.showDocs[malID] = toDocument(show)
+		addIndex(d.showIndex[SourceMAL], strconv.Itoa(malID), malID)
+		addIndex(d.showIndex[SourceTrakt], strconv.Itoa(show.Trakt.ID), malID)
+		if show.Externals != nil {
+			if show.Externals.TVDB != nil {
+				addIndex(d.showIndex[SourceTVDB], strconv.Itoa(*show.Externals.TVDB), malID)
+			}
+			if show.Externals.TMDB != nil {
+				addIndex(d.showIndex[SourceTMDB], strconv.Itoa(*show.Externals.TMDB), malID)
+			}
+			if show.Externals.IMDB != nil && *show.Externals.IMDB != "" {
+				addIndex(d.showIndex[SourceIMDB], *show.Externals.IMDB, malID)
+			}
+		}
+	}
+
+	for _, movie := range movies {
+		malID := movie.MyAnimeList.ID
+		d.Movies[malID] = movie
+		d.movieDocs[malID] = toDocument(movie)
+		addIndex(d.movieIndex[SourceMAL], strconv.Itoa(malID), malID)
+		addIndex(d.movieIndex[SourceTrakt], strconv.Itoa(movie.Trakt.ID), malID)
+		if movie.Externals != nil {
+			if movie.Externals.TMDB != nil {
+				addIndex(d.movieIndex[SourceTMDB], strconv.Itoa(*movie.Externals.TMDB), malID)
+			}
+			if movie.Externals.IMDB != nil && *movie.Externals.IMDB != "" {
+				addIndex(d.movieIndex[SourceIMDB], *movie.Externals.IMDB, malID)
+			}
+		}
+	}
+
+	for _, index := range []map[string]map[string][]int{d.showIndex, d.movieIndex} {
+		for _, ids := range index {
+			for _, malIDs := range ids {
+				sort.Ints(malIDs)
+			}
+		}
+	}
+	return d
+}
+
+// LookupShows returns the MAL IDs of shows matching an external ID
+func (d *Dataset) LookupShows(source, id string) ([]int, error) {
+	index, ok := d.showIndex[source]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ID source %q for shows", source)
+	}
+	return index[id], nil
+}
+
+// LookupMovies returns the MAL IDs of movies matching an external ID
+func (d *Dataset) LookupMovies(source, id string) ([]int, error) {
+	index, ok := d.movieIndex[source]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ID source %q for movies", source)
+	}
+	return index[id], nil
+}
+
+// addIndex appends a MAL ID under key
+func addIndex(index map[string][]int, key string, malID int) {
+	index[key] = append(index[key], malID)
+}
+
+// toDocument converts an entry into the generic form used by the GraphQL executor
+func toDocument(v interface{}) map[string]interface{} {
+	data, _ := json.Marshal(v)
+	var doc map[string]interface{}
+	json.Unmarshal(data, &doc)
+	return doc
+}
+
+// ShowList returns the shows sorted by MAL ID
+func (d *Dataset) ShowList() []OutputShow {
+	shows := make([]OutputShow, 0, len(d.Shows))
+	for _, show := range d.Shows {
+		shows = append(shows, show)
+	}
+	sort.Slice(shows, func(i, j int) bool { return shows[i].MyAnimeList.ID < shows[j].MyAnimeList.ID })
+	return shows
+}
+
+// MovieList returns the movies sorted by MAL ID
+func (d *Dataset) MovieList() []OutputMovie {
+	movies := make([]OutputMovie, 0, len(d.Movies))
+	for _, movie := range d.Movies {
+		movies = append(movies, movie)
+	}
+	sort.Slice(movies, func(i, j int) bool { return movies[i].MyAnimeList.ID < movies[j].MyAnimeList.ID })
+	return movies
+}
+
+// server serves a Dataset over HTTP and gRPC. The dataset is swapped
+// atomically on reload so in-flight requests keep a consistent view.
+type server struct {
+	data         atomic.Pointer[Dataset]
+	tvFile       string
+	movieFile    string
+	manifestFile string
+	changes      changeHub
+	reloadMu     sync.Mutex // one reload at a time, so a change is published once
+}
+
+// dataset returns the currently loaded dataset
+func (s *server) dataset() *Dataset {
+	return s.data.Load()
+}
+
+// load reads the output files and stamps them with their manifest version
+func (s *server) load() (*Dataset, error) {
+	data, err := LoadDataset(s.tvFile, s.movieFile)
+	if err != nil {
+		return nil, err
+	}
+	// Pending journals are part of the data, so they change the version too
+	files := []string{s.tvFile, s.movieFile}
+	for _, output := range []string{s.tvFile, s.movieFile} {
+		journals, _ := pendingJournals(output)
+		files = append(files, journals...)
+	}
+	data.Version, data.ModTime = datasetVersion(s.manifestFile, files...)
+	return data, nil
+}
+
+// reload re-reads the output files, swaps them in and broadcasts the changes.
+// SIGHUP and watch both call it; reloads run one after the other, so each
+// diffs against the dataset the previous one swapped in, and a slow reload
+// never swaps older files in over a newer one's, publishing changes twice.
+func (s *server) reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	old := s.data.Swap(data)
+	changes := DiffShows(old.ShowList(), data.ShowList())
+	changes = append(changes, DiffMovies(old.MovieList(), data.MovieList())...)
+	log.Printf("Reloaded %d shows and %d movies (%d changes)", len(data.Shows), len(data.Movies), len(changes))
+	if len(changes) > 0 {
+		s.changes.publish(changes)
+	}
+	return nil
+}
+
+// fileStamp is what watch compares to notice a rewritten file
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// stampFiles stats the files the server is loaded from, and the pending
+// journals of the outputs; missing files get a zero stamp
+func (s *server) stampFiles() [5]fileStamp {
+	var stamps [5]fileStamp
+	for i, path := range []string{s.tvFile, s.movieFile, s.manifestFile} {
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{info.Size(), info.ModTime()}
+		}
+	}
+	stamps[3] = stampJournals(s.tvFile)
+	stamps[4] = stampJournals(s.movieFile)
+	return stamps
+}
+
+// stampJournals sums the sizes of the pending journals of outputFile and
+// takes the latest modification time of them and their directory, so both
+// a new journal and a compact that removes them count as a change
+func stampJournals(outputFile string) fileStamp {
+	var stamp fileStamp
+	if info, err := os.Stat(journalDirFor(outputFile)); err == nil {
+		stamp.modTime = info.ModTime()
+	}
+	paths, _ := pendingJournals(outputFile)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamp.size += info.Size()
+		if info.ModTime().After(stamp.modTime) {
+			stamp.modTime = info.ModTime()
+		}
+	}
+	return stamp
+}
+
+// watch polls the output files, their journals and the manifest every
+// interval until stop is closed. It reloads once they changed and then held
+// still for a poll, so a file the generation job is still writing is not
+// loaded half-done. A failed reload keeps the current data and is retried on
+// the next change.
+func (s *server) watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	loaded := s.stampFiles()
+	var pending *[5]fileStamp
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		current := s.stampFiles()
+		switch {
+		case current == loaded:
+			pending = nil
+		case pending == nil || *pending != current:
+			pending = &current
+		default:
+			if err := s.reload(); err != nil {
+				log.Printf("Warning: reload failed: %v", err)
+			}
+			loaded, pending = current, nil
+		}
+	}
+}
+
+// changeHub fans out dataset changes to streaming subscribers
+type changeHub struct {
+	mu          sync.Mutex
+	subscribers map[chan []EntryChange]struct{}
+}
+
+// subscribe registers a new subscriber
+func (h *changeHub) subscribe() chan []EntryChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[chan []EntryChange]struct{})
+	}
+	ch := make(chan []EntryChange, 16)
+	h.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a subscriber, closing its channel
+func (h *changeHub) unsubscribe(ch chan []EntryChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish delivers changes to every subscriber. Subscribers that fall too
+// far behind are disconnected rather than silently missing changes.
+func (h *changeHub) publish(changes []EntryChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- changes:
+		default:
+			log.Printf("Warning: dropping slow change subscriber")
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// RunServe implements the `serve` subcommand
+func RunServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", envOr("ANITRAKT_ADDR", ":8080"), "Address to listen on (env ANITRAKT_ADDR)")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	grpcAddr := fs.String("grpc-addr", envOr("ANITRAKT_GRPC_ADDR", ""), "Address for the gRPC lookup service, empty to disable (env ANITRAKT_GRPC_ADDR)")
+	manifestFile := fs.String("manifest", ManifestPath(), "Dataset manifest the ETag and Last-Modified headers are derived from")
+	maxAge := fs.Duration("cache-max-age", 5*time.Minute, "max-age of the Cache-Control header on GET responses")
+	watchInterval := fs.Duration("watch", 30*time.Second, "Poll the output files and manifest this often and reload when they change (0 disables)")
+	fs.Parse(args)
+
+	s := &server{tvFile: *tvFile, movieFile: *movieFile, manifestFile: *manifestFile}
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	s.data.Store(data)
+
+	// SIGHUP reloads the output files without dropping connections
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := s.reload(); err != nil {
+				log.Printf("Warning: reload failed: %v", err)
+			}
+		}
+	}()
+	if *watchInterval > 0 {
+		go s.watch(*watchInterval, nil)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("/graphql", s.cacheable(*maxAge, s.handleGraphQL))
+	mux.HandleFunc("GET /graphql/schema", s.cacheable(*maxAge, s.handleGraphQLSchema))
+	mux.HandleFunc("GET /shows", s.cacheable(*maxAge, s.handleListShows))
+	mux.HandleFunc("GET /movies", s.cacheable(*maxAge, s.handleListMovies))
+
+	errCh := make(chan error, 2)
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			return err
+		}
+		log.Printf("Serving gRPC on %s", *grpcAddr)
+		go func() { errCh <- s.newGRPCServer().Serve(lis) }()
+	}
+
+	log.Printf("Serving %d shows and %d movies on %s", len(data.Shows), len(data.Movies), *addr)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           gzipResponses(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { errCh <- httpServer.ListenAndServe() }()
+	return <-errCh
+}
+
+// handleHealth reports the dataset size and load time
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	data := s.dataset()
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"shows":     len(data.Shows),
+		"movies":    len(data.Movies),
+		"loaded_at": data.LoadedAt.Format(time.RFC3339),
+		"version":   data.Version,
+	})
+}
+
+// writeJSONResponse encodes v as the response body
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
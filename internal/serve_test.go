@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServeWatchReloads(t *testing.T) {
+	t.Chdir(t.TempDir())
+	shows := testDataset(t).ShowList()
+	SaveJSON("tv_ex.json", shows[:1])
+
+	s := &server{tvFile: "tv_ex.json", movieFile: "movies_ex.json", manifestFile: "manifest.json"}
+	data, err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.data.Store(data)
+	updates := s.changes.subscribe()
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go s.watch(5*time.Millisecond, stop)
+
+	// A half-written file fails to load and leaves the data in place
+	os.WriteFile("tv_ex.json", []byte(`[{"myanimelist":`), 0644)
+	time.Sleep(50 * time.Millisecond)
+	if got := len(s.dataset().Shows); got != 1 {
+		t.Fatalf("dataset has %d shows after a broken write, want 1", got)
+	}
+
+	SaveJSON("tv_ex.json", shows)
+	select {
+	case changes := <-updates:
+		if len(changes) != 1 || changes[0].Change != "created" {
+			t.Errorf("changes = %+v, want the created show", changes)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not reload the rewritten output file")
+	}
+	if got := s.dataset(); len(got.Shows) != len(shows) || got.Version == data.Version {
+		t.Errorf("reloaded dataset has %d shows, version %q (was %q)", len(got.Shows), got.Version, data.Version)
+	}
+}
+
+func TestServeConcurrentReloadsPublishOnce(t *testing.T) {
+	t.Chdir(t.TempDir())
+	shows := testDataset(t).ShowList()
+	SaveJSON("tv_ex.json", shows[:1])
+
+	s := &server{tvFile: "tv_ex.json", movieFile: "movies_ex.json", manifestFile: "manifest.json"}
+	data, err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.data.Store(data)
+	updates := s.changes.subscribe()
+
+	SaveJSON("tv_ex.json", shows)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.reload()
+		}()
+	}
+	wg.Wait()
+	s.changes.unsubscribe(updates)
+
+	published := 0
+	for changes := range updates {
+		published += len(changes)
+	}
+	if published != 1 {
+		t.Errorf("published %d changes, want the created show once", published)
+	}
+}
+
+func TestLoadDatasetRejectsBrokenFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	SaveJSON("movies_ex.json", testDataset(t).MovieList())
+	os.WriteFile("tv_ex.json", []byte(`[{"myanimelist":`), 0644)
+	if _, err := LoadDataset("tv_ex.json", "movies_ex.json"); err == nil {
+		t.Error("LoadDataset loaded a truncated TV file as empty")
+	}
+	os.Remove("tv_ex.json")
+	if data, err := LoadDataset("tv_ex.json", "movies_ex.json"); err != nil || len(data.Movies) != 1 {
+		t.Errorf("LoadDataset(missing TV file) = %v, %v", data, err)
+	}
+}
+
+func TestServeWatchesJournals(t *testing.T) {
+	t.Chdir(t.TempDir())
+	shows := testDataset(t).ShowList()
+	SaveJSON("tv_ex.json", shows[:1])
+
+	s := &server{tvFile: "tv_ex.json", movieFile: "movies_ex.json", manifestFile: "manifest.json"}
+	data, err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.data.Store(data)
+	updates := s.changes.subscribe()
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go s.watch(5*time.Millisecond, stop)
+	time.Sleep(20 * time.Millisecond)
+
+	// A -journal run leaves the canonical file alone
+	created, _ := json.Marshal(shows[1])
+	dir := journalDirFor("tv_ex.json")
+	os.MkdirAll(dir, 0755)
+	SaveJSON(filepath.Join(dir, "20260101T000000Z.json"), Journal{Output: "tv_ex.json", Changes: []EntryChange{
+		{MediaType: "tv", Change: "created", MalID: shows[1].MyAnimeList.ID, New: created},
+	}})
+	select {
+	case changes := <-updates:
+		if len(changes) != 1 || changes[0].MalID != shows[1].MyAnimeList.ID {
+			t.Errorf("changes = %+v, want the journaled show", changes)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not reload after a journal was written")
+	}
+	if got := s.dataset(); len(got.Shows) != 2 || got.Version == data.Version {
+		t.Errorf("reloaded dataset has %d shows, version %q (was %q)", len(got.Shows), got.Version, data.Version)
+	}
+}
@@ -0,0 +1,311 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Signatures are written in the minisign format (legacy "Ed" algorithm, i.e.
+// plain ed25519 over the file contents) so consumers can verify the dataset
+// either with the `verify` subcommand or with the stock minisign tool:
+//
+//	minisign -Vm json/output/tv_ex.json -p anitrakt.pub
+
+const (
+	signatureSuffix     = ".minisig"
+	signatureAlgorithm  = "Ed"
+	defaultSigningKey   = "ANITRAKT_SIGNING_KEY"
+	defaultPublicKeyEnv = "ANITRAKT_PUBLIC_KEY"
+)
+
+// SigningKey is an ed25519 key pair together with its minisign key ID
+type SigningKey struct {
+	Private ed25519.PrivateKey
+	Public  ed25519.PublicKey
+	KeyID   [8]byte
+}
+
+// PublicKey is an ed25519 public key together with its minisign key ID
+type PublicKey struct {
+	Key   ed25519.PublicKey
+	KeyID [8]byte
+}
+
+// keyIDFor derives a stable key ID from the public key
+func keyIDFor(pub ed25519.PublicKey) [8]byte {
+	var id [8]byte
+	sum := sha256.Sum256(pub)
+	copy(id[:], sum[:8])
+	return id
+}
+
+// ParseSigningKey decodes a base64 ed25519 seed (32 bytes) or private key (64 bytes)
+func ParseSigningKey(encoded string) (*SigningKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("decode signing key: %w", err)
+	}
+
+	var priv ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		priv = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		priv = ed25519.PrivateKey(raw)
+	default:
+		return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+
+	pub := priv.Public().(ed25519.PublicKey)
+	return &SigningKey{Private: priv, Public: pub, KeyID: keyIDFor(pub)}, nil
+}
+
+// ParsePublicKey decodes a minisign public key, either the full two-line file
+// contents or just the base64 key line
+func ParsePublicKey(text string) (*PublicKey, error) {
+	line := ""
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "untrusted comment:") {
+			line = l
+			break
+		}
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	if len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != signatureAlgorithm {
+		return nil, errors.New("public key is not a minisign ed25519 key")
+	}
+
+	pk := &PublicKey{Key: ed25519.PublicKey(raw[10:])}
+	copy(pk.KeyID[:], raw[2:10])
+	return pk, nil
+}
+
+// EncodePublicKey renders the public key in the minisign public key file format
+func (k *SigningKey) EncodePublicKey() string {
+	raw := append([]byte(signatureAlgorithm), k.KeyID[:]...)
+	raw = append(raw, k.Public...)
+	return fmt.Sprintf("untrusted comment: minisign public key %X\n%s\n",
+		k.KeyID, base64.StdEncoding.EncodeToString(raw))
+}
+
+// Sign produces a minisign signature file for data
+func (k *SigningKey) Sign(data []byte, fileName string) []byte {
+	sig := ed25519.Sign(k.Private, data)
+	trusted := fmt.Sprintf("timestamp:%d\tfile:%s", time.Now().Unix(), fileName)
+	global := ed25519.Sign(k.Private, append(append([]byte{}, sig...), trusted...))
+
+	sigBlob := append([]byte(signatureAlgorithm), k.KeyID[:]...)
+	sigBlob = append(sigBlob, sig...)
+
+	var buf bytes.Buffer
+	buf.WriteString("untrusted comment: signature from anitrakt secret key\n")
+	buf.WriteString(base64.StdEncoding.EncodeToString(sigBlob) + "\n")
+	buf.WriteString("trusted comment: " + trusted + "\n")
+	buf.WriteString(base64.StdEncoding.EncodeToString(global) + "\n")
+	return buf.Bytes()
+}
+
+// Verify checks a minisign signature file against data
+func (pk *PublicKey) Verify(data, signature []byte) error {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 {
+		return errors.New("malformed signature file")
+	}
+
+	sigBlob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	if len(sigBlob) != 2+8+ed25519.SignatureSize {
+		return errors.New("malformed signature")
+	}
+	if string(sigBlob[:2]) != signatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sigBlob[:2])
+	}
+	if !bytes.Equal(sigBlob[2:10], pk.KeyID[:]) {
+		return fmt.Errorf("signature key ID %X does not match public key %X", sigBlob[2:10], pk.KeyID)
+	}
+
+	sig := sigBlob[10:]
+	if !ed25519.Verify(pk.Key, data, sig) {
+		return errors.New("signature verification failed")
+	}
+
+	trusted, ok := strings.CutPrefix(strings.TrimSpace(lines[2]), "trusted comment: ")
+	if !ok {
+		return errors.New("missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return fmt.Errorf("decode global signature: %w", err)
+	}
+	if !ed25519.Verify(pk.Key, append(append([]byte{}, sig...), trusted...), global) {
+		return errors.New("trusted comment signature verification failed")
+	}
+	return nil
+}
+
+// signFile writes <path>.minisig next to path
+func signFile(key *SigningKey, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+signatureSuffix, key.Sign(data, filepath.Base(path)), 0644)
+}
+
+// verifyFile checks <path>.minisig against path
+func verifyFile(pk *PublicKey, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(path + signatureSuffix)
+	if err != nil {
+		return fmt.Errorf("read signature: %w", err)
+	}
+	return pk.Verify(data, signature)
+}
+
+// RunSign implements the `sign` subcommand
+func RunSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyEnv := fs.String("key-env", defaultSigningKey, "Environment variable holding the base64 ed25519 signing key")
+	manifestPath := fs.String("manifest", ManifestPath(), "Path to the dataset manifest")
+	keygen := fs.Bool("keygen", false, "Generate a new signing key pair and exit")
+	fs.Parse(args)
+
+	if *keygen {
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			return err
+		}
+		key, err := ParseSigningKey(base64.StdEncoding.EncodeToString(seed))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Secret key (store as %s):\n%s\n\n", *keyEnv, base64.StdEncoding.EncodeToString(seed))
+		fmt.Printf("Public key (publish as anitrakt.pub):\n%s", key.EncodePublicKey())
+		return nil
+	}
+
+	encoded := os.Getenv(*keyEnv)
+	if encoded == "" {
+		return fmt.Errorf("no signing key found in $%s", *keyEnv)
+	}
+	key, err := ParseSigningKey(encoded)
+	if err != nil {
+		return err
+	}
+
+	var manifest Manifest
+	if err := LoadJSON(*manifestPath, &manifest); err != nil {
+		return err
+	}
+
+	// Refuse to sign a manifest that no longer matches the files on disk
+	for _, file := range manifest.Files {
+		current, err := describeFile(file.Path)
+		if err != nil {
+			return err
+		}
+		if current.SHA256 != file.SHA256 {
+			return fmt.Errorf("%s changed since the manifest was generated", file.Path)
+		}
+	}
+
+	if err := signFile(key, *manifestPath); err != nil {
+		return fmt.Errorf("sign %s: %w", *manifestPath, err)
+	}
+	for _, file := range manifest.Files {
+		if err := signFile(key, file.Path); err != nil {
+			return fmt.Errorf("sign %s: %w", file.Path, err)
+		}
+	}
+
+	fmt.Printf("Signed manifest and %d files with key %X\n", len(manifest.Files), key.KeyID)
+	return nil
+}
+
+// RunVerify implements the `verify` subcommand
+func RunVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	pubKey := fs.String("pubkey", "", "Public key file or base64 key line (default: $"+defaultPublicKeyEnv+")")
+	manifestPath := fs.String("manifest", ManifestPath(), "Path to the dataset manifest")
+	fs.Parse(args)
+
+	keyText := os.Getenv(defaultPublicKeyEnv)
+	if *pubKey != "" {
+		keyText = *pubKey
+		if data, err := os.ReadFile(*pubKey); err == nil {
+			keyText = string(data)
+		}
+	}
+	if keyText == "" {
+		return fmt.Errorf("no public key given; pass -pubkey or set $%s", defaultPublicKeyEnv)
+	}
+	pk, err := ParsePublicKey(keyText)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyFile(pk, *manifestPath); err != nil {
+		return fmt.Errorf("%s: %w", *manifestPath, err)
+	}
+	fmt.Printf("✓ %s\n", *manifestPath)
+
+	var manifest Manifest
+	if err := LoadJSON(*manifestPath, &manifest); err != nil {
+		return err
+	}
+
+	failures := 0
+	for _, file := range manifest.Files {
+		current, err := describeFile(file.Path)
+		switch {
+		case err != nil:
+			fmt.Printf("✗ %s: %v\n", file.Path, err)
+			failures++
+		case current.SHA256 != file.SHA256 || current.Size != file.Size:
+			fmt.Printf("✗ %s: checksum mismatch (manifest %s, actual %s)\n",
+				file.Path, shortHash(file.SHA256), shortHash(current.SHA256))
+			failures++
+		default:
+			if err := verifyFile(pk, file.Path); err != nil {
+				fmt.Printf("✗ %s: %v\n", file.Path, err)
+				failures++
+				continue
+			}
+			fmt.Printf("✓ %s\n", file.Path)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failures, len(manifest.Files))
+	}
+	return nil
+}
+
+// shortHash abbreviates a hex digest for display
+func shortHash(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testSigningKey(t *testing.T) *SigningKey {
+	t.Helper()
+	seed := make([]byte, 32)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	key, err := ParseSigningKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatalf("ParseSigningKey: %v", err)
+	}
+	return key
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	key := testSigningKey(t)
+	pk, err := ParsePublicKey(key.EncodePublicKey())
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	if pk.KeyID != key.KeyID {
+		t.Fatalf("key ID = %X, want %X", pk.KeyID, key.KeyID)
+	}
+
+	data := []byte(`[{"myanimelist":{"title":"Cowboy Bebop","id":1}}]`)
+	sig := key.Sign(data, "tv_ex.json")
+	if err := pk.Verify(data, sig); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tampered := []byte(strings.Replace(string(data), "Bebop", "Beebop", 1))
+	if err := pk.Verify(tampered, sig); err == nil {
+		t.Error("expected verification failure for tampered data")
+	}
+
+	forgedComment := strings.Replace(string(sig), "file:tv_ex.json", "file:movies_ex.json", 1)
+	if err := pk.Verify(data, []byte(forgedComment)); err == nil {
+		t.Error("expected verification failure for tampered trusted comment")
+	}
+}
+
+func TestParsePublicKeyBareLine(t *testing.T) {
+	key := testSigningKey(t)
+	lines := strings.Split(strings.TrimSpace(key.EncodePublicKey()), "\n")
+	pk, err := ParsePublicKey(lines[1])
+	if err != nil {
+		t.Fatalf("ParsePublicKey(bare line): %v", err)
+	}
+	if !pk.Key.Equal(key.Public) {
+		t.Error("parsed public key does not match signing key")
+	}
+}
+
+func TestVerifyRejectsOtherKey(t *testing.T) {
+	key := testSigningKey(t)
+	other, err := ParseSigningKey(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("ParseSigningKey: %v", err)
+	}
+	otherPK, _ := ParsePublicKey(other.EncodePublicKey())
+
+	data := []byte("payload")
+	if err := otherPK.Verify(data, key.Sign(data, "payload")); err == nil {
+		t.Error("expected key ID mismatch error")
+	}
+}
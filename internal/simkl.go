@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// simklAPIBase is the SIMKL API root
+const simklAPIBase = "https://api.simkl.com"
+
+// errSIMKLNotFound is returned when SIMKL has no entry for the MAL ID
+var errSIMKLNotFound = errors.New("not found on SIMKL")
+
+// SIMKLInfo is the SIMKL entry the simkl enrichment adds to shows and movies
+type SIMKLInfo struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// simklMatch is one result of /search/id
+type simklMatch struct {
+	Type string `json:"type"` // "anime", "tv" or "movie"
+	IDs  struct {
+		SIMKL int    `json:"simkl"`
+		Slug  string `json:"slug"`
+	} `json:"ids"`
+}
+
+// FetchSIMKL looks a MAL ID up on SIMKL. Results, misses included, are
+// cached under config.TempDir/simkl/<mal_id>.json.
+func FetchSIMKL(client *http.Client, config Config, malID int) (*SIMKLInfo, error) {
+	cacheFile := filepath.Join(config.TempDir, "simkl", fmt.Sprintf("%d.json", malID))
+	matches, err := singleFetch(cacheFile, func() ([]simklMatch, error) {
+		if useCache(config, cacheFile) {
+			if matches, ok := cacheLoad[[]simklMatch](config.Cache, cacheFile); ok {
+				return matches, nil
+			}
+		}
+
+		config.SIMKLRateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("%s/search/id?mal=%d", simklAPIBase, malID), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("simkl-api-key", config.SIMKLClientID)
+			return client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, statusError(resp, fmt.Errorf("SIMKL API error: %d", resp.StatusCode))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var matches []simklMatch
+		if err := json.Unmarshal(body, &matches); err != nil {
+			return nil, err
+		}
+		os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		cacheStore(config.Cache, cacheFile, body, matches)
+		return matches, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 || matches[0].IDs.SIMKL == 0 {
+		return nil, errSIMKLNotFound
+	}
+	return &SIMKLInfo{ID: matches[0].IDs.SIMKL, Slug: matches[0].IDs.Slug}, nil
+}
+
+// simklEnricher adds the SIMKL ID of each entry, looked up by MAL ID
+type simklEnricher struct{}
+
+func (simklEnricher) Name() string { return "simkl" }
+
+func (simklEnricher) Applies(entry EnrichEntry) bool { return true }
+
+func (e simklEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	var malID int
+	var title string
+	if entry.Show != nil {
+		malID, title = entry.Show.MyAnimeList.ID, entry.Show.MyAnimeList.Title
+	} else {
+		malID, title = entry.Movie.MyAnimeList.ID, entry.Movie.MyAnimeList.Title
+	}
+	info := e.previous(entry)
+	if ctx.Config.SIMKLClientID != "" {
+		ctx.Config.Progress.Phase("simkl")
+		found, err := FetchSIMKL(ctx.Client, ctx.Config, malID)
+		switch {
+		case errors.Is(err, errSIMKLNotFound):
+			info = nil
+		case err != nil:
+			log.Printf("Warning: SIMKL lookup of MAL ID %d failed: %v", malID, err)
+			ctx.Config.Errors.Record("simkl", malID, title, err)
+		default:
+			info = found
+		}
+	}
+	e.set(entry, info)
+}
+
+// Retain keeps the previous SIMKL entry
+func (e simklEnricher) Retain(entry EnrichEntry) {
+	e.set(entry, e.previous(entry))
+}
+
+// previous returns the SIMKL entry of the previous output, nil without one
+func (simklEnricher) previous(entry EnrichEntry) *SIMKLInfo {
+	if entry.Show != nil && entry.ExistingShow != nil {
+		return entry.ExistingShow.SIMKL
+	}
+	if entry.Movie != nil && entry.ExistingMovie != nil {
+		return entry.ExistingMovie.SIMKL
+	}
+	return nil
+}
+
+// set stores info on the show or movie of entry
+func (simklEnricher) set(entry EnrichEntry, info *SIMKLInfo) {
+	if entry.Show != nil {
+		entry.Show.SIMKL = info
+	} else {
+		entry.Movie.SIMKL = info
+	}
+}
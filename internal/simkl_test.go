@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSIMKLEnricher(t *testing.T) {
+	responses := map[string]string{
+		"1": `[{"type": "anime", "title": "Cowboy Bebop", "ids": {"simkl": 37089, "slug": "cowboy-bebop"}}]`,
+		"2": `[]`,
+	}
+	var keys []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		keys = append(keys, req.Header.Get("simkl-api-key"))
+		body, ok := responses[req.URL.Query().Get("mal")]
+		if !ok {
+			return &http.Response{StatusCode: 401, Body: io.NopCloser(strings.NewReader(`{"error": "client_id_failed"}`)), Header: http.Header{}}, nil
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	})}
+	config := Config{TempDir: t.TempDir(), Cache: NewCache(10), SIMKLClientID: "client", SIMKLRateLimiter: NewSIMKLRateLimiter()}
+	ctx := EnrichContext{Client: client, Config: config, Stats: &ProcessingStats{}}
+	previous := &SIMKLInfo{ID: 5}
+
+	tests := []struct {
+		malID int
+		want  *SIMKLInfo
+	}{
+		{1, &SIMKLInfo{ID: 37089, Slug: "cowboy-bebop"}}, // found
+		{2, nil},      // not on SIMKL: the previous ID is dropped
+		{3, previous}, // lookup failed: the previous ID is kept
+	}
+	for _, tt := range tests {
+		var movie, existing OutputMovie
+		movie.MyAnimeList.ID = tt.malID
+		existing.SIMKL = previous
+		simklEnricher{}.Enrich(ctx, EnrichEntry{Movie: &movie, ExistingMovie: &existing})
+		if (movie.SIMKL == nil) != (tt.want == nil) || movie.SIMKL != nil && *movie.SIMKL != *tt.want {
+			t.Errorf("MAL %d: SIMKL = %+v, want %+v", tt.malID, movie.SIMKL, tt.want)
+		}
+	}
+	if keys[0] != "client" {
+		t.Errorf("simkl-api-key = %q, want the client ID", keys[0])
+	}
+
+	// Cached lookups, misses included, are not repeated
+	requests := len(keys)
+	for _, malID := range []int{1, 2} {
+		var show OutputShow
+		show.MyAnimeList.ID = malID
+		simklEnricher{}.Enrich(ctx, EnrichEntry{Show: &show})
+	}
+	if len(keys) != requests {
+		t.Errorf("cached lookups made %d more requests", len(keys)-requests)
+	}
+}
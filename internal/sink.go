@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sink stores what a run produced. The JSON output file is always written;
+// -sink adds more sinks, which get the same batches after it.
+type Sink interface {
+	// Name is the kind of the sink, as given to -sink
+	Name() string
+	// Write stores the results of one media type
+	Write(batch SinkBatch) error
+}
+
+// SinkBatch is what a run produced for one media type: Shows for "tv",
+// Movies for "movies"
+type SinkBatch struct {
+	Config         Config
+	MediaType      string // "tv" or "movies"
+	OutputFile     string
+	Stats          ProcessingStats
+	Shows          map[int]OutputShow
+	ExistingShows  []OutputShow // previous output
+	Movies         map[int]OutputMovie
+	ExistingMovies []OutputMovie
+}
+
+// SinkKinds lists the sinks -sink can add, as kind[:target]
+var SinkKinds = []string{"ndjson", "sqlite", "redis", "http"}
+
+// ParseSink returns the sink of a -sink value: ndjson[:dir], sqlite[:path],
+// redis[:url] or http:url
+func ParseSink(spec string) (Sink, error) {
+	kind, target, _ := strings.Cut(spec, ":")
+	if strings.HasPrefix(target, "//") {
+		// A bare URL: redis://, http:// or https://
+		target = spec
+		if kind == "https" {
+			kind = "http"
+		}
+	}
+	switch kind {
+	case "ndjson":
+		return NDJSONSink{Dir: target}, nil
+	case "sqlite":
+		if target == "" {
+			target = "dist/anitrakt.sqlite"
+		}
+		return SQLiteSink{Path: target}, nil
+	case "redis":
+		if target == "" {
+			target = envOr("REDIS_URL", "redis://localhost:6379/0")
+		}
+		return RedisSink{URL: target, Prefix: "anitrakt:"}, nil
+	case "http":
+		if target == "" {
+			return nil, fmt.Errorf("sink %q needs a URL (http:https://...)", spec)
+		}
+		return HTTPSink{URL: target}, nil
+	case "json":
+		return nil, fmt.Errorf("the json sink is always written; -sink adds %s", strings.Join(SinkKinds, ", "))
+	}
+	return nil, fmt.Errorf("unknown sink %q (expected %s)", spec, strings.Join(SinkKinds, ", "))
+}
+
+// writeSinks writes batch to the JSON output file, then to the other sinks.
+// Only the JSON file can fail the run: it is what the next run resumes
+// from, so other sinks only log a warning.
+func writeSinks(batch SinkBatch) error {
+	if err := (JSONFileSink{}).Write(batch); err != nil {
+		return err
+	}
+	for _, sink := range batch.Config.Sinks {
+		if err := sink.Write(batch); err != nil {
+			log.Printf("Warning: %s sink failed for %s: %v", sink.Name(), batch.MediaType, err)
+		}
+	}
+	return nil
+}
+
+// JSONFileSink writes the output file, see StoreResults
+type JSONFileSink struct{}
+
+func (JSONFileSink) Name() string { return "json" }
+
+func (JSONFileSink) Write(batch SinkBatch) error {
+	if batch.MediaType == "movies" {
+		return StoreMovieResults(batch.Config, batch.OutputFile, batch.ExistingMovies, batch.Movies)
+	}
+	return StoreResults(batch.Config, batch.OutputFile, batch.ExistingShows, batch.Shows)
+}
+
+// NDJSONSink writes each media type as newline-delimited JSON, one entry per
+// line in the -sort order, for tools that stream the dataset
+type NDJSONSink struct {
+	Dir string // "" for the directory of the output file
+}
+
+func (NDJSONSink) Name() string { return "ndjson" }
+
+func (s NDJSONSink) Write(batch SinkBatch) error {
+	path := s.path(batch)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var err error
+	if batch.MediaType == "movies" {
+		err = writeNDJSON(path, sortedResults(batch.Movies, batch.Config.Sort))
+	} else {
+		err = writeNDJSON(path, sortedResults(batch.Shows, batch.Config.Sort))
+	}
+	if err == nil && batch.Config.Verbose {
+		Debugf("\nWrote %s entries to %s", batch.MediaType, path)
+	}
+	return err
+}
+
+// writeNDJSON writes entries one per line, through a temporary file so
+// readers never see a partial file
+func writeNDJSON[T any](path string, entries []T) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err = encoder.Encode(entry); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// path names the file after the output file, e.g. tv_ex.ndjson; output to
+// stdout is named after the media type
+func (s NDJSONSink) path(batch SinkBatch) string {
+	name := batch.MediaType + "_ex"
+	dir := s.Dir
+	if batch.OutputFile != StdioPath {
+		name = strings.TrimSuffix(filepath.Base(batch.OutputFile), filepath.Ext(batch.OutputFile))
+		if dir == "" {
+			dir = filepath.Dir(batch.OutputFile)
+		}
+	}
+	return filepath.Join(dir, name+".ndjson")
+}
+
+// sortedResults returns the entries of results in order
+func sortedResults[T sortable](results map[int]T, order SortOrder) []T {
+	entries := make([]T, 0, len(results))
+	for _, entry := range results {
+		entries = append(entries, entry)
+	}
+	SortEntries(entries, order)
+	return entries
+}
@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testShowBatch is a tv batch of the test dataset, every show new
+func testShowBatch(t *testing.T, config Config) SinkBatch {
+	t.Helper()
+	shows := map[int]OutputShow{}
+	for _, show := range testDataset(t).ShowList() {
+		shows[show.MyAnimeList.ID] = show
+	}
+	return SinkBatch{
+		Config:     config,
+		MediaType:  "tv",
+		OutputFile: "tv_ex.json",
+		Stats:      ProcessingStats{MediaType: "tv"},
+		Shows:      shows,
+	}
+}
+
+func TestParseSink(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Sink
+	}{
+		{"ndjson", NDJSONSink{}},
+		{"ndjson:dist", NDJSONSink{Dir: "dist"}},
+		{"sqlite", SQLiteSink{Path: "dist/anitrakt.sqlite"}},
+		{"sqlite:db/a.sqlite", SQLiteSink{Path: "db/a.sqlite"}},
+		{"redis://cache:6379/1", RedisSink{URL: "redis://cache:6379/1", Prefix: "anitrakt:"}},
+		{"http:https://example.com/hook", HTTPSink{URL: "https://example.com/hook"}},
+		{"https://example.com/hook", HTTPSink{URL: "https://example.com/hook"}},
+	}
+	for _, test := range tests {
+		sink, err := ParseSink(test.spec)
+		if err != nil || sink != test.want {
+			t.Errorf("ParseSink(%q) = %#v, %v, want %#v", test.spec, sink, err, test.want)
+		}
+	}
+	for _, spec := range []string{"json", "http", "csv"} {
+		if _, err := ParseSink(spec); err == nil {
+			t.Errorf("ParseSink(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestWriteSinksKeepsJSONOnSinkFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	batch := testShowBatch(t, Config{PushMode: "full", Sinks: []Sink{HTTPSink{URL: server.URL}}})
+	if err := writeSinks(batch); err != nil {
+		t.Fatalf("writeSinks = %v, want a failing http sink to only warn", err)
+	}
+	var shows []OutputShow
+	if err := LoadJSON(batch.OutputFile, &shows); err != nil || len(shows) != 2 {
+		t.Errorf("JSON output = %v, %v, want both shows", shows, err)
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	t.Chdir(t.TempDir())
+	batch := testShowBatch(t, Config{Sort: SortMalID})
+	if err := (NDJSONSink{}).Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open("tv_ex.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ids []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var show OutputShow
+		if err := json.Unmarshal(scanner.Bytes(), &show); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, show.MyAnimeList.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 5 {
+		t.Errorf("MAL IDs %v, want [1 5]", ids)
+	}
+
+	if path := (NDJSONSink{Dir: "dist"}).path(SinkBatch{MediaType: "movies", OutputFile: StdioPath}); path != filepath.Join("dist", "movies_ex.ndjson") {
+		t.Errorf("stdout output path = %s", path)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var payload PushPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	batch := testShowBatch(t, Config{PushMode: "full"})
+	if err := (HTTPSink{URL: server.URL}).Write(batch); err != nil {
+		t.Fatal(err)
+	}
+	if payload.MediaType != "tv" || payload.Mode != "full" {
+		t.Errorf("payload = %+v", payload)
+	}
+	if entries, ok := payload.Entries.([]any); !ok || len(entries) != 2 {
+		t.Errorf("pushed entries %v, want both shows", payload.Entries)
+	}
+}
+
+func TestSQLiteSink(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not in PATH")
+	}
+	dir := t.TempDir()
+	sink := SQLiteSink{Path: filepath.Join(dir, "anitrakt.sqlite")}
+	batch := testShowBatch(t, Config{})
+	// Twice: the second write replaces the table of the first
+	for range 2 {
+		if err := sink.Write(batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command("sqlite3", sink.Path, "SELECT count(*) FROM shows; SELECT count(*) FROM changes;").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(out)); len(got) != 2 || got[0] != "2" || got[1] != "4" {
+		t.Errorf("shows and changes = %v, want 2 shows and 2 changes per write", got)
+	}
+}
@@ -0,0 +1,394 @@
+package internal
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// siteEntry is one entry of the static site: a mapped entry with its own
+// page, or a not-found one that only appears in the index
+type siteEntry struct {
+	MediaType string // "tv" or "movies"
+	MalID     int
+	Title     string
+	AltTitles []string
+	Year      int
+	Target    string // Trakt title, with the season for shows; "" when not found
+	Page      string // path from the site root, "" when not found
+	Details   []siteField
+	Links     []siteLink
+	JSON      string // the output entry, indented
+	ReportURL string
+	Search    string // lower-cased text the search box matches
+}
+
+type siteField struct{ Name, Value string }
+
+type siteLink struct{ Name, URL string }
+
+// sitePage is the data of every page template. Root leads from the page
+// back to the site root, so the site works from any sub-path and file://.
+type sitePage struct {
+	Root        string
+	Title       string
+	GeneratedAt string
+	Repo        string
+	Entries     []siteEntry // index
+	Shows       int
+	Movies      int
+	NotFound    int
+	Entry       siteEntry // entry pages
+}
+
+// siteTemplates renders the index and entry pages. The search box filters
+// the index rows in the browser; without JavaScript the whole index shows.
+var siteTemplates = template.Must(template.New("site").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<header><a href="{{.Root}}index.html">db.trakt.extended-anitrakt</a></header>
+<main>
+{{end}}
+
+{{define "foot"}}</main>
+<footer>Generated {{.GeneratedAt}} from <a href="https://github.com/{{.Repo}}">{{.Repo}}</a></footer>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "head" .}}
+<h1>MyAnimeList → Trakt mappings</h1>
+<p>{{.Shows}} shows and {{.Movies}} movies mapped, {{.NotFound}} entries not found on Trakt.</p>
+<input id="search" type="search" placeholder="Search by title or MAL ID" autofocus>
+<p id="count"></p>
+<table id="entries">
+<thead><tr><th>Type</th><th>MAL ID</th><th>Title</th><th>Year</th><th>Trakt</th></tr></thead>
+<tbody>
+{{range .Entries}}<tr data-search="{{.Search}}"><td>{{if eq .MediaType "tv"}}TV{{else}}Movie{{end}}</td><td>{{.MalID}}</td>
+{{if .Page}}<td><a href="{{.Page}}">{{.Title}}</a></td><td>{{if .Year}}{{.Year}}{{end}}</td><td>{{.Target}}</td>
+{{else}}<td>{{.Title}}</td><td></td><td class="missing">not found · <a href="{{.ReportURL}}">report a mapping</a></td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<script>
+const search = document.getElementById("search");
+const rows = Array.from(document.querySelectorAll("#entries tbody tr"));
+const count = document.getElementById("count");
+function filter() {
+  const terms = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+  let shown = 0;
+  for (const row of rows) {
+    const match = terms.every(term => row.dataset.search.includes(term));
+    row.hidden = !match;
+    if (match) shown++;
+  }
+  count.textContent = terms.length ? shown + " of " + rows.length + " entries" : "";
+  const url = new URL(location);
+  if (terms.length) { url.searchParams.set("q", search.value); } else { url.searchParams.delete("q"); }
+  history.replaceState(null, "", url);
+}
+search.value = new URLSearchParams(location.search).get("q") || "";
+search.addEventListener("input", filter);
+filter();
+</script>
+{{template "foot" .}}{{end}}
+
+{{define "entry"}}{{template "head" .}}{{with .Entry}}
+<h1>{{.Title}}</h1>
+{{range .AltTitles}}<p class="alt">{{.}}</p>{{end}}
+<table>
+{{range .Details}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Links</h2>
+<ul>
+{{range .Links}}<li><a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>
+<p><a class="report" href="{{.ReportURL}}">Report a problem with this mapping</a></p>
+<details><summary>JSON</summary><pre>{{.JSON}}</pre></details>
+{{end}}{{template "foot" .}}{{end}}
+`))
+
+// siteCSS is written as style.css
+const siteCSS = `body { font-family: system-ui, sans-serif; margin: 0; color: #222; }
+header, footer { padding: 0.75rem 1.5rem; background: #f3f3f3; }
+header a { font-weight: bold; color: inherit; text-decoration: none; }
+footer { font-size: 0.85rem; color: #666; }
+main { padding: 1rem 1.5rem; max-width: 70rem; }
+#search { width: 100%; max-width: 40rem; padding: 0.5rem; font-size: 1rem; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.25rem 0.75rem 0.25rem 0; vertical-align: top; }
+#entries { width: 100%; }
+#entries thead th { border-bottom: 2px solid #ccc; }
+#entries td { border-bottom: 1px solid #eee; }
+.missing { color: #a33; }
+.alt { margin: 0.2rem 0; color: #555; }
+.report { color: #a33; }
+pre { background: #f7f7f7; padding: 0.75rem; overflow-x: auto; }
+`
+
+// RunSite implements the `site` subcommand, which renders the dataset into
+// a static HTML site for GitHub Pages
+func RunSite(args []string) error {
+	fs := flag.NewFlagSet("site", flag.ExitOnError)
+	output := fs.String("output", "dist/site", "Directory to write the site to")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	repo := fs.String("repo", "rensetsu/db.trakt.extended-anitrakt", "GitHub repository that problem reports are filed against")
+	fs.Parse(args)
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+	var notFoundTV, notFoundMovies []NotFoundEntry
+	LoadJSONOptional(notFoundPath(*tvFile), &notFoundTV)
+	LoadJSONOptional(notFoundPath(*movieFile), &notFoundMovies)
+	SortEntries(shows, SortMalID)
+	SortEntries(movies, SortMalID)
+
+	entries := make([]siteEntry, 0, len(shows)+len(movies)+len(notFoundTV)+len(notFoundMovies))
+	for _, show := range shows {
+		entries = append(entries, showSiteEntry(show, *repo))
+	}
+	for _, movie := range movies {
+		entries = append(entries, movieSiteEntry(movie, *repo))
+	}
+	for _, entry := range notFoundTV {
+		entries = append(entries, notFoundSiteEntry("tv", entry, *repo))
+	}
+	for _, entry := range notFoundMovies {
+		entries = append(entries, notFoundSiteEntry("movies", entry, *repo))
+	}
+
+	// Entry pages are rewritten from scratch so removed entries disappear
+	for _, dir := range []string{"tv", "movies"} {
+		if err := os.RemoveAll(filepath.Join(*output, dir)); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(*output, dir), 0755); err != nil {
+			return err
+		}
+	}
+	page := sitePage{
+		Root:        "",
+		Title:       "MyAnimeList → Trakt mappings",
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Repo:        *repo,
+		Entries:     entries,
+		Shows:       len(shows),
+		Movies:      len(movies),
+		NotFound:    len(notFoundTV) + len(notFoundMovies),
+	}
+	if err := writeSitePage(filepath.Join(*output, "index.html"), "index", page); err != nil {
+		return err
+	}
+	pages := 0
+	for _, entry := range entries {
+		if entry.Page == "" {
+			continue
+		}
+		entryPage := sitePage{Root: "../", Title: entry.Title, GeneratedAt: page.GeneratedAt, Repo: *repo, Entry: entry}
+		if err := writeSitePage(filepath.Join(*output, filepath.FromSlash(entry.Page)), "entry", entryPage); err != nil {
+			return err
+		}
+		pages++
+	}
+	if err := os.WriteFile(filepath.Join(*output, "style.css"), []byte(siteCSS), 0644); err != nil {
+		return err
+	}
+	// GitHub Pages would otherwise run the site through Jekyll
+	if err := os.WriteFile(filepath.Join(*output, ".nojekyll"), nil, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote the site to %s: %d entry pages, %d not-found entries in the index\n", *output, pages, page.NotFound)
+	return nil
+}
+
+// writeSitePage renders one page template to path
+func writeSitePage(path, name string, page sitePage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := siteTemplates.ExecuteTemplate(f, name, page); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// showSiteEntry builds the site entry of a show
+func showSiteEntry(show OutputShow, repo string) siteEntry {
+	if show.MyAnimeList.URL == "" || show.Trakt.URL == "" {
+		show.setURLs()
+	}
+	entry := siteEntry{
+		MediaType: "tv",
+		MalID:     show.MyAnimeList.ID,
+		Title:     show.MyAnimeList.Title,
+		AltTitles: siteAltTitles(show.MyAnimeList.Title, show.MyAnimeList.Titles),
+		Year:      show.ReleaseYear,
+		Target:    show.Trakt.Title,
+		Page:      fmt.Sprintf("tv/%d.html", show.MyAnimeList.ID),
+	}
+	season, splitCour := "whole show", ""
+	if show.Trakt.IsSplitCour {
+		splitCour = "yes"
+	}
+	if show.Trakt.Season != nil {
+		season = strconv.Itoa(show.Trakt.Season.Number)
+		entry.Target += " · season " + season
+	}
+	entry.Details = siteFields(
+		"MAL ID", strconv.Itoa(show.MyAnimeList.ID),
+		"Trakt show", show.Trakt.Title,
+		"Trakt ID", strconv.Itoa(show.Trakt.ID),
+		"Season", season,
+		"Split cour", splitCour,
+		"Release year", siteNumber(show.ReleaseYear),
+		"Status", show.Status,
+		"Network", show.Network,
+		"Genres", strings.Join(show.Genres, ", "),
+		"Mapping quality", show.Quality,
+	)
+
+	entry.Links = append(entry.Links, siteLink{"MyAnimeList", show.MyAnimeList.URL}, siteLink{"Trakt", show.Trakt.URL})
+	if show.Externals != nil {
+		if show.Externals.TVDB != nil {
+			entry.Links = append(entry.Links, siteLink{"TVDB", fmt.Sprintf("https://thetvdb.com/dereferrer/series/%d", *show.Externals.TVDB)})
+		}
+		if show.Externals.TMDB != nil {
+			entry.Links = append(entry.Links, siteLink{"TMDB", fmt.Sprintf("https://www.themoviedb.org/tv/%d", *show.Externals.TMDB)})
+		}
+		if show.Externals.IMDB != nil && *show.Externals.IMDB != "" {
+			entry.Links = append(entry.Links, siteLink{"IMDb", "https://www.imdb.com/title/" + *show.Externals.IMDB + "/"})
+		}
+	}
+	entry.Links = append(entry.Links, sitePartLinks(show.Parts)...)
+	finishSiteEntry(&entry, show, show.Trakt.URL, repo)
+	return entry
+}
+
+// movieSiteEntry builds the site entry of a movie
+func movieSiteEntry(movie OutputMovie, repo string) siteEntry {
+	if movie.MyAnimeList.URL == "" || movie.Trakt.URL == "" {
+		movie.setURLs()
+	}
+	entry := siteEntry{
+		MediaType: "movies",
+		MalID:     movie.MyAnimeList.ID,
+		Title:     movie.MyAnimeList.Title,
+		AltTitles: siteAltTitles(movie.MyAnimeList.Title, movie.MyAnimeList.Titles),
+		Year:      movie.ReleaseYear,
+		Target:    movie.Trakt.Title,
+		Page:      fmt.Sprintf("movies/%d.html", movie.MyAnimeList.ID),
+	}
+	entry.Details = siteFields(
+		"MAL ID", strconv.Itoa(movie.MyAnimeList.ID),
+		"Trakt movie", movie.Trakt.Title,
+		"Trakt ID", strconv.Itoa(movie.Trakt.ID),
+		"Release year", siteNumber(movie.ReleaseYear),
+		"Genres", strings.Join(movie.Genres, ", "),
+		"Mapping quality", movie.Quality,
+	)
+
+	entry.Links = append(entry.Links, siteLink{"MyAnimeList", movie.MyAnimeList.URL}, siteLink{"Trakt", movie.Trakt.URL})
+	if movie.Externals != nil {
+		if movie.Externals.TMDB != nil {
+			entry.Links = append(entry.Links, siteLink{"TMDB", fmt.Sprintf("https://www.themoviedb.org/movie/%d", *movie.Externals.TMDB)})
+		}
+		if movie.Externals.IMDB != nil && *movie.Externals.IMDB != "" {
+			entry.Links = append(entry.Links, siteLink{"IMDb", "https://www.imdb.com/title/" + *movie.Externals.IMDB + "/"})
+		}
+		if lb := movie.Externals.Letterboxd; lb != nil && lb.Slug != nil && *lb.Slug != "" {
+			entry.Links = append(entry.Links, siteLink{"Letterboxd", "https://letterboxd.com/film/" + *lb.Slug + "/"})
+		}
+	}
+	entry.Links = append(entry.Links, sitePartLinks(movie.Parts)...)
+	finishSiteEntry(&entry, movie, movie.Trakt.URL, repo)
+	return entry
+}
+
+// notFoundSiteEntry builds the index row of an entry not found on Trakt
+func notFoundSiteEntry(mediaType string, notFound NotFoundEntry, repo string) siteEntry {
+	entry := siteEntry{MediaType: mediaType, MalID: notFound.MalID, Title: notFound.Title}
+	entry.Search = strings.ToLower(fmt.Sprintf("%d %s", notFound.MalID, notFound.Title))
+	entry.ReportURL = siteReportURL(repo,
+		fmt.Sprintf("Missing mapping: MAL %d %s", notFound.MalID, notFound.Title),
+		fmt.Sprintf("MyAnimeList: https://myanimelist.net/anime/%d\nTrakt URL of this entry: \n", notFound.MalID))
+	return entry
+}
+
+// finishSiteEntry fills the fields shared by shows and movies
+func finishSiteEntry(entry *siteEntry, output any, traktURL, repo string) {
+	data, _ := json.MarshalIndent(output, "", "  ")
+	entry.JSON = string(data)
+	entry.Search = strings.ToLower(strings.Join(append([]string{strconv.Itoa(entry.MalID), entry.Title, entry.Target}, entry.AltTitles...), " "))
+	entry.ReportURL = siteReportURL(repo,
+		fmt.Sprintf("Wrong mapping: MAL %d %s", entry.MalID, entry.Title),
+		fmt.Sprintf("MyAnimeList: https://myanimelist.net/anime/%d\nMapped to: %s\n\nWhat is wrong:\n", entry.MalID, traktURL))
+}
+
+// siteReportURL returns a link opening a prefilled GitHub issue
+func siteReportURL(repo, title, body string) string {
+	return "https://github.com/" + repo + "/issues/new?" + url.Values{"title": {title}, "body": {body}}.Encode()
+}
+
+// siteAltTitles lists the jikan titles that differ from the main one
+func siteAltTitles(title string, titles *MALTitles) []string {
+	if titles == nil {
+		return nil
+	}
+	var alt []string
+	for _, t := range []string{titles.English, titles.Native, titles.Romaji} {
+		if t != "" && t != title {
+			alt = append(alt, t)
+		}
+	}
+	return alt
+}
+
+// sitePartLinks links the Trakt targets of a multi-part override
+func sitePartLinks(parts []TraktPart) []siteLink {
+	var links []siteLink
+	for _, part := range parts {
+		links = append(links, siteLink{fmt.Sprintf("Trakt (part %d)", part.Order), part.URL})
+	}
+	return links
+}
+
+// siteFields pairs up names and values, dropping empty values
+func siteFields(pairs ...string) []siteField {
+	var fields []siteField
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			fields = append(fields, siteField{pairs[i], pairs[i+1]})
+		}
+	}
+	return fields
+}
+
+// siteNumber formats a number, with "" for zero
+func siteNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
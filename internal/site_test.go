@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSite(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	shows := data.ShowList()
+	shows[0].MyAnimeList.Title = "<b>Bebop</b>"
+	SaveJSON("tv_ex.json", shows)
+	SaveJSON("movies_ex.json", data.MovieList())
+	os.MkdirAll("json/not_found", 0755)
+	SaveJSON(notFoundPath("tv_ex.json"), []NotFoundEntry{{MalID: 99, Title: "Missing Show"}})
+	// A page of an entry that is gone since must not survive a rebuild
+	os.MkdirAll("site/tv", 0755)
+	os.WriteFile("site/tv/12345.html", nil, 0644)
+
+	if err := RunSite([]string{"-output", "site", "-tv", "tv_ex.json", "-movies", "movies_ex.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := os.ReadFile("site/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`id="search"`, `href="tv/1.html"`, `href="movies/43.html"`, "Missing Show", "issues/new?", "&lt;b&gt;Bebop&lt;/b&gt;"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html lacks %q", want)
+		}
+	}
+	page, err := os.ReadFile("site/tv/1.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"https://myanimelist.net/anime/1", "https://trakt.tv/shows/", `href="../style.css"`, "Report a problem"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("tv/1.html lacks %q", want)
+		}
+	}
+	for _, path := range []string{"style.css", ".nojekyll", "movies/43.html"} {
+		if _, err := os.Stat(filepath.Join("site", path)); err != nil {
+			t.Errorf("missing %s: %v", path, err)
+		}
+	}
+	if _, err := os.Stat("site/tv/12345.html"); !os.IsNotExist(err) {
+		t.Errorf("stale page tv/12345.html survived the rebuild")
+	}
+}
@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeFormat names snapshots after the UTC time they were taken, so
+// file name order is age order
+const snapshotTimeFormat = "20060102T150405.000000000Z"
+
+// snapshotDirFor returns the snapshot directory of an output file, e.g.
+// json/snapshots/tv_ex for json/output/tv_ex.json
+func snapshotDirFor(outputFile string) string {
+	return filepath.Join(layout.SnapshotsDir(), strings.TrimSuffix(filepath.Base(outputFile), ".json"))
+}
+
+// listSnapshots lists the snapshots of an output file, oldest first
+func listSnapshots(outputFile string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(snapshotDirFor(outputFile), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// takeSnapshot copies the output file just written into its snapshot
+// directory and removes all but the newest keep snapshots. Failures only
+// warn: the output itself was written.
+func takeSnapshot(outputFile string, keep int, verbose bool) {
+	if keep <= 0 || outputFile == StdioPath {
+		return
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		log.Printf("Warning: Failed to snapshot %s: %v", outputFile, err)
+		return
+	}
+	dir := snapshotDirFor(outputFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Warning: Failed to create snapshot directory %s: %v", dir, err)
+		return
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format(snapshotTimeFormat)+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("Warning: Failed to snapshot %s: %v", outputFile, err)
+		return
+	}
+	if verbose {
+		Debugf("\nSnapshot of %s saved to %s", outputFile, path)
+	}
+
+	paths, _ := listSnapshots(outputFile)
+	for len(paths) > keep {
+		if err := os.Remove(paths[0]); err != nil {
+			log.Printf("Warning: Failed to remove old snapshot %s: %v", paths[0], err)
+		}
+		paths = paths[1:]
+	}
+}
+
+// findSnapshot resolves a snapshot name given on the command line: a full
+// name as printed by `snapshot ls` or a unique prefix of one, e.g. 20250101
+func findSnapshot(outputFile, name string) (string, error) {
+	paths, err := listSnapshots(outputFile)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, path := range paths {
+		if strings.HasPrefix(filepath.Base(path), strings.TrimSuffix(name, ".json")) {
+			matches = append(matches, path)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no snapshot %q of %s (see `snapshot ls`)", name, outputFile)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("snapshot %q of %s is ambiguous: %d match", name, outputFile, len(matches))
+}
+
+// RunSnapshot implements the `snapshot` subcommand, which inspects and
+// restores the snapshots runs keep with -snapshots
+func RunSnapshot(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: snapshot ls|diff|rollback [-output FILE] [snapshot...]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("snapshot "+action, flag.ExitOnError)
+	outputFile := fs.String("output", defaultOutput("tv"), "Output file whose snapshots to use")
+	fs.Parse(args[1:])
+
+	switch action {
+	case "ls":
+		return runSnapshotList(*outputFile)
+	case "diff":
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			return fmt.Errorf("usage: snapshot diff [-output FILE] SNAPSHOT [SNAPSHOT] (the second defaults to the output file)")
+		}
+		return runSnapshotDiff(*outputFile, fs.Args())
+	case "rollback":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: snapshot rollback [-output FILE] SNAPSHOT")
+		}
+		return runSnapshotRollback(*outputFile, fs.Arg(0))
+	default:
+		return fmt.Errorf("unknown snapshot action %q (expected ls, diff or rollback)", action)
+	}
+}
+
+// runSnapshotList prints the snapshots of an output file with their entry
+// counts, oldest first
+func runSnapshotList(outputFile string) error {
+	paths, err := listSnapshots(outputFile)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fmt.Printf("%s: no snapshots\n", outputFile)
+		return nil
+	}
+	fmt.Printf("%-30s %8s %10s\n", "SNAPSHOT", "ENTRIES", "SIZE")
+	for _, path := range paths {
+		docs, _, err := loadOutputDocuments(path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-30s %8d %10s\n", strings.TrimSuffix(filepath.Base(path), ".json"), len(docs), formatBytes(info.Size()))
+	}
+	return nil
+}
+
+// runSnapshotDiff prints the entries that differ between two snapshots, or a
+// snapshot and the current output file, down to the changed fields
+func runSnapshotDiff(outputFile string, names []string) error {
+	oldPath, err := findSnapshot(outputFile, names[0])
+	if err != nil {
+		return err
+	}
+	newPath := outputFile
+	if len(names) == 2 {
+		if newPath, err = findSnapshot(outputFile, names[1]); err != nil {
+			return err
+		}
+	}
+	oldDocs, oldTitles, err := loadOutputDocuments(oldPath)
+	if err != nil {
+		return err
+	}
+	newDocs, titles, err := loadOutputDocuments(newPath)
+	if err != nil {
+		return err
+	}
+	for malID, title := range oldTitles {
+		if _, ok := titles[malID]; !ok {
+			titles[malID] = title
+		}
+	}
+
+	changes := diffDocuments(strings.TrimSuffix(filepath.Base(outputFile), ".json"), oldDocs, newDocs, titles)
+	fmt.Printf("%s → %s: %d changes\n", oldPath, newPath, len(changes))
+	for _, change := range changes {
+		switch change.Change {
+		case "created":
+			fmt.Printf("+ %d %s\n", change.MalID, change.Title)
+		case "removed":
+			fmt.Printf("- %d %s\n", change.MalID, change.Title)
+		case "updated":
+			fmt.Printf("~ %d %s\n", change.MalID, change.Title)
+			for _, line := range diffFields(change.Old, change.New) {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	return nil
+}
+
+// runSnapshotRollback restores a snapshot as the output file. The current
+// output is snapshotted first, so a rollback can itself be rolled back.
+func runSnapshotRollback(outputFile, name string) error {
+	path, err := findSnapshot(outputFile, name)
+	if err != nil {
+		return err
+	}
+	if journals, err := pendingJournals(outputFile); err != nil {
+		return err
+	} else if len(journals) > 0 {
+		return fmt.Errorf("%s has %d pending journals that would be replayed on top of the snapshot; run `compact` first", outputFile, len(journals))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	paths, _ := listSnapshots(outputFile)
+	takeSnapshot(outputFile, len(paths)+1, false)
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+	if err := refreshKeyedOutput(outputFile); err != nil {
+		return err
+	}
+	WriteManifest()
+	fmt.Printf("Rolled %s back to %s\n", outputFile, path)
+	return nil
+}
+
+// loadOutputDocuments reads an output file or snapshot into compacted
+// documents and titles keyed by MAL ID
+func loadOutputDocuments(path string) (map[int]json.RawMessage, map[int]string, error) {
+	var entries []json.RawMessage
+	if err := LoadJSON(path, &entries); err != nil {
+		return nil, nil, err
+	}
+	docs := make(map[int]json.RawMessage, len(entries))
+	titles := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		var head struct {
+			MyAnimeList struct {
+				ID    int    `json:"id"`
+				Title string `json:"title"`
+			} `json:"myanimelist"`
+		}
+		if err := json.Unmarshal(entry, &head); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var compact bytes.Buffer
+		json.Compact(&compact, entry)
+		docs[head.MyAnimeList.ID] = compact.Bytes()
+		titles[head.MyAnimeList.ID] = head.MyAnimeList.Title
+	}
+	return docs, titles, nil
+}
+
+// diffFields lists the leaf fields that differ between two documents as
+// "path: old → new" lines, sorted by path
+func diffFields(oldDoc, newDoc json.RawMessage) []string {
+	oldFields, newFields := map[string]string{}, map[string]string{}
+	var oldValue, newValue any
+	json.Unmarshal(oldDoc, &oldValue)
+	json.Unmarshal(newDoc, &newValue)
+	flattenJSON("", oldValue, oldFields)
+	flattenJSON("", newValue, newFields)
+
+	var lines []string
+	for path, value := range newFields {
+		if old, ok := oldFields[path]; !ok {
+			lines = append(lines, fmt.Sprintf("%s: (none) → %s", path, value))
+		} else if old != value {
+			lines = append(lines, fmt.Sprintf("%s: %s → %s", path, old, value))
+		}
+	}
+	for path, old := range oldFields {
+		if _, ok := newFields[path]; !ok {
+			lines = append(lines, fmt.Sprintf("%s: %s → (none)", path, old))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// flattenJSON records the leaves of a decoded JSON value by dotted path,
+// e.g. trakt.season.number
+func flattenJSON(prefix string, value any, out map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flattenJSON(joinPath(prefix, key), child, out)
+		}
+	case []any:
+		for i, child := range v {
+			flattenJSON(joinPath(prefix, fmt.Sprint(i)), child, out)
+		}
+	default:
+		encoded, _ := json.Marshal(v)
+		out[prefix] = string(encoded)
+	}
+}
+
+// joinPath appends a key to a dotted path
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRetentionAndRollback(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputFile := "tv_ex.json"
+	config := Config{Snapshots: 2}
+
+	shows := testDataset(t).ShowList()
+	resultsMap := map[int]OutputShow{}
+	for _, show := range shows {
+		resultsMap[show.MyAnimeList.ID] = show
+	}
+	StoreResults(config, outputFile, nil, resultsMap)
+	first, _ := os.ReadFile(outputFile)
+
+	for _, title := range []string{"Renamed once", "Renamed twice"} {
+		renamed := resultsMap[5]
+		renamed.MyAnimeList.Title = title
+		resultsMap[5] = renamed
+		existing, _ := LoadShowOutput(outputFile)
+		StoreResults(config, outputFile, existing, resultsMap)
+	}
+
+	paths, _ := listSnapshots(outputFile)
+	if len(paths) != 2 {
+		t.Fatalf("kept %d snapshots, want 2: %v", len(paths), paths)
+	}
+	if data, _ := os.ReadFile(paths[1]); string(data) == string(first) {
+		t.Error("newest snapshot holds the first run")
+	}
+
+	// Write the first version back as a snapshot to roll back to
+	oldest := filepath.Join(snapshotDirFor(outputFile), "20000101T000000.000000000Z.json")
+	os.WriteFile(oldest, first, 0644)
+	if err := RunSnapshot([]string{"rollback", "-output", outputFile, "2000"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outputFile); string(data) != string(first) {
+		t.Error("rollback did not restore the snapshot")
+	}
+	if paths, _ := listSnapshots(outputFile); len(paths) != 4 {
+		t.Errorf("rollback left %d snapshots, want the 3 before it and the replaced output", len(paths))
+	}
+
+	if _, err := findSnapshot(outputFile, "2"); err == nil {
+		t.Error("findSnapshot accepted an ambiguous prefix")
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	oldDoc := []byte(`{"trakt":{"id":1,"season":{"number":1}},"title":"A"}`)
+	newDoc := []byte(`{"trakt":{"id":2,"season":{"number":1}},"tmdb":{"id":3}}`)
+	want := []string{
+		"title: \"A\" → (none)",
+		"tmdb.id: (none) → 3",
+		"trakt.id: 1 → 2",
+	}
+	if got := diffFields(oldDoc, newDoc); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffFields = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SortOrder is the entry order of the output files and list exports (-sort)
+type SortOrder string
+
+// Sort orders; ties are broken by MAL ID
+const (
+	SortMalID   SortOrder = "mal_id"
+	SortTraktID SortOrder = "trakt_id"
+	SortTitle   SortOrder = "title" // MAL title, case-insensitive
+	SortYear    SortOrder = "year"  // release year
+)
+
+// sortUsage is the usage text of every -sort flag
+const sortUsage = "Entry order of the written file: mal_id, trakt_id, title or year"
+
+// ParseSortOrder parses a -sort value; "" is mal_id
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch order := SortOrder(s); order {
+	case "":
+		return SortMalID, nil
+	case SortMalID, SortTraktID, SortTitle, SortYear:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown sort order %q (expected mal_id, trakt_id, title or year)", s)
+}
+
+// entrySortKey holds the fields an entry can be sorted by
+type entrySortKey struct {
+	malID   int
+	traktID int
+	title   string
+	year    int
+}
+
+// sortable is an output entry, OutputShow or OutputMovie
+type sortable interface {
+	sortKey() entrySortKey
+}
+
+func (s OutputShow) sortKey() entrySortKey {
+	return entrySortKey{s.MyAnimeList.ID, s.Trakt.ID, strings.ToLower(s.MyAnimeList.Title), s.ReleaseYear}
+}
+
+func (m OutputMovie) sortKey() entrySortKey {
+	return entrySortKey{m.MyAnimeList.ID, m.Trakt.ID, strings.ToLower(m.MyAnimeList.Title), m.ReleaseYear}
+}
+
+// SortEntries sorts output entries in place by order
+func SortEntries[T sortable](entries []T, order SortOrder) {
+	keys := make([]entrySortKey, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.sortKey()
+	}
+	sort.Sort(entrySorter[T]{entries, keys, order})
+}
+
+// entrySorter sorts entries along with their precomputed keys
+type entrySorter[T any] struct {
+	entries []T
+	keys    []entrySortKey
+	order   SortOrder
+}
+
+func (s entrySorter[T]) Len() int { return len(s.entries) }
+
+func (s entrySorter[T]) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s entrySorter[T]) Less(i, j int) bool {
+	a, b := s.keys[i], s.keys[j]
+	switch s.order {
+	case SortTraktID:
+		if a.traktID != b.traktID {
+			return a.traktID < b.traktID
+		}
+	case SortTitle:
+		if a.title != b.title {
+			return a.title < b.title
+		}
+	case SortYear:
+		if a.year != b.year {
+			return a.year < b.year
+		}
+	}
+	return a.malID < b.malID
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortEntries(t *testing.T) {
+	show := func(malID, traktID int, title string, year int) OutputShow {
+		var s OutputShow
+		s.MyAnimeList.ID, s.MyAnimeList.Title = malID, title
+		s.Trakt.ID, s.ReleaseYear = traktID, year
+		return s
+	}
+	shows := []OutputShow{
+		show(30, 100, "bleach", 2004),
+		show(10, 300, "Akira", 1988),
+		show(20, 100, "Cowboy Bebop", 1998),
+	}
+	tests := []struct {
+		order SortOrder
+		want  []int
+	}{
+		{SortMalID, []int{10, 20, 30}},
+		{SortTraktID, []int{20, 30, 10}}, // tie on Trakt ID 100 broken by MAL ID
+		{SortTitle, []int{10, 30, 20}},   // case-insensitive
+		{SortYear, []int{10, 20, 30}},
+	}
+	for _, tt := range tests {
+		SortEntries(shows, tt.order)
+		var got []int
+		for _, s := range shows {
+			got = append(got, s.MyAnimeList.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortEntries(%s) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	if order, err := ParseSortOrder(""); err != nil || order != SortMalID {
+		t.Errorf(`ParseSortOrder("") = %q, %v`, order, err)
+	}
+	if _, err := ParseSortOrder("popularity"); err == nil {
+		t.Error("ParseSortOrder accepted an unknown order")
+	}
+}
@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteSchema creates the tables, Datasette-friendly views and FTS indexes.
+// Full-text indexes use external content tables so Datasette detects them
+// automatically and offers a search box on the shows and movies tables.
+const sqliteSchema = `
+CREATE TABLE shows (
+  mal_id INTEGER PRIMARY KEY,
+  mal_title TEXT NOT NULL,
+  trakt_id INTEGER NOT NULL,
+  trakt_slug TEXT NOT NULL,
+  trakt_title TEXT NOT NULL,
+  season_number INTEGER,
+  season_trakt_id INTEGER,
+  season_tvdb INTEGER,
+  season_tmdb INTEGER,
+  is_split_cour INTEGER NOT NULL,
+  release_year INTEGER,
+  tvdb INTEGER,
+  tmdb INTEGER,
+  imdb TEXT
+);
+CREATE INDEX shows_trakt_id ON shows (trakt_id);
+
+CREATE TABLE movies (
+  mal_id INTEGER PRIMARY KEY,
+  mal_title TEXT NOT NULL,
+  trakt_id INTEGER NOT NULL,
+  trakt_slug TEXT NOT NULL,
+  trakt_title TEXT NOT NULL,
+  release_year INTEGER,
+  tmdb INTEGER,
+  imdb TEXT,
+  letterboxd_slug TEXT,
+  letterboxd_lid TEXT,
+  letterboxd_uid INTEGER
+);
+CREATE INDEX movies_trakt_id ON movies (trakt_id);
+
+CREATE TABLE not_found (
+  media_type TEXT NOT NULL,
+  mal_id INTEGER NOT NULL,
+  title TEXT,
+  PRIMARY KEY (media_type, mal_id)
+);
+
+CREATE TABLE changes (
+  recorded_at TEXT NOT NULL,
+  media_type TEXT NOT NULL,
+  change TEXT NOT NULL,
+  mal_id INTEGER NOT NULL,
+  title TEXT
+);
+
+CREATE VIEW shows_with_externals AS
+SELECT
+  s.mal_id, s.mal_title, s.trakt_title, s.trakt_slug, s.season_number, s.is_split_cour, s.release_year,
+  s.tvdb, s.tmdb, s.imdb, s.season_tvdb, s.season_tmdb,
+  'https://myanimelist.net/anime/' || s.mal_id AS mal_url,
+  'https://trakt.tv/shows/' || s.trakt_slug ||
+    CASE WHEN s.season_number IS NOT NULL THEN '/seasons/' || s.season_number ELSE '' END AS trakt_url,
+  CASE WHEN s.tvdb IS NOT NULL THEN 'https://thetvdb.com/dereferrer/series/' || s.tvdb END AS tvdb_url,
+  CASE WHEN s.tmdb IS NOT NULL THEN 'https://www.themoviedb.org/tv/' || s.tmdb END AS tmdb_url,
+  CASE WHEN s.imdb IS NOT NULL THEN 'https://www.imdb.com/title/' || s.imdb || '/' END AS imdb_url
+FROM shows s;
+
+CREATE VIEW unresolved AS
+SELECT media_type, mal_id, title, 'not found on Trakt' AS reason FROM not_found
+UNION ALL
+SELECT 'tv', mal_id, mal_title, 'split cour (season not found)' FROM shows WHERE is_split_cour = 1
+UNION ALL
+SELECT 'tv', mal_id, mal_title, 'missing TVDB and TMDB IDs' FROM shows WHERE tvdb IS NULL AND tmdb IS NULL
+UNION ALL
+SELECT 'movies', mal_id, mal_title, 'missing TMDB ID' FROM movies WHERE tmdb IS NULL
+UNION ALL
+SELECT 'movies', mal_id, mal_title, 'missing Letterboxd' FROM movies WHERE tmdb IS NOT NULL AND letterboxd_slug IS NULL;
+
+CREATE VIEW recent_changes AS
+SELECT recorded_at, media_type, change, mal_id, title,
+  'https://myanimelist.net/anime/' || mal_id AS mal_url
+FROM changes
+ORDER BY recorded_at DESC, media_type, mal_id;
+
+CREATE VIRTUAL TABLE shows_fts USING fts5(mal_title, trakt_title, content='shows', content_rowid='mal_id');
+CREATE VIRTUAL TABLE movies_fts USING fts5(mal_title, trakt_title, content='movies', content_rowid='mal_id');
+`
+
+// defaultSQLitePath is where export-sqlite writes, and where bundle picks the
+// database up
+const defaultSQLitePath = "dist/anitrakt.sqlite"
+
+// RunExportSQLite implements the `export-sqlite` subcommand
+func RunExportSQLite(args []string) error {
+	fs := flag.NewFlagSet("export-sqlite", flag.ExitOnError)
+	output := fs.String("output", defaultSQLitePath, "Path of the SQLite database to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	prevTV := fs.String("previous-tv", "", "Previous TV output, used to populate recent_changes instead of the changelogs")
+	prevMovies := fs.String("previous-movies", "", "Previous movie output, used to populate recent_changes instead of the changelogs")
+	changesDir := fs.String("changes-dir", layout.ChangesDir(), "Directory of the changelogs populating recent_changes")
+	changesDays := fs.Int("changes-days", 30, "Days of changelogs to load into recent_changes")
+	sqlOnly := fs.Bool("sql-only", false, "Only write the SQL script, do not invoke sqlite3")
+	fs.Parse(args)
+
+	var oldShows []OutputShow
+	var oldMovies []OutputMovie
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+
+	var notFound []NotFoundEntry
+	var notFoundRows []sqliteNotFound
+	LoadJSONOptional(notFoundPath(*tvFile), &notFound)
+	for _, entry := range notFound {
+		notFoundRows = append(notFoundRows, sqliteNotFound{"tv", entry})
+	}
+	notFound = nil
+	LoadJSONOptional(notFoundPath(*movieFile), &notFound)
+	for _, entry := range notFound {
+		notFoundRows = append(notFoundRows, sqliteNotFound{"movies", entry})
+	}
+
+	var changes []EntryChange
+	if *prevTV != "" {
+		LoadJSONOptional(*prevTV, &oldShows)
+		changes = append(changes, DiffShows(oldShows, shows)...)
+	}
+	if *prevMovies != "" {
+		LoadJSONOptional(*prevMovies, &oldMovies)
+		changes = append(changes, DiffMovies(oldMovies, movies)...)
+	}
+	if *prevTV == "" && *prevMovies == "" && *changesDir != "" {
+		since := NewRunID(time.Now().AddDate(0, 0, -*changesDays))
+		logged, err := readChangelogs(*changesDir, since)
+		if err != nil {
+			return fmt.Errorf("read changelogs: %w", err)
+		}
+		for _, change := range logged {
+			if change.RunID == "" || change.RunID >= since {
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		return err
+	}
+	sqlPath := strings.TrimSuffix(*output, filepath.Ext(*output)) + ".sql"
+	if err := writeSQLiteScript(sqlPath, shows, movies, notFoundRows, changes); err != nil {
+		return fmt.Errorf("write %s: %w", sqlPath, err)
+	}
+	fmt.Printf("Wrote SQL script %s\n", sqlPath)
+
+	if *sqlOnly {
+		return nil
+	}
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return fmt.Errorf("sqlite3 not found in PATH; pass -sql-only, or load the script manually with: sqlite3 %s < %s", *output, sqlPath)
+	}
+
+	// Build into a temp file and rename so readers never see a partial database
+	tmpPath := *output + ".tmp"
+	os.Remove(tmpPath)
+	script, err := os.Open(sqlPath)
+	if err != nil {
+		return err
+	}
+	defer script.Close()
+	cmd := exec.Command(sqlite, "-bail", tmpPath)
+	cmd.Stdin = script
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("sqlite3: %w", err)
+	}
+	if err := os.Rename(tmpPath, *output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d shows, %d movies and %d changes to %s\n", len(shows), len(movies), len(changes), *output)
+	return nil
+}
+
+// SQLiteSink keeps a SQLite database, with the schema of export-sqlite, up
+// to date with each run: the media type's table is replaced and its changes
+// are appended. It needs sqlite3 in PATH.
+type SQLiteSink struct {
+	Path string
+}
+
+func (SQLiteSink) Name() string { return "sqlite" }
+
+func (s SQLiteSink) Write(batch SinkBatch) error {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return err
+	}
+	_, statErr := os.Stat(s.Path)
+	var script bytes.Buffer
+	writeSQLiteBatch(&script, batch, os.IsNotExist(statErr))
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	cmd := exec.Command(sqlite, "-bail", s.Path)
+	cmd.Stdin = &script
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sqlite3: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	if batch.Config.Verbose {
+		Debugf("\nUpdated the %s table of %s", batch.MediaType, s.Path)
+	}
+	return nil
+}
+
+// writeSQLiteBatch renders the transaction replacing the batch's table;
+// withSchema creates the tables first, for a new database
+func writeSQLiteBatch(w io.Writer, batch SinkBatch, withSchema bool) {
+	fmt.Fprintln(w, "BEGIN;")
+	if withSchema {
+		fmt.Fprint(w, sqliteSchema)
+	}
+	if batch.MediaType == "movies" {
+		movies := sortedResults(batch.Movies, SortMalID)
+		fmt.Fprintln(w, "DELETE FROM movies;")
+		writeSQLiteMovies(w, movies)
+		writeSQLiteChanges(w, DiffMovies(batch.ExistingMovies, movies))
+		fmt.Fprintln(w, "INSERT INTO movies_fts(movies_fts) VALUES ('rebuild');")
+	} else {
+		shows := sortedResults(batch.Shows, SortMalID)
+		fmt.Fprintln(w, "DELETE FROM shows;")
+		writeSQLiteShows(w, shows)
+		writeSQLiteChanges(w, DiffShows(batch.ExistingShows, shows))
+		fmt.Fprintln(w, "INSERT INTO shows_fts(shows_fts) VALUES ('rebuild');")
+	}
+	fmt.Fprintln(w, "COMMIT;")
+}
+
+// sqliteNotFound pairs a not-found entry with its media type
+type sqliteNotFound struct {
+	mediaType string
+	entry     NotFoundEntry
+}
+
+// writeSQLiteScript renders the schema and all rows as a single transaction
+func writeSQLiteScript(path string, shows []OutputShow, movies []OutputMovie, notFound []sqliteNotFound, changes []EntryChange) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintln(w, "PRAGMA journal_mode = OFF;")
+	fmt.Fprintln(w, "BEGIN;")
+	fmt.Fprint(w, sqliteSchema)
+
+	writeSQLiteShows(w, shows)
+	writeSQLiteMovies(w, movies)
+
+	for _, row := range notFound {
+		fmt.Fprintf(w, "INSERT OR IGNORE INTO not_found VALUES (%s, %d, %s);\n",
+			sqlText(row.mediaType), row.entry.MalID, sqlText(row.entry.Title))
+	}
+
+	writeSQLiteChanges(w, changes)
+
+	fmt.Fprintln(w, "INSERT INTO shows_fts(shows_fts) VALUES ('rebuild');")
+	fmt.Fprintln(w, "INSERT INTO movies_fts(movies_fts) VALUES ('rebuild');")
+	fmt.Fprintln(w, "COMMIT;")
+	fmt.Fprintln(w, "VACUUM;")
+	return w.Flush()
+}
+
+// writeSQLiteShows renders an INSERT per show
+func writeSQLiteShows(w io.Writer, shows []OutputShow) {
+	for _, show := range shows {
+		var seasonNumber, seasonID, seasonTVDB, seasonTMDB *int
+		if show.Trakt.Season != nil {
+			seasonNumber = &show.Trakt.Season.Number
+			seasonID = &show.Trakt.Season.ID
+			if show.Trakt.Season.Externals != nil {
+				seasonTVDB = show.Trakt.Season.Externals.TVDB
+				seasonTMDB = show.Trakt.Season.Externals.TMDB
+			}
+		}
+		var tvdb, tmdb *int
+		var imdb *string
+		if show.Externals != nil {
+			tvdb, tmdb, imdb = show.Externals.TVDB, show.Externals.TMDB, show.Externals.IMDB
+		}
+		fmt.Fprintf(w, "INSERT INTO shows VALUES (%d, %s, %d, %s, %s, %s, %s, %s, %s, %s, %d, %s, %s, %s);\n",
+			show.MyAnimeList.ID, sqlText(show.MyAnimeList.Title), show.Trakt.ID, sqlText(show.Trakt.Slug),
+			sqlText(show.Trakt.Title), sqlInt(seasonNumber), sqlInt(seasonID), sqlInt(seasonTVDB), sqlInt(seasonTMDB),
+			sqlBool(show.Trakt.IsSplitCour), show.ReleaseYear, sqlInt(tvdb), sqlInt(tmdb), sqlTextPtr(imdb))
+	}
+}
+
+// writeSQLiteMovies renders an INSERT per movie
+func writeSQLiteMovies(w io.Writer, movies []OutputMovie) {
+	for _, movie := range movies {
+		var tmdb, lbUID *int
+		var imdb, lbSlug, lbLID *string
+		if movie.Externals != nil {
+			tmdb, imdb = movie.Externals.TMDB, movie.Externals.IMDB
+			if lb := movie.Externals.Letterboxd; lb != nil {
+				lbSlug, lbLID, lbUID = lb.Slug, lb.LID, lb.UID
+			}
+		}
+		fmt.Fprintf(w, "INSERT INTO movies VALUES (%d, %s, %d, %s, %s, %d, %s, %s, %s, %s, %s);\n",
+			movie.MyAnimeList.ID, sqlText(movie.MyAnimeList.Title), movie.Trakt.ID, sqlText(movie.Trakt.Slug),
+			sqlText(movie.Trakt.Title), movie.ReleaseYear, sqlInt(tmdb), sqlTextPtr(imdb),
+			sqlTextPtr(lbSlug), sqlTextPtr(lbLID), sqlInt(lbUID))
+	}
+}
+
+// writeSQLiteChanges renders an INSERT per change, recorded at the start of
+// the run that made it, or now when it names no run
+func writeSQLiteChanges(w io.Writer, changes []EntryChange) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, change := range changes {
+		recordedAt := now
+		if started, err := time.Parse(runIDFormat, change.RunID); err == nil {
+			recordedAt = started.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "INSERT INTO changes VALUES (%s, %s, %s, %d, %s);\n",
+			sqlText(recordedAt), sqlText(change.MediaType), sqlText(change.Change), change.MalID, sqlText(change.Title))
+	}
+}
+
+// sqlText quotes a string literal for SQLite
+func sqlText(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlTextPtr quotes an optional string, rendering nil or empty as NULL
+func sqlTextPtr(s *string) string {
+	if s == nil || *s == "" {
+		return "NULL"
+	}
+	return sqlText(*s)
+}
+
+// sqlInt renders an optional integer, rendering nil as NULL
+func sqlInt(v *int) string {
+	if v == nil {
+		return "NULL"
+	}
+	return strconv.Itoa(*v)
+}
+
+// sqlBool renders a boolean as 0 or 1
+func sqlBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
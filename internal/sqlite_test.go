@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRunExportSQLiteScript(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	shows := data.ShowList()
+	shows[0].MyAnimeList.Title = "Cowboy Bebop: The 'Real' Folk Blues"
+	SaveJSON("tv_ex.json", shows)
+	SaveJSON("movies_ex.json", data.MovieList())
+	SaveJSON("tv_prev.json", shows[:1])
+	os.MkdirAll("json/not_found", 0755)
+	SaveJSON(notFoundPath("tv_ex.json"), []NotFoundEntry{{MalID: 99, Title: "Missing Show"}})
+
+	if err := RunExportSQLite([]string{"-output", "anitrakt.sqlite", "-tv", "tv_ex.json", "-movies", "movies_ex.json", "-previous-tv", "tv_prev.json", "-sql-only"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("anitrakt.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = regexp.MustCompile(`INSERT INTO changes VALUES \('[^']*'`).ReplaceAll(got, []byte(`INSERT INTO changes VALUES ('RECORDED_AT'`))
+	checkGolden(t, "anitrakt.sql", got)
+}
+
+func TestRunExportSQLiteChangesFromChangelogs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	SaveJSON("tv_ex.json", data.ShowList())
+	now := time.Now()
+	SaveJSON(changelogPath("changes", now), Changelog{Changes: []EntryChange{
+		{MediaType: "tv", Change: "created", MalID: 1, Title: "Cowboy Bebop", RunID: NewRunID(now)},
+	}})
+	SaveJSON(changelogPath("changes", now.AddDate(0, 0, -60)), Changelog{Changes: []EntryChange{
+		{MediaType: "tv", Change: "created", MalID: 5, Title: "Too Old", RunID: NewRunID(now.AddDate(0, 0, -60))},
+	}})
+
+	if err := RunExportSQLite([]string{"-output", "anitrakt.sqlite", "-tv", "tv_ex.json", "-movies", "", "-changes-dir", "changes", "-sql-only"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile("anitrakt.sql")
+	inserts := regexp.MustCompile(`INSERT INTO changes VALUES \('([^']*)', 'tv', 'created', (\d+)`).FindAllSubmatch(got, -1)
+	if len(inserts) != 1 || string(inserts[0][2]) != "1" {
+		t.Fatalf("changes = %q, want the one logged in the last 30 days", inserts)
+	}
+	if want := now.UTC().Format("2006-01-02T15:04:05Z"); string(inserts[0][1]) != want {
+		t.Errorf("recorded_at = %s, want the run start %s", inserts[0][1], want)
+	}
+}
+
+func TestRunExportSQLiteFailsWithoutSQLite(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+	SaveJSON("tv_ex.json", testDataset(t).ShowList())
+	if err := RunExportSQLite([]string{"-output", "anitrakt.sqlite", "-tv", "tv_ex.json", "-movies", ""}); err == nil {
+		t.Error("export-sqlite succeeded without sqlite3 and without a database")
+	}
+}
@@ -7,10 +7,43 @@ import (
 	"strings"
 )
 
+// OutputCacheStats outputs the cache hit/miss table of a run
+func OutputCacheStats(counters map[string]CacheCounter) {
+	if len(counters) == 0 {
+		return
+	}
+	output := "\n## Cache - Summary\n\n" + FormatCacheCounters(counters)
+	writeSummary(output)
+}
+
+// FormatCacheCounters renders per-endpoint cache counters as a markdown table
+func FormatCacheCounters(counters map[string]CacheCounter) string {
+	output := "| Endpoint | Memory Hits | Disk Hits | Misses | Hit Rate |\n|----------|-------------|-----------|--------|----------|\n"
+	var total CacheCounter
+	for _, endpoint := range sortedKeys(counters) {
+		counter := counters[endpoint]
+		output += formatCacheRow(endpoint, counter)
+		total.MemoryHits += counter.MemoryHits
+		total.DiskHits += counter.DiskHits
+		total.Misses += counter.Misses
+	}
+	output += formatCacheRow("**Total**", total)
+	output += fmt.Sprintf("\n%d API requests saved by the cache.\n", total.MemoryHits+total.DiskHits)
+	return output
+}
+
+// formatCacheRow renders one row of the cache table
+func formatCacheRow(endpoint string, counter CacheCounter) string {
+	hits := counter.MemoryHits + counter.DiskHits
+	rate := "-"
+	if lookups := hits + counter.Misses; lookups > 0 {
+		rate = fmt.Sprintf("%.1f%%", float64(hits)/float64(lookups)*100)
+	}
+	return fmt.Sprintf("| %s | %d | %d | %d | %s |\n", endpoint, counter.MemoryHits, counter.DiskHits, counter.Misses, rate)
+}
+
 // OutputStats outputs processing statistics
 func OutputStats(mediaType string, stats ProcessingStats) {
-	summaryFile := os.Getenv("GITHUB_STEP_SUMMARY")
-
 	title := strings.ToUpper(mediaType[:1]) + mediaType[1:]
 	diff := stats.TotalAfter - stats.TotalBefore
 	diffStr := fmt.Sprintf("%+d", diff)
@@ -25,6 +58,25 @@ func OutputStats(mediaType string, stats ProcessingStats) {
 	output += fmt.Sprintf("| Updated | - | %d | +%d |\n", stats.Updated, stats.Updated)
 	output += fmt.Sprintf("| Modified (Overridden) | - | %d | +%d |\n", stats.Modified, stats.Modified)
 	output += fmt.Sprintf("| Not Found | - | %d | +%d |\n", stats.NotFound, stats.NotFound)
+	if stats.Healed > 0 {
+		output += fmt.Sprintf("| Healed (Not Found → Found) | - | %d | +%d |\n", stats.Healed, stats.Healed)
+	}
+	if stats.Failed > 0 {
+		output += fmt.Sprintf("| Failed (Errors) | - | %d | - |\n", stats.Failed)
+	}
+	if stats.Redirected > 0 {
+		output += fmt.Sprintf("| Redirected (Trakt Merge/Rename) | - | %d | +%d |\n", stats.Redirected, stats.Redirected)
+	}
+	if stats.Warnings > 0 {
+		output += fmt.Sprintf("| Warnings | - | %d | - |\n", stats.Warnings)
+	}
+
+	if len(stats.Requests) > 0 {
+		output += fmt.Sprintf("\n**API requests:** %s\n", FormatRequests(stats.Requests))
+	}
+	if stats.StoppedEarly != "" {
+		output += fmt.Sprintf("\n**Stopped early:** %s. The remaining entries are picked up by the next run.\n", stats.StoppedEarly)
+	}
 
 	if len(stats.CreatedDetails) > 0 {
 		output += fmt.Sprintf("\n### ✨ Created (%d)\n\n", len(stats.CreatedDetails))
@@ -58,6 +110,23 @@ func OutputStats(mediaType string, stats ProcessingStats) {
 		}
 	}
 
+	if len(stats.HealedDetails) > 0 {
+		output += fmt.Sprintf("\n### 🩹 Healed (%d)\n\n", len(stats.HealedDetails))
+		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
+		for _, detail := range stats.HealedDetails {
+			output += fmt.Sprintf("| %s | %d | %s |\n", detail.Title, detail.MalID, detail.Reason)
+		}
+	}
+
+	if len(stats.RedirectDetails) > 0 {
+		output += fmt.Sprintf("\n### ↪️ Trakt Redirects (%d)\n\n", len(stats.RedirectDetails))
+		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
+		for _, detail := range stats.RedirectDetails {
+			output += fmt.Sprintf("| %s | %d | %s |\n", detail.Title, detail.MalID, detail.Reason)
+		}
+		output += "\n**Note:** The entries now point at the item Trakt kept; the input mapping still names the old one.\n"
+	}
+
 	if len(stats.LetterboxdNotFoundDetails) > 0 {
 		output += fmt.Sprintf("\n### 📽️ Letterboxd Not Found (%d)\n\n", len(stats.LetterboxdNotFoundDetails))
 		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
@@ -67,6 +136,32 @@ func OutputStats(mediaType string, stats ProcessingStats) {
 		output += "\n**Note:** These films exist on Trakt but not on Letterboxd.\n"
 	}
 
+	if len(stats.ValidationDetails) > 0 {
+		output += fmt.Sprintf("\n### 🔎 External ID Checks (%d)\n\n", len(stats.ValidationDetails))
+		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
+		for _, detail := range stats.ValidationDetails {
+			output += fmt.Sprintf("| %s | %d | %s |\n", detail.Title, detail.MalID, detail.Reason)
+		}
+	}
+
+	if len(stats.CollisionDetails) > 0 {
+		output += fmt.Sprintf("\n### 🧭 External ID Collisions (%d)\n\n", len(stats.CollisionDetails))
+		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
+		for _, detail := range stats.CollisionDetails {
+			output += fmt.Sprintf("| %s | %d | %s |\n", detail.Title, detail.MalID, detail.Reason)
+		}
+		output += "\n**Note:** Different Trakt entries sharing an IMDB or TMDB ID usually means one mapping is wrong.\n"
+	}
+
+	if len(stats.WarningDetails) > 0 {
+		output += fmt.Sprintf("\n### 🟡 Warnings (%d)\n\n", len(stats.WarningDetails))
+		output += "| Title | MAL ID | Kind | Reason |\n|-------|--------|------|--------|\n"
+		for _, warning := range stats.WarningDetails {
+			output += fmt.Sprintf("| %s | %d | %s | %s |\n", warning.Title, warning.MalID, warning.Kind, warning.Reason)
+		}
+		output += "\n**Note:** These entries were written, but are worth a look: see `review` for low-confidence mappings.\n"
+	}
+
 	if len(stats.DuplicateDetails) > 0 {
 		output += fmt.Sprintf("\n### ⚠️ Duplicates - Invalid Trakt IDs (%d)\n\n", len(stats.DuplicateDetails))
 		output += "| Title | MAL ID | Reason |\n|-------|--------|--------|\n"
@@ -76,7 +171,16 @@ func OutputStats(mediaType string, stats ProcessingStats) {
 		output += "\n**Note:** These indicate duplicate MAL IDs in the input with multiple Trakt IDs. Consider removing the invalid Trakt IDs from the upstream project.\n"
 	}
 
-	if summaryFile != "" {
+	writeSummary(output)
+}
+
+// writeSummary appends output to the GitHub step summary, or prints it. The
+// log file gets a copy either way.
+func writeSummary(output string) {
+	if logFile != nil {
+		fmt.Fprintln(logFile, output)
+	}
+	if summaryFile := os.Getenv("GITHUB_STEP_SUMMARY"); summaryFile != "" {
 		f, err := os.OpenFile(summaryFile, os.O_APPEND|os.O_WRONLY, 0644)
 		if err != nil {
 			log.Printf("Warning: Could not write to GITHUB_STEP_SUMMARY: %v", err)
@@ -84,6 +188,8 @@ func OutputStats(mediaType string, stats ProcessingStats) {
 		}
 		defer f.Close()
 		f.WriteString(output)
+	} else if useColor {
+		fmt.Println(colorizeSummary(output))
 	} else {
 		fmt.Println(output)
 	}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Subcommand is a named entry point dispatched from main before the regular
+// processing flags are parsed. Each subcommand owns its own flag set.
+type Subcommand struct {
+	Name    string
+	Summary string
+	Run     func(args []string) error
+}
+
+// subcommands lists every subcommand known to the binary, keyed by name.
+var subcommands = map[string]Subcommand{
+	"bundle": {
+		Name:    "bundle",
+		Summary: "Pack the dataset, manifest and signatures into a versioned tar.gz",
+		Run:     RunBundle,
+	},
+	"cache": {
+		Name:    "cache",
+		Summary: "Manage the API cache (ls, clear, clean, warm, stats)",
+		Run:     RunCache,
+	},
+	"compact": {
+		Name:    "compact",
+		Summary: "Fold pending run journals into the output files",
+		Run:     RunCompact,
+	},
+	"export-bolt": {
+		Name:    "export-bolt",
+		Summary: "Write a read-only bbolt key-value database with forward and reverse indexes",
+		Run:     RunExportBolt,
+	},
+	"export-hama": {
+		Name:    "export-hama",
+		Summary: "Write an anime-list XML mapping for the HAMA Plex agent",
+		Run:     RunExportHAMA,
+	},
+	"export-jellyfin": {
+		Name:    "export-jellyfin",
+		Summary: "Write a provider ID cross map for Jellyfin anime plugins",
+		Run:     RunExportJellyfin,
+	},
+	"export-kodi": {
+		Name:    "export-kodi",
+		Summary: "Write an anime-list XML mapping for Kodi's AniDB scrapers",
+		Run:     RunExportKodi,
+	},
+	"export-redis": {
+		Name:    "export-redis",
+		Summary: "Load mappings into Redis hashes and publish a change notification",
+		Run:     RunExportRedis,
+	},
+	"export-sqlite": {
+		Name:    "export-sqlite",
+		Summary: "Write a Datasette-friendly SQLite database with views and full-text search",
+		Run:     RunExportSQLite,
+	},
+	"export-trakt-shows": {
+		Name:    "export-trakt-shows",
+		Summary: "Write the TV dataset grouped by Trakt show, with the MAL entries per season",
+		Run:     RunExportTraktShows,
+	},
+	"history": {
+		Name:    "history",
+		Summary: "Print or chart dataset growth, not-found and override counts over time",
+		Run:     RunHistory,
+	},
+	"import-anime-list": {
+		Name:    "import-anime-list",
+		Summary: "Cross-check TVDB IDs and seasons against ScudLee's anime-list.xml",
+		Run:     RunImportAnimeList,
+	},
+	"keychain": {
+		Name:    "keychain",
+		Summary: "Store, delete or check Trakt credentials in the OS keychain",
+		Run:     RunKeychain,
+	},
+	"lint-input": {
+		Name:    "lint-input",
+		Summary: "Check input files for missing fields, bad IDs and duplicate rows",
+		Run:     RunLintInput,
+	},
+	"review": {
+		Name:    "review",
+		Summary: "Walk through not-found and low-confidence entries and record overrides",
+		Run:     RunReview,
+	},
+	"scrape": {
+		Name:    "scrape",
+		Summary: "Scrape the AniTrakt index pages into input files",
+		Run:     RunScrape,
+	},
+	"serve": {
+		Name:    "serve",
+		Summary: "Serve the dataset over HTTP with a GraphQL endpoint",
+		Run:     RunServe,
+	},
+	"sign": {
+		Name:    "sign",
+		Summary: "Sign the dataset manifest and output files with an ed25519 key",
+		Run:     RunSign,
+	},
+	"site": {
+		Name:    "site",
+		Summary: "Render the dataset into a static HTML site with search, for GitHub Pages",
+		Run:     RunSite,
+	},
+	"snapshot": {
+		Name:    "snapshot",
+		Summary: "List, diff or roll back to the output snapshots kept with -snapshots",
+		Run:     RunSnapshot,
+	},
+	"sync-list": {
+		Name:    "sync-list",
+		Summary: "Create or update a Trakt list from MAL IDs using the mappings",
+		Run:     RunSyncList,
+	},
+	"upload": {
+		Name:    "upload",
+		Summary: "Upload dataset artifacts to an S3-compatible bucket (S3, R2, GCS)",
+		Run:     RunUpload,
+	},
+	"verify": {
+		Name:    "verify",
+		Summary: "Verify dataset signatures and manifest checksums",
+		Run:     RunVerify,
+	},
+	"verify-live": {
+		Name:    "verify-live",
+		Summary: "Re-fetch a random sample of mappings from Trakt and fail if too many drifted",
+		Run:     RunVerifyLive,
+	},
+}
+
+// LookupSubcommand returns the subcommand registered under name, if any.
+func LookupSubcommand(name string) (Subcommand, bool) {
+	cmd, ok := subcommands[name]
+	return cmd, ok
+}
+
+// PrintSubcommands writes the list of available subcommands to stderr.
+func PrintSubcommands() {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "\nSubcommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, subcommands[name].Summary)
+	}
+}
@@ -0,0 +1,91 @@
+PRAGMA journal_mode = OFF;
+BEGIN;
+
+CREATE TABLE shows (
+  mal_id INTEGER PRIMARY KEY,
+  mal_title TEXT NOT NULL,
+  trakt_id INTEGER NOT NULL,
+  trakt_slug TEXT NOT NULL,
+  trakt_title TEXT NOT NULL,
+  season_number INTEGER,
+  season_trakt_id INTEGER,
+  season_tvdb INTEGER,
+  season_tmdb INTEGER,
+  is_split_cour INTEGER NOT NULL,
+  release_year INTEGER,
+  tvdb INTEGER,
+  tmdb INTEGER,
+  imdb TEXT
+);
+CREATE INDEX shows_trakt_id ON shows (trakt_id);
+
+CREATE TABLE movies (
+  mal_id INTEGER PRIMARY KEY,
+  mal_title TEXT NOT NULL,
+  trakt_id INTEGER NOT NULL,
+  trakt_slug TEXT NOT NULL,
+  trakt_title TEXT NOT NULL,
+  release_year INTEGER,
+  tmdb INTEGER,
+  imdb TEXT,
+  letterboxd_slug TEXT,
+  letterboxd_lid TEXT,
+  letterboxd_uid INTEGER
+);
+CREATE INDEX movies_trakt_id ON movies (trakt_id);
+
+CREATE TABLE not_found (
+  media_type TEXT NOT NULL,
+  mal_id INTEGER NOT NULL,
+  title TEXT,
+  PRIMARY KEY (media_type, mal_id)
+);
+
+CREATE TABLE changes (
+  recorded_at TEXT NOT NULL,
+  media_type TEXT NOT NULL,
+  change TEXT NOT NULL,
+  mal_id INTEGER NOT NULL,
+  title TEXT
+);
+
+CREATE VIEW shows_with_externals AS
+SELECT
+  s.mal_id, s.mal_title, s.trakt_title, s.trakt_slug, s.season_number, s.is_split_cour, s.release_year,
+  s.tvdb, s.tmdb, s.imdb, s.season_tvdb, s.season_tmdb,
+  'https://myanimelist.net/anime/' || s.mal_id AS mal_url,
+  'https://trakt.tv/shows/' || s.trakt_slug ||
+    CASE WHEN s.season_number IS NOT NULL THEN '/seasons/' || s.season_number ELSE '' END AS trakt_url,
+  CASE WHEN s.tvdb IS NOT NULL THEN 'https://thetvdb.com/dereferrer/series/' || s.tvdb END AS tvdb_url,
+  CASE WHEN s.tmdb IS NOT NULL THEN 'https://www.themoviedb.org/tv/' || s.tmdb END AS tmdb_url,
+  CASE WHEN s.imdb IS NOT NULL THEN 'https://www.imdb.com/title/' || s.imdb || '/' END AS imdb_url
+FROM shows s;
+
+CREATE VIEW unresolved AS
+SELECT media_type, mal_id, title, 'not found on Trakt' AS reason FROM not_found
+UNION ALL
+SELECT 'tv', mal_id, mal_title, 'split cour (season not found)' FROM shows WHERE is_split_cour = 1
+UNION ALL
+SELECT 'tv', mal_id, mal_title, 'missing TVDB and TMDB IDs' FROM shows WHERE tvdb IS NULL AND tmdb IS NULL
+UNION ALL
+SELECT 'movies', mal_id, mal_title, 'missing TMDB ID' FROM movies WHERE tmdb IS NULL
+UNION ALL
+SELECT 'movies', mal_id, mal_title, 'missing Letterboxd' FROM movies WHERE tmdb IS NOT NULL AND letterboxd_slug IS NULL;
+
+CREATE VIEW recent_changes AS
+SELECT recorded_at, media_type, change, mal_id, title,
+  'https://myanimelist.net/anime/' || mal_id AS mal_url
+FROM changes
+ORDER BY recorded_at DESC, media_type, mal_id;
+
+CREATE VIRTUAL TABLE shows_fts USING fts5(mal_title, trakt_title, content='shows', content_rowid='mal_id');
+CREATE VIRTUAL TABLE movies_fts USING fts5(mal_title, trakt_title, content='movies', content_rowid='mal_id');
+INSERT INTO shows VALUES (1, 'Cowboy Bebop: The ''Real'' Folk Blues', 30857, 'cowboy-bebop', 'Cowboy Bebop', 1, 1, NULL, NULL, 0, 1998, 76885, 30991, 'tt0213338');
+INSERT INTO shows VALUES (5, 'Cowboy Bebop: Tengoku no Tobira', 30857, 'cowboy-bebop', 'Cowboy Bebop', 0, 2, NULL, NULL, 0, 2001, NULL, NULL, NULL);
+INSERT INTO movies VALUES (43, 'Koukaku Kidoutai', 1, 'ghost-in-the-shell-1995', 'Ghost in the Shell', 1995, 9323, 'tt0113568', 'ghost-in-the-shell', NULL, NULL);
+INSERT OR IGNORE INTO not_found VALUES ('tv', 99, 'Missing Show');
+INSERT INTO changes VALUES ('RECORDED_AT', 'tv', 'created', 5, 'Cowboy Bebop: Tengoku no Tobira');
+INSERT INTO shows_fts(shows_fts) VALUES ('rebuild');
+INSERT INTO movies_fts(movies_fts) VALUES ('rebuild');
+COMMIT;
+VACUUM;
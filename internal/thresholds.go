@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Thresholds fail a run whose stats show degraded data quality
+// (-fail-on-not-found-over, -fail-on-warning-over). Unlike the guardrails
+// they do not stop the write: the results are saved, and the exit code turns
+// the scheduled workflow red before it publishes them.
+type Thresholds struct {
+	MaxNotFound int // entries not found on Trakt this run (0 = unlimited)
+	MaxWarnings int // data-quality warnings on written entries (0 = unlimited)
+}
+
+// check returns an ExitThreshold error when the stats of a media type's run
+// exceed a threshold
+func (t Thresholds) check(mediaType string, stats ProcessingStats) error {
+	var exceeded []string
+	if t.MaxNotFound > 0 && stats.NotFound > t.MaxNotFound {
+		exceeded = append(exceeded, fmt.Sprintf("%d not found (-fail-on-not-found-over %d)", stats.NotFound, t.MaxNotFound))
+	}
+	if t.MaxWarnings > 0 && stats.Warnings > t.MaxWarnings {
+		exceeded = append(exceeded, fmt.Sprintf("%d warnings (-fail-on-warning-over %d)", stats.Warnings, t.MaxWarnings))
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return exitErrorf(ExitThreshold, "%s: %s", mediaType, strings.Join(exceeded, ", "))
+}
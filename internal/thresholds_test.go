@@ -0,0 +1,24 @@
+package internal
+
+import "testing"
+
+func TestThresholds(t *testing.T) {
+	stats := ProcessingStats{NotFound: 51, Warnings: 100}
+	tests := []struct {
+		thresholds Thresholds
+		want       int
+	}{
+		{Thresholds{}, ExitOK},
+		{Thresholds{MaxNotFound: 51, MaxWarnings: 100}, ExitOK},
+		{Thresholds{MaxNotFound: 50}, ExitThreshold},
+		{Thresholds{MaxWarnings: 99}, ExitThreshold},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.thresholds.check("shows", stats)); got != tt.want {
+			t.Errorf("%+v: exit code = %d, want %d", tt.thresholds, got, tt.want)
+		}
+	}
+	if err := (Thresholds{MaxNotFound: 50}).check("shows", stats); !SavedResults(err) {
+		t.Errorf("a threshold error must count as saved results: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"html"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxUnescapes bounds HTML unescaping; MAL titles are sometimes escaped
+// twice (&amp;#039;)
+const maxUnescapes = 3
+
+// CleanTitle unescapes HTML entities in a title and normalizes it to NFC, so
+// the same title always compares equal. raw is the original title when
+// cleaning changed it, "" otherwise.
+func CleanTitle(title string) (clean, raw string) {
+	clean = title
+	for range maxUnescapes {
+		unescaped := html.UnescapeString(clean)
+		if unescaped == clean {
+			break
+		}
+		clean = unescaped
+	}
+	clean = norm.NFC.String(clean)
+	if clean != title {
+		raw = title
+	}
+	return clean, raw
+}
+
+// withCleanTitle returns the show with CleanTitle applied
+func (s InputShow) withCleanTitle() InputShow {
+	s.Title, s.RawTitle = CleanTitle(s.Title)
+	return s
+}
+
+// withCleanTitle returns the movie with CleanTitle applied
+func (m InputMovie) withCleanTitle() InputMovie {
+	m.Title, m.RawTitle = CleanTitle(m.Title)
+	return m
+}
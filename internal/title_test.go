@@ -0,0 +1,21 @@
+package internal
+
+import "testing"
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in, clean, raw string
+	}{
+		{"Steins;Gate", "Steins;Gate", ""},
+		{"Kaguya-sama wa Kokurasetai: Tensai-tachi no Ren&#039;ai Zunousen", "Kaguya-sama wa Kokurasetai: Tensai-tachi no Ren'ai Zunousen", "Kaguya-sama wa Kokurasetai: Tensai-tachi no Ren&#039;ai Zunousen"},
+		{"Tom &amp;amp; Jerry", "Tom & Jerry", "Tom &amp;amp; Jerry"},
+		{"Pokémon", "Pokémon", ""},
+		{"Pokémon", "Pokémon", "Pokémon"}, // decomposed é
+	}
+	for _, tt := range tests {
+		clean, raw := CleanTitle(tt.in)
+		if clean != tt.clean || raw != tt.raw {
+			t.Errorf("CleanTitle(%q) = %q, %q; want %q, %q", tt.in, clean, raw, tt.clean, tt.raw)
+		}
+	}
+}
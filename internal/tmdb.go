@@ -0,0 +1,434 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// tmdbAPIBase is the TMDB v3 API root
+const tmdbAPIBase = "https://api.themoviedb.org/3"
+
+// errTMDBNotFound is returned when TMDB answers 404 or finds no match
+var errTMDBNotFound = errors.New("not found on TMDB")
+
+// TMDBInfo is the TMDB metadata the tmdb enrichment adds to shows and movies
+type TMDBInfo struct {
+	OriginalTitle     string   `json:"original_title"`
+	AlternativeTitles []string `json:"alternative_titles,omitempty"`
+	CollectionID      *int     `json:"collection_id,omitempty"` // movies that belong to a series
+}
+
+// tmdbDetails is the part of /movie/{id} and /tv/{id} (with
+// append_to_response=alternative_titles) that is kept
+type tmdbDetails struct {
+	OriginalTitle       string `json:"original_title"` // movies
+	OriginalName        string `json:"original_name"`  // shows
+	PosterPath          string `json:"poster_path"`
+	BackdropPath        string `json:"backdrop_path"`
+	BelongsToCollection *struct {
+		ID int `json:"id"`
+	} `json:"belongs_to_collection"`
+	AlternativeTitles struct {
+		Titles  []tmdbTitle `json:"titles"`  // movies
+		Results []tmdbTitle `json:"results"` // shows
+	} `json:"alternative_titles"`
+}
+
+type tmdbTitle struct {
+	Title string `json:"title"`
+}
+
+// tmdbFindResult is the response of /find/{external_id}
+type tmdbFindResult struct {
+	MovieResults []struct {
+		ID int `json:"id"`
+	} `json:"movie_results"`
+	TVResults []struct {
+		ID int `json:"id"`
+	} `json:"tv_results"`
+}
+
+// info converts the response to the output form, dropping alternative titles
+// that repeat the original one
+func (d tmdbDetails) info() *TMDBInfo {
+	info := &TMDBInfo{OriginalTitle: d.OriginalTitle}
+	if info.OriginalTitle == "" {
+		info.OriginalTitle = d.OriginalName
+	}
+	seen := map[string]bool{info.OriginalTitle: true}
+	for _, title := range append(d.AlternativeTitles.Titles, d.AlternativeTitles.Results...) {
+		if title.Title != "" && !seen[title.Title] {
+			seen[title.Title] = true
+			info.AlternativeTitles = append(info.AlternativeTitles, title.Title)
+		}
+	}
+	if d.BelongsToCollection != nil {
+		id := d.BelongsToCollection.ID
+		info.CollectionID = &id
+	}
+	return info
+}
+
+// FetchTMDBDetails fetches a movie or show ("movie" or "tv") from TMDB,
+// including its alternative titles
+func FetchTMDBDetails(client *http.Client, config Config, mediaType string, id int) (*tmdbDetails, error) {
+	cacheFile := filepath.Join(config.TempDir, "tmdb", fmt.Sprintf("%s_%d.json", mediaType, id))
+	return singleFetch(cacheFile, func() (*tmdbDetails, error) {
+		if useCache(config, cacheFile) {
+			if details, ok := cacheLoad[tmdbDetails](config.Cache, cacheFile); ok {
+				return &details, nil
+			}
+		}
+		var details tmdbDetails
+		body, err := tmdbGet(client, config, fmt.Sprintf("/%s/%d?append_to_response=alternative_titles", mediaType, id), &details)
+		if err != nil {
+			return nil, err
+		}
+		cacheStore(config.Cache, cacheFile, body, details)
+		return &details, nil
+	})
+}
+
+// FindTMDBByIMDB returns the TMDB ID of the movie or show ("movie" or "tv")
+// with the given IMDB ID
+func FindTMDBByIMDB(client *http.Client, config Config, mediaType, imdbID string) (int, error) {
+	result, err := findTMDB(client, config, imdbID)
+	if err != nil {
+		return 0, err
+	}
+	if ids := result.ids(mediaType); len(ids) > 0 {
+		return ids[0], nil
+	}
+	return 0, errTMDBNotFound
+}
+
+// findTMDB looks an IMDB ID up in both TMDB namespaces
+func findTMDB(client *http.Client, config Config, imdbID string) (*tmdbFindResult, error) {
+	cacheFile := filepath.Join(config.TempDir, "tmdb", fmt.Sprintf("find_%s.json", imdbID))
+	return singleFetch(cacheFile, func() (*tmdbFindResult, error) {
+		if useCache(config, cacheFile) {
+			if result, ok := cacheLoad[tmdbFindResult](config.Cache, cacheFile); ok {
+				return &result, nil
+			}
+		}
+		var result tmdbFindResult
+		body, err := tmdbGet(client, config, fmt.Sprintf("/find/%s?external_source=imdb_id", imdbID), &result)
+		if err != nil {
+			return nil, err
+		}
+		cacheStore(config.Cache, cacheFile, body, result)
+		return &result, nil
+	})
+}
+
+// ids lists the TMDB IDs found in a namespace ("movie" or "tv")
+func (r *tmdbFindResult) ids(mediaType string) []int {
+	results := r.TVResults
+	if mediaType == "movie" {
+		results = r.MovieResults
+	}
+	ids := make([]int, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+// otherTMDBNamespace is the namespace a TMDB ID is mistaken for
+func otherTMDBNamespace(mediaType string) string {
+	if mediaType == "movie" {
+		return "tv"
+	}
+	return "movie"
+}
+
+// wrongTMDBNamespace reports whether a TMDB ID stored for a mediaType entry
+// belongs to the other namespace. Movie and TV IDs overlap, so the ID may
+// resolve in both; the IMDB ID, when known, tells which object was meant.
+// Without one, only an ID missing from its own namespace (found is false) and
+// present in the other counts.
+func wrongTMDBNamespace(client *http.Client, config Config, mediaType string, tmdbID int, imdbID *string, found bool) bool {
+	other := otherTMDBNamespace(mediaType)
+	if imdbID != nil && *imdbID != "" {
+		result, err := findTMDB(client, config, *imdbID)
+		if err == nil {
+			return slices.Contains(result.ids(other), tmdbID) && !slices.Contains(result.ids(mediaType), tmdbID)
+		}
+	}
+	if found {
+		return false
+	}
+	_, err := FetchTMDBDetails(client, config, other, tmdbID)
+	return err == nil
+}
+
+// redactAPIKey removes a v3 key from a transport error, whose URL carries
+// it, before the error is logged and written to the error report
+func redactAPIKey(err error, key string) error {
+	if key == "" || !strings.Contains(err.Error(), key) {
+		return err
+	}
+	if urlErr, ok := err.(*neturl.Error); ok {
+		redacted := *urlErr
+		redacted.URL = strings.ReplaceAll(urlErr.URL, key, "REDACTED")
+		if !strings.Contains(redacted.Error(), key) {
+			return &redacted
+		}
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), key, "REDACTED"))
+}
+
+// tmdbGet performs a GET against the TMDB API, decodes the response into out
+// and returns the raw body for caching. path must carry a query string. Keys
+// that look like a v4 read access token (a JWT) are sent as a bearer token,
+// anything else as api_key.
+func tmdbGet(client *http.Client, config Config, path string, out any) ([]byte, error) {
+	url := tmdbAPIBase + path
+	bearer := strings.Count(config.TMDBAPIKey, ".") == 2
+	if !bearer {
+		url += "&api_key=" + config.TMDBAPIKey
+	}
+
+	config.TMDBRateLimiter.Wait()
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if bearer {
+			req.Header.Set("Authorization", "Bearer "+config.TMDBAPIKey)
+		}
+		return client.Do(req)
+	})
+	if err != nil {
+		return nil, redactAPIKey(err, config.TMDBAPIKey)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 404 {
+		return nil, errTMDBNotFound
+	}
+	if resp.StatusCode != 200 {
+		return nil, statusError(resp, fmt.Errorf("TMDB API error: %d", resp.StatusCode))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// tmdbEnabled reports whether the tmdb enrichment is selected and has a key
+func tmdbEnabled(config Config) bool {
+	return config.Enrichments["tmdb"] && config.TMDBAPIKey != ""
+}
+
+// tmdbEnricher checks TMDB IDs, fills missing ones and adds TMDB metadata;
+// see enrichTMDB. Reused entries were checked when they were fetched and keep
+// their metadata, so a run only calls TMDB for what Trakt returned to it.
+type tmdbEnricher struct{}
+
+func (tmdbEnricher) Name() string { return "tmdb" }
+
+func (tmdbEnricher) Applies(entry EnrichEntry) bool {
+	if entry.Reused {
+		return false
+	}
+	if entry.Show != nil {
+		return entry.Show.Externals != nil
+	}
+	return entry.Movie != nil && entry.Movie.Externals != nil
+}
+
+func (tmdbEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	if entry.Show != nil {
+		enrichShowTMDB(ctx.Client, ctx.Config, entry.Show, entry.ExistingShow, ctx.Stats)
+	} else {
+		enrichMovieTMDB(ctx.Client, ctx.Config, entry.Movie, entry.ExistingMovie, ctx.Stats)
+	}
+}
+
+// Retain keeps the previous TMDB metadata
+func (tmdbEnricher) Retain(entry EnrichEntry) {
+	if entry.Show != nil && entry.ExistingShow != nil {
+		entry.Show.TMDB = entry.ExistingShow.TMDB
+	} else if entry.Movie != nil && entry.ExistingMovie != nil {
+		entry.Movie.TMDB = entry.ExistingMovie.TMDB
+	}
+}
+
+// enrichShowTMDB runs the tmdb enrichment on a show; see enrichTMDB
+func enrichShowTMDB(client *http.Client, config Config, show, existing *OutputShow, stats *ProcessingStats) {
+	var previous *TMDBInfo
+	if existing != nil {
+		previous = existing.TMDB
+	}
+	if show.Externals == nil {
+		show.TMDB = previous
+		return
+	}
+	hadTMDB := show.Externals.TMDB != nil
+	show.TMDB = enrichTMDB(client, config, "tv", show.MyAnimeList.ID, show.MyAnimeList.Title,
+		&show.Externals.TMDB, show.Externals.IMDB, previous, stats)
+	if !hadTMDB && show.Externals.TMDB != nil {
+		setExternalSource(&show.ExternalSources, "tmdb", "tmdb")
+	}
+	fillTMDBImages(client, config, "tv", show.Externals.TMDB, &show.Images)
+	backfillSeasonExternals(client, config, show, stats)
+}
+
+// tmdbSeasonIDs is the response of /tv/{id}/season/{number}/external_ids
+type tmdbSeasonIDs struct {
+	ID     int `json:"id"` // TMDB season ID
+	TVDBID int `json:"tvdb_id"`
+}
+
+// backfillSeasonExternals fills the season TMDB and TVDB IDs Trakt left
+// empty from TMDB's season. Shows whose TMDB ID failed the checks are left
+// alone.
+func backfillSeasonExternals(client *http.Client, config Config, show *OutputShow, stats *ProcessingStats) {
+	season := show.Trakt.Season
+	if show.TMDB == nil || show.Externals.TMDB == nil || season == nil || season.Number == 0 {
+		return
+	}
+	if season.Externals == nil {
+		season.Externals = &TraktExternalsSeason{}
+	}
+	externals := season.Externals
+	if externals.TMDB != nil && *externals.TMDB != 0 && externals.TVDB != nil && *externals.TVDB != 0 {
+		return
+	}
+
+	tvID := *show.Externals.TMDB
+	cacheFile := filepath.Join(config.TempDir, "tmdb", fmt.Sprintf("tv_%d_season_%d.json", tvID, season.Number))
+	ids, err := singleFetch(cacheFile, func() (*tmdbSeasonIDs, error) {
+		if useCache(config, cacheFile) {
+			if ids, ok := cacheLoad[tmdbSeasonIDs](config.Cache, cacheFile); ok {
+				return &ids, nil
+			}
+		}
+		var ids tmdbSeasonIDs
+		body, err := tmdbGet(client, config, fmt.Sprintf("/tv/%d/season/%d/external_ids?", tvID, season.Number), &ids)
+		if err != nil {
+			return nil, err
+		}
+		cacheStore(config.Cache, cacheFile, body, ids)
+		return &ids, nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTMDBNotFound) {
+			log.Printf("Warning: Failed to fetch TMDB season %d of show %d: %v", season.Number, tvID, err)
+			config.Errors.Record("tmdb", show.MyAnimeList.ID, show.MyAnimeList.Title, err)
+		}
+		return
+	}
+
+	var filled []string
+	if (externals.TMDB == nil || *externals.TMDB == 0) && ids.ID != 0 {
+		externals.TMDB = &ids.ID
+		setExternalSource(&show.ExternalSources, "season_tmdb", "tmdb")
+		filled = append(filled, fmt.Sprintf("TMDB %d", ids.ID))
+	}
+	if (externals.TVDB == nil || *externals.TVDB == 0) && ids.TVDBID != 0 {
+		externals.TVDB = &ids.TVDBID
+		setExternalSource(&show.ExternalSources, "season_tvdb", "tmdb")
+		filled = append(filled, fmt.Sprintf("TVDB %d", ids.TVDBID))
+	}
+	if len(filled) > 0 {
+		stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+			MalID:  show.MyAnimeList.ID,
+			Title:  show.MyAnimeList.Title,
+			Reason: fmt.Sprintf("Season %d IDs filled from TMDB: %s", season.Number, strings.Join(filled, ", ")),
+		})
+	}
+}
+
+// enrichMovieTMDB runs the tmdb enrichment on a movie; see enrichTMDB
+func enrichMovieTMDB(client *http.Client, config Config, movie, existing *OutputMovie, stats *ProcessingStats) {
+	var previous *TMDBInfo
+	if existing != nil {
+		previous = existing.TMDB
+	}
+	if movie.Externals == nil {
+		movie.TMDB = previous
+		return
+	}
+	hadTMDB := movie.Externals.TMDB != nil
+	movie.TMDB = enrichTMDB(client, config, "movie", movie.MyAnimeList.ID, movie.MyAnimeList.Title,
+		&movie.Externals.TMDB, movie.Externals.IMDB, previous, stats)
+	if !hadTMDB && movie.Externals.TMDB != nil {
+		setExternalSource(&movie.ExternalSources, "tmdb", "tmdb")
+	}
+	fillTMDBImages(client, config, "movie", movie.Externals.TMDB, &movie.Images)
+}
+
+// enrichTMDB fills a missing TMDB ID from the IMDB ID, checks that the TMDB
+// ID Trakt reports exists and returns its TMDB metadata. Findings are
+// recorded in stats.ValidationDetails. When the enrichment is off or TMDB
+// cannot be reached, the previous metadata is kept.
+func enrichTMDB(client *http.Client, config Config, mediaType string, malID int, title string, tmdbID **int, imdbID *string, previous *TMDBInfo, stats *ProcessingStats) *TMDBInfo {
+	if !tmdbEnabled(config) {
+		return previous
+	}
+	config.Progress.Phase("tmdb")
+
+	if *tmdbID == nil && imdbID != nil && *imdbID != "" {
+		id, err := FindTMDBByIMDB(client, config, mediaType, *imdbID)
+		switch {
+		case err == nil:
+			*tmdbID = &id
+			stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+				MalID:  malID,
+				Title:  title,
+				Reason: fmt.Sprintf("TMDB ID %d filled from IMDB ID %s", id, *imdbID),
+			})
+		case !errors.Is(err, errTMDBNotFound):
+			log.Printf("Warning: TMDB lookup of IMDB ID %s failed: %v", *imdbID, err)
+			config.Errors.Record("tmdb", malID, title, err)
+		}
+	}
+	if *tmdbID == nil {
+		return previous
+	}
+
+	details, err := FetchTMDBDetails(client, config, mediaType, **tmdbID)
+	if (err == nil || errors.Is(err, errTMDBNotFound)) &&
+		wrongTMDBNamespace(client, config, mediaType, **tmdbID, imdbID, err == nil) {
+		stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: fmt.Sprintf("TMDB ID %d reported by Trakt is a TMDB %s, not a %s", **tmdbID, otherTMDBNamespace(mediaType), mediaType),
+		})
+		return nil
+	}
+	if errors.Is(err, errTMDBNotFound) {
+		stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+			MalID:  malID,
+			Title:  title,
+			Reason: fmt.Sprintf("TMDB %s ID %d reported by Trakt does not exist on TMDB", mediaType, **tmdbID),
+		})
+		return nil
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to fetch TMDB %s %d: %v", mediaType, **tmdbID, err)
+		config.Errors.Record("tmdb", malID, title, err)
+		return previous
+	}
+	info := details.info()
+	if config.Verbose {
+		Debugf("\n    - TMDB %s %d: %s", mediaType, **tmdbID, info.OriginalTitle)
+	}
+	return info
+}
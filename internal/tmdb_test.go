@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTMDBDetailsInfo(t *testing.T) {
+	var movie tmdbDetails
+	json.Unmarshal([]byte(`{
+		"original_title": "カウボーイビバップ 天国の扉",
+		"belongs_to_collection": {"id": 1133},
+		"alternative_titles": {"titles": [
+			{"title": "Cowboy Bebop: The Movie"},
+			{"title": "カウボーイビバップ 天国の扉"},
+			{"title": "Cowboy Bebop: The Movie"},
+			{"title": "Cowboy Bebop: Knockin' on Heaven's Door"}
+		]}
+	}`), &movie)
+	info := movie.info()
+	want := []string{"Cowboy Bebop: The Movie", "Cowboy Bebop: Knockin' on Heaven's Door"}
+	if info.OriginalTitle != "カウボーイビバップ 天国の扉" || !slices.Equal(info.AlternativeTitles, want) {
+		t.Errorf("movie info = %+v", info)
+	}
+	if info.CollectionID == nil || *info.CollectionID != 1133 {
+		t.Errorf("collection ID = %v, want 1133", info.CollectionID)
+	}
+
+	var show tmdbDetails
+	json.Unmarshal([]byte(`{"original_name": "カウボーイビバップ", "alternative_titles": {"results": [{"title": "Cowboy Bebop"}]}}`), &show)
+	info = show.info()
+	if info.OriginalTitle != "カウボーイビバップ" || !slices.Equal(info.AlternativeTitles, []string{"Cowboy Bebop"}) || info.CollectionID != nil {
+		t.Errorf("show info = %+v", info)
+	}
+}
+
+func TestWrongTMDBNamespace(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "tmdb"), 0755)
+	// tt100 is movie 42 on TMDB; 42 is also some unrelated show's ID
+	os.WriteFile(filepath.Join(dir, "tmdb", "find_tt100.json"), []byte(`{"movie_results": [{"id": 42}], "tv_results": []}`), 0644)
+	config := Config{TempDir: dir, Cache: NewCache(10)}
+	imdb := "tt100"
+
+	if !wrongTMDBNamespace(nil, config, "tv", 42, &imdb, true) {
+		t.Error("show holding a movie's TMDB ID was not flagged")
+	}
+	if wrongTMDBNamespace(nil, config, "movie", 42, &imdb, true) {
+		t.Error("movie holding its own TMDB ID was flagged")
+	}
+	if wrongTMDBNamespace(nil, config, "tv", 7, nil, true) {
+		t.Error("show found in its own namespace without an IMDB ID was flagged")
+	}
+}
+
+func TestBackfillSeasonExternals(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "tmdb"), 0755)
+	os.WriteFile(filepath.Join(dir, "tmdb", "tv_30991_season_2.json"), []byte(`{"id": 61234, "tvdb_id": 0}`), 0644)
+	config := Config{TempDir: dir, Cache: NewCache(10)}
+
+	var show OutputShow
+	tmdbID, tvdbID := 30991, 500
+	show.Externals = &TraktExternalsShow{TMDB: &tmdbID}
+	show.TMDB = &TMDBInfo{}
+	show.Trakt.Season = &struct {
+		ID            int                   `json:"id"`
+		Number        int                   `json:"number"`
+		EpisodeCount  int                   `json:"episode_count,omitempty"`
+		EpisodeOffset int                   `json:"episode_offset,omitempty"`
+		Externals     *TraktExternalsSeason `json:"externals"`
+	}{Number: 2, Externals: &TraktExternalsSeason{TVDB: &tvdbID}}
+
+	var stats ProcessingStats
+	backfillSeasonExternals(nil, config, &show, &stats)
+	externals := show.Trakt.Season.Externals
+	if externals.TMDB == nil || *externals.TMDB != 61234 || *externals.TVDB != 500 {
+		t.Errorf("season externals = %+v", externals)
+	}
+	if show.ExternalSources["season_tmdb"] != "tmdb" || show.ExternalSources["season_tvdb"] != "" {
+		t.Errorf("external sources = %v, want only season_tmdb from tmdb", show.ExternalSources)
+	}
+	if len(stats.ValidationDetails) != 1 {
+		t.Errorf("validation details = %+v", stats.ValidationDetails)
+	}
+}
+
+func TestTMDBGetRedactsAPIKey(t *testing.T) {
+	const key = "0123456789abcdef0123456789abcdef"
+	config := Config{TMDBAPIKey: key, TMDBRateLimiter: NewTMDBRateLimiter()}
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection reset by peer")
+	})}
+
+	var out tmdbDetails
+	_, err := tmdbGet(client, config, "/movie/1?language=en", &out)
+	if err == nil {
+		t.Fatal("tmdbGet succeeded on a failing transport")
+	}
+	if strings.Contains(err.Error(), key) {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+	if !strings.Contains(err.Error(), "connection reset by peer") {
+		t.Errorf("error lost its cause: %v", err)
+	}
+}
+
+func TestTMDBEnricherSkipsReusedMovies(t *testing.T) {
+	config := Config{TMDBAPIKey: "key", TMDBRateLimiter: NewTMDBRateLimiter(), Enrichments: map[string]bool{"tmdb": true}}
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Errorf("reused movie fetched %s", r.URL.Path)
+		return nil, errors.New("unexpected request")
+	})}
+
+	var existing OutputMovie
+	json.Unmarshal([]byte(`{"myanimelist": {"id": 43}, "externals": {"tmdb": 9323}, "tmdb": {"original_title": "攻殻機動隊"}}`), &existing)
+	movie := existing
+	movie.TMDB = nil
+	var stats ProcessingStats
+	runEnrichers(EnrichContext{Client: client, Config: config, Stats: &stats},
+		EnrichEntry{Movie: &movie, ExistingMovie: &existing, Reused: true})
+
+	if movie.TMDB == nil || movie.TMDB.OriginalTitle != "攻殻機動隊" {
+		t.Errorf("TMDB = %+v, want the existing metadata", movie.TMDB)
+	}
+	if len(stats.ValidationDetails) != 0 {
+		t.Errorf("validation details = %+v", stats.ValidationDetails)
+	}
+}
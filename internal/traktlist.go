@@ -0,0 +1,438 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// traktListClient performs authenticated calls against the user's lists
+type traktListClient struct {
+	client      *http.Client
+	baseURL     string // Trakt API root
+	apiKey      string
+	token       string
+	rateLimiter *RateLimiter
+}
+
+// TraktList is the subset of a Trakt list object the sync needs
+type TraktList struct {
+	Name string `json:"name"`
+	IDs  struct {
+		Trakt int    `json:"trakt"`
+		Slug  string `json:"slug"`
+	} `json:"ids"`
+}
+
+// traktListItem is one entry returned by the list items endpoint
+type traktListItem struct {
+	Type   string `json:"type"`
+	Season *struct {
+		IDs struct {
+			Trakt int `json:"trakt"`
+		} `json:"ids"`
+	} `json:"season"`
+	Show *struct {
+		IDs struct {
+			Trakt int `json:"trakt"`
+		} `json:"ids"`
+	} `json:"show"`
+	Movie *struct {
+		IDs struct {
+			Trakt int `json:"trakt"`
+		} `json:"ids"`
+	} `json:"movie"`
+}
+
+// traktListIDs is an item reference in add/remove payloads
+type traktListIDs struct {
+	IDs struct {
+		Trakt int `json:"trakt"`
+	} `json:"ids"`
+}
+
+// traktListPayload is the body of the add/remove items endpoints
+type traktListPayload struct {
+	Shows   []traktListIDs `json:"shows,omitempty"`
+	Seasons []traktListIDs `json:"seasons,omitempty"`
+	Movies  []traktListIDs `json:"movies,omitempty"`
+}
+
+// traktListResult is the response of the add/remove items endpoints
+type traktListResult struct {
+	Added    map[string]int   `json:"added"`
+	Deleted  map[string]int   `json:"deleted"`
+	Existing map[string]int   `json:"existing"`
+	NotFound traktListPayload `json:"not_found"`
+}
+
+// RunSyncList implements the `sync-list` subcommand
+func RunSyncList(args []string) error {
+	fs := flag.NewFlagSet("sync-list", flag.ExitOnError)
+	token := fs.String("token", os.Getenv("TRAKT_ACCESS_TOKEN"), "Trakt OAuth access token (env TRAKT_ACCESS_TOKEN)")
+	apiKey := fs.String("api-key", os.Getenv("TRAKT_API_KEY"), "Trakt client ID (env TRAKT_API_KEY)")
+	listName := fs.String("list", "", "Name of the Trakt list to create or update (required)")
+	description := fs.String("description", "Synced from MyAnimeList IDs by db.trakt.extended-anitrakt", "Description used when creating the list")
+	privacy := fs.String("privacy", "private", "Privacy used when creating the list: private, friends or public")
+	malIDs := fs.String("mal-ids", "", "Comma-separated MAL IDs to sync")
+	input := fs.String("input", "", "File with MAL IDs, one per line (# starts a comment)")
+	prune := fs.Bool("prune", false, "Remove list items that are not in the given MAL IDs")
+	dryRun := fs.Bool("dry-run", false, "Resolve and report changes without modifying the list")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	keychain := fs.Bool("keychain", false, "Read a missing -token or -api-key from the OS keychain (see `keychain store`)")
+	apiURL := fs.String("trakt-api-url", envOr("TRAKT_API_URL", DefaultTraktAPIURL), "Trakt API root (env TRAKT_API_URL)")
+	fs.Parse(args)
+
+	if *keychain && *token == "" {
+		*token = KeychainLookup(KeychainAccessToken)
+	}
+	if *keychain && *apiKey == "" {
+		*apiKey = KeychainLookup(KeychainAPIKey)
+	}
+	if *listName == "" {
+		return fmt.Errorf("-list is required")
+	}
+	if *token == "" || *apiKey == "" {
+		return fmt.Errorf("a Trakt access token (-token) and client ID (-api-key) are required")
+	}
+	switch *privacy {
+	case "private", "friends", "public":
+	default:
+		return fmt.Errorf("invalid -privacy %q (expected private, friends or public)", *privacy)
+	}
+
+	ids, err := readMALIDs(*malIDs, *input)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no MAL IDs given (use -mal-ids or -input)")
+	}
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	wanted, unmapped := resolveListItems(ids, shows, movies)
+	fmt.Printf("Resolved %d of %d MAL IDs to Trakt items\n", len(ids)-len(unmapped), len(ids))
+	if len(unmapped) > 0 {
+		fmt.Printf("Not in the dataset: %s\n", joinInts(unmapped))
+	}
+
+	tc := &traktListClient{
+		client:      NewHTTPClient(30 * time.Second),
+		baseURL:     strings.TrimSuffix(*apiURL, "/"),
+		apiKey:      *apiKey,
+		token:       *token,
+		rateLimiter: NewRateLimiter(),
+	}
+
+	list, err := tc.findList(*listName)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]bool)
+	if list == nil {
+		fmt.Printf("List %q does not exist yet\n", *listName)
+		if !*dryRun {
+			if list, err = tc.createList(*listName, *description, *privacy); err != nil {
+				return err
+			}
+			fmt.Printf("Created list %q (%s)\n", list.Name, list.IDs.Slug)
+		}
+	} else {
+		if current, err = tc.listItems(list.IDs.Trakt); err != nil {
+			return err
+		}
+	}
+
+	var toAdd, toRemove []string
+	for key := range wanted {
+		if !current[key] {
+			toAdd = append(toAdd, key)
+		}
+	}
+	if *prune {
+		for key := range current {
+			if !wanted[key] {
+				toRemove = append(toRemove, key)
+			}
+		}
+	}
+	sort.Strings(toAdd)
+	sort.Strings(toRemove)
+	fmt.Printf("%d items to add, %d to remove, %d already present\n", len(toAdd), len(toRemove), len(wanted)-len(toAdd))
+
+	if *dryRun || list == nil {
+		return nil
+	}
+
+	if len(toAdd) > 0 {
+		result, err := tc.modifyItems(list.IDs.Trakt, "", buildListPayload(toAdd))
+		if err != nil {
+			return fmt.Errorf("add items: %w", err)
+		}
+		fmt.Printf("Added %s\n", formatListCounts(result.Added))
+		// Items Trakt cannot find point at stale mappings in the dataset
+		if missing := listPayloadKeys(result.NotFound); len(missing) > 0 {
+			fmt.Printf("Warning: Trakt did not recognise %d mapped items: %s\n", len(missing), strings.Join(missing, ", "))
+		}
+	}
+	if len(toRemove) > 0 {
+		result, err := tc.modifyItems(list.IDs.Trakt, "/remove", buildListPayload(toRemove))
+		if err != nil {
+			return fmt.Errorf("remove items: %w", err)
+		}
+		fmt.Printf("Removed %s\n", formatListCounts(result.Deleted))
+	}
+	return nil
+}
+
+// readMALIDs collects MAL IDs from a comma-separated flag and/or a file
+func readMALIDs(list, path string) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	add := func(field string) error {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return nil
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid MAL ID %q", field)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+		return nil
+	}
+
+	for _, field := range strings.Split(list, ",") {
+		if err := add(field); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+				if err := add(field); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// resolveListItems maps MAL IDs to list item keys ("season:ID", "show:ID" or
+// "movie:ID"). Shows resolve to their season when it is known, so every cour
+// of a multi-season show lands on the list as its own item.
+func resolveListItems(malIDs []int, shows []OutputShow, movies []OutputMovie) (map[string]bool, []int) {
+	showByMAL := make(map[int]OutputShow, len(shows))
+	for _, show := range shows {
+		showByMAL[show.MyAnimeList.ID] = show
+	}
+	movieByMAL := make(map[int]OutputMovie, len(movies))
+	for _, movie := range movies {
+		movieByMAL[movie.MyAnimeList.ID] = movie
+	}
+
+	wanted := make(map[string]bool)
+	var unmapped []int
+	for _, malID := range malIDs {
+		if show, ok := showByMAL[malID]; ok {
+			if show.Trakt.Season != nil && show.Trakt.Season.ID != 0 {
+				wanted[fmt.Sprintf("season:%d", show.Trakt.Season.ID)] = true
+			} else {
+				wanted[fmt.Sprintf("show:%d", show.Trakt.ID)] = true
+			}
+			continue
+		}
+		if movie, ok := movieByMAL[malID]; ok {
+			wanted[fmt.Sprintf("movie:%d", movie.Trakt.ID)] = true
+			continue
+		}
+		unmapped = append(unmapped, malID)
+	}
+	return wanted, unmapped
+}
+
+// buildListPayload converts item keys into an add/remove request body
+func buildListPayload(keys []string) traktListPayload {
+	var payload traktListPayload
+	for _, key := range keys {
+		kind, rawID, _ := strings.Cut(key, ":")
+		var item traktListIDs
+		item.IDs.Trakt, _ = strconv.Atoi(rawID)
+		switch kind {
+		case "show":
+			payload.Shows = append(payload.Shows, item)
+		case "season":
+			payload.Seasons = append(payload.Seasons, item)
+		case "movie":
+			payload.Movies = append(payload.Movies, item)
+		}
+	}
+	return payload
+}
+
+// listPayloadKeys is the inverse of buildListPayload
+func listPayloadKeys(payload traktListPayload) []string {
+	var keys []string
+	for _, item := range payload.Shows {
+		keys = append(keys, fmt.Sprintf("show:%d", item.IDs.Trakt))
+	}
+	for _, item := range payload.Seasons {
+		keys = append(keys, fmt.Sprintf("season:%d", item.IDs.Trakt))
+	}
+	for _, item := range payload.Movies {
+		keys = append(keys, fmt.Sprintf("movie:%d", item.IDs.Trakt))
+	}
+	return keys
+}
+
+// formatListCounts renders Trakt's per-type counts
+func formatListCounts(counts map[string]int) string {
+	var parts []string
+	for _, kind := range sortedKeys(counts) {
+		if counts[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[kind], kind))
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// joinInts formats IDs as a comma-separated string
+func joinInts(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findList returns the user's list with the given name or slug, or nil
+func (tc *traktListClient) findList(name string) (*TraktList, error) {
+	var lists []TraktList
+	if err := tc.do("GET", "/users/me/lists", nil, &lists); err != nil {
+		return nil, fmt.Errorf("fetch lists: %w", err)
+	}
+	for i := range lists {
+		if strings.EqualFold(lists[i].Name, name) || lists[i].IDs.Slug == name {
+			return &lists[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// createList creates a new list for the user
+func (tc *traktListClient) createList(name, description, privacy string) (*TraktList, error) {
+	body := map[string]interface{}{
+		"name":            name,
+		"description":     description,
+		"privacy":         privacy,
+		"display_numbers": false,
+		"allow_comments":  true,
+	}
+	var list TraktList
+	if err := tc.do("POST", "/users/me/lists", body, &list); err != nil {
+		return nil, fmt.Errorf("create list: %w", err)
+	}
+	return &list, nil
+}
+
+// listItems returns the keys of every item currently on the list
+func (tc *traktListClient) listItems(listID int) (map[string]bool, error) {
+	var items []traktListItem
+	if err := tc.do("GET", fmt.Sprintf("/users/me/lists/%d/items", listID), nil, &items); err != nil {
+		return nil, fmt.Errorf("fetch list items: %w", err)
+	}
+	keys := make(map[string]bool, len(items))
+	for _, item := range items {
+		switch {
+		case item.Type == "season" && item.Season != nil:
+			keys[fmt.Sprintf("season:%d", item.Season.IDs.Trakt)] = true
+		case item.Type == "show" && item.Show != nil:
+			keys[fmt.Sprintf("show:%d", item.Show.IDs.Trakt)] = true
+		case item.Type == "movie" && item.Movie != nil:
+			keys[fmt.Sprintf("movie:%d", item.Movie.IDs.Trakt)] = true
+		}
+	}
+	return keys, nil
+}
+
+// modifyItems adds (suffix "") or removes (suffix "/remove") list items
+func (tc *traktListClient) modifyItems(listID int, suffix string, payload traktListPayload) (*traktListResult, error) {
+	var result traktListResult
+	if err := tc.do("POST", fmt.Sprintf("/users/me/lists/%d/items%s", listID, suffix), payload, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// do performs an authenticated Trakt API call, decoding the JSON response into out
+func (tc *traktListClient) do(method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	tc.rateLimiter.Wait()
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+		req, err := http.NewRequest(method, tc.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("trakt-api-version", "2")
+		req.Header.Set("trakt-api-key", tc.apiKey)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		return tc.client.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == 401 {
+		return fmt.Errorf("unauthorized: the access token is invalid or expired")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("API error: %d %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil && len(data) > 0 {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
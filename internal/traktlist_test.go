@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRunSyncList(t *testing.T) {
+	t.Chdir(t.TempDir())
+	data := testDataset(t)
+	SaveJSON("tv_ex.json", data.ShowList())
+	SaveJSON("movies_ex.json", data.MovieList())
+
+	posted := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("trakt-api-key") != "key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /users/me/lists":
+			io.WriteString(w, `[{"name": "Watchlist", "ids": {"trakt": 6, "slug": "watchlist"}}, {"name": "Anime", "ids": {"trakt": 7, "slug": "anime"}}]`)
+		case "GET /users/me/lists/7/items":
+			// MAL 1's season is already on the list, movie 99 is not wanted
+			io.WriteString(w, `[
+				{"type": "season", "season": {"ids": {"trakt": 1}}, "show": {"ids": {"trakt": 30857}}},
+				{"type": "movie", "movie": {"ids": {"trakt": 99}}}
+			]`)
+		case "POST /users/me/lists/7/items":
+			body, _ := io.ReadAll(r.Body)
+			posted["add"] = string(body)
+			io.WriteString(w, `{"added": {"seasons": 1, "movies": 1}, "existing": {}, "not_found": {}}`)
+		case "POST /users/me/lists/7/items/remove":
+			body, _ := io.ReadAll(r.Body)
+			posted["remove"] = string(body)
+			io.WriteString(w, `{"deleted": {"movies": 1}, "not_found": {}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	args := []string{"-trakt-api-url", server.URL, "-token", "token", "-api-key", "key", "-list", "anime",
+		"-mal-ids", "1,5,43,999", "-tv", "tv_ex.json", "-movies", "movies_ex.json"}
+	if err := RunSyncList(append(args, "-prune")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"add":    `{"seasons":[{"ids":{"trakt":2}}],"movies":[{"ids":{"trakt":1}}]}`,
+		"remove": `{"movies":[{"ids":{"trakt":99}}]}`,
+	}
+	for kind, body := range want {
+		var got traktListPayload
+		json.Unmarshal([]byte(posted[kind]), &got)
+		if gotJSON, _ := json.Marshal(got); string(gotJSON) != body {
+			t.Errorf("%s payload = %s, want %s", kind, posted[kind], body)
+		}
+	}
+
+	// Without -prune nothing is removed; a dry run changes nothing
+	clear(posted)
+	if err := RunSyncList(args); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := posted["remove"]; ok || posted["add"] == "" {
+		t.Errorf("sync without -prune posted %v, want only an add", posted)
+	}
+	clear(posted)
+	if err := RunSyncList(append(args, "-prune", "-dry-run")); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 0 {
+		t.Errorf("dry run posted %v", posted)
+	}
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// TraktShowsExport groups the TV dataset by Trakt show, for tools that start
+// from a Trakt item and need the MAL entries covering it
+type TraktShowsExport struct {
+	GeneratedAt string          `json:"generated_at"`
+	Shows       []TraktShowView `json:"shows"`
+}
+
+// TraktShowView is one Trakt show with every MAL entry mapped to it
+type TraktShowView struct {
+	ID        int                 `json:"id"`
+	Slug      string              `json:"slug"`
+	Title     string              `json:"title"`
+	URL       string              `json:"url"`
+	Externals *TraktExternalsShow `json:"externals"`
+	Entries   []TraktShowEntry    `json:"entries"`
+}
+
+// TraktShowEntry is a MAL entry within a Trakt show. Season is null when the
+// entry covers the whole show (a split cour, a show-level part, or absolute
+// numbering).
+type TraktShowEntry struct {
+	MalID          int    `json:"mal_id"`
+	Title          string `json:"title"`
+	Season         *int   `json:"season"`
+	EpisodeCount   int    `json:"episode_count,omitempty"`
+	EpisodeOffset  int    `json:"episode_offset,omitempty"`  // for a later cour of the season
+	AbsoluteOffset *int   `json:"absolute_offset,omitempty"` // for a show numbered absolutely
+	ReleaseYear    int    `json:"release_year,omitempty"`
+	Part           int    `json:"part,omitempty"` // watch order within a multi-part entry
+}
+
+// RunExportTraktShows implements the `export-trakt-shows` subcommand
+func RunExportTraktShows(args []string) error {
+	fs := flag.NewFlagSet("export-trakt-shows", flag.ExitOnError)
+	output := fs.String("output", "dist/trakt-shows.json", "Path of the JSON file to write")
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	fs.Parse(args)
+
+	shows, err := LoadShowOutput(*tvFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 {
+		return fmt.Errorf("no entries found in %s", *tvFile)
+	}
+
+	export := TraktShowsExport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Shows:       groupByTraktShow(shows),
+	}
+	if err := os.MkdirAll(filepath.Dir(*output), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d Trakt shows covering %d MAL entries to %s\n", len(export.Shows), len(shows), *output)
+	return nil
+}
+
+// groupByTraktShow inverts the dataset. A multi-part entry is listed under
+// every show its parts point at. Shows are sorted by Trakt ID and their
+// entries by season (whole-show entries first), release year, episode
+// offsets and MAL ID.
+func groupByTraktShow(shows []OutputShow) []TraktShowView {
+	byID := make(map[int]*TraktShowView)
+	view := func(id int, slug, title string) *TraktShowView {
+		v, ok := byID[id]
+		if !ok {
+			v = &TraktShowView{ID: id, Slug: slug, Title: title, URL: "https://trakt.tv/shows/" + slug}
+			byID[id] = v
+		}
+		if v.Title == "" {
+			v.Title = title
+		}
+		return v
+	}
+
+	for _, show := range shows {
+		if len(show.Parts) > 0 {
+			for _, part := range show.Parts {
+				v := view(part.ID, part.Slug, part.Title)
+				v.Entries = append(v.Entries, TraktShowEntry{
+					MalID:       show.MyAnimeList.ID,
+					Title:       show.MyAnimeList.Title,
+					Season:      part.Season,
+					ReleaseYear: show.ReleaseYear,
+					Part:        part.Order,
+				})
+				if part.ID == show.Trakt.ID && v.Externals == nil {
+					v.Externals = show.Externals
+				}
+			}
+			continue
+		}
+
+		v := view(show.Trakt.ID, show.Trakt.Slug, show.Trakt.Title)
+		if v.Externals == nil {
+			v.Externals = show.Externals
+		}
+		entry := TraktShowEntry{MalID: show.MyAnimeList.ID, Title: show.MyAnimeList.Title, ReleaseYear: show.ReleaseYear, AbsoluteOffset: show.AbsoluteOffset}
+		if show.Trakt.Season != nil {
+			season := show.Trakt.Season.Number
+			entry.Season = &season
+			entry.EpisodeCount = show.Trakt.Season.EpisodeCount
+			entry.EpisodeOffset = show.Trakt.Season.EpisodeOffset
+		}
+		v.Entries = append(v.Entries, entry)
+	}
+
+	views := make([]TraktShowView, 0, len(byID))
+	for _, v := range byID {
+		slices.SortFunc(v.Entries, func(a, b TraktShowEntry) int {
+			return cmp.Or(
+				cmp.Compare(optionalOrder(a.Season), optionalOrder(b.Season)),
+				cmp.Compare(a.ReleaseYear, b.ReleaseYear),
+				cmp.Compare(a.EpisodeOffset, b.EpisodeOffset),
+				cmp.Compare(optionalOrder(a.AbsoluteOffset), optionalOrder(b.AbsoluteOffset)),
+				cmp.Compare(a.MalID, b.MalID),
+			)
+		})
+		views = append(views, *v)
+	}
+	slices.SortFunc(views, func(a, b TraktShowView) int { return cmp.Compare(a.ID, b.ID) })
+	return views
+}
+
+// optionalOrder sorts an absent number first, e.g. whole-show entries before
+// season 0 (specials)
+func optionalOrder(n *int) int {
+	if n == nil {
+		return -1
+	}
+	return *n
+}
@@ -0,0 +1,36 @@
+package internal
+
+import "testing"
+
+func TestGroupByTraktShow(t *testing.T) {
+	season := func(show *OutputShow, number int) {
+		show.Trakt.Season = &struct {
+			ID            int                   `json:"id"`
+			Number        int                   `json:"number"`
+			EpisodeCount  int                   `json:"episode_count,omitempty"`
+			EpisodeOffset int                   `json:"episode_offset,omitempty"`
+			Externals     *TraktExternalsSeason `json:"externals"`
+		}{Number: number, EpisodeCount: 12}
+	}
+	var s2, s1, other OutputShow
+	s2.MyAnimeList.ID, s2.Trakt.ID, s2.Trakt.Slug = 2, 100, "show"
+	season(&s2, 2)
+	s1.MyAnimeList.ID, s1.Trakt.ID, s1.Trakt.Slug = 1, 100, "show"
+	season(&s1, 1)
+	other.MyAnimeList.ID, other.Trakt.ID, other.Trakt.Slug = 3, 50, "other"
+
+	views := groupByTraktShow([]OutputShow{s2, other, s1})
+	if len(views) != 2 || views[0].ID != 50 || views[1].ID != 100 {
+		t.Fatalf("views = %+v, want shows 50 and 100", views)
+	}
+	entries := views[1].Entries
+	if len(entries) != 2 || entries[0].MalID != 1 || entries[1].MalID != 2 {
+		t.Fatalf("entries = %+v, want MAL 1 (season 1) before MAL 2 (season 2)", entries)
+	}
+	if *entries[0].Season != 1 || entries[0].EpisodeCount != 12 {
+		t.Errorf("entries[0] = %+v", entries[0])
+	}
+	if views[0].Entries[0].Season != nil {
+		t.Errorf("seasonless entry got season %d", *views[0].Entries[0].Season)
+	}
+}
@@ -0,0 +1,176 @@
+// Package trakttest runs a fake Trakt API for tests: canned responses by
+// path, injected rate limits and latency, and a log of the requests made.
+// Point Config.TraktAPIURL (or -trakt-api-url) at Server.URL to use it.
+package trakttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a fake Trakt API. Paths without a response answer 404, like
+// Trakt does for unknown items; the query string is not matched.
+type Server struct {
+	*httptest.Server
+	APIKey string // when set, requests with another trakt-api-key get 403
+
+	mu         sync.Mutex
+	responses  map[string]response
+	prefixes   map[string]response // answers for paths under a prefix
+	latency    time.Duration
+	limited    int // requests still to answer 429
+	retryAfter int // Retry-After of the 429s, in seconds
+	requests   []string
+}
+
+// response is a canned answer to one path
+type response struct {
+	status int
+	body   []byte
+}
+
+// NewServer starts a fake Trakt API that is closed when the test ends. It
+// answers /genres/shows, so the preflight check passes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{responses: map[string]response{}, prefixes: map[string]response{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	s.Handle("/genres/shows", http.StatusOK, []any{})
+	return s
+}
+
+// Handle answers path with status and body: a string or []byte is sent as
+// is, anything else as JSON
+func (s *Server) Handle(path string, status int, body any) {
+	data := encode(path, body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[path] = response{status, data}
+}
+
+// HandlePrefix answers every path under prefix that has no response of its
+// own, like Handle
+func (s *Server) HandlePrefix(prefix string, status int, body any) {
+	data := encode(prefix, body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefixes[prefix] = response{status, data}
+}
+
+// encode renders a canned body for Handle and HandlePrefix
+func encode(path string, body any) []byte {
+	var data []byte
+	switch body := body.(type) {
+	case nil:
+	case string:
+		data = []byte(body)
+	case []byte:
+		data = body
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			panic(fmt.Sprintf("trakttest: %s: %v", path, err))
+		}
+	}
+	return data
+}
+
+// Show answers /shows/{id} and /shows/{slug} with show
+func (s *Server) Show(id int, slug string, show any) {
+	s.Handle(fmt.Sprintf("/shows/%d", id), http.StatusOK, show)
+	s.Handle("/shows/"+slug, http.StatusOK, show)
+}
+
+// Seasons answers /shows/{id}/seasons with seasons
+func (s *Server) Seasons(showID int, seasons any) {
+	s.Handle(fmt.Sprintf("/shows/%d/seasons", showID), http.StatusOK, seasons)
+}
+
+// Updates answers /{mediaType}/updates/{since}, whatever the date, with
+// updates; mediaType is "shows" or "movies"
+func (s *Server) Updates(mediaType string, updates any) {
+	s.HandlePrefix("/"+mediaType+"/updates/", http.StatusOK, updates)
+}
+
+// Movie answers /movies/{id} and /movies/{slug} with movie
+func (s *Server) Movie(id int, slug string, movie any) {
+	s.Handle(fmt.Sprintf("/movies/%d", id), http.StatusOK, movie)
+	s.Handle("/movies/"+slug, http.StatusOK, movie)
+}
+
+// RateLimit answers the next n requests with 429 and a Retry-After of
+// retryAfter seconds
+func (s *Server) RateLimit(n, retryAfter int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited, s.retryAfter = n, retryAfter
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Requests lists the requests made so far as "METHOD /path"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Count returns how many requests were made for path, whatever the method
+// and status
+func (s *Server) Count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for _, request := range s.requests {
+		if _, p, _ := strings.Cut(request, " "); p == path {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	latency := s.latency
+	limited := s.limited > 0
+	if limited {
+		s.limited--
+	}
+	resp, ok := s.responses[r.URL.Path]
+	for prefix, prefixResp := range s.prefixes {
+		if !ok && strings.HasPrefix(r.URL.Path, prefix) {
+			resp, ok = prefixResp, true
+		}
+	}
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+	switch {
+	case r.Header.Get("trakt-api-version") != "2":
+		http.Error(w, "missing trakt-api-version", http.StatusBadRequest)
+	case s.APIKey != "" && r.Header.Get("trakt-api-key") != s.APIKey:
+		w.WriteHeader(http.StatusForbidden)
+	case limited:
+		w.Header().Set("Retry-After", strconv.Itoa(s.retryAfter))
+		w.WriteHeader(http.StatusTooManyRequests)
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.status)
+		w.Write(resp.body)
+	}
+}
@@ -0,0 +1,36 @@
+package trakttest
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	server.APIKey = "key"
+	server.Handle("/movies/47", http.StatusOK, `{"title": "Akira"}`)
+	get := func(path, key string) int {
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("trakt-api-version", "2")
+		req.Header.Set("trakt-api-key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	server.RateLimit(1, 0)
+	statuses := []int{get("/movies/47", "key"), get("/movies/47", "key"), get("/movies/47", "bad"), get("/movies/1", "key")}
+	if want := []int{429, 200, 403, 404}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if n := server.Count("/movies/47"); n != 3 {
+		t.Errorf("Count = %d, want 3", n)
+	}
+	if got := server.Requests(); len(got) != 4 || got[3] != "GET /movies/1" {
+		t.Errorf("Requests = %v", got)
+	}
+}
@@ -0,0 +1,234 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// tvdbAPIBase is the TheTVDB v4 API root
+const tvdbAPIBase = "https://api4.thetvdb.com/v4"
+
+// errTVDBNotFound is returned when TheTVDB has no series with the given ID
+var errTVDBNotFound = errors.New("not found on TheTVDB")
+
+// TVDBClient talks to the TheTVDB v4 API. It logs in on first use and keeps
+// the bearer token, or the login error, for the rest of the run.
+type TVDBClient struct {
+	apiKey      string
+	pin         string
+	client      *http.Client
+	rateLimiter *RateLimiter
+
+	mu       sync.Mutex
+	token    string
+	loginErr error
+}
+
+// NewTVDBClient creates a client for apiKey (and the subscriber pin, if the
+// key needs one), or returns nil when apiKey is empty
+func NewTVDBClient(apiKey, pin string) *TVDBClient {
+	if apiKey == "" {
+		return nil
+	}
+	return &TVDBClient{
+		apiKey:      apiKey,
+		pin:         pin,
+		client:      NewHTTPClient(30 * time.Second),
+		rateLimiter: NewTVDBRateLimiter(),
+	}
+}
+
+// tvdbSeries is the part of /series/{id} that is checked
+type tvdbSeries struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Year    string `json:"year"`
+	Aliases []struct {
+		Name string `json:"name"`
+	} `json:"aliases"`
+}
+
+// login exchanges the API key for a bearer token, once per run
+func (c *TVDBClient) login() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" || c.loginErr != nil {
+		return c.token, c.loginErr
+	}
+
+	payload, _ := json.Marshal(map[string]string{"apikey": c.apiKey, "pin": c.pin})
+	resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+		req, err := http.NewRequest("POST", tvdbAPIBase+"/login", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return c.client.Do(req)
+	})
+	if err != nil {
+		c.loginErr = err
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		c.loginErr = fmt.Errorf("TheTVDB login failed: %d", resp.StatusCode)
+		return "", c.loginErr
+	}
+
+	var login struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		c.loginErr = err
+		return "", err
+	}
+	c.token = login.Data.Token
+	return c.token, nil
+}
+
+// FetchTVDBSeries fetches a series from TheTVDB
+func FetchTVDBSeries(config Config, id int) (*tvdbSeries, error) {
+	cacheFile := filepath.Join(config.TempDir, "tvdb", fmt.Sprintf("series_%d.json", id))
+	return singleFetch(cacheFile, func() (*tvdbSeries, error) {
+		if useCache(config, cacheFile) {
+			if series, ok := cacheLoad[tvdbSeries](config.Cache, cacheFile); ok {
+				return &series, nil
+			}
+		}
+
+		c := config.TVDB
+		token, err := c.login()
+		if err != nil {
+			return nil, err
+		}
+		c.rateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("%s/series/%d", tvdbAPIBase, id), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			return c.client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == 404 {
+			return nil, errTVDBNotFound
+		}
+		if resp.StatusCode != 200 {
+			return nil, statusError(resp, fmt.Errorf("TheTVDB API error: %d", resp.StatusCode))
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		var envelope struct {
+			Data tvdbSeries `json:"data"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, err
+		}
+		body, _ = json.MarshalIndent(envelope.Data, "", "  ")
+		cacheStore(config.Cache, cacheFile, body, envelope.Data)
+		return &envelope.Data, nil
+	})
+}
+
+// matches reports whether the series plausibly is the Trakt show: its name or
+// one of its aliases matches the title, or its year is within one of year
+func (s tvdbSeries) matches(title string, year int) bool {
+	want := normalizeTitle(title)
+	for _, name := range append([]string{s.Name}, aliasNames(s)...) {
+		if got := normalizeTitle(name); got != "" && want != "" && (strings.Contains(got, want) || strings.Contains(want, got)) {
+			return true
+		}
+	}
+	seriesYear, err := strconv.Atoi(s.Year)
+	return err == nil && year != 0 && seriesYear >= year-1 && seriesYear <= year+1
+}
+
+// aliasNames lists the alias names of a series
+func aliasNames(s tvdbSeries) []string {
+	names := make([]string, 0, len(s.Aliases))
+	for _, alias := range s.Aliases {
+		names = append(names, alias.Name)
+	}
+	return names
+}
+
+// normalizeTitle lowercases a title and drops everything but letters and
+// digits, so punctuation and spacing differences do not count
+func normalizeTitle(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, title)
+}
+
+// tvdbEnricher checks the TVDB IDs Trakt reports for shows; see
+// validateShowTVDB
+type tvdbEnricher struct{}
+
+func (tvdbEnricher) Name() string { return "tvdb" }
+
+func (tvdbEnricher) Applies(entry EnrichEntry) bool {
+	return entry.Show != nil && entry.Show.Externals != nil && entry.Show.Externals.TVDB != nil
+}
+
+func (tvdbEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	validateShowTVDB(ctx.Config, entry.Show, ctx.Stats)
+}
+
+// validateShowTVDB checks that the TVDB ID Trakt reports for a show exists
+// and points to the same series. Problems are recorded in
+// stats.ValidationDetails; the output is left unchanged.
+func validateShowTVDB(config Config, show *OutputShow, stats *ProcessingStats) {
+	if !config.Enrichments["tvdb"] || config.TVDB == nil || show.Externals == nil || show.Externals.TVDB == nil {
+		return
+	}
+	tvdbID := *show.Externals.TVDB
+	config.Progress.Phase("tvdb")
+
+	series, err := FetchTVDBSeries(config, tvdbID)
+	var reason string
+	switch {
+	case errors.Is(err, errTVDBNotFound):
+		reason = fmt.Sprintf("TVDB ID %d reported by Trakt does not exist on TheTVDB", tvdbID)
+	case err != nil:
+		log.Printf("Warning: Failed to fetch TheTVDB series %d: %v", tvdbID, err)
+		config.Errors.Record("tvdb", show.MyAnimeList.ID, show.MyAnimeList.Title, err)
+		return
+	case !series.matches(show.Trakt.Title, show.ReleaseYear):
+		reason = fmt.Sprintf("TVDB ID %d points to %q (%s), not %q (%d)",
+			tvdbID, series.Name, series.Year, show.Trakt.Title, show.ReleaseYear)
+	default:
+		if config.Verbose {
+			Debugf("\n    - TVDB %d: %s", tvdbID, series.Name)
+		}
+		return
+	}
+	stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+		MalID:  show.MyAnimeList.ID,
+		Title:  show.MyAnimeList.Title,
+		Reason: reason,
+	})
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTVDBSeriesMatches(t *testing.T) {
+	var series tvdbSeries
+	json.Unmarshal([]byte(`{"id": 76885, "name": "カウボーイビバップ", "year": "1998", "aliases": [{"name": "Cowboy Bebop"}]}`), &series)
+
+	cases := []struct {
+		title string
+		year  int
+		want  bool
+	}{
+		{"Cowboy Bebop", 1998, true},
+		{"cowboy bebop!", 2005, true}, // alias match despite punctuation
+		{"Space Dandy", 1999, true},   // year within one
+		{"Space Dandy", 2014, false},
+		{"", 0, false},
+	}
+	for _, c := range cases {
+		if got := series.matches(c.title, c.year); got != c.want {
+			t.Errorf("matches(%q, %d) = %v, want %v", c.title, c.year, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Credentials holds the access keys used to sign S3 requests
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Client uploads objects to an S3-compatible bucket (AWS S3, Cloudflare R2,
+// or Google Cloud Storage via its XML interoperability API with HMAC keys)
+// using path-style URLs and AWS Signature Version 4.
+type S3Client struct {
+	Endpoint    string // e.g. https://<account>.r2.cloudflarestorage.com
+	Bucket      string
+	Region      string // "auto" for R2, "us-east-1" etc. for S3/GCS
+	Credentials S3Credentials
+	HTTPClient  *http.Client
+}
+
+// UploadPointer is the "latest" pointer object written after a successful upload
+type UploadPointer struct {
+	Version     string   `json:"version"`
+	UploadedAt  string   `json:"uploaded_at"`
+	BaseURL     string   `json:"base_url"`
+	Files       []string `json:"files"`
+	ManifestURL string   `json:"manifest_url,omitempty"`
+}
+
+const (
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	pointerCacheControl   = "public, max-age=60, must-revalidate"
+)
+
+// RunUpload implements the `upload` subcommand
+func RunUpload(args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	endpoint := fs.String("endpoint", os.Getenv("S3_ENDPOINT"), "S3-compatible endpoint URL (env S3_ENDPOINT)")
+	bucket := fs.String("bucket", os.Getenv("S3_BUCKET"), "Bucket name (env S3_BUCKET)")
+	region := fs.String("region", envOr("S3_REGION", "auto"), "Signing region (env S3_REGION; \"auto\" for R2)")
+	prefix := fs.String("prefix", "", "Key prefix inside the bucket")
+	// Versioned objects are cached as immutable, so the default must not
+	// repeat across runs, even on the same day
+	version := fs.String("version", time.Now().UTC().Format("20060102T150405Z"), "Version directory to upload into; never reuse one")
+	manifestPath := fs.String("manifest", ManifestPath(), "Path to the dataset manifest")
+	publicURL := fs.String("public-url", "", "Public base URL recorded in the latest pointer (defaults to endpoint/bucket)")
+	dryRun := fs.Bool("dry-run", false, "Print what would be uploaded without uploading")
+	var includes stringList
+	fs.Var(&includes, "include", "Extra file or glob to upload (repeatable)")
+	fs.Parse(args)
+
+	if *endpoint == "" || *bucket == "" {
+		return fmt.Errorf("both -endpoint and -bucket (or S3_ENDPOINT / S3_BUCKET) are required")
+	}
+
+	client := &S3Client{
+		Endpoint: strings.TrimSuffix(*endpoint, "/"),
+		Bucket:   *bucket,
+		Region:   *region,
+		Credentials: S3Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		HTTPClient: NewHTTPClient(5 * time.Minute),
+	}
+	if !*dryRun && (client.Credentials.AccessKeyID == "" || client.Credentials.SecretAccessKey == "") {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	files, err := bundleFiles(*manifestPath, includes)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("nothing to upload")
+	}
+	// Keys mirror the local paths, which must not leave the version prefix
+	names := make(map[string]string, len(files))
+	for _, file := range files {
+		name, err := uploadName(file)
+		if err != nil {
+			return err
+		}
+		names[file] = name
+	}
+	manifestName, _ := uploadName(*manifestPath)
+
+	versionPrefix := path.Join(*prefix, *version)
+	pointer := UploadPointer{
+		Version:    *version,
+		UploadedAt: time.Now().UTC().Format(time.RFC3339),
+		BaseURL:    strings.TrimSuffix(*publicURL, "/"),
+	}
+	if pointer.BaseURL == "" {
+		pointer.BaseURL = client.Endpoint + "/" + client.Bucket
+	}
+	pointer.BaseURL += "/" + versionPrefix
+
+	// Versioned objects are uploaded first and never change afterwards
+	for _, file := range files {
+		key := path.Join(versionPrefix, names[file])
+		pointer.Files = append(pointer.Files, names[file])
+		if names[file] == manifestName {
+			pointer.ManifestURL = pointer.BaseURL + "/" + names[file]
+		}
+
+		if *dryRun {
+			fmt.Printf("would upload %s → s3://%s/%s\n", file, client.Bucket, key)
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := client.PutObject(key, data, contentTypeFor(file), immutableCacheControl); err != nil {
+			return fmt.Errorf("upload %s: %w", file, err)
+		}
+		fmt.Printf("uploaded %s → s3://%s/%s\n", file, client.Bucket, key)
+	}
+
+	// Swapping the single pointer object is atomic: readers see either the
+	// previous version or the complete new one, never a mix of both.
+	pointerKey := path.Join(*prefix, "latest.json")
+	body, err := json.MarshalIndent(pointer, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("would update s3://%s/%s → %s\n", client.Bucket, pointerKey, *version)
+		return nil
+	}
+	if err := client.PutObject(pointerKey, body, "application/json", pointerCacheControl); err != nil {
+		return fmt.Errorf("update latest pointer: %w", err)
+	}
+	fmt.Printf("updated s3://%s/%s → %s\n", client.Bucket, pointerKey, *version)
+	return nil
+}
+
+// uploadName returns the slash-separated path a file is uploaded under,
+// relative to the working directory or, for a data layout elsewhere, to the
+// layout's base. Files outside both are rejected, as their keys would leave
+// the version prefix.
+func uploadName(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	base, err := filepath.Abs(layout.Base)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range []string{wd, base} {
+		if rel, err := filepath.Rel(dir, abs); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("cannot upload %s: it is outside both the working directory and the data directory %s", file, layout.Base)
+}
+
+// PutObject uploads data to key with the given content type and cache control
+func (c *S3Client) PutObject(key string, data []byte, contentType, cacheControl string) error {
+	objectURL := c.Endpoint + "/" + s3EscapePath(c.Bucket+"/"+key)
+	req, err := http.NewRequest("PUT", objectURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", cacheControl)
+
+	sum := sha256.Sum256(data)
+	signS3Request(req, c.Credentials, c.Region, hex.EncodeToString(sum[:]), time.Now())
+
+	retryConfig := DefaultRetryConfig()
+	resp, err := RetryWithBackoff(retryConfig, func() (*http.Response, error) {
+		// Rewind the body for every attempt
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		return c.HTTPClient.Do(req)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req. Every header
+// already present on the request (plus host) is included in the signature.
+func signS3Request(req *http.Request, creds S3Credentials, region, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(strings.TrimPrefix(req.URL.EscapedPath(), "/")),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath URI-encodes each path segment as required by SigV4
+func s3EscapePath(p string) string {
+	p, _ = url.PathUnescape(p)
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = s3Escape(seg)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// s3CanonicalQuery renders query parameters sorted and URI-encoded
+func s3CanonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string{}, values[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything except RFC 3986 unreserved characters
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// contentTypeFor returns the MIME type used when uploading a dataset artifact.
+// Types the mime package may not know are listed first; anything unknown is
+// uploaded as binary.
+func contentTypeFor(file string) string {
+	switch {
+	case strings.HasSuffix(file, ".json"):
+		return "application/json"
+	case strings.HasSuffix(file, ".tar.gz"), strings.HasSuffix(file, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(file, ".sqlite"):
+		return "application/vnd.sqlite3"
+	case strings.HasSuffix(file, ".db"):
+		// export-bolt writes a bbolt file, which has no registered type
+		return "application/octet-stream"
+	case strings.HasSuffix(file, ".sha256"), strings.HasSuffix(file, ".minisig"), strings.HasSuffix(file, ".txt"):
+		return "text/plain; charset=utf-8"
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(file)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// envOr returns the value of an environment variable or a fallback
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
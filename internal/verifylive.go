@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// liveSample is one published mapping picked by verify-live
+type liveSample struct {
+	mediaType string // "tv" or "movies"
+	malID     int
+	title     string
+	traktID   int
+	slug      string
+	season    *int // shows mapped to one season
+}
+
+// liveDrift is how a sampled mapping differs from Trakt now; "" when it
+// still matches
+type liveDrift struct {
+	sample liveSample
+	reason string
+	err    error // lookups that failed for another reason than 404
+}
+
+// RunVerifyLive implements the `verify-live` subcommand: it re-fetches a
+// random sample of the published mappings from Trakt and fails when too many
+// have drifted, as a canary between full refreshes
+func RunVerifyLive(args []string) error {
+	fs := flag.NewFlagSet("verify-live", flag.ExitOnError)
+	tvFile := fs.String("tv", defaultOutput("tv"), "TV output file")
+	movieFile := fs.String("movies", defaultOutput("movies"), "Movie output file")
+	size := fs.Int("sample", 50, "Number of mappings to check")
+	maxDrift := fs.Float64("max-drift-percent", 2, "Fail when more than this percentage of the sample has drifted")
+	seed := fs.Uint64("seed", 0, "Random seed, to repeat a sample (0 = random; the seed used is printed)")
+	apiKey := fs.String("api-key", os.Getenv("TRAKT_API_KEY"), "Trakt client ID (env TRAKT_API_KEY)")
+	keychain := fs.Bool("keychain", false, "Read a missing -api-key from the OS keychain (see `keychain store`)")
+	fs.Parse(args)
+
+	if *keychain && *apiKey == "" {
+		*apiKey = KeychainLookup(KeychainAPIKey)
+	}
+	if *apiKey == "" {
+		return fmt.Errorf("a Trakt client ID is required (-api-key or TRAKT_API_KEY)")
+	}
+	if *size < 1 {
+		return fmt.Errorf("-sample must be at least 1")
+	}
+
+	shows, movies, err := LoadOutputs(*tvFile, *movieFile)
+	if err != nil {
+		return err
+	}
+	if len(shows) == 0 && len(movies) == 0 {
+		return fmt.Errorf("no entries found in %s or %s", *tvFile, *movieFile)
+	}
+	if *seed == 0 {
+		*seed = rand.Uint64()
+	}
+	samples := sampleMappings(shows, movies, *size, *seed)
+
+	// Lookups bypass the API cache, which is what a stale mapping would hide
+	tempDir, err := os.MkdirTemp("", "verify-live")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	for _, dir := range []string{"shows", "movies", "seasons"} {
+		os.MkdirAll(filepath.Join(tempDir, dir), 0755)
+	}
+	config := Config{
+		APIKey:      *apiKey,
+		TraktAPIURL: os.Getenv("TRAKT_API_URL"),
+		Force:       true,
+		TempDir:     tempDir,
+		Cache:       NewCache(0),
+		RateLimiter: NewRateLimiter(),
+	}
+	client := NewHTTPClient(30 * time.Second)
+
+	fmt.Printf("Checking %d of %d mappings against Trakt (seed %d)\n", len(samples), len(shows)+len(movies), *seed)
+	drifted, failed := 0, 0
+	for _, sample := range samples {
+		result := checkLiveMapping(client, config, sample)
+		switch {
+		case result.err != nil:
+			failed++
+			fmt.Printf("? %s %d %s: %v\n", sample.mediaType, sample.malID, sample.title, strings.TrimSpace(result.err.Error()))
+		case result.reason != "":
+			drifted++
+			fmt.Printf("✗ %s %d %s: %s\n", sample.mediaType, sample.malID, sample.title, result.reason)
+		}
+	}
+
+	checked := len(samples) - failed
+	percent := 0.0
+	if checked > 0 {
+		percent = float64(drifted) / float64(checked) * 100
+	}
+	fmt.Printf("%d of %d checked mappings drifted (%.1f%%), %d lookups failed\n", drifted, checked, percent, failed)
+	if percent > *maxDrift {
+		return exitErrorf(ExitDrift, "%.1f%% of the sampled mappings drifted, more than -max-drift-percent %g; a full refresh is due", percent, *maxDrift)
+	}
+	if checked == 0 {
+		return fmt.Errorf("every lookup failed; nothing was verified")
+	}
+	return nil
+}
+
+// sampleMappings picks up to n mappings across shows and movies, the same
+// ones for the same seed and dataset
+func sampleMappings(shows []OutputShow, movies []OutputMovie, n int, seed uint64) []liveSample {
+	pool := make([]liveSample, 0, len(shows)+len(movies))
+	for _, show := range shows {
+		sample := liveSample{mediaType: "tv", malID: show.MyAnimeList.ID, title: show.MyAnimeList.Title, traktID: show.Trakt.ID, slug: show.Trakt.Slug}
+		if show.Trakt.Season != nil {
+			number := show.Trakt.Season.Number
+			sample.season = &number
+		}
+		pool = append(pool, sample)
+	}
+	for _, movie := range movies {
+		pool = append(pool, liveSample{mediaType: "movies", malID: movie.MyAnimeList.ID, title: movie.MyAnimeList.Title, traktID: movie.Trakt.ID, slug: movie.Trakt.Slug})
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	return pool[:min(n, len(pool))]
+}
+
+// checkLiveMapping compares a sampled mapping with Trakt: the item must
+// still exist under the same ID and slug, and a mapped season must exist
+func checkLiveMapping(client *http.Client, config Config, sample liveSample) liveDrift {
+	result := liveDrift{sample: sample}
+	var traktID int
+	var slug string
+	var err error
+	if sample.mediaType == "tv" {
+		var show *TraktShow
+		if show, err = FetchTraktShow(client, config, sample.traktID); err == nil {
+			traktID, slug = show.IDs.Trakt, show.IDs.Slug
+		}
+	} else {
+		var movie *TraktMovie
+		if movie, err = FetchTraktMovie(client, config, sample.traktID); err == nil {
+			traktID, slug = movie.IDs.Trakt, movie.IDs.Slug
+		}
+	}
+	switch {
+	case errors.Is(err, errTraktNotFound):
+		result.reason = fmt.Sprintf("Trakt %d was deleted", sample.traktID)
+		return result
+	case err != nil:
+		result.err = err
+		return result
+	case traktID != sample.traktID:
+		result.reason = fmt.Sprintf("Trakt %d was merged into %d", sample.traktID, traktID)
+		return result
+	case slug != sample.slug:
+		result.reason = fmt.Sprintf("slug changed from %s to %s", sample.slug, slug)
+		return result
+	}
+
+	if sample.season != nil {
+		cacheFile := filepath.Join(config.TempDir, "seasons", fmt.Sprintf("%d.json", sample.traktID))
+		seasons, err := fetchTraktSeasons(client, config, sample.traktID, cacheFile)
+		if err != nil {
+			result.err = err
+		} else if findSeason(seasons, *sample.season) == nil {
+			result.reason = fmt.Sprintf("season %d is gone", *sample.season)
+		}
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLiveMapping(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"shows", "movies", "seasons"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0755)
+	}
+	responses := map[string]struct {
+		status int
+		body   string
+	}{
+		"/shows/1":         {200, `{"ids": {"trakt": 1, "slug": "one"}}`},
+		"/shows/2":         {200, `{"ids": {"trakt": 2, "slug": "two-renamed"}}`},
+		"/shows/4":         {200, `{"ids": {"trakt": 40, "slug": "four"}}`},
+		"/shows/5":         {200, `{"ids": {"trakt": 5, "slug": "five"}}`},
+		"/shows/5/seasons": {200, `[{"number": 1}]`},
+		"/movies/6":        {500, ``},
+	}
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		response, ok := responses[req.URL.Path]
+		if !ok {
+			response.status = 404
+		}
+		return &http.Response{StatusCode: response.status, Body: io.NopCloser(strings.NewReader(response.body)), Header: http.Header{}, Request: req}, nil
+	})}
+	config := Config{TempDir: dir, Cache: NewCache(0), RateLimiter: NewRateLimiter(), Force: true}
+
+	two := 2
+	tests := []struct {
+		sample liveSample
+		drift  string
+		failed bool
+	}{
+		{liveSample{mediaType: "tv", traktID: 1, slug: "one"}, "", false},
+		{liveSample{mediaType: "tv", traktID: 2, slug: "two"}, "slug changed from two to two-renamed", false},
+		{liveSample{mediaType: "tv", traktID: 3, slug: "three"}, "Trakt 3 was deleted", false},
+		{liveSample{mediaType: "tv", traktID: 4, slug: "four"}, "Trakt 4 was merged into 40", false},
+		{liveSample{mediaType: "tv", traktID: 5, slug: "five", season: &two}, "season 2 is gone", false},
+		{liveSample{mediaType: "movies", traktID: 6, slug: "six"}, "", true},
+	}
+	for _, tt := range tests {
+		result := checkLiveMapping(client, config, tt.sample)
+		if result.reason != tt.drift || (result.err != nil) != tt.failed {
+			t.Errorf("checkLiveMapping(Trakt %d) = %q, %v; want %q, failed %v", tt.sample.traktID, result.reason, result.err, tt.drift, tt.failed)
+		}
+	}
+}
+
+func TestSampleMappings(t *testing.T) {
+	data := testDataset(t)
+	shows, movies := data.ShowList(), data.MovieList()
+	first := sampleMappings(shows, movies, 2, 42)
+	again := sampleMappings(shows, movies, 2, 42)
+	if len(first) != 2 || len(again) != 2 {
+		t.Fatalf("sample of 2 has %d and %d mappings", len(first), len(again))
+	}
+	for i := range first {
+		if first[i].mediaType != again[i].mediaType || first[i].malID != again[i].malID {
+			t.Errorf("the same seed picked %s %d, then %s %d", first[i].mediaType, first[i].malID, again[i].mediaType, again[i].malID)
+		}
+	}
+	if all := sampleMappings(shows, movies, 100, 1); len(all) != len(shows)+len(movies) {
+		t.Errorf("sample of 100 has %d mappings, want all %d", len(all), len(shows)+len(movies))
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Warning kinds: data-quality signals on an entry that was still written,
+// unlike errors, which keep an entry out of the output
+const (
+	WarningLowConfidence    = "low_confidence"    // the Trakt title shares no word with the MAL title
+	WarningYearMismatch     = "year_mismatch"     // the MAL title's year is not the Trakt release year
+	WarningExternalsMissing = "externals_missing" // no IMDB or TMDB ID, nor a TVDB ID for shows
+	WarningFallback         = "fallback_used"     // not found by the input's Trakt ID
+	WarningEpisodeMismatch  = "episode_mismatch"  // MAL and Trakt season episode counts disagree
+)
+
+// EntryWarning is a data-quality warning on one entry
+type EntryWarning struct {
+	ChangeDetail
+	Kind string `json:"kind"`
+}
+
+// titleYear matches a year in a MAL title, e.g. "Hunter x Hunter (2011)"
+var titleYear = regexp.MustCompile(`\((\d{4})\)`)
+
+// showWarnings lists the warnings of a resolved show, after its override
+func showWarnings(show *OutputShow) []EntryWarning {
+	season := 0
+	if show.Trakt.Season != nil {
+		season = show.Trakt.Season.Number
+	}
+	externals := show.Externals
+	missing := ""
+	if externals == nil || externals.IMDB == nil && externals.TMDB == nil && externals.TVDB == nil {
+		missing = "No IMDB, TMDB or TVDB ID"
+	}
+	// The release year of a show is its first season's
+	return entryWarnings(show.MyAnimeList.ID, show.MyAnimeList.Title, show.Trakt.Title, show.Quality, show.ReleaseYear, season <= 1, missing)
+}
+
+// movieWarnings lists the warnings of a resolved movie, after its override
+func movieWarnings(movie *OutputMovie) []EntryWarning {
+	externals := movie.Externals
+	missing := ""
+	if externals == nil || externals.IMDB == nil && externals.TMDB == nil {
+		missing = "No IMDB or TMDB ID"
+	}
+	return entryWarnings(movie.MyAnimeList.ID, movie.MyAnimeList.Title, movie.Trakt.Title, movie.Quality, movie.ReleaseYear, true, missing)
+}
+
+// entryWarnings checks an entry; missing is the reason of its missing
+// externals, "" when it has some. An override vouches for the mapping, so
+// only missing externals are reported for overridden entries.
+func entryWarnings(malID int, title, traktTitle, quality string, year int, checkYear bool, missing string) []EntryWarning {
+	var warnings []EntryWarning
+	add := func(kind, reason string) {
+		warnings = append(warnings, EntryWarning{ChangeDetail{MalID: malID, Title: title, Reason: reason}, kind})
+	}
+
+	if quality != QualityOverride {
+		switch quality {
+		case QualitySlug:
+			add(WarningFallback, "Found by the guessed slug, the input's Trakt ID is gone")
+		case QualitySearchHigh, QualitySearchLow:
+			add(WarningFallback, "Found by external ID search")
+		}
+		if quality == QualitySearchLow || lowConfidence(title, traktTitle) {
+			add(WarningLowConfidence, fmt.Sprintf("Mapped to %q", traktTitle))
+		}
+		if match := titleYear.FindStringSubmatch(title); match != nil && checkYear && year != 0 {
+			if titled, _ := strconv.Atoi(match[1]); titled != year {
+				add(WarningYearMismatch, fmt.Sprintf("MAL title says %d, Trakt says %d", titled, year))
+			}
+		}
+	}
+	if missing != "" {
+		add(WarningExternalsMissing, missing)
+	}
+	return warnings
+}
+
+// warningDetails returns warnings as change details, the kind leading the
+// reason
+func warningDetails(warnings []EntryWarning) []ChangeDetail {
+	details := make([]ChangeDetail, len(warnings))
+	for i, warning := range warnings {
+		details[i] = warning.ChangeDetail
+		details[i].Reason = warning.Kind + ": " + warning.Reason
+	}
+	return details
+}
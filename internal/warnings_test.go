@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEntryWarnings(t *testing.T) {
+	kinds := func(warnings []EntryWarning) []string {
+		var kinds []string
+		for _, warning := range warnings {
+			kinds = append(kinds, warning.Kind)
+		}
+		return kinds
+	}
+	imdb := "tt0000001"
+
+	show := &OutputShow{Quality: QualityExactID, ReleaseYear: 2011, Externals: &TraktExternalsShow{IMDB: &imdb}}
+	show.MyAnimeList.Title, show.Trakt.Title = "Hunter x Hunter (2011)", "Hunter x Hunter"
+	if got := showWarnings(show); len(got) != 0 {
+		t.Errorf("clean show: warnings = %v", got)
+	}
+
+	show.ReleaseYear, show.Quality, show.Externals = 1999, QualitySlug, nil
+	want := []string{WarningFallback, WarningYearMismatch, WarningExternalsMissing}
+	if got := kinds(showWarnings(show)); !slices.Equal(got, want) {
+		t.Errorf("show warnings = %v, want %v", got, want)
+	}
+
+	// Later seasons carry the year of the first
+	show.Trakt.Season = &struct {
+		ID            int                   `json:"id"`
+		Number        int                   `json:"number"`
+		EpisodeCount  int                   `json:"episode_count,omitempty"`
+		EpisodeOffset int                   `json:"episode_offset,omitempty"`
+		Externals     *TraktExternalsSeason `json:"externals"`
+	}{Number: 2}
+	if got := kinds(showWarnings(show)); slices.Contains(got, WarningYearMismatch) {
+		t.Errorf("season 2: warnings = %v, want no year mismatch", got)
+	}
+
+	movie := &OutputMovie{Quality: QualitySearchLow, Externals: &TraktExternalsMovie{IMDB: &imdb}}
+	movie.MyAnimeList.Title, movie.Trakt.Title = "Kimi no Na wa.", "Your Name."
+	want = []string{WarningFallback, WarningLowConfidence}
+	if got := kinds(movieWarnings(movie)); !slices.Equal(got, want) {
+		t.Errorf("movie warnings = %v, want %v", got, want)
+	}
+
+	// An override vouches for the mapping, not for the externals
+	movie.Quality, movie.Externals = QualityOverride, &TraktExternalsMovie{}
+	want = []string{WarningExternalsMissing}
+	if got := kinds(movieWarnings(movie)); !slices.Equal(got, want) {
+		t.Errorf("overridden movie warnings = %v, want %v", got, want)
+	}
+}
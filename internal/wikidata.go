@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// wikidataEndpoint is the Wikidata Query Service SPARQL endpoint
+const wikidataEndpoint = "https://query.wikidata.org/sparql"
+
+// wikidataUserAgent identifies the project, as the Wikimedia User-Agent
+// policy asks of bots
+const wikidataUserAgent = "db.trakt.extended-anitrakt/1.0 (https://github.com/rensetsu/db.trakt.extended-anitrakt)"
+
+// wikidataQuery selects the TVDB (P4835), TMDB TV (P4983) and TMDB movie
+// (P4947) IDs of the items with an IMDB ID (P345)
+const wikidataQuery = `SELECT ?tvdb ?tmdbTV ?tmdbMovie WHERE {
+  ?item wdt:P345 %q .
+  OPTIONAL { ?item wdt:P4835 ?tvdb }
+  OPTIONAL { ?item wdt:P4983 ?tmdbTV }
+  OPTIONAL { ?item wdt:P4947 ?tmdbMovie }
+}`
+
+// errWikidataNotFound is returned when no Wikidata item has the IMDB ID
+var errWikidataNotFound = errors.New("not found on Wikidata")
+
+// SourceWikidata marks external IDs backfilled from Wikidata in
+// ExternalSources
+const SourceWikidata = "wikidata"
+
+// wikidataResponse is the SPARQL JSON result of wikidataQuery
+type wikidataResponse struct {
+	Results struct {
+		Bindings []map[string]struct {
+			Value string `json:"value"`
+		} `json:"bindings"`
+	} `json:"results"`
+}
+
+// WikidataIDs are the external IDs Wikidata records for an IMDB ID, 0 when
+// it has none
+type WikidataIDs struct {
+	TVDB      int
+	TMDBTV    int
+	TMDBMovie int
+}
+
+// ids takes the first value of each ID from the result rows
+func (r wikidataResponse) ids() WikidataIDs {
+	var ids WikidataIDs
+	for _, row := range r.Results.Bindings {
+		for name, field := range map[string]*int{"tvdb": &ids.TVDB, "tmdbTV": &ids.TMDBTV, "tmdbMovie": &ids.TMDBMovie} {
+			if *field == 0 {
+				*field, _ = strconv.Atoi(row[name].Value)
+			}
+		}
+	}
+	return ids
+}
+
+// FetchWikidataIDs looks an IMDB ID up on Wikidata. Results are cached under
+// config.TempDir/wikidata/<imdb_id>.json.
+func FetchWikidataIDs(client *http.Client, config Config, imdbID string) (*WikidataIDs, error) {
+	cacheFile := filepath.Join(config.TempDir, "wikidata", imdbID+".json")
+	response, err := singleFetch(cacheFile, func() (*wikidataResponse, error) {
+		if useCache(config, cacheFile) {
+			if response, ok := cacheLoad[wikidataResponse](config.Cache, cacheFile); ok {
+				return &response, nil
+			}
+		}
+
+		config.WikidataRateLimiter.Wait()
+		resp, err := RetryWithBackoff(DefaultRetryConfig(), func() (*http.Response, error) {
+			url := wikidataEndpoint + "?format=json&query=" + neturl.QueryEscape(fmt.Sprintf(wikidataQuery, imdbID))
+			req, err := http.NewRequest("GET", url, nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Accept", "application/sparql-results+json")
+			req.Header.Set("User-Agent", wikidataUserAgent)
+			return client.Do(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, statusError(resp, fmt.Errorf("wikidata API error: %d", resp.StatusCode))
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var response wikidataResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, err
+		}
+		os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		cacheStore(config.Cache, cacheFile, body, response)
+		return &response, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results.Bindings) == 0 {
+		return nil, errWikidataNotFound
+	}
+	ids := response.ids()
+	return &ids, nil
+}
+
+// wikidataEnabled reports whether the wikidata enrichment runs
+func wikidataEnabled(config Config) bool {
+	return config.Enrichments["wikidata"] && config.WikidataRateLimiter != nil
+}
+
+// wikidataEnricher fills missing TVDB and TMDB IDs from Wikidata by IMDB ID
+type wikidataEnricher struct{}
+
+func (wikidataEnricher) Name() string { return "wikidata" }
+
+func (wikidataEnricher) Applies(entry EnrichEntry) bool {
+	switch {
+	case entry.Show != nil:
+		return entry.Show.Externals != nil && entry.Show.Externals.IMDB != nil && *entry.Show.Externals.IMDB != ""
+	case entry.Movie != nil:
+		return entry.Movie.Externals != nil && entry.Movie.Externals.IMDB != nil && *entry.Movie.Externals.IMDB != ""
+	}
+	return false
+}
+
+func (wikidataEnricher) Enrich(ctx EnrichContext, entry EnrichEntry) {
+	if entry.Show != nil {
+		enrichShowWikidata(ctx.Client, ctx.Config, entry.Show, ctx.Stats)
+	} else {
+		enrichMovieWikidata(ctx.Client, ctx.Config, entry.Movie, ctx.Stats)
+	}
+}
+
+// enrichShowWikidata fills a show's missing TVDB and TMDB IDs from Wikidata
+// by its IMDB ID
+func enrichShowWikidata(client *http.Client, config Config, show *OutputShow, stats *ProcessingStats) {
+	if !wikidataEnabled(config) || show.Externals == nil || show.Externals.IMDB == nil || *show.Externals.IMDB == "" {
+		return
+	}
+	externals := show.Externals
+	if externals.TVDB != nil && externals.TMDB != nil {
+		return
+	}
+	ids := fetchWikidataIDs(client, config, *externals.IMDB, show.MyAnimeList.ID, show.MyAnimeList.Title)
+	if ids == nil {
+		return
+	}
+	backfill := func(name, label string, field **int, id int) {
+		if *field != nil || id == 0 {
+			return
+		}
+		*field = &id
+		setExternalSource(&show.ExternalSources, name, SourceWikidata)
+		recordWikidataFill(stats, show.MyAnimeList.ID, show.MyAnimeList.Title, label, id, *externals.IMDB)
+	}
+	backfill("tvdb", "TVDB", &externals.TVDB, ids.TVDB)
+	backfill("tmdb", "TMDB", &externals.TMDB, ids.TMDBTV)
+}
+
+// enrichMovieWikidata fills a movie's missing TMDB ID from Wikidata by its
+// IMDB ID
+func enrichMovieWikidata(client *http.Client, config Config, movie *OutputMovie, stats *ProcessingStats) {
+	if !wikidataEnabled(config) || movie.Externals == nil || movie.Externals.IMDB == nil || *movie.Externals.IMDB == "" {
+		return
+	}
+	if movie.Externals.TMDB != nil {
+		return
+	}
+	ids := fetchWikidataIDs(client, config, *movie.Externals.IMDB, movie.MyAnimeList.ID, movie.MyAnimeList.Title)
+	if ids == nil || ids.TMDBMovie == 0 {
+		return
+	}
+	id := ids.TMDBMovie
+	movie.Externals.TMDB = &id
+	setExternalSource(&movie.ExternalSources, "tmdb", SourceWikidata)
+	recordWikidataFill(stats, movie.MyAnimeList.ID, movie.MyAnimeList.Title, "TMDB", id, *movie.Externals.IMDB)
+}
+
+// fetchWikidataIDs wraps FetchWikidataIDs for the enrichment, logging
+// failures other than a missing item
+func fetchWikidataIDs(client *http.Client, config Config, imdbID string, malID int, title string) *WikidataIDs {
+	config.Progress.Phase("wikidata")
+	ids, err := FetchWikidataIDs(client, config, imdbID)
+	if err != nil {
+		if !errors.Is(err, errWikidataNotFound) {
+			log.Printf("Warning: Wikidata lookup of IMDB ID %s failed: %v", imdbID, err)
+			config.Errors.Record("wikidata", malID, title, err)
+		}
+		return nil
+	}
+	return ids
+}
+
+// recordWikidataFill lists a backfilled ID under the run's external ID checks
+func recordWikidataFill(stats *ProcessingStats, malID int, title, label string, id int, imdbID string) {
+	stats.ValidationDetails = append(stats.ValidationDetails, ChangeDetail{
+		MalID:  malID,
+		Title:  title,
+		Reason: fmt.Sprintf("%s ID %d filled from Wikidata (IMDB ID %s)", label, id, imdbID),
+	})
+}
+
+// setExternalSource records where a backfilled external ID came from
+func setExternalSource(sources *map[string]string, name, source string) {
+	if *sources == nil {
+		*sources = make(map[string]string)
+	}
+	(*sources)[name] = source
+}
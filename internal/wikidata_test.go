@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnrichShowWikidata(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "wikidata"), 0755)
+	os.WriteFile(filepath.Join(dir, "wikidata", "tt0213338.json"), []byte(`{"results": {"bindings": [
+		{"tvdb": {"value": "76885"}, "tmdbTV": {"value": "30991"}}
+	]}}`), 0644)
+	config := Config{
+		TempDir:             dir,
+		Cache:               NewCache(10),
+		Enrichments:         map[string]bool{"wikidata": true},
+		WikidataRateLimiter: NewWikidataRateLimiter(),
+	}
+
+	imdb, tmdb := "tt0213338", 1
+	var show OutputShow
+	show.Externals = &TraktExternalsShow{IMDB: &imdb, TMDB: &tmdb}
+	var stats ProcessingStats
+	enrichShowWikidata(nil, config, &show, &stats)
+
+	if show.Externals.TVDB == nil || *show.Externals.TVDB != 76885 {
+		t.Errorf("TVDB = %v, want 76885", show.Externals.TVDB)
+	}
+	if *show.Externals.TMDB != 1 {
+		t.Errorf("TMDB ID from Trakt was replaced with %d", *show.Externals.TMDB)
+	}
+	if len(show.ExternalSources) != 1 || show.ExternalSources["tvdb"] != SourceWikidata || len(stats.ValidationDetails) != 1 {
+		t.Errorf("sources = %v, details = %+v", show.ExternalSources, stats.ValidationDetails)
+	}
+}
@@ -1,65 +1,277 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rensetsu/db.trakt.extended-anitrakt/internal"
 )
 
 func main() {
+	// Flag defaults of the data files follow the layout, so load it first
+	internal.LoadLayout()
+
+	// Subcommands own their flag sets, so dispatch them before parsing the
+	// regular processing flags.
+	if len(os.Args) > 1 {
+		if cmd, ok := internal.LookupSubcommand(os.Args[1]); ok {
+			godotenv.Load()
+			if err := cmd.Run(os.Args[2:]); err != nil {
+				log.Printf("%s: %v", cmd.Name, err)
+				os.Exit(internal.ExitCode(err))
+			}
+			return
+		}
+	}
+
 	config := internal.ParseFlags()
+	if config.LogFile != "" {
+		logFile, err := internal.OpenLogFile(config.LogFile)
+		if err != nil {
+			log.Fatalf("Failed to open -log-file: %v", err)
+		}
+		defer logFile.Close()
+	}
+	internal.SetLogLevel(config.LogLevel)
+	if config.OutputFile == internal.StdioPath {
+		// Keep stdout clean for the results; logs already go to stderr
+		internal.RedirectStdout()
+	}
+	internal.SetColor(config.Color)
 
 	if err := godotenv.Load(); err != nil && config.Verbose {
-		fmt.Println("No .env file found, using environment variables")
+		internal.Debugf("No .env file found, using environment variables\n")
 	}
 
+	// -api-key, -api-key-file, TRAKT_API_KEY, TRAKT_API_KEY_FILE, the OS
+	// keychain with -keychain, then a prompt
+	if config.APIKey == "" && config.APIKeyFile != "" {
+		config.APIKey = readAPIKey("-api-key-file", config.APIKeyFile)
+	}
 	if config.APIKey == "" {
 		config.APIKey = os.Getenv("TRAKT_API_KEY")
 	}
+	if source := os.Getenv("TRAKT_API_KEY_FILE"); config.APIKey == "" && source != "" {
+		config.APIKey = readAPIKey("TRAKT_API_KEY_FILE", source)
+	}
 
+	if config.APIKey == "" && config.Keychain {
+		config.APIKey = internal.KeychainLookup(internal.KeychainAPIKey)
+	}
+	prompted := false
 	if config.APIKey == "" {
+		// The key would be read from the input stream otherwise
+		if slices.Contains(config.TvFiles, internal.StdioPath) || slices.Contains(config.MovieFiles, internal.StdioPath) {
+			log.Fatal("No Trakt API key and stdin is the input: pass -api-key or -api-key-file, or set TRAKT_API_KEY or TRAKT_API_KEY_FILE")
+		}
 		config.APIKey = internal.PromptForAPIKey()
+		prompted = true
 	}
 
-	// Create temp directory structure
+	// Optional: without a TMDB, TVDB or SIMKL key those enrichments are skipped
+	config.TMDBAPIKey = os.Getenv("TMDB_API_KEY")
+	config.SIMKLClientID = os.Getenv("SIMKL_CLIENT_ID")
+	config.TVDB = internal.NewTVDBClient(os.Getenv("TVDB_API_KEY"), os.Getenv("TVDB_PIN"))
+
+	if config.PprofAddr != "" {
+		internal.StartPprof(config.PprofAddr)
+	}
+
+	// One pooled transport for Trakt, the enrichments and Letterboxd
+	internal.ConfigureHTTP(config.HTTP)
+
+	// Initialize rate limiters
+	config.RateLimiter = internal.NewRateLimiter()
+	config.LetterboxdRateLimiter = internal.NewLetterboxdRateLimiter(config.LetterboxdRate)
+	config.TMDBRateLimiter = internal.NewTMDBRateLimiter()
+	config.WikidataRateLimiter = internal.NewWikidataRateLimiter()
+	config.JikanRateLimiter = internal.NewJikanRateLimiter()
+	config.SIMKLRateLimiter = internal.NewSIMKLRateLimiter()
 	config.TempDir = filepath.Join(os.TempDir(), "trakt_data")
+
+	// A bad key would otherwise surface as thousands of retried 403s
+	if !config.NoPreflight {
+		limit, err := internal.Preflight(internal.NewHTTPClient(30*time.Second), config)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(internal.ExitCode(err))
+		}
+		internal.OutputPreflight(limit)
+	}
+
+	// Only a key that passed the preflight check is worth remembering
+	if prompted && config.Keychain {
+		if err := internal.KeychainSet(internal.KeychainAPIKey, config.APIKey); err != nil {
+			log.Printf("Warning: Failed to save the API key to the OS keychain: %v", err)
+		} else {
+			internal.Infof("Saved the API key to the OS keychain; later runs with -keychain skip the prompt\n")
+		}
+	}
+
+	if !config.Daemon {
+		config.RunID = internal.NewRunID(time.Now())
+		err := run(config)
+		internal.NotifyWebhook(config)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(internal.ExitCode(err))
+		}
+		return
+	}
+
+	// Daemon mode: repeat the run until interrupted. A signal during a run
+	// stops it as usual; between runs it ends the daemon cleanly. The change
+	// feed holds back each run's changes until the run is over.
+	var feed *internal.ChangeFeed
+	if config.ChangesAddr != "" {
+		feed = internal.StartChangeFeed(config.ChangesAddr, config.ChangelogDir)
+	}
+	for {
+		config.RunID = internal.NewRunID(time.Now())
+		feed.SetRunning(config.RunID)
+		err := run(config)
+		feed.SetRunning("")
+		internal.NotifyWebhook(config)
+		if err != nil {
+			log.Printf("Run failed (exit code %d): %v", internal.ExitCode(err), err)
+		}
+		log.Printf("Next run at %s", time.Now().Add(config.Interval).Format(time.RFC3339))
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-ctx.Done():
+			stop()
+			log.Printf("Daemon stopped")
+			return
+		case <-time.After(config.Interval):
+		}
+		stop()
+	}
+}
+
+// run performs one processing run: it fetches, enriches and saves the inputs,
+// then removes the per-run cache directories. The Trakt responses persist
+// between runs, kept fresh by the Trakt updates feed. An error that saved nothing
+// stops the run; otherwise the worst partial failure is returned.
+func run(config internal.Config) error {
+	start := time.Now()
+	internal.StartResources()
+	// Create temp directory structure
 	os.MkdirAll(filepath.Join(config.TempDir, "shows"), 0755)
 	os.MkdirAll(filepath.Join(config.TempDir, "movies"), 0755)
 	os.MkdirAll(filepath.Join(config.TempDir, "seasons"), 0755)
 	os.MkdirAll(filepath.Join(config.TempDir, "letterboxd"), 0755)
 	os.MkdirAll(filepath.Join(config.TempDir, "search"), 0755) // Fribb TMDB search cache
+	os.MkdirAll(filepath.Join(config.TempDir, "tmdb"), 0755)
+	os.MkdirAll(filepath.Join(config.TempDir, "tvdb"), 0755)
+	os.MkdirAll(filepath.Join(config.TempDir, "popularity"), 0755)
 
-	// Initialize rate limiters
-	config.RateLimiter = internal.NewRateLimiter()
-	config.LetterboxdRateLimiter = internal.NewLetterboxdRateLimiter()
+	// Fresh per run: the in-memory cache must not outlive the disk cache
+	// it fronts, and budgets apply to each run
+	config.Cache = internal.NewCache(config.CacheEntries)
+	config.Budget = config.Budget.ForRun()
+	if config.ErrorsFile != "" {
+		config.Errors = internal.NewErrorLog()
+		defer func() {
+			if err := config.Errors.Write(config.ErrorsFile); err != nil {
+				log.Printf("Warning: Failed to write error report: %v", err)
+			}
+		}()
+	}
+
+	// Deferred, so a run that fails part-way still reports what it did
+	if config.ReportHTML != "" || config.HistoryFile != "" {
+		config.Report = internal.NewRunReport()
+		defer func() {
+			if err := config.Report.AppendHistory(config.HistoryFile, config.RunID); err != nil {
+				log.Printf("Warning: Failed to update the run history: %v", err)
+			}
+			if err := config.Report.Write(config.ReportHTML, internal.CurrentResources(config.Cache.Stats()), config.ChangelogDir); err != nil {
+				log.Printf("Warning: Failed to write HTML report: %v", err)
+			}
+		}()
+	}
+
+	// Drop cached responses Trakt has changed since they were fetched
+	if !config.Force {
+		internal.InvalidateUpdatedCache(config)
+	}
 
 	// Create progress marker
 	progressFile := filepath.Join(os.TempDir(), ".progress")
 	os.WriteFile(progressFile, []byte{}, 0644)
 
 	defer func() {
-		// Clean up temp directories except letterboxd (persisted by GitHub Actions cache)
-		os.RemoveAll(filepath.Join(config.TempDir, "shows"))
-		os.RemoveAll(filepath.Join(config.TempDir, "movies"))
-		os.RemoveAll(filepath.Join(config.TempDir, "seasons"))
+		// Clean up the per-run directories. The Trakt responses (shows,
+		// movies, seasons) and letterboxd persist, like in the GitHub Actions
+		// cache; InvalidateUpdatedCache drops what Trakt changed since.
 		os.RemoveAll(filepath.Join(config.TempDir, "search"))
+		os.RemoveAll(filepath.Join(config.TempDir, "tmdb"))
+		os.RemoveAll(filepath.Join(config.TempDir, "tvdb"))
+		os.RemoveAll(filepath.Join(config.TempDir, "popularity"))
 		os.Remove(progressFile)
+
+		// Whatever persists stays within the limits
+		if removed, freed, err := internal.PruneDiskCache(config.TempDir, config.CacheLimits, false); err != nil {
+			log.Printf("Warning: Failed to prune cache: %v", err)
+		} else if removed > 0 && config.Verbose {
+			internal.Debugf("Evicted %d cache entries (%d bytes)\n", removed, freed)
+		}
 	}()
 
-	if config.TvFile != "" {
-		internal.ProcessShows(config)
+	var steps []func(internal.Config) error
+	if len(config.TvFiles) > 0 {
+		steps = append(steps, internal.ProcessShows)
 	}
-	if config.MovieFile != "" {
-		internal.ProcessMovies(config)
+	if len(config.MovieFiles) > 0 {
+		steps = append(steps, internal.ProcessMovies)
 	}
 	// Fribb-based ingestion: triggered when -fribb or -animeapi was explicitly
 	// passed on the command line, even as an empty string (empty = fetch from
 	// the internet).  We use config.UseFribb (set via flag.Visit) instead of
 	// checking FribbFile != "" so that `-fribb ""` is handled correctly.
 	if config.UseFribb {
-		internal.ProcessFribb(config)
+		steps = append(steps, internal.ProcessFribb)
+	}
+	var result error
+	for _, step := range steps {
+		err := step(config)
+		if !internal.SavedResults(err) {
+			return err
+		}
+		result = internal.WorseError(result, err)
+	}
+
+	internal.OutputCacheStats(config.Cache.Stats())
+	internal.SaveCacheStats(config.TempDir, config.Cache.Stats())
+	report := internal.CurrentResources(config.Cache.Stats())
+	internal.OutputResources(report)
+	if config.ResourcesFile != "" {
+		if err := internal.SaveResources(config.ResourcesFile, report); err != nil {
+			log.Printf("Warning: Failed to write resource report: %v", err)
+		}
+	}
+	internal.OutputRuntimeStats(config, start)
+	// Streamed results never touch json/output, so its manifest stays as is
+	if config.OutputFile != internal.StdioPath || config.UseFribb {
+		internal.WriteManifest()
+	}
+	return result
+}
+
+// readAPIKey reads the Trakt API key from a secret source, exiting when it
+// cannot: a key the user pointed at but that cannot be read is not worth a
+// prompt
+func readAPIKey(name, source string) string {
+	key, err := internal.ReadSecret(source)
+	if err != nil {
+		log.Fatalf("Failed to read the API key from %s: %v", name, err)
 	}
+	return key
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal"
+	"github.com/rensetsu/db.trakt.extended-anitrakt/internal/trakttest"
+)
+
+func TestRunInvalidatesUpdatedCacheBetweenRuns(t *testing.T) {
+	t.Chdir(t.TempDir())
+	internal.LoadLayout()
+
+	server := trakttest.NewServer(t)
+	server.Show(30857, "cowboy-bebop", map[string]any{
+		"title": "Cowboy Bebop", "year": 1998, "status": "returning series", "updated_at": "2026-01-01T00:00:00.000Z",
+		"ids": map[string]any{"trakt": 30857, "slug": "cowboy-bebop"},
+	})
+	server.Seasons(30857, []map[string]any{{"number": 1, "episode_count": 26, "ids": map[string]any{"trakt": 1}}})
+	server.Updates("shows", []any{})
+	server.Updates("movies", []any{})
+
+	os.WriteFile("tv.json", []byte(`[{"title": "Cowboy Bebop", "mal_id": 1, "trakt_id": 30857, "season": 1, "type": "shows"}]`), 0644)
+	config := internal.Config{
+		APIKey:        "key",
+		TraktAPIURL:   server.URL,
+		TempDir:       t.TempDir(),
+		TvFiles:       []string{"tv.json"},
+		NoProgress:    true,
+		RefreshAiring: true,
+		RateLimiter:   internal.NewRateLimiter(),
+		Budget:        &internal.Budget{},
+	}
+
+	if err := run(config); err != nil {
+		t.Fatalf("first run = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.TempDir, "shows", "30857.json")); err != nil {
+		t.Fatalf("the show's response was not kept after the run: %v", err)
+	}
+
+	// Trakt changed the show after it was cached, so the airing refresh has
+	// to fetch it again rather than read the cache
+	server.Updates("shows", []map[string]any{{
+		"updated_at": time.Now().UTC().Format(time.RFC3339),
+		"show":       map[string]any{"ids": map[string]any{"trakt": 30857}},
+	}})
+	if err := run(config); err != nil {
+		t.Fatalf("second run = %v", err)
+	}
+	if n := server.Count("/shows/30857"); n != 2 {
+		t.Errorf("/shows/30857 fetched %d times, want 2", n)
+	}
+	if n := server.Count("/shows/30857/seasons"); n != 2 {
+		t.Errorf("/shows/30857/seasons fetched %d times, want 2", n)
+	}
+}
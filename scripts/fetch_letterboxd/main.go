@@ -30,7 +30,8 @@ func main() {
 		TempDir:               filepath.Join(os.TempDir(), "trakt_data"),
 		Verbose:               *verbose,
 		Force:                 *force,
-		LetterboxdRateLimiter: internal.NewLetterboxdRateLimiter(),
+		LetterboxdRateLimiter: internal.NewLetterboxdRateLimiter(internal.DefaultLetterboxdRate),
+		LetterboxdDelay:       internal.DefaultLetterboxdDelay,
 	}
 
 	// Ensure cache directory exists
@@ -51,13 +52,16 @@ func main() {
 			needsFetch = true
 		}
 
-		if needsFetch && movie.Externals != nil && movie.Externals.TMDB != nil && *movie.Externals.TMDB != 0 {
+		// Movies without a TMDB ID are looked up by IMDB ID
+		hasTMDB := movie.Externals != nil && movie.Externals.TMDB != nil && *movie.Externals.TMDB != 0
+		hasIMDB := movie.Externals != nil && movie.Externals.IMDB != nil && *movie.Externals.IMDB != ""
+		if needsFetch && (hasTMDB || hasIMDB) {
 			toFetch = append(toFetch, movie)
 		}
 	}
 
 	if len(toFetch) == 0 {
-		fmt.Println("All movies already have Letterboxd metadata or are missing TMDB IDs.")
+		fmt.Println("All movies already have Letterboxd metadata or are missing TMDB and IMDB IDs.")
 		os.Exit(0)
 	}
 
@@ -67,14 +71,18 @@ func main() {
 	failCount := 0
 
 	for i, movie := range toFetch {
-		fmt.Printf("[%d/%d] Fetching Letterboxd data for: %s (MAL ID: %d, TMDB ID: %d)...", i+1, len(toFetch), movie.MyAnimeList.Title, movie.MyAnimeList.ID, *movie.Externals.TMDB)
-
-		var existingLetterboxd *internal.Letterboxd
-		if movie.Externals != nil {
-			existingLetterboxd = movie.Externals.Letterboxd
+		var tmdbID int
+		if movie.Externals.TMDB != nil {
+			tmdbID = *movie.Externals.TMDB
+		}
+		var imdbID string
+		if movie.Externals.IMDB != nil {
+			imdbID = *movie.Externals.IMDB
 		}
+		fmt.Printf("[%d/%d] Fetching Letterboxd data for: %s (MAL ID: %d, TMDB ID: %d, IMDB ID: %s)...", i+1, len(toFetch), movie.MyAnimeList.Title, movie.MyAnimeList.ID, tmdbID, imdbID)
 
-		lbInfo, err := internal.FetchLetterboxdInfo(client, config, *movie.Externals.TMDB, existingLetterboxd)
+		existingLetterboxd := movie.Externals.Letterboxd
+		lbInfo, err := internal.FetchLetterboxdInfo(client, config, tmdbID, imdbID, existingLetterboxd)
 		if err != nil {
 			fmt.Printf(" ERROR: %v\n", err)
 			failCount++
@@ -100,7 +108,7 @@ func main() {
 
 	if successCount > 0 {
 		fmt.Printf("\nSaving %d updated movies to %s...\n", successCount, *movieFile)
-		internal.SaveMovieResults(*movieFile, resultsMap)
+		internal.SaveMovieResults(*movieFile, resultsMap, internal.SortMalID)
 	}
 
 	fmt.Printf("\nFinished: %d successfully fetched, %d failed/skipped.\n", successCount, failCount)